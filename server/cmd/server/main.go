@@ -2,7 +2,11 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"log"
+	"os"
+	"time"
 
 	"lemma/internal/app"
 	"lemma/internal/logging"
@@ -28,6 +32,14 @@ func main() {
 	logging.Setup(cfg.LogLevel)
 	logging.Debug("Configuration loaded", "config", cfg.Redact())
 
+	// Run a maintenance command instead of the server if one is given
+	if len(os.Args) > 1 {
+		if err := runCommand(cfg, os.Args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	// Initialize and start server
 	options, err := app.DefaultOptions(cfg)
 	if err != nil {
@@ -46,3 +58,108 @@ func main() {
 		log.Fatal("Server error:", err)
 	}
 }
+
+// runCommand executes a maintenance command given on the command line
+func runCommand(cfg *app.Config, args []string) error {
+	switch args[0] {
+	case "rotate-jwt-key":
+		algorithm := ""
+		if len(args) > 1 {
+			algorithm = args[1]
+		}
+
+		key, err := app.RotateJWTKey(cfg, algorithm)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Created JWT signing key %s (%s)\n", key.ID, key.Algorithm)
+		return nil
+	case "rotate-encryption-key":
+		keyID, count, err := app.RotateEncryptionKey(cfg)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Re-encrypted %d secrets with key %s\n", count, keyID)
+		return nil
+	case "migrate":
+		return runMigrateCommand(cfg, args[1:])
+	case "backup":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: backup FILE")
+		}
+
+		manifest, err := app.Backup(cfg, args[1])
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Created backup %s (schema version %d)\n", args[1], manifest.SchemaVersion)
+		return nil
+	case "restore":
+		if len(args) < 2 {
+			return fmt.Errorf("usage: restore FILE")
+		}
+
+		manifest, err := app.Restore(cfg, args[1])
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Restored backup from %s (schema version %d)\n",
+			manifest.CreatedAt.Format(time.RFC3339), manifest.SchemaVersion)
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q, available commands: rotate-jwt-key [HS256|RS256|EdDSA], rotate-encryption-key, %s, backup FILE, restore FILE",
+			args[0], migrateUsage)
+	}
+}
+
+const migrateUsage = "migrate status|up [--to N]|down --to N"
+
+// runMigrateCommand shows the state of the database migrations or migrates the database
+func runMigrateCommand(cfg *app.Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: %s", migrateUsage)
+	}
+
+	flags := flag.NewFlagSet("migrate "+args[0], flag.ContinueOnError)
+	to := flags.Int("to", -1, "version to migrate to")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "status":
+		statuses, err := app.MigrationStatus(cfg)
+		if err != nil {
+			return err
+		}
+
+		for _, status := range statuses {
+			fmt.Printf("%4d  %s\n", status.Version, status.State)
+		}
+		return nil
+	case "up":
+		if err := app.MigrateUp(cfg, *to); err != nil {
+			return err
+		}
+
+		fmt.Println("Database migrated")
+		return nil
+	case "down":
+		if *to < 0 {
+			return fmt.Errorf("migrate down requires the version to migrate to, usage: %s", migrateUsage)
+		}
+
+		if err := app.MigrateDown(cfg, *to); err != nil {
+			return err
+		}
+
+		fmt.Printf("Database migrated down to version %d\n", *to)
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command %q, usage: %s", args[0], migrateUsage)
+	}
+}
@@ -19,31 +19,102 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/admin/stats": {
+        "/admin/audit-events": {
             "get": {
                 "security": [
                     {
                         "CookieAuth": []
                     }
                 ],
-                "description": "Get system-wide statistics as an admin",
+                "description": "Returns a page of the audit log, newest first. Events can be filtered by type, actor, target, workspace and time.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "Admin"
                 ],
-                "summary": "Get system statistics",
-                "operationId": "adminGetSystemStats",
+                "summary": "List audit events",
+                "operationId": "adminListAuditEvents",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Event type, e.g. login_failed",
+                        "name": "event",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID of the user who performed the action",
+                        "name": "actorId",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "user",
+                            "workspace",
+                            "session",
+                            "file",
+                            "invitation",
+                            "share_link",
+                            "system"
+                        ],
+                        "type": "string",
+                        "description": "Type of the target",
+                        "name": "targetType",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the target, the path for files",
+                        "name": "targetId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Workspace ID",
+                        "name": "workspaceId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only events at or after this time (RFC 3339)",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only events before this time (RFC 3339)",
+                        "name": "until",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of events, 50 by default and at most 500",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of events to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.SystemStats"
+                            "$ref": "#/definitions/handlers.AuditEventsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid query parameter",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Failed to get file stats",
+                        "description": "Failed to list audit events",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
@@ -51,34 +122,72 @@ const docTemplate = `{
                 }
             }
         },
-        "/admin/users": {
+        "/admin/backup": {
             "get": {
                 "security": [
                     {
                         "CookieAuth": []
                     }
                 ],
-                "description": "Returns the list of all users",
+                "description": "Downloads a gzipped tar archive of the database and all workspace files, with a manifest as first entry.\nFile writes are paused while the archive is created. Only SQLite databases can be backed up.",
+                "produces": [
+                    "application/gzip"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Create a backup",
+                "operationId": "adminCreateBackup",
+                "responses": {
+                    "200": {
+                        "description": "Backup archive",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to create backup",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "Backups are only supported for SQLite databases",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/invitations": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Lists all invitations including expired and used up ones",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "Admin"
                 ],
-                "summary": "List all users",
-                "operationId": "adminListUsers",
+                "summary": "List invitations",
+                "operationId": "adminListInvitations",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/models.User"
+                                "$ref": "#/definitions/models.Invitation"
                             }
                         }
                     },
                     "500": {
-                        "description": "Failed to list users",
+                        "description": "Failed to list invitations",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
@@ -91,7 +200,7 @@ const docTemplate = `{
                         "CookieAuth": []
                     }
                 ],
-                "description": "Create a new user as an admin",
+                "description": "Creates an invitation link for signing up with the given role. The token is only returned in this response.",
                 "consumes": [
                     "application/json"
                 ],
@@ -101,16 +210,16 @@ const docTemplate = `{
                 "tags": [
                     "Admin"
                 ],
-                "summary": "Create a new user",
-                "operationId": "adminCreateUser",
+                "summary": "Create invitation",
+                "operationId": "adminCreateInvitation",
                 "parameters": [
                     {
-                        "description": "User details",
-                        "name": "user",
+                        "description": "Invitation details",
+                        "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.CreateUserRequest"
+                            "$ref": "#/definitions/handlers.CreateInvitationRequest"
                         }
                     }
                 ],
@@ -118,23 +227,17 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.User"
+                            "$ref": "#/definitions/handlers.CreateInvitationResponse"
                         }
                     },
                     "400": {
-                        "description": "Password must be at least 8 characters",
-                        "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
-                        }
-                    },
-                    "409": {
-                        "description": "Email already exists",
+                        "description": "Expiry must be in the future",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Failed to initialize user workspace",
+                        "description": "Failed to create invitation",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
@@ -142,59 +245,90 @@ const docTemplate = `{
                 }
             }
         },
-        "/admin/users/{userId}": {
-            "get": {
+        "/admin/invitations/{invitationId}": {
+            "delete": {
                 "security": [
                     {
                         "CookieAuth": []
                     }
                 ],
-                "description": "Get a specific user as an admin",
-                "produces": [
-                    "application/json"
-                ],
+                "description": "Deletes an invitation so it can no longer be used to sign up",
                 "tags": [
                     "Admin"
                 ],
-                "summary": "Get a specific user",
-                "operationId": "adminGetUser",
+                "summary": "Delete invitation",
+                "operationId": "adminDeleteInvitation",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "User ID",
-                        "name": "userId",
+                        "description": "Invitation ID",
+                        "name": "invitationId",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/models.User"
-                        }
+                    "204": {
+                        "description": "No Content - Invitation deleted successfully"
                     },
                     "400": {
-                        "description": "Invalid user ID",
+                        "description": "Invalid invitation ID",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
                     "404": {
-                        "description": "User not found",
+                        "description": "Invitation not found",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     }
                 }
-            },
-            "put": {
+            }
+        },
+        "/admin/jwt-keys": {
+            "get": {
                 "security": [
                     {
                         "CookieAuth": []
                     }
                 ],
-                "description": "Update a specific user as an admin",
+                "description": "Lists the keys of the JWT keyring that can still be used to verify tokens",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "List JWT signing keys",
+                "operationId": "adminListJWTKeys",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.JWTKey"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to list JWT keys",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/jwt-keys/rotate": {
+            "post": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Creates a new active JWT signing key. Tokens signed with previous keys stay valid until they expire.",
                 "consumes": [
                     "application/json"
                 ],
@@ -204,23 +338,15 @@ const docTemplate = `{
                 "tags": [
                     "Admin"
                 ],
-                "summary": "Update a specific user",
-                "operationId": "adminUpdateUser",
+                "summary": "Rotate the JWT signing key",
+                "operationId": "adminRotateJWTKey",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "User ID",
-                        "name": "userId",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "User details",
-                        "name": "user",
+                        "description": "Rotation options",
+                        "name": "body",
                         "in": "body",
-                        "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.UpdateUserRequest"
+                            "$ref": "#/definitions/handlers.RotateJWTKeyRequest"
                         }
                     }
                 ],
@@ -228,74 +354,99 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.User"
+                            "$ref": "#/definitions/models.JWTKey"
                         }
                     },
                     "400": {
-                        "description": "Invalid request body",
+                        "description": "Unsupported signing algorithm",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "User not found",
+                    "500": {
+                        "description": "Failed to rotate JWT key",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
+                    }
+                }
+            }
+        },
+        "/admin/settings/quota": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Gets the storage quota of users without their own quota. Zero limits are unlimited.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Get default storage quota",
+                "operationId": "adminGetDefaultQuota",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.StorageQuota"
+                        }
                     },
                     "500": {
-                        "description": "Failed to update user",
+                        "description": "Failed to get default quota",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     }
                 }
             },
-            "delete": {
+            "put": {
                 "security": [
                     {
                         "CookieAuth": []
                     }
                 ],
-                "description": "Delete a specific user as an admin",
+                "description": "Sets the storage quota of users without their own quota. Zero limits are unlimited.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
                     "Admin"
                 ],
-                "summary": "Delete a specific user",
-                "operationId": "adminDeleteUser",
+                "summary": "Update default storage quota",
+                "operationId": "adminUpdateDefaultQuota",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "User ID",
-                        "name": "userId",
-                        "in": "path",
-                        "required": true
+                        "description": "Default storage quota",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.StorageQuota"
+                        }
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content"
-                    },
-                    "400": {
-                        "description": "Cannot delete your own account",
-                        "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
-                        }
-                    },
-                    "403": {
-                        "description": "Cannot delete other admin users",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "$ref": "#/definitions/models.StorageQuota"
                         }
                     },
-                    "404": {
-                        "description": "User not found",
+                    "400": {
+                        "description": "Quota limits must not be negative",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Failed to delete user",
+                        "description": "Failed to update default quota",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
@@ -303,44 +454,44 @@ const docTemplate = `{
                 }
             }
         },
-        "/admin/workspaces": {
+        "/admin/settings/registration": {
             "get": {
                 "security": [
                     {
                         "CookieAuth": []
                     }
                 ],
-                "description": "List all workspaces and their stats as an admin",
+                "description": "Gets whether users can sign up without an invitation and the role they get",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "Admin"
                 ],
-                "summary": "List all workspaces",
-                "operationId": "adminListWorkspaces",
+                "summary": "Get registration settings",
+                "operationId": "adminGetRegistrationSettings",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/handlers.WorkspaceStats"
-                            }
+                            "$ref": "#/definitions/models.RegistrationSettings"
                         }
                     },
                     "500": {
-                        "description": "Failed to get file stats",
+                        "description": "Failed to get registration settings",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     }
                 }
-            }
-        },
-        "/auth/login": {
-            "post": {
-                "description": "Logs in a user and returns a session with access and refresh tokens",
+            },
+            "put": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Sets whether users can sign up without an invitation, the role they get and whether they have to confirm their email address.\nThe default role can be editor or viewer.",
                 "consumes": [
                     "application/json"
                 ],
@@ -348,17 +499,18 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "Admin"
                 ],
-                "summary": "Login",
+                "summary": "Update registration settings",
+                "operationId": "adminUpdateRegistrationSettings",
                 "parameters": [
                     {
-                        "description": "Login request",
+                        "description": "Registration settings",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.LoginRequest"
+                            "$ref": "#/definitions/models.RegistrationSettings"
                         }
                     }
                 ],
@@ -366,29 +518,17 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.LoginResponse"
-                        },
-                        "headers": {
-                            "X-CSRF-Token": {
-                                "type": "string",
-                                "description": "CSRF token for future requests"
-                            }
+                            "$ref": "#/definitions/models.RegistrationSettings"
                         }
                     },
                     "400": {
-                        "description": "Email and password are required",
-                        "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
-                        }
-                    },
-                    "401": {
-                        "description": "Invalid credentials",
+                        "description": "Invalid default role",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Failed to generate CSRF token",
+                        "description": "Failed to update registration settings",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
@@ -396,26 +536,31 @@ const docTemplate = `{
                 }
             }
         },
-        "/auth/logout": {
-            "post": {
-                "description": "Log out invalidates the user's session",
+        "/admin/stats": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Get system-wide statistics as an admin",
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "auth"
+                    "Admin"
                 ],
-                "summary": "Logout",
-                "operationId": "logout",
+                "summary": "Get system statistics",
+                "operationId": "adminGetSystemStats",
                 "responses": {
-                    "204": {
-                        "description": "No Content"
-                    },
-                    "400": {
-                        "description": "Session ID required",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "$ref": "#/definitions/handlers.SystemStats"
                         }
                     },
                     "500": {
-                        "description": "Failed to logout",
+                        "description": "Failed to get file stats",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
@@ -423,31 +568,34 @@ const docTemplate = `{
                 }
             }
         },
-        "/auth/me": {
+        "/admin/trash/users": {
             "get": {
                 "security": [
                     {
                         "CookieAuth": []
                     }
                 ],
-                "description": "Returns the current authenticated user",
+                "description": "Returns the users in the trash, most recently deleted first. They are permanently deleted after the trash retention period.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "Admin"
                 ],
-                "summary": "Get current user",
-                "operationId": "getCurrentUser",
+                "summary": "List deleted users",
+                "operationId": "adminListDeletedUsers",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.User"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.User"
+                            }
                         }
                     },
-                    "404": {
-                        "description": "User not found",
+                    "500": {
+                        "description": "Failed to list deleted users",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
@@ -455,44 +603,40 @@ const docTemplate = `{
                 }
             }
         },
-        "/auth/refresh": {
+        "/admin/trash/users/{userId}/restore": {
             "post": {
-                "description": "Refreshes the access token using the refresh token",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
                 ],
+                "description": "Restores a user from the trash together with the workspaces deleted with them",
                 "tags": [
-                    "auth"
+                    "Admin"
+                ],
+                "summary": "Restore a deleted user",
+                "operationId": "adminRestoreUser",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Refresh token",
-                "operationId": "refreshToken",
                 "responses": {
-                    "200": {
-                        "description": "OK",
-                        "headers": {
-                            "X-CSRF-Token": {
-                                "type": "string",
-                                "description": "New CSRF token"
-                            }
-                        }
+                    "204": {
+                        "description": "No Content - User restored successfully"
                     },
                     "400": {
-                        "description": "Refresh token required",
-                        "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
-                        }
-                    },
-                    "401": {
-                        "description": "Invalid refresh token",
+                        "description": "Invalid user ID",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Failed to generate CSRF token",
+                    "404": {
+                        "description": "Deleted user not found",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
@@ -500,134 +644,81 @@ const docTemplate = `{
                 }
             }
         },
-        "/profile": {
-            "put": {
+        "/admin/trash/workspaces": {
+            "get": {
                 "security": [
                     {
                         "CookieAuth": []
                     }
                 ],
-                "description": "Updates the user's profile",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Returns the workspaces in the trash, including the workspaces of deleted users, most recently deleted first.\nThey are permanently deleted with their files after the trash retention period.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "users"
-                ],
-                "summary": "Update profile",
-                "operationId": "updateProfile",
-                "parameters": [
-                    {
-                        "description": "Profile update request",
-                        "name": "body",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handlers.UpdateProfileRequest"
-                        }
-                    }
+                    "Admin"
                 ],
+                "summary": "List deleted workspaces",
+                "operationId": "adminListDeletedWorkspaces",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.User"
-                        }
-                    },
-                    "400": {
-                        "description": "Current password is required to change email",
-                        "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
-                        }
-                    },
-                    "401": {
-                        "description": "Current password is incorrect",
-                        "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
-                        }
-                    },
-                    "404": {
-                        "description": "User not found",
-                        "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
-                        }
-                    },
-                    "409": {
-                        "description": "Email already in use",
-                        "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Workspace"
+                            }
                         }
                     },
                     "500": {
-                        "description": "Failed to update profile",
+                        "description": "Failed to list deleted workspaces",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/admin/trash/workspaces/{workspaceId}/restore": {
+            "post": {
                 "security": [
                     {
                         "CookieAuth": []
                     }
                 ],
-                "description": "Deletes the user's account and all associated data",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
+                "description": "Restores a workspace from the trash. Workspaces of deleted users are restored by restoring their owner.\nIf a member has access to another workspace with the same name by now, a number is appended to the name.",
                 "tags": [
-                    "users"
+                    "Admin"
                 ],
-                "summary": "Delete account",
-                "operationId": "deleteAccount",
+                "summary": "Restore a deleted workspace",
+                "operationId": "adminRestoreWorkspace",
                 "parameters": [
                     {
-                        "description": "Account deletion request",
-                        "name": "body",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handlers.DeleteAccountRequest"
-                        }
+                        "type": "integer",
+                        "description": "Workspace ID",
+                        "name": "workspaceId",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "204": {
-                        "description": "No Content - Account deleted successfully"
+                        "description": "No Content - Workspace restored successfully"
                     },
                     "400": {
-                        "description": "Invalid request body",
-                        "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
-                        }
-                    },
-                    "401": {
-                        "description": "Password is incorrect",
-                        "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
-                        }
-                    },
-                    "403": {
-                        "description": "Cannot delete the last admin account",
+                        "description": "Invalid workspace ID",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
                     "404": {
-                        "description": "User not found",
+                        "description": "Deleted workspace not found",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Failed to delete account",
+                    "409": {
+                        "description": "The workspace owner is deleted",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
@@ -635,34 +726,34 @@ const docTemplate = `{
                 }
             }
         },
-        "/workspaces": {
+        "/admin/users": {
             "get": {
                 "security": [
                     {
                         "CookieAuth": []
                     }
                 ],
-                "description": "Lists all workspaces for the current user",
+                "description": "Returns the list of all users",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "workspaces"
+                    "Admin"
                 ],
-                "summary": "List workspaces",
-                "operationId": "listWorkspaces",
+                "summary": "List all users",
+                "operationId": "adminListUsers",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/models.Workspace"
+                                "$ref": "#/definitions/models.User"
                             }
                         }
                     },
                     "500": {
-                        "description": "Failed to list workspaces",
+                        "description": "Failed to list users",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
@@ -675,7 +766,7 @@ const docTemplate = `{
                         "CookieAuth": []
                     }
                 ],
-                "description": "Creates a new workspace",
+                "description": "Create a new user as an admin",
                 "consumes": [
                     "application/json"
                 ],
@@ -683,18 +774,18 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "workspaces"
+                    "Admin"
                 ],
-                "summary": "Create workspace",
-                "operationId": "createWorkspace",
+                "summary": "Create a new user",
+                "operationId": "adminCreateUser",
                 "parameters": [
                     {
-                        "description": "Workspace",
-                        "name": "body",
+                        "description": "User details",
+                        "name": "user",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.Workspace"
+                            "$ref": "#/definitions/handlers.CreateUserRequest"
                         }
                     }
                 ],
@@ -702,17 +793,23 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Workspace"
+                            "$ref": "#/definitions/models.User"
                         }
                     },
                     "400": {
-                        "description": "Invalid workspace",
+                        "description": "Password must be at least 8 characters",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Email already exists",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Failed to setup git repo",
+                        "description": "Failed to initialize user workspace",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
@@ -720,67 +817,111 @@ const docTemplate = `{
                 }
             }
         },
-        "/workspaces/last": {
+        "/admin/users/export": {
             "get": {
                 "security": [
                     {
                         "CookieAuth": []
                     }
                 ],
-                "description": "Returns the name of the last opened workspace",
+                "description": "Exports all users as a JSON array or, with format=csv, as CSV with a header row.\nThe export starts with the columns of user imports, so it can be imported again.",
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "text/csv"
                 ],
                 "tags": [
-                    "workspaces"
+                    "Admin"
+                ],
+                "summary": "Export users",
+                "operationId": "adminExportUsers",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "json (default) or csv",
+                        "name": "format",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Get last workspace name",
-                "operationId": "getLastWorkspaceName",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.LastWorkspaceNameResponse"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.UserExportRow"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid format",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Failed to get last workspace",
+                        "description": "Failed to list workspaces",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     }
                 }
-            },
-            "put": {
+            }
+        },
+        "/admin/users/import": {
+            "post": {
                 "security": [
                     {
                         "CookieAuth": []
                     }
                 ],
-                "description": "Updates the name of the last opened workspace",
+                "description": "Creates users from a JSON array or, with the text/csv content type, from CSV with a header row.\nThe CSV columns are email, displayName, role and workspace; other columns are ignored.\nRows are imported independently and the response reports the outcome of each row.\nImported users either get a temporary password they have to change on their first login,\nor an invitation link for signing up. Passwords and links are only returned in this response.",
                 "consumes": [
-                    "application/json"
+                    "application/json",
+                    "text/csv"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "workspaces"
+                    "Admin"
                 ],
-                "summary": "Update last workspace name",
-                "operationId": "updateLastWorkspaceName",
-                "responses": {
-                    "204": {
-                        "description": "No Content - Last workspace updated successfully"
+                "summary": "Import users",
+                "operationId": "adminImportUsers",
+                "parameters": [
+                    {
+                        "description": "Users to import",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.UserImportRow"
+                            }
+                        }
                     },
-                    "400": {
-                        "description": "Invalid request body",
+                    {
+                        "type": "boolean",
+                        "description": "Only validate the rows",
+                        "name": "dryRun",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "password (default) or invite",
+                        "name": "credentials",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.ErrorResponse"
+                            "$ref": "#/definitions/handlers.UserImportResponse"
                         }
                     },
-                    "500": {
-                        "description": "Failed to update last workspace",
+                    "400": {
+                        "description": "Too many rows",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
@@ -788,27 +929,27 @@ const docTemplate = `{
                 }
             }
         },
-        "/workspaces/{workspace_name}": {
+        "/admin/users/{userId}": {
             "get": {
                 "security": [
                     {
                         "CookieAuth": []
                     }
                 ],
-                "description": "Returns the current workspace",
+                "description": "Get a specific user as an admin, along with their storage usage and quota",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "workspaces"
+                    "Admin"
                 ],
-                "summary": "Get workspace",
-                "operationId": "getWorkspace",
+                "summary": "Get a specific user",
+                "operationId": "adminGetUser",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Workspace name",
-                        "name": "workspace_name",
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "userId",
                         "in": "path",
                         "required": true
                     }
@@ -817,11 +958,23 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Workspace"
+                            "$ref": "#/definitions/handlers.UserStorageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid user ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Internal server error",
+                        "description": "Failed to get storage usage",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
@@ -834,7 +987,7 @@ const docTemplate = `{
                         "CookieAuth": []
                     }
                 ],
-                "description": "Updates the current workspace",
+                "description": "Update a specific user as an admin",
                 "consumes": [
                     "application/json"
                 ],
@@ -842,25 +995,25 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "workspaces"
+                    "Admin"
                 ],
-                "summary": "Update workspace",
-                "operationId": "updateWorkspace",
+                "summary": "Update a specific user",
+                "operationId": "adminUpdateUser",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Workspace name",
-                        "name": "workspace_name",
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "userId",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Workspace",
-                        "name": "body",
+                        "description": "User details",
+                        "name": "user",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/models.Workspace"
+                            "$ref": "#/definitions/handlers.UpdateUserRequest"
                         }
                     }
                 ],
@@ -868,7 +1021,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/models.Workspace"
+                            "$ref": "#/definitions/models.User"
                         }
                     },
                     "400": {
@@ -877,8 +1030,14 @@ const docTemplate = `{
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
                     "500": {
-                        "description": "Failed to setup git repo",
+                        "description": "Failed to revoke user sessions",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
@@ -891,83 +1050,45 @@ const docTemplate = `{
                         "CookieAuth": []
                     }
                 ],
-                "description": "Deletes the current workspace",
-                "produces": [
-                    "application/json"
-                ],
+                "description": "Delete a specific user as an admin. The user and their workspaces are moved to the trash and permanently deleted after the trash retention period.",
                 "tags": [
-                    "workspaces"
+                    "Admin"
                 ],
-                "summary": "Delete workspace",
-                "operationId": "deleteWorkspace",
+                "summary": "Delete a specific user",
+                "operationId": "adminDeleteUser",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Workspace name",
-                        "name": "workspace_name",
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "userId",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/handlers.DeleteWorkspaceResponse"
-                        }
+                    "204": {
+                        "description": "No Content"
                     },
                     "400": {
-                        "description": "Cannot delete the last workspace",
+                        "description": "Cannot delete your own account",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Failed to commit transaction",
+                    "403": {
+                        "description": "Cannot delete other admin users",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
-                    }
-                }
-            }
-        },
-        "/workspaces/{workspace_name}/files": {
-            "get": {
-                "security": [
-                    {
-                        "CookieAuth": []
-                    }
-                ],
-                "description": "Lists all files in the user's workspace",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "files"
-                ],
-                "summary": "List files",
-                "operationId": "listFiles",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Workspace name",
-                        "name": "workspace_name",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
+                    },
+                    "404": {
+                        "description": "User not found",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/storage.FileNode"
-                            }
+                            "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Failed to list files",
+                        "description": "Failed to delete user",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
@@ -975,106 +1096,115 @@ const docTemplate = `{
                 }
             }
         },
-        "/workspaces/{workspace_name}/files/last": {
-            "get": {
+        "/admin/users/{userId}/quota": {
+            "put": {
                 "security": [
                     {
                         "CookieAuth": []
                     }
                 ],
-                "description": "Returns the path of the last opened file in the user's workspace",
+                "description": "Sets the storage quota of a user, replacing the system default for them. Zero limits are unlimited.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "files"
+                    "Admin"
                 ],
-                "summary": "Get last opened file",
-                "operationId": "getLastOpenedFile",
+                "summary": "Update a user's storage quota",
+                "operationId": "adminUpdateUserQuota",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Workspace name",
-                        "name": "workspace_name",
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "userId",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Storage quota",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.StorageQuota"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.LastOpenedFileResponse"
+                            "$ref": "#/definitions/handlers.StorageUsageResponse"
                         }
                     },
                     "400": {
-                        "description": "Invalid file path",
+                        "description": "Quota limits must not be negative",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Failed to get last opened file",
+                        "description": "Failed to get storage usage",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     }
                 }
             },
-            "put": {
+            "delete": {
                 "security": [
                     {
                         "CookieAuth": []
                     }
                 ],
-                "description": "Updates the last opened file in the user's workspace",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Removes the storage quota of a user, so the system default applies to them again",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "files"
+                    "Admin"
                 ],
-                "summary": "Update last opened file",
-                "operationId": "updateLastOpenedFile",
+                "summary": "Reset a user's storage quota",
+                "operationId": "adminDeleteUserQuota",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Workspace name",
-                        "name": "workspace_name",
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "userId",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "Update last opened file request",
-                        "name": "body",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handlers.UpdateLastOpenedFileRequest"
-                        }
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content - Last opened file updated successfully"
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.StorageUsageResponse"
+                        }
                     },
                     "400": {
-                        "description": "Invalid file path",
+                        "description": "Invalid user ID",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
                     "404": {
-                        "description": "File not found",
+                        "description": "User not found",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Failed to update file",
+                        "description": "Failed to get storage usage",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
@@ -1082,149 +1212,182 @@ const docTemplate = `{
                 }
             }
         },
-        "/workspaces/{workspace_name}/files/lookup": {
+        "/admin/users/{userId}/sessions": {
             "get": {
                 "security": [
                     {
                         "CookieAuth": []
                     }
                 ],
-                "description": "Returns the paths of files with the given name in the user's workspace",
+                "description": "Returns the active sessions of a specific user as an admin",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "files"
+                    "Admin"
                 ],
-                "summary": "Lookup file by name",
-                "operationId": "lookupFileByName",
+                "summary": "List a user's sessions",
+                "operationId": "adminListUserSessions",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Workspace name",
-                        "name": "workspace_name",
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "userId",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "File name",
-                        "name": "filename",
-                        "in": "query",
-                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.LookupResponse"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.SessionResponse"
+                            }
                         }
                     },
                     "400": {
-                        "description": "Filename is required",
+                        "description": "Invalid user ID",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
                     "404": {
-                        "description": "File not found",
+                        "description": "User not found",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
-                    }
-                }
+                    },
+                    "500": {
+                        "description": "Failed to list sessions",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Revokes all sessions of a specific user as an admin, except the admin's own session",
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Revoke all sessions of a user",
+                "operationId": "adminRevokeUserSessions",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content - Sessions revoked successfully"
+                    },
+                    "400": {
+                        "description": "Invalid user ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to revoke sessions",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
             }
         },
-        "/workspaces/{workspace_name}/files/{file_path}": {
-            "get": {
+        "/admin/users/{userId}/sessions/{sessionId}": {
+            "delete": {
                 "security": [
                     {
                         "CookieAuth": []
                     }
                 ],
-                "description": "Returns the content of a file in the user's workspace",
-                "produces": [
-                    "text/plain"
-                ],
+                "description": "Revokes a specific session of a specific user as an admin",
                 "tags": [
-                    "files"
+                    "Admin"
                 ],
-                "summary": "Get file content",
-                "operationId": "getFileContent",
+                "summary": "Revoke a session of a user",
+                "operationId": "adminRevokeUserSession",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Workspace name",
-                        "name": "workspace_name",
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "userId",
                         "in": "path",
                         "required": true
                     },
                     {
                         "type": "string",
-                        "description": "File path",
-                        "name": "file_path",
+                        "description": "Session ID",
+                        "name": "sessionId",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "Raw file content",
-                        "schema": {
-                            "type": "string"
-                        }
+                    "204": {
+                        "description": "No Content - Session revoked successfully"
                     },
                     "400": {
-                        "description": "Invalid file path",
+                        "description": "Invalid user ID",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
                     "404": {
-                        "description": "File not found",
+                        "description": "Session not found",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Failed to write response",
+                        "description": "Failed to revoke session",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     }
                 }
-            },
+            }
+        },
+        "/admin/users/{userId}/suspend": {
             "post": {
                 "security": [
                     {
                         "CookieAuth": []
                     }
                 ],
-                "description": "Saves the content of a file in the user's workspace",
-                "consumes": [
-                    "text/plain"
-                ],
+                "description": "Suspends a specific user as an admin. Suspended users keep their data but can't log in, and all their sessions are revoked.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "files"
+                    "Admin"
                 ],
-                "summary": "Save file",
-                "operationId": "saveFile",
+                "summary": "Suspend a user",
+                "operationId": "adminSuspendUser",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Workspace name",
-                        "name": "workspace_name",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "File path",
-                        "name": "file_path",
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "userId",
                         "in": "path",
                         "required": true
                     }
@@ -1233,69 +1396,70 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.SaveFileResponse"
+                            "$ref": "#/definitions/models.User"
                         }
                     },
                     "400": {
-                        "description": "Invalid file path",
+                        "description": "Cannot suspend your own account",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Failed to save file",
+                        "description": "Failed to revoke user sessions",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/admin/users/{userId}/unlock": {
+            "post": {
                 "security": [
                     {
                         "CookieAuth": []
                     }
                 ],
-                "description": "Deletes a file in the user's workspace",
+                "description": "Lifts a login lockout of a specific user caused by repeated failed login attempts",
                 "tags": [
-                    "files"
+                    "Admin"
                 ],
-                "summary": "Delete file",
-                "operationId": "deleteFile",
+                "summary": "Unlock a user",
+                "operationId": "adminUnlockUser",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Workspace name",
-                        "name": "workspace_name",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "File path",
-                        "name": "file_path",
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "userId",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "204": {
-                        "description": "No Content - File deleted successfully"
+                        "description": "No Content - User unlocked successfully"
                     },
                     "400": {
-                        "description": "Invalid file path",
+                        "description": "Invalid user ID",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
                     "404": {
-                        "description": "File not found",
+                        "description": "User not found",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Failed to delete file",
+                        "description": "Failed to unlock user",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
@@ -1303,55 +1467,52 @@ const docTemplate = `{
                 }
             }
         },
-        "/workspaces/{workspace_name}/git/commit": {
+        "/admin/users/{userId}/unsuspend": {
             "post": {
                 "security": [
                     {
                         "CookieAuth": []
                     }
                 ],
-                "description": "Stages, commits, and pushes changes to the remote repository",
+                "description": "Lifts the suspension of a specific user as an admin, so they can log in again",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "git"
+                    "Admin"
                 ],
-                "summary": "Stage, commit, and push changes",
-                "operationId": "stageCommitAndPush",
+                "summary": "Unsuspend a user",
+                "operationId": "adminUnsuspendUser",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Workspace name",
-                        "name": "workspace_name",
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "userId",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "Commit request",
-                        "name": "body",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handlers.CommitRequest"
-                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.CommitResponse"
+                            "$ref": "#/definitions/models.User"
                         }
                     },
                     "400": {
-                        "description": "Commit message is required",
+                        "description": "Invalid user ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Failed to stage, commit, and push changes",
+                        "description": "Failed to unsuspend user",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
@@ -1359,27 +1520,62 @@ const docTemplate = `{
                 }
             }
         },
-        "/workspaces/{workspace_name}/git/pull": {
-            "post": {
+        "/admin/workspaces": {
+            "get": {
                 "security": [
                     {
                         "CookieAuth": []
                     }
                 ],
-                "description": "Pulls changes from the remote repository",
+                "description": "List all workspaces and their stats as an admin",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "git"
+                    "Admin"
                 ],
-                "summary": "Pull changes from remote",
-                "operationId": "pullChanges",
+                "summary": "List all workspaces",
+                "operationId": "adminListWorkspaces",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.WorkspaceStats"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to get file stats",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/workspaces/{workspaceId}/quota": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Gets the storage used by a workspace, including its Git repository, trash and revisions, and the quota set for it",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Get a workspace's storage quota",
+                "operationId": "adminGetWorkspaceQuota",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Workspace name",
-                        "name": "workspace_name",
+                        "type": "integer",
+                        "description": "Workspace ID",
+                        "name": "workspaceId",
                         "in": "path",
                         "required": true
                     }
@@ -1388,47 +1584,3134 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/handlers.PullResponse"
+                            "$ref": "#/definitions/handlers.WorkspaceStorageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid workspace ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Workspace not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     },
                     "500": {
-                        "description": "Failed to pull changes",
+                        "description": "Failed to get storage usage",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
                     }
                 }
-            }
-        }
-    },
-    "definitions": {
-        "handlers.CommitRequest": {
-            "type": "object",
-            "properties": {
-                "message": {
-                    "type": "string",
-                    "example": "Initial commit"
-                }
-            }
-        },
-        "handlers.CommitResponse": {
-            "type": "object",
-            "properties": {
-                "commitHash": {
-                    "type": "string",
-                    "example": "a1b2c3d4"
-                }
-            }
-        },
-        "handlers.CreateUserRequest": {
-            "type": "object",
-            "properties": {
-                "displayName": {
-                    "type": "string"
-                },
+            },
+            "put": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Limits the storage used by a workspace, in addition to the quota of its owner. Zero limits are unlimited.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Update a workspace's storage quota",
+                "operationId": "adminUpdateWorkspaceQuota",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Workspace ID",
+                        "name": "workspaceId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Storage quota",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.StorageQuota"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.WorkspaceStorageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Quota limits must not be negative",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Workspace not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to get storage usage",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Removes the storage quota of a workspace, so only the quota of its owner applies",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Remove a workspace's storage quota",
+                "operationId": "adminDeleteWorkspaceQuota",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Workspace ID",
+                        "name": "workspaceId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.WorkspaceStorageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid workspace ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Workspace not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to get storage usage",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/workspaces/{workspaceId}/transfer": {
+            "post": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Makes another user the owner of a workspace as an admin. Its files and Git repository are moved to the new owner.\nGit is disabled and the Git credentials of the previous owner are removed.\nIf the new owner or a remaining member already has access to a workspace with the same name, a number is appended to the name.\nA previous owner that is left without a workspace gets a new default workspace.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "Transfer a workspace",
+                "operationId": "adminTransferWorkspace",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Workspace ID",
+                        "name": "workspaceId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New owner",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.TransferWorkspaceRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Workspace"
+                        }
+                    },
+                    "400": {
+                        "description": "Cannot transfer to a suspended user",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to transfer workspace",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "507": {
+                        "description": "Storage quota exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/forgot-password": {
+            "post": {
+                "description": "Sends a single-use password reset link to the email address if it belongs to a user.\nThe response is the same whether or not the user exists.",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Request password reset",
+                "operationId": "forgotPassword",
+                "parameters": [
+                    {
+                        "description": "Forgot password request",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ForgotPasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content - Reset link sent if the user exists"
+                    },
+                    "400": {
+                        "description": "Email is required",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/jwks": {
+            "get": {
+                "description": "Returns the public keys used to sign tokens, allowing other services to verify them. Only keys with asymmetric algorithms are included.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Get JSON Web Key Set",
+                "operationId": "getJWKS",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/auth.JWKSet"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to get public keys",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/login": {
+            "post": {
+                "description": "Logs in a user and returns a session with access and refresh tokens.\nIf LDAP is configured, directory users are authenticated against the directory.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Login",
+                "parameters": [
+                    {
+                        "description": "Login request",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.LoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.LoginResponse"
+                        },
+                        "headers": {
+                            "X-CSRF-Token": {
+                                "type": "string",
+                                "description": "CSRF token for future requests"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Email and password are required",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid credentials",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Email address not verified",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many failed login attempts",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        },
+                        "headers": {
+                            "Retry-After": {
+                                "type": "string",
+                                "description": "Seconds until the next login attempt is allowed"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to generate CSRF token",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/logout": {
+            "post": {
+                "description": "Log out invalidates the user's session",
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Logout",
+                "operationId": "logout",
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Session ID required",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to logout",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/me": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Returns the current authenticated user",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Get current user",
+                "operationId": "getCurrentUser",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/refresh": {
+            "post": {
+                "description": "Refreshes the access token using the refresh token. The refresh token is rotated on every use; reusing an already rotated refresh token revokes the session, unless it was rotated in the last few seconds, in which case the current refresh token is returned.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Refresh token",
+                "operationId": "refreshToken",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "headers": {
+                            "X-CSRF-Token": {
+                                "type": "string",
+                                "description": "New CSRF token"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Refresh token required",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid refresh token",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to generate CSRF token",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/reset-password": {
+            "post": {
+                "description": "Sets a new password using a token from a password reset email. All sessions of the user are revoked and a login lockout of the account is lifted.",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Reset password",
+                "operationId": "resetPassword",
+                "parameters": [
+                    {
+                        "description": "Reset password request",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ResetPasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content - Password reset successfully"
+                    },
+                    "400": {
+                        "description": "Invalid or expired token",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to revoke sessions",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/sessions": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Returns the active sessions of the current user",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "List sessions",
+                "operationId": "listSessions",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/handlers.SessionResponse"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to list sessions",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Revokes all sessions of the current user except the one used to make the request",
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Revoke other sessions",
+                "operationId": "revokeOtherSessions",
+                "responses": {
+                    "204": {
+                        "description": "No Content - Sessions revoked successfully"
+                    },
+                    "500": {
+                        "description": "Failed to revoke sessions",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/sessions/{sessionId}": {
+            "delete": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Revokes one of the current user's sessions. Revoking the current session logs the user out.",
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Revoke session",
+                "operationId": "revokeSession",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "sessionId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content - Session revoked successfully"
+                    },
+                    "404": {
+                        "description": "Session not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to revoke session",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/signup": {
+            "post": {
+                "description": "Creates a new user with an invitation token, or with the default role if registration is open.\nIf email verification is required, users signing up without an invitation are sent a link to confirm\ntheir email address with /auth/verify-email and can't log in before.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Sign up",
+                "operationId": "signup",
+                "parameters": [
+                    {
+                        "description": "Signup request",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.SignupRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid or expired invitation",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Registration requires an invitation",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Email already exists",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to send verification email",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/verify-email": {
+            "post": {
+                "description": "Changes the user's email to the address confirmed by a token from a verification email. All sessions of the user are revoked.\nUsers who signed up and have to confirm their email address can log in afterwards.",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Verify email change",
+                "operationId": "verifyEmail",
+                "parameters": [
+                    {
+                        "description": "Verify email request",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.VerifyEmailRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content - Email changed successfully"
+                    },
+                    "400": {
+                        "description": "Invalid or expired token",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Email already in use",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to revoke sessions",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/profile": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Returns the user's profile along with the storage used by their workspaces and their quota",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get profile",
+                "operationId": "getProfile",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserStorageResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to get storage usage",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Updates the user's profile. Changing the email or password revokes all other sessions of the user.\nIf email verification is enabled, a new email is only applied once it's confirmed through a link sent to the new address.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Update profile",
+                "operationId": "updateProfile",
+                "parameters": [
+                    {
+                        "description": "Profile update request",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UpdateProfileRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.User"
+                        }
+                    },
+                    "400": {
+                        "description": "Current password is required to change email",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Current password is incorrect",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Email already in use",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to send verification email",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Deletes the user's account. The account and its workspaces are moved to the trash and permanently deleted after the trash retention period.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Delete account",
+                "operationId": "deleteAccount",
+                "parameters": [
+                    {
+                        "description": "Account deletion request",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.DeleteAccountRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content - Account deleted successfully"
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Password is incorrect",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Cannot delete the last admin account",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to delete account",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/profile/export": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Downloads a zip archive of the user's profile and the files and settings of the workspaces they own.\nGit tokens are not exported. The archive is streamed while it is created, so errors after the download started abort it.",
+                "produces": [
+                    "application/zip"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Export profile",
+                "operationId": "exportProfile",
+                "responses": {
+                    "200": {
+                        "description": "Profile export",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to list workspaces",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/shares/{token}/{file_path}": {
+            "get": {
+                "description": "Serves the file of a share link, or a file within its folder given by the path after the token.\nMarkdown is rendered to an HTML page unless the raw format is requested.\nThe root of a folder link lists its files, as HTML page or as JSON file tree in the raw format.\nThe password of a protected link is sent in the X-Share-Password header or with basic auth.",
+                "produces": [
+                    "text/html",
+                    "text/plain",
+                    "application/json"
+                ],
+                "tags": [
+                    "shares"
+                ],
+                "summary": "Open share link",
+                "operationId": "getSharedContent",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Share link token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "File path within a shared folder",
+                        "name": "file_path",
+                        "in": "path"
+                    },
+                    {
+                        "enum": [
+                            "html",
+                            "raw"
+                        ],
+                        "type": "string",
+                        "description": "Response format",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Rendered or raw file content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or missing password",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "File not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too many failed password attempts",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to render file",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/workspaces": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Lists all workspaces the current user owns or is a member of, owned workspaces first.\nGit tokens of shared workspaces are omitted.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "workspaces"
+                ],
+                "summary": "List workspaces",
+                "operationId": "listWorkspaces",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.Workspace"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to list workspaces",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Creates a new workspace",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "workspaces"
+                ],
+                "summary": "Create workspace",
+                "operationId": "createWorkspace",
+                "parameters": [
+                    {
+                        "description": "Workspace",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Workspace"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Workspace"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid workspace",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "A workspace with this name already exists",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to setup git repo",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "507": {
+                        "description": "Storage quota exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/workspaces/last": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Returns the name of the last opened workspace",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "workspaces"
+                ],
+                "summary": "Get last workspace name",
+                "operationId": "getLastWorkspaceName",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.LastWorkspaceNameResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to get last workspace",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Updates the name of the last opened workspace",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "workspaces"
+                ],
+                "summary": "Update last workspace name",
+                "operationId": "updateLastWorkspaceName",
+                "responses": {
+                    "204": {
+                        "description": "No Content - Last workspace updated successfully"
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to update last workspace",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/workspaces/{workspace_name}": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Returns the current workspace. The Git token is omitted if the workspace is shared with the user.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "workspaces"
+                ],
+                "summary": "Get workspace",
+                "operationId": "getWorkspace",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Workspace name",
+                        "name": "workspace_name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Workspace"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal server error",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Updates the current workspace",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "workspaces"
+                ],
+                "summary": "Update workspace",
+                "operationId": "updateWorkspace",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Workspace name",
+                        "name": "workspace_name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Workspace",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Workspace"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.Workspace"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "A member already has a workspace with this name",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to setup git repo",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "507": {
+                        "description": "Storage quota exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Moves the current workspace to the trash. It is permanently deleted with its files after the trash retention period.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "workspaces"
+                ],
+                "summary": "Delete workspace",
+                "operationId": "deleteWorkspace",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Workspace name",
+                        "name": "workspace_name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.DeleteWorkspaceResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Cannot delete the last workspace",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to commit transaction",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/workspaces/{workspace_name}/files": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Lists all files in the user's workspace",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "List files",
+                "operationId": "listFiles",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Workspace name",
+                        "name": "workspace_name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/storage.FileNode"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to list files",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/workspaces/{workspace_name}/files/last": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Returns the path of the last opened file in the user's workspace",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Get last opened file",
+                "operationId": "getLastOpenedFile",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Workspace name",
+                        "name": "workspace_name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.LastOpenedFileResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid file path",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to get last opened file",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Updates the last opened file in the user's workspace",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Update last opened file",
+                "operationId": "updateLastOpenedFile",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Workspace name",
+                        "name": "workspace_name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Update last opened file request",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UpdateLastOpenedFileRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content - Last opened file updated successfully"
+                    },
+                    "400": {
+                        "description": "Invalid file path",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "File not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to update file",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/workspaces/{workspace_name}/files/lookup": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Returns the paths of files with the given name in the user's workspace",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Lookup file by name",
+                "operationId": "lookupFileByName",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Workspace name",
+                        "name": "workspace_name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "File name",
+                        "name": "filename",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.LookupResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Filename is required",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "File not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/workspaces/{workspace_name}/files/{file_path}": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Returns the content of a file in the user's workspace",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Get file content",
+                "operationId": "getFileContent",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Workspace name",
+                        "name": "workspace_name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "File path",
+                        "name": "file_path",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Raw file content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid file path",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "File not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to write response",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Saves the content of a file in the user's workspace",
+                "consumes": [
+                    "text/plain"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Save file",
+                "operationId": "saveFile",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Workspace name",
+                        "name": "workspace_name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "File path",
+                        "name": "file_path",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.SaveFileResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid file path",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to save file",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "507": {
+                        "description": "Storage quota exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Moves a file in the user's workspace to the trash of the workspace, where it can be restored until it expires",
+                "tags": [
+                    "files"
+                ],
+                "summary": "Delete file",
+                "operationId": "deleteFile",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Workspace name",
+                        "name": "workspace_name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "File path",
+                        "name": "file_path",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content - File deleted successfully"
+                    },
+                    "400": {
+                        "description": "Invalid file path",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "File not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to delete file",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/workspaces/{workspace_name}/git/commit": {
+            "post": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Stages, commits, and pushes changes to the remote repository",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "git"
+                ],
+                "summary": "Stage, commit, and push changes",
+                "operationId": "stageCommitAndPush",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Workspace name",
+                        "name": "workspace_name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Commit request",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CommitRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CommitResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Commit message is required",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to stage, commit, and push changes",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/workspaces/{workspace_name}/git/pull": {
+            "post": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Pulls changes from the remote repository. The pull is stopped if the workspace outgrows its storage quota.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "git"
+                ],
+                "summary": "Pull changes from remote",
+                "operationId": "pullChanges",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Workspace name",
+                        "name": "workspace_name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.PullResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to pull changes",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "507": {
+                        "description": "Storage quota exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/workspaces/{workspace_name}/members": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Lists the users with access to the current workspace, the owner first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "workspaces"
+                ],
+                "summary": "List workspace members",
+                "operationId": "listWorkspaceMembers",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Workspace name",
+                        "name": "workspace_name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.WorkspaceMember"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to list workspace members",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Shares the current workspace with a user as editor or viewer",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "workspaces"
+                ],
+                "summary": "Add workspace member",
+                "operationId": "addWorkspaceMember",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Workspace name",
+                        "name": "workspace_name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Add workspace member request",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.AddWorkspaceMemberRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.WorkspaceMember"
+                        }
+                    },
+                    "400": {
+                        "description": "Role must be editor or viewer",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "User not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "User already has a workspace with this name",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to add workspace member",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/workspaces/{workspace_name}/members/{userId}": {
+            "put": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Changes the role of a member of the current workspace",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "workspaces"
+                ],
+                "summary": "Update workspace member",
+                "operationId": "updateWorkspaceMember",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Workspace name",
+                        "name": "workspace_name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "User ID of the member",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Update workspace member request",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UpdateWorkspaceMemberRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/models.WorkspaceMember"
+                        }
+                    },
+                    "400": {
+                        "description": "Cannot change the role of the workspace owner",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Workspace member not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to update workspace member",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Removes a user's access to the current workspace",
+                "tags": [
+                    "workspaces"
+                ],
+                "summary": "Remove workspace member",
+                "operationId": "removeWorkspaceMember",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Workspace name",
+                        "name": "workspace_name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "User ID of the member",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content - Member removed successfully"
+                    },
+                    "400": {
+                        "description": "Cannot remove the workspace owner",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Workspace member not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to remove workspace member",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/workspaces/{workspace_name}/revisions": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Returns the previous versions of a file, most recent first.\nRevisions are only kept in workspaces without a Git repository.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "List file revisions",
+                "operationId": "listFileRevisions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Workspace name",
+                        "name": "workspace_name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "File path",
+                        "name": "path",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/storage.FileRevision"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid file path",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to list revisions",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/workspaces/{workspace_name}/revisions/diff": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Returns the line-based difference between two versions of a file.\nIf to is omitted, the revision is compared to the current content of the file.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Diff file revisions",
+                "operationId": "diffFileRevisions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Workspace name",
+                        "name": "workspace_name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "File path",
+                        "name": "path",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Revision ID to compare from",
+                        "name": "from",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Revision ID to compare to",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.FileDiffResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid file path",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "File not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to diff revisions",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/workspaces/{workspace_name}/revisions/{revision_id}": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Returns the content of a previous version of a file",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Get file revision",
+                "operationId": "getFileRevision",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Workspace name",
+                        "name": "workspace_name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Revision ID",
+                        "name": "revision_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "File path",
+                        "name": "path",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Raw file content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid file path",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Revision not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to read revision",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/workspaces/{workspace_name}/revisions/{revision_id}/restore": {
+            "post": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Replaces the content of a file with a previous version.\nThe replaced content is kept as a new revision, so restoring can be undone.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Restore file revision",
+                "operationId": "restoreFileRevision",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Workspace name",
+                        "name": "workspace_name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Revision ID",
+                        "name": "revision_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "File path",
+                        "name": "path",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/storage.FileRevision"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid file path",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Revision not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to restore revision",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "507": {
+                        "description": "Storage quota exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/workspaces/{workspace_name}/shares": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Lists the share links of the current workspace, newest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "workspaces"
+                ],
+                "summary": "List share links",
+                "operationId": "listShareLinks",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Workspace name",
+                        "name": "workspace_name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.ShareLink"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to list share links",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Creates a public read-only link to a file or folder of the current workspace. The token is only returned in this response.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "workspaces"
+                ],
+                "summary": "Create share link",
+                "operationId": "createShareLink",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Workspace name",
+                        "name": "workspace_name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Share link details",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreateShareLinkRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreateShareLinkResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Expiry must be in the future",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "File not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to create share link",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/workspaces/{workspace_name}/shares/{shareId}": {
+            "delete": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Revokes a share link of the current workspace so it can no longer be opened",
+                "tags": [
+                    "workspaces"
+                ],
+                "summary": "Revoke share link",
+                "operationId": "deleteShareLink",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Workspace name",
+                        "name": "workspace_name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Share link ID",
+                        "name": "shareId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content - Share link revoked successfully"
+                    },
+                    "400": {
+                        "description": "Invalid share link ID",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Share link not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/workspaces/{workspace_name}/trash": {
+            "get": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Returns the deleted files and folders of the current workspace, most recently deleted first.\nThey are permanently deleted after the trash retention period.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "List trash",
+                "operationId": "listTrash",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Workspace name",
+                        "name": "workspace_name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/storage.TrashItem"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to list trash",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Permanently deletes all files and folders in the trash of the current workspace",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Empty trash",
+                "operationId": "emptyTrash",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Workspace name",
+                        "name": "workspace_name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.EmptyTrashResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to empty trash",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/workspaces/{workspace_name}/trash/{item_id}/restore": {
+            "post": {
+                "security": [
+                    {
+                        "CookieAuth": []
+                    }
+                ],
+                "description": "Moves a deleted file or folder from the trash back to its original path",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "files"
+                ],
+                "summary": "Restore trash item",
+                "operationId": "restoreTrashItem",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Workspace name",
+                        "name": "workspace_name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Trash item ID",
+                        "name": "item_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/storage.TrashItem"
+                        }
+                    },
+                    "404": {
+                        "description": "Trash item not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "A file already exists at the original path",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to restore trash item",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "507": {
+                        "description": "Storage quota exceeded",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "auth.JWK": {
+            "type": "object",
+            "properties": {
+                "alg": {
+                    "type": "string"
+                },
+                "crv": {
+                    "description": "Curve of OKP keys",
+                    "type": "string"
+                },
+                "e": {
+                    "description": "RSA exponent",
+                    "type": "string"
+                },
+                "kid": {
+                    "type": "string"
+                },
+                "kty": {
+                    "type": "string"
+                },
+                "n": {
+                    "description": "RSA modulus",
+                    "type": "string"
+                },
+                "use": {
+                    "type": "string"
+                },
+                "x": {
+                    "description": "Ed25519 public key",
+                    "type": "string"
+                }
+            }
+        },
+        "auth.JWKSet": {
+            "type": "object",
+            "properties": {
+                "keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/auth.JWK"
+                    }
+                }
+            }
+        },
+        "handlers.AddWorkspaceMemberRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "role": {
+                    "$ref": "#/definitions/models.WorkspaceRole"
+                }
+            }
+        },
+        "handlers.AuditEventsResponse": {
+            "type": "object",
+            "properties": {
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.AuditEvent"
+                    }
+                },
+                "total": {
+                    "description": "Number of events matching the filter",
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.CommitRequest": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "example": "Initial commit"
+                }
+            }
+        },
+        "handlers.CommitResponse": {
+            "type": "object",
+            "properties": {
+                "commitHash": {
+                    "type": "string",
+                    "example": "a1b2c3d4"
+                }
+            }
+        },
+        "handlers.CreateInvitationRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "description": "Restricts the invitation to this email if set",
+                    "type": "string"
+                },
+                "expiresAt": {
+                    "description": "Defaults to 7 days from now",
+                    "type": "string"
+                },
+                "maxUses": {
+                    "description": "Defaults to 1",
+                    "type": "integer"
+                },
+                "role": {
+                    "$ref": "#/definitions/models.UserRole"
+                },
+                "workspaceName": {
+                    "description": "Name of the user's initial workspace, Main if empty",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.CreateInvitationResponse": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "createdBy": {
+                    "type": "integer"
+                },
+                "email": {
+                    "description": "Restricts the invitation to this email if set",
+                    "type": "string"
+                },
+                "expiresAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "link": {
+                    "type": "string"
+                },
+                "maxUses": {
+                    "type": "integer"
+                },
+                "role": {
+                    "$ref": "#/definitions/models.UserRole"
+                },
+                "token": {
+                    "type": "string"
+                },
+                "useCount": {
+                    "type": "integer"
+                },
+                "workspaceName": {
+                    "description": "Name of the user's initial workspace, Main if empty",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.CreateShareLinkRequest": {
+            "type": "object",
+            "properties": {
+                "expiresAt": {
+                    "description": "The link doesn't expire if not set",
+                    "type": "string"
+                },
+                "password": {
+                    "description": "Required to open the link if set",
+                    "type": "string"
+                },
+                "path": {
+                    "description": "File or folder to share",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.CreateShareLinkResponse": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "createdBy": {
+                    "type": "integer"
+                },
+                "expiresAt": {
+                    "description": "The link doesn't expire if not set",
+                    "type": "string"
+                },
+                "hasPassword": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "isFolder": {
+                    "type": "boolean"
+                },
+                "link": {
+                    "type": "string"
+                },
+                "path": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                },
+                "workspaceId": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.CreateUserRequest": {
+            "type": "object",
+            "properties": {
+                "displayName": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "mustChangePassword": {
+                    "description": "Require a password change on the first login",
+                    "type": "boolean"
+                },
+                "password": {
+                    "type": "string"
+                },
+                "role": {
+                    "$ref": "#/definitions/models.UserRole"
+                }
+            }
+        },
+        "handlers.DeleteAccountRequest": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.DeleteWorkspaceResponse": {
+            "type": "object",
+            "properties": {
+                "nextWorkspaceName": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.EmptyTrashResponse": {
+            "type": "object",
+            "properties": {
+                "deleted": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.FileDiffResponse": {
+            "type": "object",
+            "properties": {
+                "chunks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/storage.DiffChunk"
+                    }
+                },
+                "from": {
+                    "type": "integer"
+                },
+                "path": {
+                    "type": "string"
+                },
+                "to": {
+                    "description": "0 is the current content of the file",
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.ForgotPasswordRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.LastOpenedFileResponse": {
+            "type": "object",
+            "properties": {
+                "lastOpenedFilePath": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.LastWorkspaceNameResponse": {
+            "type": "object",
+            "properties": {
+                "lastWorkspaceName": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.LoginRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.LoginResponse": {
+            "type": "object",
+            "properties": {
+                "expiresAt": {
+                    "type": "string"
+                },
+                "sessionId": {
+                    "type": "string"
+                },
+                "user": {
+                    "$ref": "#/definitions/models.User"
+                }
+            }
+        },
+        "handlers.LookupResponse": {
+            "type": "object",
+            "properties": {
+                "paths": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "handlers.PullResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "example": "Pulled changes from remote"
+                }
+            }
+        },
+        "handlers.ResetPasswordRequest": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.RotateJWTKeyRequest": {
+            "type": "object",
+            "properties": {
+                "algorithm": {
+                    "description": "HS256, RS256 or EdDSA; the configured algorithm if empty",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.SaveFileResponse": {
+            "type": "object",
+            "properties": {
+                "filePath": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.SessionResponse": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "description": "When this session was created",
+                    "type": "string"
+                },
+                "current": {
+                    "description": "Whether this is the session used to make the request",
+                    "type": "boolean"
+                },
+                "expiresAt": {
+                    "description": "When this session expires",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "Unique session identifier",
+                    "type": "string"
+                },
+                "ipAddress": {
+                    "description": "IP address of the client that created the session",
+                    "type": "string"
+                },
+                "lastUsedAt": {
+                    "description": "When this session was last used to authenticate a request",
+                    "type": "string"
+                },
+                "userAgent": {
+                    "description": "User agent of the client that created the session",
+                    "type": "string"
+                },
+                "userId": {
+                    "description": "ID of the user this session belongs to",
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.SignupRequest": {
+            "type": "object",
+            "properties": {
+                "displayName": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "invitationToken": {
+                    "description": "Required unless registration is open",
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.StorageUsageResponse": {
+            "type": "object",
+            "properties": {
+                "defaultQuota": {
+                    "description": "Whether the system default quota applies",
+                    "type": "boolean"
+                },
+                "quota": {
+                    "description": "Zero limits are unlimited",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.StorageQuota"
+                        }
+                    ]
+                },
+                "usedBytes": {
+                    "type": "integer"
+                },
+                "usedFiles": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.SystemStats": {
+            "type": "object",
+            "properties": {
+                "activeUsers": {
+                    "description": "Users with activity in last 30 days",
+                    "type": "integer"
+                },
+                "totalFiles": {
+                    "type": "integer"
+                },
+                "totalSize": {
+                    "type": "integer"
+                },
+                "totalUsers": {
+                    "type": "integer"
+                },
+                "totalWorkspaces": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.TransferWorkspaceRequest": {
+            "type": "object",
+            "properties": {
+                "previousOwnerRole": {
+                    "description": "Role of the previous owner, who loses access if it is empty",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.WorkspaceRole"
+                        }
+                    ]
+                },
+                "userId": {
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.UpdateLastOpenedFileRequest": {
+            "type": "object",
+            "properties": {
+                "filePath": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.UpdateProfileRequest": {
+            "type": "object",
+            "properties": {
+                "currentPassword": {
+                    "type": "string"
+                },
+                "displayName": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "newPassword": {
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.UpdateUserRequest": {
+            "type": "object",
+            "properties": {
+                "displayName": {
+                    "type": "string"
+                },
                 "email": {
                     "type": "string"
                 },
+                "mustChangePassword": {
+                    "description": "Require a password change on the next login",
+                    "type": "boolean"
+                },
                 "password": {
                     "type": "string"
                 },
@@ -1437,191 +4720,424 @@ const docTemplate = `{
                 }
             }
         },
-        "handlers.DeleteAccountRequest": {
+        "handlers.UpdateWorkspaceMemberRequest": {
             "type": "object",
             "properties": {
-                "password": {
+                "role": {
+                    "$ref": "#/definitions/models.WorkspaceRole"
+                }
+            }
+        },
+        "handlers.UserExportRow": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "displayName": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "mustChangePassword": {
+                    "type": "boolean"
+                },
+                "role": {
+                    "$ref": "#/definitions/models.UserRole"
+                },
+                "suspendedAt": {
+                    "type": "string"
+                },
+                "workspace": {
+                    "description": "Name of the user's initial workspace, Main if empty",
                     "type": "string"
                 }
             }
         },
-        "handlers.DeleteWorkspaceResponse": {
+        "handlers.UserImportResponse": {
             "type": "object",
             "properties": {
-                "nextWorkspaceName": {
+                "credentials": {
                     "type": "string"
+                },
+                "dryRun": {
+                    "type": "boolean"
+                },
+                "failed": {
+                    "type": "integer"
+                },
+                "rows": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.UserImportResult"
+                    }
+                },
+                "valid": {
+                    "description": "Rows that were imported, or would be without a dry run",
+                    "type": "integer"
                 }
             }
         },
-        "handlers.ErrorResponse": {
+        "handlers.UserImportResult": {
             "type": "object",
             "properties": {
-                "message": {
+                "email": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "invitationId": {
+                    "type": "integer"
+                },
+                "invitationLink": {
+                    "type": "string"
+                },
+                "row": {
+                    "description": "Starts at 1, not counting the CSV header",
+                    "type": "integer"
+                },
+                "temporaryPassword": {
                     "type": "string"
+                },
+                "userId": {
+                    "type": "integer"
                 }
             }
         },
-        "handlers.LastOpenedFileResponse": {
+        "handlers.UserImportRow": {
             "type": "object",
             "properties": {
-                "lastOpenedFilePath": {
+                "displayName": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "role": {
+                    "$ref": "#/definitions/models.UserRole"
+                },
+                "workspace": {
+                    "description": "Name of the user's initial workspace, Main if empty",
                     "type": "string"
                 }
             }
         },
-        "handlers.LastWorkspaceNameResponse": {
+        "handlers.UserStorageResponse": {
             "type": "object",
+            "required": [
+                "email",
+                "id",
+                "role"
+            ],
             "properties": {
-                "lastWorkspaceName": {
+                "authSource": {
+                    "description": "AuthSource is where the user authenticates, local when empty",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.AuthSource"
+                        }
+                    ]
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "deletedAt": {
+                    "description": "DeletedAt is only set for users in the trash",
+                    "type": "string"
+                },
+                "displayName": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "emailVerificationPending": {
+                    "description": "EmailVerificationPending blocks logging in until the user confirms their email address",
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "lastWorkspaceId": {
+                    "type": "integer"
+                },
+                "mustChangePassword": {
+                    "description": "MustChangePassword blocks everything but changing the password until the user does so",
+                    "type": "boolean"
+                },
+                "role": {
+                    "enum": [
+                        "admin",
+                        "editor",
+                        "viewer"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.UserRole"
+                        }
+                    ]
+                },
+                "storage": {
+                    "$ref": "#/definitions/handlers.StorageUsageResponse"
+                },
+                "suspendedAt": {
+                    "description": "SuspendedAt is only set for suspended users, who can't log in",
                     "type": "string"
                 }
             }
         },
-        "handlers.LoginRequest": {
+        "handlers.VerifyEmailRequest": {
             "type": "object",
             "properties": {
-                "email": {
-                    "type": "string"
-                },
-                "password": {
+                "token": {
                     "type": "string"
                 }
             }
         },
-        "handlers.LoginResponse": {
+        "handlers.WorkspaceStats": {
             "type": "object",
             "properties": {
-                "expiresAt": {
+                "totalFiles": {
+                    "type": "integer"
+                },
+                "totalSize": {
+                    "type": "integer"
+                },
+                "userEmail": {
                     "type": "string"
                 },
-                "sessionId": {
+                "userID": {
+                    "type": "integer"
+                },
+                "workspaceCreatedAt": {
                     "type": "string"
                 },
-                "user": {
-                    "$ref": "#/definitions/models.User"
-                }
-            }
-        },
-        "handlers.LookupResponse": {
-            "type": "object",
-            "properties": {
-                "paths": {
-                    "type": "array",
-                    "items": {
-                        "type": "string"
-                    }
+                "workspaceID": {
+                    "type": "integer"
+                },
+                "workspaceName": {
+                    "type": "string"
                 }
             }
         },
-        "handlers.PullResponse": {
+        "handlers.WorkspaceStorageResponse": {
             "type": "object",
             "properties": {
-                "message": {
-                    "type": "string",
-                    "example": "Pulled changes from remote"
+                "quota": {
+                    "description": "Only the quota of the owner applies if not set",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.StorageQuota"
+                        }
+                    ]
+                },
+                "usedBytes": {
+                    "type": "integer"
+                },
+                "usedFiles": {
+                    "type": "integer"
+                },
+                "workspaceId": {
+                    "type": "integer"
                 }
             }
         },
-        "handlers.SaveFileResponse": {
+        "models.AuditEvent": {
             "type": "object",
             "properties": {
-                "filePath": {
+                "actorId": {
+                    "description": "Not set for unauthenticated requests",
+                    "type": "integer"
+                },
+                "createdAt": {
                     "type": "string"
                 },
-                "size": {
+                "details": {
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "event": {
+                    "type": "string"
+                },
+                "id": {
                     "type": "integer"
                 },
-                "updatedAt": {
+                "ipAddress": {
+                    "type": "string"
+                },
+                "requestId": {
+                    "type": "string"
+                },
+                "targetId": {
                     "type": "string"
+                },
+                "targetType": {
+                    "$ref": "#/definitions/models.AuditTargetType"
+                },
+                "workspaceId": {
+                    "type": "integer"
                 }
             }
         },
-        "handlers.SystemStats": {
+        "models.AuditTargetType": {
+            "type": "string",
+            "enum": [
+                "user",
+                "workspace",
+                "session",
+                "file",
+                "invitation",
+                "share_link",
+                "system"
+            ],
+            "x-enum-varnames": [
+                "AuditTargetUser",
+                "AuditTargetWorkspace",
+                "AuditTargetSession",
+                "AuditTargetFile",
+                "AuditTargetInvitation",
+                "AuditTargetShareLink",
+                "AuditTargetSystem"
+            ]
+        },
+        "models.AuthSource": {
+            "type": "string",
+            "enum": [
+                "local",
+                "ldap"
+            ],
+            "x-enum-varnames": [
+                "AuthSourceLocal",
+                "AuthSourceLDAP"
+            ]
+        },
+        "models.Invitation": {
             "type": "object",
             "properties": {
-                "activeUsers": {
-                    "description": "Users with activity in last 30 days",
-                    "type": "integer"
+                "createdAt": {
+                    "type": "string"
                 },
-                "totalFiles": {
+                "createdBy": {
                     "type": "integer"
                 },
-                "totalSize": {
+                "email": {
+                    "description": "Restricts the invitation to this email if set",
+                    "type": "string"
+                },
+                "expiresAt": {
+                    "type": "string"
+                },
+                "id": {
                     "type": "integer"
                 },
-                "totalUsers": {
+                "maxUses": {
                     "type": "integer"
                 },
-                "totalWorkspaces": {
+                "role": {
+                    "$ref": "#/definitions/models.UserRole"
+                },
+                "useCount": {
                     "type": "integer"
-                }
-            }
-        },
-        "handlers.UpdateLastOpenedFileRequest": {
-            "type": "object",
-            "properties": {
-                "filePath": {
+                },
+                "workspaceName": {
+                    "description": "Name of the user's initial workspace, Main if empty",
                     "type": "string"
                 }
             }
         },
-        "handlers.UpdateProfileRequest": {
+        "models.JWTKey": {
             "type": "object",
             "properties": {
-                "currentPassword": {
+                "active": {
+                    "description": "Whether new tokens are signed with this key",
+                    "type": "boolean"
+                },
+                "algorithm": {
+                    "description": "Signing algorithm (HS256, RS256 or EdDSA)",
                     "type": "string"
                 },
-                "displayName": {
+                "createdAt": {
+                    "description": "When the key was created",
                     "type": "string"
                 },
-                "email": {
+                "id": {
+                    "description": "Key identifier, used as the kid header of tokens",
                     "type": "string"
                 },
-                "newPassword": {
+                "publicKey": {
+                    "description": "PEM encoded public key for asymmetric algorithms",
+                    "type": "string"
+                },
+                "retiredAt": {
+                    "description": "When the key stopped signing new tokens",
                     "type": "string"
                 }
             }
         },
-        "handlers.UpdateUserRequest": {
+        "models.RegistrationSettings": {
             "type": "object",
             "properties": {
-                "displayName": {
-                    "type": "string"
-                },
-                "email": {
-                    "type": "string"
+                "defaultRole": {
+                    "description": "Role of users signing up without an invitation",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.UserRole"
+                        }
+                    ]
                 },
-                "password": {
-                    "type": "string"
+                "openRegistration": {
+                    "type": "boolean"
                 },
-                "role": {
-                    "$ref": "#/definitions/models.UserRole"
+                "requireEmailVerification": {
+                    "description": "RequireEmailVerification keeps users signing up without an invitation from logging in\nuntil they confirm their email address",
+                    "type": "boolean"
                 }
             }
         },
-        "handlers.WorkspaceStats": {
+        "models.ShareLink": {
             "type": "object",
             "properties": {
-                "totalFiles": {
-                    "type": "integer"
+                "createdAt": {
+                    "type": "string"
                 },
-                "totalSize": {
+                "createdBy": {
                     "type": "integer"
                 },
-                "userEmail": {
+                "expiresAt": {
+                    "description": "The link doesn't expire if not set",
                     "type": "string"
                 },
-                "userID": {
+                "hasPassword": {
+                    "type": "boolean"
+                },
+                "id": {
                     "type": "integer"
                 },
-                "workspaceCreatedAt": {
+                "isFolder": {
+                    "type": "boolean"
+                },
+                "path": {
                     "type": "string"
                 },
-                "workspaceID": {
+                "workspaceId": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.StorageQuota": {
+            "type": "object",
+            "properties": {
+                "maxBytes": {
                     "type": "integer"
                 },
-                "workspaceName": {
-                    "type": "string"
+                "maxFiles": {
+                    "type": "integer"
                 }
             }
         },
@@ -1633,15 +5149,31 @@ const docTemplate = `{
                 "role"
             ],
             "properties": {
+                "authSource": {
+                    "description": "AuthSource is where the user authenticates, local when empty",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.AuthSource"
+                        }
+                    ]
+                },
                 "createdAt": {
                     "type": "string"
                 },
+                "deletedAt": {
+                    "description": "DeletedAt is only set for users in the trash",
+                    "type": "string"
+                },
                 "displayName": {
                     "type": "string"
                 },
                 "email": {
                     "type": "string"
                 },
+                "emailVerificationPending": {
+                    "description": "EmailVerificationPending blocks logging in until the user confirms their email address",
+                    "type": "boolean"
+                },
                 "id": {
                     "type": "integer",
                     "minimum": 1
@@ -1649,6 +5181,10 @@ const docTemplate = `{
                 "lastWorkspaceId": {
                     "type": "integer"
                 },
+                "mustChangePassword": {
+                    "description": "MustChangePassword blocks everything but changing the password until the user does so",
+                    "type": "boolean"
+                },
                 "role": {
                     "enum": [
                         "admin",
@@ -1660,6 +5196,10 @@ const docTemplate = `{
                             "$ref": "#/definitions/models.UserRole"
                         }
                     ]
+                },
+                "suspendedAt": {
+                    "description": "SuspendedAt is only set for suspended users, who can't log in",
+                    "type": "string"
                 }
             }
         },
@@ -1690,6 +5230,10 @@ const docTemplate = `{
                 "createdAt": {
                     "type": "string"
                 },
+                "deletedAt": {
+                    "description": "DeletedAt is only set for workspaces in the trash",
+                    "type": "string"
+                },
                 "gitAutoCommit": {
                     "type": "boolean"
                 },
@@ -1724,6 +5268,14 @@ const docTemplate = `{
                 "name": {
                     "type": "string"
                 },
+                "role": {
+                    "description": "Role of the requesting user, only set for workspaces looked up by member",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.WorkspaceRole"
+                        }
+                    ]
+                },
                 "showHiddenFiles": {
                     "type": "boolean"
                 },
@@ -1741,6 +5293,54 @@ const docTemplate = `{
                 }
             }
         },
+        "models.WorkspaceMember": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "displayName": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "role": {
+                    "$ref": "#/definitions/models.WorkspaceRole"
+                },
+                "userId": {
+                    "type": "integer"
+                },
+                "workspaceId": {
+                    "type": "integer"
+                }
+            }
+        },
+        "models.WorkspaceRole": {
+            "type": "string",
+            "enum": [
+                "owner",
+                "editor",
+                "viewer"
+            ],
+            "x-enum-varnames": [
+                "WorkspaceRoleOwner",
+                "WorkspaceRoleEditor",
+                "WorkspaceRoleViewer"
+            ]
+        },
+        "storage.DiffChunk": {
+            "type": "object",
+            "properties": {
+                "op": {
+                    "description": "equal, insert or delete",
+                    "type": "string"
+                },
+                "text": {
+                    "type": "string"
+                }
+            }
+        },
         "storage.FileNode": {
             "type": "object",
             "properties": {
@@ -1760,6 +5360,49 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "storage.FileRevision": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "description": "When the content was replaced by a newer version",
+                    "type": "string"
+                },
+                "hash": {
+                    "description": "SHA-256 of the content",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "size": {
+                    "type": "integer"
+                }
+            }
+        },
+        "storage.TrashItem": {
+            "type": "object",
+            "properties": {
+                "deletedAt": {
+                    "type": "string"
+                },
+                "deletedBy": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "isFolder": {
+                    "type": "boolean"
+                },
+                "path": {
+                    "description": "Original path within the workspace",
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                }
+            }
         }
     },
     "securityDefinitions": {
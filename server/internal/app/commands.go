@@ -0,0 +1,159 @@
+package app
+
+import (
+	"fmt"
+	"os"
+
+	"lemma/internal/backup"
+	"lemma/internal/db"
+	"lemma/internal/models"
+	"lemma/internal/secrets"
+	"lemma/internal/storage"
+)
+
+// RotateJWTKey creates a new active JWT signing key in the database using the given
+// algorithm, or the configured algorithm if empty. A running server picks up the new
+// key within a minute; tokens signed with previous keys stay valid until they expire.
+func RotateJWTKey(cfg *Config, algorithm string) (*models.JWTKey, error) {
+	if cfg.JWTSigningKey != "" {
+		return nil, fmt.Errorf("LEMMA_JWT_SIGNING_KEY is set, unset it to use the rotating keyring")
+	}
+
+	secretsService, err := initSecretsService(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	database, err := initDatabase(cfg, secretsService)
+	if err != nil {
+		return nil, err
+	}
+	defer database.Close()
+
+	keyring, err := initKeyring(cfg, database)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := keyring.Rotate(algorithm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate JWT key: %w", err)
+	}
+
+	return key, nil
+}
+
+// RotateEncryptionKey re-encrypts all encrypted data in the database, e.g. git tokens of
+// workspaces, with LEMMA_ENCRYPTION_KEY. Data encrypted with a previous key can only be
+// decrypted if the key is listed in LEMMA_PREVIOUS_ENCRYPTION_KEYS. It returns the
+// ID of the new key and the number of re-encrypted values.
+func RotateEncryptionKey(cfg *Config) (string, int, error) {
+	keyID, err := secrets.KeyID(cfg.EncryptionKey)
+	if err != nil {
+		return "", 0, err
+	}
+
+	secretsService, err := initSecretsService(cfg)
+	if err != nil {
+		return "", 0, err
+	}
+
+	database, err := initDatabase(cfg, secretsService)
+	if err != nil {
+		return "", 0, err
+	}
+	defer database.Close()
+
+	count, err := database.ReencryptSecrets()
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to re-encrypt secrets: %w", err)
+	}
+
+	return keyID, count, nil
+}
+
+// MigrationStatus returns the state of the database migrations without applying any
+func MigrationStatus(cfg *Config) ([]db.MigrationStatus, error) {
+	database, err := openMigrationDatabase(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer database.Close()
+
+	return database.MigrationStatus()
+}
+
+// MigrateUp applies the pending database migrations up to the given version,
+// or all of them if the version is negative
+func MigrateUp(cfg *Config, version int) error {
+	database, err := openMigrationDatabase(cfg)
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	if version < 0 {
+		return database.Migrate()
+	}
+	return database.MigrateUp(version)
+}
+
+// MigrateDown reverts the database migrations newer than the given version
+func MigrateDown(cfg *Config, version int) error {
+	database, err := openMigrationDatabase(cfg)
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	return database.MigrateDown(version)
+}
+
+func openMigrationDatabase(cfg *Config) (db.Database, error) {
+	secretsService, err := initSecretsService(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return openDatabase(cfg, secretsService)
+}
+
+// Backup writes a backup archive of the database and the data directory to the given path
+func Backup(cfg *Config, path string) (*backup.Manifest, error) {
+	database, err := openMigrationDatabase(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer database.Close()
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create backup file: %w", err)
+	}
+
+	manifest, err := backup.Create(file, database, storage.NewService(cfg.WorkDir), cfg.DBPath)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return nil, fmt.Errorf("failed to create backup: %w", err)
+	}
+
+	return manifest, nil
+}
+
+// Restore replaces the database and the data directory with the contents of the backup
+// archive at the given path. The server must not be running.
+func Restore(cfg *Config, path string) (*backup.Manifest, error) {
+	if cfg.DBURL != "" {
+		return nil, fmt.Errorf("backups can only be restored to SQLite databases, unset LEMMA_DB_URL")
+	}
+
+	manifest, err := backup.Restore(path, cfg.DBPath, cfg.WorkDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore backup: %w", err)
+	}
+
+	return manifest, nil
+}
@@ -2,8 +2,11 @@ package app
 
 import (
 	"fmt"
+	"lemma/internal/auth"
 	"lemma/internal/logging"
 	"lemma/internal/secrets"
+	"net/netip"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
@@ -12,33 +15,73 @@ import (
 
 // Config holds the configuration for the application
 type Config struct {
-	DBPath            string
-	WorkDir           string
-	StaticPath        string
-	Port              string
-	RootURL           string
-	Domain            string
-	CORSOrigins       []string
-	AdminEmail        string
-	AdminPassword     string
-	EncryptionKey     string
-	JWTSigningKey     string
-	RateLimitRequests int
-	RateLimitWindow   time.Duration
-	IsDevelopment     bool
-	LogLevel          logging.LogLevel
+	DBPath                 string
+	DBURL                  string
+	DBMaxOpenConns         int
+	DBMaxIdleConns         int
+	DBConnMaxLifetime      time.Duration
+	WorkDir                string
+	StaticPath             string
+	TrashRetention         time.Duration
+	RevisionsMaxCount      int
+	RevisionsMaxAge        time.Duration
+	Port                   string
+	RootURL                string
+	Domain                 string
+	CORSOrigins            []string
+	TrustedProxies         []netip.Prefix
+	AdminEmail             string
+	AdminPassword          string
+	EncryptionKey          string
+	PreviousEncryptionKeys []string
+	JWTSigningKey          string
+	JWTAlgorithm           string
+	RateLimitRequests      int
+	RateLimitWindow        time.Duration
+	LoginMaxAttempts       int
+	LoginLockoutDuration   time.Duration
+	SMTPHost               string
+	SMTPPort               int
+	SMTPUsername           string
+	SMTPPassword           string
+	MailFrom               string
+	MailDir                string
+	VerifyEmailChanges     bool
+	LDAPURL                string
+	LDAPStartTLS           bool
+	LDAPBindDN             string
+	LDAPBindPassword       string
+	LDAPBaseDN             string
+	LDAPUserFilter         string
+	LDAPAdminGroup         string
+	LDAPEditorGroup        string
+	LDAPViewerGroup        string
+	MetricsToken           string
+	MetricsAddr            string
+	IsDevelopment          bool
+	LogLevel               logging.LogLevel
 }
 
 // DefaultConfig returns a new Config instance with default values
 func DefaultConfig() *Config {
 	return &Config{
-		DBPath:            "./lemma.db",
-		WorkDir:           "./data",
-		StaticPath:        "../app/dist",
-		Port:              "8080",
-		RateLimitRequests: 100,
-		RateLimitWindow:   time.Minute * 15,
-		IsDevelopment:     false,
+		DBPath:               "./lemma.db",
+		DBMaxOpenConns:       10,
+		DBMaxIdleConns:       5,
+		DBConnMaxLifetime:    time.Hour,
+		WorkDir:              "./data",
+		StaticPath:           "../app/dist",
+		TrashRetention:       30 * 24 * time.Hour,
+		RevisionsMaxCount:    50,
+		RevisionsMaxAge:      30 * 24 * time.Hour,
+		Port:                 "8080",
+		JWTAlgorithm:         auth.AlgorithmHS256,
+		RateLimitRequests:    100,
+		RateLimitWindow:      time.Minute * 15,
+		LoginMaxAttempts:     10,
+		LoginLockoutDuration: time.Minute * 15,
+		SMTPPort:             587,
+		IsDevelopment:        false,
 	}
 }
 
@@ -48,10 +91,31 @@ func (c *Config) validate() error {
 		return fmt.Errorf("LEMMA_ADMIN_EMAIL and LEMMA_ADMIN_PASSWORD must be set")
 	}
 
+	if !auth.ValidAlgorithm(c.JWTAlgorithm) {
+		return fmt.Errorf("invalid LEMMA_JWT_ALGORITHM: %s", c.JWTAlgorithm)
+	}
+
+	if c.SMTPHost != "" && c.MailFrom == "" {
+		return fmt.Errorf("LEMMA_MAIL_FROM must be set when LEMMA_SMTP_HOST is set")
+	}
+
+	if c.DBURL != "" && !strings.HasPrefix(c.DBURL, "postgres://") && !strings.HasPrefix(c.DBURL, "postgresql://") {
+		return fmt.Errorf("LEMMA_DB_URL must be a postgres:// URL")
+	}
+
+	if c.LDAPURL != "" && c.LDAPBaseDN == "" {
+		return fmt.Errorf("LEMMA_LDAP_BASE_DN must be set when LEMMA_LDAP_URL is set")
+	}
+
 	// Validate encryption key
 	if err := secrets.ValidateKey(c.EncryptionKey); err != nil {
 		return fmt.Errorf("invalid LEMMA_ENCRYPTION_KEY: %w", err)
 	}
+	for _, key := range c.PreviousEncryptionKeys {
+		if err := secrets.ValidateKey(key); err != nil {
+			return fmt.Errorf("invalid LEMMA_PREVIOUS_ENCRYPTION_KEYS: %w", err)
+		}
+	}
 
 	return nil
 }
@@ -62,7 +126,17 @@ func (c *Config) Redact() *Config {
 	redacted.AdminPassword = "[REDACTED]"
 	redacted.AdminEmail = "[REDACTED]"
 	redacted.EncryptionKey = "[REDACTED]"
+	redacted.PreviousEncryptionKeys = make([]string, len(c.PreviousEncryptionKeys))
+	for i := range redacted.PreviousEncryptionKeys {
+		redacted.PreviousEncryptionKeys[i] = "[REDACTED]"
+	}
 	redacted.JWTSigningKey = "[REDACTED]"
+	redacted.SMTPPassword = "[REDACTED]"
+	redacted.LDAPBindPassword = "[REDACTED]"
+	redacted.MetricsToken = "[REDACTED]"
+	if dbURL, err := url.Parse(c.DBURL); err == nil {
+		redacted.DBURL = dbURL.Redacted()
+	}
 	return &redacted
 }
 
@@ -78,6 +152,32 @@ func LoadConfig() (*Config, error) {
 		config.DBPath = dbPath
 	}
 
+	if dbURL := os.Getenv("LEMMA_DB_URL"); dbURL != "" {
+		config.DBURL = dbURL
+	}
+
+	// Configure the database connection pool
+	if maxOpenStr := os.Getenv("LEMMA_DB_MAX_OPEN_CONNS"); maxOpenStr != "" {
+		parsed, err := strconv.Atoi(maxOpenStr)
+		if err == nil {
+			config.DBMaxOpenConns = parsed
+		}
+	}
+
+	if maxIdleStr := os.Getenv("LEMMA_DB_MAX_IDLE_CONNS"); maxIdleStr != "" {
+		parsed, err := strconv.Atoi(maxIdleStr)
+		if err == nil {
+			config.DBMaxIdleConns = parsed
+		}
+	}
+
+	if lifetimeStr := os.Getenv("LEMMA_DB_CONN_MAX_LIFETIME"); lifetimeStr != "" {
+		parsed, err := time.ParseDuration(lifetimeStr)
+		if err == nil {
+			config.DBConnMaxLifetime = parsed
+		}
+	}
+
 	if workDir := os.Getenv("LEMMA_WORKDIR"); workDir != "" {
 		config.WorkDir = workDir
 	}
@@ -86,6 +186,28 @@ func LoadConfig() (*Config, error) {
 		config.StaticPath = staticPath
 	}
 
+	if retentionStr := os.Getenv("LEMMA_TRASH_RETENTION"); retentionStr != "" {
+		parsed, err := time.ParseDuration(retentionStr)
+		if err == nil {
+			config.TrashRetention = parsed
+		}
+	}
+
+	// Configure the retention of file revisions
+	if countStr := os.Getenv("LEMMA_REVISIONS_MAX_COUNT"); countStr != "" {
+		parsed, err := strconv.Atoi(countStr)
+		if err == nil {
+			config.RevisionsMaxCount = parsed
+		}
+	}
+
+	if ageStr := os.Getenv("LEMMA_REVISIONS_MAX_AGE"); ageStr != "" {
+		parsed, err := time.ParseDuration(ageStr)
+		if err == nil {
+			config.RevisionsMaxAge = parsed
+		}
+	}
+
 	if port := os.Getenv("LEMMA_PORT"); port != "" {
 		config.Port = port
 	}
@@ -102,11 +224,31 @@ func LoadConfig() (*Config, error) {
 		config.CORSOrigins = strings.Split(corsOrigins, ",")
 	}
 
+	// Client IPs are only taken from forwarded headers set by these proxies
+	if trustedProxies := os.Getenv("LEMMA_TRUSTED_PROXIES"); trustedProxies != "" {
+		parsed, err := parseTrustedProxies(trustedProxies)
+		if err != nil {
+			return nil, fmt.Errorf("invalid LEMMA_TRUSTED_PROXIES: %w", err)
+		}
+		config.TrustedProxies = parsed
+	}
+
 	config.AdminEmail = os.Getenv("LEMMA_ADMIN_EMAIL")
 	config.AdminPassword = os.Getenv("LEMMA_ADMIN_PASSWORD")
 	config.EncryptionKey = os.Getenv("LEMMA_ENCRYPTION_KEY")
+
+	if previousKeys := os.Getenv("LEMMA_PREVIOUS_ENCRYPTION_KEYS"); previousKeys != "" {
+		for _, key := range strings.Split(previousKeys, ",") {
+			config.PreviousEncryptionKeys = append(config.PreviousEncryptionKeys, strings.TrimSpace(key))
+		}
+	}
+
 	config.JWTSigningKey = os.Getenv("LEMMA_JWT_SIGNING_KEY")
 
+	if jwtAlgorithm := os.Getenv("LEMMA_JWT_ALGORITHM"); jwtAlgorithm != "" {
+		config.JWTAlgorithm = jwtAlgorithm
+	}
+
 	// Configure rate limiting
 	if reqStr := os.Getenv("LEMMA_RATE_LIMIT_REQUESTS"); reqStr != "" {
 		parsed, err := strconv.Atoi(reqStr)
@@ -122,6 +264,63 @@ func LoadConfig() (*Config, error) {
 		}
 	}
 
+	// Configure login brute-force protection
+	if attemptsStr := os.Getenv("LEMMA_LOGIN_MAX_ATTEMPTS"); attemptsStr != "" {
+		parsed, err := strconv.Atoi(attemptsStr)
+		if err == nil {
+			config.LoginMaxAttempts = parsed
+		}
+	}
+
+	if lockoutStr := os.Getenv("LEMMA_LOGIN_LOCKOUT_DURATION"); lockoutStr != "" {
+		parsed, err := time.ParseDuration(lockoutStr)
+		if err == nil {
+			config.LoginLockoutDuration = parsed
+		}
+	}
+
+	// Configure outgoing email
+	config.SMTPHost = os.Getenv("LEMMA_SMTP_HOST")
+	config.SMTPUsername = os.Getenv("LEMMA_SMTP_USERNAME")
+	config.SMTPPassword = os.Getenv("LEMMA_SMTP_PASSWORD")
+	config.MailFrom = os.Getenv("LEMMA_MAIL_FROM")
+	config.MailDir = os.Getenv("LEMMA_MAIL_DIR")
+
+	if portStr := os.Getenv("LEMMA_SMTP_PORT"); portStr != "" {
+		parsed, err := strconv.Atoi(portStr)
+		if err == nil {
+			config.SMTPPort = parsed
+		}
+	}
+
+	if verifyStr := os.Getenv("LEMMA_VERIFY_EMAIL_CHANGES"); verifyStr != "" {
+		parsed, err := strconv.ParseBool(verifyStr)
+		if err == nil {
+			config.VerifyEmailChanges = parsed
+		}
+	}
+
+	// Configure LDAP authentication
+	config.LDAPURL = os.Getenv("LEMMA_LDAP_URL")
+	config.LDAPBindDN = os.Getenv("LEMMA_LDAP_BIND_DN")
+	config.LDAPBindPassword = os.Getenv("LEMMA_LDAP_BIND_PASSWORD")
+	config.LDAPBaseDN = os.Getenv("LEMMA_LDAP_BASE_DN")
+	config.LDAPUserFilter = os.Getenv("LEMMA_LDAP_USER_FILTER")
+	config.LDAPAdminGroup = os.Getenv("LEMMA_LDAP_ADMIN_GROUP")
+	config.LDAPEditorGroup = os.Getenv("LEMMA_LDAP_EDITOR_GROUP")
+	config.LDAPViewerGroup = os.Getenv("LEMMA_LDAP_VIEWER_GROUP")
+
+	if startTLSStr := os.Getenv("LEMMA_LDAP_START_TLS"); startTLSStr != "" {
+		parsed, err := strconv.ParseBool(startTLSStr)
+		if err == nil {
+			config.LDAPStartTLS = parsed
+		}
+	}
+
+	// Configure the metrics endpoint, which is only served if a token or a separate address is set
+	config.MetricsToken = os.Getenv("LEMMA_METRICS_TOKEN")
+	config.MetricsAddr = os.Getenv("LEMMA_METRICS_ADDR")
+
 	// Configure log level, if isDevelopment is set, default to debug
 	if logLevel := os.Getenv("LEMMA_LOG_LEVEL"); logLevel != "" {
 		parsed := logging.ParseLogLevel(logLevel)
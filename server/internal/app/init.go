@@ -11,6 +11,7 @@ import (
 	"lemma/internal/auth"
 	"lemma/internal/db"
 	"lemma/internal/logging"
+	"lemma/internal/mailer"
 	"lemma/internal/models"
 	"lemma/internal/secrets"
 	"lemma/internal/storage"
@@ -19,7 +20,7 @@ import (
 // initSecretsService initializes the secrets service
 func initSecretsService(cfg *Config) (secrets.Service, error) {
 	logging.Debug("initializing secrets service")
-	secretsService, err := secrets.NewService(cfg.EncryptionKey)
+	secretsService, err := secrets.NewService(cfg.EncryptionKey, cfg.PreviousEncryptionKeys...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize secrets service: %w", err)
 	}
@@ -28,41 +29,80 @@ func initSecretsService(cfg *Config) (secrets.Service, error) {
 
 // initDatabase initializes and migrates the database
 func initDatabase(cfg *Config, secretsService secrets.Service) (db.Database, error) {
-	logging.Debug("initializing database", "path", cfg.DBPath)
-
-	database, err := db.Init(cfg.DBPath, secretsService)
+	database, err := openDatabase(cfg, secretsService)
 	if err != nil {
-		return nil, fmt.Errorf("failed to initialize database: %w", err)
+		return nil, err
 	}
 
 	if err := database.Migrate(); err != nil {
+		database.Close()
 		return nil, fmt.Errorf("failed to apply database migrations: %w", err)
 	}
 
 	return database, nil
 }
 
-// initAuth initializes JWT and session services
-func initAuth(cfg *Config, database db.Database) (auth.JWTManager, auth.SessionManager, auth.CookieManager, error) {
-	logging.Debug("initializing authentication services")
+// openDatabase initializes the database without applying migrations
+func openDatabase(cfg *Config, secretsService secrets.Service) (db.Database, error) {
+	// A database URL takes precedence over the path of the SQLite database
+	dbURL := cfg.DBPath
+	if cfg.DBURL != "" {
+		dbURL = cfg.DBURL
+		logging.Debug("initializing database", "url", cfg.Redact().DBURL)
+	} else {
+		logging.Debug("initializing database", "path", cfg.DBPath)
+	}
+
+	database, err := db.InitWithOptions(dbURL, secretsService, db.Options{
+		MaxOpenConns:    cfg.DBMaxOpenConns,
+		MaxIdleConns:    cfg.DBMaxIdleConns,
+		ConnMaxLifetime: cfg.DBConnMaxLifetime,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize database: %w", err)
+	}
 
-	accessTokeExpiry := 15 * time.Minute
-	refreshTokenExpiry := 7 * 24 * time.Hour
+	return database, nil
+}
+
+// Token lifetimes used by the JWT service
+const (
+	accessTokenExpiry  = 15 * time.Minute
+	refreshTokenExpiry = 7 * 24 * time.Hour
+)
+
+// initKeyring initializes the keyring used to sign JWT tokens
+func initKeyring(cfg *Config, database db.Database) (auth.Keyring, error) {
+	logging.Debug("initializing JWT keyring")
 
-	// Get or generate JWT signing key
-	signingKey := cfg.JWTSigningKey
-	if signingKey == "" {
-		logging.Debug("no JWT signing key provided, generating new key")
-		var err error
-		signingKey, err = database.EnsureJWTSecret()
-		if err != nil {
-			return nil, nil, nil, fmt.Errorf("failed to ensure JWT secret: %w", err)
-		}
+	// A signing key from the environment overrides the keyring stored in the database
+	if cfg.JWTSigningKey != "" {
+		logging.Debug("using JWT signing key from configuration")
+		return auth.NewStaticKeyring(cfg.JWTSigningKey), nil
+	}
+
+	// Secret of versions before the keyring, imported so existing sessions stay valid
+	legacySecret, _ := database.GetSystemSetting(db.JWTSecretKey)
+
+	keyring, err := auth.NewKeyring(database, auth.KeyringConfig{
+		Algorithm:       cfg.JWTAlgorithm,
+		RetentionPeriod: refreshTokenExpiry,
+		LegacySecret:    legacySecret,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize JWT keyring: %w", err)
 	}
 
+	return keyring, nil
+}
+
+// initAuth initializes JWT and session services
+func initAuth(cfg *Config, database db.Database, keyring auth.Keyring) (auth.JWTManager, auth.SessionManager, auth.CookieManager, error) {
+	logging.Debug("initializing authentication services")
+
 	jwtManager, err := auth.NewJWTService(auth.JWTConfig{
-		SigningKey:         signingKey,
-		AccessTokenExpiry:  accessTokeExpiry,
+		Keyring:            keyring,
+		AccessTokenExpiry:  accessTokenExpiry,
 		RefreshTokenExpiry: refreshTokenExpiry,
 	})
 	if err != nil {
@@ -75,6 +115,60 @@ func initAuth(cfg *Config, database db.Database) (auth.JWTManager, auth.SessionM
 	return jwtManager, sessionManager, cookieService, nil
 }
 
+// initLoginLimiter initializes brute-force protection for the login endpoint
+func initLoginLimiter(cfg *Config, database db.Database) auth.LoginLimiter {
+	logging.Debug("initializing login limiter")
+
+	return auth.NewLoginLimiter(database, auth.LoginLimiterConfig{
+		MaxEmailAttempts: cfg.LoginMaxAttempts,
+		LockoutDuration:  cfg.LoginLockoutDuration,
+	})
+}
+
+// initAuthenticator initializes login authentication. With LDAP configured, directory users
+// are authenticated against the directory and other users fall back to local passwords.
+func initAuthenticator(cfg *Config, database db.Database, storageManager storage.Manager) auth.Authenticator {
+	logging.Debug("initializing authenticator")
+
+	local := auth.NewLocalAuthenticator(database)
+	if cfg.LDAPURL == "" {
+		return local
+	}
+
+	ldapAuthenticator := auth.NewLDAPAuthenticator(auth.LDAPConfig{
+		URL:          cfg.LDAPURL,
+		StartTLS:     cfg.LDAPStartTLS,
+		BindDN:       cfg.LDAPBindDN,
+		BindPassword: cfg.LDAPBindPassword,
+		BaseDN:       cfg.LDAPBaseDN,
+		UserFilter:   cfg.LDAPUserFilter,
+		AdminGroup:   cfg.LDAPAdminGroup,
+		EditorGroup:  cfg.LDAPEditorGroup,
+		ViewerGroup:  cfg.LDAPViewerGroup,
+	}, database, storageManager)
+
+	return auth.NewChainAuthenticator(ldapAuthenticator, local)
+}
+
+// initMailer initializes the mailer used for account emails
+func initMailer(cfg *Config) (mailer.Mailer, error) {
+	logging.Debug("initializing mailer")
+
+	m, err := mailer.New(mailer.Config{
+		SMTPHost:     cfg.SMTPHost,
+		SMTPPort:     cfg.SMTPPort,
+		SMTPUsername: cfg.SMTPUsername,
+		SMTPPassword: cfg.SMTPPassword,
+		From:         cfg.MailFrom,
+		Dir:          cfg.MailDir,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize mailer: %w", err)
+	}
+
+	return m, nil
+}
+
 // setupAdminUser creates the admin user if it doesn't exist
 func setupAdminUser(database db.Database, storageManager storage.Manager, cfg *Config) error {
 	// Check if admin user exists
@@ -1,9 +1,12 @@
 package app
 
 import (
+	"fmt"
 	"lemma/internal/auth"
 	"lemma/internal/db"
 	"lemma/internal/logging"
+	"lemma/internal/mailer"
+	"lemma/internal/metrics"
 	"lemma/internal/storage"
 )
 
@@ -12,9 +15,15 @@ type Options struct {
 	Config         *Config
 	Database       db.Database
 	Storage        storage.Manager
+	Keyring        auth.Keyring
 	JWTManager     auth.JWTManager
 	SessionManager auth.SessionManager
 	CookieService  auth.CookieManager
+	LoginLimiter   auth.LoginLimiter
+	Authenticator  auth.Authenticator
+	UserTokens     auth.UserTokenManager
+	Mailer         mailer.Mailer
+	Metrics        *metrics.Metrics
 }
 
 // DefaultOptions creates server options with default configuration
@@ -31,14 +40,42 @@ func DefaultOptions(cfg *Config) (*Options, error) {
 		return nil, err
 	}
 
+	// Initialize metrics, file saves and Git operations are measured by the storage
+	metricsCollector := metrics.New()
+
 	// Initialize storage
-	storageManager := storage.NewService(cfg.WorkDir)
+	storageManager := storage.NewServiceWithOptions(cfg.WorkDir, storage.Options{
+		MaxRevisions:   cfg.RevisionsMaxCount,
+		RevisionMaxAge: cfg.RevisionsMaxAge,
+		Quotas:         database,
+		Usage:          database,
+		Observer:       metricsCollector,
+	})
+
+	if err := metricsCollector.Register(metrics.NewStateCollector(database, storageManager)); err != nil {
+		return nil, fmt.Errorf("failed to register state metrics: %w", err)
+	}
 
 	// Initialize logger
 	logging.Setup(cfg.LogLevel)
 
 	// Initialize auth services
-	jwtManager, sessionService, cookieService, err := initAuth(cfg, database)
+	keyring, err := initKeyring(cfg, database)
+	if err != nil {
+		return nil, err
+	}
+
+	jwtManager, sessionService, cookieService, err := initAuth(cfg, database, keyring)
+	if err != nil {
+		return nil, err
+	}
+
+	loginLimiter := initLoginLimiter(cfg, database)
+	authenticator := initAuthenticator(cfg, database, storageManager)
+	userTokens := auth.NewUserTokenManager(database, auth.UserTokenConfig{})
+
+	// Initialize mailer
+	mailService, err := initMailer(cfg)
 	if err != nil {
 		return nil, err
 	}
@@ -52,8 +89,14 @@ func DefaultOptions(cfg *Config) (*Options, error) {
 		Config:         cfg,
 		Database:       database,
 		Storage:        storageManager,
+		Keyring:        keyring,
 		JWTManager:     jwtManager,
 		SessionManager: sessionService,
 		CookieService:  cookieService,
+		LoginLimiter:   loginLimiter,
+		Authenticator:  authenticator,
+		UserTokens:     userTokens,
+		Mailer:         mailService,
+		Metrics:        metricsCollector,
 	}, nil
 }
@@ -0,0 +1,96 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// parseTrustedProxies parses a comma-separated list of IP addresses and CIDR ranges
+func parseTrustedProxies(value string) ([]netip.Prefix, error) {
+	var proxies []netip.Prefix
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid proxy range %q: %w", entry, err)
+			}
+			proxies = append(proxies, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy address %q: %w", entry, err)
+		}
+		proxies = append(proxies, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return proxies, nil
+}
+
+// TrustedRealIP sets the remote address of requests from the trusted proxies to the client IP
+// forwarded by them. Any client can set forwarded headers, so requests from other addresses
+// keep the address of the connection they were sent on.
+func TrustedRealIP(proxies []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if peer, ok := peerAddr(r.RemoteAddr); ok && isTrustedProxy(peer, proxies) {
+				if clientIP := forwardedClientIP(r, proxies); clientIP.IsValid() {
+					r.RemoteAddr = clientIP.String()
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// forwardedClientIP returns the client IP from the X-Real-IP header or, if it isn't set, the
+// rightmost address in X-Forwarded-For that isn't a trusted proxy. Addresses left of it were
+// sent by the client and can't be trusted.
+func forwardedClientIP(r *http.Request, proxies []netip.Prefix) netip.Addr {
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); realIP != "" {
+		addr, err := netip.ParseAddr(realIP)
+		if err != nil {
+			return netip.Addr{}
+		}
+		return addr.Unmap()
+	}
+
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(forwarded[i]))
+		if err != nil {
+			return netip.Addr{}
+		}
+		addr = addr.Unmap()
+		if !isTrustedProxy(addr, proxies) || i == 0 {
+			return addr
+		}
+	}
+	return netip.Addr{}
+}
+
+// peerAddr returns the IP of the remote address of a connection
+func peerAddr(remoteAddr string) (netip.Addr, bool) {
+	addrPort, err := netip.ParseAddrPort(remoteAddr)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addrPort.Addr().Unmap(), true
+}
+
+// isTrustedProxy reports whether an address belongs to one of the trusted proxies
+func isTrustedProxy(addr netip.Addr, proxies []netip.Prefix) bool {
+	for _, proxy := range proxies {
+		if proxy.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
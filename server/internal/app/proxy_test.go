@@ -0,0 +1,97 @@
+package app_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"lemma/internal/app"
+	_ "lemma/internal/testenv"
+)
+
+func TestTrustedRealIP(t *testing.T) {
+	proxies := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("192.0.2.1/32"),
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		want       string
+	}{
+		{
+			name:       "untrusted peer can't spoof its address",
+			remoteAddr: "203.0.113.5:1234",
+			headers:    map[string]string{"X-Real-IP": "198.51.100.1", "X-Forwarded-For": "198.51.100.1"},
+			want:       "203.0.113.5:1234",
+		},
+		{
+			name:       "real IP header of trusted proxy",
+			remoteAddr: "10.1.2.3:1234",
+			headers:    map[string]string{"X-Real-IP": "198.51.100.1"},
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "last untrusted forwarded address",
+			remoteAddr: "192.0.2.1:1234",
+			headers:    map[string]string{"X-Forwarded-For": "1.2.3.4, 198.51.100.2, 10.0.0.2"},
+			want:       "198.51.100.2",
+		},
+		{
+			name:       "forwarded only by trusted proxies",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string]string{"X-Forwarded-For": "10.0.0.3, 10.0.0.2"},
+			want:       "10.0.0.3",
+		},
+		{
+			name:       "invalid forwarded address",
+			remoteAddr: "10.0.0.1:1234",
+			headers:    map[string]string{"X-Forwarded-For": "not-an-ip"},
+			want:       "10.0.0.1:1234",
+		},
+		{
+			name:       "trusted proxy without forwarded headers",
+			remoteAddr: "10.0.0.1:1234",
+			want:       "10.0.0.1:1234",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			handler := app.TrustedRealIP(proxies)(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				got = r.RemoteAddr
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if got != tt.want {
+				t.Errorf("RemoteAddr = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("no trusted proxies", func(t *testing.T) {
+		var got string
+		handler := app.TrustedRealIP(nil)(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+			got = r.RemoteAddr
+		}))
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		req.Header.Set("X-Real-IP", "198.51.100.1")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		if got != "10.0.0.1:1234" {
+			t.Errorf("RemoteAddr = %v, want 10.0.0.1:1234", got)
+		}
+	})
+}
@@ -0,0 +1,116 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"lemma/internal/db"
+	"lemma/internal/logging"
+	"lemma/internal/storage"
+)
+
+// purgeInterval is how often the trash is checked for users, workspaces and files to purge
+const purgeInterval = time.Hour
+
+// PurgeDeleted permanently deletes the users and workspaces that were moved to the trash
+// before the cutoff, together with their files. Files are removed first, so a failed purge
+// is retried by the next one. It returns the number of purged users and workspaces.
+func PurgeDeleted(database db.Database, files storage.WorkspaceManager, cutoff time.Time) (int, int, error) {
+	users, err := database.GetDeletedUsers()
+	if err != nil {
+		return 0, 0, err
+	}
+	workspaces, err := database.GetDeletedWorkspaces()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var errs []error
+	purgedUsers := make(map[int]bool)
+	for _, user := range users {
+		if !user.DeletedAt.Before(cutoff) {
+			continue
+		}
+
+		// The workspaces of a user are purged with the user
+		failed := false
+		for _, workspace := range workspaces {
+			if workspace.UserID != user.ID {
+				continue
+			}
+			if err := files.DeleteUserWorkspace(workspace.UserID, workspace.ID); err != nil {
+				errs = append(errs, fmt.Errorf("failed to purge files of workspace %d: %w", workspace.ID, err))
+				failed = true
+			}
+		}
+		if failed {
+			continue
+		}
+
+		if err := database.PurgeUser(user.ID); err != nil {
+			errs = append(errs, fmt.Errorf("failed to purge user %d: %w", user.ID, err))
+			continue
+		}
+		purgedUsers[user.ID] = true
+	}
+
+	purgedWorkspaces := 0
+	for _, workspace := range workspaces {
+		if purgedUsers[workspace.UserID] || !workspace.DeletedAt.Before(cutoff) {
+			continue
+		}
+
+		if err := files.DeleteUserWorkspace(workspace.UserID, workspace.ID); err != nil {
+			errs = append(errs, fmt.Errorf("failed to purge files of workspace %d: %w", workspace.ID, err))
+			continue
+		}
+		if err := database.PurgeWorkspace(workspace.ID); err != nil {
+			errs = append(errs, fmt.Errorf("failed to purge workspace %d: %w", workspace.ID, err))
+			continue
+		}
+		purgedWorkspaces++
+	}
+
+	return len(purgedUsers), purgedWorkspaces, errors.Join(errs...)
+}
+
+// runPurgeJob purges the trash, the trash of the workspaces and expired file revisions when it
+// starts and then every purgeInterval, until stop is closed
+func runPurgeJob(options *Options, stop <-chan struct{}) {
+	log := logging.WithGroup("purge")
+
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+
+	for {
+		cutoff := time.Now().Add(-options.Config.TrashRetention)
+		users, workspaces, err := PurgeDeleted(options.Database, options.Storage, cutoff)
+		if err != nil {
+			log.Error("failed to purge trash", "error", err.Error())
+		}
+		files, err := options.Storage.PurgeExpiredTrash(cutoff)
+		if err != nil {
+			log.Error("failed to purge workspace trash", "error", err.Error())
+		}
+		if users > 0 || workspaces > 0 || files > 0 {
+			log.Info("purged trash",
+				"users", users,
+				"workspaces", workspaces,
+				"files", files)
+		}
+		revisions, err := options.Storage.PurgeExpiredRevisions()
+		if err != nil {
+			log.Error("failed to purge file revisions", "error", err.Error())
+		}
+		if revisions > 0 {
+			log.Info("purged file revisions", "revisions", revisions)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
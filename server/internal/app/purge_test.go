@@ -0,0 +1,91 @@
+package app_test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"lemma/internal/app"
+	"lemma/internal/db"
+	"lemma/internal/models"
+	"lemma/internal/storage"
+
+	_ "lemma/internal/testenv"
+)
+
+func TestPurgeDeleted(t *testing.T) {
+	database, err := db.NewTestDB(":memory:", nil)
+	if err != nil {
+		t.Fatalf("failed to create test database: %v", err)
+	}
+	defer database.Close()
+
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+	files := storage.NewService(t.TempDir())
+
+	createUser := func(email string) *models.User {
+		t.Helper()
+		user, err := database.CreateUser(&models.User{Email: email, PasswordHash: "hash", Role: models.RoleEditor})
+		if err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+		if err := files.InitializeUserWorkspace(user.ID, user.LastWorkspaceID); err != nil {
+			t.Fatalf("failed to initialize workspace: %v", err)
+		}
+		return user
+	}
+
+	deletedUser := createUser("deleted@example.com")
+	if err := database.DeleteUser(deletedUser.ID); err != nil {
+		t.Fatalf("failed to delete user: %v", err)
+	}
+
+	owner := createUser("owner@example.com")
+	deletedWorkspace := &models.Workspace{UserID: owner.ID, Name: "Deleted"}
+	if err := database.CreateWorkspace(deletedWorkspace); err != nil {
+		t.Fatalf("failed to create workspace: %v", err)
+	}
+	if err := files.InitializeUserWorkspace(owner.ID, deletedWorkspace.ID); err != nil {
+		t.Fatalf("failed to initialize workspace: %v", err)
+	}
+	if err := database.DeleteWorkspace(deletedWorkspace.ID); err != nil {
+		t.Fatalf("failed to delete workspace: %v", err)
+	}
+
+	t.Run("keeps items within the retention period", func(t *testing.T) {
+		users, workspaces, err := app.PurgeDeleted(database, files, time.Now().Add(-time.Hour))
+		if err != nil {
+			t.Fatalf("PurgeDeleted() error = %v", err)
+		}
+		if users != 0 || workspaces != 0 {
+			t.Errorf("PurgeDeleted() = %d users, %d workspaces, want none", users, workspaces)
+		}
+	})
+
+	t.Run("purges items deleted before the cutoff", func(t *testing.T) {
+		users, workspaces, err := app.PurgeDeleted(database, files, time.Now().Add(time.Hour))
+		if err != nil {
+			t.Fatalf("PurgeDeleted() error = %v", err)
+		}
+		if users != 1 || workspaces != 1 {
+			t.Errorf("PurgeDeleted() = %d users, %d workspaces, want 1 and 1", users, workspaces)
+		}
+
+		if exists, err := database.EmailExists(deletedUser.Email); err != nil || exists {
+			t.Errorf("purged user still exists: %v", err)
+		}
+		for _, path := range []string{
+			files.GetWorkspacePath(deletedUser.ID, deletedUser.LastWorkspaceID),
+			files.GetWorkspacePath(owner.ID, deletedWorkspace.ID),
+		} {
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				t.Errorf("workspace directory %s was not removed", path)
+			}
+		}
+		if _, err := os.Stat(files.GetWorkspacePath(owner.ID, owner.LastWorkspaceID)); err != nil {
+			t.Errorf("workspace directory of active workspace was removed: %v", err)
+		}
+	})
+}
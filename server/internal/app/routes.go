@@ -27,8 +27,11 @@ func setupRouter(o Options) *chi.Mux {
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
+	r.Use(TrustedRealIP(o.Config.TrustedProxies))
 	r.Use(middleware.Timeout(30 * time.Second))
+	if o.Metrics != nil {
+		r.Use(o.Metrics.Middleware)
+	}
 
 	// Security headers
 	r.Use(secure.New(secure.Options{
@@ -50,7 +53,7 @@ func setupRouter(o Options) *chi.Mux {
 	}
 
 	// Initialize auth middleware and handler
-	authMiddleware := auth.NewMiddleware(o.JWTManager, o.SessionManager, o.CookieService)
+	authMiddleware := auth.NewMiddleware(o.JWTManager, o.SessionManager, o.CookieService, o.Database)
 	handler := &handlers.Handler{
 		DB:      o.Database,
 		Storage: o.Storage,
@@ -62,6 +65,11 @@ func setupRouter(o Options) *chi.Mux {
 		))
 	}
 
+	// Metrics are served here if they are protected by a token and not by a separate address
+	if o.Metrics != nil && o.Config.MetricsToken != "" && o.Config.MetricsAddr == "" {
+		r.Handle("/metrics", o.Metrics.Handler(o.Config.MetricsToken))
+	}
+
 	// API routes
 	r.Route("/api/v1", func(r chi.Router) {
 		// Rate limiting for API routes
@@ -74,8 +82,15 @@ func setupRouter(o Options) *chi.Mux {
 
 		// Public routes (no authentication required)
 		r.Group(func(r chi.Router) {
-			r.Post("/auth/login", handler.Login(o.SessionManager, o.CookieService))
+			r.Post("/auth/login", handler.Login(o.Authenticator, o.SessionManager, o.CookieService, o.LoginLimiter))
 			r.Post("/auth/refresh", handler.RefreshToken(o.SessionManager, o.CookieService))
+			r.Get("/auth/jwks", handler.GetJWKS(o.Keyring))
+			r.Post("/auth/forgot-password", handler.ForgotPassword(o.UserTokens, o.Mailer, o.Config.RootURL))
+			r.Post("/auth/reset-password", handler.ResetPassword(o.UserTokens, o.LoginLimiter))
+			r.Post("/auth/verify-email", handler.VerifyEmail(o.UserTokens))
+			r.Post("/auth/signup", handler.Signup(o.UserTokens, o.Mailer, o.Config.RootURL))
+			r.Get("/shares/{token}", handler.GetSharedContent(o.LoginLimiter))
+			r.Get("/shares/{token}/*", handler.GetSharedContent(o.LoginLimiter))
 		})
 
 		// Protected routes (authentication required)
@@ -83,65 +98,153 @@ func setupRouter(o Options) *chi.Mux {
 			r.Use(authMiddleware.Authenticate)
 			r.Use(context.WithUserContextMiddleware)
 
-			// Auth routes
+			// Routes that stay available to users who have to change their password
 			r.Post("/auth/logout", handler.Logout(o.SessionManager, o.CookieService))
 			r.Get("/auth/me", handler.GetCurrentUser())
+			r.Get("/profile", handler.GetProfile())
+			r.Put("/profile", handler.UpdateProfile(o.UserTokens, o.Mailer, o.Config.RootURL, o.Config.VerifyEmailChanges))
+
+			r.Group(func(r chi.Router) {
+				r.Use(authMiddleware.RequirePasswordChanged)
+
+				// Auth routes
+				r.Get("/auth/sessions", handler.ListSessions())
+				r.Delete("/auth/sessions", handler.RevokeOtherSessions())
+				r.Delete("/auth/sessions/{sessionId}", handler.RevokeSession(o.CookieService))
+
+				// User profile routes
+				r.Get("/profile/export", handler.ExportProfile())
+				r.Delete("/profile", handler.DeleteAccount())
 
-			// User profile routes
-			r.Put("/profile", handler.UpdateProfile())
-			r.Delete("/profile", handler.DeleteAccount())
-
-			// Admin-only routes
-			r.Route("/admin", func(r chi.Router) {
-				r.Use(authMiddleware.RequireRole("admin"))
-				// User management
-				r.Route("/users", func(r chi.Router) {
-					r.Get("/", handler.AdminListUsers())
-					r.Post("/", handler.AdminCreateUser())
-					r.Get("/{userId}", handler.AdminGetUser())
-					r.Put("/{userId}", handler.AdminUpdateUser())
-					r.Delete("/{userId}", handler.AdminDeleteUser())
+				// Admin-only routes
+				r.Route("/admin", func(r chi.Router) {
+					r.Use(authMiddleware.RequirePermission(auth.PermissionAdmin))
+					// User management
+					r.Route("/users", func(r chi.Router) {
+						r.Get("/", handler.AdminListUsers())
+						r.Post("/", handler.AdminCreateUser())
+						r.Post("/import", handler.AdminImportUsers(o.Config.RootURL))
+						r.Get("/export", handler.AdminExportUsers())
+						r.Get("/{userId}", handler.AdminGetUser())
+						r.Put("/{userId}", handler.AdminUpdateUser())
+						r.Delete("/{userId}", handler.AdminDeleteUser())
+						r.Get("/{userId}/sessions", handler.AdminListUserSessions())
+						r.Delete("/{userId}/sessions", handler.AdminRevokeUserSessions())
+						r.Delete("/{userId}/sessions/{sessionId}", handler.AdminRevokeUserSession())
+						r.Post("/{userId}/unlock", handler.AdminUnlockUser(o.LoginLimiter))
+						r.Post("/{userId}/suspend", handler.AdminSuspendUser())
+						r.Post("/{userId}/unsuspend", handler.AdminUnsuspendUser())
+						r.Put("/{userId}/quota", handler.AdminUpdateUserQuota())
+						r.Delete("/{userId}/quota", handler.AdminDeleteUserQuota())
+					})
+					// Invitation management
+					r.Route("/invitations", func(r chi.Router) {
+						r.Get("/", handler.AdminListInvitations())
+						r.Post("/", handler.AdminCreateInvitation(o.Config.RootURL))
+						r.Delete("/{invitationId}", handler.AdminDeleteInvitation())
+					})
+					// Registration settings
+					r.Get("/settings/registration", handler.AdminGetRegistrationSettings())
+					r.Put("/settings/registration", handler.AdminUpdateRegistrationSettings())
+					// Storage quota settings
+					r.Get("/settings/quota", handler.AdminGetDefaultQuota())
+					r.Put("/settings/quota", handler.AdminUpdateDefaultQuota())
+					// Workspace management
+					r.Route("/workspaces", func(r chi.Router) {
+						r.Get("/", handler.AdminListWorkspaces())
+						r.Post("/{workspaceId}/transfer", handler.AdminTransferWorkspace())
+						r.Get("/{workspaceId}/quota", handler.AdminGetWorkspaceQuota())
+						r.Put("/{workspaceId}/quota", handler.AdminUpdateWorkspaceQuota())
+						r.Delete("/{workspaceId}/quota", handler.AdminDeleteWorkspaceQuota())
+					})
+					// Deleted users and workspaces
+					r.Route("/trash", func(r chi.Router) {
+						r.Get("/users", handler.AdminListDeletedUsers())
+						r.Post("/users/{userId}/restore", handler.AdminRestoreUser())
+						r.Get("/workspaces", handler.AdminListDeletedWorkspaces())
+						r.Post("/workspaces/{workspaceId}/restore", handler.AdminRestoreWorkspace())
+					})
+					// JWT key management
+					r.Route("/jwt-keys", func(r chi.Router) {
+						r.Get("/", handler.AdminListJWTKeys(o.Keyring))
+						r.Post("/rotate", handler.AdminRotateJWTKey(o.Keyring))
+					})
+					// Audit log
+					r.Get("/audit-events", handler.AdminListAuditEvents())
+					// Backups
+					r.Get("/backup", handler.AdminCreateBackup(o.Config.DBPath))
+					// System stats
+					r.Get("/stats", handler.AdminGetSystemStats())
 				})
-				// Workspace management
+
+				// Workspace routes
+				// Remembering the last opened workspace and file only needs read access
 				r.Route("/workspaces", func(r chi.Router) {
-					r.Get("/", handler.AdminListWorkspaces())
-				})
-				// System stats
-				r.Get("/stats", handler.AdminGetSystemStats())
-			})
+					read := authMiddleware.RequirePermission(auth.PermissionRead)
+					write := authMiddleware.RequirePermission(auth.PermissionWrite)
+					manage := authMiddleware.RequirePermission(auth.PermissionManage)
 
-			// Workspace routes
-			r.Route("/workspaces", func(r chi.Router) {
-				r.Get("/", handler.ListWorkspaces())
-				r.Post("/", handler.CreateWorkspace())
-				r.Get("/last", handler.GetLastWorkspaceName())
-				r.Put("/last", handler.UpdateLastWorkspaceName())
-
-				// Single workspace routes
-				r.Route("/{workspaceName}", func(r chi.Router) {
-					r.Use(context.WithWorkspaceContextMiddleware(o.Database))
-					r.Use(authMiddleware.RequireWorkspaceAccess)
-
-					r.Get("/", handler.GetWorkspace())
-					r.Put("/", handler.UpdateWorkspace())
-					r.Delete("/", handler.DeleteWorkspace())
-
-					// File routes
-					r.Route("/files", func(r chi.Router) {
-						r.Get("/", handler.ListFiles())
-						r.Get("/last", handler.GetLastOpenedFile())
-						r.Put("/last", handler.UpdateLastOpenedFile())
-						r.Get("/lookup", handler.LookupFileByName())
-
-						r.Post("/*", handler.SaveFile())
-						r.Get("/*", handler.GetFileContent())
-						r.Delete("/*", handler.DeleteFile())
-					})
+					r.With(read).Get("/", handler.ListWorkspaces())
+					r.With(write).Post("/", handler.CreateWorkspace())
+					r.With(read).Get("/last", handler.GetLastWorkspaceName())
+					r.With(read).Put("/last", handler.UpdateLastWorkspaceName())
+
+					// Single workspace routes
+					r.Route("/{workspaceName}", func(r chi.Router) {
+						r.Use(context.WithWorkspaceContextMiddleware(o.Database))
+						r.Use(authMiddleware.RequireWorkspaceAccess)
+
+						r.With(read).Get("/", handler.GetWorkspace())
+						r.With(manage).Put("/", handler.UpdateWorkspace())
+						r.With(manage).Delete("/", handler.DeleteWorkspace())
+
+						// Member routes
+						r.Route("/members", func(r chi.Router) {
+							r.With(read).Get("/", handler.ListWorkspaceMembers())
+							r.With(manage).Post("/", handler.AddWorkspaceMember())
+							r.With(manage).Put("/{userId}", handler.UpdateWorkspaceMember())
+							r.With(manage).Delete("/{userId}", handler.RemoveWorkspaceMember())
+						})
+
+						// Share link routes
+						r.Route("/shares", func(r chi.Router) {
+							r.With(manage).Get("/", handler.ListShareLinks())
+							r.With(manage).Post("/", handler.CreateShareLink(o.Config.RootURL))
+							r.With(manage).Delete("/{shareId}", handler.DeleteShareLink())
+						})
+
+						// File routes
+						r.Route("/files", func(r chi.Router) {
+							r.With(read).Get("/", handler.ListFiles())
+							r.With(read).Get("/last", handler.GetLastOpenedFile())
+							r.With(read).Put("/last", handler.UpdateLastOpenedFile())
+							r.With(read).Get("/lookup", handler.LookupFileByName())
+
+							r.With(write).Post("/*", handler.SaveFile())
+							r.With(read).Get("/*", handler.GetFileContent())
+							r.With(write).Delete("/*", handler.DeleteFile())
+						})
+
+						// Trash routes
+						r.Route("/trash", func(r chi.Router) {
+							r.With(read).Get("/", handler.ListTrash())
+							r.With(manage).Delete("/", handler.EmptyTrash())
+							r.With(write).Post("/{itemId}/restore", handler.RestoreTrashItem())
+						})
+
+						// File revision routes
+						r.Route("/revisions", func(r chi.Router) {
+							r.With(read).Get("/", handler.ListFileRevisions())
+							r.With(read).Get("/diff", handler.DiffFileRevisions())
+							r.With(read).Get("/{revisionId}", handler.GetFileRevision())
+							r.With(write).Post("/{revisionId}/restore", handler.RestoreFileRevision())
+						})
 
-					// Git routes
-					r.Route("/git", func(r chi.Router) {
-						r.Post("/commit", handler.StageCommitAndPush())
-						r.Post("/pull", handler.PullChanges())
+						// Git routes
+						r.Route("/git", func(r chi.Router) {
+							r.With(write).Post("/commit", handler.StageCommitAndPush())
+							r.With(write).Post("/pull", handler.PullChanges())
+						})
 					})
 				})
 			})
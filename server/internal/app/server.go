@@ -11,6 +11,7 @@ import (
 type Server struct {
 	router  *chi.Mux
 	options *Options
+	stop    chan struct{}
 }
 
 // NewServer creates a new server instance with the given options
@@ -18,11 +19,26 @@ func NewServer(options *Options) *Server {
 	return &Server{
 		router:  setupRouter(*options),
 		options: options,
+		stop:    make(chan struct{}),
 	}
 }
 
-// Start configures and starts the HTTP server
+// Start configures and starts the HTTP server and the background jobs
 func (s *Server) Start() error {
+	go runPurgeJob(s.options, s.stop)
+
+	// Serve metrics on their own address, which is usually only reachable internally
+	if s.options.Metrics != nil && s.options.Config.MetricsAddr != "" {
+		go func() {
+			metricsRouter := http.NewServeMux()
+			metricsRouter.Handle("/metrics", s.options.Metrics.Handler(s.options.Config.MetricsToken))
+			logging.Info("starting metrics server", "address", s.options.Config.MetricsAddr)
+			if err := http.ListenAndServe(s.options.Config.MetricsAddr, metricsRouter); err != nil {
+				logging.Error("metrics server failed", "error", err.Error())
+			}
+		}()
+	}
+
 	// Start server
 	addr := ":" + s.options.Config.Port
 	logging.Info("starting server", "address", addr)
@@ -32,6 +48,7 @@ func (s *Server) Start() error {
 // Close handles graceful shutdown of server dependencies
 func (s *Server) Close() error {
 	logging.Info("shutting down server")
+	close(s.stop)
 	return s.options.Database.Close()
 }
 
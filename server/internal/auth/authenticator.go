@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"errors"
+	"lemma/internal/db"
+	"lemma/internal/logging"
+	"lemma/internal/models"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	// ErrInvalidCredentials is returned when the password doesn't match the user
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrUnknownUser is returned when an authenticator doesn't know the user, so the next one can be tried
+	ErrUnknownUser = errors.New("unknown user")
+)
+
+func getAuthenticatorLogger() logging.Logger {
+	return getAuthLogger().WithGroup("authenticator")
+}
+
+// Authenticator verifies login credentials against an identity backend
+type Authenticator interface {
+	// Authenticate returns the local user for valid credentials. Backends that manage
+	// users externally create or update the local user as needed.
+	Authenticate(email, password string) (*models.User, error)
+}
+
+// localAuthenticator verifies passwords against the bcrypt hashes stored in the database
+type localAuthenticator struct {
+	db db.UserStore
+}
+
+// NewLocalAuthenticator creates an authenticator for users with a password stored in the database
+func NewLocalAuthenticator(db db.UserStore) Authenticator {
+	return &localAuthenticator{db: db}
+}
+
+// Authenticate compares the password with the user's password hash
+func (a *localAuthenticator) Authenticate(email, password string) (*models.User, error) {
+	user, err := a.db.GetUserByEmail(email)
+	if err != nil {
+		getAuthenticatorLogger().Debug("user not found",
+			"email", email,
+			"error", err.Error())
+		return nil, ErrUnknownUser
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	return user, nil
+}
+
+// chainAuthenticator tries authenticators in order until one knows the user
+type chainAuthenticator struct {
+	authenticators []Authenticator
+}
+
+// NewChainAuthenticator creates an authenticator that tries each authenticator in order.
+// The next authenticator is only tried if the previous one returns ErrUnknownUser.
+func NewChainAuthenticator(authenticators ...Authenticator) Authenticator {
+	return &chainAuthenticator{authenticators: authenticators}
+}
+
+// Authenticate returns the result of the first authenticator that knows the user
+func (a *chainAuthenticator) Authenticate(email, password string) (*models.User, error) {
+	for _, authenticator := range a.authenticators {
+		user, err := authenticator.Authenticate(email, password)
+		if errors.Is(err, ErrUnknownUser) {
+			continue
+		}
+		return user, err
+	}
+
+	return nil, ErrUnknownUser
+}
@@ -0,0 +1,422 @@
+package auth_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"lemma/internal/auth"
+	"lemma/internal/auth/ldaptest"
+	"lemma/internal/models"
+	_ "lemma/internal/testenv"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Mock UserStore
+type mockUserStore struct {
+	users  map[string]*models.User
+	nextID int
+}
+
+func newMockUserStore() *mockUserStore {
+	return &mockUserStore{
+		users:  make(map[string]*models.User),
+		nextID: 1,
+	}
+}
+
+func (m *mockUserStore) CreateUserWithWorkspace(user *models.User, _ string) (*models.User, error) {
+	return m.CreateUser(user)
+}
+
+func (m *mockUserStore) CreateUser(user *models.User) (*models.User, error) {
+	if _, exists := m.users[user.Email]; exists {
+		return nil, fmt.Errorf("user already exists")
+	}
+	created := *user
+	created.ID = m.nextID
+	created.LastWorkspaceID = m.nextID
+	m.nextID++
+	m.users[created.Email] = &created
+	copied := created
+	return &copied, nil
+}
+
+func (m *mockUserStore) GetUserByEmail(email string) (*models.User, error) {
+	user, exists := m.users[email]
+	if !exists {
+		return nil, fmt.Errorf("user not found")
+	}
+	copied := *user
+	return &copied, nil
+}
+
+func (m *mockUserStore) GetUserByID(userID int) (*models.User, error) {
+	for _, user := range m.users {
+		if user.ID == userID {
+			copied := *user
+			return &copied, nil
+		}
+	}
+	return nil, fmt.Errorf("user not found")
+}
+
+func (m *mockUserStore) GetAllUsers() ([]*models.User, error) {
+	var users []*models.User
+	for _, user := range m.users {
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+func (m *mockUserStore) UpdateUser(user *models.User) error {
+	copied := *user
+	m.users[user.Email] = &copied
+	return nil
+}
+
+func (m *mockUserStore) DeleteUser(userID int) error {
+	for email, user := range m.users {
+		if user.ID == userID {
+			delete(m.users, email)
+		}
+	}
+	return nil
+}
+
+func (m *mockUserStore) GetDeletedUsers() ([]*models.User, error) {
+	return nil, nil
+}
+
+func (m *mockUserStore) RestoreUser(_ int) error {
+	return nil
+}
+
+func (m *mockUserStore) PurgeUser(_ int) error {
+	return nil
+}
+
+func (m *mockUserStore) EmailExists(email string) (bool, error) {
+	_, ok := m.users[email]
+	return ok, nil
+}
+
+func (m *mockUserStore) UpdateLastWorkspace(_ int, _ string) error {
+	return nil
+}
+
+func (m *mockUserStore) GetLastWorkspaceName(_ int) (string, error) {
+	return "", nil
+}
+
+func (m *mockUserStore) CountAdminUsers() (int, error) {
+	return 0, nil
+}
+
+// Mock WorkspaceManager
+type mockWorkspaceManager struct {
+	initialized map[int]bool
+}
+
+func (m *mockWorkspaceManager) ValidatePath(_, _ int, path string) (string, error) {
+	return path, nil
+}
+
+func (m *mockWorkspaceManager) GetWorkspacePath(_, _ int) string {
+	return ""
+}
+
+func (m *mockWorkspaceManager) InitializeUserWorkspace(userID, _ int) error {
+	m.initialized[userID] = true
+	return nil
+}
+
+func (m *mockWorkspaceManager) DeleteUserWorkspace(_, _ int) error {
+	return nil
+}
+
+func (m *mockWorkspaceManager) TransferWorkspace(_, _ int, _ *models.Workspace, commit func() error) error {
+	return commit()
+}
+
+func addLocalUser(t *testing.T, store *mockUserStore, email, password string, role models.UserRole) {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	if _, err := store.CreateUser(&models.User{Email: email, PasswordHash: string(hash), Role: role}); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+}
+
+func TestLocalAuthenticator(t *testing.T) {
+	store := newMockUserStore()
+	addLocalUser(t, store, "local@example.com", "password123", models.RoleEditor)
+	authenticator := auth.NewLocalAuthenticator(store)
+
+	testCases := []struct {
+		name     string
+		email    string
+		password string
+		wantErr  error
+	}{
+		{
+			name:     "valid credentials",
+			email:    "local@example.com",
+			password: "password123",
+		},
+		{
+			name:     "wrong password",
+			email:    "local@example.com",
+			password: "wrongpassword",
+			wantErr:  auth.ErrInvalidCredentials,
+		},
+		{
+			name:     "unknown user",
+			email:    "nobody@example.com",
+			password: "password123",
+			wantErr:  auth.ErrUnknownUser,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			user, err := authenticator.Authenticate(tc.email, tc.password)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("Authenticate() error = %v, want %v", err, tc.wantErr)
+			}
+			if tc.wantErr == nil && user.Email != tc.email {
+				t.Errorf("Authenticate() user = %s, want %s", user.Email, tc.email)
+			}
+		})
+	}
+}
+
+func newTestLDAPServer(t *testing.T) *ldaptest.Server {
+	t.Helper()
+	server, err := ldaptest.NewServer(
+		ldaptest.Entry{
+			DN:       "cn=service,dc=example,dc=com",
+			Password: "servicepass",
+		},
+		ldaptest.Entry{
+			DN:       "uid=alice,ou=people,dc=example,dc=com",
+			Password: "alicepass",
+			Attributes: map[string][]string{
+				"mail":     {"alice@example.com"},
+				"cn":       {"Alice"},
+				"memberOf": {"cn=admins,ou=groups,dc=example,dc=com"},
+			},
+		},
+		ldaptest.Entry{
+			DN:       "uid=bob,ou=people,dc=example,dc=com",
+			Password: "bobpass",
+			Attributes: map[string][]string{
+				"mail":     {"bob@example.com"},
+				"cn":       {"Bob"},
+				"memberOf": {"cn=readers,ou=groups,dc=example,dc=com"},
+			},
+		},
+		ldaptest.Entry{
+			DN:       "uid=carol,ou=people,dc=example,dc=com",
+			Password: "carolpass",
+			Attributes: map[string][]string{
+				"mail": {"carol@example.com"},
+				"cn":   {"Carol"},
+			},
+		},
+	)
+	if err != nil {
+		t.Fatalf("failed to start LDAP server: %v", err)
+	}
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestLDAPAuthenticator(t *testing.T) {
+	server := newTestLDAPServer(t)
+
+	config := auth.LDAPConfig{
+		URL:          server.URL,
+		BindDN:       "cn=service,dc=example,dc=com",
+		BindPassword: "servicepass",
+		BaseDN:       "ou=people,dc=example,dc=com",
+		AdminGroup:   "cn=admins,ou=groups,dc=example,dc=com",
+		ViewerGroup:  "CN=Readers,OU=Groups,DC=example,DC=com",
+	}
+
+	t.Run("first login creates user with role from groups", func(t *testing.T) {
+		store := newMockUserStore()
+		workspaces := &mockWorkspaceManager{initialized: make(map[int]bool)}
+		authenticator := auth.NewLDAPAuthenticator(config, store, workspaces)
+
+		testCases := []struct {
+			email    string
+			password string
+			wantRole models.UserRole
+			wantName string
+		}{
+			{"alice@example.com", "alicepass", models.RoleAdmin, "Alice"},
+			{"bob@example.com", "bobpass", models.RoleViewer, "Bob"},
+		}
+
+		for _, tc := range testCases {
+			user, err := authenticator.Authenticate(tc.email, tc.password)
+			if err != nil {
+				t.Fatalf("Authenticate(%s) error = %v", tc.email, err)
+			}
+			if user.Role != tc.wantRole {
+				t.Errorf("Authenticate(%s) role = %s, want %s", tc.email, user.Role, tc.wantRole)
+			}
+			if user.DisplayName != tc.wantName {
+				t.Errorf("Authenticate(%s) display name = %s, want %s", tc.email, user.DisplayName, tc.wantName)
+			}
+			if !workspaces.initialized[user.ID] {
+				t.Errorf("workspace of %s not initialized", tc.email)
+			}
+
+			// The directory password is not a valid local password
+			if _, err := auth.NewLocalAuthenticator(store).Authenticate(tc.email, tc.password); !errors.Is(err, auth.ErrInvalidCredentials) {
+				t.Errorf("local Authenticate(%s) error = %v, want %v", tc.email, err, auth.ErrInvalidCredentials)
+			}
+		}
+	})
+
+	t.Run("role is updated on login", func(t *testing.T) {
+		store := newMockUserStore()
+		if _, err := store.CreateUser(&models.User{
+			Email:      "alice@example.com",
+			Role:       models.RoleViewer,
+			AuthSource: models.AuthSourceLDAP,
+		}); err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+		authenticator := auth.NewLDAPAuthenticator(config, store, &mockWorkspaceManager{initialized: make(map[int]bool)})
+
+		user, err := authenticator.Authenticate("alice@example.com", "alicepass")
+		if err != nil {
+			t.Fatalf("Authenticate() error = %v", err)
+		}
+		if user.Role != models.RoleAdmin {
+			t.Errorf("role = %s, want %s", user.Role, models.RoleAdmin)
+		}
+
+		stored, _ := store.GetUserByEmail("alice@example.com")
+		if stored.Role != models.RoleAdmin || stored.DisplayName != "Alice" {
+			t.Errorf("stored user = %+v, want admin role and display name Alice", stored)
+		}
+	})
+
+	t.Run("local users are not taken over", func(t *testing.T) {
+		store := newMockUserStore()
+		addLocalUser(t, store, "alice@example.com", "localpass", models.RoleViewer)
+		authenticator := auth.NewLDAPAuthenticator(config, store, &mockWorkspaceManager{initialized: make(map[int]bool)})
+
+		if _, err := authenticator.Authenticate("alice@example.com", "alicepass"); !errors.Is(err, auth.ErrUnknownUser) {
+			t.Fatalf("Authenticate() error = %v, want %v", err, auth.ErrUnknownUser)
+		}
+
+		stored, _ := store.GetUserByEmail("alice@example.com")
+		if stored.Role != models.RoleViewer || stored.AuthSource == models.AuthSourceLDAP {
+			t.Errorf("stored user = %+v, want unchanged local viewer", stored)
+		}
+	})
+
+	t.Run("rejected logins", func(t *testing.T) {
+		authenticator := auth.NewLDAPAuthenticator(config, newMockUserStore(), &mockWorkspaceManager{initialized: make(map[int]bool)})
+
+		testCases := []struct {
+			name     string
+			email    string
+			password string
+			wantErr  error
+		}{
+			{"wrong password", "alice@example.com", "wrongpass", auth.ErrInvalidCredentials},
+			{"empty password", "alice@example.com", "", auth.ErrInvalidCredentials},
+			{"no group with access", "carol@example.com", "carolpass", auth.ErrInvalidCredentials},
+			{"unknown user", "nobody@example.com", "password", auth.ErrUnknownUser},
+			{"filter injection", "*)(mail=alice@example.com", "alicepass", auth.ErrUnknownUser},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				if _, err := authenticator.Authenticate(tc.email, tc.password); !errors.Is(err, tc.wantErr) {
+					t.Errorf("Authenticate() error = %v, want %v", err, tc.wantErr)
+				}
+			})
+		}
+	})
+
+	t.Run("without groups every directory user is an editor", func(t *testing.T) {
+		noGroups := config
+		noGroups.AdminGroup = ""
+		noGroups.ViewerGroup = ""
+		authenticator := auth.NewLDAPAuthenticator(noGroups, newMockUserStore(), &mockWorkspaceManager{initialized: make(map[int]bool)})
+
+		user, err := authenticator.Authenticate("carol@example.com", "carolpass")
+		if err != nil {
+			t.Fatalf("Authenticate() error = %v", err)
+		}
+		if user.Role != models.RoleEditor {
+			t.Errorf("role = %s, want %s", user.Role, models.RoleEditor)
+		}
+	})
+
+	t.Run("unreachable server", func(t *testing.T) {
+		unreachable := config
+		unreachable.URL = "ldap://127.0.0.1:1"
+		authenticator := auth.NewLDAPAuthenticator(unreachable, newMockUserStore(), &mockWorkspaceManager{initialized: make(map[int]bool)})
+
+		_, err := authenticator.Authenticate("alice@example.com", "alicepass")
+		if err == nil || errors.Is(err, auth.ErrUnknownUser) || errors.Is(err, auth.ErrInvalidCredentials) {
+			t.Errorf("Authenticate() error = %v, want connection error", err)
+		}
+	})
+}
+
+func TestChainAuthenticator(t *testing.T) {
+	server := newTestLDAPServer(t)
+
+	store := newMockUserStore()
+	addLocalUser(t, store, "admin@example.com", "adminpass", models.RoleAdmin)
+	// A local account whose mail is also in the directory, without a group with access
+	addLocalUser(t, store, "carol@example.com", "localpass", models.RoleEditor)
+	ldapAuthenticator := auth.NewLDAPAuthenticator(auth.LDAPConfig{
+		URL:         server.URL,
+		BaseDN:      "ou=people,dc=example,dc=com",
+		AdminGroup:  "cn=admins,ou=groups,dc=example,dc=com",
+		ViewerGroup: "cn=readers,ou=groups,dc=example,dc=com",
+	}, store, &mockWorkspaceManager{initialized: make(map[int]bool)})
+	authenticator := auth.NewChainAuthenticator(ldapAuthenticator, auth.NewLocalAuthenticator(store))
+
+	testCases := []struct {
+		name     string
+		email    string
+		password string
+		wantErr  error
+	}{
+		{"directory user", "alice@example.com", "alicepass", nil},
+		{"directory user with wrong password", "alice@example.com", "wrongpass", auth.ErrInvalidCredentials},
+		// Directory users can't fall back to their local password
+		{"directory user with local password", "alice@example.com", "adminpass", auth.ErrInvalidCredentials},
+		{"local user", "admin@example.com", "adminpass", nil},
+		{"local user with wrong password", "admin@example.com", "wrongpass", auth.ErrInvalidCredentials},
+		{"local user also in directory", "carol@example.com", "localpass", nil},
+		{"local user with directory password", "carol@example.com", "carolpass", auth.ErrInvalidCredentials},
+		{"unknown user", "nobody@example.com", "password", auth.ErrUnknownUser},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			user, err := authenticator.Authenticate(tc.email, tc.password)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("Authenticate() error = %v, want %v", err, tc.wantErr)
+			}
+			if tc.wantErr == nil && user.Email != tc.email {
+				t.Errorf("Authenticate() user = %s, want %s", user.Email, tc.email)
+			}
+		})
+	}
+}
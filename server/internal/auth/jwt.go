@@ -3,6 +3,7 @@ package auth
 
 import (
 	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"lemma/internal/logging"
 	"time"
@@ -25,14 +26,16 @@ const (
 // Claims represents the custom claims we store in JWT tokens
 type Claims struct {
 	jwt.RegisteredClaims           // Embedded standard JWT claims
-	UserID               int       `json:"uid"`  // User identifier
-	Role                 string    `json:"role"` // User role (admin, editor, viewer)
-	Type                 TokenType `json:"type"` // Token type (access or refresh)
+	UserID               int       `json:"uid"`   // User identifier
+	Role                 string    `json:"role"`  // User role (admin, editor, viewer)
+	Type                 TokenType `json:"type"`  // Token type (access or refresh)
+	Nonce                string    `json:"nonce"` // Random value making every issued token unique
 }
 
 // JWTConfig holds the configuration for the JWT service
 type JWTConfig struct {
-	SigningKey         string        // Secret key used to sign tokens
+	SigningKey         string        // Static HS256 secret used to sign tokens if no Keyring is set
+	Keyring            Keyring       // Keyring providing rotating signing keys
 	AccessTokenExpiry  time.Duration // How long access tokens are valid
 	RefreshTokenExpiry time.Duration // How long refresh tokens are valid
 }
@@ -50,10 +53,13 @@ type jwtService struct {
 }
 
 // NewJWTService creates a new JWT service with the provided configuration
-// Returns an error if the signing key is missing
+// Returns an error if both the keyring and the signing key are missing
 func NewJWTService(config JWTConfig) (JWTManager, error) {
-	if config.SigningKey == "" {
-		return nil, fmt.Errorf("signing key is required")
+	if config.Keyring == nil {
+		if config.SigningKey == "" {
+			return nil, fmt.Errorf("signing key is required")
+		}
+		config.Keyring = NewStaticKeyring(config.SigningKey)
 	}
 
 	// Set default expiry times if not provided
@@ -97,10 +103,20 @@ func (s *jwtService) generateToken(userID int, role string, sessionID string, to
 		UserID: userID,
 		Role:   role,
 		Type:   tokenType,
+		Nonce:  hex.EncodeToString(nonce),
+	}
+
+	key, err := s.config.Keyring.ActiveKey()
+	if err != nil {
+		return "", fmt.Errorf("failed to get signing key: %w", err)
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signedToken, err := token.SignedString([]byte(s.config.SigningKey))
+	token := jwt.NewWithClaims(key.Method, claims)
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
+
+	signedToken, err := token.SignedString(key.SignKey)
 	if err != nil {
 		return "", err
 	}
@@ -113,11 +129,17 @@ func (s *jwtService) ValidateToken(tokenString string) (*Claims, error) {
 	log := getJWTLogger()
 
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		// Validate the signing method
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		kid, _ := token.Header["kid"].(string)
+		key, err := s.config.Keyring.Key(kid)
+		if err != nil {
+			return nil, err
+		}
+
+		// Validate the signing method against the key
+		if token.Method.Alg() != key.Method.Alg() {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return []byte(s.config.SigningKey), nil
+		return key.VerifyKey, nil
 	})
 
 	if err != nil {
@@ -0,0 +1,505 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"lemma/internal/db"
+	"lemma/internal/logging"
+	"lemma/internal/models"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func getKeyringLogger() logging.Logger {
+	return getAuthLogger().WithGroup("keyring")
+}
+
+// Supported JWT signing algorithms
+const (
+	AlgorithmHS256 = "HS256" // HMAC with SHA-256, tokens can only be verified by Lemma itself
+	AlgorithmRS256 = "RS256" // RSA PKCS#1 v1.5 with SHA-256
+	AlgorithmEdDSA = "EdDSA" // Ed25519 signatures
+)
+
+// LegacyKeyID is the ID of the key imported from the single JWT secret used before the keyring.
+// Tokens without a kid header are verified with this key.
+const LegacyKeyID = "legacy"
+
+// keyringRefreshInterval is how often the keyring reloads keys from the database to
+// pick up rotations made by other processes
+const keyringRefreshInterval = time.Minute
+
+// keyringMissReloadInterval is the minimum time between reloads for tokens with an unknown key ID,
+// so forged key IDs can't make every request load and decrypt all keys
+const keyringMissReloadInterval = 5 * time.Second
+
+// ValidAlgorithm reports whether algorithm is a supported JWT signing algorithm
+func ValidAlgorithm(algorithm string) bool {
+	switch algorithm {
+	case AlgorithmHS256, AlgorithmRS256, AlgorithmEdDSA:
+		return true
+	}
+	return false
+}
+
+// SigningKey is a parsed key of the keyring
+type SigningKey struct {
+	ID        string            // Key identifier, used as kid header
+	Method    jwt.SigningMethod // Signing method matching the key's algorithm
+	SignKey   interface{}       // Key used to sign tokens
+	VerifyKey interface{}       // Key used to verify tokens
+}
+
+// JWK is a public key in JSON Web Key format
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`   // RSA modulus
+	E         string `json:"e,omitempty"`   // RSA exponent
+	Curve     string `json:"crv,omitempty"` // Curve of OKP keys
+	X         string `json:"x,omitempty"`   // Ed25519 public key
+}
+
+// JWKSet is a set of public keys in JSON Web Key Set format
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// Keyring provides the keys used to sign and verify JWT tokens
+type Keyring interface {
+	ActiveKey() (*SigningKey, error)
+	Key(id string) (*SigningKey, error)
+	Rotate(algorithm string) (*models.JWTKey, error)
+	Keys() ([]*models.JWTKey, error)
+	JWKS() (*JWKSet, error)
+}
+
+// KeyringConfig holds the configuration for the database backed keyring
+type KeyringConfig struct {
+	Algorithm       string        // Algorithm for newly generated keys, HS256 if empty
+	RetentionPeriod time.Duration // How long a retired key keeps validating tokens, should cover the longest token expiry
+	LegacySecret    string        // JWT secret of previous versions, imported as the first key if set
+}
+
+// keyring is a Keyring storing keys in the database
+type keyring struct {
+	mu       sync.RWMutex
+	reloadMu sync.Mutex // Serializes reloads triggered by age, so concurrent callers reload once
+	store    db.JWTKeyStore
+	config   KeyringConfig
+	keys     []*models.JWTKey
+	parsed   map[string]*SigningKey
+	loadedAt time.Time
+}
+
+// NewKeyring creates a keyring backed by the database. If the database doesn't
+// contain any keys yet, the legacy secret is imported or a new key is generated.
+func NewKeyring(store db.JWTKeyStore, config KeyringConfig) (Keyring, error) {
+	log := getKeyringLogger()
+
+	if config.Algorithm == "" {
+		config.Algorithm = AlgorithmHS256
+	}
+	if !ValidAlgorithm(config.Algorithm) {
+		return nil, fmt.Errorf("unsupported signing algorithm: %s", config.Algorithm)
+	}
+	if config.RetentionPeriod == 0 {
+		config.RetentionPeriod = 7 * 24 * time.Hour
+	}
+
+	k := &keyring{
+		store:  store,
+		config: config,
+	}
+
+	if err := k.reload(); err != nil {
+		return nil, err
+	}
+
+	if len(k.keys) == 0 {
+		var key *models.JWTKey
+		if config.LegacySecret != "" {
+			log.Info("importing existing JWT secret into keyring")
+			key = &models.JWTKey{
+				ID:         LegacyKeyID,
+				Algorithm:  AlgorithmHS256,
+				PrivateKey: config.LegacySecret,
+				Active:     true,
+			}
+		} else {
+			log.Info("no JWT keys found, generating new key", "algorithm", config.Algorithm)
+			var err error
+			key, err = generateJWTKey(config.Algorithm)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if err := store.CreateJWTKey(key); err != nil {
+			return nil, fmt.Errorf("failed to store JWT key: %w", err)
+		}
+
+		if err := k.reload(); err != nil {
+			return nil, err
+		}
+	}
+
+	return k, nil
+}
+
+// ActiveKey returns the key new tokens are signed with
+func (k *keyring) ActiveKey() (*SigningKey, error) {
+	if err := k.reloadIfStale(); err != nil {
+		return nil, err
+	}
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	for _, key := range k.keys {
+		if key.Active {
+			return k.parsed[key.ID], nil
+		}
+	}
+
+	return nil, fmt.Errorf("no active JWT key")
+}
+
+// Key returns the key with the given ID if it may still be used to verify tokens.
+// An empty ID refers to the legacy key.
+func (k *keyring) Key(id string) (*SigningKey, error) {
+	if id == "" {
+		id = LegacyKeyID
+	}
+
+	key, ok := k.lookup(id)
+	if !ok {
+		// The key may have been created by another process
+		if err := k.reloadIfOlderThan(keyringMissReloadInterval); err != nil {
+			return nil, err
+		}
+		key, ok = k.lookup(id)
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("unknown key ID: %s", id)
+	}
+
+	return key, nil
+}
+
+// Rotate generates a new active key. Previously active keys keep validating tokens
+// until the retention period has passed.
+func (k *keyring) Rotate(algorithm string) (*models.JWTKey, error) {
+	log := getKeyringLogger()
+
+	if algorithm == "" {
+		algorithm = k.config.Algorithm
+	}
+
+	key, err := generateJWTKey(algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := k.store.CreateJWTKey(key); err != nil {
+		return nil, fmt.Errorf("failed to store JWT key: %w", err)
+	}
+
+	if err := k.store.DeleteRetiredJWTKeys(time.Now().Add(-k.config.RetentionPeriod)); err != nil {
+		log.Warn("failed to delete expired JWT keys", "error", err.Error())
+	}
+
+	if err := k.reload(); err != nil {
+		return nil, err
+	}
+
+	log.Info("rotated JWT signing key",
+		"keyId", key.ID,
+		"algorithm", key.Algorithm)
+
+	return key, nil
+}
+
+// Keys returns all keys that can still be used to verify tokens, newest first
+func (k *keyring) Keys() ([]*models.JWTKey, error) {
+	if err := k.reload(); err != nil {
+		return nil, err
+	}
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	keys := make([]*models.JWTKey, 0, len(k.keys))
+	keys = append(keys, k.keys...)
+	return keys, nil
+}
+
+// JWKS returns the public keys of all asymmetric keys that can still be used to verify tokens
+func (k *keyring) JWKS() (*JWKSet, error) {
+	if err := k.reloadIfStale(); err != nil {
+		return nil, err
+	}
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	set := &JWKSet{Keys: []JWK{}}
+	for _, key := range k.keys {
+		jwk, ok := publicJWK(k.parsed[key.ID])
+		if ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+
+	return set, nil
+}
+
+func (k *keyring) lookup(id string) (*SigningKey, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	key, ok := k.parsed[id]
+	return key, ok
+}
+
+func (k *keyring) reloadIfStale() error {
+	return k.reloadIfOlderThan(keyringRefreshInterval)
+}
+
+// reloadIfOlderThan reloads the keys if they were loaded longer than age ago
+func (k *keyring) reloadIfOlderThan(age time.Duration) error {
+	if !k.loadedBefore(age) {
+		return nil
+	}
+
+	k.reloadMu.Lock()
+	defer k.reloadMu.Unlock()
+
+	// Another caller may have reloaded while this one waited
+	if !k.loadedBefore(age) {
+		return nil
+	}
+	return k.reload()
+}
+
+func (k *keyring) loadedBefore(age time.Duration) bool {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	return time.Since(k.loadedAt) > age
+}
+
+// reload loads all keys that can still verify tokens from the database
+func (k *keyring) reload() error {
+	stored, err := k.store.GetJWTKeys()
+	if err != nil {
+		return fmt.Errorf("failed to load JWT keys: %w", err)
+	}
+
+	cutoff := time.Now().Add(-k.config.RetentionPeriod)
+	keys := make([]*models.JWTKey, 0, len(stored))
+	parsed := make(map[string]*SigningKey, len(stored))
+	for _, key := range stored {
+		if !key.Active && key.RetiredAt != nil && key.RetiredAt.Before(cutoff) {
+			continue
+		}
+
+		signingKey, err := parseJWTKey(key)
+		if err != nil {
+			return err
+		}
+
+		keys = append(keys, key)
+		parsed[key.ID] = signingKey
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.keys = keys
+	k.parsed = parsed
+	k.loadedAt = time.Now()
+	return nil
+}
+
+// staticKeyring is a Keyring with a single, fixed HS256 key that can't be rotated
+type staticKeyring struct {
+	key *SigningKey
+}
+
+// NewStaticKeyring creates a keyring with a single HS256 secret, used when the
+// signing key is provided by configuration
+func NewStaticKeyring(secret string) Keyring {
+	return &staticKeyring{
+		key: &SigningKey{
+			ID:        "",
+			Method:    jwt.SigningMethodHS256,
+			SignKey:   []byte(secret),
+			VerifyKey: []byte(secret),
+		},
+	}
+}
+
+// ActiveKey returns the static key
+func (k *staticKeyring) ActiveKey() (*SigningKey, error) {
+	return k.key, nil
+}
+
+// Key returns the static key for tokens without a key ID
+func (k *staticKeyring) Key(id string) (*SigningKey, error) {
+	if id != k.key.ID {
+		return nil, fmt.Errorf("unknown key ID: %s", id)
+	}
+	return k.key, nil
+}
+
+// Rotate is not supported for a static key
+func (k *staticKeyring) Rotate(_ string) (*models.JWTKey, error) {
+	return nil, fmt.Errorf("signing key is set by configuration and can't be rotated")
+}
+
+// Keys returns no keys, the static key is not stored
+func (k *staticKeyring) Keys() ([]*models.JWTKey, error) {
+	return []*models.JWTKey{}, nil
+}
+
+// JWKS returns an empty set, HS256 keys can't be published
+func (k *staticKeyring) JWKS() (*JWKSet, error) {
+	return &JWKSet{Keys: []JWK{}}, nil
+}
+
+// generateJWTKey creates a new active key for the given algorithm
+func generateJWTKey(algorithm string) (*models.JWTKey, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("failed to generate key ID: %w", err)
+	}
+
+	key := &models.JWTKey{
+		ID:        hex.EncodeToString(id),
+		Algorithm: algorithm,
+		Active:    true,
+		CreatedAt: time.Now(),
+	}
+
+	var privateKey, publicKey interface{}
+	switch algorithm {
+	case AlgorithmHS256:
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, fmt.Errorf("failed to generate secret: %w", err)
+		}
+		key.PrivateKey = base64.StdEncoding.EncodeToString(secret)
+		return key, nil
+	case AlgorithmRS256:
+		rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate RSA key: %w", err)
+		}
+		privateKey, publicKey = rsaKey, &rsaKey.PublicKey
+	case AlgorithmEdDSA:
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate Ed25519 key: %w", err)
+		}
+		privateKey, publicKey = priv, pub
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm: %s", algorithm)
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode private key: %w", err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode public key: %w", err)
+	}
+
+	key.PrivateKey = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}))
+	key.PublicKey = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}))
+	return key, nil
+}
+
+// parseJWTKey converts a stored key into a SigningKey
+func parseJWTKey(key *models.JWTKey) (*SigningKey, error) {
+	if key.Algorithm == AlgorithmHS256 {
+		return &SigningKey{
+			ID:        key.ID,
+			Method:    jwt.SigningMethodHS256,
+			SignKey:   []byte(key.PrivateKey),
+			VerifyKey: []byte(key.PrivateKey),
+		}, nil
+	}
+
+	block, _ := pem.Decode([]byte(key.PrivateKey))
+	if block == nil {
+		return nil, fmt.Errorf("invalid private key of JWT key %s", key.ID)
+	}
+	privateKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key of JWT key %s: %w", key.ID, err)
+	}
+
+	switch priv := privateKey.(type) {
+	case *rsa.PrivateKey:
+		if key.Algorithm != AlgorithmRS256 {
+			break
+		}
+		return &SigningKey{
+			ID:        key.ID,
+			Method:    jwt.SigningMethodRS256,
+			SignKey:   priv,
+			VerifyKey: &priv.PublicKey,
+		}, nil
+	case ed25519.PrivateKey:
+		if key.Algorithm != AlgorithmEdDSA {
+			break
+		}
+		return &SigningKey{
+			ID:        key.ID,
+			Method:    jwt.SigningMethodEdDSA,
+			SignKey:   priv,
+			VerifyKey: priv.Public(),
+		}, nil
+	}
+
+	return nil, fmt.Errorf("private key of JWT key %s doesn't match algorithm %s", key.ID, key.Algorithm)
+}
+
+// publicJWK returns the public part of an asymmetric key as JWK
+func publicJWK(key *SigningKey) (JWK, bool) {
+	switch pub := key.VerifyKey.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			KeyType:   "RSA",
+			KeyID:     key.ID,
+			Use:       "sig",
+			Algorithm: AlgorithmRS256,
+			N:         base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}, true
+	case ed25519.PublicKey:
+		return JWK{
+			KeyType:   "OKP",
+			KeyID:     key.ID,
+			Use:       "sig",
+			Algorithm: AlgorithmEdDSA,
+			Curve:     "Ed25519",
+			X:         base64.RawURLEncoding.EncodeToString(pub),
+		}, true
+	}
+	return JWK{}, false
+}
@@ -0,0 +1,266 @@
+package auth_test
+
+import (
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"lemma/internal/auth"
+	"lemma/internal/models"
+	_ "lemma/internal/testenv"
+)
+
+// Mock JWTKeyStore
+type mockJWTKeyStore struct {
+	keys  map[string]*models.JWTKey
+	loads int // Number of GetJWTKeys calls
+}
+
+func newMockJWTKeyStore() *mockJWTKeyStore {
+	return &mockJWTKeyStore{
+		keys: make(map[string]*models.JWTKey),
+	}
+}
+
+func (m *mockJWTKeyStore) CreateJWTKey(key *models.JWTKey) error {
+	if _, exists := m.keys[key.ID]; exists {
+		return errors.New("key already exists")
+	}
+	if key.CreatedAt.IsZero() {
+		key.CreatedAt = time.Now()
+	}
+	if key.Active {
+		for _, existing := range m.keys {
+			if existing.Active {
+				retiredAt := key.CreatedAt
+				existing.Active = false
+				existing.RetiredAt = &retiredAt
+			}
+		}
+	}
+	copied := *key
+	m.keys[key.ID] = &copied
+	return nil
+}
+
+func (m *mockJWTKeyStore) GetJWTKeys() ([]*models.JWTKey, error) {
+	m.loads++
+	keys := make([]*models.JWTKey, 0, len(m.keys))
+	for _, key := range m.keys {
+		copied := *key
+		keys = append(keys, &copied)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.After(keys[j].CreatedAt) })
+	return keys, nil
+}
+
+func (m *mockJWTKeyStore) DeleteRetiredJWTKeys(retiredBefore time.Time) error {
+	for id, key := range m.keys {
+		if !key.Active && key.RetiredAt != nil && !key.RetiredAt.After(retiredBefore) {
+			delete(m.keys, id)
+		}
+	}
+	return nil
+}
+
+func TestNewKeyring(t *testing.T) {
+	t.Run("generates key with configured algorithm", func(t *testing.T) {
+		for _, algorithm := range []string{auth.AlgorithmHS256, auth.AlgorithmRS256, auth.AlgorithmEdDSA} {
+			t.Run(algorithm, func(t *testing.T) {
+				store := newMockJWTKeyStore()
+				keyring, err := auth.NewKeyring(store, auth.KeyringConfig{Algorithm: algorithm})
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				key, err := keyring.ActiveKey()
+				if err != nil {
+					t.Fatalf("failed to get active key: %v", err)
+				}
+				if key.Method.Alg() != algorithm {
+					t.Errorf("algorithm = %v, want %v", key.Method.Alg(), algorithm)
+				}
+				if len(store.keys) != 1 {
+					t.Errorf("stored keys = %d, want 1", len(store.keys))
+				}
+			})
+		}
+	})
+
+	t.Run("imports legacy secret", func(t *testing.T) {
+		// Tokens issued before the keyring have no kid header
+		legacyService, _ := auth.NewJWTService(auth.JWTConfig{SigningKey: "legacy-secret"})
+		token, err := legacyService.GenerateAccessToken(1, "admin", "session")
+		if err != nil {
+			t.Fatalf("failed to generate token: %v", err)
+		}
+
+		keyring, err := auth.NewKeyring(newMockJWTKeyStore(), auth.KeyringConfig{
+			Algorithm:    auth.AlgorithmEdDSA,
+			LegacySecret: "legacy-secret",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		service, _ := auth.NewJWTService(auth.JWTConfig{Keyring: keyring})
+		if _, err := service.ValidateToken(token); err != nil {
+			t.Errorf("legacy token rejected: %v", err)
+		}
+	})
+
+	t.Run("keeps existing keys", func(t *testing.T) {
+		store := newMockJWTKeyStore()
+		first, err := auth.NewKeyring(store, auth.KeyringConfig{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		firstKey, _ := first.ActiveKey()
+
+		second, err := auth.NewKeyring(store, auth.KeyringConfig{LegacySecret: "ignored"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		secondKey, _ := second.ActiveKey()
+
+		if firstKey.ID != secondKey.ID {
+			t.Errorf("active key = %v, want %v", secondKey.ID, firstKey.ID)
+		}
+	})
+
+	t.Run("rejects unsupported algorithm", func(t *testing.T) {
+		if _, err := auth.NewKeyring(newMockJWTKeyStore(), auth.KeyringConfig{Algorithm: "none"}); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+}
+
+func TestKeyringRotate(t *testing.T) {
+	store := newMockJWTKeyStore()
+	keyring, err := auth.NewKeyring(store, auth.KeyringConfig{RetentionPeriod: time.Hour})
+	if err != nil {
+		t.Fatalf("failed to create keyring: %v", err)
+	}
+	service, _ := auth.NewJWTService(auth.JWTConfig{Keyring: keyring})
+
+	oldToken, err := service.GenerateAccessToken(1, "admin", "session")
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	oldKey, _ := keyring.ActiveKey()
+
+	newKey, err := keyring.Rotate(auth.AlgorithmRS256)
+	if err != nil {
+		t.Fatalf("failed to rotate key: %v", err)
+	}
+
+	t.Run("new tokens use the new key", func(t *testing.T) {
+		active, err := keyring.ActiveKey()
+		if err != nil {
+			t.Fatalf("failed to get active key: %v", err)
+		}
+		if active.ID != newKey.ID || active.ID == oldKey.ID {
+			t.Errorf("active key = %v, want %v", active.ID, newKey.ID)
+		}
+
+		token, err := service.GenerateAccessToken(1, "admin", "session")
+		if err != nil {
+			t.Fatalf("failed to generate token: %v", err)
+		}
+		if _, err := service.ValidateToken(token); err != nil {
+			t.Errorf("new token rejected: %v", err)
+		}
+	})
+
+	t.Run("old tokens stay valid", func(t *testing.T) {
+		if _, err := service.ValidateToken(oldToken); err != nil {
+			t.Errorf("old token rejected after rotation: %v", err)
+		}
+	})
+
+	t.Run("lists retired keys", func(t *testing.T) {
+		keys, err := keyring.Keys()
+		if err != nil {
+			t.Fatalf("failed to list keys: %v", err)
+		}
+		if len(keys) != 2 {
+			t.Fatalf("keys = %d, want 2", len(keys))
+		}
+		if !keys[0].Active || keys[1].Active || keys[1].RetiredAt == nil {
+			t.Errorf("expected newest key to be active and the old key to be retired")
+		}
+	})
+
+	t.Run("publishes asymmetric keys only", func(t *testing.T) {
+		jwks, err := keyring.JWKS()
+		if err != nil {
+			t.Fatalf("failed to get JWKS: %v", err)
+		}
+		if len(jwks.Keys) != 1 {
+			t.Fatalf("JWKS keys = %d, want 1", len(jwks.Keys))
+		}
+		jwk := jwks.Keys[0]
+		if jwk.KeyID != newKey.ID || jwk.KeyType != "RSA" || jwk.N == "" || jwk.E == "" {
+			t.Errorf("unexpected JWK: %+v", jwk)
+		}
+	})
+
+	t.Run("expired keys stop validating", func(t *testing.T) {
+		retiredAt := time.Now().Add(-2 * time.Hour)
+		store.keys[oldKey.ID].RetiredAt = &retiredAt
+
+		// A fresh keyring doesn't have the old key cached
+		reloaded, err := auth.NewKeyring(store, auth.KeyringConfig{RetentionPeriod: time.Hour})
+		if err != nil {
+			t.Fatalf("failed to create keyring: %v", err)
+		}
+		reloadedService, _ := auth.NewJWTService(auth.JWTConfig{Keyring: reloaded})
+		if _, err := reloadedService.ValidateToken(oldToken); err == nil {
+			t.Error("expected token of expired key to be rejected")
+		}
+	})
+}
+
+func TestKeyringUnknownKey(t *testing.T) {
+	store := newMockJWTKeyStore()
+	keyring, err := auth.NewKeyring(store, auth.KeyringConfig{})
+	if err != nil {
+		t.Fatalf("failed to create keyring: %v", err)
+	}
+	loads := store.loads
+
+	// Unknown key IDs are rejected without loading the keys again on every lookup
+	for i := 0; i < 10; i++ {
+		if _, err := keyring.Key("forged"); err == nil {
+			t.Fatal("expected unknown key ID to be rejected")
+		}
+	}
+	if store.loads != loads {
+		t.Errorf("keys loaded %d times for unknown key IDs, want 0", store.loads-loads)
+	}
+
+	active, err := keyring.ActiveKey()
+	if err != nil {
+		t.Fatalf("failed to get active key: %v", err)
+	}
+	if _, err := keyring.Key(active.ID); err != nil {
+		t.Errorf("Key(%s) error = %v", active.ID, err)
+	}
+}
+
+func TestStaticKeyring(t *testing.T) {
+	keyring := auth.NewStaticKeyring("test-key")
+
+	if _, err := keyring.Rotate(auth.AlgorithmHS256); err == nil {
+		t.Error("expected rotation of static key to fail")
+	}
+
+	jwks, err := keyring.JWKS()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(jwks.Keys) != 0 {
+		t.Errorf("JWKS keys = %d, want 0", len(jwks.Keys))
+	}
+}
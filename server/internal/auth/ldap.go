@@ -0,0 +1,250 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"lemma/internal/db"
+	"lemma/internal/logging"
+	"lemma/internal/models"
+	"lemma/internal/storage"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func getLDAPLogger() logging.Logger {
+	return getAuthenticatorLogger().WithGroup("ldap")
+}
+
+// LDAPConfig holds the configuration for authenticating users against an LDAP directory
+type LDAPConfig struct {
+	URL                  string // ldap:// or ldaps:// URL of the directory server
+	StartTLS             bool   // Upgrade ldap:// connections with StartTLS
+	BindDN               string // Service account used to search for users, anonymous if empty
+	BindPassword         string
+	BaseDN               string // Subtree users are searched in
+	UserFilter           string // Filter for finding a user, %s is replaced with the escaped email
+	DisplayNameAttribute string
+	GroupAttribute       string // Attribute listing the DNs of the user's groups
+	AdminGroup           string // Members get the admin role
+	EditorGroup          string // Members get the editor role
+	ViewerGroup          string // Members get the viewer role
+	Timeout              time.Duration
+}
+
+// ldapAuthenticator verifies credentials by binding as the user's directory entry
+type ldapAuthenticator struct {
+	config     LDAPConfig
+	db         db.UserStore
+	workspaces storage.WorkspaceManager
+}
+
+// NewLDAPAuthenticator creates an authenticator for users in an LDAP directory, using defaults
+// for unset config values. Users are created locally on their first login and their role is
+// updated from their group membership on every login.
+func NewLDAPAuthenticator(config LDAPConfig, db db.UserStore, workspaces storage.WorkspaceManager) Authenticator {
+	if config.UserFilter == "" {
+		config.UserFilter = "(mail=%s)"
+	}
+	if config.DisplayNameAttribute == "" {
+		config.DisplayNameAttribute = "cn"
+	}
+	if config.GroupAttribute == "" {
+		config.GroupAttribute = "memberOf"
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 10 * time.Second
+	}
+
+	return &ldapAuthenticator{
+		config:     config,
+		db:         db,
+		workspaces: workspaces,
+	}
+}
+
+// Authenticate looks up the user's entry, binds with the given password and syncs the local user
+func (a *ldapAuthenticator) Authenticate(email, password string) (*models.User, error) {
+	log := getLDAPLogger()
+
+	// An empty password would be an unauthenticated bind, which many servers accept
+	if password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	// Accounts created locally are left to the local authenticator, so a directory
+	// entry with the same mail can't take them over
+	existing, err := a.db.GetUserByEmail(email)
+	if err != nil {
+		existing = nil
+	} else if existing.AuthSource != models.AuthSourceLDAP {
+		log.Debug("user is not managed by LDAP", "email", email)
+		return nil, ErrUnknownUser
+	}
+
+	conn, err := a.connect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if a.config.BindDN != "" {
+		if err := conn.Bind(a.config.BindDN, a.config.BindPassword); err != nil {
+			return nil, fmt.Errorf("failed to bind LDAP service account: %w", err)
+		}
+	}
+
+	result, err := conn.Search(ldap.NewSearchRequest(
+		a.config.BaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, int(a.config.Timeout.Seconds()), false,
+		fmt.Sprintf(a.config.UserFilter, ldap.EscapeFilter(email)),
+		[]string{a.config.DisplayNameAttribute, a.config.GroupAttribute},
+		nil,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to search LDAP user: %w", err)
+	}
+
+	if len(result.Entries) == 0 {
+		log.Debug("user not found in directory", "email", email)
+		return nil, ErrUnknownUser
+	}
+	if len(result.Entries) > 1 {
+		return nil, fmt.Errorf("LDAP user filter matched %d entries for %s", len(result.Entries), email)
+	}
+	entry := result.Entries[0]
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("failed to bind LDAP user: %w", err)
+	}
+
+	role, ok := a.roleForGroups(entry.GetAttributeValues(a.config.GroupAttribute))
+	if !ok {
+		log.Warn("LDAP user is not a member of any group with access",
+			"email", email,
+			"dn", entry.DN)
+		return nil, ErrInvalidCredentials
+	}
+
+	return a.syncUser(existing, email, entry.GetAttributeValue(a.config.DisplayNameAttribute), role)
+}
+
+func (a *ldapAuthenticator) connect() (*ldap.Conn, error) {
+	conn, err := ldap.DialURL(a.config.URL, ldap.DialWithDialer(&net.Dialer{Timeout: a.config.Timeout}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to LDAP server: %w", err)
+	}
+	conn.SetTimeout(a.config.Timeout)
+
+	if a.config.StartTLS {
+		parsed, err := url.Parse(a.config.URL)
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("invalid LDAP URL: %w", err)
+		}
+		if err := conn.StartTLS(&tls.Config{ServerName: parsed.Hostname()}); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+
+	return conn, nil
+}
+
+// roleForGroups returns the most privileged role the groups grant. Without configured
+// groups every directory user gets the editor role.
+func (a *ldapAuthenticator) roleForGroups(groups []string) (models.UserRole, bool) {
+	if a.config.AdminGroup == "" && a.config.EditorGroup == "" && a.config.ViewerGroup == "" {
+		return models.RoleEditor, true
+	}
+
+	isMember := func(group string) bool {
+		if group == "" {
+			return false
+		}
+		for _, g := range groups {
+			if strings.EqualFold(g, group) {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch {
+	case isMember(a.config.AdminGroup):
+		return models.RoleAdmin, true
+	case isMember(a.config.EditorGroup):
+		return models.RoleEditor, true
+	case isMember(a.config.ViewerGroup):
+		return models.RoleViewer, true
+	}
+	return "", false
+}
+
+// syncUser creates the local user on first login or updates the role and display name
+// of the user LDAP created before
+func (a *ldapAuthenticator) syncUser(user *models.User, email, displayName string, role models.UserRole) (*models.User, error) {
+	log := getLDAPLogger()
+
+	if user == nil {
+		// Directory users can't log in with a local password
+		randomPassword := make([]byte, 32)
+		if _, err := rand.Read(randomPassword); err != nil {
+			return nil, fmt.Errorf("failed to generate password: %w", err)
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(base64.StdEncoding.EncodeToString(randomPassword)), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash password: %w", err)
+		}
+
+		user, err = a.db.CreateUser(&models.User{
+			Email:        email,
+			DisplayName:  displayName,
+			PasswordHash: string(hash),
+			Role:         role,
+			AuthSource:   models.AuthSourceLDAP,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create LDAP user: %w", err)
+		}
+
+		if err := a.workspaces.InitializeUserWorkspace(user.ID, user.LastWorkspaceID); err != nil {
+			return nil, fmt.Errorf("failed to initialize user workspace: %w", err)
+		}
+
+		log.Info("created user from LDAP directory",
+			"userId", user.ID,
+			"email", email,
+			"role", role)
+		return user, nil
+	}
+
+	if user.Role == role && (displayName == "" || user.DisplayName == displayName) {
+		return user, nil
+	}
+
+	if user.Role != role {
+		log.Info("updating role of LDAP user",
+			"userId", user.ID,
+			"oldRole", user.Role,
+			"newRole", role)
+	}
+	user.Role = role
+	if displayName != "" {
+		user.DisplayName = displayName
+	}
+	if err := a.db.UpdateUser(user); err != nil {
+		return nil, fmt.Errorf("failed to update LDAP user: %w", err)
+	}
+
+	return user, nil
+}
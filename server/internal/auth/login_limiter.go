@@ -0,0 +1,197 @@
+package auth
+
+import (
+	"fmt"
+	"lemma/internal/db"
+	"lemma/internal/logging"
+	"lemma/internal/models"
+	"strings"
+	"time"
+)
+
+func getLoginLimiterLogger() logging.Logger {
+	return getAuthLogger().WithGroup("loginlimiter")
+}
+
+// LoginLimiterConfig holds the configuration for brute-force protection of the login endpoint
+type LoginLimiterConfig struct {
+	FreeAttempts     int           // Failed attempts allowed before delays are enforced
+	BaseDelay        time.Duration // Delay after the first attempt beyond FreeAttempts, doubled for each further failure
+	MaxDelay         time.Duration // Upper bound for the progressive delay
+	MaxEmailAttempts int           // Failed attempts for one email before the account is locked
+	MaxIPAttempts    int           // Failed attempts from one IP before the IP is locked
+	LockoutDuration  time.Duration // How long a lockout lasts
+	FailureWindow    time.Duration // Failures older than this are forgotten
+}
+
+// LoginLimiter tracks failed logins per email and per client IP
+type LoginLimiter interface {
+	// Check returns how long the caller has to wait before a login attempt
+	// for email from ip is allowed. A zero duration means the attempt is allowed.
+	Check(email, ip string) (time.Duration, error)
+	// RecordFailure counts a failed login and returns the attempts it locked out
+	RecordFailure(email, ip string) ([]*models.LoginAttempt, error)
+	RecordSuccess(email string) error
+	Unlock(email string) error
+}
+
+// loginLimiter is a database backed LoginLimiter
+type loginLimiter struct {
+	db     db.LoginAttemptStore
+	config LoginLimiterConfig
+}
+
+// NewLoginLimiter creates a new login limiter, using defaults for unset config values
+func NewLoginLimiter(db db.LoginAttemptStore, config LoginLimiterConfig) LoginLimiter {
+	if config.FreeAttempts == 0 {
+		config.FreeAttempts = 3
+	}
+	if config.BaseDelay == 0 {
+		config.BaseDelay = time.Second
+	}
+	if config.MaxDelay == 0 {
+		config.MaxDelay = time.Minute
+	}
+	if config.MaxEmailAttempts == 0 {
+		config.MaxEmailAttempts = 10
+	}
+	if config.MaxIPAttempts == 0 {
+		config.MaxIPAttempts = 50
+	}
+	if config.LockoutDuration == 0 {
+		config.LockoutDuration = 15 * time.Minute
+	}
+	if config.FailureWindow == 0 {
+		config.FailureWindow = time.Hour
+	}
+
+	return &loginLimiter{
+		db:     db,
+		config: config,
+	}
+}
+
+// Check returns the remaining wait time for a login attempt, considering both the email and the IP
+func (l *loginLimiter) Check(email, ip string) (time.Duration, error) {
+	now := time.Now()
+
+	var wait time.Duration
+	for _, key := range l.keys(email, ip) {
+		attempt, err := l.db.GetLoginAttempt(key.scope, key.value)
+		if err != nil {
+			return 0, err
+		}
+
+		if w := l.waitTime(attempt, now); w > wait {
+			wait = w
+		}
+	}
+
+	return wait, nil
+}
+
+// RecordFailure counts a failed login for the email and IP and locks them out once
+// the configured maximum is reached. It returns the attempts that were locked out by this failure.
+func (l *loginLimiter) RecordFailure(email, ip string) ([]*models.LoginAttempt, error) {
+	log := getLoginLimiterLogger()
+	now := time.Now()
+
+	var lockouts []*models.LoginAttempt
+	for _, key := range l.keys(email, ip) {
+		// The count is incremented in a single statement, so concurrent failures can't overwrite each other
+		attempt, err := l.db.RecordLoginFailure(key.scope, key.value, now, now.Add(-l.config.FailureWindow))
+		if err != nil {
+			return nil, err
+		}
+
+		log.Debug("recorded failed login",
+			"scope", key.scope,
+			"key", key.value,
+			"failedCount", attempt.FailedCount)
+
+		if !attempt.LockedUntil.IsZero() || attempt.FailedCount < l.maxAttempts(key.scope) {
+			continue
+		}
+
+		lockedUntil := now.Add(l.config.LockoutDuration)
+		locked, err := l.db.LockLogin(key.scope, key.value, lockedUntil)
+		if err != nil {
+			return nil, err
+		}
+		if locked {
+			log.Debug("locked out login",
+				"scope", key.scope,
+				"key", key.value,
+				"lockedUntil", lockedUntil)
+			attempt.LockedUntil = lockedUntil
+			lockouts = append(lockouts, attempt)
+		}
+	}
+
+	return lockouts, nil
+}
+
+// RecordSuccess clears the failures recorded for an email after a successful login.
+// Failures recorded for the client IP are kept so a valid account can't be used to
+// reset the IP counter.
+func (l *loginLimiter) RecordSuccess(email string) error {
+	return l.db.DeleteLoginAttempt(models.LoginAttemptScopeEmail, normalizeEmail(email))
+}
+
+// Unlock lifts a lockout of an email and clears its failures
+func (l *loginLimiter) Unlock(email string) error {
+	if err := l.db.DeleteLoginAttempt(models.LoginAttemptScopeEmail, normalizeEmail(email)); err != nil {
+		return fmt.Errorf("failed to unlock login: %w", err)
+	}
+
+	return nil
+}
+
+// waitTime returns how long the next attempt has to wait based on the recorded failures
+func (l *loginLimiter) waitTime(attempt *models.LoginAttempt, now time.Time) time.Duration {
+	if attempt.LockedUntil.After(now) {
+		return attempt.LockedUntil.Sub(now)
+	}
+
+	if !attempt.LockedUntil.IsZero() || now.Sub(attempt.LastFailedAt) > l.config.FailureWindow {
+		return 0
+	}
+
+	excess := attempt.FailedCount - l.config.FreeAttempts
+	if excess < 0 {
+		return 0
+	}
+
+	// Double the delay for every failure beyond the free attempts
+	delay := l.config.BaseDelay
+	for i := 0; i < excess && delay < l.config.MaxDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, l.config.MaxDelay)
+
+	return max(attempt.LastFailedAt.Add(delay).Sub(now), 0)
+}
+
+func (l *loginLimiter) maxAttempts(scope models.LoginAttemptScope) int {
+	if scope == models.LoginAttemptScopeIP {
+		return l.config.MaxIPAttempts
+	}
+	return l.config.MaxEmailAttempts
+}
+
+type loginAttemptKey struct {
+	scope models.LoginAttemptScope
+	value string
+}
+
+func (l *loginLimiter) keys(email, ip string) []loginAttemptKey {
+	keys := []loginAttemptKey{{models.LoginAttemptScopeEmail, normalizeEmail(email)}}
+	if ip != "" {
+		keys = append(keys, loginAttemptKey{models.LoginAttemptScopeIP, ip})
+	}
+	return keys
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,219 @@
+package auth_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"lemma/internal/auth"
+	"lemma/internal/models"
+	_ "lemma/internal/testenv"
+)
+
+// Mock LoginAttemptStore
+type mockLoginAttemptStore struct {
+	mu       sync.Mutex
+	attempts map[models.LoginAttemptScope]map[string]*models.LoginAttempt
+}
+
+func newMockLoginAttemptStore() *mockLoginAttemptStore {
+	return &mockLoginAttemptStore{
+		attempts: map[models.LoginAttemptScope]map[string]*models.LoginAttempt{
+			models.LoginAttemptScopeEmail: {},
+			models.LoginAttemptScopeIP:    {},
+		},
+	}
+}
+
+func (m *mockLoginAttemptStore) GetLoginAttempt(scope models.LoginAttemptScope, key string) (*models.LoginAttempt, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if attempt, exists := m.attempts[scope][key]; exists {
+		copied := *attempt
+		return &copied, nil
+	}
+	return &models.LoginAttempt{Scope: scope, Key: key}, nil
+}
+
+func (m *mockLoginAttemptStore) RecordLoginFailure(scope models.LoginAttemptScope, key string, now, staleBefore time.Time) (*models.LoginAttempt, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	attempt, exists := m.attempts[scope][key]
+	if !exists {
+		attempt = &models.LoginAttempt{Scope: scope, Key: key}
+		m.attempts[scope][key] = attempt
+	}
+	if attempt.LastFailedAt.Before(staleBefore) || (!attempt.LockedUntil.IsZero() && !now.Before(attempt.LockedUntil)) {
+		attempt.FailedCount = 0
+		attempt.LockedUntil = time.Time{}
+	}
+	attempt.FailedCount++
+	attempt.LastFailedAt = now
+	copied := *attempt
+	return &copied, nil
+}
+
+func (m *mockLoginAttemptStore) LockLogin(scope models.LoginAttemptScope, key string, until time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	attempt, exists := m.attempts[scope][key]
+	if !exists || !attempt.LockedUntil.IsZero() {
+		return false, nil
+	}
+	attempt.LockedUntil = until
+	return true, nil
+}
+
+func (m *mockLoginAttemptStore) DeleteLoginAttempt(scope models.LoginAttemptScope, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.attempts[scope], key)
+	return nil
+}
+
+func TestLoginLimiter(t *testing.T) {
+	config := auth.LoginLimiterConfig{
+		FreeAttempts:     2,
+		BaseDelay:        time.Minute,
+		MaxDelay:         4 * time.Minute,
+		MaxEmailAttempts: 5,
+		MaxIPAttempts:    8,
+		LockoutDuration:  time.Hour,
+	}
+
+	recordFailures := func(t *testing.T, limiter auth.LoginLimiter, email, ip string, n int) {
+		t.Helper()
+		for i := 0; i < n; i++ {
+			if _, err := limiter.RecordFailure(email, ip); err != nil {
+				t.Fatalf("failed to record failure: %v", err)
+			}
+		}
+	}
+
+	checkWait := func(t *testing.T, limiter auth.LoginLimiter, email, ip string) time.Duration {
+		t.Helper()
+		wait, err := limiter.Check(email, ip)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return wait
+	}
+
+	t.Run("free attempts are not delayed", func(t *testing.T) {
+		limiter := auth.NewLoginLimiter(newMockLoginAttemptStore(), config)
+
+		recordFailures(t, limiter, "user@example.com", "10.0.0.1", 1)
+		if wait := checkWait(t, limiter, "user@example.com", "10.0.0.1"); wait != 0 {
+			t.Errorf("wait = %v, want 0", wait)
+		}
+	})
+
+	t.Run("delays grow with each failure", func(t *testing.T) {
+		limiter := auth.NewLoginLimiter(newMockLoginAttemptStore(), config)
+
+		tests := []struct {
+			failures int
+			maxWait  time.Duration
+		}{
+			{2, time.Minute},
+			{3, 2 * time.Minute},
+			{4, 4 * time.Minute},
+		}
+
+		failures := 0
+		for _, tt := range tests {
+			recordFailures(t, limiter, "user@example.com", "10.0.0.1", tt.failures-failures)
+			failures = tt.failures
+
+			wait := checkWait(t, limiter, "user@example.com", "10.0.0.1")
+			if wait <= tt.maxWait/2 || wait > tt.maxWait {
+				t.Errorf("after %d failures wait = %v, want close to %v", tt.failures, wait, tt.maxWait)
+			}
+		}
+	})
+
+	t.Run("email is locked after max attempts", func(t *testing.T) {
+		limiter := auth.NewLoginLimiter(newMockLoginAttemptStore(), config)
+
+		recordFailures(t, limiter, "User@Example.com ", "10.0.0.1", 5)
+
+		// The lockout applies to the account from any IP, regardless of email casing
+		wait := checkWait(t, limiter, "user@example.com", "10.0.0.2")
+		if wait <= 55*time.Minute {
+			t.Errorf("wait = %v, want lockout of about an hour", wait)
+		}
+
+		// Unlocking lifts the account lockout
+		if err := limiter.Unlock("user@example.com"); err != nil {
+			t.Fatalf("failed to unlock: %v", err)
+		}
+		if wait := checkWait(t, limiter, "user@example.com", "10.0.0.2"); wait != 0 {
+			t.Errorf("wait after unlock = %v, want 0", wait)
+		}
+	})
+
+	t.Run("ip is locked across accounts", func(t *testing.T) {
+		limiter := auth.NewLoginLimiter(newMockLoginAttemptStore(), config)
+
+		for i := 0; i < 8; i++ {
+			recordFailures(t, limiter, string(rune('a'+i))+"@example.com", "10.0.0.3", 1)
+		}
+
+		wait := checkWait(t, limiter, "fresh@example.com", "10.0.0.3")
+		if wait <= 55*time.Minute {
+			t.Errorf("wait = %v, want lockout of about an hour", wait)
+		}
+
+		if wait := checkWait(t, limiter, "fresh@example.com", "10.0.0.4"); wait != 0 {
+			t.Errorf("wait from other ip = %v, want 0", wait)
+		}
+	})
+
+	t.Run("success resets email failures only", func(t *testing.T) {
+		limiter := auth.NewLoginLimiter(newMockLoginAttemptStore(), config)
+
+		recordFailures(t, limiter, "user@example.com", "10.0.0.5", 3)
+		if err := limiter.RecordSuccess("user@example.com"); err != nil {
+			t.Fatalf("failed to record success: %v", err)
+		}
+
+		if wait := checkWait(t, limiter, "user@example.com", "10.0.0.6"); wait != 0 {
+			t.Errorf("wait for email = %v, want 0", wait)
+		}
+		if wait := checkWait(t, limiter, "other@example.com", "10.0.0.5"); wait == 0 {
+			t.Error("expected ip failures to be kept after a successful login")
+		}
+	})
+
+	t.Run("concurrent failures are all counted", func(t *testing.T) {
+		limiter := auth.NewLoginLimiter(newMockLoginAttemptStore(), config)
+
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		var lockouts []*models.LoginAttempt
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				locked, err := limiter.RecordFailure("user@example.com", "10.0.0.7")
+				if err != nil {
+					t.Errorf("failed to record failure: %v", err)
+				}
+				mu.Lock()
+				lockouts = append(lockouts, locked...)
+				mu.Unlock()
+			}()
+		}
+		wg.Wait()
+
+		// Only the failure reaching the maximum starts the lockout
+		if len(lockouts) != 1 || lockouts[0].Scope != models.LoginAttemptScopeEmail {
+			t.Errorf("lockouts = %+v, want one lockout of the email", lockouts)
+		}
+
+		wait := checkWait(t, limiter, "user@example.com", "10.0.0.8")
+		if wait <= 55*time.Minute {
+			t.Errorf("wait = %v, want lockout of about an hour", wait)
+		}
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"crypto/subtle"
 	"lemma/internal/context"
 	"lemma/internal/logging"
+	"lemma/internal/models"
 	"net/http"
 )
 
@@ -11,19 +12,26 @@ func getMiddlewareLogger() logging.Logger {
 	return getAuthLogger().WithGroup("middleware")
 }
 
+// UserProvider provides the users that requests are authenticated for
+type UserProvider interface {
+	GetUserByID(id int) (*models.User, error)
+}
+
 // Middleware handles JWT authentication for protected routes
 type Middleware struct {
 	jwtManager     JWTManager
 	sessionManager SessionManager
 	cookieManager  CookieManager
+	users          UserProvider
 }
 
 // NewMiddleware creates a new authentication middleware
-func NewMiddleware(jwtManager JWTManager, sessionManager SessionManager, cookieManager CookieManager) *Middleware {
+func NewMiddleware(jwtManager JWTManager, sessionManager SessionManager, cookieManager CookieManager, users UserProvider) *Middleware {
 	return &Middleware{
 		jwtManager:     jwtManager,
 		sessionManager: sessionManager,
 		cookieManager:  cookieManager,
+		users:          users,
 	}
 }
 
@@ -61,10 +69,20 @@ func (m *Middleware) Authenticate(next http.Handler) http.Handler {
 		// Check if session is still valid in database
 		session, err := m.sessionManager.ValidateSession(claims.ID)
 		if err != nil || session == nil {
-			log.Warn("attempt to access protected route with invalid session", "error", err.Error())
-			m.cookieManager.InvalidateCookie("access_token")
-			m.cookieManager.InvalidateCookie("refresh_token")
-			m.cookieManager.InvalidateCookie("csrf_token")
+			log.Warn("attempt to access protected route with invalid session", "error", err)
+			m.invalidateCookies(w)
+			http.Error(w, "Session invalid or expired", http.StatusUnauthorized)
+			return
+		}
+
+		// Check if the user still exists and isn't suspended
+		user, err := m.users.GetUserByID(claims.UserID)
+		if err != nil || user.Suspended() {
+			log.Warn("attempt to access protected route as unavailable user",
+				"userId", claims.UserID,
+				"suspended", user != nil && user.Suspended(),
+			)
+			m.invalidateCookies(w)
 			http.Error(w, "Session invalid or expired", http.StatusUnauthorized)
 			return
 		}
@@ -92,10 +110,13 @@ func (m *Middleware) Authenticate(next http.Handler) http.Handler {
 			}
 		}
 
-		// Create handler context with user information
+		// Create handler context with user information. The role is taken from the user rather
+		// than the token, so role changes apply to existing sessions right away.
 		hctx := &context.HandlerContext{
-			UserID:   claims.UserID,
-			UserRole: claims.Role,
+			UserID:             claims.UserID,
+			UserRole:           string(user.Role),
+			SessionID:          session.ID,
+			MustChangePassword: user.MustChangePassword,
 		}
 
 		// Add context to request and continue
@@ -103,6 +124,36 @@ func (m *Middleware) Authenticate(next http.Handler) http.Handler {
 	})
 }
 
+// invalidateCookies clears the authentication cookies of the client
+func (m *Middleware) invalidateCookies(w http.ResponseWriter) {
+	for _, name := range []string{"access_token", "refresh_token", "csrf_token"} {
+		http.SetCookie(w, m.cookieManager.InvalidateCookie(name))
+	}
+}
+
+// RequirePasswordChanged middleware rejects requests of users who have to change their password
+// before they can do anything else
+func (m *Middleware) RequirePasswordChanged(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := context.GetRequestContext(w, r)
+		if !ok {
+			return
+		}
+
+		if ctx.MustChangePassword {
+			getMiddlewareLogger().Debug("attempt to access protected route before changing password",
+				"handler", "RequirePasswordChanged",
+				"clientIP", r.RemoteAddr,
+				"userId", ctx.UserID,
+			)
+			http.Error(w, "Password change required", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 // RequireRole returns a middleware that ensures the user has the required role
 func (m *Middleware) RequireRole(role string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -129,6 +180,36 @@ func (m *Middleware) RequireRole(role string) func(http.Handler) http.Handler {
 	}
 }
 
+// RequirePermission returns a middleware that ensures the user's role grants the permission.
+// On workspace routes, the user's role in the workspace must grant it as well.
+func (m *Middleware) RequirePermission(permission Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			log := getMiddlewareLogger().With(
+				"handler", "RequirePermission",
+				"requiredPermission", permission,
+				"clientIP", r.RemoteAddr,
+			)
+
+			ctx, ok := context.GetRequestContext(w, r)
+			if !ok {
+				return
+			}
+
+			if !hasContextPermission(ctx, permission) {
+				log.Warn("attempt to access protected route without required permission",
+					"userId", ctx.UserID,
+					"role", ctx.UserRole,
+				)
+				http.Error(w, "Insufficient permissions", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // RequireWorkspaceAccess returns a middleware that ensures the user has access to the workspace
 func (m *Middleware) RequireWorkspaceAccess(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -149,8 +230,8 @@ func (m *Middleware) RequireWorkspaceAccess(next http.Handler) http.Handler {
 			return
 		}
 
-		// Check if user has access (either owner or admin)
-		if ctx.Workspace.UserID != ctx.UserID && ctx.UserRole != "admin" {
+		// Check if user has access (either member or admin)
+		if workspaceRole(ctx) == "" && ctx.UserRole != "admin" {
 			log.Warn("attempt to access workspace without permission")
 			http.Error(w, "Not Found", http.StatusNotFound)
 			return
@@ -25,12 +25,12 @@ func newMockSessionManager() *mockSessionManager {
 	}
 }
 
-func (m *mockSessionManager) CreateSession(_ int, _ string) (*models.Session, string, error) {
+func (m *mockSessionManager) CreateSession(_ int, _, _, _ string) (*models.Session, string, error) {
 	return nil, "", nil // Not needed for these tests
 }
 
-func (m *mockSessionManager) RefreshSession(_ string) (string, error) {
-	return "", nil // Not needed for these tests
+func (m *mockSessionManager) RefreshSession(_ string) (string, string, error) {
+	return "", "", nil // Not needed for these tests
 }
 
 func (m *mockSessionManager) ValidateSession(sessionID string) (*models.Session, error) {
@@ -50,6 +50,26 @@ func (m *mockSessionManager) CleanExpiredSessions() error {
 	return nil
 }
 
+// Mock UserProvider
+type mockUserProvider map[int]*models.User
+
+func (m mockUserProvider) GetUserByID(id int) (*models.User, error) {
+	user, exists := m[id]
+	if !exists {
+		return nil, fmt.Errorf("user not found")
+	}
+	return user, nil
+}
+
+func newMockUserProvider() mockUserProvider {
+	suspendedAt := time.Now()
+	return mockUserProvider{
+		1: {ID: 1, Role: models.RoleAdmin},
+		2: {ID: 2, Role: models.RoleEditor, SuspendedAt: &suspendedAt},
+		3: {ID: 3, Role: models.RoleEditor, MustChangePassword: true},
+	}
+}
+
 // Complete mockResponseWriter implementation
 type mockResponseWriter struct {
 	headers    http.Header
@@ -85,7 +105,7 @@ func TestAuthenticateMiddleware(t *testing.T) {
 	jwtService, _ := auth.NewJWTService(config)
 	sessionManager := newMockSessionManager()
 	cookieManager := auth.NewCookieService(true, "localhost")
-	middleware := auth.NewMiddleware(jwtService, sessionManager, cookieManager)
+	middleware := auth.NewMiddleware(jwtService, sessionManager, cookieManager, newMockUserProvider())
 
 	testCases := []struct {
 		name           string
@@ -126,6 +146,42 @@ func TestAuthenticateMiddleware(t *testing.T) {
 			method:         "GET",
 			wantStatusCode: http.StatusUnauthorized,
 		},
+		{
+			name: "valid session of suspended user",
+			setupRequest: func(sessionID string) *http.Request {
+				req := httptest.NewRequest("GET", "/test", nil)
+				token, _ := jwtService.GenerateAccessToken(2, "editor", sessionID)
+				req.AddCookie(cookieManager.GenerateAccessTokenCookie(token))
+				return req
+			},
+			setupSession: func(sessionID string) {
+				sessionManager.sessions[sessionID] = &models.Session{
+					ID:        sessionID,
+					UserID:    2,
+					ExpiresAt: time.Now().Add(15 * time.Minute),
+				}
+			},
+			method:         "GET",
+			wantStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name: "valid session of unknown user",
+			setupRequest: func(sessionID string) *http.Request {
+				req := httptest.NewRequest("GET", "/test", nil)
+				token, _ := jwtService.GenerateAccessToken(99, "editor", sessionID)
+				req.AddCookie(cookieManager.GenerateAccessTokenCookie(token))
+				return req
+			},
+			setupSession: func(sessionID string) {
+				sessionManager.sessions[sessionID] = &models.Session{
+					ID:        sessionID,
+					UserID:    99,
+					ExpiresAt: time.Now().Add(15 * time.Minute),
+				}
+			},
+			method:         "GET",
+			wantStatusCode: http.StatusUnauthorized,
+		},
 		{
 			name: "missing auth cookie",
 			setupRequest: func(_ string) *http.Request {
@@ -217,11 +273,15 @@ func TestAuthenticateMiddleware(t *testing.T) {
 				t.Error("next handler was called when it shouldn't have been")
 			}
 
-			// For unauthorized responses, check if cookies were invalidated
-			if w.statusCode == http.StatusUnauthorized {
-				for _, cookie := range w.Header()["Set-Cookie"] {
-					if strings.Contains(cookie, "Max-Age=0") {
-						t.Error("cookies were not properly invalidated")
+			// For unauthorized responses with a token, check if cookies were invalidated
+			if _, err := req.Cookie("access_token"); err == nil && w.statusCode == http.StatusUnauthorized {
+				cookies := w.Header()["Set-Cookie"]
+				if len(cookies) != 3 {
+					t.Fatalf("got %d cookies, want 3 invalidated cookies", len(cookies))
+				}
+				for _, cookie := range cookies {
+					if !strings.Contains(cookie, "Max-Age=0") {
+						t.Errorf("cookie %q was not invalidated", cookie)
 					}
 				}
 			}
@@ -229,6 +289,67 @@ func TestAuthenticateMiddleware(t *testing.T) {
 	}
 }
 
+func TestRequirePasswordChanged(t *testing.T) {
+	config := auth.JWTConfig{
+		SigningKey:         "test-key",
+		AccessTokenExpiry:  15 * time.Minute,
+		RefreshTokenExpiry: 24 * time.Hour,
+	}
+	jwtService, _ := auth.NewJWTService(config)
+	sessionManager := newMockSessionManager()
+	cookieManager := auth.NewCookieService(true, "localhost")
+	middleware := auth.NewMiddleware(jwtService, sessionManager, cookieManager, newMockUserProvider())
+
+	testCases := []struct {
+		name           string
+		userID         int
+		wantStatusCode int
+	}{
+		{
+			name:           "user without required password change",
+			userID:         1,
+			wantStatusCode: http.StatusOK,
+		},
+		{
+			name:           "user with required password change",
+			userID:         3,
+			wantStatusCode: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sessionID := tc.name
+			sessionManager.sessions[sessionID] = &models.Session{
+				ID:        sessionID,
+				UserID:    tc.userID,
+				ExpiresAt: time.Now().Add(15 * time.Minute),
+			}
+			token, _ := jwtService.GenerateAccessToken(tc.userID, "editor", sessionID)
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.AddCookie(cookieManager.GenerateAccessTokenCookie(token))
+			w := newMockResponseWriter()
+
+			nextCalled := false
+			next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				nextCalled = true
+				w.WriteHeader(http.StatusOK)
+			})
+
+			// The context middleware runs between the two in the router
+			handler := middleware.Authenticate(context.WithUserContextMiddleware(middleware.RequirePasswordChanged(next)))
+			handler.ServeHTTP(w, req)
+
+			if w.statusCode != tc.wantStatusCode {
+				t.Errorf("status code = %v, want %v", w.statusCode, tc.wantStatusCode)
+			}
+			if nextCalled != (tc.wantStatusCode == http.StatusOK) {
+				t.Errorf("next handler called = %v, want %v", nextCalled, tc.wantStatusCode == http.StatusOK)
+			}
+		})
+	}
+}
+
 func TestRequireRole(t *testing.T) {
 	config := auth.JWTConfig{
 		SigningKey:         "test-key",
@@ -236,7 +357,7 @@ func TestRequireRole(t *testing.T) {
 		RefreshTokenExpiry: 24 * time.Hour,
 	}
 	jwtService, _ := auth.NewJWTService(config)
-	middleware := auth.NewMiddleware(jwtService, &mockSessionManager{}, auth.NewCookieService(true, "localhost"))
+	middleware := auth.NewMiddleware(jwtService, &mockSessionManager{}, auth.NewCookieService(true, "localhost"), newMockUserProvider())
 
 	testCases := []struct {
 		name           string
@@ -303,12 +424,79 @@ func TestRequireRole(t *testing.T) {
 	}
 }
 
+func TestRequirePermission(t *testing.T) {
+	config := auth.JWTConfig{
+		SigningKey:         "test-key",
+		AccessTokenExpiry:  15 * time.Minute,
+		RefreshTokenExpiry: 24 * time.Hour,
+	}
+	jwtService, _ := auth.NewJWTService(config)
+	middleware := auth.NewMiddleware(jwtService, &mockSessionManager{}, auth.NewCookieService(true, "localhost"), newMockUserProvider())
+
+	permissions := []auth.Permission{auth.PermissionRead, auth.PermissionWrite, auth.PermissionManage, auth.PermissionAdmin}
+
+	// Expected result for read, write, manage and admin permission
+	testCases := []struct {
+		name          string
+		userRole      string
+		workspaceRole models.WorkspaceRole // Role in a workspace of user 2, no workspace if empty
+		want          []bool
+	}{
+		{name: "admin", userRole: "admin", want: []bool{true, true, true, true}},
+		{name: "editor", userRole: "editor", want: []bool{true, true, true, false}},
+		{name: "viewer", userRole: "viewer", want: []bool{true, false, false, false}},
+		{name: "unknown", userRole: "unknown", want: []bool{false, false, false, false}},
+		{name: "workspace owner", userRole: "editor", workspaceRole: models.WorkspaceRoleOwner, want: []bool{true, true, true, false}},
+		{name: "workspace editor", userRole: "editor", workspaceRole: models.WorkspaceRoleEditor, want: []bool{true, true, false, false}},
+		{name: "workspace viewer", userRole: "editor", workspaceRole: models.WorkspaceRoleViewer, want: []bool{true, false, false, false}},
+		{name: "viewer as workspace editor", userRole: "viewer", workspaceRole: models.WorkspaceRoleEditor, want: []bool{true, false, false, false}},
+		{name: "admin as workspace viewer", userRole: "admin", workspaceRole: models.WorkspaceRoleViewer, want: []bool{true, true, true, true}},
+	}
+
+	for _, tc := range testCases {
+		for i, permission := range permissions {
+			t.Run(fmt.Sprintf("%s requires %s", tc.name, permission), func(t *testing.T) {
+				hctx := &context.HandlerContext{
+					UserID:   1,
+					UserRole: tc.userRole,
+				}
+				if tc.workspaceRole != "" {
+					hctx.Workspace = &models.Workspace{ID: 1, UserID: 2, Role: tc.workspaceRole}
+				}
+
+				req := httptest.NewRequest("GET", "/test", nil)
+				req = context.WithHandlerContext(req, hctx)
+				w := newMockResponseWriter()
+
+				nextCalled := false
+				next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+					nextCalled = true
+					w.WriteHeader(http.StatusOK)
+				})
+
+				middleware.RequirePermission(permission)(next).ServeHTTP(w, req)
+
+				wantStatusCode := http.StatusForbidden
+				if tc.want[i] {
+					wantStatusCode = http.StatusOK
+				}
+				if w.statusCode != wantStatusCode {
+					t.Errorf("status code = %v, want %v", w.statusCode, wantStatusCode)
+				}
+				if nextCalled != tc.want[i] {
+					t.Errorf("next handler called = %v, want %v", nextCalled, tc.want[i])
+				}
+			})
+		}
+	}
+}
+
 func TestRequireWorkspaceAccess(t *testing.T) {
 	config := auth.JWTConfig{
 		SigningKey: "test-key",
 	}
 	jwtService, _ := auth.NewJWTService(config)
-	middleware := auth.NewMiddleware(jwtService, &mockSessionManager{}, auth.NewCookieService(true, "localhost"))
+	middleware := auth.NewMiddleware(jwtService, &mockSessionManager{}, auth.NewCookieService(true, "localhost"), newMockUserProvider())
 
 	testCases := []struct {
 		name           string
@@ -343,6 +531,21 @@ func TestRequireWorkspaceAccess(t *testing.T) {
 			},
 			wantStatusCode: http.StatusOK,
 		},
+		{
+			name: "workspace member access",
+			setupContext: func() *context.HandlerContext {
+				return &context.HandlerContext{
+					UserID:   2,
+					UserRole: "editor",
+					Workspace: &models.Workspace{
+						ID:     1,
+						UserID: 1,
+						Role:   models.WorkspaceRoleViewer,
+					},
+				}
+			},
+			wantStatusCode: http.StatusOK,
+		},
 		{
 			name: "unauthorized access attempt",
 			setupContext: func() *context.HandlerContext {
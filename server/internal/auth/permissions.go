@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"lemma/internal/context"
+	"lemma/internal/models"
+)
+
+// Permission is a capability required to access a route
+type Permission string
+
+const (
+	// PermissionRead allows reading workspaces, files and git state
+	PermissionRead Permission = "read"
+	// PermissionWrite allows changing files, and committing and pulling changes
+	PermissionWrite Permission = "write"
+	// PermissionManage allows changing workspace settings and members, and deleting workspaces
+	PermissionManage Permission = "manage"
+	// PermissionAdmin allows managing users and system settings
+	PermissionAdmin Permission = "admin"
+)
+
+// rolePermissions maps each user role to the permissions it grants
+var rolePermissions = map[models.UserRole][]Permission{
+	models.RoleAdmin:  {PermissionRead, PermissionWrite, PermissionManage, PermissionAdmin},
+	models.RoleEditor: {PermissionRead, PermissionWrite, PermissionManage},
+	models.RoleViewer: {PermissionRead},
+}
+
+// workspaceRolePermissions maps each workspace role to the permissions it grants in the workspace
+var workspaceRolePermissions = map[models.WorkspaceRole][]Permission{
+	models.WorkspaceRoleOwner:  {PermissionRead, PermissionWrite, PermissionManage},
+	models.WorkspaceRoleEditor: {PermissionRead, PermissionWrite},
+	models.WorkspaceRoleViewer: {PermissionRead},
+}
+
+// HasPermission reports whether the role grants the permission. Unknown roles grant nothing.
+func HasPermission(role models.UserRole, permission Permission) bool {
+	return containsPermission(rolePermissions[role], permission)
+}
+
+// HasWorkspacePermission reports whether the workspace role grants the permission
+func HasWorkspacePermission(role models.WorkspaceRole, permission Permission) bool {
+	return containsPermission(workspaceRolePermissions[role], permission)
+}
+
+// hasContextPermission reports whether the user of the request context has the permission.
+// On workspace routes, both the user's role and their role in the workspace must grant it,
+// except for admins.
+func hasContextPermission(ctx *context.HandlerContext, permission Permission) bool {
+	if !HasPermission(models.UserRole(ctx.UserRole), permission) {
+		return false
+	}
+
+	if ctx.Workspace == nil || ctx.UserRole == string(models.RoleAdmin) {
+		return true
+	}
+
+	return HasWorkspacePermission(workspaceRole(ctx), permission)
+}
+
+// workspaceRole returns the role of the user in the workspace of the request context
+func workspaceRole(ctx *context.HandlerContext) models.WorkspaceRole {
+	if ctx.Workspace.Role != "" {
+		return ctx.Workspace.Role
+	}
+	if ctx.Workspace.UserID == ctx.UserID {
+		return models.WorkspaceRoleOwner
+	}
+	return ""
+}
+
+func containsPermission(permissions []Permission, permission Permission) bool {
+	for _, p := range permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
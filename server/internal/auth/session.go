@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"errors"
 	"fmt"
 	"lemma/internal/db"
 	"lemma/internal/logging"
@@ -16,13 +17,39 @@ func getSessionLogger() logging.Logger {
 
 // SessionManager is an interface for managing user sessions
 type SessionManager interface {
-	CreateSession(userID int, role string) (*models.Session, string, error)
-	RefreshSession(refreshToken string) (string, error)
+	CreateSession(userID int, role, userAgent, ipAddress string) (*models.Session, string, error)
+	RefreshSession(refreshToken string) (string, string, error)
 	ValidateSession(sessionID string) (*models.Session, error)
 	InvalidateSession(token string) error
 	CleanExpiredSessions() error
 }
 
+// ErrRefreshTokenReused is returned by RefreshSession when a refresh token that has
+// already been rotated is presented again. The token family is revoked when this happens.
+var ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+
+// RefreshTokenReusedError identifies the token family that was revoked because a refresh token
+// was reused. It matches ErrRefreshTokenReused.
+type RefreshTokenReusedError struct {
+	SessionID string
+	UserID    int
+}
+
+func (e *RefreshTokenReusedError) Error() string {
+	return ErrRefreshTokenReused.Error()
+}
+
+func (e *RefreshTokenReusedError) Unwrap() error {
+	return ErrRefreshTokenReused
+}
+
+// refreshTokenReuseWindow is how long a rotated refresh token is still accepted, so concurrent
+// refreshes, for example from two browser tabs, don't revoke the session
+const refreshTokenReuseWindow = 10 * time.Second
+
+// lastUsedUpdateInterval limits how often the last used time of a session is written to the database
+const lastUsedUpdateInterval = time.Minute
+
 // sessionManager manages user sessions in the database
 type sessionManager struct {
 	db         db.SessionStore // Database store for sessions
@@ -38,8 +65,9 @@ func NewSessionService(db db.SessionStore, jwtManager JWTManager) *sessionManage
 	}
 }
 
-// CreateSession creates a new user session for a user with the given userID and role
-func (s *sessionManager) CreateSession(userID int, role string) (*models.Session, string, error) {
+// CreateSession creates a new user session for a user with the given userID and role.
+// The userAgent and ipAddress identify the client the session was created for.
+func (s *sessionManager) CreateSession(userID int, role, userAgent, ipAddress string) (*models.Session, string, error) {
 	log := getSessionLogger()
 
 	// Generate a new session ID
@@ -63,12 +91,16 @@ func (s *sessionManager) CreateSession(userID int, role string) (*models.Session
 	}
 
 	// Create a new session record
+	now := time.Now()
 	session := &models.Session{
 		ID:           sessionID,
 		UserID:       userID,
 		RefreshToken: refreshToken,
+		UserAgent:    userAgent,
+		IPAddress:    ipAddress,
 		ExpiresAt:    claims.ExpiresAt.Time,
-		CreatedAt:    time.Now(),
+		CreatedAt:    now,
+		LastUsedAt:   now,
 	}
 
 	// Store the session
@@ -85,31 +117,108 @@ func (s *sessionManager) CreateSession(userID int, role string) (*models.Session
 	return session, accessToken, nil
 }
 
-// RefreshSession creates a new access token using a refreshToken
-func (s *sessionManager) RefreshSession(refreshToken string) (string, error) {
+// RefreshSession creates a new access token using a refreshToken and rotates the refresh token.
+// It returns the new access token and the refresh token that replaces refreshToken.
+// Presenting a refresh token that was already rotated revokes the whole token family
+// (the session) and returns a RefreshTokenReusedError, unless it was rotated within the reuse window.
+func (s *sessionManager) RefreshSession(refreshToken string) (string, string, error) {
+	log := getSessionLogger()
+
 	// Get session from database
 	session, err := s.db.GetSessionByRefreshToken(refreshToken)
 	if err != nil {
-		return "", fmt.Errorf("invalid session: %w", err)
+		if rotated, rotatedErr := s.db.GetRotatedRefreshToken(refreshToken); rotatedErr == nil {
+			if time.Since(rotated.RotatedAt) <= refreshTokenReuseWindow {
+				return s.refreshRotatedSession(rotated, refreshToken)
+			}
+			s.revokeTokenFamily(rotated)
+			return "", "", &RefreshTokenReusedError{SessionID: rotated.SessionID, UserID: rotated.UserID}
+		}
+		return "", "", fmt.Errorf("invalid session: %w", err)
 	}
 
 	// Validate the refresh token
 	claims, err := s.jwtManager.ValidateToken(refreshToken)
 	if err != nil {
-		return "", fmt.Errorf("invalid refresh token: %w", err)
+		return "", "", fmt.Errorf("invalid refresh token: %w", err)
 	}
 
 	if claims.UserID != session.UserID {
-		return "", fmt.Errorf("token does not match session")
+		return "", "", fmt.Errorf("token does not match session")
 	}
 
 	// Generate a new access token
 	newToken, err := s.jwtManager.GenerateAccessToken(claims.UserID, claims.Role, session.ID)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 
-	return newToken, nil
+	// Rotate the refresh token, the session keeps its original expiry
+	newRefreshToken, err := s.jwtManager.GenerateRefreshToken(claims.UserID, claims.Role, session.ID)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	if err := s.db.RotateRefreshToken(session, newRefreshToken); err != nil {
+		// A concurrent refresh may have rotated the token since the session was read
+		if rotated, rotatedErr := s.db.GetRotatedRefreshToken(refreshToken); rotatedErr == nil &&
+			time.Since(rotated.RotatedAt) <= refreshTokenReuseWindow {
+			return s.refreshRotatedSession(rotated, refreshToken)
+		}
+		return "", "", fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+
+	log.Debug("rotated refresh token",
+		"sessionId", session.ID,
+		"userId", session.UserID)
+
+	return newToken, newRefreshToken, nil
+}
+
+// refreshRotatedSession handles a refresh token that was rotated within the reuse window. It creates
+// a new access token and returns the current refresh token of the session instead of rotating it again.
+func (s *sessionManager) refreshRotatedSession(rotated *models.RotatedRefreshToken, refreshToken string) (string, string, error) {
+	claims, err := s.jwtManager.ValidateToken(refreshToken)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid refresh token: %w", err)
+	}
+
+	session, err := s.db.GetSessionByID(rotated.SessionID)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid session: %w", err)
+	}
+
+	if claims.UserID != session.UserID {
+		return "", "", fmt.Errorf("token does not match session")
+	}
+
+	newToken, err := s.jwtManager.GenerateAccessToken(claims.UserID, claims.Role, session.ID)
+	if err != nil {
+		return "", "", err
+	}
+
+	getSessionLogger().Debug("accepted recently rotated refresh token",
+		"sessionId", session.ID,
+		"userId", session.UserID,
+		"rotatedAt", rotated.RotatedAt)
+
+	return newToken, session.RefreshToken, nil
+}
+
+// revokeTokenFamily deletes the session a reused refresh token belongs to and records a security event
+func (s *sessionManager) revokeTokenFamily(rotated *models.RotatedRefreshToken) {
+	log := getSessionLogger()
+
+	log.Warn("security event: refresh token reuse detected, revoking token family",
+		"sessionId", rotated.SessionID,
+		"userId", rotated.UserID,
+		"rotatedAt", rotated.RotatedAt)
+
+	if err := s.db.DeleteSession(rotated.SessionID); err != nil {
+		log.Debug("token family already revoked",
+			"sessionId", rotated.SessionID,
+			"error", err.Error())
+	}
 }
 
 // ValidateSession checks if a session with the given sessionID is valid
@@ -122,6 +231,18 @@ func (s *sessionManager) ValidateSession(sessionID string) (*models.Session, err
 		return nil, fmt.Errorf("failed to get session: %w", err)
 	}
 
+	// Record activity, but avoid a database write on every request
+	now := time.Now()
+	if now.Sub(session.LastUsedAt) > lastUsedUpdateInterval {
+		if err := s.db.UpdateSessionLastUsed(sessionID, now); err != nil {
+			log.Warn("failed to update session last used time",
+				"sessionId", sessionID,
+				"error", err.Error())
+		} else {
+			session.LastUsedAt = now
+		}
+	}
+
 	log.Debug("validated session",
 		"sessionId", sessionID,
 		"userId", session.UserID,
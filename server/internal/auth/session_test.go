@@ -15,12 +15,14 @@ import (
 type mockSessionStore struct {
 	sessions        map[string]*models.Session
 	sessionsByToken map[string]*models.Session
+	rotatedTokens   map[string]*models.RotatedRefreshToken
 }
 
 func newMockSessionStore() *mockSessionStore {
 	return &mockSessionStore{
 		sessions:        make(map[string]*models.Session),
 		sessionsByToken: make(map[string]*models.Session),
+		rotatedTokens:   make(map[string]*models.RotatedRefreshToken),
 	}
 }
 
@@ -52,6 +54,62 @@ func (m *mockSessionStore) GetSessionByRefreshToken(refreshToken string) (*model
 	return session, nil
 }
 
+func (m *mockSessionStore) GetSessionsByUserID(userID int) ([]*models.Session, error) {
+	var sessions []*models.Session
+	for _, session := range m.sessions {
+		if session.UserID == userID && session.ExpiresAt.After(time.Now()) {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, nil
+}
+
+func (m *mockSessionStore) UpdateSessionLastUsed(sessionID string, lastUsedAt time.Time) error {
+	session, exists := m.sessions[sessionID]
+	if !exists {
+		return errors.New("session not found")
+	}
+	session.LastUsedAt = lastUsedAt
+	return nil
+}
+
+func (m *mockSessionStore) RotateRefreshToken(session *models.Session, newRefreshToken string) error {
+	stored, exists := m.sessionsByToken[session.RefreshToken]
+	if !exists || stored.ID != session.ID {
+		return errors.New("session not found or refresh token already rotated")
+	}
+	m.rotatedTokens[session.RefreshToken] = &models.RotatedRefreshToken{
+		Token:     session.RefreshToken,
+		SessionID: session.ID,
+		UserID:    session.UserID,
+		RotatedAt: time.Now(),
+		ExpiresAt: session.ExpiresAt,
+	}
+	delete(m.sessionsByToken, session.RefreshToken)
+	stored.RefreshToken = newRefreshToken
+	session.RefreshToken = newRefreshToken
+	m.sessionsByToken[newRefreshToken] = stored
+	return nil
+}
+
+func (m *mockSessionStore) GetRotatedRefreshToken(refreshToken string) (*models.RotatedRefreshToken, error) {
+	rotated, exists := m.rotatedTokens[refreshToken]
+	if !exists {
+		return nil, errors.New("rotated refresh token not found")
+	}
+	return rotated, nil
+}
+
+func (m *mockSessionStore) DeleteUserSessions(userID int, exceptSessionID string) error {
+	for id, session := range m.sessions {
+		if session.UserID == userID && id != exceptSessionID {
+			delete(m.sessionsByToken, session.RefreshToken)
+			delete(m.sessions, id)
+		}
+	}
+	return nil
+}
+
 func (m *mockSessionStore) DeleteSession(sessionID string) error {
 	session, exists := m.sessions[sessionID]
 	if !exists {
@@ -72,6 +130,16 @@ func (m *mockSessionStore) CleanExpiredSessions() error {
 	return nil
 }
 
+func (m *mockSessionStore) CountActiveSessions() (int, error) {
+	count := 0
+	for _, session := range m.sessions {
+		if session.ExpiresAt.After(time.Now()) {
+			count++
+		}
+	}
+	return count, nil
+}
+
 func TestCreateSession(t *testing.T) {
 	config := auth.JWTConfig{
 		SigningKey:         "test-key",
@@ -104,7 +172,7 @@ func TestCreateSession(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			session, accessToken, err := sessionService.CreateSession(tc.userID, tc.role)
+			session, accessToken, err := sessionService.CreateSession(tc.userID, tc.role, "test-agent", "127.0.0.1")
 			if tc.wantErr {
 				if err == nil {
 					t.Error("expected error, got nil")
@@ -130,6 +198,15 @@ func TestCreateSession(t *testing.T) {
 			if storedSession.RefreshToken != session.RefreshToken {
 				t.Error("stored refresh token doesn't match")
 			}
+			if storedSession.UserAgent != "test-agent" {
+				t.Errorf("user agent = %v, want %v", storedSession.UserAgent, "test-agent")
+			}
+			if storedSession.IPAddress != "127.0.0.1" {
+				t.Errorf("ip address = %v, want %v", storedSession.IPAddress, "127.0.0.1")
+			}
+			if storedSession.LastUsedAt.IsZero() {
+				t.Error("last used time was not set")
+			}
 
 			// Verify access token
 			claims, err := jwtService.ValidateToken(accessToken)
@@ -237,6 +314,10 @@ func TestValidateSession(t *testing.T) {
 			if session.ID != sessionID {
 				t.Errorf("session ID = %v, want %v", session.ID, sessionID)
 			}
+
+			if time.Since(session.LastUsedAt) > time.Minute {
+				t.Errorf("last used time = %v, want it to be updated", session.LastUsedAt)
+			}
 		})
 	}
 }
@@ -307,7 +388,7 @@ func TestRefreshSession(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			refreshToken := tc.setupSession()
-			newAccessToken, err := sessionService.RefreshSession(refreshToken)
+			newAccessToken, newRefreshToken, err := sessionService.RefreshSession(refreshToken)
 
 			if tc.wantErr {
 				if err == nil {
@@ -332,8 +413,89 @@ func TestRefreshSession(t *testing.T) {
 			if claims.Type != auth.AccessToken {
 				t.Errorf("token type = %v, want access token", claims.Type)
 			}
+
+			// Verify the refresh token was rotated
+			if newRefreshToken == refreshToken {
+				t.Error("refresh token was not rotated")
+			}
+			if _, err := mockDB.GetSessionByRefreshToken(newRefreshToken); err != nil {
+				t.Errorf("new refresh token not stored: %v", err)
+			}
 		})
 	}
+
+	t.Run("recently rotated refresh token is accepted", func(t *testing.T) {
+		token, _ := jwtService.GenerateRefreshToken(1, "admin", "test-session-4")
+		session := &models.Session{
+			ID:           "test-session-4",
+			UserID:       1,
+			RefreshToken: token,
+			ExpiresAt:    time.Now().Add(24 * time.Hour),
+			CreatedAt:    time.Now(),
+		}
+		if err := mockDB.CreateSession(session); err != nil {
+			t.Fatalf("failed to create session: %v", err)
+		}
+
+		_, newRefreshToken, err := sessionService.RefreshSession(token)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		// A concurrent refresh with the previous token gets the current refresh token
+		accessToken, refreshToken, err := sessionService.RefreshSession(token)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if refreshToken != newRefreshToken {
+			t.Error("expected the current refresh token of the session")
+		}
+		if _, err := jwtService.ValidateToken(accessToken); err != nil {
+			t.Errorf("failed to validate access token: %v", err)
+		}
+		if _, err := mockDB.GetSessionByID(session.ID); err != nil {
+			t.Errorf("session was revoked: %v", err)
+		}
+	})
+
+	t.Run("reused refresh token revokes token family", func(t *testing.T) {
+		token, _ := jwtService.GenerateRefreshToken(1, "admin", "test-session-3")
+		session := &models.Session{
+			ID:           "test-session-3",
+			UserID:       1,
+			RefreshToken: token,
+			ExpiresAt:    time.Now().Add(24 * time.Hour),
+			CreatedAt:    time.Now(),
+		}
+		if err := mockDB.CreateSession(session); err != nil {
+			t.Fatalf("failed to create session: %v", err)
+		}
+
+		_, newRefreshToken, err := sessionService.RefreshSession(token)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		// Presenting the rotated token again is treated as theft once the reuse window has passed
+		mockDB.rotatedTokens[token].RotatedAt = time.Now().Add(-time.Minute)
+		_, _, err = sessionService.RefreshSession(token)
+		if !errors.Is(err, auth.ErrRefreshTokenReused) {
+			t.Errorf("error = %v, want %v", err, auth.ErrRefreshTokenReused)
+		}
+		var reused *auth.RefreshTokenReusedError
+		if !errors.As(err, &reused) || reused.SessionID != session.ID || reused.UserID != session.UserID {
+			t.Errorf("error = %#v, want the revoked session", err)
+		}
+
+		if _, err := mockDB.GetSessionByID(session.ID); err == nil {
+			t.Error("expected session to be revoked")
+		}
+
+		// The latest token of the family is no longer valid either
+		if _, _, err := sessionService.RefreshSession(newRefreshToken); err == nil {
+			t.Error("expected refresh with latest token to fail after family revocation")
+		}
+	})
 }
 
 func TestCleanExpiredSessions(t *testing.T) {
@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"lemma/internal/db"
+	"lemma/internal/logging"
+	"lemma/internal/models"
+	"time"
+)
+
+// ErrInvalidUserToken is returned when a user token is unknown, expired or already redeemed
+var ErrInvalidUserToken = errors.New("invalid or expired token")
+
+func getUserTokenLogger() logging.Logger {
+	return getAuthLogger().WithGroup("usertokens")
+}
+
+// UserTokenConfig holds the lifetimes of single-use user tokens
+type UserTokenConfig struct {
+	PasswordResetExpiry time.Duration // How long a password reset link is valid
+	EmailChangeExpiry   time.Duration // How long an email verification link is valid
+}
+
+// UserTokenManager issues and redeems single-use tokens that are sent to users by email
+type UserTokenManager interface {
+	// Issue creates a new token for the user and invalidates earlier tokens of the same purpose.
+	// For email changes, email is the new address that is confirmed by redeeming the token.
+	Issue(userID int, purpose models.UserTokenPurpose, email string) (string, error)
+	// Redeem consumes a token, returning ErrInvalidUserToken if it can't be used
+	Redeem(token string, purpose models.UserTokenPurpose) (*models.UserToken, error)
+}
+
+// userTokenManager is a database backed UserTokenManager
+type userTokenManager struct {
+	db     db.UserTokenStore
+	config UserTokenConfig
+}
+
+// NewUserTokenManager creates a new user token manager, using defaults for unset config values
+func NewUserTokenManager(db db.UserTokenStore, config UserTokenConfig) UserTokenManager {
+	if config.PasswordResetExpiry == 0 {
+		config.PasswordResetExpiry = time.Hour
+	}
+	if config.EmailChangeExpiry == 0 {
+		config.EmailChangeExpiry = 24 * time.Hour
+	}
+
+	return &userTokenManager{
+		db:     db,
+		config: config,
+	}
+}
+
+// Issue generates a random token and stores its hash
+func (m *userTokenManager) Issue(userID int, purpose models.UserTokenPurpose, email string) (string, error) {
+	token, err := GenerateToken()
+	if err != nil {
+		return "", err
+	}
+
+	expiry := m.config.PasswordResetExpiry
+	if purpose == models.UserTokenPurposeEmailChange {
+		expiry = m.config.EmailChangeExpiry
+	}
+
+	userToken := &models.UserToken{
+		TokenHash: HashToken(token),
+		UserID:    userID,
+		Purpose:   purpose,
+		Email:     email,
+		ExpiresAt: time.Now().Add(expiry),
+	}
+	if err := m.db.CreateUserToken(userToken); err != nil {
+		return "", fmt.Errorf("failed to store token: %w", err)
+	}
+
+	getUserTokenLogger().Debug("issued user token",
+		"userId", userID,
+		"purpose", purpose,
+		"expiresAt", userToken.ExpiresAt)
+
+	return token, nil
+}
+
+// Redeem looks up the token by its hash and consumes it
+func (m *userTokenManager) Redeem(token string, purpose models.UserTokenPurpose) (*models.UserToken, error) {
+	if token == "" {
+		return nil, ErrInvalidUserToken
+	}
+
+	userToken, err := m.db.ConsumeUserToken(HashToken(token), purpose)
+	if err != nil {
+		getUserTokenLogger().Debug("failed to redeem user token",
+			"purpose", purpose,
+			"error", err.Error())
+		return nil, ErrInvalidUserToken
+	}
+
+	return userToken, nil
+}
+
+// GenerateToken returns a random URL-safe token for links sent to users
+func GenerateToken() (string, error) {
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(tokenBytes), nil
+}
+
+// HashToken returns the hash under which a token generated by GenerateToken is stored
+func HashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,132 @@
+package auth_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"lemma/internal/auth"
+	"lemma/internal/models"
+	_ "lemma/internal/testenv"
+)
+
+// Mock UserTokenStore
+type mockUserTokenStore struct {
+	tokens map[string]*models.UserToken
+}
+
+func newMockUserTokenStore() *mockUserTokenStore {
+	return &mockUserTokenStore{
+		tokens: make(map[string]*models.UserToken),
+	}
+}
+
+func (m *mockUserTokenStore) CreateUserToken(token *models.UserToken) error {
+	for hash, existing := range m.tokens {
+		if existing.UserID == token.UserID && existing.Purpose == token.Purpose {
+			delete(m.tokens, hash)
+		}
+	}
+	copied := *token
+	m.tokens[token.TokenHash] = &copied
+	return nil
+}
+
+func (m *mockUserTokenStore) ConsumeUserToken(tokenHash string, purpose models.UserTokenPurpose) (*models.UserToken, error) {
+	token, exists := m.tokens[tokenHash]
+	if !exists || token.Purpose != purpose || !token.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("user token not found or expired")
+	}
+	delete(m.tokens, tokenHash)
+	return token, nil
+}
+
+func (m *mockUserTokenStore) CleanExpiredUserTokens() error {
+	return nil
+}
+
+func TestUserTokenManager(t *testing.T) {
+	t.Run("issued token can be redeemed once", func(t *testing.T) {
+		store := newMockUserTokenStore()
+		manager := auth.NewUserTokenManager(store, auth.UserTokenConfig{})
+
+		token, err := manager.Issue(1, models.UserTokenPurposeEmailChange, "new@example.com")
+		if err != nil {
+			t.Fatalf("failed to issue token: %v", err)
+		}
+
+		// Only the hash of the token is stored
+		if _, exists := store.tokens[token]; exists {
+			t.Error("token stored in plain text")
+		}
+		for _, stored := range store.tokens {
+			if remaining := time.Until(stored.ExpiresAt); remaining < 23*time.Hour || remaining > 24*time.Hour {
+				t.Errorf("token expires in %v, want 24h", remaining)
+			}
+		}
+
+		userToken, err := manager.Redeem(token, models.UserTokenPurposeEmailChange)
+		if err != nil {
+			t.Fatalf("failed to redeem token: %v", err)
+		}
+		if userToken.UserID != 1 || userToken.Email != "new@example.com" {
+			t.Errorf("redeemed token = %+v, want user 1 and email new@example.com", userToken)
+		}
+
+		if _, err := manager.Redeem(token, models.UserTokenPurposeEmailChange); !errors.Is(err, auth.ErrInvalidUserToken) {
+			t.Errorf("second redemption error = %v, want %v", err, auth.ErrInvalidUserToken)
+		}
+	})
+
+	t.Run("token is bound to its purpose", func(t *testing.T) {
+		manager := auth.NewUserTokenManager(newMockUserTokenStore(), auth.UserTokenConfig{})
+
+		token, err := manager.Issue(1, models.UserTokenPurposeEmailChange, "new@example.com")
+		if err != nil {
+			t.Fatalf("failed to issue token: %v", err)
+		}
+
+		if _, err := manager.Redeem(token, models.UserTokenPurposePasswordReset); !errors.Is(err, auth.ErrInvalidUserToken) {
+			t.Errorf("error = %v, want %v", err, auth.ErrInvalidUserToken)
+		}
+	})
+
+	t.Run("expired token is rejected", func(t *testing.T) {
+		manager := auth.NewUserTokenManager(newMockUserTokenStore(), auth.UserTokenConfig{
+			PasswordResetExpiry: -time.Minute,
+		})
+
+		token, err := manager.Issue(1, models.UserTokenPurposePasswordReset, "")
+		if err != nil {
+			t.Fatalf("failed to issue token: %v", err)
+		}
+
+		if _, err := manager.Redeem(token, models.UserTokenPurposePasswordReset); !errors.Is(err, auth.ErrInvalidUserToken) {
+			t.Errorf("error = %v, want %v", err, auth.ErrInvalidUserToken)
+		}
+	})
+
+	t.Run("new token invalidates earlier tokens", func(t *testing.T) {
+		manager := auth.NewUserTokenManager(newMockUserTokenStore(), auth.UserTokenConfig{})
+
+		first, err := manager.Issue(1, models.UserTokenPurposePasswordReset, "")
+		if err != nil {
+			t.Fatalf("failed to issue token: %v", err)
+		}
+		second, err := manager.Issue(1, models.UserTokenPurposePasswordReset, "")
+		if err != nil {
+			t.Fatalf("failed to issue token: %v", err)
+		}
+		if first == second {
+			t.Fatal("issued identical tokens")
+		}
+
+		if _, err := manager.Redeem(first, models.UserTokenPurposePasswordReset); !errors.Is(err, auth.ErrInvalidUserToken) {
+			t.Errorf("error = %v, want %v", err, auth.ErrInvalidUserToken)
+		}
+		if _, err := manager.Redeem(second, models.UserTokenPurposePasswordReset); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
@@ -0,0 +1,163 @@
+// Package backup creates and restores archives of a Lemma instance, containing its
+// SQLite database and the workspace files of the data directory.
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"lemma/internal/db"
+	"lemma/internal/logging"
+	"lemma/internal/storage"
+)
+
+// FormatVersion is the version of the archive layout written by Create
+const FormatVersion = 1
+
+// Names of the entries in a backup archive
+const (
+	manifestName = "manifest.json"
+	databaseName = "lemma.db"
+	dataDir      = "data"
+)
+
+// Manifest describes the contents of a backup archive. It is the first entry of the archive.
+type Manifest struct {
+	FormatVersion int       `json:"formatVersion"`
+	CreatedAt     time.Time `json:"createdAt"`
+	Dialect       string    `json:"dialect"`
+	SchemaVersion int       `json:"schemaVersion"`
+}
+
+var logger logging.Logger
+
+func getLogger() logging.Logger {
+	if logger == nil {
+		logger = logging.WithGroup("backup")
+	}
+	return logger
+}
+
+// FileName returns the file name of a backup archive created at the given time
+func FileName(createdAt time.Time) string {
+	return fmt.Sprintf("lemma-backup-%s.tar.gz", createdAt.UTC().Format("20060102-150405"))
+}
+
+// Create writes a gzipped tar archive of the database and the files of the storage to w.
+// File writes are paused while the archive is created, so the database and files match.
+// The files of the SQLite database at dbPath are left out if it is in the storage directory.
+func Create(w io.Writer, database db.BackupStore, files storage.BackupManager, dbPath string) (*Manifest, error) {
+	log := getLogger()
+
+	tempDir, err := os.MkdirTemp("", "lemma-backup-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	gzipWriter := gzip.NewWriter(w)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	manifest := &Manifest{
+		FormatVersion: FormatVersion,
+		CreatedAt:     time.Now().UTC(),
+		Dialect:       db.DialectSQLite,
+	}
+
+	var fileCount int
+	err = files.WithWritesPaused(func() error {
+		backupPath := filepath.Join(tempDir, databaseName)
+		if err := database.Backup(backupPath); err != nil {
+			return err
+		}
+
+		schemaVersion, err := database.SchemaVersion()
+		if err != nil {
+			return err
+		}
+		manifest.SchemaVersion = schemaVersion
+
+		if err := writeManifest(tarWriter, manifest); err != nil {
+			return err
+		}
+
+		if err := writeFile(tarWriter, databaseName, backupPath); err != nil {
+			return fmt.Errorf("failed to archive database: %w", err)
+		}
+
+		fileCount, err = files.ArchiveFiles(tarWriter, dataDir, databaseFiles(dbPath)...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish archive: %w", err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish archive: %w", err)
+	}
+
+	log.Info("backup created",
+		"schemaVersion", manifest.SchemaVersion,
+		"files", fileCount)
+	return manifest, nil
+}
+
+// databaseFiles returns the paths of the SQLite database at dbPath and its journals
+func databaseFiles(dbPath string) []string {
+	return []string{dbPath, dbPath + "-wal", dbPath + "-shm", dbPath + "-journal"}
+}
+
+func writeManifest(tw *tar.Writer, manifest *Manifest) error {
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	header := &tar.Header{
+		Name:    manifestName,
+		Mode:    0644,
+		Size:    int64(len(content)),
+		ModTime: manifest.CreatedAt,
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if _, err := tw.Write(content); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return nil
+}
+
+func writeFile(tw *tar.Writer, name, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	header.Name = name
+
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, file)
+	return err
+}
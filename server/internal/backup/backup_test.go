@@ -0,0 +1,316 @@
+package backup_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"lemma/internal/backup"
+	"lemma/internal/db"
+	"lemma/internal/models"
+	"lemma/internal/storage"
+
+	_ "lemma/internal/testenv"
+)
+
+func TestBackupAndRestore(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "lemma.db")
+	workDir := filepath.Join(dir, "data")
+
+	database, err := db.Init(dbPath, nil)
+	if err != nil {
+		t.Fatalf("failed to initialize database: %v", err)
+	}
+	defer database.Close()
+
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	user, err := database.CreateUser(&models.User{
+		Email:        "backup@example.com",
+		PasswordHash: "hash",
+		Role:         models.RoleEditor,
+	})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	files := storage.NewService(workDir)
+	if err := files.SaveFile(user.ID, 1, "notes/todo.md", []byte("# Todo")); err != nil {
+		t.Fatalf("failed to save file: %v", err)
+	}
+
+	var archive bytes.Buffer
+	manifest, err := backup.Create(&archive, database, files, dbPath)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	t.Run("manifest is the first entry", func(t *testing.T) {
+		if manifest.FormatVersion != backup.FormatVersion || manifest.SchemaVersion != 18 {
+			t.Errorf("manifest = %+v, want format version %d and schema version 18", manifest, backup.FormatVersion)
+		}
+
+		entries := readArchive(t, archive.Bytes())
+		if entries[0].name != "manifest.json" {
+			t.Fatalf("first entry = %q, want manifest.json", entries[0].name)
+		}
+
+		var archived backup.Manifest
+		if err := json.Unmarshal(entries[0].content, &archived); err != nil {
+			t.Fatalf("failed to decode manifest: %v", err)
+		}
+		if archived.SchemaVersion != manifest.SchemaVersion {
+			t.Errorf("archived schema version = %d, want %d", archived.SchemaVersion, manifest.SchemaVersion)
+		}
+	})
+
+	t.Run("archive contains database and files", func(t *testing.T) {
+		names := make(map[string]bool)
+		for _, entry := range readArchive(t, archive.Bytes()) {
+			names[entry.name] = true
+		}
+
+		for _, name := range []string{"lemma.db", "data/1/1/notes/todo.md"} {
+			if !names[name] {
+				t.Errorf("archive is missing %q", name)
+			}
+		}
+	})
+
+	t.Run("restore replaces database and data directory", func(t *testing.T) {
+		restoreDir := t.TempDir()
+		restoreDB := filepath.Join(restoreDir, "lemma.db")
+		restoreWorkDir := filepath.Join(restoreDir, "data")
+		if err := os.MkdirAll(restoreWorkDir, 0755); err != nil {
+			t.Fatalf("failed to create data directory: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(restoreWorkDir, "old.md"), []byte("old"), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+
+		archivePath := writeArchive(t, archive.Bytes())
+		restored, err := backup.Restore(archivePath, restoreDB, restoreWorkDir)
+		if err != nil {
+			t.Fatalf("Restore() error = %v", err)
+		}
+		if restored.SchemaVersion != manifest.SchemaVersion {
+			t.Errorf("restored schema version = %d, want %d", restored.SchemaVersion, manifest.SchemaVersion)
+		}
+
+		content, err := os.ReadFile(filepath.Join(restoreWorkDir, "1", "1", "notes", "todo.md"))
+		if err != nil || string(content) != "# Todo" {
+			t.Errorf("restored file content = %q, %v, want %q", content, err, "# Todo")
+		}
+		if _, err := os.Stat(filepath.Join(restoreWorkDir+".pre-restore", "old.md")); err != nil {
+			t.Errorf("previous data directory was not kept: %v", err)
+		}
+
+		restoredDB, err := db.Init(restoreDB, nil)
+		if err != nil {
+			t.Fatalf("failed to open restored database: %v", err)
+		}
+		defer restoredDB.Close()
+
+		if _, err := restoredDB.GetUserByEmail("backup@example.com"); err != nil {
+			t.Errorf("user missing from restored database: %v", err)
+		}
+	})
+
+	t.Run("invalid archives are rejected before replacing", func(t *testing.T) {
+		entries := readArchive(t, archive.Bytes())
+
+		testCases := []struct {
+			name    string
+			entries []archiveEntry
+			wantErr string
+		}{
+			{
+				name:    "missing manifest",
+				entries: entries[1:],
+				wantErr: "first entry",
+			},
+			{
+				name:    "missing database",
+				entries: entries[:1],
+				wantErr: "database is missing",
+			},
+			{
+				name:    "path outside data directory",
+				entries: append(entries[:2:2], archiveEntry{name: "data/../escape.md", content: []byte("x")}),
+				wantErr: "outside the data directory",
+			},
+			{
+				name: "newer schema",
+				entries: append([]archiveEntry{
+					manifestEntry(t, backup.Manifest{FormatVersion: backup.FormatVersion, Dialect: db.DialectSQLite, SchemaVersion: 19}),
+				}, entries[1:]...),
+				wantErr: "doesn't match manifest",
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				restoreDir := t.TempDir()
+				restoreDB := filepath.Join(restoreDir, "lemma.db")
+				if err := os.WriteFile(restoreDB, []byte("current"), 0644); err != nil {
+					t.Fatalf("failed to write database: %v", err)
+				}
+
+				archivePath := writeArchive(t, buildArchive(t, tc.entries))
+				_, err := backup.Restore(archivePath, restoreDB, filepath.Join(restoreDir, "data"))
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("Restore() error = %v, want error containing %q", err, tc.wantErr)
+				}
+
+				content, err := os.ReadFile(restoreDB)
+				if err != nil || string(content) != "current" {
+					t.Errorf("database was replaced by invalid backup")
+				}
+				if _, err := os.Stat(restoreDB + ".restore"); !errors.Is(err, os.ErrNotExist) {
+					t.Errorf("staged database was not cleaned up")
+				}
+			})
+		}
+	})
+}
+
+func TestBackupWithDatabaseInDataDirectory(t *testing.T) {
+	workDir := filepath.Join(t.TempDir(), "data")
+	dbPath := filepath.Join(workDir, "lemma.db")
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		t.Fatalf("failed to create data directory: %v", err)
+	}
+
+	database, err := db.Init(dbPath, nil)
+	if err != nil {
+		t.Fatalf("failed to initialize database: %v", err)
+	}
+	defer database.Close()
+
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	files := storage.NewService(workDir)
+	if err := files.SaveFile(1, 1, "note.md", []byte("# Note")); err != nil {
+		t.Fatalf("failed to save file: %v", err)
+	}
+
+	var archive bytes.Buffer
+	if _, err := backup.Create(&archive, database, files, dbPath); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+
+	for _, entry := range readArchive(t, archive.Bytes()) {
+		if entry.name == "data/lemma.db" {
+			t.Error("live database was archived as data file")
+		}
+	}
+
+	restoreWorkDir := filepath.Join(t.TempDir(), "data")
+	restoreDB := filepath.Join(restoreWorkDir, "lemma.db")
+	if _, err := backup.Restore(writeArchive(t, archive.Bytes()), restoreDB, restoreWorkDir); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+
+	for _, path := range []string{restoreDB, filepath.Join(restoreWorkDir, "1", "1", "note.md")} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s was not restored: %v", path, err)
+		}
+	}
+}
+
+type archiveEntry struct {
+	name    string
+	isDir   bool
+	content []byte
+}
+
+func readArchive(t *testing.T, data []byte) []archiveEntry {
+	t.Helper()
+
+	gzipReader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to read archive: %v", err)
+	}
+	tarReader := tar.NewReader(gzipReader)
+
+	var entries []archiveEntry
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			return entries
+		}
+		if err != nil {
+			t.Fatalf("failed to read archive: %v", err)
+		}
+
+		content, err := io.ReadAll(tarReader)
+		if err != nil {
+			t.Fatalf("failed to read archive entry: %v", err)
+		}
+		entries = append(entries, archiveEntry{
+			name:    header.Name,
+			isDir:   header.Typeflag == tar.TypeDir,
+			content: content,
+		})
+	}
+}
+
+func buildArchive(t *testing.T, entries []archiveEntry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buf)
+	tarWriter := tar.NewWriter(gzipWriter)
+	for _, entry := range entries {
+		header := &tar.Header{Name: entry.name, Mode: 0644, Size: int64(len(entry.content))}
+		if entry.isDir {
+			header.Typeflag = tar.TypeDir
+			header.Mode = 0755
+		}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			t.Fatalf("failed to write archive: %v", err)
+		}
+		if _, err := tarWriter.Write(entry.content); err != nil {
+			t.Fatalf("failed to write archive: %v", err)
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		t.Fatalf("failed to write archive: %v", err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		t.Fatalf("failed to write archive: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func manifestEntry(t *testing.T, manifest backup.Manifest) archiveEntry {
+	t.Helper()
+
+	content, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatalf("failed to encode manifest: %v", err)
+	}
+	return archiveEntry{name: "manifest.json", content: content}
+}
+
+func writeArchive(t *testing.T, data []byte) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "backup.tar.gz")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("failed to write archive: %v", err)
+	}
+	return path
+}
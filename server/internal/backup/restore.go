@@ -0,0 +1,289 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"lemma/internal/db"
+)
+
+// Suffixes of the paths a restore is staged at and the replaced database and data directory are kept at
+const (
+	stagingSuffix  = ".restore"
+	previousSuffix = ".pre-restore"
+)
+
+// Restore replaces the SQLite database at dbPath and the data directory workDir with the
+// contents of the backup archive at archivePath. The archive is extracted and validated
+// before anything is replaced; the replaced database and data directory are kept with
+// the suffix ".pre-restore". Lemma must not be running while a backup is restored.
+func Restore(archivePath, dbPath, workDir string) (*Manifest, error) {
+	log := getLogger()
+
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer file.Close()
+
+	gzipReader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup: %w", err)
+	}
+	defer gzipReader.Close()
+
+	tarReader := tar.NewReader(gzipReader)
+	manifest, err := readManifest(tarReader)
+	if err != nil {
+		return nil, err
+	}
+
+	stagedDB := dbPath + stagingSuffix
+	stagedData := workDir + stagingSuffix
+
+	// A database in the data directory is replaced together with it
+	dbInWorkDir := false
+	if relPath, ok := relativePath(workDir, dbPath); ok {
+		stagedDB = filepath.Join(stagedData, relPath)
+		dbInWorkDir = true
+	}
+
+	for _, path := range []string{stagedDB, stagedData} {
+		if err := os.RemoveAll(path); err != nil {
+			return nil, fmt.Errorf("failed to clean up previous restore: %w", err)
+		}
+	}
+	// The staged paths are moved into place on success, so this only removes leftovers of a failed restore
+	defer os.RemoveAll(stagedDB)
+	defer os.RemoveAll(stagedData)
+
+	if err := extract(tarReader, stagedDB, stagedData); err != nil {
+		return nil, err
+	}
+
+	if err := validateDatabase(stagedDB, manifest); err != nil {
+		return nil, err
+	}
+
+	if dbInWorkDir {
+		err = replaceDataDirectory(workDir, stagedData)
+	} else {
+		err = replace(dbPath, stagedDB, workDir, stagedData)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	log.Info("backup restored",
+		"createdAt", manifest.CreatedAt,
+		"schemaVersion", manifest.SchemaVersion)
+	return manifest, nil
+}
+
+func readManifest(tr *tar.Reader) (*Manifest, error) {
+	header, err := tr.Next()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup: %w", err)
+	}
+	if header.Name != manifestName {
+		return nil, fmt.Errorf("invalid backup: first entry is %q, expected %q", header.Name, manifestName)
+	}
+
+	var manifest Manifest
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("invalid backup manifest: %w", err)
+	}
+
+	if manifest.FormatVersion != FormatVersion {
+		return nil, fmt.Errorf("unsupported backup format version %d", manifest.FormatVersion)
+	}
+	if manifest.Dialect != db.DialectSQLite {
+		return nil, fmt.Errorf("unsupported backup database %q", manifest.Dialect)
+	}
+
+	return &manifest, nil
+}
+
+// extract writes the database of the archive to dbPath and its data directory to dataPath
+func extract(tr *tar.Reader, dbPath, dataPath string) error {
+	if err := os.MkdirAll(dataPath, 0755); err != nil {
+		return fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	hasDatabase := false
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read backup: %w", err)
+		}
+
+		if header.Name == databaseName {
+			if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+				return fmt.Errorf("failed to create database directory: %w", err)
+			}
+			if err := extractFile(tr, dbPath, 0644); err != nil {
+				return fmt.Errorf("failed to extract database: %w", err)
+			}
+			hasDatabase = true
+			continue
+		}
+
+		relPath, ok := strings.CutPrefix(header.Name, dataDir+"/")
+		if !ok {
+			return fmt.Errorf("invalid backup: unexpected entry %q", header.Name)
+		}
+		relPath = strings.TrimSuffix(relPath, "/")
+		if relPath == "" {
+			continue
+		}
+		if !filepath.IsLocal(relPath) {
+			return fmt.Errorf("invalid backup: entry %q is outside the data directory", header.Name)
+		}
+		target := filepath.Join(dataPath, filepath.FromSlash(relPath))
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return fmt.Errorf("failed to create directory %q: %w", relPath, err)
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return fmt.Errorf("failed to create directory of %q: %w", relPath, err)
+			}
+			if err := extractFile(tr, target, header.FileInfo().Mode().Perm()); err != nil {
+				return fmt.Errorf("failed to extract %q: %w", relPath, err)
+			}
+		default:
+			return fmt.Errorf("invalid backup: entry %q is not a file or directory", header.Name)
+		}
+	}
+
+	if !hasDatabase {
+		return fmt.Errorf("invalid backup: database is missing")
+	}
+	return nil
+}
+
+func extractFile(r io.Reader, path string, perm os.FileMode) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// validateDatabase checks that the extracted database is intact and can be used by this version of Lemma
+func validateDatabase(dbPath string, manifest *Manifest) error {
+	database, err := db.Init(dbPath, nil)
+	if err != nil {
+		return fmt.Errorf("failed to open backup database: %w", err)
+	}
+	defer database.Close()
+
+	if err := database.CheckIntegrity(); err != nil {
+		return fmt.Errorf("invalid backup: %w", err)
+	}
+
+	schemaVersion, err := database.SchemaVersion()
+	if err != nil {
+		return fmt.Errorf("invalid backup: %w", err)
+	}
+	if schemaVersion != manifest.SchemaVersion {
+		return fmt.Errorf("invalid backup: database schema version %d doesn't match manifest version %d",
+			schemaVersion, manifest.SchemaVersion)
+	}
+
+	statuses, err := database.MigrationStatus()
+	if err != nil {
+		return fmt.Errorf("invalid backup: %w", err)
+	}
+	for _, status := range statuses {
+		switch status.State {
+		case db.MigrationUnknown:
+			return fmt.Errorf("%w: backup has migration %d applied", db.ErrDatabaseNewer, status.Version)
+		case db.MigrationModified:
+			return fmt.Errorf("invalid backup: migration %d was modified after it was applied", status.Version)
+		}
+	}
+
+	return nil
+}
+
+// replace moves the staged database and data directory into place, keeping the current ones
+func replace(dbPath, stagedDB, workDir, stagedData string) error {
+	// Journal files belong to the replaced database and must not be applied to the restored one
+	for _, path := range databaseFiles(dbPath) {
+		if err := moveAside(path); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Rename(stagedDB, dbPath); err != nil {
+		return fmt.Errorf("failed to replace database: %w", err)
+	}
+
+	if err := replaceDataDirectory(workDir, stagedData); err != nil {
+		return fmt.Errorf("%w, the previous database was kept at %q", err, dbPath+previousSuffix)
+	}
+
+	return nil
+}
+
+// replaceDataDirectory moves the staged data directory into place, keeping the current one
+func replaceDataDirectory(workDir, stagedData string) error {
+	if err := moveAside(workDir); err != nil {
+		return err
+	}
+
+	if err := os.Rename(stagedData, workDir); err != nil {
+		return fmt.Errorf("failed to replace data directory: %w", err)
+	}
+
+	return nil
+}
+
+// relativePath returns the path of target relative to dir, if target is inside dir
+func relativePath(dir, target string) (string, bool) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", false
+	}
+	absTarget, err := filepath.Abs(target)
+	if err != nil {
+		return "", false
+	}
+
+	relPath, err := filepath.Rel(absDir, absTarget)
+	if err != nil || !filepath.IsLocal(relPath) {
+		return "", false
+	}
+	return relPath, true
+}
+
+// moveAside renames the file or directory at path to keep it, if it exists
+func moveAside(path string) error {
+	previous := path + previousSuffix
+	if err := os.RemoveAll(previous); err != nil {
+		return fmt.Errorf("failed to remove %q: %w", previous, err)
+	}
+
+	if err := os.Rename(path, previous); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to move %q aside: %w", path, err)
+	}
+	return nil
+}
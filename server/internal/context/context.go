@@ -18,15 +18,20 @@ const (
 
 // UserClaims represents user information from authentication
 type UserClaims struct {
-	UserID int
-	Role   string
+	UserID             int
+	Role               string
+	SessionID          string
+	MustChangePassword bool
 }
 
 // HandlerContext holds the request-specific data available to all handlers
 type HandlerContext struct {
 	UserID    int
 	UserRole  string
+	SessionID string            // ID of the session used to authenticate the request
 	Workspace *models.Workspace // Optional, only set for workspace routes
+	// MustChangePassword is set for users who have to change their password before doing anything else
+	MustChangePassword bool
 }
 
 var logger logging.Logger
@@ -69,7 +74,9 @@ func GetUserFromContext(ctx context.Context) (*UserClaims, error) {
 	}
 
 	return &UserClaims{
-		UserID: hctx.UserID,
-		Role:   hctx.UserRole,
+		UserID:             hctx.UserID,
+		Role:               hctx.UserRole,
+		SessionID:          hctx.SessionID,
+		MustChangePassword: hctx.MustChangePassword,
 	}, nil
 }
@@ -22,8 +22,10 @@ func WithUserContextMiddleware(next http.Handler) http.Handler {
 		}
 
 		hctx := &HandlerContext{
-			UserID:   claims.UserID,
-			UserRole: claims.Role,
+			UserID:             claims.UserID,
+			UserRole:           claims.Role,
+			SessionID:          claims.SessionID,
+			MustChangePassword: claims.MustChangePassword,
 		}
 
 		r = WithHandlerContext(r, hctx)
@@ -31,7 +33,8 @@ func WithUserContextMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-// WithWorkspaceContextMiddleware adds workspace information to the request context
+// WithWorkspaceContextMiddleware adds workspace information to the request context.
+// The workspace is looked up among the workspaces the user is a member of.
 func WithWorkspaceContextMiddleware(db db.WorkspaceReader) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		log := getLogger()
@@ -42,7 +45,7 @@ func WithWorkspaceContextMiddleware(db db.WorkspaceReader) func(http.Handler) ht
 			}
 
 			workspaceName := chi.URLParam(r, "workspaceName")
-			workspace, err := db.GetWorkspaceByName(ctx.UserID, workspaceName)
+			workspace, err := db.GetMemberWorkspaceByName(ctx.UserID, workspaceName)
 			if err != nil {
 				log.Error("failed to get workspace",
 					"error", err,
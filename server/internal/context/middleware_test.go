@@ -14,11 +14,19 @@ import (
 
 // MockDB implements the minimal database interface needed for testing
 type MockDB struct {
-	GetWorkspaceByNameFunc func(userID int, workspaceName string) (*models.Workspace, error)
+	GetMemberWorkspaceByNameFunc func(userID int, workspaceName string) (*models.Workspace, error)
 }
 
-func (m *MockDB) GetWorkspaceByName(userID int, workspaceName string) (*models.Workspace, error) {
-	return m.GetWorkspaceByNameFunc(userID, workspaceName)
+func (m *MockDB) GetMemberWorkspaceByName(userID int, workspaceName string) (*models.Workspace, error) {
+	return m.GetMemberWorkspaceByNameFunc(userID, workspaceName)
+}
+
+func (m *MockDB) GetWorkspaceByName(_ int, _ string) (*models.Workspace, error) {
+	return nil, nil
+}
+
+func (m *MockDB) GetWorkspacesByMemberID(_ int) ([]*models.Workspace, error) {
+	return nil, nil
 }
 
 func (m *MockDB) GetWorkspaceByID(_ int) (*models.Workspace, error) {
@@ -33,6 +41,10 @@ func (m *MockDB) GetAllWorkspaces() ([]*models.Workspace, error) {
 	return nil, nil
 }
 
+func (m *MockDB) GetDeletedWorkspaces() ([]*models.Workspace, error) {
+	return nil, nil
+}
+
 func TestWithUserContextMiddleware(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -150,7 +162,7 @@ func TestWithWorkspaceContextMiddleware(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mockDB := &MockDB{
-				GetWorkspaceByNameFunc: func(_ int, _ string) (*models.Workspace, error) {
+				GetMemberWorkspaceByNameFunc: func(_ int, _ string) (*models.Workspace, error) {
 					return tt.mockWorkspace, tt.mockError
 				},
 			}
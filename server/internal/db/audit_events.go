@@ -0,0 +1,119 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"lemma/internal/models"
+)
+
+// CreateAuditEvent inserts a new audit event and sets its ID
+func (db *database) CreateAuditEvent(event *models.AuditEvent) error {
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+
+	details := []byte("{}")
+	if len(event.Details) > 0 {
+		var err error
+		details, err = json.Marshal(event.Details)
+		if err != nil {
+			return fmt.Errorf("failed to encode audit event details: %w", err)
+		}
+	}
+
+	err := db.QueryRow(`
+        INSERT INTO audit_events (event, actor_id, target_type, target_id, workspace_id, ip_address, request_id, details, created_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+        RETURNING id`,
+		event.Event, event.ActorID, event.TargetType, event.TargetID, event.WorkspaceID,
+		event.IPAddress, event.RequestID, string(details), event.CreatedAt,
+	).Scan(&event.ID)
+	if err != nil {
+		return fmt.Errorf("failed to insert audit event: %w", err)
+	}
+
+	return nil
+}
+
+// GetAuditEvents retrieves the audit events matching the filter, newest first,
+// together with the total number of matching events for paging
+func (db *database) GetAuditEvents(filter models.AuditEventFilter) ([]*models.AuditEvent, int, error) {
+	var conditions []string
+	var args []any
+	if filter.Event != "" {
+		conditions = append(conditions, "event = ?")
+		args = append(args, filter.Event)
+	}
+	if filter.ActorID != nil {
+		conditions = append(conditions, "actor_id = ?")
+		args = append(args, *filter.ActorID)
+	}
+	if filter.TargetType != "" {
+		conditions = append(conditions, "target_type = ?")
+		args = append(args, filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		conditions = append(conditions, "target_id = ?")
+		args = append(args, filter.TargetID)
+	}
+	if filter.WorkspaceID != nil {
+		conditions = append(conditions, "workspace_id = ?")
+		args = append(args, *filter.WorkspaceID)
+	}
+	if filter.Since != nil {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, *filter.Since)
+	}
+	if filter.Until != nil {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, *filter.Until)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM audit_events "+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count audit events: %w", err)
+	}
+
+	rows, err := db.Query(`
+        SELECT id, event, actor_id, target_type, target_id, workspace_id, ip_address, request_id, details, created_at
+        FROM audit_events `+where+`
+        ORDER BY created_at DESC, id DESC
+        LIMIT ? OFFSET ?`,
+		append(args, filter.Limit, filter.Offset)...,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query audit events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []*models.AuditEvent{}
+	for rows.Next() {
+		event := &models.AuditEvent{}
+		var details string
+		err := rows.Scan(
+			&event.ID, &event.Event, &event.ActorID, &event.TargetType, &event.TargetID,
+			&event.WorkspaceID, &event.IPAddress, &event.RequestID, &details, &event.CreatedAt,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan audit event row: %w", err)
+		}
+		if err := json.Unmarshal([]byte(details), &event.Details); err != nil {
+			return nil, 0, fmt.Errorf("failed to decode audit event details: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating audit event rows: %w", err)
+	}
+
+	return events, total, nil
+}
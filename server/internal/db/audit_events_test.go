@@ -0,0 +1,100 @@
+package db_test
+
+import (
+	"testing"
+	"time"
+
+	"lemma/internal/db"
+	"lemma/internal/models"
+	_ "lemma/internal/testenv"
+)
+
+func TestAuditEventOperations(t *testing.T) {
+	database, err := db.NewTestDB(":memory:", &mockSecrets{})
+	if err != nil {
+		t.Fatalf("failed to create test database: %v", err)
+	}
+	defer database.Close()
+
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	actorID := 1
+	workspaceID := 7
+	start := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	events := []*models.AuditEvent{
+		{Event: "login_failed", IPAddress: "192.0.2.1", Details: map[string]any{"email": "a@example.com"}, CreatedAt: start},
+		{Event: "login", ActorID: &actorID, TargetType: models.AuditTargetUser, TargetID: "1", IPAddress: "192.0.2.1", RequestID: "req-1", CreatedAt: start.Add(time.Minute)},
+		{Event: "file_deleted", ActorID: &actorID, TargetType: models.AuditTargetFile, TargetID: "notes/todo.md", WorkspaceID: &workspaceID, CreatedAt: start.Add(2 * time.Minute)},
+	}
+	for _, event := range events {
+		if err := database.CreateAuditEvent(event); err != nil {
+			t.Fatalf("CreateAuditEvent() error = %v", err)
+		}
+		if event.ID == 0 {
+			t.Error("CreateAuditEvent() did not set the ID")
+		}
+	}
+
+	t.Run("lists events newest first", func(t *testing.T) {
+		got, total, err := database.GetAuditEvents(models.AuditEventFilter{Limit: 10})
+		if err != nil {
+			t.Fatalf("GetAuditEvents() error = %v", err)
+		}
+		if total != 3 || len(got) != 3 {
+			t.Fatalf("GetAuditEvents() = %d events, total %d, want 3 and 3", len(got), total)
+		}
+		if got[0].Event != "file_deleted" || got[2].Event != "login_failed" {
+			t.Errorf("GetAuditEvents() order = %s, %s, %s", got[0].Event, got[1].Event, got[2].Event)
+		}
+		if got[2].ActorID != nil || got[2].Details["email"] != "a@example.com" {
+			t.Errorf("GetAuditEvents() event = %+v, want no actor and email detail", got[2])
+		}
+		if got[0].WorkspaceID == nil || *got[0].WorkspaceID != workspaceID {
+			t.Errorf("GetAuditEvents() workspace ID = %v, want %d", got[0].WorkspaceID, workspaceID)
+		}
+	})
+
+	t.Run("filters events", func(t *testing.T) {
+		since := start.Add(30 * time.Second)
+		tests := []struct {
+			name   string
+			filter models.AuditEventFilter
+			want   []string
+		}{
+			{"by event", models.AuditEventFilter{Event: "login"}, []string{"login"}},
+			{"by actor", models.AuditEventFilter{ActorID: &actorID}, []string{"file_deleted", "login"}},
+			{"by target", models.AuditEventFilter{TargetType: models.AuditTargetFile, TargetID: "notes/todo.md"}, []string{"file_deleted"}},
+			{"by workspace", models.AuditEventFilter{WorkspaceID: &workspaceID}, []string{"file_deleted"}},
+			{"by time", models.AuditEventFilter{Since: &since}, []string{"file_deleted", "login"}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				tt.filter.Limit = 10
+				got, total, err := database.GetAuditEvents(tt.filter)
+				if err != nil {
+					t.Fatalf("GetAuditEvents() error = %v", err)
+				}
+				if total != len(tt.want) || len(got) != len(tt.want) {
+					t.Fatalf("GetAuditEvents() = %d events, total %d, want %d", len(got), total, len(tt.want))
+				}
+				for i, event := range got {
+					if event.Event != tt.want[i] {
+						t.Errorf("event %d = %s, want %s", i, event.Event, tt.want[i])
+					}
+				}
+			})
+		}
+	})
+
+	t.Run("pages events", func(t *testing.T) {
+		got, total, err := database.GetAuditEvents(models.AuditEventFilter{Limit: 1, Offset: 1})
+		if err != nil {
+			t.Fatalf("GetAuditEvents() error = %v", err)
+		}
+		if total != 3 || len(got) != 1 || got[0].Event != "login" {
+			t.Errorf("GetAuditEvents() = %d events, total %d, want the second of 3", len(got), total)
+		}
+	})
+}
@@ -0,0 +1,106 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// ErrBackupNotSupported is returned when backing up a database that isn't SQLite
+var ErrBackupNotSupported = errors.New("online backups are only supported for SQLite databases")
+
+// backupStepPages is the number of pages copied per backup step. Writers can
+// access the database between steps.
+const backupStepPages = 1024
+
+// Backup copies the database to a new SQLite database file at destPath using
+// SQLite's online backup API, so the database stays usable while it is copied
+func (db *database) Backup(destPath string) error {
+	log := getLogger()
+
+	if db.dialect.name != DialectSQLite {
+		return ErrBackupNotSupported
+	}
+
+	dest, err := sql.Open(db.dialect.driverName, destPath)
+	if err != nil {
+		return fmt.Errorf("failed to open backup database: %w", err)
+	}
+	defer dest.Close()
+
+	ctx := context.Background()
+	destConn, err := dest.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect to backup database: %w", err)
+	}
+	defer destConn.Close()
+
+	srcConn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer srcConn.Close()
+
+	err = destConn.Raw(func(destDriverConn any) error {
+		return srcConn.Raw(func(srcDriverConn any) error {
+			destSQLite, ok := destDriverConn.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("unexpected backup connection type %T", destDriverConn)
+			}
+			srcSQLite, ok := srcDriverConn.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("unexpected database connection type %T", srcDriverConn)
+			}
+
+			backup, err := destSQLite.Backup("main", srcSQLite, "main")
+			if err != nil {
+				return err
+			}
+
+			for done := false; !done; {
+				if done, err = backup.Step(backupStepPages); err != nil {
+					backup.Close()
+					return err
+				}
+			}
+
+			return backup.Finish()
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to back up database: %w", err)
+	}
+
+	log.Info("database backed up", "path", destPath)
+	return nil
+}
+
+// CheckIntegrity verifies the structure of a SQLite database file. Other
+// databases are assumed to be intact.
+func (db *database) CheckIntegrity() error {
+	if db.dialect.name != DialectSQLite {
+		return nil
+	}
+
+	var result string
+	if err := db.QueryRow("PRAGMA integrity_check").Scan(&result); err != nil {
+		return fmt.Errorf("failed to check database integrity: %w", err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("database is corrupt: %s", result)
+	}
+
+	return nil
+}
+
+// SchemaVersion returns the version of the latest applied migration
+func (db *database) SchemaVersion() (int, error) {
+	var version int
+	if err := db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM migrations").Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to get schema version: %w", err)
+	}
+	return version, nil
+}
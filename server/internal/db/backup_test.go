@@ -0,0 +1,71 @@
+package db_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"lemma/internal/db"
+	"lemma/internal/models"
+	_ "lemma/internal/testenv"
+)
+
+func TestBackup(t *testing.T) {
+	dir := t.TempDir()
+
+	database, err := db.NewTestDB(filepath.Join(dir, "lemma.db"), &mockSecrets{})
+	if err != nil {
+		t.Fatalf("failed to create test database: %v", err)
+	}
+	defer database.Close()
+
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	user, err := database.CreateUser(&models.User{
+		Email:        "backup@example.com",
+		DisplayName:  "Backup User",
+		PasswordHash: "hash",
+		Role:         models.RoleEditor,
+	})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	backupPath := filepath.Join(dir, "backup.db")
+	if err := database.Backup(backupPath); err != nil {
+		t.Fatalf("Backup() error = %v", err)
+	}
+
+	backup, err := db.NewTestDB(backupPath, &mockSecrets{})
+	if err != nil {
+		t.Fatalf("failed to open backup: %v", err)
+	}
+	defer backup.Close()
+
+	t.Run("backup is intact", func(t *testing.T) {
+		if err := backup.CheckIntegrity(); err != nil {
+			t.Errorf("CheckIntegrity() error = %v", err)
+		}
+	})
+
+	t.Run("backup has the schema version", func(t *testing.T) {
+		version, err := backup.SchemaVersion()
+		if err != nil {
+			t.Fatalf("SchemaVersion() error = %v", err)
+		}
+		if version != 18 {
+			t.Errorf("SchemaVersion() = %d, want 18", version)
+		}
+	})
+
+	t.Run("backup has the data", func(t *testing.T) {
+		got, err := backup.GetUserByEmail("backup@example.com")
+		if err != nil {
+			t.Fatalf("failed to get user from backup: %v", err)
+		}
+		if got.ID != user.ID || got.DisplayName != user.DisplayName {
+			t.Errorf("user in backup = %+v, want %+v", got, user)
+		}
+	})
+}
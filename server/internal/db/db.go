@@ -4,6 +4,7 @@ package db
 import (
 	"database/sql"
 	"fmt"
+	"time"
 
 	"lemma/internal/logging"
 	"lemma/internal/models"
@@ -15,11 +16,16 @@ import (
 // UserStore defines the methods for interacting with user data in the database
 type UserStore interface {
 	CreateUser(user *models.User) (*models.User, error)
+	CreateUserWithWorkspace(user *models.User, workspaceName string) (*models.User, error)
 	GetUserByEmail(email string) (*models.User, error)
 	GetUserByID(userID int) (*models.User, error)
 	GetAllUsers() ([]*models.User, error)
 	UpdateUser(user *models.User) error
 	DeleteUser(userID int) error
+	GetDeletedUsers() ([]*models.User, error)
+	RestoreUser(userID int) error
+	PurgeUser(userID int) error
+	EmailExists(email string) (bool, error)
 	UpdateLastWorkspace(userID int, workspaceName string) error
 	GetLastWorkspaceName(userID int) (string, error)
 	CountAdminUsers() (int, error)
@@ -30,7 +36,10 @@ type WorkspaceReader interface {
 	GetWorkspaceByID(workspaceID int) (*models.Workspace, error)
 	GetWorkspaceByName(userID int, workspaceName string) (*models.Workspace, error)
 	GetWorkspacesByUserID(userID int) ([]*models.Workspace, error)
+	GetMemberWorkspaceByName(userID int, workspaceName string) (*models.Workspace, error)
+	GetWorkspacesByMemberID(userID int) ([]*models.Workspace, error)
 	GetAllWorkspaces() ([]*models.Workspace, error)
+	GetDeletedWorkspaces() ([]*models.Workspace, error)
 }
 
 // WorkspaceWriter defines the methods for writing workspace data to the database
@@ -38,7 +47,10 @@ type WorkspaceWriter interface {
 	CreateWorkspace(workspace *models.Workspace) error
 	UpdateWorkspace(workspace *models.Workspace) error
 	DeleteWorkspace(workspaceID int) error
+	RestoreWorkspace(workspaceID int) (string, error)
+	PurgeWorkspace(workspaceID int) error
 	UpdateWorkspaceSettings(workspace *models.Workspace) error
+	TransferWorkspace(workspace *models.Workspace, newOwnerID int, previousOwnerRole models.WorkspaceRole) (*models.Workspace, error)
 	DeleteWorkspaceTx(tx *sql.Tx, workspaceID int) error
 	UpdateLastWorkspaceTx(tx *sql.Tx, userID, workspaceID int) error
 	UpdateLastOpenedFile(workspaceID int, filePath string) error
@@ -51,32 +63,134 @@ type WorkspaceStore interface {
 	WorkspaceWriter
 }
 
+// WorkspaceMemberStore defines the methods for managing access to shared workspaces in the database
+type WorkspaceMemberStore interface {
+	CreateWorkspaceMember(member *models.WorkspaceMember) error
+	GetWorkspaceMember(workspaceID, userID int) (*models.WorkspaceMember, error)
+	GetWorkspaceMembers(workspaceID int) ([]*models.WorkspaceMember, error)
+	UpdateWorkspaceMember(member *models.WorkspaceMember) error
+	DeleteWorkspaceMember(workspaceID, userID int) error
+}
+
+// ShareLinkStore defines the methods for managing public share links in the database
+type ShareLinkStore interface {
+	CreateShareLink(link *models.ShareLink) error
+	GetShareLinks(workspaceID int) ([]*models.ShareLink, error)
+	GetShareLinkByTokenHash(tokenHash string) (*models.ShareLink, error)
+	DeleteShareLink(workspaceID, linkID int) error
+}
+
+// AuditEventStore defines the methods for recording and querying the audit log in the database
+type AuditEventStore interface {
+	CreateAuditEvent(event *models.AuditEvent) error
+	GetAuditEvents(filter models.AuditEventFilter) ([]*models.AuditEvent, int, error)
+}
+
+// QuotaStore defines the methods for managing storage quotas and usage in the database
+type QuotaStore interface {
+	GetDefaultQuota() (*models.StorageQuota, error)
+	UpdateDefaultQuota(quota *models.StorageQuota) error
+	GetUserQuota(userID int) (*models.StorageQuota, error)
+	SetUserQuota(userID int, quota *models.StorageQuota) error
+	DeleteUserQuota(userID int) error
+	GetStorageQuota(userID int) (*models.StorageQuota, error)
+	GetWorkspaceQuota(workspaceID int) (*models.StorageQuota, error)
+	SetWorkspaceQuota(workspaceID int, quota *models.StorageQuota) error
+	DeleteWorkspaceQuota(workspaceID int) error
+	GetWorkspaceUsage(workspaceID int) (*models.StorageUsage, error)
+	SetWorkspaceUsage(workspaceID int, usage *models.StorageUsage) error
+}
+
 // SessionStore defines the methods for interacting with jwt sessions in the database
 type SessionStore interface {
 	CreateSession(session *models.Session) error
 	GetSessionByRefreshToken(refreshToken string) (*models.Session, error)
 	GetSessionByID(sessionID string) (*models.Session, error)
+	GetSessionsByUserID(userID int) ([]*models.Session, error)
+	UpdateSessionLastUsed(sessionID string, lastUsedAt time.Time) error
+	RotateRefreshToken(session *models.Session, newRefreshToken string) error
+	GetRotatedRefreshToken(refreshToken string) (*models.RotatedRefreshToken, error)
 	DeleteSession(sessionID string) error
+	DeleteUserSessions(userID int, exceptSessionID string) error
 	CleanExpiredSessions() error
+	CountActiveSessions() (int, error)
+}
+
+// LoginAttemptStore defines the methods for tracking failed login attempts in the database
+type LoginAttemptStore interface {
+	GetLoginAttempt(scope models.LoginAttemptScope, key string) (*models.LoginAttempt, error)
+	RecordLoginFailure(scope models.LoginAttemptScope, key string, now, staleBefore time.Time) (*models.LoginAttempt, error)
+	LockLogin(scope models.LoginAttemptScope, key string, until time.Time) (bool, error)
+	DeleteLoginAttempt(scope models.LoginAttemptScope, key string) error
+}
+
+// JWTKeyStore defines the methods for storing the JWT signing keyring in the database
+type JWTKeyStore interface {
+	CreateJWTKey(key *models.JWTKey) error
+	GetJWTKeys() ([]*models.JWTKey, error)
+	DeleteRetiredJWTKeys(retiredBefore time.Time) error
+}
+
+// UserTokenStore defines the methods for storing single-use user tokens in the database
+type UserTokenStore interface {
+	CreateUserToken(token *models.UserToken) error
+	ConsumeUserToken(tokenHash string, purpose models.UserTokenPurpose) (*models.UserToken, error)
+	CleanExpiredUserTokens() error
+}
+
+// InvitationStore defines the methods for managing signup invitations in the database
+type InvitationStore interface {
+	CreateInvitation(invitation *models.Invitation) error
+	GetInvitations() ([]*models.Invitation, error)
+	GetInvitationByTokenHash(tokenHash string) (*models.Invitation, error)
+	DeleteInvitation(invitationID int) error
+	CreateUserWithInvitation(user *models.User, invitationID int) (*models.User, error)
+}
+
+// BackupStore defines the methods for backing up the database
+type BackupStore interface {
+	Backup(destPath string) error
+	CheckIntegrity() error
+	SchemaVersion() (int, error)
+}
+
+// SecretStore defines the methods for managing encrypted data in the database
+type SecretStore interface {
+	ReencryptSecrets() (int, error)
 }
 
 // SystemStore defines the methods for interacting with system settings and stats in the database
 type SystemStore interface {
 	GetSystemStats() (*UserStats, error)
-	EnsureJWTSecret() (string, error)
 	GetSystemSetting(key string) (string, error)
 	SetSystemSetting(key, value string) error
+	GetRegistrationSettings() (*models.RegistrationSettings, error)
+	UpdateRegistrationSettings(settings *models.RegistrationSettings) error
 }
 
 // Database defines the methods for interacting with the database
 type Database interface {
 	UserStore
 	WorkspaceStore
+	WorkspaceMemberStore
+	ShareLinkStore
+	AuditEventStore
+	QuotaStore
 	SessionStore
+	LoginAttemptStore
+	JWTKeyStore
+	UserTokenStore
+	InvitationStore
 	SystemStore
+	BackupStore
+	SecretStore
 	Begin() (*sql.Tx, error)
+	Stats() sql.DBStats
 	Close() error
 	Migrate() error
+	MigrateUp(version int) error
+	MigrateDown(version int) error
+	MigrationStatus() ([]MigrationStatus, error)
 }
 
 // Verify that the database implements the required interfaces
@@ -85,10 +199,20 @@ var (
 	_ Database = (*database)(nil)
 
 	// Component interfaces
-	_ UserStore      = (*database)(nil)
-	_ WorkspaceStore = (*database)(nil)
-	_ SessionStore   = (*database)(nil)
-	_ SystemStore    = (*database)(nil)
+	_ UserStore            = (*database)(nil)
+	_ WorkspaceStore       = (*database)(nil)
+	_ WorkspaceMemberStore = (*database)(nil)
+	_ ShareLinkStore       = (*database)(nil)
+	_ AuditEventStore      = (*database)(nil)
+	_ QuotaStore           = (*database)(nil)
+	_ SessionStore         = (*database)(nil)
+	_ LoginAttemptStore    = (*database)(nil)
+	_ JWTKeyStore          = (*database)(nil)
+	_ UserTokenStore       = (*database)(nil)
+	_ InvitationStore      = (*database)(nil)
+	_ SystemStore          = (*database)(nil)
+	_ BackupStore          = (*database)(nil)
+	_ SecretStore          = (*database)(nil)
 
 	// Sub-interfaces
 	_ WorkspaceReader = (*database)(nil)
@@ -107,30 +231,60 @@ func getLogger() logging.Logger {
 // database represents the database connection
 type database struct {
 	*sql.DB
+	dialect        *dialect
 	secretsService secrets.Service
 }
 
-// Init initializes the database connection
-func Init(dbPath string, secretsService secrets.Service) (Database, error) {
+// Options configures the connection pool of the database
+type Options struct {
+	MaxOpenConns    int           // Maximum number of open connections, unlimited if 0
+	MaxIdleConns    int           // Maximum number of idle connections, the database/sql default if 0
+	ConnMaxLifetime time.Duration // Maximum time a connection is reused, unlimited if 0
+}
+
+// Init initializes the database connection with the default connection pool. The database
+// is PostgreSQL for postgres:// URLs and otherwise the SQLite database at the given path.
+func Init(dbURL string, secretsService secrets.Service) (Database, error) {
+	return InitWithOptions(dbURL, secretsService, Options{})
+}
+
+// InitWithOptions initializes the database connection with the given connection pool options
+func InitWithOptions(dbURL string, secretsService secrets.Service, options Options) (Database, error) {
 	log := getLogger()
 
-	db, err := sql.Open("sqlite3", dbPath)
+	dialect, dsn := parseDatabaseURL(dbURL)
+	db, err := sql.Open(dialect.driverName, dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	if err := db.Ping(); err != nil {
-		return nil, fmt.Errorf("failed to ping database: %w", err)
+	if options.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(options.MaxOpenConns)
+	}
+	if options.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(options.MaxIdleConns)
+	}
+	if options.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(options.ConnMaxLifetime)
 	}
 
-	// Enable foreign keys for this connection
-	if _, err := db.Exec("PRAGMA foreign_keys = ON"); err != nil {
-		return nil, fmt.Errorf("failed to enable foreign keys: %w", err)
+	// Every connection to an in-memory SQLite database opens a separate, empty database
+	if dialect == sqliteDialect && isSQLiteMemory(dsn) {
+		db.SetMaxOpenConns(1)
+		db.SetMaxIdleConns(1)
+		db.SetConnMaxLifetime(0)
+	}
+
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
-	log.Debug("foreign keys enabled")
+	log.Debug("database opened",
+		"dialect", dialect.name,
+		"maxOpenConns", db.Stats().MaxOpenConnections)
 
 	database := &database{
 		DB:             db,
+		dialect:        dialect,
 		secretsService: secretsService,
 	}
 
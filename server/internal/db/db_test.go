@@ -0,0 +1,45 @@
+package db_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"lemma/internal/db"
+	_ "lemma/internal/testenv"
+)
+
+func TestConnectionPragmas(t *testing.T) {
+	database, err := db.NewTestDB(filepath.Join(t.TempDir(), "lemma.db"), nil)
+	if err != nil {
+		t.Fatalf("failed to create test database: %v", err)
+	}
+	defer database.Close()
+
+	// Hold several connections at once, so the pool has to open new ones
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		conn, err := database.TestDB().Conn(ctx)
+		if err != nil {
+			t.Fatalf("failed to open connection: %v", err)
+		}
+		defer conn.Close()
+
+		var journalMode string
+		var foreignKeys, busyTimeout int
+		if err := conn.QueryRowContext(ctx, "PRAGMA journal_mode").Scan(&journalMode); err != nil {
+			t.Fatalf("failed to query journal mode: %v", err)
+		}
+		if err := conn.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&foreignKeys); err != nil {
+			t.Fatalf("failed to query foreign keys: %v", err)
+		}
+		if err := conn.QueryRowContext(ctx, "PRAGMA busy_timeout").Scan(&busyTimeout); err != nil {
+			t.Fatalf("failed to query busy timeout: %v", err)
+		}
+
+		if journalMode != "wal" || foreignKeys != 1 || busyTimeout != 5000 {
+			t.Errorf("connection %d: journal_mode = %q, foreign_keys = %d, busy_timeout = %d, want wal, 1, 5000",
+				i, journalMode, foreignKeys, busyTimeout)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package db
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Names of the supported database dialects
+const (
+	DialectSQLite   = "sqlite"
+	DialectPostgres = "postgres"
+)
+
+// dialect describes what differs between the supported database systems.
+// Queries are written with ? placeholders and portable SQL, so only the
+// driver, the schema migrations and the connection setup are dialect specific.
+type dialect struct {
+	name       string
+	driverName string
+	migrations []Migration
+}
+
+var sqliteDialect = &dialect{
+	name:       DialectSQLite,
+	driverName: "sqlite3",
+	migrations: sqliteMigrations,
+}
+
+var postgresDialect = &dialect{
+	name:       DialectPostgres,
+	driverName: postgresDriverName,
+	migrations: postgresMigrations,
+}
+
+// parseDatabaseURL returns the dialect of the database URL and the data source name for its driver.
+// postgres:// and postgresql:// URLs select PostgreSQL, anything else is the path of a SQLite database.
+func parseDatabaseURL(dbURL string) (*dialect, string) {
+	if strings.HasPrefix(dbURL, "postgres://") || strings.HasPrefix(dbURL, "postgresql://") {
+		return postgresDialect, dbURL
+	}
+	return sqliteDialect, sqliteDSN(strings.TrimPrefix(dbURL, "sqlite://"))
+}
+
+// sqliteBusyTimeout is how long, in milliseconds, a connection waits for another one to release a lock
+const sqliteBusyTimeout = "5000"
+
+// sqliteDSN adds the pragmas to the path of a SQLite database. They are part of the data source
+// name, as database/sql opens connections on demand and a PRAGMA statement only affects one of them.
+// Transactions take the write lock when they begin, so concurrent writers wait for the busy
+// timeout instead of failing when a read lock can't be upgraded.
+func sqliteDSN(path string) string {
+	params := url.Values{}
+	params.Set("_foreign_keys", "on")
+	params.Set("_busy_timeout", sqliteBusyTimeout)
+	params.Set("_txlock", "immediate")
+	if !isSQLiteMemory(path) {
+		params.Set("_journal_mode", "WAL")
+		params.Set("_synchronous", "NORMAL")
+	}
+
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	return path + separator + params.Encode()
+}
+
+// isSQLiteMemory reports whether the SQLite data source is an in-memory database
+func isSQLiteMemory(dsn string) bool {
+	return strings.HasPrefix(dsn, ":memory:") || strings.Contains(dsn, "mode=memory")
+}
@@ -0,0 +1,177 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"lemma/internal/models"
+)
+
+// ErrInvitationUnavailable is returned when an invitation has expired or reached its maximum uses
+var ErrInvitationUnavailable = errors.New("invitation expired or used up")
+
+// CreateInvitation inserts a new invitation and sets its ID
+func (db *database) CreateInvitation(invitation *models.Invitation) error {
+	log := getLogger().WithGroup("invitations")
+
+	if invitation.CreatedAt.IsZero() {
+		invitation.CreatedAt = time.Now()
+	}
+
+	err := db.QueryRow(`
+        INSERT INTO invitations (
+            token_hash, role, email, workspace_name, max_uses, use_count, expires_at, created_by, created_at
+        )
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+        RETURNING id`,
+		invitation.TokenHash, invitation.Role, invitation.Email, invitation.WorkspaceName, invitation.MaxUses,
+		invitation.UseCount, invitation.ExpiresAt, invitation.CreatedBy, invitation.CreatedAt,
+	).Scan(&invitation.ID)
+	if err != nil {
+		return fmt.Errorf("failed to insert invitation: %w", err)
+	}
+
+	log.Debug("invitation created",
+		"invitation_id", invitation.ID,
+		"role", invitation.Role,
+		"created_by", invitation.CreatedBy)
+	return nil
+}
+
+// GetInvitations retrieves all invitations, newest first
+func (db *database) GetInvitations() ([]*models.Invitation, error) {
+	rows, err := db.Query(`
+        SELECT id, token_hash, role, email, workspace_name, max_uses, use_count, expires_at, created_by, created_at
+        FROM invitations
+        ORDER BY created_at DESC, id DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query invitations: %w", err)
+	}
+	defer rows.Close()
+
+	var invitations []*models.Invitation
+	for rows.Next() {
+		invitation, err := scanInvitation(rows)
+		if err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, invitation)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating invitation rows: %w", err)
+	}
+
+	return invitations, nil
+}
+
+// GetInvitationByTokenHash retrieves an invitation by the hash of its token
+func (db *database) GetInvitationByTokenHash(tokenHash string) (*models.Invitation, error) {
+	row := db.QueryRow(`
+        SELECT id, token_hash, role, email, workspace_name, max_uses, use_count, expires_at, created_by, created_at
+        FROM invitations
+        WHERE token_hash = ?`,
+		tokenHash,
+	)
+
+	invitation, err := scanInvitation(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("invitation not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return invitation, nil
+}
+
+// DeleteInvitation removes an invitation so it can no longer be redeemed
+func (db *database) DeleteInvitation(invitationID int) error {
+	result, err := db.Exec("DELETE FROM invitations WHERE id = ?", invitationID)
+	if err != nil {
+		return fmt.Errorf("failed to delete invitation: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("invitation not found")
+	}
+
+	return nil
+}
+
+// CreateUserWithInvitation counts a use of the invitation and creates the user in one transaction.
+// ErrInvitationUnavailable is returned if the invitation has expired or is used up.
+func (db *database) CreateUserWithInvitation(user *models.User, invitationID int) (*models.User, error) {
+	log := getLogger().WithGroup("invitations")
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+        UPDATE invitations SET use_count = use_count + 1
+        WHERE id = ? AND use_count < max_uses AND expires_at > ?`,
+		invitationID, time.Now(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update invitation: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return nil, ErrInvitationUnavailable
+	}
+
+	var workspaceName string
+	err = tx.QueryRow("SELECT workspace_name FROM invitations WHERE id = ?", invitationID).Scan(&workspaceName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invitation workspace name: %w", err)
+	}
+	if workspaceName == "" {
+		workspaceName = models.DefaultWorkspaceName
+	}
+
+	if err := db.createUserTx(tx, user, workspaceName); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	log.Debug("created user with invitation",
+		"user_id", user.ID,
+		"invitation_id", invitationID)
+	return user, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanInvitation(row rowScanner) (*models.Invitation, error) {
+	invitation := &models.Invitation{}
+	err := row.Scan(
+		&invitation.ID, &invitation.TokenHash, &invitation.Role, &invitation.Email,
+		&invitation.WorkspaceName, &invitation.MaxUses, &invitation.UseCount, &invitation.ExpiresAt,
+		&invitation.CreatedBy, &invitation.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan invitation: %w", err)
+	}
+	return invitation, nil
+}
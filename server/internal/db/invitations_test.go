@@ -0,0 +1,192 @@
+package db_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"lemma/internal/db"
+	"lemma/internal/models"
+	_ "lemma/internal/testenv"
+)
+
+func TestInvitationOperations(t *testing.T) {
+	database, err := db.NewTestDB(":memory:", &mockSecrets{})
+	if err != nil {
+		t.Fatalf("failed to create test database: %v", err)
+	}
+	defer database.Close()
+
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	admin, err := database.CreateUser(&models.User{
+		Email:        "admin@example.com",
+		DisplayName:  "Admin",
+		PasswordHash: "hash",
+		Role:         models.RoleAdmin,
+	})
+	if err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+
+	createInvitation := func(t *testing.T, hash string, maxUses int, expiresAt time.Time) *models.Invitation {
+		t.Helper()
+		invitation := &models.Invitation{
+			TokenHash: hash,
+			Role:      models.RoleViewer,
+			MaxUses:   maxUses,
+			ExpiresAt: expiresAt,
+			CreatedBy: admin.ID,
+		}
+		if err := database.CreateInvitation(invitation); err != nil {
+			t.Fatalf("failed to create invitation: %v", err)
+		}
+		return invitation
+	}
+
+	newUser := func(email string) *models.User {
+		return &models.User{
+			Email:        email,
+			PasswordHash: "hash",
+			Role:         models.RoleViewer,
+		}
+	}
+
+	t.Run("CreateInvitation and GetInvitationByTokenHash", func(t *testing.T) {
+		invitation := createInvitation(t, "lookup-hash", 1, time.Now().Add(time.Hour))
+		if invitation.ID == 0 {
+			t.Fatal("expected invitation ID to be set")
+		}
+
+		got, err := database.GetInvitationByTokenHash("lookup-hash")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got.ID != invitation.ID || got.Role != models.RoleViewer || got.CreatedBy != admin.ID {
+			t.Errorf("invitation = %+v, want %+v", got, invitation)
+		}
+
+		if _, err := database.GetInvitationByTokenHash("unknown-hash"); err == nil {
+			t.Error("expected error for unknown invitation, got nil")
+		}
+	})
+
+	t.Run("CreateUserWithInvitation respects max uses", func(t *testing.T) {
+		invitation := createInvitation(t, "limited-hash", 2, time.Now().Add(time.Hour))
+
+		for _, email := range []string{"first@example.com", "second@example.com"} {
+			user, err := database.CreateUserWithInvitation(newUser(email), invitation.ID)
+			if err != nil {
+				t.Fatalf("failed to create user with invitation: %v", err)
+			}
+			if user.ID == 0 || user.LastWorkspaceID == 0 {
+				t.Errorf("user = %+v, want ID and default workspace to be set", user)
+			}
+		}
+
+		_, err := database.CreateUserWithInvitation(newUser("third@example.com"), invitation.ID)
+		if !errors.Is(err, db.ErrInvitationUnavailable) {
+			t.Errorf("error = %v, want %v", err, db.ErrInvitationUnavailable)
+		}
+		if _, err := database.GetUserByEmail("third@example.com"); err == nil {
+			t.Error("user was created with a used up invitation")
+		}
+
+		got, err := database.GetInvitationByTokenHash("limited-hash")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got.UseCount != 2 {
+			t.Errorf("UseCount = %d, want 2", got.UseCount)
+		}
+	})
+
+	t.Run("CreateUserWithInvitation rejects expired invitation", func(t *testing.T) {
+		invitation := createInvitation(t, "expired-hash", 1, time.Now().Add(-time.Minute))
+
+		_, err := database.CreateUserWithInvitation(newUser("late@example.com"), invitation.ID)
+		if !errors.Is(err, db.ErrInvitationUnavailable) {
+			t.Errorf("error = %v, want %v", err, db.ErrInvitationUnavailable)
+		}
+	})
+
+	t.Run("failed user creation does not use the invitation", func(t *testing.T) {
+		invitation := createInvitation(t, "duplicate-hash", 1, time.Now().Add(time.Hour))
+
+		if _, err := database.CreateUserWithInvitation(newUser(admin.Email), invitation.ID); err == nil {
+			t.Fatal("expected error for duplicate email, got nil")
+		}
+
+		got, err := database.GetInvitationByTokenHash("duplicate-hash")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got.UseCount != 0 {
+			t.Errorf("UseCount = %d, want 0", got.UseCount)
+		}
+	})
+
+	t.Run("GetInvitations and DeleteInvitation", func(t *testing.T) {
+		invitations, err := database.GetInvitations()
+		if err != nil {
+			t.Fatalf("failed to get invitations: %v", err)
+		}
+		if len(invitations) != 4 {
+			t.Fatalf("got %d invitations, want 4", len(invitations))
+		}
+		if invitations[0].TokenHash != "duplicate-hash" {
+			t.Errorf("first invitation = %q, want newest first", invitations[0].TokenHash)
+		}
+
+		if err := database.DeleteInvitation(invitations[0].ID); err != nil {
+			t.Fatalf("failed to delete invitation: %v", err)
+		}
+		if err := database.DeleteInvitation(invitations[0].ID); err == nil {
+			t.Error("expected error deleting missing invitation, got nil")
+		}
+
+		invitations, err = database.GetInvitations()
+		if err != nil {
+			t.Fatalf("failed to get invitations: %v", err)
+		}
+		if len(invitations) != 3 {
+			t.Errorf("got %d invitations, want 3", len(invitations))
+		}
+	})
+
+	t.Run("CreateUserWithInvitation names the initial workspace", func(t *testing.T) {
+		invitation := &models.Invitation{
+			TokenHash:     "workspace-hash",
+			Role:          models.RoleViewer,
+			WorkspaceName: "Course",
+			MaxUses:       1,
+			ExpiresAt:     time.Now().Add(time.Hour),
+			CreatedBy:     admin.ID,
+		}
+		if err := database.CreateInvitation(invitation); err != nil {
+			t.Fatalf("failed to create invitation: %v", err)
+		}
+
+		got, err := database.GetInvitationByTokenHash("workspace-hash")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got.WorkspaceName != "Course" {
+			t.Errorf("WorkspaceName = %q, want %q", got.WorkspaceName, "Course")
+		}
+
+		user, err := database.CreateUserWithInvitation(newUser("course@example.com"), invitation.ID)
+		if err != nil {
+			t.Fatalf("failed to create user with invitation: %v", err)
+		}
+		workspace, err := database.GetWorkspaceByID(user.LastWorkspaceID)
+		if err != nil {
+			t.Fatalf("failed to get workspace: %v", err)
+		}
+		if workspace.Name != "Course" {
+			t.Errorf("workspace name = %q, want %q", workspace.Name, "Course")
+		}
+	})
+}
@@ -0,0 +1,115 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"lemma/internal/models"
+)
+
+// CreateJWTKey stores a new JWT key with its private key encrypted.
+// If the key is active, all previously active keys are retired in the same transaction.
+func (db *database) CreateJWTKey(key *models.JWTKey) error {
+	log := getLogger().WithGroup("jwt_keys")
+
+	encryptedKey, err := db.encryptToken(key.PrivateKey)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt private key: %w", err)
+	}
+
+	if key.CreatedAt.IsZero() {
+		key.CreatedAt = time.Now()
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if key.Active {
+		_, err = tx.Exec("UPDATE jwt_keys SET active = FALSE, retired_at = ? WHERE active = TRUE", key.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("failed to retire active keys: %w", err)
+		}
+	}
+
+	_, err = tx.Exec(`
+        INSERT INTO jwt_keys (id, algorithm, private_key, public_key, active, created_at)
+        VALUES (?, ?, ?, ?, ?, ?)`,
+		key.ID, key.Algorithm, encryptedKey, key.PublicKey, key.Active, key.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert JWT key: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	log.Debug("JWT key created",
+		"key_id", key.ID,
+		"algorithm", key.Algorithm,
+		"active", key.Active)
+	return nil
+}
+
+// GetJWTKeys retrieves all JWT keys with decrypted private keys, newest first
+func (db *database) GetJWTKeys() ([]*models.JWTKey, error) {
+	rows, err := db.Query(`
+        SELECT id, algorithm, private_key, public_key, active, created_at, retired_at
+        FROM jwt_keys
+        ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query JWT keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []*models.JWTKey{}
+	for rows.Next() {
+		key := &models.JWTKey{}
+		var encryptedKey string
+		var retiredAt sql.NullTime
+		err := rows.Scan(&key.ID, &key.Algorithm, &encryptedKey, &key.PublicKey,
+			&key.Active, &key.CreatedAt, &retiredAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan JWT key row: %w", err)
+		}
+
+		key.PrivateKey, err = db.decryptToken(encryptedKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt private key of JWT key %s: %w", key.ID, err)
+		}
+
+		if retiredAt.Valid {
+			key.RetiredAt = &retiredAt.Time
+		}
+
+		keys = append(keys, key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating JWT key rows: %w", err)
+	}
+
+	return keys, nil
+}
+
+// DeleteRetiredJWTKeys removes keys that were retired before the given time
+func (db *database) DeleteRetiredJWTKeys(retiredBefore time.Time) error {
+	log := getLogger().WithGroup("jwt_keys")
+
+	result, err := db.Exec("DELETE FROM jwt_keys WHERE active = FALSE AND retired_at <= ?", retiredBefore)
+	if err != nil {
+		return fmt.Errorf("failed to delete retired JWT keys: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	log.Debug("deleted retired JWT keys", "keys_removed", rowsAffected)
+	return nil
+}
@@ -0,0 +1,96 @@
+package db_test
+
+import (
+	"testing"
+	"time"
+
+	"lemma/internal/db"
+	"lemma/internal/models"
+	_ "lemma/internal/testenv"
+)
+
+func TestJWTKeyOperations(t *testing.T) {
+	database, err := db.NewTestDB(":memory:", &mockSecrets{})
+	if err != nil {
+		t.Fatalf("failed to create test database: %v", err)
+	}
+	defer database.Close()
+
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	first := &models.JWTKey{
+		ID:         "first",
+		Algorithm:  "HS256",
+		PrivateKey: "first-secret",
+		Active:     true,
+		CreatedAt:  time.Now().Add(-time.Hour),
+	}
+	second := &models.JWTKey{
+		ID:         "second",
+		Algorithm:  "EdDSA",
+		PrivateKey: "second-private",
+		PublicKey:  "second-public",
+		Active:     true,
+	}
+
+	t.Run("CreateJWTKey", func(t *testing.T) {
+		if err := database.CreateJWTKey(first); err != nil {
+			t.Fatalf("failed to create key: %v", err)
+		}
+		if err := database.CreateJWTKey(second); err != nil {
+			t.Fatalf("failed to create key: %v", err)
+		}
+
+		// Duplicate key IDs are rejected
+		if err := database.CreateJWTKey(&models.JWTKey{ID: "first", Algorithm: "HS256", PrivateKey: "x"}); err == nil {
+			t.Error("expected error for duplicate key ID")
+		}
+
+		// Unsupported algorithms are rejected
+		if err := database.CreateJWTKey(&models.JWTKey{ID: "bad", Algorithm: "none", PrivateKey: "x"}); err == nil {
+			t.Error("expected error for unsupported algorithm")
+		}
+	})
+
+	t.Run("GetJWTKeys", func(t *testing.T) {
+		keys, err := database.GetJWTKeys()
+		if err != nil {
+			t.Fatalf("failed to get keys: %v", err)
+		}
+		if len(keys) != 2 {
+			t.Fatalf("keys = %d, want 2", len(keys))
+		}
+
+		// Newest first, creating an active key retires the previous one
+		if keys[0].ID != "second" || !keys[0].Active || keys[0].RetiredAt != nil {
+			t.Errorf("unexpected newest key: %+v", keys[0])
+		}
+		if keys[1].ID != "first" || keys[1].Active || keys[1].RetiredAt == nil {
+			t.Errorf("unexpected retired key: %+v", keys[1])
+		}
+		if keys[0].PrivateKey != "second-private" || keys[0].PublicKey != "second-public" {
+			t.Errorf("key material not stored: %+v", keys[0])
+		}
+	})
+
+	t.Run("DeleteRetiredJWTKeys", func(t *testing.T) {
+		// Keys retired after the cutoff are kept
+		if err := database.DeleteRetiredJWTKeys(time.Now().Add(-time.Hour)); err != nil {
+			t.Fatalf("failed to delete keys: %v", err)
+		}
+		keys, _ := database.GetJWTKeys()
+		if len(keys) != 2 {
+			t.Fatalf("keys = %d, want 2", len(keys))
+		}
+
+		if err := database.DeleteRetiredJWTKeys(time.Now()); err != nil {
+			t.Fatalf("failed to delete keys: %v", err)
+		}
+		keys, _ = database.GetJWTKeys()
+		if len(keys) != 1 || keys[0].ID != "second" {
+			t.Errorf("expected only the active key to remain, got %d keys", len(keys))
+		}
+	})
+}
@@ -0,0 +1,106 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"lemma/internal/models"
+)
+
+// GetLoginAttempt retrieves the failed login record for the given scope and key.
+// If no failures are recorded an empty attempt with a zero FailedCount is returned.
+func (db *database) GetLoginAttempt(scope models.LoginAttemptScope, key string) (*models.LoginAttempt, error) {
+	attempt := &models.LoginAttempt{
+		Scope: scope,
+		Key:   key,
+	}
+
+	var lockedUntil sql.NullTime
+	err := db.QueryRow(`
+        SELECT failed_count, last_failed_at, locked_until
+        FROM login_attempts
+        WHERE scope = ? AND key = ?`,
+		scope, key,
+	).Scan(&attempt.FailedCount, &attempt.LastFailedAt, &lockedUntil)
+
+	if err == sql.ErrNoRows {
+		return attempt, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch login attempt: %w", err)
+	}
+
+	if lockedUntil.Valid {
+		attempt.LockedUntil = lockedUntil.Time
+	}
+
+	return attempt, nil
+}
+
+// RecordLoginFailure atomically counts a failed login for the given scope and key and returns the
+// updated record. The count starts again at one if the last failure happened before staleBefore
+// or a lockout has ended by now.
+func (db *database) RecordLoginFailure(scope models.LoginAttemptScope, key string, now, staleBefore time.Time) (*models.LoginAttempt, error) {
+	attempt := &models.LoginAttempt{
+		Scope: scope,
+		Key:   key,
+	}
+
+	var lockedUntil sql.NullTime
+	err := db.QueryRow(`
+        INSERT INTO login_attempts (scope, key, failed_count, last_failed_at)
+        VALUES (?, ?, 1, ?)
+        ON CONFLICT (scope, key) DO UPDATE SET
+            failed_count = CASE
+                WHEN login_attempts.last_failed_at < ? OR login_attempts.locked_until <= ? THEN 1
+                ELSE login_attempts.failed_count + 1
+            END,
+            locked_until = CASE
+                WHEN login_attempts.last_failed_at < ? OR login_attempts.locked_until <= ? THEN NULL
+                ELSE login_attempts.locked_until
+            END,
+            last_failed_at = excluded.last_failed_at
+        RETURNING failed_count, last_failed_at, locked_until`,
+		scope, key, now, staleBefore, now, staleBefore, now,
+	).Scan(&attempt.FailedCount, &attempt.LastFailedAt, &lockedUntil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record failed login: %w", err)
+	}
+
+	if lockedUntil.Valid {
+		attempt.LockedUntil = lockedUntil.Time
+	}
+
+	return attempt, nil
+}
+
+// LockLogin locks out logins for the given scope and key until the given time. It returns false
+// without changing the record if it is already locked, so only one caller starts a lockout.
+func (db *database) LockLogin(scope models.LoginAttemptScope, key string, until time.Time) (bool, error) {
+	result, err := db.Exec(`
+        UPDATE login_attempts SET locked_until = ?
+        WHERE scope = ? AND key = ? AND locked_until IS NULL`,
+		until, scope, key,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to lock login: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// DeleteLoginAttempt clears the failed login record for the given scope and key
+func (db *database) DeleteLoginAttempt(scope models.LoginAttemptScope, key string) error {
+	_, err := db.Exec("DELETE FROM login_attempts WHERE scope = ? AND key = ?", scope, key)
+	if err != nil {
+		return fmt.Errorf("failed to delete login attempt: %w", err)
+	}
+
+	return nil
+}
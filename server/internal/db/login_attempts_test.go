@@ -0,0 +1,165 @@
+package db_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"lemma/internal/db"
+	"lemma/internal/models"
+	_ "lemma/internal/testenv"
+)
+
+func TestLoginAttemptOperations(t *testing.T) {
+	database, err := db.NewTestDB(":memory:", &mockSecrets{})
+	if err != nil {
+		t.Fatalf("failed to create test database: %v", err)
+	}
+	defer database.Close()
+
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	t.Run("GetLoginAttempt without failures", func(t *testing.T) {
+		attempt, err := database.GetLoginAttempt(models.LoginAttemptScopeEmail, "none@example.com")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if attempt.FailedCount != 0 {
+			t.Errorf("FailedCount = %v, want 0", attempt.FailedCount)
+		}
+		if attempt.Key != "none@example.com" || attempt.Scope != models.LoginAttemptScopeEmail {
+			t.Errorf("attempt = %+v, want scope and key to be set", attempt)
+		}
+	})
+
+	t.Run("RecordLoginFailure", func(t *testing.T) {
+		now := time.Now().Truncate(time.Second)
+		for i := 1; i <= 2; i++ {
+			attempt, err := database.RecordLoginFailure(models.LoginAttemptScopeIP, "10.0.0.1", now, now.Add(-time.Hour))
+			if err != nil {
+				t.Fatalf("failed to record failed login: %v", err)
+			}
+			if attempt.FailedCount != i {
+				t.Errorf("FailedCount = %v, want %v", attempt.FailedCount, i)
+			}
+		}
+
+		got, err := database.GetLoginAttempt(models.LoginAttemptScopeIP, "10.0.0.1")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got.FailedCount != 2 {
+			t.Errorf("FailedCount = %v, want 2", got.FailedCount)
+		}
+		if !got.LastFailedAt.Equal(now) {
+			t.Errorf("LastFailedAt = %v, want %v", got.LastFailedAt, now)
+		}
+
+		// The same key in another scope is tracked separately
+		other, err := database.GetLoginAttempt(models.LoginAttemptScopeEmail, "10.0.0.1")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if other.FailedCount != 0 {
+			t.Errorf("FailedCount in other scope = %v, want 0", other.FailedCount)
+		}
+
+		// Failures older than staleBefore are forgotten
+		later := now.Add(2 * time.Hour)
+		attempt, err := database.RecordLoginFailure(models.LoginAttemptScopeIP, "10.0.0.1", later, later.Add(-time.Hour))
+		if err != nil {
+			t.Fatalf("failed to record failed login: %v", err)
+		}
+		if attempt.FailedCount != 1 {
+			t.Errorf("FailedCount after stale failures = %v, want 1", attempt.FailedCount)
+		}
+	})
+
+	t.Run("RecordLoginFailure concurrently", func(t *testing.T) {
+		now := time.Now()
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := database.RecordLoginFailure(models.LoginAttemptScopeEmail, "race@example.com", now, now.Add(-time.Hour)); err != nil {
+					t.Errorf("failed to record failed login: %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		got, err := database.GetLoginAttempt(models.LoginAttemptScopeEmail, "race@example.com")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got.FailedCount != 10 {
+			t.Errorf("FailedCount = %v, want 10", got.FailedCount)
+		}
+	})
+
+	t.Run("LockLogin", func(t *testing.T) {
+		now := time.Now().Truncate(time.Second)
+		lockedUntil := now.Add(time.Hour)
+
+		locked, err := database.LockLogin(models.LoginAttemptScopeIP, "10.0.0.1", lockedUntil)
+		if err != nil {
+			t.Fatalf("failed to lock login: %v", err)
+		}
+		if !locked {
+			t.Error("LockLogin() = false, want true")
+		}
+
+		// Only the first caller starts the lockout
+		locked, err = database.LockLogin(models.LoginAttemptScopeIP, "10.0.0.1", lockedUntil.Add(time.Hour))
+		if err != nil {
+			t.Fatalf("failed to lock login: %v", err)
+		}
+		if locked {
+			t.Error("LockLogin() of locked login = true, want false")
+		}
+
+		got, err := database.GetLoginAttempt(models.LoginAttemptScopeIP, "10.0.0.1")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !got.LockedUntil.Equal(lockedUntil) {
+			t.Errorf("LockedUntil = %v, want %v", got.LockedUntil, lockedUntil)
+		}
+
+		// Failures during the lockout keep it
+		attempt, err := database.RecordLoginFailure(models.LoginAttemptScopeIP, "10.0.0.1", now, now.Add(-time.Hour))
+		if err != nil {
+			t.Fatalf("failed to record failed login: %v", err)
+		}
+		if !attempt.LockedUntil.Equal(lockedUntil) || attempt.FailedCount != 2 {
+			t.Errorf("attempt = %+v, want the lockout to be kept", attempt)
+		}
+
+		// The first failure after the lockout starts counting again
+		after := lockedUntil.Add(time.Second)
+		attempt, err = database.RecordLoginFailure(models.LoginAttemptScopeIP, "10.0.0.1", after, after.Add(-time.Hour))
+		if err != nil {
+			t.Fatalf("failed to record failed login: %v", err)
+		}
+		if !attempt.LockedUntil.IsZero() || attempt.FailedCount != 1 {
+			t.Errorf("attempt = %+v, want the lockout to be lifted", attempt)
+		}
+	})
+
+	t.Run("DeleteLoginAttempt", func(t *testing.T) {
+		if err := database.DeleteLoginAttempt(models.LoginAttemptScopeIP, "10.0.0.1"); err != nil {
+			t.Fatalf("failed to delete login attempt: %v", err)
+		}
+
+		got, err := database.GetLoginAttempt(models.LoginAttemptScopeIP, "10.0.0.1")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got.FailedCount != 0 || !got.LockedUntil.IsZero() {
+			t.Errorf("attempt = %+v, want cleared", got)
+		}
+	})
+}
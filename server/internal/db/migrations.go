@@ -1,19 +1,50 @@
 package db
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"sort"
 )
 
 // Migration represents a database migration
 type Migration struct {
 	Version int
-	SQL     string
+	Up      string // SQL applying the migration
+	Down    string // SQL reverting the migration
 }
 
-var migrations = []Migration{
+// Checksum returns the checksum of the migration's SQL, used to detect
+// migrations that were modified after they were applied
+func (m Migration) Checksum() string {
+	sum := sha256.Sum256([]byte(m.Up))
+	return hex.EncodeToString(sum[:])
+}
+
+// Migration states reported by MigrationStatus
+const (
+	MigrationPending  = "pending"
+	MigrationApplied  = "applied"
+	MigrationModified = "modified" // applied, but the migration changed since
+	MigrationUnknown  = "unknown"  // applied, but unknown to this version of Lemma
+)
+
+// MigrationStatus holds the state of a single migration
+type MigrationStatus struct {
+	Version int
+	State   string
+}
+
+// ErrDatabaseNewer is returned when the database has migrations applied that
+// this version of Lemma doesn't know, e.g. after a downgrade
+var ErrDatabaseNewer = errors.New("database schema is newer than this version of Lemma")
+
+// sqliteMigrations are the migrations of the SQLite schema
+var sqliteMigrations = []Migration{
 	{
 		Version: 1,
-		SQL: `
+		Up: `
             -- Create users table
             CREATE TABLE IF NOT EXISTS users (
                 id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -72,70 +103,540 @@ var migrations = []Migration{
             CREATE INDEX idx_sessions_user_id ON sessions(user_id);
             CREATE INDEX idx_sessions_expires_at ON sessions(expires_at);
             CREATE INDEX idx_sessions_refresh_token ON sessions(refresh_token);
+        `,
+		Down: `
+            DROP TABLE IF EXISTS system_settings;
+            DROP TABLE IF EXISTS sessions;
+            DROP TABLE IF EXISTS workspaces;
+            DROP TABLE IF EXISTS users;
+        `,
+	},
+	{
+		Version: 2,
+		Up: `
+            -- Track client details and activity for each session
+            ALTER TABLE sessions ADD COLUMN user_agent TEXT NOT NULL DEFAULT '';
+            ALTER TABLE sessions ADD COLUMN ip_address TEXT NOT NULL DEFAULT '';
+            ALTER TABLE sessions ADD COLUMN last_used_at TIMESTAMP;
+            UPDATE sessions SET last_used_at = created_at;
+        `,
+		Down: `
+            ALTER TABLE sessions DROP COLUMN last_used_at;
+            ALTER TABLE sessions DROP COLUMN ip_address;
+            ALTER TABLE sessions DROP COLUMN user_agent;
+        `,
+	},
+	{
+		Version: 3,
+		Up: `
+            -- Remember refresh tokens that were rotated out of a session so reuse can be detected
+            CREATE TABLE IF NOT EXISTS rotated_refresh_tokens (
+                token TEXT PRIMARY KEY,
+                session_id TEXT NOT NULL,
+                user_id INTEGER NOT NULL,
+                rotated_at TIMESTAMP NOT NULL,
+                expires_at TIMESTAMP NOT NULL
+            );
+
+            CREATE INDEX idx_rotated_refresh_tokens_session_id ON rotated_refresh_tokens(session_id);
+            CREATE INDEX idx_rotated_refresh_tokens_expires_at ON rotated_refresh_tokens(expires_at);
+        `,
+		Down: `
+            DROP TABLE IF EXISTS rotated_refresh_tokens;
+        `,
+	},
+	{
+		Version: 4,
+		Up: `
+            -- Track failed login attempts per email and per client IP
+            CREATE TABLE IF NOT EXISTS login_attempts (
+                scope TEXT NOT NULL CHECK(scope IN ('email', 'ip')),
+                key TEXT NOT NULL,
+                failed_count INTEGER NOT NULL DEFAULT 0,
+                last_failed_at TIMESTAMP NOT NULL,
+                locked_until TIMESTAMP,
+                PRIMARY KEY (scope, key)
+            );
+        `,
+		Down: `
+            DROP TABLE IF EXISTS login_attempts;
+        `,
+	},
+	{
+		Version: 5,
+		Up: `
+            -- Keyring for signing and verifying JWT tokens
+            CREATE TABLE IF NOT EXISTS jwt_keys (
+                id TEXT PRIMARY KEY,
+                algorithm TEXT NOT NULL CHECK(algorithm IN ('HS256', 'RS256', 'EdDSA')),
+                private_key TEXT NOT NULL,
+                public_key TEXT NOT NULL DEFAULT '',
+                active BOOLEAN NOT NULL DEFAULT 0,
+                created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                retired_at TIMESTAMP
+            );
+        `,
+		Down: `
+            DROP TABLE IF EXISTS jwt_keys;
+        `,
+	},
+	{
+		Version: 6,
+		Up: `
+            -- Single-use tokens for password resets and email verification
+            CREATE TABLE IF NOT EXISTS user_tokens (
+                token_hash TEXT PRIMARY KEY,
+                user_id INTEGER NOT NULL,
+                purpose TEXT NOT NULL CHECK(purpose IN ('password_reset', 'email_change')),
+                email TEXT NOT NULL DEFAULT '',
+                expires_at TIMESTAMP NOT NULL,
+                created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+            );
+            CREATE INDEX IF NOT EXISTS idx_user_tokens_user_id ON user_tokens(user_id);
+            CREATE INDEX IF NOT EXISTS idx_user_tokens_expires_at ON user_tokens(expires_at);
+        `,
+		Down: `
+            DROP TABLE IF EXISTS user_tokens;
+        `,
+	},
+	{
+		Version: 7,
+		Up: `
+            -- Invitations for signing up with a role chosen by an admin
+            CREATE TABLE IF NOT EXISTS invitations (
+                id INTEGER PRIMARY KEY AUTOINCREMENT,
+                token_hash TEXT NOT NULL UNIQUE,
+                role TEXT NOT NULL CHECK(role IN ('admin', 'editor', 'viewer')),
+                email TEXT NOT NULL DEFAULT '',
+                max_uses INTEGER NOT NULL CHECK(max_uses > 0),
+                use_count INTEGER NOT NULL DEFAULT 0,
+                expires_at TIMESTAMP NOT NULL,
+                created_by INTEGER NOT NULL,
+                created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                FOREIGN KEY (created_by) REFERENCES users (id) ON DELETE CASCADE
+            );
+        `,
+		Down: `
+            DROP TABLE IF EXISTS invitations;
+        `,
+	},
+	{
+		Version: 8,
+		Up: `
+            -- Users with access to a workspace, including its owner
+            CREATE TABLE IF NOT EXISTS workspace_members (
+                workspace_id INTEGER NOT NULL,
+                user_id INTEGER NOT NULL,
+                role TEXT NOT NULL CHECK(role IN ('owner', 'editor', 'viewer')),
+                created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                PRIMARY KEY (workspace_id, user_id),
+                FOREIGN KEY (workspace_id) REFERENCES workspaces (id) ON DELETE CASCADE,
+                FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+            );
+
+            CREATE INDEX idx_workspace_members_user_id ON workspace_members(user_id);
+            CREATE UNIQUE INDEX idx_workspace_members_owner ON workspace_members(workspace_id) WHERE role = 'owner';
+
+            -- Existing workspaces are owned by the user they belong to
+            INSERT INTO workspace_members (workspace_id, user_id, role)
+            SELECT id, user_id, 'owner' FROM workspaces;
+        `,
+		Down: `
+            DROP TABLE IF EXISTS workspace_members;
+        `,
+	},
+	{
+		Version: 9,
+		Up: `
+            -- Public read-only links to files and folders of a workspace
+            CREATE TABLE IF NOT EXISTS share_links (
+                id INTEGER PRIMARY KEY AUTOINCREMENT,
+                workspace_id INTEGER NOT NULL,
+                token_hash TEXT NOT NULL UNIQUE,
+                path TEXT NOT NULL,
+                is_folder BOOLEAN NOT NULL DEFAULT FALSE,
+                password_hash TEXT NOT NULL DEFAULT '',
+                expires_at TIMESTAMP,
+                created_by INTEGER NOT NULL,
+                created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                FOREIGN KEY (workspace_id) REFERENCES workspaces (id) ON DELETE CASCADE,
+                FOREIGN KEY (created_by) REFERENCES users (id) ON DELETE CASCADE
+            );
+            CREATE INDEX idx_share_links_workspace_id ON share_links(workspace_id);
+        `,
+		Down: `
+            DROP TABLE IF EXISTS share_links;
+        `,
+	},
+	{
+		Version: 10,
+		Up: `
+            -- Deleted users and workspaces are kept in the trash until they are purged
+            ALTER TABLE users ADD COLUMN deleted_at TIMESTAMP;
+            ALTER TABLE workspaces ADD COLUMN deleted_at TIMESTAMP;
+        `,
+		Down: `
+            ALTER TABLE workspaces DROP COLUMN deleted_at;
+            ALTER TABLE users DROP COLUMN deleted_at;
+        `,
+	},
+	{
+		Version: 11,
+		Up: `
+            -- Security and admin relevant events, kept when the actor or target is purged
+            CREATE TABLE IF NOT EXISTS audit_events (
+                id INTEGER PRIMARY KEY AUTOINCREMENT,
+                event TEXT NOT NULL,
+                actor_id INTEGER,
+                target_type TEXT NOT NULL DEFAULT '',
+                target_id TEXT NOT NULL DEFAULT '',
+                workspace_id INTEGER,
+                ip_address TEXT NOT NULL DEFAULT '',
+                request_id TEXT NOT NULL DEFAULT '',
+                details TEXT NOT NULL DEFAULT '{}',
+                created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+            );
+            CREATE INDEX idx_audit_events_created_at ON audit_events(created_at);
+            CREATE INDEX idx_audit_events_actor_id ON audit_events(actor_id);
+            CREATE INDEX idx_audit_events_target ON audit_events(target_type, target_id);
+        `,
+		Down: `
+            DROP TABLE IF EXISTS audit_events;
+        `,
+	},
+	{
+		Version: 12,
+		Up: `
+            -- Storage quotas of users that differ from the system default, zero means unlimited
+            CREATE TABLE IF NOT EXISTS user_quotas (
+                user_id INTEGER PRIMARY KEY,
+                max_bytes INTEGER NOT NULL DEFAULT 0,
+                max_files INTEGER NOT NULL DEFAULT 0,
+                updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+            );
+        `,
+		Down: `
+            DROP TABLE IF EXISTS user_quotas;
+        `,
+	},
+	{
+		Version: 13,
+		Up: `
+            -- Suspended users can't log in, and users can be required to change their password
+            ALTER TABLE users ADD COLUMN suspended_at TIMESTAMP;
+            ALTER TABLE users ADD COLUMN must_change_password BOOLEAN NOT NULL DEFAULT 0;
+        `,
+		Down: `
+            ALTER TABLE users DROP COLUMN must_change_password;
+            ALTER TABLE users DROP COLUMN suspended_at;
+        `,
+	},
+	{
+		Version: 14,
+		Up: `
+            -- Name of the initial workspace of users signing up with an invitation, Main if empty
+            ALTER TABLE invitations ADD COLUMN workspace_name TEXT NOT NULL DEFAULT '';
+        `,
+		Down: `
+            ALTER TABLE invitations DROP COLUMN workspace_name;
+        `,
+	},
+	{
+		Version: 15,
+		Up: `
+            -- Storage quotas of single workspaces, zero means unlimited
+            CREATE TABLE IF NOT EXISTS workspace_quotas (
+                workspace_id INTEGER PRIMARY KEY,
+                max_bytes INTEGER NOT NULL DEFAULT 0,
+                max_files INTEGER NOT NULL DEFAULT 0,
+                updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                FOREIGN KEY (workspace_id) REFERENCES workspaces (id) ON DELETE CASCADE
+            );
+
+            -- Storage used by workspaces, kept current as files change so it survives restarts
+            CREATE TABLE IF NOT EXISTS workspace_usage (
+                workspace_id INTEGER PRIMARY KEY,
+                bytes INTEGER NOT NULL DEFAULT 0,
+                files INTEGER NOT NULL DEFAULT 0,
+                updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                FOREIGN KEY (workspace_id) REFERENCES workspaces (id) ON DELETE CASCADE
+            );
+        `,
+		Down: `
+            DROP TABLE IF EXISTS workspace_usage;
+            DROP TABLE IF EXISTS workspace_quotas;
+        `,
+	},
+	{
+		Version: 16,
+		Up: `
+            -- Where users authenticate, LDAP only manages the users it created
+            ALTER TABLE users ADD COLUMN auth_source TEXT NOT NULL DEFAULT 'local' CHECK(auth_source IN ('local', 'ldap'));
+        `,
+		Down: `
+            ALTER TABLE users DROP COLUMN auth_source;
+        `,
+	},
+	{
+		Version: 17,
+		Up: `
+            -- Users signing up without an invitation can't log in until they confirm their email address
+            ALTER TABLE users ADD COLUMN email_verification_pending BOOLEAN NOT NULL DEFAULT 0;
+        `,
+		Down: `
+            ALTER TABLE users DROP COLUMN email_verification_pending;
+        `,
+	},
+	{
+		Version: 18,
+		Up: `
+            -- The file count of workspaces no longer includes Git objects, trash and revisions,
+            -- so the stored usage is discarded and measured again
+            DELETE FROM workspace_usage;
+        `,
+		Down: `
+            DELETE FROM workspace_usage;
         `,
 	},
 }
 
-// Migrate applies all database migrations
+// Migrate applies all pending database migrations
 func (db *database) Migrate() error {
+	return db.MigrateUp(db.latestMigrationVersion())
+}
+
+// MigrateUp applies all pending migrations up to and including the given version
+func (db *database) MigrateUp(version int) error {
 	log := getLogger().WithGroup("migrations")
-	log.Info("starting database migration")
+	log.Info("starting database migration", "dialect", db.dialect.name, "target_version", version)
 
-	// Create migrations table if it doesn't exist
+	if version < 0 || version > db.latestMigrationVersion() {
+		return fmt.Errorf("unknown migration version %d", version)
+	}
+
+	applied, err := db.verifyMigrations()
+	if err != nil {
+		return err
+	}
+
+	currentVersion := 0
+	for _, migration := range db.dialect.migrations {
+		if _, ok := applied[migration.Version]; ok {
+			currentVersion = migration.Version
+			continue
+		}
+		if migration.Version > version {
+			break
+		}
+
+		if err := db.runMigration(migration.Version, migration.Up,
+			"INSERT INTO migrations (version, checksum) VALUES (?, ?)", migration.Version, migration.Checksum()); err != nil {
+			return err
+		}
+
+		currentVersion = migration.Version
+		log.Debug("migration applied", "migration_version", migration.Version)
+	}
+
+	log.Info("database migration completed", "final_version", currentVersion)
+	return nil
+}
+
+// MigrateDown reverts all applied migrations newer than the given version
+func (db *database) MigrateDown(version int) error {
+	log := getLogger().WithGroup("migrations")
+	log.Info("starting database rollback", "dialect", db.dialect.name, "target_version", version)
+
+	if version < 0 || version > db.latestMigrationVersion() {
+		return fmt.Errorf("unknown migration version %d", version)
+	}
+
+	applied, err := db.verifyMigrations()
+	if err != nil {
+		return err
+	}
+
+	for i := len(db.dialect.migrations) - 1; i >= 0; i-- {
+		migration := db.dialect.migrations[i]
+		if migration.Version <= version {
+			break
+		}
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		if migration.Down == "" {
+			return fmt.Errorf("migration %d can't be reverted", migration.Version)
+		}
+
+		if err := db.runMigration(migration.Version, migration.Down,
+			"DELETE FROM migrations WHERE version = ?", migration.Version); err != nil {
+			return err
+		}
+
+		log.Debug("migration reverted", "migration_version", migration.Version)
+	}
+
+	log.Info("database rollback completed", "final_version", version)
+	return nil
+}
+
+// MigrationStatus returns the state of all migrations known to this version of
+// Lemma, followed by applied migrations it doesn't know
+func (db *database) MigrationStatus() ([]MigrationStatus, error) {
+	applied, err := db.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(db.dialect.migrations))
+	for _, migration := range db.dialect.migrations {
+		status := MigrationStatus{Version: migration.Version, State: MigrationPending}
+		if checksum, ok := applied[migration.Version]; ok {
+			status.State = MigrationApplied
+			if checksum != "" && checksum != migration.Checksum() {
+				status.State = MigrationModified
+			}
+			delete(applied, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+
+	unknown := make([]int, 0, len(applied))
+	for version := range applied {
+		unknown = append(unknown, version)
+	}
+	sort.Ints(unknown)
+	for _, version := range unknown {
+		statuses = append(statuses, MigrationStatus{Version: version, State: MigrationUnknown})
+	}
+
+	return statuses, nil
+}
+
+func (db *database) latestMigrationVersion() int {
+	if len(db.dialect.migrations) == 0 {
+		return 0
+	}
+	return db.dialect.migrations[len(db.dialect.migrations)-1].Version
+}
+
+// appliedMigrations returns the checksums of the applied migrations by version,
+// creating the migrations table if it doesn't exist
+func (db *database) appliedMigrations() (map[int]string, error) {
 	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS migrations (
-        version INTEGER PRIMARY KEY
+        version INTEGER PRIMARY KEY,
+        checksum TEXT NOT NULL DEFAULT ''
     )`)
 	if err != nil {
-		return fmt.Errorf("failed to create migrations table: %w", err)
+		return nil, fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	// Migrations tables of versions before checksums only have the version column
+	if _, err := db.Exec("SELECT checksum FROM migrations WHERE version = 0"); err != nil {
+		if _, err := db.Exec("ALTER TABLE migrations ADD COLUMN checksum TEXT NOT NULL DEFAULT ''"); err != nil {
+			return nil, fmt.Errorf("failed to add checksum to migrations table: %w", err)
+		}
 	}
 
-	// Get current version
-	var currentVersion int
-	err = db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM migrations").Scan(&currentVersion)
+	rows, err := db.Query("SELECT version, checksum FROM migrations")
 	if err != nil {
-		return fmt.Errorf("failed to get current migration version: %w", err)
+		return nil, fmt.Errorf("failed to get applied migrations: %w", err)
 	}
+	defer rows.Close()
 
-	// Apply new migrations
-	for _, migration := range migrations {
-		if migration.Version > currentVersion {
-			log := log.With("migration_version", migration.Version)
-			tx, err := db.Begin()
-			if err != nil {
-				return fmt.Errorf("failed to begin transaction for migration %d: %w", migration.Version, err)
-			}
+	applied := make(map[int]string)
+	for rows.Next() {
+		var version int
+		var checksum string
+		if err := rows.Scan(&version, &checksum); err != nil {
+			return nil, fmt.Errorf("failed to scan migration: %w", err)
+		}
+		applied[version] = checksum
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate migrations: %w", err)
+	}
 
-			// Execute migration SQL
-			_, err = tx.Exec(migration.SQL)
-			if err != nil {
-				if rbErr := tx.Rollback(); rbErr != nil {
-					return fmt.Errorf("migration %d failed: %v, rollback failed: %v",
-						migration.Version, err, rbErr)
-				}
-				return fmt.Errorf("migration %d failed: %w", migration.Version, err)
-			}
+	return applied, nil
+}
 
-			// Update migrations table
-			_, err = tx.Exec("INSERT INTO migrations (version) VALUES (?)", migration.Version)
-			if err != nil {
-				if rbErr := tx.Rollback(); rbErr != nil {
-					return fmt.Errorf("failed to update migration version: %v, rollback failed: %v",
-						err, rbErr)
-				}
-				return fmt.Errorf("failed to update migration version: %w", err)
+// verifyMigrations returns the applied migrations after checking that all of them
+// are known and unmodified. Missing checksums of migrations applied before
+// checksums were recorded are filled in.
+func (db *database) verifyMigrations() (map[int]string, error) {
+	applied, err := db.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	latestVersion := db.latestMigrationVersion()
+	known := make(map[int]Migration, len(db.dialect.migrations))
+	for _, migration := range db.dialect.migrations {
+		known[migration.Version] = migration
+	}
+
+	for version, checksum := range applied {
+		migration, ok := known[version]
+		if !ok {
+			if version > latestVersion {
+				return nil, fmt.Errorf("%w: migration %d is applied, latest known migration is %d",
+					ErrDatabaseNewer, version, latestVersion)
 			}
+			return nil, fmt.Errorf("unknown migration %d is applied", version)
+		}
 
-			// Commit transaction
-			err = tx.Commit()
+		if checksum == "" {
+			_, err := db.Exec("UPDATE migrations SET checksum = ? WHERE version = ?", migration.Checksum(), version)
 			if err != nil {
-				return fmt.Errorf("failed to commit migration %d: %w", migration.Version, err)
+				return nil, fmt.Errorf("failed to record checksum of migration %d: %w", version, err)
 			}
+			applied[version] = migration.Checksum()
+			continue
+		}
 
-			currentVersion = migration.Version
-			log.Debug("migration applied", "new_version", currentVersion)
+		if checksum != migration.Checksum() {
+			return nil, fmt.Errorf("migration %d was modified after it was applied", version)
 		}
 	}
 
-	log.Info("database migration completed", "final_version", currentVersion)
+	return applied, nil
+}
+
+// runMigration executes the SQL of a migration together with the statement
+// updating the migrations table in a single transaction
+func (db *database) runMigration(version int, migrationSQL, bookkeepingSQL string, args ...any) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction for migration %d: %w", version, err)
+	}
+
+	// Execute migration SQL
+	_, err = tx.Exec(migrationSQL)
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("migration %d failed: %v, rollback failed: %v",
+				version, err, rbErr)
+		}
+		return fmt.Errorf("migration %d failed: %w", version, err)
+	}
+
+	// Update migrations table
+	_, err = tx.Exec(bookkeepingSQL, args...)
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("failed to update migration version: %v, rollback failed: %v",
+				err, rbErr)
+		}
+		return fmt.Errorf("failed to update migration version: %w", err)
+	}
+
+	// Commit transaction
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("failed to commit migration %d: %w", version, err)
+	}
+
 	return nil
 }
@@ -0,0 +1,364 @@
+package db
+
+// postgresMigrations are the migrations of the PostgreSQL schema.
+// Their versions match the SQLite migrations making the same changes.
+var postgresMigrations = []Migration{
+	{
+		Version: 1,
+		Up: `
+            -- Create users table
+            CREATE TABLE IF NOT EXISTS users (
+                id INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+                email TEXT NOT NULL UNIQUE,
+                display_name TEXT,
+                password_hash TEXT NOT NULL,
+                role TEXT NOT NULL CHECK(role IN ('admin', 'editor', 'viewer')),
+                created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+                last_workspace_id INTEGER
+            );
+
+            -- Create workspaces table with integrated settings
+            CREATE TABLE IF NOT EXISTS workspaces (
+                id INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+                user_id INTEGER NOT NULL,
+                name TEXT NOT NULL,
+                created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+                last_opened_file_path TEXT,
+                -- Settings fields
+                theme TEXT NOT NULL DEFAULT 'light' CHECK(theme IN ('light', 'dark')),
+                auto_save BOOLEAN NOT NULL DEFAULT FALSE,
+                git_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+                git_url TEXT,
+                git_user TEXT,
+                git_token TEXT,
+                git_auto_commit BOOLEAN NOT NULL DEFAULT FALSE,
+                git_commit_msg_template TEXT DEFAULT '${action} ${filename}',
+                git_commit_name TEXT,
+                git_commit_email TEXT,
+                show_hidden_files BOOLEAN NOT NULL DEFAULT FALSE,
+                created_by INTEGER REFERENCES users(id),
+                updated_by INTEGER REFERENCES users(id),
+                updated_at TIMESTAMPTZ,
+                FOREIGN KEY (user_id) REFERENCES users (id)
+            );
+
+            -- Create sessions table for authentication
+            CREATE TABLE IF NOT EXISTS sessions (
+                id TEXT PRIMARY KEY,
+                user_id INTEGER NOT NULL,
+                refresh_token TEXT NOT NULL,
+                expires_at TIMESTAMPTZ NOT NULL,
+                created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+                FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+            );
+
+            -- Create system_settings table for application settings
+            CREATE TABLE IF NOT EXISTS system_settings (
+                key TEXT PRIMARY KEY,
+                value TEXT NOT NULL,
+                created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+                updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+            );
+
+            -- Create indexes for performance
+            CREATE INDEX idx_sessions_user_id ON sessions(user_id);
+            CREATE INDEX idx_sessions_expires_at ON sessions(expires_at);
+            CREATE INDEX idx_sessions_refresh_token ON sessions(refresh_token);
+        `,
+		Down: `
+            DROP TABLE IF EXISTS system_settings;
+            DROP TABLE IF EXISTS sessions;
+            DROP TABLE IF EXISTS workspaces;
+            DROP TABLE IF EXISTS users;
+        `,
+	},
+	{
+		Version: 2,
+		Up: `
+            -- Track client details and activity for each session
+            ALTER TABLE sessions ADD COLUMN user_agent TEXT NOT NULL DEFAULT '';
+            ALTER TABLE sessions ADD COLUMN ip_address TEXT NOT NULL DEFAULT '';
+            ALTER TABLE sessions ADD COLUMN last_used_at TIMESTAMPTZ;
+            UPDATE sessions SET last_used_at = created_at;
+        `,
+		Down: `
+            ALTER TABLE sessions DROP COLUMN last_used_at;
+            ALTER TABLE sessions DROP COLUMN ip_address;
+            ALTER TABLE sessions DROP COLUMN user_agent;
+        `,
+	},
+	{
+		Version: 3,
+		Up: `
+            -- Remember refresh tokens that were rotated out of a session so reuse can be detected
+            CREATE TABLE IF NOT EXISTS rotated_refresh_tokens (
+                token TEXT PRIMARY KEY,
+                session_id TEXT NOT NULL,
+                user_id INTEGER NOT NULL,
+                rotated_at TIMESTAMPTZ NOT NULL,
+                expires_at TIMESTAMPTZ NOT NULL
+            );
+
+            CREATE INDEX idx_rotated_refresh_tokens_session_id ON rotated_refresh_tokens(session_id);
+            CREATE INDEX idx_rotated_refresh_tokens_expires_at ON rotated_refresh_tokens(expires_at);
+        `,
+		Down: `
+            DROP TABLE IF EXISTS rotated_refresh_tokens;
+        `,
+	},
+	{
+		Version: 4,
+		Up: `
+            -- Track failed login attempts per email and per client IP
+            CREATE TABLE IF NOT EXISTS login_attempts (
+                scope TEXT NOT NULL CHECK(scope IN ('email', 'ip')),
+                key TEXT NOT NULL,
+                failed_count INTEGER NOT NULL DEFAULT 0,
+                last_failed_at TIMESTAMPTZ NOT NULL,
+                locked_until TIMESTAMPTZ,
+                PRIMARY KEY (scope, key)
+            );
+        `,
+		Down: `
+            DROP TABLE IF EXISTS login_attempts;
+        `,
+	},
+	{
+		Version: 5,
+		Up: `
+            -- Keyring for signing and verifying JWT tokens
+            CREATE TABLE IF NOT EXISTS jwt_keys (
+                id TEXT PRIMARY KEY,
+                algorithm TEXT NOT NULL CHECK(algorithm IN ('HS256', 'RS256', 'EdDSA')),
+                private_key TEXT NOT NULL,
+                public_key TEXT NOT NULL DEFAULT '',
+                active BOOLEAN NOT NULL DEFAULT FALSE,
+                created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                retired_at TIMESTAMPTZ
+            );
+        `,
+		Down: `
+            DROP TABLE IF EXISTS jwt_keys;
+        `,
+	},
+	{
+		Version: 6,
+		Up: `
+            -- Single-use tokens for password resets and email verification
+            CREATE TABLE IF NOT EXISTS user_tokens (
+                token_hash TEXT PRIMARY KEY,
+                user_id INTEGER NOT NULL,
+                purpose TEXT NOT NULL CHECK(purpose IN ('password_reset', 'email_change')),
+                email TEXT NOT NULL DEFAULT '',
+                expires_at TIMESTAMPTZ NOT NULL,
+                created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+            );
+            CREATE INDEX IF NOT EXISTS idx_user_tokens_user_id ON user_tokens(user_id);
+            CREATE INDEX IF NOT EXISTS idx_user_tokens_expires_at ON user_tokens(expires_at);
+        `,
+		Down: `
+            DROP TABLE IF EXISTS user_tokens;
+        `,
+	},
+	{
+		Version: 7,
+		Up: `
+            -- Invitations for signing up with a role chosen by an admin
+            CREATE TABLE IF NOT EXISTS invitations (
+                id INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+                token_hash TEXT NOT NULL UNIQUE,
+                role TEXT NOT NULL CHECK(role IN ('admin', 'editor', 'viewer')),
+                email TEXT NOT NULL DEFAULT '',
+                max_uses INTEGER NOT NULL CHECK(max_uses > 0),
+                use_count INTEGER NOT NULL DEFAULT 0,
+                expires_at TIMESTAMPTZ NOT NULL,
+                created_by INTEGER NOT NULL,
+                created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                FOREIGN KEY (created_by) REFERENCES users (id) ON DELETE CASCADE
+            );
+        `,
+		Down: `
+            DROP TABLE IF EXISTS invitations;
+        `,
+	},
+	{
+		Version: 8,
+		Up: `
+            -- Users with access to a workspace, including its owner
+            CREATE TABLE IF NOT EXISTS workspace_members (
+                workspace_id INTEGER NOT NULL,
+                user_id INTEGER NOT NULL,
+                role TEXT NOT NULL CHECK(role IN ('owner', 'editor', 'viewer')),
+                created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                PRIMARY KEY (workspace_id, user_id),
+                FOREIGN KEY (workspace_id) REFERENCES workspaces (id) ON DELETE CASCADE,
+                FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+            );
+
+            CREATE INDEX idx_workspace_members_user_id ON workspace_members(user_id);
+            CREATE UNIQUE INDEX idx_workspace_members_owner ON workspace_members(workspace_id) WHERE role = 'owner';
+
+            -- Existing workspaces are owned by the user they belong to
+            INSERT INTO workspace_members (workspace_id, user_id, role)
+            SELECT id, user_id, 'owner' FROM workspaces;
+        `,
+		Down: `
+            DROP TABLE IF EXISTS workspace_members;
+        `,
+	},
+	{
+		Version: 9,
+		Up: `
+            -- Public read-only links to files and folders of a workspace
+            CREATE TABLE IF NOT EXISTS share_links (
+                id INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+                workspace_id INTEGER NOT NULL,
+                token_hash TEXT NOT NULL UNIQUE,
+                path TEXT NOT NULL,
+                is_folder BOOLEAN NOT NULL DEFAULT FALSE,
+                password_hash TEXT NOT NULL DEFAULT '',
+                expires_at TIMESTAMPTZ,
+                created_by INTEGER NOT NULL,
+                created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                FOREIGN KEY (workspace_id) REFERENCES workspaces (id) ON DELETE CASCADE,
+                FOREIGN KEY (created_by) REFERENCES users (id) ON DELETE CASCADE
+            );
+            CREATE INDEX idx_share_links_workspace_id ON share_links(workspace_id);
+        `,
+		Down: `
+            DROP TABLE IF EXISTS share_links;
+        `,
+	},
+	{
+		Version: 10,
+		Up: `
+            -- Deleted users and workspaces are kept in the trash until they are purged
+            ALTER TABLE users ADD COLUMN deleted_at TIMESTAMPTZ;
+            ALTER TABLE workspaces ADD COLUMN deleted_at TIMESTAMPTZ;
+        `,
+		Down: `
+            ALTER TABLE workspaces DROP COLUMN deleted_at;
+            ALTER TABLE users DROP COLUMN deleted_at;
+        `,
+	},
+	{
+		Version: 11,
+		Up: `
+            -- Security and admin relevant events, kept when the actor or target is purged
+            CREATE TABLE IF NOT EXISTS audit_events (
+                id INTEGER GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+                event TEXT NOT NULL,
+                actor_id INTEGER,
+                target_type TEXT NOT NULL DEFAULT '',
+                target_id TEXT NOT NULL DEFAULT '',
+                workspace_id INTEGER,
+                ip_address TEXT NOT NULL DEFAULT '',
+                request_id TEXT NOT NULL DEFAULT '',
+                details TEXT NOT NULL DEFAULT '{}',
+                created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+            );
+            CREATE INDEX idx_audit_events_created_at ON audit_events(created_at);
+            CREATE INDEX idx_audit_events_actor_id ON audit_events(actor_id);
+            CREATE INDEX idx_audit_events_target ON audit_events(target_type, target_id);
+        `,
+		Down: `
+            DROP TABLE IF EXISTS audit_events;
+        `,
+	},
+	{
+		Version: 12,
+		Up: `
+            -- Storage quotas of users that differ from the system default, zero means unlimited
+            CREATE TABLE IF NOT EXISTS user_quotas (
+                user_id INTEGER PRIMARY KEY,
+                max_bytes BIGINT NOT NULL DEFAULT 0,
+                max_files INTEGER NOT NULL DEFAULT 0,
+                updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+            );
+        `,
+		Down: `
+            DROP TABLE IF EXISTS user_quotas;
+        `,
+	},
+	{
+		Version: 13,
+		Up: `
+            -- Suspended users can't log in, and users can be required to change their password
+            ALTER TABLE users ADD COLUMN suspended_at TIMESTAMPTZ;
+            ALTER TABLE users ADD COLUMN must_change_password BOOLEAN NOT NULL DEFAULT FALSE;
+        `,
+		Down: `
+            ALTER TABLE users DROP COLUMN must_change_password;
+            ALTER TABLE users DROP COLUMN suspended_at;
+        `,
+	},
+	{
+		Version: 14,
+		Up: `
+            -- Name of the initial workspace of users signing up with an invitation, Main if empty
+            ALTER TABLE invitations ADD COLUMN workspace_name TEXT NOT NULL DEFAULT '';
+        `,
+		Down: `
+            ALTER TABLE invitations DROP COLUMN workspace_name;
+        `,
+	},
+	{
+		Version: 15,
+		Up: `
+            -- Storage quotas of single workspaces, zero means unlimited
+            CREATE TABLE IF NOT EXISTS workspace_quotas (
+                workspace_id INTEGER PRIMARY KEY,
+                max_bytes BIGINT NOT NULL DEFAULT 0,
+                max_files INTEGER NOT NULL DEFAULT 0,
+                updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                FOREIGN KEY (workspace_id) REFERENCES workspaces (id) ON DELETE CASCADE
+            );
+
+            -- Storage used by workspaces, kept current as files change so it survives restarts
+            CREATE TABLE IF NOT EXISTS workspace_usage (
+                workspace_id INTEGER PRIMARY KEY,
+                bytes BIGINT NOT NULL DEFAULT 0,
+                files INTEGER NOT NULL DEFAULT 0,
+                updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+                FOREIGN KEY (workspace_id) REFERENCES workspaces (id) ON DELETE CASCADE
+            );
+        `,
+		Down: `
+            DROP TABLE IF EXISTS workspace_usage;
+            DROP TABLE IF EXISTS workspace_quotas;
+        `,
+	},
+	{
+		Version: 16,
+		Up: `
+            -- Where users authenticate, LDAP only manages the users it created
+            ALTER TABLE users ADD COLUMN auth_source TEXT NOT NULL DEFAULT 'local' CHECK(auth_source IN ('local', 'ldap'));
+        `,
+		Down: `
+            ALTER TABLE users DROP COLUMN auth_source;
+        `,
+	},
+	{
+		Version: 17,
+		Up: `
+            -- Users signing up without an invitation can't log in until they confirm their email address
+            ALTER TABLE users ADD COLUMN email_verification_pending BOOLEAN NOT NULL DEFAULT FALSE;
+        `,
+		Down: `
+            ALTER TABLE users DROP COLUMN email_verification_pending;
+        `,
+	},
+	{
+		Version: 18,
+		Up: `
+            -- The file count of workspaces no longer includes Git objects, trash and revisions,
+            -- so the stored usage is discarded and measured again
+            DELETE FROM workspace_usage;
+        `,
+		Down: `
+            DELETE FROM workspace_usage;
+        `,
+	},
+}
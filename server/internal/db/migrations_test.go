@@ -1,6 +1,8 @@
 package db_test
 
 import (
+	"errors"
+	"strings"
 	"testing"
 
 	"lemma/internal/db"
@@ -29,8 +31,8 @@ func TestMigrate(t *testing.T) {
 			t.Fatalf("failed to get migration version: %v", err)
 		}
 
-		if version != 1 { // Current number of migrations in production code
-			t.Errorf("expected migration version 1, got %d", version)
+		if version != 18 { // Current number of migrations in production code
+			t.Errorf("expected migration version 18, got %d", version)
 		}
 
 		// Verify number of migration entries matches versions applied
@@ -40,14 +42,14 @@ func TestMigrate(t *testing.T) {
 			t.Fatalf("failed to count migrations: %v", err)
 		}
 
-		if count != 1 {
-			t.Errorf("expected 1 migration entries, got %d", count)
+		if count != 18 {
+			t.Errorf("expected 18 migration entries, got %d", count)
 		}
 	})
 
 	t.Run("migrations create expected schema", func(t *testing.T) {
 		// Verify tables exist
-		tables := []string{"users", "workspaces", "sessions", "system_settings", "migrations"}
+		tables := []string{"users", "workspaces", "sessions", "system_settings", "rotated_refresh_tokens", "login_attempts", "jwt_keys", "user_tokens", "invitations", "workspace_members", "share_links", "migrations"}
 		for _, table := range tables {
 			if !tableExists(t, database, table) {
 				t.Errorf("table %q does not exist", table)
@@ -62,6 +64,11 @@ func TestMigrate(t *testing.T) {
 			{"sessions", "idx_sessions_user_id"},
 			{"sessions", "idx_sessions_expires_at"},
 			{"sessions", "idx_sessions_refresh_token"},
+			{"rotated_refresh_tokens", "idx_rotated_refresh_tokens_session_id"},
+			{"user_tokens", "idx_user_tokens_user_id"},
+			{"workspace_members", "idx_workspace_members_user_id"},
+			{"workspace_members", "idx_workspace_members_owner"},
+			{"share_links", "idx_share_links_workspace_id"},
 		}
 
 		for _, idx := range indexes {
@@ -84,8 +91,8 @@ func TestMigrate(t *testing.T) {
 			t.Fatalf("failed to count migrations: %v", err)
 		}
 
-		if count != 1 {
-			t.Errorf("expected 1 migration entries, got %d", count)
+		if count != 18 {
+			t.Errorf("expected 18 migration entries, got %d", count)
 		}
 	})
 
@@ -120,21 +127,205 @@ func TestMigrate(t *testing.T) {
 			t.Fatalf("failed to get migration version: %v", err)
 		}
 
-		if version != 1 {
-			t.Errorf("expected migration version to remain at 1, got %d", version)
+		if version != 18 {
+			t.Errorf("expected migration version to remain at 5, got %d", version)
 		}
 	})
 }
 
+func TestMigrateDown(t *testing.T) {
+	database, err := db.NewTestDB(":memory:", &mockSecrets{})
+	if err != nil {
+		t.Fatalf("failed to initialize database: %v", err)
+	}
+	defer database.Close()
+
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("failed to run initial migrations: %v", err)
+	}
+
+	t.Run("reverts migrations newer than the version", func(t *testing.T) {
+		if err := database.MigrateDown(5); err != nil {
+			t.Fatalf("failed to migrate down: %v", err)
+		}
+
+		if version := migrationVersion(t, database); version != 5 {
+			t.Errorf("expected migration version 5, got %d", version)
+		}
+
+		for _, table := range []string{"user_tokens", "invitations", "workspace_members", "share_links"} {
+			if tableExists(t, database, table) {
+				t.Errorf("table %q still exists", table)
+			}
+		}
+		if !tableExists(t, database, "jwt_keys") {
+			t.Error("table \"jwt_keys\" of migration 5 was removed")
+		}
+	})
+
+	t.Run("status reports pending migrations", func(t *testing.T) {
+		statuses, err := database.MigrationStatus()
+		if err != nil {
+			t.Fatalf("failed to get migration status: %v", err)
+		}
+
+		if len(statuses) != 18 {
+			t.Fatalf("expected 18 migrations, got %d", len(statuses))
+		}
+		for _, status := range statuses {
+			want := db.MigrationApplied
+			if status.Version > 5 {
+				want = db.MigrationPending
+			}
+			if status.State != want {
+				t.Errorf("migration %d: state = %q, want %q", status.Version, status.State, want)
+			}
+		}
+	})
+
+	t.Run("migrates up to a version", func(t *testing.T) {
+		if err := database.MigrateUp(7); err != nil {
+			t.Fatalf("failed to migrate up: %v", err)
+		}
+
+		if version := migrationVersion(t, database); version != 7 {
+			t.Errorf("expected migration version 7, got %d", version)
+		}
+		if !tableExists(t, database, "invitations") {
+			t.Error("table \"invitations\" does not exist")
+		}
+		if tableExists(t, database, "workspace_members") {
+			t.Error("table \"workspace_members\" of migration 8 exists")
+		}
+	})
+
+	t.Run("reverts and reapplies all migrations", func(t *testing.T) {
+		if err := database.MigrateDown(0); err != nil {
+			t.Fatalf("failed to migrate down: %v", err)
+		}
+		if tableExists(t, database, "users") {
+			t.Error("table \"users\" still exists")
+		}
+
+		if err := database.Migrate(); err != nil {
+			t.Fatalf("failed to migrate up: %v", err)
+		}
+		if version := migrationVersion(t, database); version != 18 {
+			t.Errorf("expected migration version 18, got %d", version)
+		}
+	})
+
+	t.Run("rejects unknown versions", func(t *testing.T) {
+		if err := database.MigrateDown(-1); err == nil {
+			t.Error("expected error for negative version, got nil")
+		}
+		if err := database.MigrateUp(100); err == nil {
+			t.Error("expected error for unknown version, got nil")
+		}
+	})
+}
+
+func TestMigrationChecks(t *testing.T) {
+	database, err := db.NewTestDB(":memory:", &mockSecrets{})
+	if err != nil {
+		t.Fatalf("failed to initialize database: %v", err)
+	}
+	defer database.Close()
+
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("failed to run initial migrations: %v", err)
+	}
+
+	t.Run("records missing checksums", func(t *testing.T) {
+		if _, err := database.TestDB().Exec("UPDATE migrations SET checksum = ''"); err != nil {
+			t.Fatalf("failed to clear checksums: %v", err)
+		}
+
+		if err := database.Migrate(); err != nil {
+			t.Fatalf("failed to run migrations: %v", err)
+		}
+
+		var missing int
+		err := database.TestDB().QueryRow("SELECT COUNT(*) FROM migrations WHERE checksum = ''").Scan(&missing)
+		if err != nil {
+			t.Fatalf("failed to count checksums: %v", err)
+		}
+		if missing != 0 {
+			t.Errorf("expected all checksums to be recorded, %d are missing", missing)
+		}
+	})
+
+	t.Run("detects modified migrations", func(t *testing.T) {
+		if _, err := database.TestDB().Exec("UPDATE migrations SET checksum = 'edited' WHERE version = 3"); err != nil {
+			t.Fatalf("failed to change checksum: %v", err)
+		}
+
+		err := database.Migrate()
+		if err == nil || !strings.Contains(err.Error(), "migration 3 was modified") {
+			t.Errorf("expected modified migration error, got %v", err)
+		}
+
+		statuses, err := database.MigrationStatus()
+		if err != nil {
+			t.Fatalf("failed to get migration status: %v", err)
+		}
+		if statuses[2].State != db.MigrationModified {
+			t.Errorf("migration 3: state = %q, want %q", statuses[2].State, db.MigrationModified)
+		}
+
+		if _, err := database.TestDB().Exec("UPDATE migrations SET checksum = '' WHERE version = 3"); err != nil {
+			t.Fatalf("failed to reset checksum: %v", err)
+		}
+	})
+
+	t.Run("refuses databases newer than the migrations", func(t *testing.T) {
+		if _, err := database.TestDB().Exec("INSERT INTO migrations (version, checksum) VALUES (100, 'future')"); err != nil {
+			t.Fatalf("failed to insert migration: %v", err)
+		}
+
+		if err := database.Migrate(); !errors.Is(err, db.ErrDatabaseNewer) {
+			t.Errorf("expected ErrDatabaseNewer, got %v", err)
+		}
+		if err := database.MigrateDown(5); !errors.Is(err, db.ErrDatabaseNewer) {
+			t.Errorf("expected ErrDatabaseNewer, got %v", err)
+		}
+
+		statuses, err := database.MigrationStatus()
+		if err != nil {
+			t.Fatalf("failed to get migration status: %v", err)
+		}
+		last := statuses[len(statuses)-1]
+		if last.Version != 100 || last.State != db.MigrationUnknown {
+			t.Errorf("last status = %+v, want version 100 unknown", last)
+		}
+	})
+}
+
+func migrationVersion(t *testing.T, database db.TestDatabase) int {
+	t.Helper()
+
+	var version int
+	err := database.TestDB().QueryRow("SELECT COALESCE(MAX(version), 0) FROM migrations").Scan(&version)
+	if err != nil {
+		t.Fatalf("failed to get migration version: %v", err)
+	}
+	return version
+}
+
 func tableExists(t *testing.T, database db.TestDatabase, tableName string) bool {
 	t.Helper()
 
-	var name string
-	err := database.TestDB().QueryRow(`
+	query := `
 		SELECT name FROM sqlite_master 
-		WHERE type='table' AND name=?`,
-		tableName,
-	).Scan(&name)
+		WHERE type='table' AND name=?`
+	if database.Dialect() == db.DialectPostgres {
+		query = `
+		SELECT table_name FROM information_schema.tables
+		WHERE table_schema = current_schema() AND table_name = ?`
+	}
+
+	var name string
+	err := database.TestDB().QueryRow(query, tableName).Scan(&name)
 
 	return err == nil
 }
@@ -142,12 +333,17 @@ func tableExists(t *testing.T, database db.TestDatabase, tableName string) bool
 func indexExists(t *testing.T, database db.TestDatabase, tableName, indexName string) bool {
 	t.Helper()
 
-	var name string
-	err := database.TestDB().QueryRow(`
+	query := `
 		SELECT name FROM sqlite_master 
-		WHERE type='index' AND tbl_name=? AND name=?`,
-		tableName, indexName,
-	).Scan(&name)
+		WHERE type='index' AND tbl_name=? AND name=?`
+	if database.Dialect() == db.DialectPostgres {
+		query = `
+		SELECT indexname FROM pg_indexes
+		WHERE schemaname = current_schema() AND tablename = ? AND indexname = ?`
+	}
+
+	var name string
+	err := database.TestDB().QueryRow(query, tableName, indexName).Scan(&name)
 
 	return err == nil
 }
@@ -0,0 +1,216 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"lemma/internal/models"
+)
+
+const (
+	// DefaultQuotaBytesKey is the key of the setting that limits the bytes stored by users without their own quota
+	DefaultQuotaBytesKey = "default_quota_bytes"
+	// DefaultQuotaFilesKey is the key of the setting that limits the files stored by users without their own quota
+	DefaultQuotaFilesKey = "default_quota_files"
+)
+
+// GetDefaultQuota retrieves the storage quota of users without their own quota.
+// Storage is unlimited unless configured otherwise.
+func (db *database) GetDefaultQuota() (*models.StorageQuota, error) {
+	quota := &models.StorageQuota{}
+
+	maxBytes, err := db.GetSystemSetting(DefaultQuotaBytesKey)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get default quota bytes setting: %w", err)
+	}
+	if err == nil {
+		quota.MaxBytes, _ = strconv.ParseInt(maxBytes, 10, 64)
+	}
+
+	maxFiles, err := db.GetSystemSetting(DefaultQuotaFilesKey)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get default quota files setting: %w", err)
+	}
+	if err == nil {
+		quota.MaxFiles, _ = strconv.Atoi(maxFiles)
+	}
+
+	return quota, nil
+}
+
+// UpdateDefaultQuota stores the storage quota of users without their own quota in a single transaction
+func (db *database) UpdateDefaultQuota(quota *models.StorageQuota) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	values := map[string]string{
+		DefaultQuotaBytesKey: strconv.FormatInt(quota.MaxBytes, 10),
+		DefaultQuotaFilesKey: strconv.Itoa(quota.MaxFiles),
+	}
+	for key, value := range values {
+		_, err := tx.Exec(`
+            INSERT INTO system_settings (key, value)
+            VALUES (?, ?)
+            ON CONFLICT(key) DO UPDATE SET value = excluded.value`,
+			key, value)
+		if err != nil {
+			return fmt.Errorf("failed to store system setting: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetUserQuota retrieves the storage quota set for the user.
+// It returns nil if the system default applies to the user.
+func (db *database) GetUserQuota(userID int) (*models.StorageQuota, error) {
+	quota := &models.StorageQuota{}
+	err := db.QueryRow(`
+        SELECT max_bytes, max_files
+        FROM user_quotas
+        WHERE user_id = ?`,
+		userID).
+		Scan(&quota.MaxBytes, &quota.MaxFiles)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user quota: %w", err)
+	}
+
+	return quota, nil
+}
+
+// SetUserQuota stores the storage quota of the user, replacing the system default for them
+func (db *database) SetUserQuota(userID int, quota *models.StorageQuota) error {
+	_, err := db.Exec(`
+        INSERT INTO user_quotas (user_id, max_bytes, max_files, updated_at)
+        VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+        ON CONFLICT(user_id) DO UPDATE SET
+            max_bytes = excluded.max_bytes,
+            max_files = excluded.max_files,
+            updated_at = excluded.updated_at`,
+		userID, quota.MaxBytes, quota.MaxFiles)
+	if err != nil {
+		return fmt.Errorf("failed to store user quota: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteUserQuota removes the storage quota of the user, so the system default applies to them again
+func (db *database) DeleteUserQuota(userID int) error {
+	_, err := db.Exec("DELETE FROM user_quotas WHERE user_id = ?", userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete user quota: %w", err)
+	}
+
+	return nil
+}
+
+// GetStorageQuota returns the storage quota that applies to the user,
+// which is their own quota or the system default
+func (db *database) GetStorageQuota(userID int) (*models.StorageQuota, error) {
+	quota, err := db.GetUserQuota(userID)
+	if err != nil {
+		return nil, err
+	}
+	if quota != nil {
+		return quota, nil
+	}
+
+	return db.GetDefaultQuota()
+}
+
+// GetWorkspaceQuota retrieves the storage quota of the workspace.
+// It returns nil if the workspace is only limited by the quota of its owner.
+func (db *database) GetWorkspaceQuota(workspaceID int) (*models.StorageQuota, error) {
+	quota := &models.StorageQuota{}
+	err := db.QueryRow(`
+        SELECT max_bytes, max_files
+        FROM workspace_quotas
+        WHERE workspace_id = ?`,
+		workspaceID).
+		Scan(&quota.MaxBytes, &quota.MaxFiles)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workspace quota: %w", err)
+	}
+
+	return quota, nil
+}
+
+// SetWorkspaceQuota stores the storage quota of the workspace
+func (db *database) SetWorkspaceQuota(workspaceID int, quota *models.StorageQuota) error {
+	_, err := db.Exec(`
+        INSERT INTO workspace_quotas (workspace_id, max_bytes, max_files, updated_at)
+        VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+        ON CONFLICT(workspace_id) DO UPDATE SET
+            max_bytes = excluded.max_bytes,
+            max_files = excluded.max_files,
+            updated_at = excluded.updated_at`,
+		workspaceID, quota.MaxBytes, quota.MaxFiles)
+	if err != nil {
+		return fmt.Errorf("failed to store workspace quota: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteWorkspaceQuota removes the storage quota of the workspace
+func (db *database) DeleteWorkspaceQuota(workspaceID int) error {
+	_, err := db.Exec("DELETE FROM workspace_quotas WHERE workspace_id = ?", workspaceID)
+	if err != nil {
+		return fmt.Errorf("failed to delete workspace quota: %w", err)
+	}
+
+	return nil
+}
+
+// GetWorkspaceUsage retrieves the recorded storage usage of the workspace.
+// It returns nil if no usage was recorded for the workspace yet.
+func (db *database) GetWorkspaceUsage(workspaceID int) (*models.StorageUsage, error) {
+	usage := &models.StorageUsage{}
+	err := db.QueryRow(`
+        SELECT bytes, files
+        FROM workspace_usage
+        WHERE workspace_id = ?`,
+		workspaceID).
+		Scan(&usage.Bytes, &usage.Files)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workspace usage: %w", err)
+	}
+
+	return usage, nil
+}
+
+// SetWorkspaceUsage records the storage usage of the workspace
+func (db *database) SetWorkspaceUsage(workspaceID int, usage *models.StorageUsage) error {
+	_, err := db.Exec(`
+        INSERT INTO workspace_usage (workspace_id, bytes, files, updated_at)
+        VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+        ON CONFLICT(workspace_id) DO UPDATE SET
+            bytes = excluded.bytes,
+            files = excluded.files,
+            updated_at = excluded.updated_at`,
+		workspaceID, usage.Bytes, usage.Files)
+	if err != nil {
+		return fmt.Errorf("failed to store workspace usage: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,176 @@
+package db_test
+
+import (
+	"testing"
+
+	"lemma/internal/db"
+	"lemma/internal/models"
+	_ "lemma/internal/testenv"
+)
+
+func TestQuotaOperations(t *testing.T) {
+	database, err := db.NewTestDB(":memory:", &mockSecrets{})
+	if err != nil {
+		t.Fatalf("failed to create test database: %v", err)
+	}
+	defer database.Close()
+
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	user, err := database.CreateUser(&models.User{
+		Email:        "quota@test.com",
+		DisplayName:  "Quota User",
+		PasswordHash: "hash",
+		Role:         models.RoleEditor,
+	})
+	if err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+
+	t.Run("default quota", func(t *testing.T) {
+		quota, err := database.GetDefaultQuota()
+		if err != nil {
+			t.Fatalf("failed to get default quota: %v", err)
+		}
+		if !quota.Unlimited() {
+			t.Errorf("default quota = %+v, want unlimited", quota)
+		}
+
+		want := models.StorageQuota{MaxBytes: 1 << 30, MaxFiles: 1000}
+		if err := database.UpdateDefaultQuota(&want); err != nil {
+			t.Fatalf("failed to update default quota: %v", err)
+		}
+
+		quota, err = database.GetDefaultQuota()
+		if err != nil {
+			t.Fatalf("failed to get default quota: %v", err)
+		}
+		if *quota != want {
+			t.Errorf("default quota = %+v, want %+v", quota, want)
+		}
+	})
+
+	t.Run("user quota overrides the default", func(t *testing.T) {
+		quota, err := database.GetUserQuota(user.ID)
+		if err != nil {
+			t.Fatalf("failed to get user quota: %v", err)
+		}
+		if quota != nil {
+			t.Errorf("user quota = %+v, want nil", quota)
+		}
+
+		want := models.StorageQuota{MaxBytes: 1024, MaxFiles: 0}
+		for _, maxBytes := range []int64{512, want.MaxBytes} {
+			if err := database.SetUserQuota(user.ID, &models.StorageQuota{MaxBytes: maxBytes}); err != nil {
+				t.Fatalf("failed to set user quota: %v", err)
+			}
+		}
+
+		quota, err = database.GetStorageQuota(user.ID)
+		if err != nil {
+			t.Fatalf("failed to get storage quota: %v", err)
+		}
+		if *quota != want {
+			t.Errorf("storage quota = %+v, want %+v", quota, want)
+		}
+	})
+
+	t.Run("deleting the user quota restores the default", func(t *testing.T) {
+		if err := database.DeleteUserQuota(user.ID); err != nil {
+			t.Fatalf("failed to delete user quota: %v", err)
+		}
+
+		quota, err := database.GetStorageQuota(user.ID)
+		if err != nil {
+			t.Fatalf("failed to get storage quota: %v", err)
+		}
+		if quota.MaxBytes != 1<<30 || quota.MaxFiles != 1000 {
+			t.Errorf("storage quota = %+v, want the default quota", quota)
+		}
+	})
+}
+
+func TestWorkspaceQuotaOperations(t *testing.T) {
+	database, err := db.NewTestDB(":memory:", &mockSecrets{})
+	if err != nil {
+		t.Fatalf("failed to create test database: %v", err)
+	}
+	defer database.Close()
+
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	user, err := database.CreateUser(&models.User{
+		Email:        "quota@test.com",
+		DisplayName:  "Quota User",
+		PasswordHash: "hash",
+		Role:         models.RoleEditor,
+	})
+	if err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+	workspace := &models.Workspace{UserID: user.ID, Name: "Limited"}
+	if err := database.CreateWorkspace(workspace); err != nil {
+		t.Fatalf("failed to create test workspace: %v", err)
+	}
+
+	t.Run("workspace quota", func(t *testing.T) {
+		quota, err := database.GetWorkspaceQuota(workspace.ID)
+		if err != nil {
+			t.Fatalf("failed to get workspace quota: %v", err)
+		}
+		if quota != nil {
+			t.Errorf("workspace quota = %+v, want nil", quota)
+		}
+
+		want := models.StorageQuota{MaxBytes: 2048, MaxFiles: 10}
+		for _, maxFiles := range []int{5, want.MaxFiles} {
+			if err := database.SetWorkspaceQuota(workspace.ID, &models.StorageQuota{MaxBytes: want.MaxBytes, MaxFiles: maxFiles}); err != nil {
+				t.Fatalf("failed to set workspace quota: %v", err)
+			}
+		}
+
+		quota, err = database.GetWorkspaceQuota(workspace.ID)
+		if err != nil {
+			t.Fatalf("failed to get workspace quota: %v", err)
+		}
+		if quota == nil || *quota != want {
+			t.Errorf("workspace quota = %+v, want %+v", quota, want)
+		}
+
+		if err := database.DeleteWorkspaceQuota(workspace.ID); err != nil {
+			t.Fatalf("failed to delete workspace quota: %v", err)
+		}
+		if quota, err = database.GetWorkspaceQuota(workspace.ID); err != nil || quota != nil {
+			t.Errorf("workspace quota after delete = %+v, %v, want nil", quota, err)
+		}
+	})
+
+	t.Run("workspace usage", func(t *testing.T) {
+		usage, err := database.GetWorkspaceUsage(workspace.ID)
+		if err != nil {
+			t.Fatalf("failed to get workspace usage: %v", err)
+		}
+		if usage != nil {
+			t.Errorf("workspace usage = %+v, want nil", usage)
+		}
+
+		want := models.StorageUsage{Bytes: 4096, Files: 3}
+		for _, bytes := range []int64{100, want.Bytes} {
+			if err := database.SetWorkspaceUsage(workspace.ID, &models.StorageUsage{Bytes: bytes, Files: want.Files}); err != nil {
+				t.Fatalf("failed to set workspace usage: %v", err)
+			}
+		}
+
+		usage, err = database.GetWorkspaceUsage(workspace.ID)
+		if err != nil {
+			t.Fatalf("failed to get workspace usage: %v", err)
+		}
+		if usage == nil || *usage != want {
+			t.Errorf("workspace usage = %+v, want %+v", usage, want)
+		}
+	})
+}
@@ -0,0 +1,92 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// encryptedColumns are the columns holding data encrypted by the secrets service
+var encryptedColumns = []struct {
+	table    string
+	idColumn string
+	column   string
+}{
+	{"workspaces", "id", "git_token"},
+	{"jwt_keys", "id", "private_key"},
+}
+
+// ReencryptSecrets decrypts all encrypted data with any of the keys of the secrets service
+// and encrypts it again with its current key, in a single transaction. It returns the
+// number of re-encrypted values.
+func (db *database) ReencryptSecrets() (int, error) {
+	log := getLogger().WithGroup("secrets")
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	total := 0
+	for _, c := range encryptedColumns {
+		count, err := db.reencryptColumn(tx, c.table, c.idColumn, c.column)
+		if err != nil {
+			return 0, err
+		}
+		total += count
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	log.Info("secrets re-encrypted", "count", total)
+	return total, nil
+}
+
+func (db *database) reencryptColumn(tx *sql.Tx, table, idColumn, column string) (int, error) {
+	type encryptedValue struct {
+		id    any
+		value string
+	}
+
+	rows, err := tx.Query(fmt.Sprintf("SELECT %s, %s FROM %s WHERE %s IS NOT NULL AND %s != ''",
+		idColumn, column, table, column, column))
+	if err != nil {
+		return 0, fmt.Errorf("failed to query %s.%s: %w", table, column, err)
+	}
+
+	// All rows are read before updating, as a transaction can't run statements while rows are open
+	var values []encryptedValue
+	for rows.Next() {
+		var v encryptedValue
+		if err := rows.Scan(&v.id, &v.value); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan %s.%s: %w", table, column, err)
+		}
+		values = append(values, v)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to iterate %s.%s: %w", table, column, err)
+	}
+
+	for _, v := range values {
+		plaintext, err := db.decryptToken(v.value)
+		if err != nil {
+			return 0, fmt.Errorf("failed to decrypt %s.%s of %v: %w", table, column, v.id, err)
+		}
+
+		encrypted, err := db.encryptToken(plaintext)
+		if err != nil {
+			return 0, fmt.Errorf("failed to encrypt %s.%s of %v: %w", table, column, v.id, err)
+		}
+
+		_, err = tx.Exec(fmt.Sprintf("UPDATE %s SET %s = ? WHERE %s = ?", table, column, idColumn), encrypted, v.id)
+		if err != nil {
+			return 0, fmt.Errorf("failed to update %s.%s of %v: %w", table, column, v.id, err)
+		}
+	}
+
+	return len(values), nil
+}
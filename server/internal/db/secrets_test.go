@@ -0,0 +1,110 @@
+package db_test
+
+import (
+	"encoding/base64"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"lemma/internal/db"
+	"lemma/internal/models"
+	"lemma/internal/secrets"
+	_ "lemma/internal/testenv"
+)
+
+func TestReencryptSecrets(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "lemma.db")
+	oldKey := base64.StdEncoding.EncodeToString(make([]byte, 32))
+	newKey := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32)))
+
+	newSecrets := func(t *testing.T, key string, previousKeys ...string) secrets.Service {
+		t.Helper()
+		service, err := secrets.NewService(key, previousKeys...)
+		if err != nil {
+			t.Fatalf("failed to create secrets service: %v", err)
+		}
+		return service
+	}
+
+	openDB := func(t *testing.T, secretsService secrets.Service) db.TestDatabase {
+		t.Helper()
+		database, err := db.NewTestDB(dbPath, secretsService)
+		if err != nil {
+			t.Fatalf("failed to create test database: %v", err)
+		}
+		return database
+	}
+
+	// Store a git token and a JWT key encrypted with the old key
+	database := openDB(t, newSecrets(t, oldKey))
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+	user, err := database.CreateUser(&models.User{
+		Email:        "test@example.com",
+		PasswordHash: "hash",
+		Role:         models.RoleEditor,
+	})
+	if err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+	workspace := &models.Workspace{UserID: user.ID, Name: "Git Workspace", GitToken: "secret-token"}
+	if err := database.CreateWorkspace(workspace); err != nil {
+		t.Fatalf("failed to create workspace: %v", err)
+	}
+	if err := database.CreateJWTKey(&models.JWTKey{ID: "key-1", Algorithm: "HS256", PrivateKey: "private", Active: true}); err != nil {
+		t.Fatalf("failed to create JWT key: %v", err)
+	}
+	database.Close()
+
+	t.Run("re-encrypts with the current key", func(t *testing.T) {
+		database := openDB(t, newSecrets(t, newKey, oldKey))
+		defer database.Close()
+
+		count, err := database.ReencryptSecrets()
+		if err != nil {
+			t.Fatalf("ReencryptSecrets() error = %v", err)
+		}
+		if count != 2 {
+			t.Errorf("ReencryptSecrets() count = %d, want 2", count)
+		}
+	})
+
+	t.Run("secrets decrypt without the previous key", func(t *testing.T) {
+		database := openDB(t, newSecrets(t, newKey))
+		defer database.Close()
+
+		got, err := database.GetWorkspaceByID(workspace.ID)
+		if err != nil {
+			t.Fatalf("failed to get workspace: %v", err)
+		}
+		if got.GitToken != "secret-token" {
+			t.Errorf("GitToken = %q, want %q", got.GitToken, "secret-token")
+		}
+
+		keys, err := database.GetJWTKeys()
+		if err != nil {
+			t.Fatalf("failed to get JWT keys: %v", err)
+		}
+		if len(keys) != 1 || keys[0].PrivateKey != "private" {
+			t.Errorf("GetJWTKeys() = %+v, want key with private key %q", keys, "private")
+		}
+	})
+
+	t.Run("fails without the key of existing secrets", func(t *testing.T) {
+		otherKey := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("o", 32)))
+		database := openDB(t, newSecrets(t, otherKey))
+		defer database.Close()
+
+		if _, err := database.ReencryptSecrets(); err == nil {
+			t.Error("expected error, got nil")
+		}
+
+		// The transaction is rolled back, so the secrets stay readable with the current key
+		database = openDB(t, newSecrets(t, newKey))
+		defer database.Close()
+		if got, err := database.GetWorkspaceByID(workspace.ID); err != nil || got.GitToken != "secret-token" {
+			t.Errorf("GetWorkspaceByID() = %v, %v after failed rotation", got, err)
+		}
+	})
+}
@@ -10,10 +10,15 @@ import (
 
 // CreateSession inserts a new session record into the database
 func (db *database) CreateSession(session *models.Session) error {
+	if session.LastUsedAt.IsZero() {
+		session.LastUsedAt = session.CreatedAt
+	}
+
 	_, err := db.Exec(`
-        INSERT INTO sessions (id, user_id, refresh_token, expires_at, created_at)
-        VALUES (?, ?, ?, ?, ?)`,
-		session.ID, session.UserID, session.RefreshToken, session.ExpiresAt, session.CreatedAt,
+        INSERT INTO sessions (id, user_id, refresh_token, user_agent, ip_address, expires_at, created_at, last_used_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		session.ID, session.UserID, session.RefreshToken, session.UserAgent, session.IPAddress,
+		session.ExpiresAt, session.CreatedAt, session.LastUsedAt,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to store session: %w", err)
@@ -26,11 +31,12 @@ func (db *database) CreateSession(session *models.Session) error {
 func (db *database) GetSessionByRefreshToken(refreshToken string) (*models.Session, error) {
 	session := &models.Session{}
 	err := db.QueryRow(`
-        SELECT id, user_id, refresh_token, expires_at, created_at
+        SELECT id, user_id, refresh_token, user_agent, ip_address, expires_at, created_at, last_used_at
         FROM sessions
         WHERE refresh_token = ? AND expires_at > ?`,
 		refreshToken, time.Now(),
-	).Scan(&session.ID, &session.UserID, &session.RefreshToken, &session.ExpiresAt, &session.CreatedAt)
+	).Scan(&session.ID, &session.UserID, &session.RefreshToken, &session.UserAgent, &session.IPAddress,
+		&session.ExpiresAt, &session.CreatedAt, &session.LastUsedAt)
 
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("session not found or expired")
@@ -46,11 +52,12 @@ func (db *database) GetSessionByRefreshToken(refreshToken string) (*models.Sessi
 func (db *database) GetSessionByID(sessionID string) (*models.Session, error) {
 	session := &models.Session{}
 	err := db.QueryRow(`
-        SELECT id, user_id, refresh_token, expires_at, created_at
+        SELECT id, user_id, refresh_token, user_agent, ip_address, expires_at, created_at, last_used_at
         FROM sessions
         WHERE id = ? AND expires_at > ?`,
 		sessionID, time.Now(),
-	).Scan(&session.ID, &session.UserID, &session.RefreshToken, &session.ExpiresAt, &session.CreatedAt)
+	).Scan(&session.ID, &session.UserID, &session.RefreshToken, &session.UserAgent, &session.IPAddress,
+		&session.ExpiresAt, &session.CreatedAt, &session.LastUsedAt)
 
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("session not found")
@@ -62,6 +69,110 @@ func (db *database) GetSessionByID(sessionID string) (*models.Session, error) {
 	return session, nil
 }
 
+// GetSessionsByUserID retrieves all active sessions for a user, most recently used first
+func (db *database) GetSessionsByUserID(userID int) ([]*models.Session, error) {
+	rows, err := db.Query(`
+        SELECT id, user_id, refresh_token, user_agent, ip_address, expires_at, created_at, last_used_at
+        FROM sessions
+        WHERE user_id = ? AND expires_at > ?
+        ORDER BY last_used_at DESC`,
+		userID, time.Now(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []*models.Session{}
+	for rows.Next() {
+		session := &models.Session{}
+		err := rows.Scan(&session.ID, &session.UserID, &session.RefreshToken, &session.UserAgent, &session.IPAddress,
+			&session.ExpiresAt, &session.CreatedAt, &session.LastUsedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session row: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating session rows: %w", err)
+	}
+
+	return sessions, nil
+}
+
+// UpdateSessionLastUsed records the time a session was last used
+func (db *database) UpdateSessionLastUsed(sessionID string, lastUsedAt time.Time) error {
+	_, err := db.Exec("UPDATE sessions SET last_used_at = ? WHERE id = ?", lastUsedAt, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to update session last used time: %w", err)
+	}
+
+	return nil
+}
+
+// RotateRefreshToken replaces the refresh token of a session with newRefreshToken and
+// records the previous token so that any later use of it can be detected as reuse.
+// The rotation fails if the session's refresh token has already been rotated.
+func (db *database) RotateRefreshToken(session *models.Session, newRefreshToken string) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("UPDATE sessions SET refresh_token = ? WHERE id = ? AND refresh_token = ?",
+		newRefreshToken, session.ID, session.RefreshToken)
+	if err != nil {
+		return fmt.Errorf("failed to update refresh token: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("session not found or refresh token already rotated")
+	}
+
+	_, err = tx.Exec(`
+        INSERT INTO rotated_refresh_tokens (token, session_id, user_id, rotated_at, expires_at)
+        VALUES (?, ?, ?, ?, ?)`,
+		session.RefreshToken, session.ID, session.UserID, time.Now(), session.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store rotated refresh token: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	session.RefreshToken = newRefreshToken
+	return nil
+}
+
+// GetRotatedRefreshToken retrieves a refresh token that has already been rotated out of its session
+func (db *database) GetRotatedRefreshToken(refreshToken string) (*models.RotatedRefreshToken, error) {
+	rotated := &models.RotatedRefreshToken{}
+	err := db.QueryRow(`
+        SELECT token, session_id, user_id, rotated_at, expires_at
+        FROM rotated_refresh_tokens
+        WHERE token = ? AND expires_at > ?`,
+		refreshToken, time.Now(),
+	).Scan(&rotated.Token, &rotated.SessionID, &rotated.UserID, &rotated.RotatedAt, &rotated.ExpiresAt)
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("rotated refresh token not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch rotated refresh token: %w", err)
+	}
+
+	return rotated, nil
+}
+
 // DeleteSession removes a session from the database
 func (db *database) DeleteSession(sessionID string) error {
 	result, err := db.Exec("DELETE FROM sessions WHERE id = ?", sessionID)
@@ -81,6 +192,36 @@ func (db *database) DeleteSession(sessionID string) error {
 	return nil
 }
 
+// DeleteUserSessions removes all sessions of a user except the one with exceptSessionID.
+// Pass an empty exceptSessionID to remove every session of the user.
+func (db *database) DeleteUserSessions(userID int, exceptSessionID string) error {
+	log := getLogger().WithGroup("sessions")
+	result, err := db.Exec("DELETE FROM sessions WHERE user_id = ? AND id != ?", userID, exceptSessionID)
+	if err != nil {
+		return fmt.Errorf("failed to delete user sessions: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	log.Debug("deleted user sessions",
+		"user_id", userID,
+		"sessions_removed", rowsAffected)
+	return nil
+}
+
+// CountActiveSessions returns the number of sessions that have not expired
+func (db *database) CountActiveSessions() (int, error) {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM sessions WHERE expires_at > ?", time.Now()).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count active sessions: %w", err)
+	}
+	return count, nil
+}
+
 // CleanExpiredSessions removes all expired sessions from the database
 func (db *database) CleanExpiredSessions() error {
 	log := getLogger().WithGroup("sessions")
@@ -94,6 +235,18 @@ func (db *database) CleanExpiredSessions() error {
 		return fmt.Errorf("failed to get rows affected: %w", err)
 	}
 
-	log.Info("cleaned expired sessions", "sessions_removed", rowsAffected)
+	result, err = db.Exec("DELETE FROM rotated_refresh_tokens WHERE expires_at <= ?", time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to clean expired rotated refresh tokens: %w", err)
+	}
+
+	tokensRemoved, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	log.Info("cleaned expired sessions",
+		"sessions_removed", rowsAffected,
+		"rotated_tokens_removed", tokensRemoved)
 	return nil
 }
@@ -238,6 +238,193 @@ func TestSessionOperations(t *testing.T) {
 		}
 	})
 
+	t.Run("GetSessionsByUserID", func(t *testing.T) {
+		otherUser, err := database.CreateUser(&models.User{
+			Email:        "sessions-list@example.com",
+			DisplayName:  "Sessions User",
+			PasswordHash: "hash",
+			Role:         "editor",
+		})
+		if err != nil {
+			t.Fatalf("failed to create test user: %v", err)
+		}
+
+		sessions := []*models.Session{
+			{
+				ID:           uuid.New().String(),
+				UserID:       otherUser.ID,
+				RefreshToken: "list-token-older",
+				UserAgent:    "agent-1",
+				IPAddress:    "10.0.0.1",
+				ExpiresAt:    time.Now().Add(24 * time.Hour),
+				CreatedAt:    time.Now().Add(-2 * time.Hour),
+			},
+			{
+				ID:           uuid.New().String(),
+				UserID:       otherUser.ID,
+				RefreshToken: "list-token-newer",
+				UserAgent:    "agent-2",
+				IPAddress:    "10.0.0.2",
+				ExpiresAt:    time.Now().Add(24 * time.Hour),
+				CreatedAt:    time.Now().Add(-1 * time.Hour),
+			},
+			{
+				ID:           uuid.New().String(),
+				UserID:       otherUser.ID,
+				RefreshToken: "list-token-expired",
+				ExpiresAt:    time.Now().Add(-1 * time.Hour),
+				CreatedAt:    time.Now().Add(-3 * time.Hour),
+			},
+		}
+		for _, s := range sessions {
+			if err := database.CreateSession(s); err != nil {
+				t.Fatalf("failed to create session: %v", err)
+			}
+		}
+
+		got, err := database.GetSessionsByUserID(otherUser.ID)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if len(got) != 2 {
+			t.Fatalf("got %d sessions, want 2", len(got))
+		}
+		if got[0].ID != sessions[1].ID {
+			t.Errorf("first session = %v, want most recently used %v", got[0].ID, sessions[1].ID)
+		}
+		if got[0].UserAgent != "agent-2" || got[0].IPAddress != "10.0.0.2" {
+			t.Errorf("client details = (%v, %v), want (agent-2, 10.0.0.2)", got[0].UserAgent, got[0].IPAddress)
+		}
+
+		// Touching the older session moves it to the front
+		if err := database.UpdateSessionLastUsed(sessions[0].ID, time.Now()); err != nil {
+			t.Fatalf("failed to update last used time: %v", err)
+		}
+		got, err = database.GetSessionsByUserID(otherUser.ID)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got[0].ID != sessions[0].ID {
+			t.Errorf("first session = %v, want %v", got[0].ID, sessions[0].ID)
+		}
+	})
+
+	t.Run("DeleteUserSessions", func(t *testing.T) {
+		otherUser, err := database.CreateUser(&models.User{
+			Email:        "sessions-delete@example.com",
+			DisplayName:  "Sessions User",
+			PasswordHash: "hash",
+			Role:         "editor",
+		})
+		if err != nil {
+			t.Fatalf("failed to create test user: %v", err)
+		}
+
+		keep := &models.Session{
+			ID:           uuid.New().String(),
+			UserID:       otherUser.ID,
+			RefreshToken: "delete-user-keep",
+			ExpiresAt:    time.Now().Add(24 * time.Hour),
+			CreatedAt:    time.Now(),
+		}
+		remove := &models.Session{
+			ID:           uuid.New().String(),
+			UserID:       otherUser.ID,
+			RefreshToken: "delete-user-remove",
+			ExpiresAt:    time.Now().Add(24 * time.Hour),
+			CreatedAt:    time.Now(),
+		}
+		unrelated := &models.Session{
+			ID:           uuid.New().String(),
+			UserID:       user.ID,
+			RefreshToken: "delete-user-unrelated",
+			ExpiresAt:    time.Now().Add(24 * time.Hour),
+			CreatedAt:    time.Now(),
+		}
+		for _, s := range []*models.Session{keep, remove, unrelated} {
+			if err := database.CreateSession(s); err != nil {
+				t.Fatalf("failed to create session: %v", err)
+			}
+		}
+
+		if err := database.DeleteUserSessions(otherUser.ID, keep.ID); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if _, err := database.GetSessionByID(keep.ID); err != nil {
+			t.Errorf("kept session was deleted: %v", err)
+		}
+		if _, err := database.GetSessionByID(remove.ID); err == nil {
+			t.Error("session was not deleted")
+		}
+		if _, err := database.GetSessionByID(unrelated.ID); err != nil {
+			t.Errorf("session of another user was deleted: %v", err)
+		}
+
+		// An empty exception removes every session
+		if err := database.DeleteUserSessions(otherUser.ID, ""); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if _, err := database.GetSessionByID(keep.ID); err == nil {
+			t.Error("session was not deleted")
+		}
+	})
+
+	t.Run("RotateRefreshToken", func(t *testing.T) {
+		session := &models.Session{
+			ID:           uuid.New().String(),
+			UserID:       user.ID,
+			RefreshToken: "rotate-old-token",
+			ExpiresAt:    time.Now().Add(24 * time.Hour),
+			CreatedAt:    time.Now(),
+		}
+		if err := database.CreateSession(session); err != nil {
+			t.Fatalf("failed to create session: %v", err)
+		}
+
+		stale := *session
+		if err := database.RotateRefreshToken(session, "rotate-new-token"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if session.RefreshToken != "rotate-new-token" {
+			t.Errorf("refresh token = %v, want %v", session.RefreshToken, "rotate-new-token")
+		}
+
+		// The session is only reachable with the new token
+		if _, err := database.GetSessionByRefreshToken("rotate-old-token"); err == nil {
+			t.Error("expected old refresh token to be rejected")
+		}
+		got, err := database.GetSessionByRefreshToken("rotate-new-token")
+		if err != nil {
+			t.Fatalf("failed to get session by new token: %v", err)
+		}
+		if got.ID != session.ID {
+			t.Errorf("session ID = %v, want %v", got.ID, session.ID)
+		}
+
+		// The old token is remembered as part of the session's token family
+		rotated, err := database.GetRotatedRefreshToken("rotate-old-token")
+		if err != nil {
+			t.Fatalf("failed to get rotated refresh token: %v", err)
+		}
+		if rotated.SessionID != session.ID {
+			t.Errorf("rotated token session ID = %v, want %v", rotated.SessionID, session.ID)
+		}
+		if rotated.UserID != user.ID {
+			t.Errorf("rotated token user ID = %v, want %v", rotated.UserID, user.ID)
+		}
+
+		if _, err := database.GetRotatedRefreshToken("rotate-new-token"); err == nil {
+			t.Error("expected current refresh token not to be marked as rotated")
+		}
+
+		// Rotating from a stale token fails
+		if err := database.RotateRefreshToken(&stale, "rotate-other-token"); err == nil {
+			t.Error("expected error when rotating an already rotated token")
+		}
+	})
+
 	t.Run("CleanExpiredSessions", func(t *testing.T) {
 		// Create a mix of valid and expired sessions
 		sessions := []*models.Session{
@@ -292,4 +479,41 @@ func TestSessionOperations(t *testing.T) {
 			}
 		}
 	})
+
+	t.Run("CountActiveSessions", func(t *testing.T) {
+		before, err := database.CountActiveSessions()
+		if err != nil {
+			t.Fatalf("failed to count active sessions: %v", err)
+		}
+
+		sessions := []*models.Session{
+			{
+				ID:           uuid.New().String(),
+				UserID:       user.ID,
+				RefreshToken: "valid-count-token",
+				ExpiresAt:    time.Now().Add(24 * time.Hour),
+				CreatedAt:    time.Now(),
+			},
+			{
+				ID:           uuid.New().String(),
+				UserID:       user.ID,
+				RefreshToken: "expired-count-token",
+				ExpiresAt:    time.Now().Add(-1 * time.Hour),
+				CreatedAt:    time.Now().Add(-2 * time.Hour),
+			},
+		}
+		for _, s := range sessions {
+			if err := database.CreateSession(s); err != nil {
+				t.Fatalf("failed to create session: %v", err)
+			}
+		}
+
+		after, err := database.CountActiveSessions()
+		if err != nil {
+			t.Fatalf("failed to count active sessions: %v", err)
+		}
+		if after != before+1 {
+			t.Errorf("CountActiveSessions() = %d, want %d", after, before+1)
+		}
+	})
 }
@@ -0,0 +1,131 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"lemma/internal/models"
+)
+
+// CreateShareLink inserts a new share link and sets its ID
+func (db *database) CreateShareLink(link *models.ShareLink) error {
+	log := getLogger().WithGroup("share_links")
+
+	if link.CreatedAt.IsZero() {
+		link.CreatedAt = time.Now()
+	}
+
+	err := db.QueryRow(`
+        INSERT INTO share_links (workspace_id, token_hash, path, is_folder, password_hash, expires_at, created_by, created_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+        RETURNING id`,
+		link.WorkspaceID, link.TokenHash, link.Path, link.IsFolder, link.PasswordHash,
+		link.ExpiresAt, link.CreatedBy, link.CreatedAt,
+	).Scan(&link.ID)
+	if err != nil {
+		return fmt.Errorf("failed to insert share link: %w", err)
+	}
+	link.HasPassword = link.PasswordHash != ""
+
+	log.Debug("share link created",
+		"share_link_id", link.ID,
+		"workspace_id", link.WorkspaceID,
+		"created_by", link.CreatedBy)
+	return nil
+}
+
+// GetShareLinks retrieves all share links of a workspace, newest first
+func (db *database) GetShareLinks(workspaceID int) ([]*models.ShareLink, error) {
+	rows, err := db.Query(`
+        SELECT id, workspace_id, token_hash, path, is_folder, password_hash, expires_at, created_by, created_at
+        FROM share_links
+        WHERE workspace_id = ?
+        ORDER BY created_at DESC, id DESC`,
+		workspaceID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query share links: %w", err)
+	}
+	defer rows.Close()
+
+	links := []*models.ShareLink{}
+	for rows.Next() {
+		link, err := scanShareLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating share link rows: %w", err)
+	}
+
+	return links, nil
+}
+
+// GetShareLinkByTokenHash retrieves a share link by the hash of its token.
+// Links of deleted workspaces or created by deleted or suspended users are not found.
+func (db *database) GetShareLinkByTokenHash(tokenHash string) (*models.ShareLink, error) {
+	row := db.QueryRow(`
+        SELECT s.id, s.workspace_id, s.token_hash, s.path, s.is_folder, s.password_hash,
+            s.expires_at, s.created_by, s.created_at
+        FROM share_links s
+        JOIN workspaces w ON w.id = s.workspace_id
+        JOIN users u ON u.id = s.created_by
+        WHERE s.token_hash = ? AND w.deleted_at IS NULL AND u.deleted_at IS NULL
+            AND u.suspended_at IS NULL`,
+		tokenHash,
+	)
+
+	link, err := scanShareLink(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("share link not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return link, nil
+}
+
+// DeleteShareLink revokes a share link of a workspace
+func (db *database) DeleteShareLink(workspaceID, linkID int) error {
+	result, err := db.Exec("DELETE FROM share_links WHERE id = ? AND workspace_id = ?", linkID, workspaceID)
+	if err != nil {
+		return fmt.Errorf("failed to delete share link: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("share link not found")
+	}
+
+	return nil
+}
+
+func scanShareLink(row rowScanner) (*models.ShareLink, error) {
+	link := &models.ShareLink{}
+	var expiresAt sql.NullTime
+	err := row.Scan(
+		&link.ID, &link.WorkspaceID, &link.TokenHash, &link.Path, &link.IsFolder,
+		&link.PasswordHash, &expiresAt, &link.CreatedBy, &link.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan share link: %w", err)
+	}
+
+	if expiresAt.Valid {
+		link.ExpiresAt = &expiresAt.Time
+	}
+	link.HasPassword = link.PasswordHash != ""
+	return link, nil
+}
@@ -0,0 +1,153 @@
+package db_test
+
+import (
+	"testing"
+	"time"
+
+	"lemma/internal/db"
+	"lemma/internal/models"
+	_ "lemma/internal/testenv"
+)
+
+func TestShareLinkOperations(t *testing.T) {
+	database, err := db.NewTestDB(":memory:", &mockSecrets{})
+	if err != nil {
+		t.Fatalf("failed to create test database: %v", err)
+	}
+	defer database.Close()
+
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	user, err := database.CreateUser(&models.User{
+		Email:        "owner@example.com",
+		DisplayName:  "Owner",
+		PasswordHash: "hash",
+		Role:         models.RoleEditor,
+	})
+	if err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+
+	workspace := &models.Workspace{UserID: user.ID, Name: "Shared Notes"}
+	if err := database.CreateWorkspace(workspace); err != nil {
+		t.Fatalf("failed to create workspace: %v", err)
+	}
+
+	createShareLink := func(t *testing.T, workspaceID int, hash string, expiresAt *time.Time) *models.ShareLink {
+		t.Helper()
+		link := &models.ShareLink{
+			WorkspaceID: workspaceID,
+			TokenHash:   hash,
+			Path:        "notes/readme.md",
+			ExpiresAt:   expiresAt,
+			CreatedBy:   user.ID,
+		}
+		if err := database.CreateShareLink(link); err != nil {
+			t.Fatalf("failed to create share link: %v", err)
+		}
+		return link
+	}
+
+	t.Run("CreateShareLink and GetShareLinkByTokenHash", func(t *testing.T) {
+		link := createShareLink(t, workspace.ID, "lookup-hash", nil)
+		if link.ID == 0 {
+			t.Fatal("expected share link ID to be set")
+		}
+
+		got, err := database.GetShareLinkByTokenHash("lookup-hash")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got.ID != link.ID || got.Path != link.Path || got.IsFolder || got.HasPassword || got.ExpiresAt != nil {
+			t.Errorf("share link = %+v, want %+v", got, link)
+		}
+
+		if _, err := database.GetShareLinkByTokenHash("unknown-hash"); err == nil {
+			t.Error("expected error for unknown share link, got nil")
+		}
+	})
+
+	t.Run("password and expiry are stored", func(t *testing.T) {
+		expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+		link := &models.ShareLink{
+			WorkspaceID:  workspace.ID,
+			TokenHash:    "protected-hash",
+			Path:         "notes",
+			IsFolder:     true,
+			PasswordHash: "password-hash",
+			ExpiresAt:    &expiresAt,
+			CreatedBy:    user.ID,
+		}
+		if err := database.CreateShareLink(link); err != nil {
+			t.Fatalf("failed to create share link: %v", err)
+		}
+
+		got, err := database.GetShareLinkByTokenHash("protected-hash")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !got.IsFolder || !got.HasPassword || got.PasswordHash != "password-hash" {
+			t.Errorf("share link = %+v, want folder with password", got)
+		}
+		if got.ExpiresAt == nil || !got.ExpiresAt.Equal(expiresAt) {
+			t.Errorf("ExpiresAt = %v, want %v", got.ExpiresAt, expiresAt)
+		}
+		if got.Expired(time.Now()) || !got.Expired(expiresAt) {
+			t.Error("unexpected result of Expired")
+		}
+	})
+
+	t.Run("GetShareLinks and DeleteShareLink", func(t *testing.T) {
+		other := &models.Workspace{UserID: user.ID, Name: "Other"}
+		if err := database.CreateWorkspace(other); err != nil {
+			t.Fatalf("failed to create workspace: %v", err)
+		}
+		otherLink := createShareLink(t, other.ID, "other-hash", nil)
+
+		links, err := database.GetShareLinks(workspace.ID)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(links) != 2 {
+			t.Fatalf("expected 2 share links, got %d", len(links))
+		}
+
+		// Links can only be revoked through their workspace
+		if err := database.DeleteShareLink(workspace.ID, otherLink.ID); err == nil {
+			t.Error("expected error deleting share link of another workspace, got nil")
+		}
+
+		if err := database.DeleteShareLink(workspace.ID, links[0].ID); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := database.DeleteShareLink(workspace.ID, links[0].ID); err == nil {
+			t.Error("expected error deleting share link twice, got nil")
+		}
+
+		links, err = database.GetShareLinks(workspace.ID)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(links) != 1 {
+			t.Errorf("expected 1 share link after delete, got %d", len(links))
+		}
+	})
+
+	t.Run("deleting a workspace revokes its share links", func(t *testing.T) {
+		doomed := &models.Workspace{UserID: user.ID, Name: "Doomed"}
+		if err := database.CreateWorkspace(doomed); err != nil {
+			t.Fatalf("failed to create workspace: %v", err)
+		}
+		createShareLink(t, doomed.ID, "doomed-hash", nil)
+
+		if err := database.DeleteWorkspace(doomed.ID); err != nil {
+			t.Fatalf("failed to delete workspace: %v", err)
+		}
+
+		if _, err := database.GetShareLinkByTokenHash("doomed-hash"); err == nil {
+			t.Error("expected share link to be deleted with its workspace")
+		}
+	})
+}
@@ -1,14 +1,25 @@
 package db
 
 import (
-	"crypto/rand"
-	"encoding/base64"
+	"database/sql"
+	"errors"
 	"fmt"
+	"strconv"
+	"time"
+
+	"lemma/internal/models"
 )
 
 const (
-	// JWTSecretKey is the key for the JWT secret in the system settings
+	// JWTSecretKey is the key of the JWT secret used by versions before the JWT keyring
 	JWTSecretKey = "jwt_secret"
+	// OpenRegistrationKey is the key of the setting that allows signing up without an invitation
+	OpenRegistrationKey = "open_registration"
+	// DefaultRoleKey is the key of the role given to users signing up without an invitation
+	DefaultRoleKey = "default_role"
+	// RequireEmailVerificationKey is the key of the setting that requires users signing up without
+	// an invitation to confirm their email address
+	RequireEmailVerificationKey = "require_email_verification"
 )
 
 // UserStats represents system-wide statistics
@@ -18,34 +29,6 @@ type UserStats struct {
 	ActiveUsers     int `json:"activeUsers"` // Users with activity in last 30 days
 }
 
-// EnsureJWTSecret makes sure a JWT signing secret exists in the database
-// If no secret exists, it generates and stores a new one
-func (db *database) EnsureJWTSecret() (string, error) {
-	log := getLogger().WithGroup("system")
-
-	// First, try to get existing secret
-	secret, err := db.GetSystemSetting(JWTSecretKey)
-	if err == nil {
-		return secret, nil
-	}
-
-	// Generate new secret if none exists
-	newSecret, err := generateRandomSecret(32) // 256 bits
-	if err != nil {
-		return "", fmt.Errorf("failed to generate JWT secret: %w", err)
-	}
-
-	// Store the new secret
-	err = db.SetSystemSetting(JWTSecretKey, newSecret)
-	if err != nil {
-		return "", fmt.Errorf("failed to store JWT secret: %w", err)
-	}
-
-	log.Info("new JWT secret generated and stored")
-
-	return newSecret, nil
-}
-
 // GetSystemSetting retrieves a system setting by key
 func (db *database) GetSystemSetting(key string) (string, error) {
 	var value string
@@ -62,8 +45,8 @@ func (db *database) SetSystemSetting(key, value string) error {
 	_, err := db.Exec(`
         INSERT INTO system_settings (key, value)
         VALUES (?, ?)
-        ON CONFLICT(key) DO UPDATE SET value = ?`,
-		key, value, value)
+        ON CONFLICT(key) DO UPDATE SET value = excluded.value`,
+		key, value)
 
 	if err != nil {
 		return fmt.Errorf("failed to store system setting: %w", err)
@@ -72,19 +55,71 @@ func (db *database) SetSystemSetting(key, value string) error {
 	return nil
 }
 
-// generateRandomSecret generates a cryptographically secure random string
-func generateRandomSecret(bytes int) (string, error) {
-	log := getLogger().WithGroup("system")
-	log.Debug("generating random secret", "bytes", bytes)
+// GetRegistrationSettings retrieves the registration settings. Registration is closed
+// and the default role is editor unless configured otherwise.
+func (db *database) GetRegistrationSettings() (*models.RegistrationSettings, error) {
+	settings := &models.RegistrationSettings{
+		OpenRegistration:         false,
+		DefaultRole:              models.RoleEditor,
+		RequireEmailVerification: true,
+	}
 
-	b := make([]byte, bytes)
-	_, err := rand.Read(b)
+	openRegistration, err := db.GetSystemSetting(OpenRegistrationKey)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get open registration setting: %w", err)
+	}
+	if err == nil {
+		settings.OpenRegistration, _ = strconv.ParseBool(openRegistration)
+	}
+
+	defaultRole, err := db.GetSystemSetting(DefaultRoleKey)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get default role setting: %w", err)
+	}
+	if err == nil {
+		settings.DefaultRole = models.UserRole(defaultRole)
+	}
+
+	requireEmailVerification, err := db.GetSystemSetting(RequireEmailVerificationKey)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get email verification setting: %w", err)
+	}
+	if err == nil {
+		settings.RequireEmailVerification, _ = strconv.ParseBool(requireEmailVerification)
+	}
+
+	return settings, nil
+}
+
+// UpdateRegistrationSettings stores the registration settings in a single transaction
+func (db *database) UpdateRegistrationSettings(settings *models.RegistrationSettings) error {
+	tx, err := db.Begin()
 	if err != nil {
-		return "", fmt.Errorf("failed to generate random bytes: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	values := map[string]string{
+		OpenRegistrationKey:         strconv.FormatBool(settings.OpenRegistration),
+		DefaultRoleKey:              string(settings.DefaultRole),
+		RequireEmailVerificationKey: strconv.FormatBool(settings.RequireEmailVerification),
+	}
+	for key, value := range values {
+		_, err := tx.Exec(`
+            INSERT INTO system_settings (key, value)
+            VALUES (?, ?)
+            ON CONFLICT(key) DO UPDATE SET value = excluded.value`,
+			key, value)
+		if err != nil {
+			return fmt.Errorf("failed to store system setting: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	secret := base64.StdEncoding.EncodeToString(b)
-	return secret, nil
+	return nil
 }
 
 // GetSystemStats returns system-wide statistics
@@ -92,13 +127,13 @@ func (db *database) GetSystemStats() (*UserStats, error) {
 	stats := &UserStats{}
 
 	// Get total users
-	err := db.QueryRow("SELECT COUNT(*) FROM users").Scan(&stats.TotalUsers)
+	err := db.QueryRow("SELECT COUNT(*) FROM users WHERE deleted_at IS NULL").Scan(&stats.TotalUsers)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get total users count: %w", err)
 	}
 
 	// Get total workspaces
-	err = db.QueryRow("SELECT COUNT(*) FROM workspaces").Scan(&stats.TotalWorkspaces)
+	err = db.QueryRow("SELECT COUNT(*) FROM workspaces WHERE deleted_at IS NULL").Scan(&stats.TotalWorkspaces)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get total workspaces count: %w", err)
 	}
@@ -107,7 +142,8 @@ func (db *database) GetSystemStats() (*UserStats, error) {
 	err = db.QueryRow(`
         SELECT COUNT(DISTINCT user_id)
         FROM sessions
-        WHERE created_at > datetime('now', '-30 days')`).
+        WHERE created_at > ?`,
+		time.Now().AddDate(0, 0, -30)).
 		Scan(&stats.ActiveUsers)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get active users count: %w", err)
@@ -1,7 +1,6 @@
 package db_test
 
 import (
-	"encoding/base64"
 	"fmt"
 	"strings"
 	"testing"
@@ -118,30 +117,29 @@ func TestSystemOperations(t *testing.T) {
 		}
 	})
 
-	t.Run("EnsureJWTSecret", func(t *testing.T) {
-		// First call should generate a new secret
-		secret1, err := database.EnsureJWTSecret()
+	t.Run("RegistrationSettings", func(t *testing.T) {
+		settings, err := database.GetRegistrationSettings()
 		if err != nil {
-			t.Fatalf("failed to ensure JWT secret: %v", err)
+			t.Fatalf("failed to get registration settings: %v", err)
+		}
+		if settings.OpenRegistration || settings.DefaultRole != models.RoleEditor || !settings.RequireEmailVerification {
+			t.Errorf("default settings = %+v, want closed registration with editor role and email verification", settings)
 		}
 
-		// Verify the secret is a valid base64-encoded string of sufficient length
-		decoded, err := base64.StdEncoding.DecodeString(secret1)
+		err = database.UpdateRegistrationSettings(&models.RegistrationSettings{
+			OpenRegistration: true,
+			DefaultRole:      models.RoleViewer,
+		})
 		if err != nil {
-			t.Errorf("secret is not valid base64: %v", err)
-		}
-		if len(decoded) < 32 {
-			t.Errorf("secret length = %d, want >= 32", len(decoded))
+			t.Fatalf("failed to update registration settings: %v", err)
 		}
 
-		// Second call should return the same secret
-		secret2, err := database.EnsureJWTSecret()
+		settings, err = database.GetRegistrationSettings()
 		if err != nil {
-			t.Fatalf("failed to get existing JWT secret: %v", err)
+			t.Fatalf("failed to get registration settings: %v", err)
 		}
-
-		if secret2 != secret1 {
-			t.Errorf("got different secret on second call")
+		if !settings.OpenRegistration || settings.DefaultRole != models.RoleViewer || settings.RequireEmailVerification {
+			t.Errorf("settings = %+v, want open registration with viewer role without email verification", settings)
 		}
 	})
 
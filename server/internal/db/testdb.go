@@ -3,28 +3,99 @@
 package db
 
 import (
+	"crypto/rand"
 	"database/sql"
+	"encoding/hex"
+	"fmt"
 	"lemma/internal/secrets"
+	"net/url"
+	"os"
 )
 
+// TestDatabaseURLEnv names the environment variable with the URL of a PostgreSQL
+// database. If it is set, in-memory test databases are created in PostgreSQL instead of SQLite.
+const TestDatabaseURLEnv = "LEMMA_TEST_DB_URL"
+
 type TestDatabase interface {
 	Database
 	TestDB() *sql.DB
+	Dialect() string
 }
 
 func NewTestDB(dbPath string, secretsService secrets.Service) (TestDatabase, error) {
+	if baseURL := os.Getenv(TestDatabaseURLEnv); baseURL != "" && dbPath == ":memory:" {
+		return newPostgresTestDB(baseURL, secretsService)
+	}
+
 	db, err := Init(dbPath, secretsService)
 	if err != nil {
 		return nil, err
 	}
 
-	return &testDatabase{db.(*database)}, nil
+	return &testDatabase{database: db.(*database)}, nil
+}
+
+// newPostgresTestDB creates a test database in a new schema, which is dropped when the database is closed
+func newPostgresTestDB(baseURL string, secretsService secrets.Service) (TestDatabase, error) {
+	admin, err := sql.Open(postgresDriverName, baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open postgres test database: %w", err)
+	}
+
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		admin.Close()
+		return nil, fmt.Errorf("failed to generate schema name: %w", err)
+	}
+	schema := "lemma_test_" + hex.EncodeToString(suffix)
+
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		admin.Close()
+		return nil, fmt.Errorf("failed to create test schema: %w", err)
+	}
+	dropSchema := func() error {
+		defer admin.Close()
+		_, err := admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+		return err
+	}
+
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		dropSchema()
+		return nil, fmt.Errorf("failed to parse postgres test database URL: %w", err)
+	}
+	query := u.Query()
+	query.Set("search_path", schema)
+	u.RawQuery = query.Encode()
+
+	db, err := Init(u.String(), secretsService)
+	if err != nil {
+		dropSchema()
+		return nil, err
+	}
+
+	return &testDatabase{database: db.(*database), cleanup: dropSchema}, nil
 }
 
 type testDatabase struct {
 	*database
+	cleanup func() error
 }
 
 func (td *testDatabase) TestDB() *sql.DB {
 	return td.DB
 }
+
+func (td *testDatabase) Dialect() string {
+	return td.dialect.name
+}
+
+func (td *testDatabase) Close() error {
+	err := td.database.Close()
+	if td.cleanup != nil {
+		if cleanupErr := td.cleanup(); cleanupErr != nil && err == nil {
+			err = fmt.Errorf("failed to drop test schema: %w", cleanupErr)
+		}
+	}
+	return err
+}
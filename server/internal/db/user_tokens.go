@@ -0,0 +1,110 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"lemma/internal/models"
+)
+
+// CreateUserToken stores a new single-use token. Outstanding tokens of the same
+// purpose for the user are removed, so only the most recently issued token is valid.
+func (db *database) CreateUserToken(token *models.UserToken) error {
+	log := getLogger().WithGroup("user_tokens")
+
+	if token.CreatedAt.IsZero() {
+		token.CreatedAt = time.Now()
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("DELETE FROM user_tokens WHERE user_id = ? AND purpose = ?", token.UserID, token.Purpose)
+	if err != nil {
+		return fmt.Errorf("failed to delete outstanding user tokens: %w", err)
+	}
+
+	_, err = tx.Exec(`
+        INSERT INTO user_tokens (token_hash, user_id, purpose, email, expires_at, created_at)
+        VALUES (?, ?, ?, ?, ?, ?)`,
+		token.TokenHash, token.UserID, token.Purpose, token.Email, token.ExpiresAt, token.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert user token: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	log.Debug("user token created",
+		"user_id", token.UserID,
+		"purpose", token.Purpose)
+	return nil
+}
+
+// ConsumeUserToken retrieves an unexpired token by its hash and purpose and deletes it,
+// so each token can only be redeemed once
+func (db *database) ConsumeUserToken(tokenHash string, purpose models.UserTokenPurpose) (*models.UserToken, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	token := &models.UserToken{}
+	err = tx.QueryRow(`
+        SELECT token_hash, user_id, purpose, email, expires_at, created_at
+        FROM user_tokens
+        WHERE token_hash = ? AND purpose = ? AND expires_at > ?`,
+		tokenHash, purpose, time.Now(),
+	).Scan(&token.TokenHash, &token.UserID, &token.Purpose, &token.Email, &token.ExpiresAt, &token.CreatedAt)
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("user token not found or expired")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch user token: %w", err)
+	}
+
+	// A concurrent redemption may have consumed the token in the meantime
+	result, err := tx.Exec("DELETE FROM user_tokens WHERE token_hash = ?", tokenHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete user token: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return nil, fmt.Errorf("user token not found or expired")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return token, nil
+}
+
+// CleanExpiredUserTokens removes all expired user tokens from the database
+func (db *database) CleanExpiredUserTokens() error {
+	log := getLogger().WithGroup("user_tokens")
+	result, err := db.Exec("DELETE FROM user_tokens WHERE expires_at <= ?", time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to clean expired user tokens: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	log.Info("cleaned expired user tokens", "tokens_removed", rowsAffected)
+	return nil
+}
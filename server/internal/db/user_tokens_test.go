@@ -0,0 +1,114 @@
+package db_test
+
+import (
+	"testing"
+	"time"
+
+	"lemma/internal/db"
+	"lemma/internal/models"
+	_ "lemma/internal/testenv"
+)
+
+func TestUserTokenOperations(t *testing.T) {
+	database, err := db.NewTestDB(":memory:", &mockSecrets{})
+	if err != nil {
+		t.Fatalf("failed to create test database: %v", err)
+	}
+	defer database.Close()
+
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	user, err := database.CreateUser(&models.User{
+		Email:        "test@example.com",
+		DisplayName:  "Test User",
+		PasswordHash: "hash",
+		Role:         "editor",
+	})
+	if err != nil {
+		t.Fatalf("failed to create test user: %v", err)
+	}
+
+	newToken := func(hash string, purpose models.UserTokenPurpose, expiresAt time.Time) *models.UserToken {
+		return &models.UserToken{
+			TokenHash: hash,
+			UserID:    user.ID,
+			Purpose:   purpose,
+			ExpiresAt: expiresAt,
+		}
+	}
+
+	t.Run("ConsumeUserToken is single-use", func(t *testing.T) {
+		token := newToken("reset-hash", models.UserTokenPurposePasswordReset, time.Now().Add(time.Hour))
+		if err := database.CreateUserToken(token); err != nil {
+			t.Fatalf("failed to create user token: %v", err)
+		}
+
+		got, err := database.ConsumeUserToken("reset-hash", models.UserTokenPurposePasswordReset)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got.UserID != user.ID || got.Purpose != models.UserTokenPurposePasswordReset {
+			t.Errorf("token = %+v, want user %d and purpose password_reset", got, user.ID)
+		}
+
+		if _, err := database.ConsumeUserToken("reset-hash", models.UserTokenPurposePasswordReset); err == nil {
+			t.Error("expected error when consuming a token twice")
+		}
+	})
+
+	t.Run("ConsumeUserToken checks purpose and expiry", func(t *testing.T) {
+		if err := database.CreateUserToken(newToken("email-hash", models.UserTokenPurposeEmailChange, time.Now().Add(time.Hour))); err != nil {
+			t.Fatalf("failed to create user token: %v", err)
+		}
+		if _, err := database.ConsumeUserToken("email-hash", models.UserTokenPurposePasswordReset); err == nil {
+			t.Error("expected error when consuming a token for another purpose")
+		}
+
+		if err := database.CreateUserToken(newToken("expired-hash", models.UserTokenPurposePasswordReset, time.Now().Add(-time.Minute))); err != nil {
+			t.Fatalf("failed to create user token: %v", err)
+		}
+		if _, err := database.ConsumeUserToken("expired-hash", models.UserTokenPurposePasswordReset); err == nil {
+			t.Error("expected error when consuming an expired token")
+		}
+	})
+
+	t.Run("CreateUserToken replaces outstanding tokens", func(t *testing.T) {
+		if err := database.CreateUserToken(newToken("first-hash", models.UserTokenPurposePasswordReset, time.Now().Add(time.Hour))); err != nil {
+			t.Fatalf("failed to create user token: %v", err)
+		}
+		if err := database.CreateUserToken(newToken("second-hash", models.UserTokenPurposePasswordReset, time.Now().Add(time.Hour))); err != nil {
+			t.Fatalf("failed to create user token: %v", err)
+		}
+
+		if _, err := database.ConsumeUserToken("first-hash", models.UserTokenPurposePasswordReset); err == nil {
+			t.Error("expected superseded token to be invalid")
+		}
+		if _, err := database.ConsumeUserToken("second-hash", models.UserTokenPurposePasswordReset); err != nil {
+			t.Errorf("unexpected error consuming latest token: %v", err)
+		}
+
+		// Tokens of other purposes are kept
+		if _, err := database.ConsumeUserToken("email-hash", models.UserTokenPurposeEmailChange); err != nil {
+			t.Errorf("unexpected error consuming email change token: %v", err)
+		}
+	})
+
+	t.Run("CleanExpiredUserTokens", func(t *testing.T) {
+		if err := database.CreateUserToken(newToken("valid-hash", models.UserTokenPurposeEmailChange, time.Now().Add(time.Hour))); err != nil {
+			t.Fatalf("failed to create user token: %v", err)
+		}
+		if err := database.CleanExpiredUserTokens(); err != nil {
+			t.Fatalf("failed to clean expired user tokens: %v", err)
+		}
+
+		var count int
+		if err := database.TestDB().QueryRow("SELECT COUNT(*) FROM user_tokens").Scan(&count); err != nil {
+			t.Fatalf("failed to count user tokens: %v", err)
+		}
+		if count != 1 {
+			t.Errorf("user token count = %d, want 1", count)
+		}
+	})
+}
@@ -4,12 +4,18 @@ import (
 	"database/sql"
 	"fmt"
 	"lemma/internal/models"
+	"time"
 )
 
 // CreateUser inserts a new user record into the database
 func (db *database) CreateUser(user *models.User) (*models.User, error) {
+	return db.CreateUserWithWorkspace(user, models.DefaultWorkspaceName)
+}
+
+// CreateUserWithWorkspace inserts a new user record with an initial workspace of the given name
+func (db *database) CreateUserWithWorkspace(user *models.User, workspaceName string) (*models.User, error) {
 	log := getLogger().WithGroup("users")
-	log.Debug("creating user", "email", user.Email)
+	log.Debug("creating user", "email", user.Email, "workspace_name", workspaceName)
 
 	tx, err := db.Begin()
 	if err != nil {
@@ -17,82 +23,87 @@ func (db *database) CreateUser(user *models.User) (*models.User, error) {
 	}
 	defer tx.Rollback()
 
-	result, err := tx.Exec(`
-        INSERT INTO users (email, display_name, password_hash, role)
-        VALUES (?, ?, ?, ?)`,
-		user.Email, user.DisplayName, user.PasswordHash, user.Role)
-	if err != nil {
-		return nil, fmt.Errorf("failed to insert user: %w", err)
+	if err := db.createUserTx(tx, user, workspaceName); err != nil {
+		return nil, err
 	}
 
-	userID, err := result.LastInsertId()
+	err = tx.Commit()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get last insert ID: %w", err)
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
-	user.ID = int(userID)
 
-	// Retrieve the created_at timestamp
-	err = tx.QueryRow("SELECT created_at FROM users WHERE id = ?", user.ID).Scan(&user.CreatedAt)
+	log.Debug("created user", "user_id", user.ID)
+	return user, nil
+}
+
+// createUserTx inserts a user with an initial workspace in a transaction
+func (db *database) createUserTx(tx *sql.Tx, user *models.User, workspaceName string) error {
+	if user.AuthSource == "" {
+		user.AuthSource = models.AuthSourceLocal
+	}
+
+	err := tx.QueryRow(`
+        INSERT INTO users (
+            email, display_name, password_hash, role, must_change_password, auth_source,
+            email_verification_pending
+        )
+        VALUES (?, ?, ?, ?, ?, ?, ?)
+        RETURNING id, created_at`,
+		user.Email, user.DisplayName, user.PasswordHash, user.Role, user.MustChangePassword, user.AuthSource,
+		user.EmailVerificationPending).
+		Scan(&user.ID, &user.CreatedAt)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get created timestamp: %w", err)
+		return fmt.Errorf("failed to insert user: %w", err)
 	}
 
 	// Create default workspace with default settings
 	defaultWorkspace := &models.Workspace{
 		UserID: user.ID,
-		Name:   "Main",
+		Name:   workspaceName,
 	}
 	defaultWorkspace.SetDefaultSettings()
 
 	// Create workspace with settings
 	err = db.createWorkspaceTx(tx, defaultWorkspace)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create default workspace: %w", err)
+		return fmt.Errorf("failed to create default workspace: %w", err)
 	}
 
 	// Update user's last workspace ID
 	_, err = tx.Exec("UPDATE users SET last_workspace_id = ? WHERE id = ?", defaultWorkspace.ID, user.ID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to update last workspace ID: %w", err)
+		return fmt.Errorf("failed to update last workspace ID: %w", err)
 	}
 
-	err = tx.Commit()
-	if err != nil {
-		return nil, fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
-	log.Debug("created user", "user_id", user.ID)
-
 	user.LastWorkspaceID = defaultWorkspace.ID
-	return user, nil
+	return nil
 }
 
 // Helper function to create a workspace in a transaction
 func (db *database) createWorkspaceTx(tx *sql.Tx, workspace *models.Workspace) error {
 	log := getLogger().WithGroup("users")
-	result, err := tx.Exec(`
+	err := tx.QueryRow(`
         INSERT INTO workspaces (
             user_id, name,
             theme, auto_save, show_hidden_files,
             git_enabled, git_url, git_user, git_token,
             git_auto_commit, git_commit_msg_template,
             git_commit_name, git_commit_email
-        ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+        ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+        RETURNING id`,
 		workspace.UserID, workspace.Name,
 		workspace.Theme, workspace.AutoSave, workspace.ShowHiddenFiles,
 		workspace.GitEnabled, workspace.GitURL, workspace.GitUser, workspace.GitToken,
 		workspace.GitAutoCommit, workspace.GitCommitMsgTemplate,
 		workspace.GitCommitName, workspace.GitCommitEmail,
-	)
+	).Scan(&workspace.ID)
 	if err != nil {
 		return fmt.Errorf("failed to insert workspace: %w", err)
 	}
 
-	id, err := result.LastInsertId()
-	if err != nil {
-		return fmt.Errorf("failed to get workspace ID: %w", err)
+	if err := createOwnerMemberTx(tx, workspace); err != nil {
+		return err
 	}
-	workspace.ID = int(id)
 
 	log.Debug("created user workspace",
 		"workspace_id", workspace.ID,
@@ -105,11 +116,14 @@ func (db *database) GetUserByID(id int) (*models.User, error) {
 	err := db.QueryRow(`
         SELECT 
             id, email, display_name, password_hash, role, created_at, 
-            last_workspace_id
+            last_workspace_id, must_change_password, suspended_at, auth_source,
+            email_verification_pending
         FROM users
-        WHERE id = ?`, id).
+        WHERE id = ? AND deleted_at IS NULL`, id).
 		Scan(&user.ID, &user.Email, &user.DisplayName, &user.PasswordHash,
-			&user.Role, &user.CreatedAt, &user.LastWorkspaceID)
+			&user.Role, &user.CreatedAt, &user.LastWorkspaceID,
+			&user.MustChangePassword, &user.SuspendedAt, &user.AuthSource,
+			&user.EmailVerificationPending)
 
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("user not found")
@@ -125,11 +139,14 @@ func (db *database) GetUserByEmail(email string) (*models.User, error) {
 	err := db.QueryRow(`
         SELECT 
             id, email, display_name, password_hash, role, created_at, 
-            last_workspace_id
+            last_workspace_id, must_change_password, suspended_at, auth_source,
+            email_verification_pending
         FROM users
-        WHERE email = ?`, email).
+        WHERE email = ? AND deleted_at IS NULL`, email).
 		Scan(&user.ID, &user.Email, &user.DisplayName, &user.PasswordHash,
-			&user.Role, &user.CreatedAt, &user.LastWorkspaceID)
+			&user.Role, &user.CreatedAt, &user.LastWorkspaceID,
+			&user.MustChangePassword, &user.SuspendedAt, &user.AuthSource,
+			&user.EmailVerificationPending)
 
 	if err == sql.ErrNoRows {
 		return nil, fmt.Errorf("user not found")
@@ -144,10 +161,11 @@ func (db *database) GetUserByEmail(email string) (*models.User, error) {
 func (db *database) UpdateUser(user *models.User) error {
 	result, err := db.Exec(`
         UPDATE users
-        SET email = ?, display_name = ?, password_hash = ?, role = ?, last_workspace_id = ?
-        WHERE id = ?`,
+        SET email = ?, display_name = ?, password_hash = ?, role = ?, last_workspace_id = ?,
+            must_change_password = ?, suspended_at = ?, email_verification_pending = ?
+        WHERE id = ? AND deleted_at IS NULL`,
 		user.Email, user.DisplayName, user.PasswordHash, user.Role,
-		user.LastWorkspaceID, user.ID)
+		user.LastWorkspaceID, user.MustChangePassword, user.SuspendedAt, user.EmailVerificationPending, user.ID)
 
 	if err != nil {
 		return fmt.Errorf("failed to update user: %w", err)
@@ -169,8 +187,10 @@ func (db *database) GetAllUsers() ([]*models.User, error) {
 	rows, err := db.Query(`
         SELECT 
             id, email, display_name, role, created_at,
-            last_workspace_id
+            last_workspace_id, must_change_password, suspended_at, auth_source,
+            email_verification_pending
         FROM users
+        WHERE deleted_at IS NULL
         ORDER BY id ASC`)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query users: %w", err)
@@ -183,6 +203,8 @@ func (db *database) GetAllUsers() ([]*models.User, error) {
 		err := rows.Scan(
 			&user.ID, &user.Email, &user.DisplayName, &user.Role,
 			&user.CreatedAt, &user.LastWorkspaceID,
+			&user.MustChangePassword, &user.SuspendedAt, &user.AuthSource,
+			&user.EmailVerificationPending,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan user row: %w", err)
@@ -200,8 +222,14 @@ func (db *database) UpdateLastWorkspace(userID int, workspaceName string) error
 	}
 	defer tx.Rollback()
 
+	// Owned workspaces take precedence over shared workspaces with the same name
 	var workspaceID int
-	err = tx.QueryRow("SELECT id FROM workspaces WHERE user_id = ? AND name = ?",
+	err = tx.QueryRow(`
+        SELECT w.id FROM workspaces w
+        JOIN workspace_members m ON m.workspace_id = w.id
+        WHERE m.user_id = ? AND w.name = ? AND w.deleted_at IS NULL
+        ORDER BY m.role = 'owner' DESC, w.id
+        LIMIT 1`,
 		userID, workspaceName).Scan(&workspaceID)
 	if err != nil {
 		return fmt.Errorf("failed to find workspace: %w", err)
@@ -221,6 +249,8 @@ func (db *database) UpdateLastWorkspace(userID int, workspaceName string) error
 	return nil
 }
 
+// DeleteUser moves a user and the workspaces they own to the trash. The user's sessions are
+// deleted, and members whose last workspace belongs to the user are moved to their own workspaces.
 func (db *database) DeleteUser(id int) error {
 	log := getLogger().WithGroup("users")
 	log.Debug("deleting user", "user_id", id)
@@ -231,25 +261,228 @@ func (db *database) DeleteUser(id int) error {
 	}
 	defer tx.Rollback()
 
-	// Delete all user's workspaces first
+	// The user and the workspaces share the deletion time, so they can be restored together
+	deletedAt := time.Now().UTC().Truncate(time.Second)
+
+	result, err := tx.Exec("UPDATE users SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL", deletedAt, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	_, err = tx.Exec("UPDATE workspaces SET deleted_at = ? WHERE user_id = ? AND deleted_at IS NULL", deletedAt, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete workspaces: %w", err)
+	}
+
+	// Move members of the user's workspaces whose last workspace is one of them to their own workspaces
+	_, err = tx.Exec(`
+        UPDATE users
+        SET last_workspace_id = (
+            SELECT MIN(w.id) FROM workspaces w WHERE w.user_id = users.id AND w.deleted_at IS NULL
+        )
+        WHERE id != ? AND last_workspace_id IN (SELECT id FROM workspaces WHERE user_id = ?)`,
+		id, id)
+	if err != nil {
+		return fmt.Errorf("failed to reassign last workspaces: %w", err)
+	}
+
+	// Deleted users are logged out
+	_, err = tx.Exec("DELETE FROM sessions WHERE user_id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete sessions: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	log.Debug("deleted user", "user_id", id)
+	return nil
+}
+
+// GetDeletedUsers retrieves all users in the trash, most recently deleted first
+func (db *database) GetDeletedUsers() ([]*models.User, error) {
+	rows, err := db.Query(`
+        SELECT
+            id, email, display_name, role, created_at,
+            last_workspace_id, must_change_password, suspended_at, auth_source,
+            email_verification_pending, deleted_at
+        FROM users
+        WHERE deleted_at IS NOT NULL
+        ORDER BY deleted_at DESC, id DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query deleted users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []*models.User{}
+	for rows.Next() {
+		user := &models.User{}
+		var deletedAt time.Time
+		err := rows.Scan(
+			&user.ID, &user.Email, &user.DisplayName, &user.Role,
+			&user.CreatedAt, &user.LastWorkspaceID,
+			&user.MustChangePassword, &user.SuspendedAt, &user.AuthSource,
+			&user.EmailVerificationPending, &deletedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user row: %w", err)
+		}
+		user.DeletedAt = &deletedAt
+		users = append(users, user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating user rows: %w", err)
+	}
+
+	return users, nil
+}
+
+// RestoreUser restores a user from the trash together with the workspaces deleted with them
+func (db *database) RestoreUser(id int) error {
+	log := getLogger().WithGroup("users")
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+        UPDATE workspaces SET deleted_at = NULL
+        WHERE user_id = ? AND deleted_at = (SELECT deleted_at FROM users WHERE id = ?)`,
+		id, id)
+	if err != nil {
+		return fmt.Errorf("failed to restore workspaces: %w", err)
+	}
+
+	result, err := tx.Exec("UPDATE users SET deleted_at = NULL WHERE id = ? AND deleted_at IS NOT NULL", id)
+	if err != nil {
+		return fmt.Errorf("failed to restore user: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("deleted user not found")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	log.Debug("restored user", "user_id", id)
+	return nil
+}
+
+// PurgeUser permanently deletes a user in the trash with all their workspaces
+func (db *database) PurgeUser(id int) error {
+	log := getLogger().WithGroup("users")
+	log.Debug("purging user", "user_id", id)
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Move members of the user's workspaces whose last workspace is one of them to their own workspaces
+	_, err = tx.Exec(`
+        UPDATE users
+        SET last_workspace_id = (
+            SELECT MIN(w.id) FROM workspaces w WHERE w.user_id = users.id AND w.deleted_at IS NULL
+        )
+        WHERE id != ? AND last_workspace_id IN (SELECT id FROM workspaces WHERE user_id = ?)`,
+		id, id)
+	if err != nil {
+		return fmt.Errorf("failed to reassign last workspaces: %w", err)
+	}
+
+	// Delete the user's memberships and the members of the user's workspaces
+	_, err = tx.Exec(`
+        DELETE FROM workspace_members
+        WHERE user_id = ? OR workspace_id IN (SELECT id FROM workspaces WHERE user_id = ?)`,
+		id, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete workspace members: %w", err)
+	}
+
+	// Revoke the share links of the user's workspaces and the ones the user created
+	_, err = tx.Exec(`
+        DELETE FROM share_links
+        WHERE created_by = ? OR workspace_id IN (SELECT id FROM workspaces WHERE user_id = ?)`,
+		id, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete share links: %w", err)
+	}
+
+	// Delete the storage quotas and usage of the user's workspaces
+	_, err = tx.Exec(`
+        DELETE FROM workspace_quotas
+        WHERE workspace_id IN (SELECT id FROM workspaces WHERE user_id = ?)`,
+		id)
+	if err != nil {
+		return fmt.Errorf("failed to delete workspace quotas: %w", err)
+	}
+
+	_, err = tx.Exec(`
+        DELETE FROM workspace_usage
+        WHERE workspace_id IN (SELECT id FROM workspaces WHERE user_id = ?)`,
+		id)
+	if err != nil {
+		return fmt.Errorf("failed to delete workspace usage: %w", err)
+	}
+
+	// Delete all user's workspaces
 	log.Debug("deleting user workspaces", "user_id", id)
 	_, err = tx.Exec("DELETE FROM workspaces WHERE user_id = ?", id)
 	if err != nil {
 		return fmt.Errorf("failed to delete workspaces: %w", err)
 	}
 
+	// Delete all user's sessions
+	_, err = tx.Exec("DELETE FROM sessions WHERE user_id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete sessions: %w", err)
+	}
+
+	// Delete the user's storage quota
+	_, err = tx.Exec("DELETE FROM user_quotas WHERE user_id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete storage quota: %w", err)
+	}
+
 	// Delete the user
-	_, err = tx.Exec("DELETE FROM users WHERE id = ?", id)
+	result, err := tx.Exec("DELETE FROM users WHERE id = ? AND deleted_at IS NOT NULL", id)
 	if err != nil {
 		return fmt.Errorf("failed to delete user: %w", err)
 	}
 
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("deleted user not found")
+	}
+
 	err = tx.Commit()
 	if err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	log.Debug("deleted user", "user_id", id)
+	log.Debug("purged user", "user_id", id)
 	return nil
 }
 
@@ -260,7 +493,7 @@ func (db *database) GetLastWorkspaceName(userID int) (string, error) {
             w.name
         FROM workspaces w
         JOIN users u ON u.last_workspace_id = w.id
-        WHERE u.id = ?`, userID).
+        WHERE u.id = ? AND w.deleted_at IS NULL`, userID).
 		Scan(&workspaceName)
 
 	if err == sql.ErrNoRows {
@@ -273,10 +506,23 @@ func (db *database) GetLastWorkspaceName(userID int) (string, error) {
 	return workspaceName, nil
 }
 
-// CountAdminUsers returns the number of admin users in the system
+// EmailExists reports whether a user has the email, including users in the trash
+func (db *database) EmailExists(email string) (bool, error) {
+	var exists bool
+	err := db.QueryRow("SELECT EXISTS (SELECT 1 FROM users WHERE email = ?)", email).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check email: %w", err)
+	}
+
+	return exists, nil
+}
+
+// CountAdminUsers returns the number of admin users in the system that aren't suspended
 func (db *database) CountAdminUsers() (int, error) {
 	var count int
-	err := db.QueryRow("SELECT COUNT(*) FROM users WHERE role = 'admin'").Scan(&count)
+	err := db.QueryRow(
+		"SELECT COUNT(*) FROM users WHERE role = 'admin' AND deleted_at IS NULL AND suspended_at IS NULL",
+	).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("failed to count admin users: %w", err)
 	}
@@ -3,6 +3,7 @@ package db_test
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"lemma/internal/db"
 	"lemma/internal/models"
@@ -241,6 +242,69 @@ func TestUserOperations(t *testing.T) {
 		}
 	})
 
+	t.Run("Suspension and required password change", func(t *testing.T) {
+		user, err := database.CreateUser(&models.User{
+			Email:              "suspend@example.com",
+			PasswordHash:       "hash",
+			Role:               models.RoleAdmin,
+			MustChangePassword: true,
+		})
+		if err != nil {
+			t.Fatalf("failed to create test user: %v", err)
+		}
+
+		created, err := database.GetUserByEmail(user.Email)
+		if err != nil {
+			t.Fatalf("failed to get created user: %v", err)
+		}
+		if !created.MustChangePassword || created.Suspended() {
+			t.Errorf("created user: MustChangePassword = %v, Suspended = %v, want true, false",
+				created.MustChangePassword, created.Suspended())
+		}
+
+		adminsBefore, err := database.CountAdminUsers()
+		if err != nil {
+			t.Fatalf("failed to count admin users: %v", err)
+		}
+
+		suspendedAt := time.Now().UTC().Truncate(time.Second)
+		created.SuspendedAt = &suspendedAt
+		created.MustChangePassword = false
+		if err := database.UpdateUser(created); err != nil {
+			t.Fatalf("failed to update user: %v", err)
+		}
+
+		updated, err := database.GetUserByID(user.ID)
+		if err != nil {
+			t.Fatalf("failed to get updated user: %v", err)
+		}
+		if updated.MustChangePassword {
+			t.Error("MustChangePassword = true, want false")
+		}
+		if updated.SuspendedAt == nil || !updated.SuspendedAt.Equal(suspendedAt) {
+			t.Errorf("SuspendedAt = %v, want %v", updated.SuspendedAt, suspendedAt)
+		}
+
+		users, err := database.GetAllUsers()
+		if err != nil {
+			t.Fatalf("failed to get all users: %v", err)
+		}
+		for _, u := range users {
+			if u.ID == user.ID && !u.Suspended() {
+				t.Error("GetAllUsers() returned the user as not suspended")
+			}
+		}
+
+		// Suspended admins don't count as admins
+		adminsAfter, err := database.CountAdminUsers()
+		if err != nil {
+			t.Fatalf("failed to count admin users: %v", err)
+		}
+		if adminsAfter != adminsBefore-1 {
+			t.Errorf("CountAdminUsers() = %d after suspending an admin, want %d", adminsAfter, adminsBefore-1)
+		}
+	})
+
 	t.Run("GetAllUsers", func(t *testing.T) {
 		// Create several test users
 		testUsers := []*models.User{
@@ -369,6 +433,64 @@ func TestUserOperations(t *testing.T) {
 		}
 	})
 
+	t.Run("RestoreUser and PurgeUser", func(t *testing.T) {
+		user, err := database.CreateUser(&models.User{
+			Email:        "trash@example.com",
+			PasswordHash: "hash",
+			Role:         models.RoleEditor,
+		})
+		if err != nil {
+			t.Fatalf("failed to create test user: %v", err)
+		}
+		if err := database.DeleteUser(user.ID); err != nil {
+			t.Fatalf("failed to delete user: %v", err)
+		}
+
+		// The email of a deleted user stays taken until the user is purged
+		if _, err := database.GetUserByEmail(user.Email); err == nil {
+			t.Error("expected error getting deleted user by email, got nil")
+		}
+		if exists, err := database.EmailExists(user.Email); err != nil || !exists {
+			t.Errorf("EmailExists() = %v, %v, want true", exists, err)
+		}
+
+		deleted, err := database.GetDeletedUsers()
+		if err != nil {
+			t.Fatalf("failed to get deleted users: %v", err)
+		}
+		if len(deleted) == 0 || deleted[0].ID != user.ID || deleted[0].DeletedAt == nil {
+			t.Fatalf("GetDeletedUsers() = %+v, want user %d first", deleted, user.ID)
+		}
+
+		if err := database.RestoreUser(user.ID); err != nil {
+			t.Fatalf("failed to restore user: %v", err)
+		}
+		if _, err := database.GetUserByID(user.ID); err != nil {
+			t.Errorf("restored user not found: %v", err)
+		}
+		workspaces, err := database.GetWorkspacesByUserID(user.ID)
+		if err != nil || len(workspaces) != 1 {
+			t.Errorf("GetWorkspacesByUserID() = %d workspaces, %v, want the restored default workspace", len(workspaces), err)
+		}
+
+		if err := database.PurgeUser(user.ID); err == nil {
+			t.Error("expected error purging a user that isn't deleted, got nil")
+		}
+
+		if err := database.DeleteUser(user.ID); err != nil {
+			t.Fatalf("failed to delete user: %v", err)
+		}
+		if err := database.PurgeUser(user.ID); err != nil {
+			t.Fatalf("failed to purge user: %v", err)
+		}
+		if exists, err := database.EmailExists(user.Email); err != nil || exists {
+			t.Errorf("EmailExists() = %v, %v after purge, want false", exists, err)
+		}
+		if err := database.RestoreUser(user.ID); err == nil {
+			t.Error("expected error restoring a purged user, got nil")
+		}
+	})
+
 	t.Run("CountAdminUsers", func(t *testing.T) {
 		// Create users with different roles
 		testUsers := []*models.User{
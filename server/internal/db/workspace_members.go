@@ -0,0 +1,451 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"lemma/internal/models"
+	"strings"
+)
+
+// memberWorkspaceQuery selects workspaces together with the role of a member
+const memberWorkspaceQuery = `
+        SELECT
+            w.id, w.user_id, w.name, w.created_at,
+            w.theme, w.auto_save, w.show_hidden_files,
+            w.git_enabled, w.git_url, w.git_user, w.git_token,
+            w.git_auto_commit, w.git_commit_msg_template,
+            w.git_commit_name, w.git_commit_email,
+            m.role
+        FROM workspaces w
+        JOIN workspace_members m ON m.workspace_id = w.id
+        WHERE w.deleted_at IS NULL`
+
+// GetMemberWorkspaceByName retrieves a workspace the user is a member of by its name.
+// If the user owns a workspace with the name, it takes precedence over shared workspaces.
+func (db *database) GetMemberWorkspaceByName(userID int, workspaceName string) (*models.Workspace, error) {
+	row := db.QueryRow(memberWorkspaceQuery+`
+        AND m.user_id = ? AND w.name = ?
+        ORDER BY m.role = 'owner' DESC, w.id
+        LIMIT 1`,
+		userID, workspaceName,
+	)
+
+	workspace, err := db.scanMemberWorkspace(row)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("workspace not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return workspace, nil
+}
+
+// GetWorkspacesByMemberID retrieves all workspaces the user is a member of, owned workspaces first
+func (db *database) GetWorkspacesByMemberID(userID int) ([]*models.Workspace, error) {
+	rows, err := db.Query(memberWorkspaceQuery+`
+        AND m.user_id = ?
+        ORDER BY m.role = 'owner' DESC, w.id`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query workspaces: %w", err)
+	}
+	defer rows.Close()
+
+	var workspaces []*models.Workspace
+	for rows.Next() {
+		workspace, err := db.scanMemberWorkspace(rows)
+		if err != nil {
+			return nil, err
+		}
+		workspaces = append(workspaces, workspace)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating workspace rows: %w", err)
+	}
+
+	return workspaces, nil
+}
+
+func (db *database) scanMemberWorkspace(row rowScanner) (*models.Workspace, error) {
+	workspace := &models.Workspace{}
+	var encryptedToken string
+
+	err := row.Scan(
+		&workspace.ID, &workspace.UserID, &workspace.Name, &workspace.CreatedAt,
+		&workspace.Theme, &workspace.AutoSave, &workspace.ShowHiddenFiles,
+		&workspace.GitEnabled, &workspace.GitURL, &workspace.GitUser, &encryptedToken,
+		&workspace.GitAutoCommit, &workspace.GitCommitMsgTemplate,
+		&workspace.GitCommitName, &workspace.GitCommitEmail,
+		&workspace.Role,
+	)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan workspace row: %w", err)
+	}
+
+	workspace.GitToken, err = db.decryptToken(encryptedToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt token: %w", err)
+	}
+
+	return workspace, nil
+}
+
+// CreateWorkspaceMember gives a user access to a workspace
+func (db *database) CreateWorkspaceMember(member *models.WorkspaceMember) error {
+	log := getLogger().WithGroup("workspace_members")
+
+	_, err := db.Exec(`
+        INSERT INTO workspace_members (workspace_id, user_id, role)
+        VALUES (?, ?, ?)`,
+		member.WorkspaceID, member.UserID, member.Role,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert workspace member: %w", err)
+	}
+
+	err = db.QueryRow(`
+        SELECT u.email, COALESCE(u.display_name, ''), m.created_at
+        FROM workspace_members m
+        JOIN users u ON u.id = m.user_id
+        WHERE m.workspace_id = ? AND m.user_id = ?`,
+		member.WorkspaceID, member.UserID,
+	).Scan(&member.Email, &member.DisplayName, &member.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to fetch created workspace member: %w", err)
+	}
+
+	log.Debug("workspace member created",
+		"workspace_id", member.WorkspaceID,
+		"user_id", member.UserID,
+		"role", member.Role)
+	return nil
+}
+
+// GetWorkspaceMember retrieves a user's membership of a workspace
+func (db *database) GetWorkspaceMember(workspaceID, userID int) (*models.WorkspaceMember, error) {
+	member := &models.WorkspaceMember{}
+	err := db.QueryRow(`
+        SELECT m.workspace_id, m.user_id, u.email, COALESCE(u.display_name, ''), m.role, m.created_at
+        FROM workspace_members m
+        JOIN users u ON u.id = m.user_id
+        JOIN workspaces w ON w.id = m.workspace_id
+        WHERE m.workspace_id = ? AND m.user_id = ? AND u.deleted_at IS NULL AND w.deleted_at IS NULL`,
+		workspaceID, userID,
+	).Scan(&member.WorkspaceID, &member.UserID, &member.Email, &member.DisplayName, &member.Role, &member.CreatedAt)
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("workspace member not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch workspace member: %w", err)
+	}
+
+	return member, nil
+}
+
+// GetWorkspaceMembers retrieves all members of a workspace, the owner first
+func (db *database) GetWorkspaceMembers(workspaceID int) ([]*models.WorkspaceMember, error) {
+	rows, err := db.Query(`
+        SELECT m.workspace_id, m.user_id, u.email, COALESCE(u.display_name, ''), m.role, m.created_at
+        FROM workspace_members m
+        JOIN users u ON u.id = m.user_id
+        JOIN workspaces w ON w.id = m.workspace_id
+        WHERE m.workspace_id = ? AND u.deleted_at IS NULL AND w.deleted_at IS NULL
+        ORDER BY m.role = 'owner' DESC, m.created_at, m.user_id`,
+		workspaceID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query workspace members: %w", err)
+	}
+	defer rows.Close()
+
+	members := []*models.WorkspaceMember{}
+	for rows.Next() {
+		member := &models.WorkspaceMember{}
+		err := rows.Scan(&member.WorkspaceID, &member.UserID, &member.Email, &member.DisplayName, &member.Role, &member.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan workspace member row: %w", err)
+		}
+		members = append(members, member)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating workspace member rows: %w", err)
+	}
+
+	return members, nil
+}
+
+// UpdateWorkspaceMember changes the role of a member. The owner's role can't be changed.
+func (db *database) UpdateWorkspaceMember(member *models.WorkspaceMember) error {
+	result, err := db.Exec(`
+        UPDATE workspace_members SET role = ?
+        WHERE workspace_id = ? AND user_id = ? AND role != 'owner'`,
+		member.Role, member.WorkspaceID, member.UserID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update workspace member: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("workspace member not found")
+	}
+
+	return nil
+}
+
+// DeleteWorkspaceMember removes a user's access to a workspace. The owner can't be removed.
+// If it was the user's last opened workspace, the first workspace the user owns becomes the last one.
+func (db *database) DeleteWorkspaceMember(workspaceID, userID int) error {
+	log := getLogger().WithGroup("workspace_members")
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`
+        DELETE FROM workspace_members
+        WHERE workspace_id = ? AND user_id = ? AND role != 'owner'`,
+		workspaceID, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete workspace member: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("workspace member not found")
+	}
+
+	if err := reassignLastWorkspaceTx(tx, workspaceID, userID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	log.Debug("workspace member deleted",
+		"workspace_id", workspaceID,
+		"user_id", userID)
+	return nil
+}
+
+// TransferWorkspace makes newOwnerID the owner of the workspace and updates its UserID and Name.
+// If the new owner or a remaining member has access to another workspace with the same name, a number
+// is appended to the name.
+// Git is disabled and the Git credentials of the previous owner are cleared, so the new owner has
+// to set up Git with their own.
+// The previous owner stays a member with previousOwnerRole, or loses access if it is empty.
+// A previous owner that is left without a workspace gets a new default workspace, which is returned.
+func (db *database) TransferWorkspace(workspace *models.Workspace, newOwnerID int, previousOwnerRole models.WorkspaceRole) (*models.Workspace, error) {
+	log := getLogger().WithGroup("workspace_members")
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var previousOwnerID int
+	err = tx.QueryRow("SELECT user_id FROM workspaces WHERE id = ? AND deleted_at IS NULL", workspace.ID).
+		Scan(&previousOwnerID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("workspace not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch workspace owner: %w", err)
+	}
+
+	// The name must not clash with a workspace of anyone keeping access
+	memberIDs, err := workspaceMemberIDsTx(tx, workspace.ID)
+	if err != nil {
+		return nil, err
+	}
+	userIDs := []int{newOwnerID}
+	for _, userID := range memberIDs {
+		if userID != newOwnerID && (userID != previousOwnerID || previousOwnerRole != "") {
+			userIDs = append(userIDs, userID)
+		}
+	}
+
+	name, err := uniqueWorkspaceNameTx(tx, workspace.ID, userIDs, workspace.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`
+        UPDATE workspaces
+        SET user_id = ?, name = ?,
+            git_enabled = ?, git_url = '', git_user = '', git_token = '',
+            git_auto_commit = ?, git_commit_name = '', git_commit_email = ''
+        WHERE id = ?`,
+		newOwnerID, name, false, false, workspace.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update workspace owner: %w", err)
+	}
+
+	// The new owner may already be a member, which is replaced by the owner membership
+	if _, err := tx.Exec("DELETE FROM workspace_members WHERE workspace_id = ? AND user_id = ?",
+		workspace.ID, newOwnerID); err != nil {
+		return nil, fmt.Errorf("failed to delete workspace member: %w", err)
+	}
+	if _, err := tx.Exec("UPDATE workspace_members SET user_id = ? WHERE workspace_id = ? AND role = 'owner'",
+		newOwnerID, workspace.ID); err != nil {
+		return nil, fmt.Errorf("failed to update workspace owner member: %w", err)
+	}
+
+	if previousOwnerRole != "" {
+		_, err := tx.Exec(`
+            INSERT INTO workspace_members (workspace_id, user_id, role)
+            VALUES (?, ?, ?)`,
+			workspace.ID, previousOwnerID, previousOwnerRole,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert workspace member: %w", err)
+		}
+	}
+
+	var remaining int
+	if err := tx.QueryRow("SELECT COUNT(*) FROM workspaces WHERE user_id = ? AND deleted_at IS NULL",
+		previousOwnerID).Scan(&remaining); err != nil {
+		return nil, fmt.Errorf("failed to count workspaces: %w", err)
+	}
+
+	var replacement *models.Workspace
+	if remaining == 0 {
+		replacement = &models.Workspace{
+			UserID: previousOwnerID,
+			Name:   models.DefaultWorkspaceName,
+		}
+		replacement.SetDefaultSettings()
+		if err := db.createWorkspaceTx(tx, replacement); err != nil {
+			return nil, fmt.Errorf("failed to create replacement workspace: %w", err)
+		}
+	}
+
+	if previousOwnerRole == "" {
+		if err := reassignLastWorkspaceTx(tx, workspace.ID, previousOwnerID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	workspace.UserID = newOwnerID
+	workspace.Name = name
+	workspace.GitEnabled = false
+	workspace.GitURL = ""
+	workspace.GitUser = ""
+	workspace.GitToken = ""
+	workspace.GitAutoCommit = false
+	workspace.GitCommitName = ""
+	workspace.GitCommitEmail = ""
+
+	log.Debug("workspace transferred",
+		"workspace_id", workspace.ID,
+		"previous_owner_id", previousOwnerID,
+		"new_owner_id", newOwnerID)
+	return replacement, nil
+}
+
+// workspaceMemberIDsTx returns the IDs of all members of the workspace, including the owner
+func workspaceMemberIDsTx(tx *sql.Tx, workspaceID int) ([]int, error) {
+	rows, err := tx.Query("SELECT user_id FROM workspace_members WHERE workspace_id = ?", workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query workspace members: %w", err)
+	}
+	defer rows.Close()
+
+	var userIDs []int
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("failed to scan workspace member: %w", err)
+		}
+		userIDs = append(userIDs, userID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating workspace members: %w", err)
+	}
+	return userIDs, nil
+}
+
+// uniqueWorkspaceNameTx returns name, or name with a number appended if any of the users owns or
+// is a member of another workspace with that name. Workspaces are addressed by name, so a clash
+// would make one of them unreachable.
+func uniqueWorkspaceNameTx(tx *sql.Tx, workspaceID int, userIDs []int, name string) (string, error) {
+	placeholders := make([]string, len(userIDs))
+	args := []any{workspaceID}
+	for i, userID := range userIDs {
+		placeholders[i] = "?"
+		args = append(args, userID)
+	}
+
+	rows, err := tx.Query(`
+        SELECT DISTINCT w.name
+        FROM workspaces w
+        JOIN workspace_members m ON m.workspace_id = w.id
+        WHERE w.id != ? AND w.deleted_at IS NULL
+            AND m.user_id IN (`+strings.Join(placeholders, ", ")+`)`, args...)
+	if err != nil {
+		return "", fmt.Errorf("failed to query workspace names: %w", err)
+	}
+	defer rows.Close()
+
+	taken := make(map[string]bool)
+	for rows.Next() {
+		var existing string
+		if err := rows.Scan(&existing); err != nil {
+			return "", fmt.Errorf("failed to scan workspace name: %w", err)
+		}
+		taken[existing] = true
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("error iterating workspace names: %w", err)
+	}
+
+	unique := name
+	for i := 2; taken[unique]; i++ {
+		unique = fmt.Sprintf("%s (%d)", name, i)
+	}
+	return unique, nil
+}
+
+// reassignLastWorkspaceTx points users whose last workspace is the given workspace to the first
+// workspace they own. If userID is 0, all such users are updated.
+func reassignLastWorkspaceTx(tx *sql.Tx, workspaceID, userID int) error {
+	query := `
+        UPDATE users
+        SET last_workspace_id = (
+            SELECT MIN(id) FROM workspaces WHERE user_id = users.id AND id != ? AND deleted_at IS NULL
+        )
+        WHERE last_workspace_id = ?`
+	args := []any{workspaceID, workspaceID}
+	if userID != 0 {
+		query += " AND id = ?"
+		args = append(args, userID)
+	}
+
+	if _, err := tx.Exec(query, args...); err != nil {
+		return fmt.Errorf("failed to reassign last workspace: %w", err)
+	}
+	return nil
+}
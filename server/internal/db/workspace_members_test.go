@@ -0,0 +1,443 @@
+package db_test
+
+import (
+	"testing"
+
+	"lemma/internal/db"
+	"lemma/internal/models"
+	_ "lemma/internal/testenv"
+)
+
+func TestWorkspaceMemberOperations(t *testing.T) {
+	database, err := db.NewTestDB(":memory:", &mockSecrets{})
+	if err != nil {
+		t.Fatalf("failed to create test database: %v", err)
+	}
+	defer database.Close()
+
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	createUser := func(t *testing.T, email string) *models.User {
+		t.Helper()
+		user, err := database.CreateUser(&models.User{
+			Email:        email,
+			DisplayName:  email,
+			PasswordHash: "hash",
+			Role:         models.RoleEditor,
+		})
+		if err != nil {
+			t.Fatalf("failed to create test user: %v", err)
+		}
+		return user
+	}
+
+	owner := createUser(t, "owner@example.com")
+	member := createUser(t, "member@example.com")
+
+	shared := &models.Workspace{
+		UserID:     owner.ID,
+		Name:       "Shared",
+		GitEnabled: true,
+		GitURL:     "https://example.com/repo.git",
+		GitUser:    "owner",
+		GitToken:   "secret-token",
+	}
+	if err := database.CreateWorkspace(shared); err != nil {
+		t.Fatalf("failed to create workspace: %v", err)
+	}
+
+	t.Run("creating a workspace makes its user the owner", func(t *testing.T) {
+		members, err := database.GetWorkspaceMembers(shared.ID)
+		if err != nil {
+			t.Fatalf("failed to get members: %v", err)
+		}
+		if len(members) != 1 || members[0].UserID != owner.ID || members[0].Role != models.WorkspaceRoleOwner {
+			t.Fatalf("members = %+v, want only the owner", members)
+		}
+
+		// Also for the default workspace of new users
+		if _, err := database.GetWorkspaceMember(member.LastWorkspaceID, member.ID); err != nil {
+			t.Errorf("default workspace has no owner member: %v", err)
+		}
+	})
+
+	t.Run("shared workspaces can be found by members", func(t *testing.T) {
+		if _, err := database.GetMemberWorkspaceByName(member.ID, "Shared"); err == nil {
+			t.Fatal("expected workspace not to be found before sharing")
+		}
+
+		err := database.CreateWorkspaceMember(&models.WorkspaceMember{
+			WorkspaceID: shared.ID,
+			UserID:      member.ID,
+			Role:        models.WorkspaceRoleViewer,
+		})
+		if err != nil {
+			t.Fatalf("failed to create member: %v", err)
+		}
+
+		workspace, err := database.GetMemberWorkspaceByName(member.ID, "Shared")
+		if err != nil {
+			t.Fatalf("failed to get shared workspace: %v", err)
+		}
+		if workspace.ID != shared.ID || workspace.UserID != owner.ID || workspace.Role != models.WorkspaceRoleViewer {
+			t.Errorf("workspace = %+v, want shared workspace with viewer role", workspace)
+		}
+		if workspace.GitToken != "secret-token" {
+			t.Errorf("git token = %q, want decrypted token", workspace.GitToken)
+		}
+
+		workspaces, err := database.GetWorkspacesByMemberID(member.ID)
+		if err != nil {
+			t.Fatalf("failed to list workspaces: %v", err)
+		}
+		if len(workspaces) != 2 || workspaces[0].Role != models.WorkspaceRoleOwner || workspaces[1].ID != shared.ID {
+			t.Errorf("workspaces = %+v, want owned workspace followed by shared workspace", workspaces)
+		}
+
+		// Duplicate memberships are rejected
+		err = database.CreateWorkspaceMember(&models.WorkspaceMember{
+			WorkspaceID: shared.ID,
+			UserID:      member.ID,
+			Role:        models.WorkspaceRoleEditor,
+		})
+		if err == nil {
+			t.Error("expected error for duplicate member")
+		}
+	})
+
+	t.Run("owned workspaces take precedence over shared ones", func(t *testing.T) {
+		own := &models.Workspace{UserID: member.ID, Name: "Shared"}
+		if err := database.CreateWorkspace(own); err != nil {
+			t.Fatalf("failed to create workspace: %v", err)
+		}
+		defer database.DeleteWorkspace(own.ID)
+
+		workspace, err := database.GetMemberWorkspaceByName(member.ID, "Shared")
+		if err != nil {
+			t.Fatalf("failed to get workspace: %v", err)
+		}
+		if workspace.ID != own.ID || workspace.Role != models.WorkspaceRoleOwner {
+			t.Errorf("workspace = %+v, want owned workspace", workspace)
+		}
+	})
+
+	t.Run("UpdateWorkspaceMember", func(t *testing.T) {
+		err := database.UpdateWorkspaceMember(&models.WorkspaceMember{
+			WorkspaceID: shared.ID,
+			UserID:      member.ID,
+			Role:        models.WorkspaceRoleEditor,
+		})
+		if err != nil {
+			t.Fatalf("failed to update member: %v", err)
+		}
+
+		updated, err := database.GetWorkspaceMember(shared.ID, member.ID)
+		if err != nil {
+			t.Fatalf("failed to get member: %v", err)
+		}
+		if updated.Role != models.WorkspaceRoleEditor || updated.Email != member.Email {
+			t.Errorf("member = %+v, want editor role", updated)
+		}
+
+		// The owner's role can't be changed
+		err = database.UpdateWorkspaceMember(&models.WorkspaceMember{
+			WorkspaceID: shared.ID,
+			UserID:      owner.ID,
+			Role:        models.WorkspaceRoleViewer,
+		})
+		if err == nil {
+			t.Error("expected error when changing the owner's role")
+		}
+	})
+
+	t.Run("DeleteWorkspaceMember moves the member's last workspace", func(t *testing.T) {
+		if err := database.UpdateLastWorkspace(member.ID, "Shared"); err != nil {
+			t.Fatalf("failed to update last workspace: %v", err)
+		}
+
+		if err := database.DeleteWorkspaceMember(shared.ID, owner.ID); err == nil {
+			t.Error("expected error when removing the owner")
+		}
+
+		if err := database.DeleteWorkspaceMember(shared.ID, member.ID); err != nil {
+			t.Fatalf("failed to delete member: %v", err)
+		}
+		if err := database.DeleteWorkspaceMember(shared.ID, member.ID); err == nil {
+			t.Error("expected error when removing a non-member")
+		}
+
+		name, err := database.GetLastWorkspaceName(member.ID)
+		if err != nil {
+			t.Fatalf("failed to get last workspace: %v", err)
+		}
+		if name != "Main" {
+			t.Errorf("last workspace = %q, want Main", name)
+		}
+	})
+
+	t.Run("deleting a workspace hides its members", func(t *testing.T) {
+		temporary := &models.Workspace{UserID: owner.ID, Name: "Temporary"}
+		if err := database.CreateWorkspace(temporary); err != nil {
+			t.Fatalf("failed to create workspace: %v", err)
+		}
+		err := database.CreateWorkspaceMember(&models.WorkspaceMember{
+			WorkspaceID: temporary.ID,
+			UserID:      member.ID,
+			Role:        models.WorkspaceRoleEditor,
+		})
+		if err != nil {
+			t.Fatalf("failed to create member: %v", err)
+		}
+		if err := database.UpdateLastWorkspace(member.ID, "Temporary"); err != nil {
+			t.Fatalf("failed to update last workspace: %v", err)
+		}
+
+		if err := database.DeleteWorkspace(temporary.ID); err != nil {
+			t.Fatalf("failed to delete workspace: %v", err)
+		}
+
+		members, err := database.GetWorkspaceMembers(temporary.ID)
+		if err != nil {
+			t.Fatalf("failed to get members: %v", err)
+		}
+		if len(members) != 0 {
+			t.Errorf("got %d members of deleted workspace, want 0", len(members))
+		}
+
+		name, err := database.GetLastWorkspaceName(member.ID)
+		if err != nil {
+			t.Fatalf("failed to get last workspace: %v", err)
+		}
+		if name != "Main" {
+			t.Errorf("last workspace = %q, want Main", name)
+		}
+	})
+
+	t.Run("deleting the owner removes the members of their workspaces", func(t *testing.T) {
+		err := database.CreateWorkspaceMember(&models.WorkspaceMember{
+			WorkspaceID: shared.ID,
+			UserID:      member.ID,
+			Role:        models.WorkspaceRoleEditor,
+		})
+		if err != nil {
+			t.Fatalf("failed to create member: %v", err)
+		}
+		if err := database.UpdateLastWorkspace(member.ID, "Shared"); err != nil {
+			t.Fatalf("failed to update last workspace: %v", err)
+		}
+
+		if err := database.DeleteUser(owner.ID); err != nil {
+			t.Fatalf("failed to delete owner: %v", err)
+		}
+
+		workspaces, err := database.GetWorkspacesByMemberID(member.ID)
+		if err != nil {
+			t.Fatalf("failed to list workspaces: %v", err)
+		}
+		if len(workspaces) != 1 || workspaces[0].Name != "Main" {
+			t.Errorf("workspaces = %+v, want only the member's own workspace", workspaces)
+		}
+
+		name, err := database.GetLastWorkspaceName(member.ID)
+		if err != nil {
+			t.Fatalf("failed to get last workspace: %v", err)
+		}
+		if name != "Main" {
+			t.Errorf("last workspace = %q, want Main", name)
+		}
+	})
+}
+
+func TestTransferWorkspace(t *testing.T) {
+	database, err := db.NewTestDB(":memory:", &mockSecrets{})
+	if err != nil {
+		t.Fatalf("failed to create test database: %v", err)
+	}
+	defer database.Close()
+
+	if err := database.Migrate(); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	createUser := func(t *testing.T, email string) *models.User {
+		t.Helper()
+		user, err := database.CreateUser(&models.User{
+			Email:        email,
+			DisplayName:  email,
+			PasswordHash: "hash",
+			Role:         models.RoleEditor,
+		})
+		if err != nil {
+			t.Fatalf("failed to create test user: %v", err)
+		}
+		return user
+	}
+
+	getRole := func(t *testing.T, workspaceID, userID int) models.WorkspaceRole {
+		t.Helper()
+		member, err := database.GetWorkspaceMember(workspaceID, userID)
+		if err != nil {
+			return ""
+		}
+		return member.Role
+	}
+
+	previous := createUser(t, "previous@example.com")
+	colleague := createUser(t, "colleague@example.com")
+
+	t.Run("transfers to a member and keeps the previous owner", func(t *testing.T) {
+		notes := &models.Workspace{UserID: previous.ID, Name: "Notes"}
+		if err := database.CreateWorkspace(notes); err != nil {
+			t.Fatalf("failed to create workspace: %v", err)
+		}
+		err := database.CreateWorkspaceMember(&models.WorkspaceMember{
+			WorkspaceID: notes.ID,
+			UserID:      colleague.ID,
+			Role:        models.WorkspaceRoleViewer,
+		})
+		if err != nil {
+			t.Fatalf("failed to create member: %v", err)
+		}
+
+		replacement, err := database.TransferWorkspace(notes, colleague.ID, models.WorkspaceRoleEditor)
+		if err != nil {
+			t.Fatalf("failed to transfer workspace: %v", err)
+		}
+		if replacement != nil {
+			t.Errorf("got replacement workspace %+v, want none", replacement)
+		}
+		if notes.UserID != colleague.ID || notes.Name != "Notes" {
+			t.Errorf("workspace = %+v, want Notes of the colleague", notes)
+		}
+
+		if role := getRole(t, notes.ID, colleague.ID); role != models.WorkspaceRoleOwner {
+			t.Errorf("new owner role = %q, want owner", role)
+		}
+		if role := getRole(t, notes.ID, previous.ID); role != models.WorkspaceRoleEditor {
+			t.Errorf("previous owner role = %q, want editor", role)
+		}
+	})
+
+	t.Run("renames on name collisions and replaces the last workspace", func(t *testing.T) {
+		workspaces, err := database.GetWorkspacesByUserID(previous.ID)
+		if err != nil || len(workspaces) != 1 {
+			t.Fatalf("workspaces = %+v, err = %v, want only Main", workspaces, err)
+		}
+		main := workspaces[0]
+
+		replacement, err := database.TransferWorkspace(main, colleague.ID, "")
+		if err != nil {
+			t.Fatalf("failed to transfer workspace: %v", err)
+		}
+		if main.Name != "Main (2)" {
+			t.Errorf("workspace name = %q, want Main (2)", main.Name)
+		}
+		if role := getRole(t, main.ID, previous.ID); role != "" {
+			t.Errorf("previous owner role = %q, want no access", role)
+		}
+
+		if replacement == nil || replacement.UserID != previous.ID || replacement.Name != models.DefaultWorkspaceName {
+			t.Fatalf("replacement = %+v, want new Main of the previous owner", replacement)
+		}
+		user, err := database.GetUserByID(previous.ID)
+		if err != nil {
+			t.Fatalf("failed to get user: %v", err)
+		}
+		if user.LastWorkspaceID != replacement.ID {
+			t.Errorf("last workspace = %d, want %d", user.LastWorkspaceID, replacement.ID)
+		}
+	})
+
+	t.Run("renames on collisions with workspaces shared with the new owner or members", func(t *testing.T) {
+		third := createUser(t, "third@example.com")
+		addMember := func(t *testing.T, workspaceID, userID int) {
+			t.Helper()
+			err := database.CreateWorkspaceMember(&models.WorkspaceMember{
+				WorkspaceID: workspaceID,
+				UserID:      userID,
+				Role:        models.WorkspaceRoleViewer,
+			})
+			if err != nil {
+				t.Fatalf("failed to create member: %v", err)
+			}
+		}
+
+		// The new owner is a member of a workspace with the same name
+		shared := &models.Workspace{UserID: third.ID, Name: "Shared"}
+		if err := database.CreateWorkspace(shared); err != nil {
+			t.Fatalf("failed to create workspace: %v", err)
+		}
+		addMember(t, shared.ID, colleague.ID)
+
+		// A member of the transferred workspace owns one with the same name
+		docs := &models.Workspace{UserID: third.ID, Name: "Docs"}
+		if err := database.CreateWorkspace(docs); err != nil {
+			t.Fatalf("failed to create workspace: %v", err)
+		}
+
+		for _, name := range []string{"Shared", "Docs"} {
+			workspace := &models.Workspace{UserID: previous.ID, Name: name}
+			if err := database.CreateWorkspace(workspace); err != nil {
+				t.Fatalf("failed to create workspace: %v", err)
+			}
+			if name == "Docs" {
+				addMember(t, workspace.ID, third.ID)
+			}
+
+			if _, err := database.TransferWorkspace(workspace, colleague.ID, ""); err != nil {
+				t.Fatalf("failed to transfer workspace: %v", err)
+			}
+			if want := name + " (2)"; workspace.Name != want {
+				t.Errorf("workspace name = %q, want %q", workspace.Name, want)
+			}
+		}
+	})
+
+	t.Run("clears the git credentials of the previous owner", func(t *testing.T) {
+		synced := &models.Workspace{
+			UserID:         previous.ID,
+			Name:           "Synced",
+			GitEnabled:     true,
+			GitURL:         "https://example.com/repo.git",
+			GitUser:        "previous",
+			GitToken:       "secret",
+			GitAutoCommit:  true,
+			GitCommitName:  "Previous",
+			GitCommitEmail: "previous@example.com",
+		}
+		if err := database.CreateWorkspace(synced); err != nil {
+			t.Fatalf("failed to create workspace: %v", err)
+		}
+
+		if _, err := database.TransferWorkspace(synced, colleague.ID, ""); err != nil {
+			t.Fatalf("failed to transfer workspace: %v", err)
+		}
+
+		stored, err := database.GetWorkspaceByID(synced.ID)
+		if err != nil {
+			t.Fatalf("failed to get workspace: %v", err)
+		}
+		for _, workspace := range []*models.Workspace{synced, stored} {
+			if workspace.GitEnabled || workspace.GitURL != "" || workspace.GitUser != "" || workspace.GitToken != "" ||
+				workspace.GitAutoCommit || workspace.GitCommitName != "" || workspace.GitCommitEmail != "" {
+				t.Errorf("workspace = %+v, want git disabled without credentials", workspace)
+			}
+		}
+	})
+
+	t.Run("fails for deleted workspaces", func(t *testing.T) {
+		temporary := &models.Workspace{UserID: previous.ID, Name: "Temporary"}
+		if err := database.CreateWorkspace(temporary); err != nil {
+			t.Fatalf("failed to create workspace: %v", err)
+		}
+		if err := database.DeleteWorkspace(temporary.ID); err != nil {
+			t.Fatalf("failed to delete workspace: %v", err)
+		}
+
+		if _, err := database.TransferWorkspace(temporary, colleague.ID, ""); err == nil {
+			t.Error("expected error when transferring a deleted workspace")
+		}
+	})
+}
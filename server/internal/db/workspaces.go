@@ -2,10 +2,15 @@ package db
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"lemma/internal/models"
+	"time"
 )
 
+// ErrWorkspaceOwnerDeleted is returned when restoring a workspace whose owner is in the trash
+var ErrWorkspaceOwnerDeleted = errors.New("workspace owner is deleted")
+
 // CreateWorkspace inserts a new workspace record into the database
 func (db *database) CreateWorkspace(workspace *models.Workspace) error {
 	log := getLogger().WithGroup("workspaces")
@@ -25,30 +30,52 @@ func (db *database) CreateWorkspace(workspace *models.Workspace) error {
 		return fmt.Errorf("failed to encrypt token: %w", err)
 	}
 
-	result, err := db.Exec(`
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
         INSERT INTO workspaces (
             user_id, name, theme, auto_save, show_hidden_files,
             git_enabled, git_url, git_user, git_token, 
             git_auto_commit, git_commit_msg_template,
             git_commit_name, git_commit_email
-        ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+        ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+        RETURNING id`,
 		workspace.UserID, workspace.Name, workspace.Theme, workspace.AutoSave, workspace.ShowHiddenFiles,
 		workspace.GitEnabled, workspace.GitURL, workspace.GitUser, encryptedToken,
 		workspace.GitAutoCommit, workspace.GitCommitMsgTemplate, workspace.GitCommitName, workspace.GitCommitEmail,
-	)
+	).Scan(&workspace.ID)
 	if err != nil {
 		return fmt.Errorf("failed to insert workspace: %w", err)
 	}
 
-	id, err := result.LastInsertId()
-	if err != nil {
-		return fmt.Errorf("failed to get workspace ID: %w", err)
+	if err := createOwnerMemberTx(tx, workspace); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
-	workspace.ID = int(id)
 
 	return nil
 }
 
+// createOwnerMemberTx makes the workspace's user its owner
+func createOwnerMemberTx(tx *sql.Tx, workspace *models.Workspace) error {
+	_, err := tx.Exec(`
+        INSERT INTO workspace_members (workspace_id, user_id, role)
+        VALUES (?, ?, ?)`,
+		workspace.ID, workspace.UserID, models.WorkspaceRoleOwner,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert workspace owner: %w", err)
+	}
+	return nil
+}
+
 // GetWorkspaceByID retrieves a workspace by its ID
 func (db *database) GetWorkspaceByID(id int) (*models.Workspace, error) {
 	workspace := &models.Workspace{}
@@ -62,7 +89,7 @@ func (db *database) GetWorkspaceByID(id int) (*models.Workspace, error) {
             git_auto_commit, git_commit_msg_template,
             git_commit_name, git_commit_email
         FROM workspaces 
-        WHERE id = ?`,
+        WHERE id = ? AND deleted_at IS NULL`,
 		id,
 	).Scan(
 		&workspace.ID, &workspace.UserID, &workspace.Name, &workspace.CreatedAt,
@@ -101,7 +128,7 @@ func (db *database) GetWorkspaceByName(userID int, workspaceName string) (*model
             git_auto_commit, git_commit_msg_template,
             git_commit_name, git_commit_email
         FROM workspaces 
-        WHERE user_id = ? AND name = ?`,
+        WHERE user_id = ? AND name = ? AND deleted_at IS NULL`,
 		userID, workspaceName,
 	).Scan(
 		&workspace.ID, &workspace.UserID, &workspace.Name, &workspace.CreatedAt,
@@ -183,7 +210,7 @@ func (db *database) GetWorkspacesByUserID(userID int) ([]*models.Workspace, erro
             git_auto_commit, git_commit_msg_template,
             git_commit_name, git_commit_email
         FROM workspaces 
-        WHERE user_id = ?`,
+        WHERE user_id = ? AND deleted_at IS NULL`,
 		userID,
 	)
 	if err != nil {
@@ -259,37 +286,191 @@ func (db *database) UpdateWorkspaceSettings(workspace *models.Workspace) error {
 	return nil
 }
 
-// DeleteWorkspace removes a workspace record from the database
+// DeleteWorkspace moves a workspace to the trash
 func (db *database) DeleteWorkspace(id int) error {
-	log := getLogger().WithGroup("workspaces")
-
-	_, err := db.Exec("DELETE FROM workspaces WHERE id = ?", id)
+	tx, err := db.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to delete workspace: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := db.DeleteWorkspaceTx(tx, id); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	log.Debug("workspace deleted", "workspace_id", id)
 	return nil
 }
 
-// DeleteWorkspaceTx removes a workspace record from the database within a transaction
+// DeleteWorkspaceTx moves a workspace to the trash within a transaction. Its members and share
+// links are kept for a restore. Members whose last workspace it was are moved to the first
+// workspace they own.
 func (db *database) DeleteWorkspaceTx(tx *sql.Tx, id int) error {
 	log := getLogger().WithGroup("workspaces")
-	result, err := tx.Exec("DELETE FROM workspaces WHERE id = ?", id)
+
+	if err := reassignLastWorkspaceTx(tx, id, 0); err != nil {
+		return err
+	}
+
+	result, err := tx.Exec("UPDATE workspaces SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL",
+		time.Now().UTC().Truncate(time.Second), id)
 	if err != nil {
 		return fmt.Errorf("failed to delete workspace in transaction: %w", err)
 	}
 
-	_, err = result.RowsAffected()
+	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		return fmt.Errorf("failed to get rows affected in transaction: %w", err)
 	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("workspace not found")
+	}
 
 	log.Debug("workspace deleted",
 		"workspace_id", id)
 	return nil
 }
 
+// GetDeletedWorkspaces retrieves all workspaces in the trash, including the workspaces of
+// deleted users, most recently deleted first
+func (db *database) GetDeletedWorkspaces() ([]*models.Workspace, error) {
+	rows, err := db.Query(`
+        SELECT
+            id, user_id, name, created_at, deleted_at
+        FROM workspaces
+        WHERE deleted_at IS NOT NULL
+        ORDER BY deleted_at DESC, id DESC`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query deleted workspaces: %w", err)
+	}
+	defer rows.Close()
+
+	workspaces := []*models.Workspace{}
+	for rows.Next() {
+		workspace := &models.Workspace{}
+		var deletedAt time.Time
+		err := rows.Scan(&workspace.ID, &workspace.UserID, &workspace.Name, &workspace.CreatedAt, &deletedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan workspace row: %w", err)
+		}
+		workspace.DeletedAt = &deletedAt
+		workspaces = append(workspaces, workspace)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating workspace rows: %w", err)
+	}
+
+	return workspaces, nil
+}
+
+// RestoreWorkspace restores a workspace from the trash and returns its name. Workspaces of deleted
+// users are restored with their owner. If a member has access to another workspace with the same
+// name by now, a number is appended to the name.
+func (db *database) RestoreWorkspace(id int) (string, error) {
+	log := getLogger().WithGroup("workspaces")
+
+	tx, err := db.Begin()
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var name string
+	var ownerDeleted bool
+	err = tx.QueryRow(`
+        SELECT w.name, u.deleted_at IS NOT NULL
+        FROM workspaces w
+        JOIN users u ON u.id = w.user_id
+        WHERE w.id = ? AND w.deleted_at IS NOT NULL`,
+		id,
+	).Scan(&name, &ownerDeleted)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("deleted workspace not found")
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch deleted workspace: %w", err)
+	}
+	if ownerDeleted {
+		return "", ErrWorkspaceOwnerDeleted
+	}
+
+	memberIDs, err := workspaceMemberIDsTx(tx, id)
+	if err != nil {
+		return "", err
+	}
+	restoredName, err := uniqueWorkspaceNameTx(tx, id, memberIDs, name)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tx.Exec("UPDATE workspaces SET deleted_at = NULL, name = ? WHERE id = ?", restoredName, id)
+	if err != nil {
+		return "", fmt.Errorf("failed to restore workspace: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	log.Debug("workspace restored",
+		"workspace_id", id,
+		"name", restoredName)
+	return restoredName, nil
+}
+
+// PurgeWorkspace permanently deletes a workspace in the trash with its members and share links
+func (db *database) PurgeWorkspace(id int) error {
+	log := getLogger().WithGroup("workspaces")
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM workspace_members WHERE workspace_id = ?", id); err != nil {
+		return fmt.Errorf("failed to delete workspace members: %w", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM share_links WHERE workspace_id = ?", id); err != nil {
+		return fmt.Errorf("failed to delete share links: %w", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM workspace_quotas WHERE workspace_id = ?", id); err != nil {
+		return fmt.Errorf("failed to delete storage quota: %w", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM workspace_usage WHERE workspace_id = ?", id); err != nil {
+		return fmt.Errorf("failed to delete storage usage: %w", err)
+	}
+
+	result, err := tx.Exec("DELETE FROM workspaces WHERE id = ? AND deleted_at IS NOT NULL", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete workspace: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("deleted workspace not found")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	log.Debug("workspace purged",
+		"workspace_id", id)
+	return nil
+}
+
 // UpdateLastWorkspaceTx sets the last workspace for a user in a transaction
 func (db *database) UpdateLastWorkspaceTx(tx *sql.Tx, userID, workspaceID int) error {
 	result, err := tx.Exec("UPDATE users SET last_workspace_id = ? WHERE id = ?",
@@ -346,7 +527,8 @@ func (db *database) GetAllWorkspaces() ([]*models.Workspace, error) {
             git_enabled, git_url, git_user, git_token,
             git_auto_commit, git_commit_msg_template,
             git_commit_name, git_commit_email
-        FROM workspaces`,
+        FROM workspaces
+        WHERE deleted_at IS NULL`,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query workspaces: %w", err)
@@ -1,6 +1,7 @@
 package db_test
 
 import (
+	"errors"
 	"strings"
 	"testing"
 
@@ -389,6 +390,97 @@ func TestWorkspaceOperations(t *testing.T) {
 			t.Errorf("expected workspace not found, got %v", err)
 		}
 	})
+
+	t.Run("RestoreWorkspace and PurgeWorkspace", func(t *testing.T) {
+		workspace := &models.Workspace{UserID: user.ID, Name: "Trash Workspace"}
+		if err := database.CreateWorkspace(workspace); err != nil {
+			t.Fatalf("failed to create test workspace: %v", err)
+		}
+		if err := database.DeleteWorkspace(workspace.ID); err != nil {
+			t.Fatalf("failed to delete workspace: %v", err)
+		}
+
+		deleted, err := database.GetDeletedWorkspaces()
+		if err != nil {
+			t.Fatalf("failed to get deleted workspaces: %v", err)
+		}
+		if len(deleted) == 0 || deleted[0].ID != workspace.ID || deleted[0].DeletedAt == nil {
+			t.Fatalf("GetDeletedWorkspaces() = %+v, want workspace %d first", deleted, workspace.ID)
+		}
+
+		if name, err := database.RestoreWorkspace(workspace.ID); err != nil || name != workspace.Name {
+			t.Fatalf("RestoreWorkspace() = %q, %v, want %q", name, err, workspace.Name)
+		}
+		if _, err := database.GetWorkspaceByID(workspace.ID); err != nil {
+			t.Errorf("restored workspace not found: %v", err)
+		}
+		if err := database.PurgeWorkspace(workspace.ID); err == nil {
+			t.Error("expected error purging a workspace that isn't deleted, got nil")
+		}
+
+		if err := database.DeleteWorkspace(workspace.ID); err != nil {
+			t.Fatalf("failed to delete workspace: %v", err)
+		}
+		if err := database.PurgeWorkspace(workspace.ID); err != nil {
+			t.Fatalf("failed to purge workspace: %v", err)
+		}
+		if _, err := database.RestoreWorkspace(workspace.ID); err == nil {
+			t.Error("expected error restoring a purged workspace, got nil")
+		}
+	})
+
+	t.Run("RestoreWorkspace renames on name collisions", func(t *testing.T) {
+		deleted := &models.Workspace{UserID: user.ID, Name: "Reused"}
+		if err := database.CreateWorkspace(deleted); err != nil {
+			t.Fatalf("failed to create test workspace: %v", err)
+		}
+		if err := database.DeleteWorkspace(deleted.ID); err != nil {
+			t.Fatalf("failed to delete workspace: %v", err)
+		}
+
+		// The name was reused while the workspace was in the trash
+		reused := &models.Workspace{UserID: user.ID, Name: "Reused"}
+		if err := database.CreateWorkspace(reused); err != nil {
+			t.Fatalf("failed to create test workspace: %v", err)
+		}
+
+		name, err := database.RestoreWorkspace(deleted.ID)
+		if err != nil {
+			t.Fatalf("failed to restore workspace: %v", err)
+		}
+		if name != "Reused (2)" {
+			t.Errorf("RestoreWorkspace() name = %q, want Reused (2)", name)
+		}
+
+		restored, err := database.GetWorkspaceByID(deleted.ID)
+		if err != nil {
+			t.Fatalf("restored workspace not found: %v", err)
+		}
+		if restored.Name != name {
+			t.Errorf("restored workspace name = %q, want %q", restored.Name, name)
+		}
+		if existing, err := database.GetWorkspaceByID(reused.ID); err != nil || existing.Name != "Reused" {
+			t.Errorf("GetWorkspaceByID() = %+v, %v, want the existing workspace unchanged", existing, err)
+		}
+	})
+
+	t.Run("RestoreWorkspace requires an active owner", func(t *testing.T) {
+		owner, err := database.CreateUser(&models.User{
+			Email:        "deleted-owner@example.com",
+			PasswordHash: "hash",
+			Role:         models.RoleEditor,
+		})
+		if err != nil {
+			t.Fatalf("failed to create test user: %v", err)
+		}
+		if err := database.DeleteUser(owner.ID); err != nil {
+			t.Fatalf("failed to delete user: %v", err)
+		}
+
+		if _, err := database.RestoreWorkspace(owner.LastWorkspaceID); !errors.Is(err, db.ErrWorkspaceOwnerDeleted) {
+			t.Errorf("RestoreWorkspace() error = %v, want %v", err, db.ErrWorkspaceOwnerDeleted)
+		}
+	})
 }
 
 // Helper function to verify workspace fields
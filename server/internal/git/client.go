@@ -2,6 +2,7 @@
 package git
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -25,13 +26,13 @@ type Config struct {
 	CommitEmail string
 }
 
-// Client defines the interface for Git operations
+// Client defines the interface for Git operations. Cloning and pulling stop when ctx is canceled.
 type Client interface {
-	Clone() error
-	Pull() error
+	Clone(ctx context.Context) error
+	Pull(ctx context.Context) error
 	Commit(message string) (CommitHash, error)
 	Push() error
-	EnsureRepo() error
+	EnsureRepo(ctx context.Context) error
 }
 
 // CommitHash represents a Git commit hash
@@ -72,7 +73,7 @@ func New(url, username, token, workDir, commitName, commitEmail string) Client {
 }
 
 // Clone clones the Git repository to the local directory
-func (c *client) Clone() error {
+func (c *client) Clone(ctx context.Context) error {
 	log := getLogger()
 	log.Info("cloning git repository",
 		"url", c.URL,
@@ -84,7 +85,7 @@ func (c *client) Clone() error {
 	}
 
 	var err error
-	c.repo, err = git.PlainClone(c.WorkDir, false, &git.CloneOptions{
+	c.repo, err = git.PlainCloneContext(ctx, c.WorkDir, false, &git.CloneOptions{
 		URL:      c.URL,
 		Auth:     auth,
 		Progress: os.Stdout,
@@ -97,7 +98,7 @@ func (c *client) Clone() error {
 }
 
 // Pull pulls the latest changes from the remote repository
-func (c *client) Pull() error {
+func (c *client) Pull(ctx context.Context) error {
 	log := getLogger().With(
 		"workDir", c.WorkDir,
 	)
@@ -116,7 +117,7 @@ func (c *client) Pull() error {
 		Password: c.Token,
 	}
 
-	err = w.Pull(&git.PullOptions{
+	err = w.PullContext(ctx, &git.PullOptions{
 		Auth:     auth,
 		Progress: os.Stdout,
 	})
@@ -202,7 +203,7 @@ func (c *client) Push() error {
 }
 
 // EnsureRepo ensures the local repository is cloned and up-to-date
-func (c *client) EnsureRepo() error {
+func (c *client) EnsureRepo(ctx context.Context) error {
 	log := getLogger().With(
 		"workDir", c.WorkDir,
 	)
@@ -211,7 +212,7 @@ func (c *client) EnsureRepo() error {
 
 	if _, err := os.Stat(filepath.Join(c.WorkDir, ".git")); os.IsNotExist(err) {
 		log.Info("repository not found, initiating clone")
-		return c.Clone()
+		return c.Clone(ctx)
 	}
 
 	var err error
@@ -220,5 +221,5 @@ func (c *client) EnsureRepo() error {
 		return fmt.Errorf("failed to open existing repository: %w", err)
 	}
 
-	return c.Pull()
+	return c.Pull(ctx)
 }
@@ -0,0 +1,352 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"lemma/internal/auth"
+	"lemma/internal/logging"
+	"lemma/internal/mailer"
+	"lemma/internal/models"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ForgotPasswordRequest represents a request for a password reset link
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+// ResetPasswordRequest represents a request to set a new password with a reset token
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// VerifyEmailRequest represents a request to confirm an email change
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+func getAccountLogger() logging.Logger {
+	return getHandlersLogger().WithGroup("account")
+}
+
+// ForgotPassword godoc
+// @Summary Request password reset
+// @Description Sends a single-use password reset link to the email address if it belongs to a user.
+// @Description The response is the same whether or not the user exists.
+// @Tags auth
+// @ID forgotPassword
+// @Accept json
+// @Param body body ForgotPasswordRequest true "Forgot password request"
+// @Success 204 "No Content - Reset link sent if the user exists"
+// @Failure 400 {object} ErrorResponse "Invalid request body"
+// @Failure 400 {object} ErrorResponse "Email is required"
+// @Router /auth/forgot-password [post]
+func (h *Handler) ForgotPassword(userTokens auth.UserTokenManager, m mailer.Mailer, rootURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := getAccountLogger().With(
+			"handler", "ForgotPassword",
+			"clientIP", r.RemoteAddr,
+		)
+
+		var req ForgotPasswordRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			log.Debug("failed to decode request body",
+				"error", err.Error(),
+			)
+			respondError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if req.Email == "" {
+			respondError(w, "Email is required", http.StatusBadRequest)
+			return
+		}
+
+		// Failures past this point are only logged, so the response doesn't reveal which emails exist
+		user, err := h.DB.GetUserByEmail(req.Email)
+		if err != nil {
+			log.Debug("password reset requested for unknown email",
+				"email", req.Email,
+			)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		token, err := userTokens.Issue(user.ID, models.UserTokenPurposePasswordReset, "")
+		if err != nil {
+			log.Error("failed to issue password reset token",
+				"userID", user.ID,
+				"error", err.Error(),
+			)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if err := m.Send(passwordResetMessage(user.Email, accountLink(rootURL, "reset-password", token))); err != nil {
+			log.Error("failed to send password reset email",
+				"userID", user.ID,
+				"error", err.Error(),
+			)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		h.audit(r, "password reset requested", &models.AuditEvent{
+			Event:      "password_reset_requested",
+			TargetType: models.AuditTargetUser,
+			TargetID:   strconv.Itoa(user.ID),
+		})
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// ResetPassword godoc
+// @Summary Reset password
+// @Description Sets a new password using a token from a password reset email. All sessions of the user are revoked and a login lockout of the account is lifted.
+// @Tags auth
+// @ID resetPassword
+// @Accept json
+// @Param body body ResetPasswordRequest true "Reset password request"
+// @Success 204 "No Content - Password reset successfully"
+// @Failure 400 {object} ErrorResponse "Invalid request body"
+// @Failure 400 {object} ErrorResponse "Password must be at least 8 characters long"
+// @Failure 400 {object} ErrorResponse "Invalid or expired token"
+// @Failure 500 {object} ErrorResponse "Failed to process new password"
+// @Failure 500 {object} ErrorResponse "Failed to reset password"
+// @Failure 500 {object} ErrorResponse "Failed to revoke sessions"
+// @Router /auth/reset-password [post]
+func (h *Handler) ResetPassword(userTokens auth.UserTokenManager, loginLimiter auth.LoginLimiter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := getAccountLogger().With(
+			"handler", "ResetPassword",
+			"clientIP", r.RemoteAddr,
+		)
+
+		var req ResetPasswordRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			log.Debug("failed to decode request body",
+				"error", err.Error(),
+			)
+			respondError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		// Validate before redeeming, so a rejected password doesn't use up the token
+		if len(req.Password) < 8 {
+			log.Debug("password reset rejected - too short",
+				"passwordLength", len(req.Password),
+			)
+			respondError(w, "Password must be at least 8 characters long", http.StatusBadRequest)
+			return
+		}
+
+		userToken, err := userTokens.Redeem(req.Token, models.UserTokenPurposePasswordReset)
+		if err != nil {
+			log.Warn("invalid password reset token")
+			respondError(w, "Invalid or expired token", http.StatusBadRequest)
+			return
+		}
+
+		user, err := h.DB.GetUserByID(userToken.UserID)
+		if err != nil {
+			log.Warn("password reset token for unknown user",
+				"userID", userToken.UserID,
+			)
+			respondError(w, "Invalid or expired token", http.StatusBadRequest)
+			return
+		}
+
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			log.Error("failed to hash new password",
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to process new password", http.StatusInternalServerError)
+			return
+		}
+		user.PasswordHash = string(hashedPassword)
+		user.MustChangePassword = false
+		// The reset link was sent to the address, which confirms it
+		user.EmailVerificationPending = false
+
+		if err := h.DB.UpdateUser(user); err != nil {
+			log.Error("failed to update user in database",
+				"userID", user.ID,
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to reset password", http.StatusInternalServerError)
+			return
+		}
+
+		if err := h.DB.DeleteUserSessions(user.ID, ""); err != nil {
+			log.Error("failed to revoke sessions",
+				"userID", user.ID,
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to revoke sessions", http.StatusInternalServerError)
+			return
+		}
+
+		if err := loginLimiter.Unlock(user.Email); err != nil {
+			log.Warn("failed to lift login lockout",
+				"userID", user.ID,
+				"error", err.Error(),
+			)
+		}
+
+		h.audit(r, "password reset", &models.AuditEvent{
+			Event:      "password_reset",
+			ActorID:    &user.ID,
+			TargetType: models.AuditTargetUser,
+			TargetID:   strconv.Itoa(user.ID),
+		})
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// VerifyEmail godoc
+// @Summary Verify email change
+// @Description Changes the user's email to the address confirmed by a token from a verification email. All sessions of the user are revoked.
+// @Description Users who signed up and have to confirm their email address can log in afterwards.
+// @Tags auth
+// @ID verifyEmail
+// @Accept json
+// @Param body body VerifyEmailRequest true "Verify email request"
+// @Success 204 "No Content - Email changed successfully"
+// @Failure 400 {object} ErrorResponse "Invalid request body"
+// @Failure 400 {object} ErrorResponse "Invalid or expired token"
+// @Failure 409 {object} ErrorResponse "Email already in use"
+// @Failure 500 {object} ErrorResponse "Failed to update email"
+// @Failure 500 {object} ErrorResponse "Failed to revoke sessions"
+// @Router /auth/verify-email [post]
+func (h *Handler) VerifyEmail(userTokens auth.UserTokenManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := getAccountLogger().With(
+			"handler", "VerifyEmail",
+			"clientIP", r.RemoteAddr,
+		)
+
+		var req VerifyEmailRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			log.Debug("failed to decode request body",
+				"error", err.Error(),
+			)
+			respondError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		userToken, err := userTokens.Redeem(req.Token, models.UserTokenPurposeEmailChange)
+		if err != nil {
+			log.Warn("invalid email verification token")
+			respondError(w, "Invalid or expired token", http.StatusBadRequest)
+			return
+		}
+
+		user, err := h.DB.GetUserByID(userToken.UserID)
+		if err != nil {
+			log.Warn("email verification token for unknown user",
+				"userID", userToken.UserID,
+			)
+			respondError(w, "Invalid or expired token", http.StatusBadRequest)
+			return
+		}
+
+		// The address may have been taken since the change was requested
+		emailTaken, err := h.DB.EmailExists(userToken.Email)
+		if err == nil && emailTaken && userToken.Email != user.Email {
+			log.Debug("email change rejected - already in use",
+				"userID", user.ID,
+				"requestedEmail", userToken.Email,
+			)
+			respondError(w, "Email already in use", http.StatusConflict)
+			return
+		}
+
+		previousEmail := user.Email
+		pendingSignup := user.EmailVerificationPending
+		user.Email = userToken.Email
+		user.EmailVerificationPending = false
+		if err := h.DB.UpdateUser(user); err != nil {
+			log.Error("failed to update user in database",
+				"userID", user.ID,
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to update email", http.StatusInternalServerError)
+			return
+		}
+
+		if err := h.DB.DeleteUserSessions(user.ID, ""); err != nil {
+			log.Error("failed to revoke sessions",
+				"userID", user.ID,
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to revoke sessions", http.StatusInternalServerError)
+			return
+		}
+
+		if pendingSignup && previousEmail == user.Email {
+			h.audit(r, "email verified", &models.AuditEvent{
+				Event:      "email_verified",
+				ActorID:    &user.ID,
+				TargetType: models.AuditTargetUser,
+				TargetID:   strconv.Itoa(user.ID),
+				Details:    map[string]any{"email": user.Email},
+			})
+		} else {
+			h.audit(r, "email changed", &models.AuditEvent{
+				Event:      "email_changed",
+				ActorID:    &user.ID,
+				TargetType: models.AuditTargetUser,
+				TargetID:   strconv.Itoa(user.ID),
+				Details:    map[string]any{"previousEmail": previousEmail, "newEmail": user.Email},
+			})
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// accountLink builds a link to a frontend page that receives the token as query parameter
+func accountLink(rootURL, page, token string) string {
+	return fmt.Sprintf("%s/%s?token=%s", strings.TrimRight(rootURL, "/"), page, url.QueryEscape(token))
+}
+
+func passwordResetMessage(to, link string) mailer.Message {
+	return mailer.Message{
+		To:      to,
+		Subject: "Reset your Lemma password",
+		Body: "A password reset was requested for your Lemma account.\n\n" +
+			"Open the following link to choose a new password:\n\n" +
+			link + "\n\n" +
+			"The link can only be used once and expires soon. " +
+			"If you didn't request a password reset, you can ignore this email.\n",
+	}
+}
+
+func emailVerificationMessage(to, link string) mailer.Message {
+	return mailer.Message{
+		To:      to,
+		Subject: "Confirm your new Lemma email address",
+		Body: "A change of your Lemma account's email address to this address was requested.\n\n" +
+			"Open the following link to confirm the change:\n\n" +
+			link + "\n\n" +
+			"If you didn't request this change, you can ignore this email.\n",
+	}
+}
+
+func signupVerificationMessage(to, link string) mailer.Message {
+	return mailer.Message{
+		To:      to,
+		Subject: "Confirm your Lemma email address",
+		Body: "A Lemma account was created with this email address.\n\n" +
+			"Open the following link to confirm the address and activate the account:\n\n" +
+			link + "\n\n" +
+			"If you didn't sign up, you can ignore this email.\n",
+	}
+}
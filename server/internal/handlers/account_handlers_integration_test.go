@@ -0,0 +1,171 @@
+//go:build integration
+
+package handlers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"lemma/internal/handlers"
+	"lemma/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountHandlers_Integration(t *testing.T) {
+	h := setupTestHarness(t)
+	defer h.teardown(t)
+
+	login := func(t *testing.T, email, password string) int {
+		t.Helper()
+		req := h.newRequest(t, http.MethodPost, "/api/v1/auth/login", handlers.LoginRequest{
+			Email:    email,
+			Password: password,
+		})
+		req.RemoteAddr = "192.0.2.30:1234"
+		return h.executeRequest(req).Code
+	}
+
+	t.Run("password reset", func(t *testing.T) {
+		user := h.createTestUser(t, "forgetful@test.com", "password123", models.RoleEditor)
+
+		t.Run("missing email", func(t *testing.T) {
+			rr := h.makeRequest(t, http.MethodPost, "/api/v1/auth/forgot-password", handlers.ForgotPasswordRequest{}, nil)
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+		})
+
+		t.Run("unknown email", func(t *testing.T) {
+			rr := h.makeRequest(t, http.MethodPost, "/api/v1/auth/forgot-password",
+				handlers.ForgotPasswordRequest{Email: "nobody@test.com"}, nil)
+			assert.Equal(t, http.StatusNoContent, rr.Code)
+			assert.Empty(t, h.Mailer.Messages("nobody@test.com"))
+		})
+
+		t.Run("reset with emailed token", func(t *testing.T) {
+			rr := h.makeRequest(t, http.MethodPost, "/api/v1/auth/forgot-password",
+				handlers.ForgotPasswordRequest{Email: user.userModel.Email}, nil)
+			require.Equal(t, http.StatusNoContent, rr.Code)
+
+			messages := h.Mailer.Messages(user.userModel.Email)
+			require.Len(t, messages, 1)
+			assert.Contains(t, messages[0].Body, "http://localhost:8081/reset-password?token=")
+
+			token, err := h.Mailer.LastToken(user.userModel.Email)
+			require.NoError(t, err)
+
+			// A rejected password doesn't use up the token
+			rr = h.makeRequest(t, http.MethodPost, "/api/v1/auth/reset-password",
+				handlers.ResetPasswordRequest{Token: token, Password: "short"}, nil)
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+			rr = h.makeRequest(t, http.MethodPost, "/api/v1/auth/reset-password",
+				handlers.ResetPasswordRequest{Token: "invalid", Password: "newpassword123"}, nil)
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+			rr = h.makeRequest(t, http.MethodPost, "/api/v1/auth/reset-password",
+				handlers.ResetPasswordRequest{Token: token, Password: "newpassword123"}, nil)
+			require.Equal(t, http.StatusNoContent, rr.Code)
+
+			// Existing sessions are revoked
+			rr = h.makeRequest(t, http.MethodGet, "/api/v1/auth/me", nil, user)
+			assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+			assert.Equal(t, http.StatusUnauthorized, login(t, user.userModel.Email, "password123"))
+			assert.Equal(t, http.StatusOK, login(t, user.userModel.Email, "newpassword123"))
+
+			// Tokens are single-use
+			rr = h.makeRequest(t, http.MethodPost, "/api/v1/auth/reset-password",
+				handlers.ResetPasswordRequest{Token: token, Password: "otherpassword123"}, nil)
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+		})
+
+		t.Run("only the latest token is valid", func(t *testing.T) {
+			for i := 0; i < 2; i++ {
+				rr := h.makeRequest(t, http.MethodPost, "/api/v1/auth/forgot-password",
+					handlers.ForgotPasswordRequest{Email: user.userModel.Email}, nil)
+				require.Equal(t, http.StatusNoContent, rr.Code)
+			}
+
+			messages := h.Mailer.Messages(user.userModel.Email)
+			require.GreaterOrEqual(t, len(messages), 2)
+			first := tokenLinkPattern.FindStringSubmatch(messages[len(messages)-2].Body)
+			require.NotNil(t, first)
+
+			rr := h.makeRequest(t, http.MethodPost, "/api/v1/auth/reset-password",
+				handlers.ResetPasswordRequest{Token: first[1], Password: "newpassword456"}, nil)
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+			token, err := h.Mailer.LastToken(user.userModel.Email)
+			require.NoError(t, err)
+			rr = h.makeRequest(t, http.MethodPost, "/api/v1/auth/reset-password",
+				handlers.ResetPasswordRequest{Token: token, Password: "newpassword456"}, nil)
+			assert.Equal(t, http.StatusNoContent, rr.Code)
+		})
+	})
+
+	t.Run("email change verification", func(t *testing.T) {
+		h.Config.VerifyEmailChanges = true
+		h.restartServer()
+		defer func() {
+			h.Config.VerifyEmailChanges = false
+			h.restartServer()
+		}()
+
+		user := h.createTestUser(t, "mover@test.com", "password123", models.RoleEditor)
+		newEmail := "moved@test.com"
+
+		t.Run("email is changed after verification", func(t *testing.T) {
+			rr := h.makeRequest(t, http.MethodPut, "/api/v1/profile", handlers.UpdateProfileRequest{
+				Email:           newEmail,
+				CurrentPassword: "password123",
+			}, user)
+			require.Equal(t, http.StatusOK, rr.Code)
+			assert.Contains(t, rr.Body.String(), user.userModel.Email, "email should not change before verification")
+
+			dbUser, err := h.DB.GetUserByID(user.userModel.ID)
+			require.NoError(t, err)
+			assert.Equal(t, user.userModel.Email, dbUser.Email)
+
+			messages := h.Mailer.Messages(newEmail)
+			require.Len(t, messages, 1)
+			assert.Contains(t, messages[0].Body, "http://localhost:8081/verify-email?token=")
+
+			token, err := h.Mailer.LastToken(newEmail)
+			require.NoError(t, err)
+
+			rr = h.makeRequest(t, http.MethodPost, "/api/v1/auth/verify-email", handlers.VerifyEmailRequest{Token: token}, nil)
+			require.Equal(t, http.StatusNoContent, rr.Code)
+
+			dbUser, err = h.DB.GetUserByID(user.userModel.ID)
+			require.NoError(t, err)
+			assert.Equal(t, newEmail, dbUser.Email)
+
+			// Sessions are revoked so the user signs in with the new email
+			rr = h.makeRequest(t, http.MethodGet, "/api/v1/auth/me", nil, user)
+			assert.Equal(t, http.StatusUnauthorized, rr.Code)
+			assert.Equal(t, http.StatusOK, login(t, newEmail, "password123"))
+
+			rr = h.makeRequest(t, http.MethodPost, "/api/v1/auth/verify-email", handlers.VerifyEmailRequest{Token: token}, nil)
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+		})
+
+		t.Run("email taken before verification", func(t *testing.T) {
+			other := h.createTestUser(t, "other-mover@test.com", "password123", models.RoleEditor)
+
+			rr := h.makeRequest(t, http.MethodPut, "/api/v1/profile", handlers.UpdateProfileRequest{
+				Email:           "contested@test.com",
+				CurrentPassword: "password123",
+			}, other)
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			token, err := h.Mailer.LastToken("contested@test.com")
+			require.NoError(t, err)
+
+			h.createTestUser(t, "contested@test.com", "password123", models.RoleEditor)
+
+			rr = h.makeRequest(t, http.MethodPost, "/api/v1/auth/verify-email", handlers.VerifyEmailRequest{Token: token}, nil)
+			assert.Equal(t, http.StatusConflict, rr.Code)
+		})
+	})
+}
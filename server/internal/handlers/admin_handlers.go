@@ -3,6 +3,8 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"lemma/internal/auth"
 	"lemma/internal/context"
 	"lemma/internal/db"
 	"lemma/internal/logging"
@@ -18,18 +20,26 @@ import (
 
 // CreateUserRequest holds the request fields for creating a new user
 type CreateUserRequest struct {
-	Email       string          `json:"email"`
-	DisplayName string          `json:"displayName"`
-	Password    string          `json:"password"`
-	Role        models.UserRole `json:"role"`
+	Email              string          `json:"email"`
+	DisplayName        string          `json:"displayName"`
+	Password           string          `json:"password"`
+	Role               models.UserRole `json:"role"`
+	MustChangePassword bool            `json:"mustChangePassword"` // Require a password change on the first login
 }
 
 // UpdateUserRequest holds the request fields for updating a user
 type UpdateUserRequest struct {
-	Email       string          `json:"email,omitempty"`
-	DisplayName string          `json:"displayName,omitempty"`
-	Password    string          `json:"password,omitempty"`
-	Role        models.UserRole `json:"role,omitempty"`
+	Email              string          `json:"email,omitempty"`
+	DisplayName        string          `json:"displayName,omitempty"`
+	Password           string          `json:"password,omitempty"`
+	Role               models.UserRole `json:"role,omitempty"`
+	MustChangePassword *bool           `json:"mustChangePassword,omitempty"` // Require a password change on the next login
+}
+
+// TransferWorkspaceRequest holds the request fields for transferring a workspace to another user
+type TransferWorkspaceRequest struct {
+	UserID            int                  `json:"userId"`
+	PreviousOwnerRole models.WorkspaceRole `json:"previousOwnerRole,omitempty"` // Role of the previous owner, who loses access if it is empty
 }
 
 // WorkspaceStats holds workspace statistics
@@ -137,9 +147,9 @@ func (h *Handler) AdminCreateUser() http.HandlerFunc {
 			return
 		}
 
-		// Email existence check
-		existingUser, err := h.DB.GetUserByEmail(req.Email)
-		if err == nil && existingUser != nil {
+		// Email existence check, deleted users keep their email until they are purged
+		emailTaken, err := h.DB.EmailExists(req.Email)
+		if err == nil && emailTaken {
 			log.Warn("attempted to create user with existing email",
 				"email", req.Email,
 			)
@@ -165,10 +175,11 @@ func (h *Handler) AdminCreateUser() http.HandlerFunc {
 		}
 
 		user := &models.User{
-			Email:        req.Email,
-			DisplayName:  req.DisplayName,
-			PasswordHash: string(hashedPassword),
-			Role:         req.Role,
+			Email:              req.Email,
+			DisplayName:        req.DisplayName,
+			PasswordHash:       string(hashedPassword),
+			Role:               req.Role,
+			MustChangePassword: req.MustChangePassword,
 		}
 
 		insertedUser, err := h.DB.CreateUser(user)
@@ -192,26 +203,29 @@ func (h *Handler) AdminCreateUser() http.HandlerFunc {
 			return
 		}
 
-		log.Info("user created",
-			"newUserID", insertedUser.ID,
-			"email", insertedUser.Email,
-			"role", insertedUser.Role,
-		)
+		h.audit(r, "user created by admin", &models.AuditEvent{
+			Event:      "user_created",
+			ActorID:    &ctx.UserID,
+			TargetType: models.AuditTargetUser,
+			TargetID:   strconv.Itoa(insertedUser.ID),
+			Details:    map[string]any{"email": insertedUser.Email, "role": insertedUser.Role},
+		})
 		respondJSON(w, insertedUser)
 	}
 }
 
 // AdminGetUser godoc
 // @Summary Get a specific user
-// @Description Get a specific user as an admin
+// @Description Get a specific user as an admin, along with their storage usage and quota
 // @Tags Admin
 // @Security CookieAuth
 // @ID adminGetUser
 // @Produce json
 // @Param userId path int true "User ID"
-// @Success 200 {object} models.User
+// @Success 200 {object} UserStorageResponse
 // @Failure 400 {object} ErrorResponse "Invalid user ID"
 // @Failure 404 {object} ErrorResponse "User not found"
+// @Failure 500 {object} ErrorResponse "Failed to get storage usage"
 // @Router /admin/users/{userId} [get]
 func (h *Handler) AdminGetUser() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -245,7 +259,17 @@ func (h *Handler) AdminGetUser() http.HandlerFunc {
 			return
 		}
 
-		respondJSON(w, user)
+		usage, err := h.getStorageUsage(userID)
+		if err != nil {
+			log.Error("failed to get storage usage",
+				"targetUserID", userID,
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to get storage usage", http.StatusInternalServerError)
+			return
+		}
+
+		respondJSON(w, UserStorageResponse{User: user, Storage: *usage})
 	}
 }
 
@@ -265,6 +289,7 @@ func (h *Handler) AdminGetUser() http.HandlerFunc {
 // @Failure 404 {object} ErrorResponse "User not found"
 // @Failure 500 {object} ErrorResponse "Failed to hash password"
 // @Failure 500 {object} ErrorResponse "Failed to update user"
+// @Failure 500 {object} ErrorResponse "Failed to revoke user sessions"
 // @Router /admin/users/{userId} [put]
 func (h *Handler) AdminUpdateUser() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -309,6 +334,7 @@ func (h *Handler) AdminUpdateUser() http.HandlerFunc {
 
 		// Track what's being updated for logging
 		updates := make(map[string]interface{})
+		previousRole := user.Role
 
 		if req.Email != "" {
 			user.Email = req.Email
@@ -334,6 +360,10 @@ func (h *Handler) AdminUpdateUser() http.HandlerFunc {
 			user.PasswordHash = string(hashedPassword)
 			updates["passwordUpdated"] = true
 		}
+		if req.MustChangePassword != nil {
+			user.MustChangePassword = *req.MustChangePassword
+			updates["mustChangePassword"] = *req.MustChangePassword
+		}
 
 		if err := h.DB.UpdateUser(user); err != nil {
 			log.Error("failed to update user in database",
@@ -344,17 +374,42 @@ func (h *Handler) AdminUpdateUser() http.HandlerFunc {
 			return
 		}
 
+		// Changing credentials signs the user out everywhere except the admin's own session
+		if updates["email"] != nil || updates["passwordUpdated"] != nil {
+			if err := h.DB.DeleteUserSessions(userID, ctx.SessionID); err != nil {
+				log.Error("failed to revoke user sessions",
+					"error", err.Error(),
+					"targetUserID", userID,
+				)
+				respondError(w, "Failed to revoke user sessions", http.StatusInternalServerError)
+				return
+			}
+		}
+
 		log.Debug("user updated",
 			"targetUserID", userID,
 			"updates", updates,
 		)
+
+		details := map[string]any{"fields": updatedFields(updates)}
+		if user.Role != previousRole {
+			details["previousRole"] = previousRole
+			details["role"] = user.Role
+		}
+		h.audit(r, "user updated by admin", &models.AuditEvent{
+			Event:      "user_updated",
+			ActorID:    &ctx.UserID,
+			TargetType: models.AuditTargetUser,
+			TargetID:   strconv.Itoa(userID),
+			Details:    details,
+		})
 		respondJSON(w, user)
 	}
 }
 
 // AdminDeleteUser godoc
 // @Summary Delete a specific user
-// @Description Delete a specific user as an admin
+// @Description Delete a specific user as an admin. The user and their workspaces are moved to the trash and permanently deleted after the trash retention period.
 // @Tags Admin
 // @Security CookieAuth
 // @ID adminDeleteUser
@@ -422,15 +477,534 @@ func (h *Handler) AdminDeleteUser() http.HandlerFunc {
 			return
 		}
 
-		log.Info("user deleted",
-			"targetUserID", userID,
-			"targetUserEmail", user.Email,
-			"targetUserRole", user.Role,
+		h.audit(r, "user deleted by admin", &models.AuditEvent{
+			Event:      "user_deleted",
+			ActorID:    &ctx.UserID,
+			TargetType: models.AuditTargetUser,
+			TargetID:   strconv.Itoa(userID),
+			Details:    map[string]any{"email": user.Email, "role": user.Role},
+		})
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// AdminListUserSessions godoc
+// @Summary List a user's sessions
+// @Description Returns the active sessions of a specific user as an admin
+// @Tags Admin
+// @Security CookieAuth
+// @ID adminListUserSessions
+// @Produce json
+// @Param userId path int true "User ID"
+// @Success 200 {array} SessionResponse
+// @Failure 400 {object} ErrorResponse "Invalid user ID"
+// @Failure 404 {object} ErrorResponse "User not found"
+// @Failure 500 {object} ErrorResponse "Failed to list sessions"
+// @Router /admin/users/{userId}/sessions [get]
+func (h *Handler) AdminListUserSessions() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := context.GetRequestContext(w, r)
+		if !ok {
+			return
+		}
+		log := getAdminLogger().With(
+			"handler", "AdminListUserSessions",
+			"adminID", ctx.UserID,
+			"clientIP", r.RemoteAddr,
 		)
+
+		userID, err := strconv.Atoi(chi.URLParam(r, "userId"))
+		if err != nil {
+			log.Debug("invalid user ID format",
+				"userIDParam", chi.URLParam(r, "userId"),
+				"error", err.Error(),
+			)
+			respondError(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		if _, err := h.DB.GetUserByID(userID); err != nil {
+			log.Debug("user not found",
+				"targetUserID", userID,
+				"error", err.Error(),
+			)
+			respondError(w, "User not found", http.StatusNotFound)
+			return
+		}
+
+		sessions, err := h.DB.GetSessionsByUserID(userID)
+		if err != nil {
+			log.Error("failed to fetch sessions from database",
+				"error", err.Error(),
+				"targetUserID", userID,
+			)
+			respondError(w, "Failed to list sessions", http.StatusInternalServerError)
+			return
+		}
+
+		respondJSON(w, newSessionResponses(sessions, ctx.SessionID))
+	}
+}
+
+// AdminRevokeUserSessions godoc
+// @Summary Revoke all sessions of a user
+// @Description Revokes all sessions of a specific user as an admin, except the admin's own session
+// @Tags Admin
+// @Security CookieAuth
+// @ID adminRevokeUserSessions
+// @Param userId path int true "User ID"
+// @Success 204 "No Content - Sessions revoked successfully"
+// @Failure 400 {object} ErrorResponse "Invalid user ID"
+// @Failure 404 {object} ErrorResponse "User not found"
+// @Failure 500 {object} ErrorResponse "Failed to revoke sessions"
+// @Router /admin/users/{userId}/sessions [delete]
+func (h *Handler) AdminRevokeUserSessions() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := context.GetRequestContext(w, r)
+		if !ok {
+			return
+		}
+		log := getAdminLogger().With(
+			"handler", "AdminRevokeUserSessions",
+			"adminID", ctx.UserID,
+			"clientIP", r.RemoteAddr,
+		)
+
+		userID, err := strconv.Atoi(chi.URLParam(r, "userId"))
+		if err != nil {
+			log.Debug("invalid user ID format",
+				"userIDParam", chi.URLParam(r, "userId"),
+				"error", err.Error(),
+			)
+			respondError(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		if _, err := h.DB.GetUserByID(userID); err != nil {
+			log.Debug("user not found",
+				"targetUserID", userID,
+				"error", err.Error(),
+			)
+			respondError(w, "User not found", http.StatusNotFound)
+			return
+		}
+
+		if err := h.DB.DeleteUserSessions(userID, ctx.SessionID); err != nil {
+			log.Error("failed to delete user sessions",
+				"error", err.Error(),
+				"targetUserID", userID,
+			)
+			respondError(w, "Failed to revoke sessions", http.StatusInternalServerError)
+			return
+		}
+
+		h.audit(r, "user sessions revoked by admin", &models.AuditEvent{
+			Event:      "sessions_revoked",
+			ActorID:    &ctx.UserID,
+			TargetType: models.AuditTargetUser,
+			TargetID:   strconv.Itoa(userID),
+		})
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
 
+// AdminRevokeUserSession godoc
+// @Summary Revoke a session of a user
+// @Description Revokes a specific session of a specific user as an admin
+// @Tags Admin
+// @Security CookieAuth
+// @ID adminRevokeUserSession
+// @Param userId path int true "User ID"
+// @Param sessionId path string true "Session ID"
+// @Success 204 "No Content - Session revoked successfully"
+// @Failure 400 {object} ErrorResponse "Invalid user ID"
+// @Failure 404 {object} ErrorResponse "Session not found"
+// @Failure 500 {object} ErrorResponse "Failed to revoke session"
+// @Router /admin/users/{userId}/sessions/{sessionId} [delete]
+func (h *Handler) AdminRevokeUserSession() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := context.GetRequestContext(w, r)
+		if !ok {
+			return
+		}
+		log := getAdminLogger().With(
+			"handler", "AdminRevokeUserSession",
+			"adminID", ctx.UserID,
+			"clientIP", r.RemoteAddr,
+		)
+
+		userID, err := strconv.Atoi(chi.URLParam(r, "userId"))
+		if err != nil {
+			log.Debug("invalid user ID format",
+				"userIDParam", chi.URLParam(r, "userId"),
+				"error", err.Error(),
+			)
+			respondError(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		sessionID := chi.URLParam(r, "sessionId")
+		session, err := h.DB.GetSessionByID(sessionID)
+		if err != nil || session.UserID != userID {
+			log.Debug("session not found",
+				"targetUserID", userID,
+				"sessionID", sessionID,
+			)
+			respondError(w, "Session not found", http.StatusNotFound)
+			return
+		}
+
+		if err := h.DB.DeleteSession(sessionID); err != nil {
+			log.Error("failed to delete session",
+				"error", err.Error(),
+				"targetUserID", userID,
+				"sessionID", sessionID,
+			)
+			respondError(w, "Failed to revoke session", http.StatusInternalServerError)
+			return
+		}
+
+		h.audit(r, "user session revoked by admin", &models.AuditEvent{
+			Event:      "session_revoked",
+			ActorID:    &ctx.UserID,
+			TargetType: models.AuditTargetSession,
+			TargetID:   sessionID,
+			Details:    map[string]any{"userID": userID},
+		})
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// AdminUnlockUser godoc
+// @Summary Unlock a user
+// @Description Lifts a login lockout of a specific user caused by repeated failed login attempts
+// @Tags Admin
+// @Security CookieAuth
+// @ID adminUnlockUser
+// @Param userId path int true "User ID"
+// @Success 204 "No Content - User unlocked successfully"
+// @Failure 400 {object} ErrorResponse "Invalid user ID"
+// @Failure 404 {object} ErrorResponse "User not found"
+// @Failure 500 {object} ErrorResponse "Failed to unlock user"
+// @Router /admin/users/{userId}/unlock [post]
+func (h *Handler) AdminUnlockUser(loginLimiter auth.LoginLimiter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := context.GetRequestContext(w, r)
+		if !ok {
+			return
+		}
+		log := getAdminLogger().With(
+			"handler", "AdminUnlockUser",
+			"adminID", ctx.UserID,
+			"clientIP", r.RemoteAddr,
+		)
+
+		userID, err := strconv.Atoi(chi.URLParam(r, "userId"))
+		if err != nil {
+			log.Debug("invalid user ID format",
+				"userIDParam", chi.URLParam(r, "userId"),
+				"error", err.Error(),
+			)
+			respondError(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		user, err := h.DB.GetUserByID(userID)
+		if err != nil {
+			log.Debug("user not found",
+				"targetUserID", userID,
+				"error", err.Error(),
+			)
+			respondError(w, "User not found", http.StatusNotFound)
+			return
+		}
+
+		if err := loginLimiter.Unlock(user.Email); err != nil {
+			log.Error("failed to unlock user",
+				"error", err.Error(),
+				"targetUserID", userID,
+			)
+			respondError(w, "Failed to unlock user", http.StatusInternalServerError)
+			return
+		}
+
+		h.audit(r, "login lockout lifted by admin", &models.AuditEvent{
+			Event:      "login_unlock",
+			ActorID:    &ctx.UserID,
+			TargetType: models.AuditTargetUser,
+			TargetID:   strconv.Itoa(userID),
+		})
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// AdminSuspendUser godoc
+// @Summary Suspend a user
+// @Description Suspends a specific user as an admin. Suspended users keep their data but can't log in, and all their sessions are revoked.
+// @Tags Admin
+// @Security CookieAuth
+// @ID adminSuspendUser
+// @Produce json
+// @Param userId path int true "User ID"
+// @Success 200 {object} models.User
+// @Failure 400 {object} ErrorResponse "Invalid user ID"
+// @Failure 400 {object} ErrorResponse "Cannot suspend your own account"
+// @Failure 404 {object} ErrorResponse "User not found"
+// @Failure 500 {object} ErrorResponse "Failed to suspend user"
+// @Failure 500 {object} ErrorResponse "Failed to revoke user sessions"
+// @Router /admin/users/{userId}/suspend [post]
+func (h *Handler) AdminSuspendUser() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := context.GetRequestContext(w, r)
+		if !ok {
+			return
+		}
+		log := getAdminLogger().With(
+			"handler", "AdminSuspendUser",
+			"adminID", ctx.UserID,
+			"clientIP", r.RemoteAddr,
+		)
+
+		userID, err := strconv.Atoi(chi.URLParam(r, "userId"))
+		if err != nil {
+			log.Debug("invalid user ID format",
+				"userIDParam", chi.URLParam(r, "userId"),
+				"error", err.Error(),
+			)
+			respondError(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		if userID == ctx.UserID {
+			log.Warn("admin attempted to suspend own account")
+			respondError(w, "Cannot suspend your own account", http.StatusBadRequest)
+			return
+		}
+
+		user, err := h.DB.GetUserByID(userID)
+		if err != nil {
+			log.Debug("user not found",
+				"targetUserID", userID,
+				"error", err.Error(),
+			)
+			respondError(w, "User not found", http.StatusNotFound)
+			return
+		}
+
+		if !user.Suspended() {
+			suspendedAt := time.Now().UTC()
+			user.SuspendedAt = &suspendedAt
+			if err := h.DB.UpdateUser(user); err != nil {
+				log.Error("failed to suspend user",
+					"error", err.Error(),
+					"targetUserID", userID,
+				)
+				respondError(w, "Failed to suspend user", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		// Sessions are revoked even if the user was already suspended, in case revoking them failed before
+		if err := h.DB.DeleteUserSessions(userID, ""); err != nil {
+			log.Error("failed to revoke user sessions",
+				"error", err.Error(),
+				"targetUserID", userID,
+			)
+			respondError(w, "Failed to revoke user sessions", http.StatusInternalServerError)
+			return
+		}
+
+		log.Debug("user suspended",
+			"targetUserID", userID,
+		)
+		h.audit(r, "user suspended by admin", &models.AuditEvent{
+			Event:      "user_suspended",
+			ActorID:    &ctx.UserID,
+			TargetType: models.AuditTargetUser,
+			TargetID:   strconv.Itoa(userID),
+			Details:    map[string]any{"email": user.Email},
+		})
+		respondJSON(w, user)
+	}
+}
+
+// AdminUnsuspendUser godoc
+// @Summary Unsuspend a user
+// @Description Lifts the suspension of a specific user as an admin, so they can log in again
+// @Tags Admin
+// @Security CookieAuth
+// @ID adminUnsuspendUser
+// @Produce json
+// @Param userId path int true "User ID"
+// @Success 200 {object} models.User
+// @Failure 400 {object} ErrorResponse "Invalid user ID"
+// @Failure 404 {object} ErrorResponse "User not found"
+// @Failure 500 {object} ErrorResponse "Failed to unsuspend user"
+// @Router /admin/users/{userId}/unsuspend [post]
+func (h *Handler) AdminUnsuspendUser() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := context.GetRequestContext(w, r)
+		if !ok {
+			return
+		}
+		log := getAdminLogger().With(
+			"handler", "AdminUnsuspendUser",
+			"adminID", ctx.UserID,
+			"clientIP", r.RemoteAddr,
+		)
+
+		userID, err := strconv.Atoi(chi.URLParam(r, "userId"))
+		if err != nil {
+			log.Debug("invalid user ID format",
+				"userIDParam", chi.URLParam(r, "userId"),
+				"error", err.Error(),
+			)
+			respondError(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		user, err := h.DB.GetUserByID(userID)
+		if err != nil {
+			log.Debug("user not found",
+				"targetUserID", userID,
+				"error", err.Error(),
+			)
+			respondError(w, "User not found", http.StatusNotFound)
+			return
+		}
+
+		if !user.Suspended() {
+			respondJSON(w, user)
+			return
+		}
+
+		user.SuspendedAt = nil
+		if err := h.DB.UpdateUser(user); err != nil {
+			log.Error("failed to unsuspend user",
+				"error", err.Error(),
+				"targetUserID", userID,
+			)
+			respondError(w, "Failed to unsuspend user", http.StatusInternalServerError)
+			return
+		}
+
+		log.Debug("user unsuspended",
+			"targetUserID", userID,
+		)
+		h.audit(r, "user unsuspended by admin", &models.AuditEvent{
+			Event:      "user_unsuspended",
+			ActorID:    &ctx.UserID,
+			TargetType: models.AuditTargetUser,
+			TargetID:   strconv.Itoa(userID),
+		})
+		respondJSON(w, user)
+	}
+}
+
+// RotateJWTKeyRequest holds the request fields for rotating the JWT signing key
+type RotateJWTKeyRequest struct {
+	Algorithm string `json:"algorithm,omitempty"` // HS256, RS256 or EdDSA; the configured algorithm if empty
+}
+
+// AdminListJWTKeys godoc
+// @Summary List JWT signing keys
+// @Description Lists the keys of the JWT keyring that can still be used to verify tokens
+// @Tags Admin
+// @Security CookieAuth
+// @ID adminListJWTKeys
+// @Produce json
+// @Success 200 {array} models.JWTKey
+// @Failure 500 {object} ErrorResponse "Failed to list JWT keys"
+// @Router /admin/jwt-keys [get]
+func (h *Handler) AdminListJWTKeys(keyring auth.Keyring) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := context.GetRequestContext(w, r)
+		if !ok {
+			return
+		}
+		log := getAdminLogger().With(
+			"handler", "AdminListJWTKeys",
+			"adminID", ctx.UserID,
+			"clientIP", r.RemoteAddr,
+		)
+
+		keys, err := keyring.Keys()
+		if err != nil {
+			log.Error("failed to list JWT keys",
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to list JWT keys", http.StatusInternalServerError)
+			return
+		}
+
+		respondJSON(w, keys)
+	}
+}
+
+// AdminRotateJWTKey godoc
+// @Summary Rotate the JWT signing key
+// @Description Creates a new active JWT signing key. Tokens signed with previous keys stay valid until they expire.
+// @Tags Admin
+// @Security CookieAuth
+// @ID adminRotateJWTKey
+// @Accept json
+// @Produce json
+// @Param body body RotateJWTKeyRequest false "Rotation options"
+// @Success 200 {object} models.JWTKey
+// @Failure 400 {object} ErrorResponse "Invalid request body"
+// @Failure 400 {object} ErrorResponse "Unsupported signing algorithm"
+// @Failure 500 {object} ErrorResponse "Failed to rotate JWT key"
+// @Router /admin/jwt-keys/rotate [post]
+func (h *Handler) AdminRotateJWTKey(keyring auth.Keyring) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := context.GetRequestContext(w, r)
+		if !ok {
+			return
+		}
+		log := getAdminLogger().With(
+			"handler", "AdminRotateJWTKey",
+			"adminID", ctx.UserID,
+			"clientIP", r.RemoteAddr,
+		)
+
+		var req RotateJWTKeyRequest
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				log.Debug("failed to decode request body",
+					"error", err.Error(),
+				)
+				respondError(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+		}
+
+		if req.Algorithm != "" && !auth.ValidAlgorithm(req.Algorithm) {
+			log.Debug("unsupported signing algorithm",
+				"algorithm", req.Algorithm,
+			)
+			respondError(w, "Unsupported signing algorithm", http.StatusBadRequest)
+			return
+		}
+
+		key, err := keyring.Rotate(req.Algorithm)
+		if err != nil {
+			log.Error("failed to rotate JWT key",
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to rotate JWT key", http.StatusInternalServerError)
+			return
+		}
+
+		h.audit(r, "JWT signing key rotated", &models.AuditEvent{
+			Event:      "jwt_key_rotated",
+			ActorID:    &ctx.UserID,
+			TargetType: models.AuditTargetSystem,
+			Details:    map[string]any{"keyID": key.ID, "algorithm": key.Algorithm},
+		})
+		respondJSON(w, key)
+	}
+}
+
 // AdminListWorkspaces godoc
 // @Summary List all workspaces
 // @Description List all workspaces and their stats as an admin
@@ -505,6 +1079,155 @@ func (h *Handler) AdminListWorkspaces() http.HandlerFunc {
 	}
 }
 
+// AdminTransferWorkspace godoc
+// @Summary Transfer a workspace
+// @Description Makes another user the owner of a workspace as an admin. Its files and Git repository are moved to the new owner.
+// @Description Git is disabled and the Git credentials of the previous owner are removed.
+// @Description If the new owner or a remaining member already has access to a workspace with the same name, a number is appended to the name.
+// @Description A previous owner that is left without a workspace gets a new default workspace.
+// @Tags Admin
+// @Security CookieAuth
+// @ID adminTransferWorkspace
+// @Accept json
+// @Produce json
+// @Param workspaceId path int true "Workspace ID"
+// @Param body body TransferWorkspaceRequest true "New owner"
+// @Success 200 {object} models.Workspace
+// @Failure 400 {object} ErrorResponse "Invalid workspace ID"
+// @Failure 400 {object} ErrorResponse "Invalid request body"
+// @Failure 400 {object} ErrorResponse "Invalid role"
+// @Failure 400 {object} ErrorResponse "User already owns the workspace"
+// @Failure 400 {object} ErrorResponse "Cannot transfer to a suspended user"
+// @Failure 404 {object} ErrorResponse "Workspace not found"
+// @Failure 404 {object} ErrorResponse "User not found"
+// @Failure 507 {object} ErrorResponse "Storage quota exceeded"
+// @Failure 500 {object} ErrorResponse "Failed to transfer workspace"
+// @Router /admin/workspaces/{workspaceId}/transfer [post]
+func (h *Handler) AdminTransferWorkspace() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := context.GetRequestContext(w, r)
+		if !ok {
+			return
+		}
+		log := getAdminLogger().With(
+			"handler", "AdminTransferWorkspace",
+			"adminID", ctx.UserID,
+			"clientIP", r.RemoteAddr,
+		)
+
+		workspaceID, err := strconv.Atoi(chi.URLParam(r, "workspaceId"))
+		if err != nil {
+			log.Debug("invalid workspace ID format",
+				"workspaceIDParam", chi.URLParam(r, "workspaceId"),
+				"error", err.Error(),
+			)
+			respondError(w, "Invalid workspace ID", http.StatusBadRequest)
+			return
+		}
+
+		var req TransferWorkspaceRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			log.Debug("failed to decode request body",
+				"error", err.Error(),
+			)
+			respondError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if req.PreviousOwnerRole != "" && (req.PreviousOwnerRole == models.WorkspaceRoleOwner || !req.PreviousOwnerRole.Valid()) {
+			log.Debug("invalid previous owner role",
+				"role", req.PreviousOwnerRole,
+			)
+			respondError(w, "Invalid role", http.StatusBadRequest)
+			return
+		}
+
+		workspace, err := h.DB.GetWorkspaceByID(workspaceID)
+		if err != nil {
+			log.Debug("workspace not found",
+				"workspaceID", workspaceID,
+				"error", err.Error(),
+			)
+			respondError(w, "Workspace not found", http.StatusNotFound)
+			return
+		}
+
+		if workspace.UserID == req.UserID {
+			respondError(w, "User already owns the workspace", http.StatusBadRequest)
+			return
+		}
+
+		newOwner, err := h.DB.GetUserByID(req.UserID)
+		if err != nil {
+			log.Debug("user not found",
+				"targetUserID", req.UserID,
+				"error", err.Error(),
+			)
+			respondError(w, "User not found", http.StatusNotFound)
+			return
+		}
+		if newOwner.Suspended() {
+			respondError(w, "Cannot transfer to a suspended user", http.StatusBadRequest)
+			return
+		}
+
+		// The database is updated while the files are moved, so both are rolled back if either fails
+		previousOwnerID := workspace.UserID
+		var replacement *models.Workspace
+		err = h.Storage.TransferWorkspace(previousOwnerID, newOwner.ID, workspace, func() error {
+			var err error
+			replacement, err = h.DB.TransferWorkspace(workspace, newOwner.ID, req.PreviousOwnerRole)
+			return err
+		})
+		if errors.Is(err, storage.ErrQuotaExceeded) {
+			log.Debug("storage quota exceeded",
+				"workspaceID", workspaceID,
+				"targetUserID", newOwner.ID,
+			)
+			respondError(w, "Storage quota exceeded", http.StatusInsufficientStorage)
+			return
+		}
+		if err != nil {
+			log.Error("failed to transfer workspace",
+				"error", err.Error(),
+				"workspaceID", workspaceID,
+				"targetUserID", newOwner.ID,
+			)
+			respondError(w, "Failed to transfer workspace", http.StatusInternalServerError)
+			return
+		}
+
+		if replacement != nil {
+			if err := h.Storage.InitializeUserWorkspace(replacement.UserID, replacement.ID); err != nil {
+				log.Error("failed to initialize replacement workspace",
+					"error", err.Error(),
+					"workspaceID", replacement.ID,
+					"userID", replacement.UserID,
+				)
+			}
+		}
+
+		log.Debug("workspace transferred",
+			"workspaceID", workspaceID,
+			"previousOwnerID", previousOwnerID,
+			"targetUserID", newOwner.ID,
+		)
+		h.audit(r, "workspace transferred by admin", &models.AuditEvent{
+			Event:       "workspace_transferred",
+			ActorID:     &ctx.UserID,
+			TargetType:  models.AuditTargetWorkspace,
+			TargetID:    strconv.Itoa(workspaceID),
+			WorkspaceID: &workspaceID,
+			Details: map[string]any{
+				"previousOwnerID": previousOwnerID,
+				"newOwnerID":      newOwner.ID,
+				"name":            workspace.Name,
+			},
+		})
+		respondJSON(w, withoutSharedGitToken(workspace, ctx.UserID))
+	}
+}
+
 // AdminGetSystemStats godoc
 // @Summary Get system statistics
 // @Description Get system-wide statistics as an admin
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"testing"
 
+	"lemma/internal/auth"
 	"lemma/internal/handlers"
 	"lemma/internal/models"
 
@@ -134,6 +135,145 @@ func TestAdminHandlers_Integration(t *testing.T) {
 			// Test with non-admin session
 			rr = h.makeRequest(t, http.MethodPut, path, updateReq, h.RegularTestUser)
 			assert.Equal(t, http.StatusForbidden, rr.Code)
+
+			// Later tests create workspaces as the regular user
+			rr = h.makeRequest(t, http.MethodPut, path, handlers.UpdateUserRequest{Role: models.RoleEditor}, h.AdminTestUser)
+			require.Equal(t, http.StatusOK, rr.Code)
+		})
+
+		t.Run("user sessions", func(t *testing.T) {
+			sessionUser := h.createTestUser(t, "adminsessions@test.com", "password123", models.RoleEditor)
+			secondSession, _, err := h.SessionManager.CreateSession(sessionUser.userModel.ID, string(sessionUser.userModel.Role), "second-device", "127.0.0.1")
+			require.NoError(t, err)
+			path := fmt.Sprintf("/api/v1/admin/users/%d/sessions", sessionUser.userModel.ID)
+
+			// List sessions
+			rr := h.makeRequest(t, http.MethodGet, path, nil, h.AdminTestUser)
+			require.Equal(t, http.StatusOK, rr.Code)
+			var sessions []handlers.SessionResponse
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&sessions))
+			assert.Len(t, sessions, 2)
+
+			// Revoke a single session
+			rr = h.makeRequest(t, http.MethodDelete, path+"/"+secondSession.ID, nil, h.AdminTestUser)
+			require.Equal(t, http.StatusNoContent, rr.Code)
+			_, err = h.DB.GetSessionByID(secondSession.ID)
+			assert.Error(t, err)
+
+			// Sessions of other users cannot be revoked through this user
+			rr = h.makeRequest(t, http.MethodDelete, path+"/"+h.RegularTestUser.session.ID, nil, h.AdminTestUser)
+			assert.Equal(t, http.StatusNotFound, rr.Code)
+
+			// Revoke all sessions
+			rr = h.makeRequest(t, http.MethodDelete, path, nil, h.AdminTestUser)
+			require.Equal(t, http.StatusNoContent, rr.Code)
+			rr = h.makeRequest(t, http.MethodGet, "/api/v1/auth/me", nil, sessionUser)
+			assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+			// Admin session is unaffected
+			rr = h.makeRequest(t, http.MethodGet, path, nil, h.AdminTestUser)
+			require.Equal(t, http.StatusOK, rr.Code)
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&sessions))
+			assert.Empty(t, sessions)
+
+			// Non-existent user
+			rr = h.makeRequest(t, http.MethodGet, "/api/v1/admin/users/999999/sessions", nil, h.AdminTestUser)
+			assert.Equal(t, http.StatusNotFound, rr.Code)
+
+			// Test with non-admin session
+			rr = h.makeRequest(t, http.MethodGet, path, nil, h.RegularTestUser)
+			assert.Equal(t, http.StatusForbidden, rr.Code)
+		})
+
+		t.Run("suspend user", func(t *testing.T) {
+			suspendUser := h.createTestUser(t, "suspend@test.com", "password123", models.RoleEditor)
+			path := fmt.Sprintf("/api/v1/admin/users/%d", suspendUser.userModel.ID)
+			login := handlers.LoginRequest{Email: "suspend@test.com", Password: "password123"}
+
+			// Suspending signs the user out and blocks logging in
+			rr := h.makeRequest(t, http.MethodPost, path+"/suspend", nil, h.AdminTestUser)
+			require.Equal(t, http.StatusOK, rr.Code)
+			var suspended models.User
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&suspended))
+			assert.True(t, suspended.Suspended())
+
+			rr = h.makeRequest(t, http.MethodGet, "/api/v1/auth/me", nil, suspendUser)
+			assert.Equal(t, http.StatusUnauthorized, rr.Code)
+			rr = h.makeRequest(t, http.MethodPost, "/api/v1/auth/login", login, nil)
+			assert.Equal(t, http.StatusForbidden, rr.Code)
+
+			// The user and their data are kept
+			rr = h.makeRequest(t, http.MethodGet, path, nil, h.AdminTestUser)
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			// Unsuspending allows logging in again
+			rr = h.makeRequest(t, http.MethodPost, path+"/unsuspend", nil, h.AdminTestUser)
+			require.Equal(t, http.StatusOK, rr.Code)
+			rr = h.makeRequest(t, http.MethodPost, "/api/v1/auth/login", login, nil)
+			assert.Equal(t, http.StatusOK, rr.Code)
+
+			// Admins can't suspend themselves, and other users can't suspend anyone
+			adminPath := fmt.Sprintf("/api/v1/admin/users/%d", h.AdminTestUser.session.UserID)
+			rr = h.makeRequest(t, http.MethodPost, adminPath+"/suspend", nil, h.AdminTestUser)
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+			rr = h.makeRequest(t, http.MethodPost, path+"/suspend", nil, h.RegularTestUser)
+			assert.Equal(t, http.StatusForbidden, rr.Code)
+			rr = h.makeRequest(t, http.MethodPost, "/api/v1/admin/users/999999/suspend", nil, h.AdminTestUser)
+			assert.Equal(t, http.StatusNotFound, rr.Code)
+		})
+
+		t.Run("demoting takes effect for existing sessions", func(t *testing.T) {
+			demoted := h.createTestUser(t, "demoted@test.com", "password123", models.RoleAdmin)
+			path := fmt.Sprintf("/api/v1/admin/users/%d", demoted.userModel.ID)
+			filePath := "/api/v1/workspaces/" + models.DefaultWorkspaceName + "/files/demoted.md"
+
+			rr := h.makeRequest(t, http.MethodGet, "/api/v1/admin/users", nil, demoted)
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			rr = h.makeRequest(t, http.MethodPut, path, handlers.UpdateUserRequest{Role: models.RoleViewer}, h.AdminTestUser)
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			// The tokens of the session still carry the admin role
+			rr = h.makeRequest(t, http.MethodGet, "/api/v1/admin/users", nil, demoted)
+			assert.Equal(t, http.StatusForbidden, rr.Code)
+			rr = h.makeRequest(t, http.MethodPost, filePath, "content", demoted)
+			assert.Equal(t, http.StatusForbidden, rr.Code)
+			rr = h.makeRequest(t, http.MethodGet, "/api/v1/workspaces", nil, demoted)
+			assert.Equal(t, http.StatusOK, rr.Code)
+		})
+
+		t.Run("required password change", func(t *testing.T) {
+			passwordUser := h.createTestUser(t, "mustchange@test.com", "password123", models.RoleEditor)
+			path := fmt.Sprintf("/api/v1/admin/users/%d", passwordUser.userModel.ID)
+
+			mustChange := true
+			rr := h.makeRequest(t, http.MethodPut, path, handlers.UpdateUserRequest{MustChangePassword: &mustChange}, h.AdminTestUser)
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			// Only the password change routes are available
+			rr = h.makeRequest(t, http.MethodGet, "/api/v1/workspaces", nil, passwordUser)
+			assert.Equal(t, http.StatusForbidden, rr.Code)
+			rr = h.makeRequest(t, http.MethodGet, "/api/v1/auth/me", nil, passwordUser)
+			require.Equal(t, http.StatusOK, rr.Code)
+			var me models.User
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&me))
+			assert.True(t, me.MustChangePassword)
+
+			// Keeping the current password is rejected
+			rr = h.makeRequest(t, http.MethodPut, "/api/v1/profile", handlers.UpdateProfileRequest{
+				CurrentPassword: "password123",
+				NewPassword:     "password123",
+			}, passwordUser)
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+			rr = h.makeRequest(t, http.MethodPut, "/api/v1/profile", handlers.UpdateProfileRequest{
+				CurrentPassword: "password123",
+				NewPassword:     "newpassword123",
+			}, passwordUser)
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			rr = h.makeRequest(t, http.MethodGet, "/api/v1/workspaces", nil, passwordUser)
+			assert.Equal(t, http.StatusOK, rr.Code)
 		})
 
 		t.Run("delete user", func(t *testing.T) {
@@ -210,6 +350,61 @@ func TestAdminHandlers_Integration(t *testing.T) {
 			rr = h.makeRequest(t, http.MethodGet, "/api/v1/admin/workspaces", nil, h.RegularTestUser)
 			assert.Equal(t, http.StatusForbidden, rr.Code)
 		})
+
+		t.Run("transfer workspace", func(t *testing.T) {
+			leaving := h.createTestUser(t, "leaving@test.com", "password123", models.RoleEditor)
+			colleague := h.createTestUser(t, "colleague@test.com", "password123", models.RoleEditor)
+			workspaceID := leaving.userModel.LastWorkspaceID
+			transferPath := fmt.Sprintf("/api/v1/admin/workspaces/%d/transfer", workspaceID)
+
+			err := h.Storage.SaveFile(leaving.userModel.ID, workspaceID, "notes.md", []byte("handover"))
+			require.NoError(t, err)
+
+			rr := h.makeRequest(t, http.MethodPost, transferPath,
+				handlers.TransferWorkspaceRequest{UserID: colleague.userModel.ID}, h.RegularTestUser)
+			assert.Equal(t, http.StatusForbidden, rr.Code)
+
+			rr = h.makeRequest(t, http.MethodPost, transferPath,
+				handlers.TransferWorkspaceRequest{UserID: leaving.userModel.ID}, h.AdminTestUser)
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+			rr = h.makeRequest(t, http.MethodPost, transferPath,
+				handlers.TransferWorkspaceRequest{UserID: colleague.userModel.ID, PreviousOwnerRole: models.WorkspaceRoleOwner}, h.AdminTestUser)
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+			rr = h.makeRequest(t, http.MethodPost, transferPath,
+				handlers.TransferWorkspaceRequest{UserID: 99999}, h.AdminTestUser)
+			assert.Equal(t, http.StatusNotFound, rr.Code)
+
+			rr = h.makeRequest(t, http.MethodPost, transferPath,
+				handlers.TransferWorkspaceRequest{UserID: colleague.userModel.ID}, h.AdminTestUser)
+			require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+			var workspace models.Workspace
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&workspace))
+			assert.Equal(t, colleague.userModel.ID, workspace.UserID)
+			assert.Equal(t, "Main (2)", workspace.Name)
+
+			content, err := h.Storage.GetFileContent(colleague.userModel.ID, workspaceID, "notes.md")
+			require.NoError(t, err)
+			assert.Equal(t, "handover", string(content))
+
+			// The files are available to the new owner under the new name
+			rr = h.makeRequest(t, http.MethodGet, "/api/v1/workspaces/Main%20%282%29/files/notes.md", nil, colleague)
+			require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+			assert.Equal(t, "handover", rr.Body.String())
+
+			// The previous owner lost access and got a new default workspace
+			workspaces, err := h.DB.GetWorkspacesByMemberID(leaving.userModel.ID)
+			require.NoError(t, err)
+			require.Len(t, workspaces, 1)
+			assert.NotEqual(t, workspaceID, workspaces[0].ID)
+			assert.Equal(t, models.DefaultWorkspaceName, workspaces[0].Name)
+
+			rr = h.makeRequest(t, http.MethodPost, "/api/v1/admin/workspaces/99999/transfer",
+				handlers.TransferWorkspaceRequest{UserID: colleague.userModel.ID}, h.AdminTestUser)
+			assert.Equal(t, http.StatusNotFound, rr.Code)
+		})
 	})
 
 	t.Run("system stats", func(t *testing.T) {
@@ -240,4 +435,56 @@ func TestAdminHandlers_Integration(t *testing.T) {
 		rr = h.makeRequest(t, http.MethodGet, "/api/v1/admin/stats", nil, h.RegularTestUser)
 		assert.Equal(t, http.StatusForbidden, rr.Code)
 	})
+
+	t.Run("jwt key rotation", func(t *testing.T) {
+		rr := h.makeRequest(t, http.MethodGet, "/api/v1/admin/jwt-keys", nil, h.AdminTestUser)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var keys []*models.JWTKey
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&keys))
+		require.Len(t, keys, 1)
+		assert.True(t, keys[0].Active)
+		assert.Equal(t, auth.AlgorithmHS256, keys[0].Algorithm)
+		assert.NotContains(t, rr.Body.String(), "private")
+
+		// Test with non-admin session
+		rr = h.makeRequest(t, http.MethodPost, "/api/v1/admin/jwt-keys/rotate", nil, h.RegularTestUser)
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+
+		rr = h.makeRequest(t, http.MethodPost, "/api/v1/admin/jwt-keys/rotate",
+			handlers.RotateJWTKeyRequest{Algorithm: "none"}, h.AdminTestUser)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+		rr = h.makeRequest(t, http.MethodPost, "/api/v1/admin/jwt-keys/rotate",
+			handlers.RotateJWTKeyRequest{Algorithm: auth.AlgorithmEdDSA}, h.AdminTestUser)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var rotated models.JWTKey
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&rotated))
+		assert.True(t, rotated.Active)
+		assert.Equal(t, auth.AlgorithmEdDSA, rotated.Algorithm)
+		assert.NotEqual(t, keys[0].ID, rotated.ID)
+
+		// Tokens signed with the previous key remain valid
+		rr = h.makeRequest(t, http.MethodGet, "/api/v1/auth/me", nil, h.RegularTestUser)
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		// The public key is published for verification by other services
+		rr = h.makeRequest(t, http.MethodGet, "/api/v1/auth/jwks", nil, nil)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var jwks auth.JWKSet
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&jwks))
+		require.Len(t, jwks.Keys, 1)
+		assert.Equal(t, rotated.ID, jwks.Keys[0].KeyID)
+		assert.Equal(t, "OKP", jwks.Keys[0].KeyType)
+
+		rr = h.makeRequest(t, http.MethodGet, "/api/v1/admin/jwt-keys", nil, h.AdminTestUser)
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&keys))
+		require.Len(t, keys, 2)
+		assert.Equal(t, rotated.ID, keys[0].ID)
+		assert.False(t, keys[1].Active)
+		assert.NotNil(t, keys[1].RetiredAt)
+	})
 }
@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"lemma/internal/context"
+	"lemma/internal/models"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+const (
+	defaultAuditEventLimit = 50
+	maxAuditEventLimit     = 500
+)
+
+// AuditEventsResponse is a page of audit events
+type AuditEventsResponse struct {
+	Events []*models.AuditEvent `json:"events"`
+	Total  int                  `json:"total"` // Number of events matching the filter
+}
+
+// audit writes a security relevant event to the audit log and stores it in the database.
+// The client IP and request ID are taken from the request. Failing to store the event
+// is logged instead of failing the request, as the action has already been performed.
+func (h *Handler) audit(r *http.Request, msg string, event *models.AuditEvent) {
+	event.IPAddress = getClientIP(r)
+	event.RequestID = middleware.GetReqID(r.Context())
+
+	args := []any{"event", event.Event}
+	if event.ActorID != nil {
+		args = append(args, "actorID", *event.ActorID)
+	}
+	if event.TargetType != "" {
+		args = append(args, "targetType", event.TargetType, "targetID", event.TargetID)
+	}
+	if event.WorkspaceID != nil {
+		args = append(args, "workspaceID", *event.WorkspaceID)
+	}
+	keys := make([]string, 0, len(event.Details))
+	for key := range event.Details {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		args = append(args, key, event.Details[key])
+	}
+	args = append(args, "clientIP", event.IPAddress, "requestID", event.RequestID)
+
+	log := getAuditLogger()
+	log.Info(msg, args...)
+
+	if err := h.DB.CreateAuditEvent(event); err != nil {
+		log.Error("failed to store audit event",
+			"event", event.Event,
+			"requestID", event.RequestID,
+			"error", err.Error(),
+		)
+	}
+}
+
+// updatedFields returns the sorted names of the updated fields, without their values
+func updatedFields(updates map[string]interface{}) []string {
+	fields := make([]string, 0, len(updates))
+	for field := range updates {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// parseAuditEventFilter reads the audit event filter from the query parameters
+func parseAuditEventFilter(r *http.Request) (models.AuditEventFilter, string, bool) {
+	query := r.URL.Query()
+	filter := models.AuditEventFilter{
+		Event:      query.Get("event"),
+		TargetType: models.AuditTargetType(query.Get("targetType")),
+		TargetID:   query.Get("targetId"),
+		Limit:      defaultAuditEventLimit,
+	}
+
+	parseID := func(name string) (*int, bool) {
+		value := query.Get(name)
+		if value == "" {
+			return nil, true
+		}
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, false
+		}
+		return &id, true
+	}
+	parseTime := func(name string) (*time.Time, bool) {
+		value := query.Get(name)
+		if value == "" {
+			return nil, true
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, false
+		}
+		return &t, true
+	}
+
+	var ok bool
+	if filter.ActorID, ok = parseID("actorId"); !ok {
+		return filter, "actorId", false
+	}
+	if filter.WorkspaceID, ok = parseID("workspaceId"); !ok {
+		return filter, "workspaceId", false
+	}
+	if filter.Since, ok = parseTime("since"); !ok {
+		return filter, "since", false
+	}
+	if filter.Until, ok = parseTime("until"); !ok {
+		return filter, "until", false
+	}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxAuditEventLimit {
+			return filter, "limit", false
+		}
+		filter.Limit = limit
+	}
+	if value := query.Get("offset"); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			return filter, "offset", false
+		}
+		filter.Offset = offset
+	}
+
+	return filter, "", true
+}
+
+// AdminListAuditEvents godoc
+// @Summary List audit events
+// @Description Returns a page of the audit log, newest first. Events can be filtered by type, actor, target, workspace and time.
+// @Tags Admin
+// @Security CookieAuth
+// @ID adminListAuditEvents
+// @Produce json
+// @Param event query string false "Event type, e.g. login_failed"
+// @Param actorId query int false "ID of the user who performed the action"
+// @Param targetType query string false "Type of the target" Enums(user, workspace, session, file, invitation, share_link, system)
+// @Param targetId query string false "ID of the target, the path for files"
+// @Param workspaceId query int false "Workspace ID"
+// @Param since query string false "Only events at or after this time (RFC 3339)"
+// @Param until query string false "Only events before this time (RFC 3339)"
+// @Param limit query int false "Maximum number of events, 50 by default and at most 500"
+// @Param offset query int false "Number of events to skip"
+// @Success 200 {object} AuditEventsResponse
+// @Failure 400 {object} ErrorResponse "Invalid query parameter"
+// @Failure 500 {object} ErrorResponse "Failed to list audit events"
+// @Router /admin/audit-events [get]
+func (h *Handler) AdminListAuditEvents() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := context.GetRequestContext(w, r)
+		if !ok {
+			return
+		}
+		log := getAdminLogger().With(
+			"handler", "AdminListAuditEvents",
+			"adminID", ctx.UserID,
+			"clientIP", r.RemoteAddr,
+		)
+
+		filter, param, ok := parseAuditEventFilter(r)
+		if !ok {
+			log.Debug("invalid query parameter",
+				"param", param,
+				"value", r.URL.Query().Get(param),
+			)
+			respondError(w, "Invalid query parameter: "+param, http.StatusBadRequest)
+			return
+		}
+
+		events, total, err := h.DB.GetAuditEvents(filter)
+		if err != nil {
+			log.Error("failed to fetch audit events from database",
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to list audit events", http.StatusInternalServerError)
+			return
+		}
+
+		respondJSON(w, AuditEventsResponse{Events: events, Total: total})
+	}
+}
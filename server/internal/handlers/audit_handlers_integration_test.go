@@ -0,0 +1,104 @@
+//go:build integration
+
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"lemma/internal/handlers"
+	"lemma/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditHandlers_Integration(t *testing.T) {
+	h := setupTestHarness(t)
+	defer h.teardown(t)
+
+	listEvents := func(t *testing.T, query string) handlers.AuditEventsResponse {
+		t.Helper()
+		rr := h.makeRequest(t, http.MethodGet, "/api/v1/admin/audit-events"+query, nil, h.AdminTestUser)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var response handlers.AuditEventsResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+		return response
+	}
+
+	t.Run("records failed logins", func(t *testing.T) {
+		loginReq := handlers.LoginRequest{Email: "unknown@test.com", Password: "wrongpassword"}
+		rr := h.makeRequest(t, http.MethodPost, "/api/v1/auth/login", loginReq, nil)
+		require.Equal(t, http.StatusUnauthorized, rr.Code)
+
+		response := listEvents(t, "?event=login_failed")
+		require.Equal(t, 1, response.Total)
+		event := response.Events[0]
+		assert.Nil(t, event.ActorID)
+		assert.Equal(t, "unknown@test.com", event.Details["email"])
+		assert.NotEmpty(t, event.IPAddress)
+		assert.NotEmpty(t, event.RequestID)
+	})
+
+	t.Run("records file deletions", func(t *testing.T) {
+		workspace := h.RegularTestUser.userModel.LastWorkspaceID
+		filesURL := "/api/v1/workspaces/Main/files"
+		rr := h.makeRequestRaw(t, http.MethodPost, filesURL+"/audit.md", strings.NewReader("# Audit"), h.RegularTestUser)
+		require.Equal(t, http.StatusOK, rr.Code)
+		rr = h.makeRequest(t, http.MethodDelete, filesURL+"/audit.md", nil, h.RegularTestUser)
+		require.Equal(t, http.StatusNoContent, rr.Code)
+
+		response := listEvents(t, "?targetType=file&targetId=audit.md")
+		require.Equal(t, 1, response.Total)
+		event := response.Events[0]
+		assert.Equal(t, "file_deleted", event.Event)
+		require.NotNil(t, event.ActorID)
+		assert.Equal(t, h.RegularTestUser.session.UserID, *event.ActorID)
+		require.NotNil(t, event.WorkspaceID)
+		assert.Equal(t, workspace, *event.WorkspaceID)
+	})
+
+	t.Run("records role changes", func(t *testing.T) {
+		userPath := fmt.Sprintf("/api/v1/admin/users/%d", h.RegularTestUser.session.UserID)
+		rr := h.makeRequest(t, http.MethodPut, userPath, handlers.UpdateUserRequest{Role: models.RoleViewer}, h.AdminTestUser)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		response := listEvents(t, "?event=user_updated&actorId="+strconv.Itoa(h.AdminTestUser.session.UserID))
+		require.Equal(t, 1, response.Total)
+		event := response.Events[0]
+		assert.Equal(t, models.AuditTargetUser, event.TargetType)
+		assert.Equal(t, strconv.Itoa(h.RegularTestUser.session.UserID), event.TargetID)
+		assert.Equal(t, string(models.RoleViewer), event.Details["role"])
+		assert.Equal(t, string(models.RoleEditor), event.Details["previousRole"])
+
+		rr = h.makeRequest(t, http.MethodPut, userPath, handlers.UpdateUserRequest{Role: models.RoleEditor}, h.AdminTestUser)
+		require.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("pages events", func(t *testing.T) {
+		all := listEvents(t, "")
+		require.GreaterOrEqual(t, all.Total, 3)
+
+		page := listEvents(t, "?limit=1&offset=1")
+		assert.Equal(t, all.Total, page.Total)
+		require.Len(t, page.Events, 1)
+		assert.Equal(t, all.Events[1].ID, page.Events[0].ID)
+	})
+
+	t.Run("rejects invalid filters", func(t *testing.T) {
+		for _, query := range []string{"?actorId=abc", "?since=yesterday", "?limit=0", "?limit=1000", "?offset=-1"} {
+			rr := h.makeRequest(t, http.MethodGet, "/api/v1/admin/audit-events"+query, nil, h.AdminTestUser)
+			assert.Equal(t, http.StatusBadRequest, rr.Code, query)
+		}
+	})
+
+	t.Run("requires admin", func(t *testing.T) {
+		rr := h.makeRequest(t, http.MethodGet, "/api/v1/admin/audit-events", nil, h.RegularTestUser)
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+}
@@ -4,14 +4,17 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"lemma/internal/auth"
 	"lemma/internal/context"
 	"lemma/internal/logging"
 	"lemma/internal/models"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
-	"golang.org/x/crypto/bcrypt"
+	"github.com/go-chi/chi/v5"
 )
 
 // LoginRequest represents a user login request
@@ -27,13 +30,20 @@ type LoginResponse struct {
 	ExpiresAt time.Time    `json:"expiresAt,omitempty"`
 }
 
+// SessionResponse represents an active session of a user
+type SessionResponse struct {
+	*models.Session
+	Current bool `json:"current"` // Whether this is the session used to make the request
+}
+
 func getAuthLogger() logging.Logger {
 	return getHandlersLogger().WithGroup("auth")
 }
 
 // Login godoc
 // @Summary Login
-// @Description Logs in a user and returns a session with access and refresh tokens
+// @Description Logs in a user and returns a session with access and refresh tokens.
+// @Description If LDAP is configured, directory users are authenticated against the directory.
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -43,10 +53,16 @@ func getAuthLogger() logging.Logger {
 // @Failure 400 {object} ErrorResponse "Invalid request body"
 // @Failure 400 {object} ErrorResponse "Email and password are required"
 // @Failure 401 {object} ErrorResponse "Invalid credentials"
+// @Failure 403 {object} ErrorResponse "Account suspended"
+// @Failure 403 {object} ErrorResponse "Email address not verified"
+// @Failure 429 {object} ErrorResponse "Too many failed login attempts"
+// @Header 429 {string} Retry-After "Seconds until the next login attempt is allowed"
+// @Failure 500 {object} ErrorResponse "Failed to check login attempts"
+// @Failure 500 {object} ErrorResponse "Failed to authenticate"
 // @Failure 500 {object} ErrorResponse "Failed to create session"
 // @Failure 500 {object} ErrorResponse "Failed to generate CSRF token"
 // @Router /auth/login [post]
-func (h *Handler) Login(authManager auth.SessionManager, cookieService auth.CookieManager) http.HandlerFunc {
+func (h *Handler) Login(authenticator auth.Authenticator, authManager auth.SessionManager, cookieService auth.CookieManager, loginLimiter auth.LoginLimiter) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		log := getAuthLogger().With(
 			"handler", "Login",
@@ -71,27 +87,79 @@ func (h *Handler) Login(authManager auth.SessionManager, cookieService auth.Cook
 			return
 		}
 
-		user, err := h.DB.GetUserByEmail(req.Email)
+		clientIP := getClientIP(r)
+		retryAfter, err := loginLimiter.Check(req.Email, clientIP)
 		if err != nil {
-			log.Debug("user not found",
+			log.Error("failed to check login attempts",
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to check login attempts", http.StatusInternalServerError)
+			return
+		}
+		if retryAfter > 0 {
+			log.Warn("login attempt throttled",
+				"email", req.Email,
+				"retryAfter", retryAfter,
+			)
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			respondError(w, "Too many failed login attempts", http.StatusTooManyRequests)
+			return
+		}
+
+		user, err := authenticator.Authenticate(req.Email, req.Password)
+		if errors.Is(err, auth.ErrUnknownUser) || errors.Is(err, auth.ErrInvalidCredentials) {
+			log.Warn("invalid login attempt",
 				"email", req.Email,
 				"error", err.Error(),
 			)
+			h.recordFailedLogin(r, loginLimiter, req.Email, clientIP)
+			h.audit(r, "login failed", &models.AuditEvent{
+				Event:   "login_failed",
+				Details: map[string]any{"email": req.Email},
+			})
 			respondError(w, "Invalid credentials", http.StatusUnauthorized)
 			return
 		}
-
-		err = bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password))
 		if err != nil {
-			log.Warn("invalid password attempt",
+			log.Error("failed to authenticate",
+				"email", req.Email,
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to authenticate", http.StatusInternalServerError)
+			return
+		}
+
+		if user.Suspended() {
+			log.Warn("login attempt of suspended user",
 				"userID", user.ID,
-				"email", user.Email,
 			)
-			respondError(w, "Invalid credentials", http.StatusUnauthorized)
+			h.audit(r, "login of suspended user rejected", &models.AuditEvent{
+				Event:      "login_failed",
+				ActorID:    &user.ID,
+				TargetType: models.AuditTargetUser,
+				TargetID:   strconv.Itoa(user.ID),
+				Details:    map[string]any{"email": req.Email, "reason": "suspended"},
+			})
+			respondError(w, "Account suspended", http.StatusForbidden)
+			return
+		}
+
+		if user.EmailVerificationPending {
+			log.Debug("login attempt before email verification",
+				"userID", user.ID,
+			)
+			respondError(w, "Email address not verified", http.StatusForbidden)
 			return
 		}
 
-		session, accessToken, err := authManager.CreateSession(user.ID, string(user.Role))
+		if err := loginLimiter.RecordSuccess(req.Email); err != nil {
+			log.Warn("failed to reset failed login attempts",
+				"userID", user.ID,
+				"error", err.Error(),
+			)
+		}
+
+		session, accessToken, err := authManager.CreateSession(user.ID, string(user.Role), r.UserAgent(), getClientIP(r))
 		if err != nil {
 			log.Error("failed to create session",
 				"error", err.Error(),
@@ -130,10 +198,42 @@ func (h *Handler) Login(authManager auth.SessionManager, cookieService auth.Cook
 			"role", user.Role,
 			"sessionID", session.ID,
 		)
+		h.audit(r, "user logged in", &models.AuditEvent{
+			Event:      "login",
+			ActorID:    &user.ID,
+			TargetType: models.AuditTargetSession,
+			TargetID:   session.ID,
+		})
 		respondJSON(w, response)
 	}
 }
 
+// recordFailedLogin counts a failed login attempt and audits the lockouts it causes.
+// Errors are logged instead of failing the request.
+func (h *Handler) recordFailedLogin(r *http.Request, loginLimiter auth.LoginLimiter, email, clientIP string) {
+	lockouts, err := loginLimiter.RecordFailure(email, clientIP)
+	if err != nil {
+		getAuthLogger().Error("failed to record failed login attempt",
+			"email", email,
+			"clientIP", clientIP,
+			"error", err.Error(),
+		)
+		return
+	}
+
+	for _, lockout := range lockouts {
+		h.audit(r, "login locked out after repeated failures", &models.AuditEvent{
+			Event: "login_locked",
+			Details: map[string]any{
+				"scope":       lockout.Scope,
+				"key":         lockout.Key,
+				"failedCount": lockout.FailedCount,
+				"lockedUntil": lockout.LockedUntil,
+			},
+		})
+	}
+}
+
 // Logout godoc
 // @Summary Logout
 // @Description Log out invalidates the user's session
@@ -177,16 +277,19 @@ func (h *Handler) Logout(authManager auth.SessionManager, cookieService auth.Coo
 		http.SetCookie(w, cookieService.InvalidateCookie("refresh_token"))
 		http.SetCookie(w, cookieService.InvalidateCookie("csrf_token"))
 
-		log.Info("user logged out successfully",
-			"sessionID", sessionCookie.Value,
-		)
+		h.audit(r, "user logged out", &models.AuditEvent{
+			Event:      "logout",
+			ActorID:    &ctx.UserID,
+			TargetType: models.AuditTargetSession,
+			TargetID:   ctx.SessionID,
+		})
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
 
 // RefreshToken godoc
 // @Summary Refresh token
-// @Description Refreshes the access token using the refresh token
+// @Description Refreshes the access token using the refresh token. The refresh token is rotated on every use; reusing an already rotated refresh token revokes the session, unless it was rotated in the last few seconds, in which case the current refresh token is returned.
 // @Tags auth
 // @ID refreshToken
 // @Accept json
@@ -213,7 +316,28 @@ func (h *Handler) RefreshToken(authManager auth.SessionManager, cookieService au
 			return
 		}
 
-		accessToken, err := authManager.RefreshSession(refreshCookie.Value)
+		accessToken, refreshToken, err := authManager.RefreshSession(refreshCookie.Value)
+		if errors.Is(err, auth.ErrRefreshTokenReused) {
+			log.Warn("security event: reused refresh token presented, session revoked",
+				"userAgent", r.UserAgent(),
+			)
+			event := &models.AuditEvent{
+				Event:   "refresh_token_reused",
+				Details: map[string]any{"userAgent": r.UserAgent()},
+			}
+			var reused *auth.RefreshTokenReusedError
+			if errors.As(err, &reused) {
+				event.TargetType = models.AuditTargetSession
+				event.TargetID = reused.SessionID
+				event.Details["userID"] = reused.UserID
+			}
+			h.audit(r, "reused refresh token presented, session revoked", event)
+			http.SetCookie(w, cookieService.InvalidateCookie("access_token"))
+			http.SetCookie(w, cookieService.InvalidateCookie("refresh_token"))
+			http.SetCookie(w, cookieService.InvalidateCookie("csrf_token"))
+			respondError(w, "Invalid refresh token", http.StatusUnauthorized)
+			return
+		}
 		if err != nil {
 			log.Error("failed to refresh session",
 				"error", err.Error(),
@@ -233,6 +357,7 @@ func (h *Handler) RefreshToken(authManager auth.SessionManager, cookieService au
 		csrfTokenString := hex.EncodeToString(csrfToken)
 
 		http.SetCookie(w, cookieService.GenerateAccessTokenCookie(accessToken))
+		http.SetCookie(w, cookieService.GenerateRefreshTokenCookie(refreshToken))
 		http.SetCookie(w, cookieService.GenerateCSRFCookie(csrfTokenString))
 
 		w.Header().Set("X-CSRF-Token", csrfTokenString)
@@ -240,6 +365,35 @@ func (h *Handler) RefreshToken(authManager auth.SessionManager, cookieService au
 	}
 }
 
+// GetJWKS godoc
+// @Summary Get JSON Web Key Set
+// @Description Returns the public keys used to sign tokens, allowing other services to verify them. Only keys with asymmetric algorithms are included.
+// @Tags auth
+// @ID getJWKS
+// @Produce json
+// @Success 200 {object} auth.JWKSet
+// @Failure 500 {object} ErrorResponse "Failed to get public keys"
+// @Router /auth/jwks [get]
+func (h *Handler) GetJWKS(keyring auth.Keyring) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := getAuthLogger().With(
+			"handler", "GetJWKS",
+			"clientIP", r.RemoteAddr,
+		)
+
+		jwks, err := keyring.JWKS()
+		if err != nil {
+			log.Error("failed to get public keys",
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to get public keys", http.StatusInternalServerError)
+			return
+		}
+
+		respondJSON(w, jwks)
+	}
+}
+
 // GetCurrentUser godoc
 // @Summary Get current user
 // @Description Returns the current authenticated user
@@ -274,3 +428,149 @@ func (h *Handler) GetCurrentUser() http.HandlerFunc {
 		respondJSON(w, user)
 	}
 }
+
+// ListSessions godoc
+// @Summary List sessions
+// @Description Returns the active sessions of the current user
+// @Tags auth
+// @ID listSessions
+// @Security CookieAuth
+// @Produce json
+// @Success 200 {array} SessionResponse
+// @Failure 500 {object} ErrorResponse "Failed to list sessions"
+// @Router /auth/sessions [get]
+func (h *Handler) ListSessions() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := context.GetRequestContext(w, r)
+		if !ok {
+			return
+		}
+		log := getAuthLogger().With(
+			"handler", "ListSessions",
+			"userID", ctx.UserID,
+			"clientIP", r.RemoteAddr,
+		)
+
+		sessions, err := h.DB.GetSessionsByUserID(ctx.UserID)
+		if err != nil {
+			log.Error("failed to fetch sessions from database",
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to list sessions", http.StatusInternalServerError)
+			return
+		}
+
+		respondJSON(w, newSessionResponses(sessions, ctx.SessionID))
+	}
+}
+
+// RevokeSession godoc
+// @Summary Revoke session
+// @Description Revokes one of the current user's sessions. Revoking the current session logs the user out.
+// @Tags auth
+// @ID revokeSession
+// @Security CookieAuth
+// @Param sessionId path string true "Session ID"
+// @Success 204 "No Content - Session revoked successfully"
+// @Failure 404 {object} ErrorResponse "Session not found"
+// @Failure 500 {object} ErrorResponse "Failed to revoke session"
+// @Router /auth/sessions/{sessionId} [delete]
+func (h *Handler) RevokeSession(cookieService auth.CookieManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := context.GetRequestContext(w, r)
+		if !ok {
+			return
+		}
+		log := getAuthLogger().With(
+			"handler", "RevokeSession",
+			"userID", ctx.UserID,
+			"clientIP", r.RemoteAddr,
+		)
+
+		sessionID := chi.URLParam(r, "sessionId")
+		session, err := h.DB.GetSessionByID(sessionID)
+		if err != nil || session.UserID != ctx.UserID {
+			log.Debug("session not found",
+				"sessionID", sessionID,
+			)
+			respondError(w, "Session not found", http.StatusNotFound)
+			return
+		}
+
+		if err := h.DB.DeleteSession(sessionID); err != nil {
+			log.Error("failed to delete session",
+				"error", err.Error(),
+				"sessionID", sessionID,
+			)
+			respondError(w, "Failed to revoke session", http.StatusInternalServerError)
+			return
+		}
+
+		if sessionID == ctx.SessionID {
+			http.SetCookie(w, cookieService.InvalidateCookie("access_token"))
+			http.SetCookie(w, cookieService.InvalidateCookie("refresh_token"))
+			http.SetCookie(w, cookieService.InvalidateCookie("csrf_token"))
+		}
+
+		h.audit(r, "session revoked", &models.AuditEvent{
+			Event:      "session_revoked",
+			ActorID:    &ctx.UserID,
+			TargetType: models.AuditTargetSession,
+			TargetID:   sessionID,
+			Details:    map[string]any{"userID": ctx.UserID, "current": sessionID == ctx.SessionID},
+		})
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// RevokeOtherSessions godoc
+// @Summary Revoke other sessions
+// @Description Revokes all sessions of the current user except the one used to make the request
+// @Tags auth
+// @ID revokeOtherSessions
+// @Security CookieAuth
+// @Success 204 "No Content - Sessions revoked successfully"
+// @Failure 500 {object} ErrorResponse "Failed to revoke sessions"
+// @Router /auth/sessions [delete]
+func (h *Handler) RevokeOtherSessions() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := context.GetRequestContext(w, r)
+		if !ok {
+			return
+		}
+		log := getAuthLogger().With(
+			"handler", "RevokeOtherSessions",
+			"userID", ctx.UserID,
+			"clientIP", r.RemoteAddr,
+		)
+
+		if err := h.DB.DeleteUserSessions(ctx.UserID, ctx.SessionID); err != nil {
+			log.Error("failed to delete sessions",
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to revoke sessions", http.StatusInternalServerError)
+			return
+		}
+
+		h.audit(r, "other sessions revoked", &models.AuditEvent{
+			Event:      "sessions_revoked",
+			ActorID:    &ctx.UserID,
+			TargetType: models.AuditTargetUser,
+			TargetID:   strconv.Itoa(ctx.UserID),
+			Details:    map[string]any{"exceptSessionID": ctx.SessionID},
+		})
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// newSessionResponses wraps sessions in responses, marking the session with currentSessionID
+func newSessionResponses(sessions []*models.Session, currentSessionID string) []*SessionResponse {
+	responses := make([]*SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		responses = append(responses, &SessionResponse{
+			Session: session,
+			Current: session.ID == currentSessionID,
+		})
+	}
+	return responses
+}
@@ -4,6 +4,7 @@ package handlers_test
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -11,6 +12,8 @@ import (
 	"testing"
 	"time"
 
+	"lemma/internal/auth"
+	"lemma/internal/auth/ldaptest"
 	"lemma/internal/handlers"
 	"lemma/internal/models"
 
@@ -142,13 +145,140 @@ func TestAuthHandlers_Integration(t *testing.T) {
 				})
 			}
 		})
+
+		t.Run("brute-force protection", func(t *testing.T) {
+			lockedUser := h.createTestUser(t, "locked@test.com", "password123", models.RoleEditor)
+
+			login := func(password, remoteAddr string) *httptest.ResponseRecorder {
+				req := h.newRequest(t, http.MethodPost, "/api/v1/auth/login", handlers.LoginRequest{
+					Email:    lockedUser.userModel.Email,
+					Password: password,
+				})
+				req.RemoteAddr = remoteAddr
+				return h.executeRequest(req)
+			}
+
+			// Repeated failures throttle further attempts, even with the correct password
+			for i := 0; i < 3; i++ {
+				rr := login("wrongpassword", "198.51.100.1:1234")
+				require.Equal(t, http.StatusUnauthorized, rr.Code)
+			}
+
+			rr := login("password123", "198.51.100.2:1234")
+			assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+			assert.NotEmpty(t, rr.Header().Get("Retry-After"))
+			assert.Empty(t, rr.Result().Cookies(), "throttled login should not set cookies")
+
+			// Only admins can unlock users
+			path := fmt.Sprintf("/api/v1/admin/users/%d/unlock", lockedUser.userModel.ID)
+			rr = h.makeRequest(t, http.MethodPost, path, nil, h.RegularTestUser)
+			assert.Equal(t, http.StatusForbidden, rr.Code)
+
+			rr = h.makeRequest(t, http.MethodPost, "/api/v1/admin/users/999999/unlock", nil, h.AdminTestUser)
+			assert.Equal(t, http.StatusNotFound, rr.Code)
+
+			rr = h.makeRequest(t, http.MethodPost, path, nil, h.AdminTestUser)
+			require.Equal(t, http.StatusNoContent, rr.Code)
+
+			rr = login("password123", "198.51.100.2:1234")
+			assert.Equal(t, http.StatusOK, rr.Code)
+
+			// Failures from the same IP are still throttled after the account was unlocked
+			rr = login("password123", "198.51.100.1:1234")
+			assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+		})
+
+		t.Run("lockouts are audited", func(t *testing.T) {
+			email := "lockout@test.com"
+			for i := 0; i < 9; i++ {
+				_, err := h.LoginLimiter.RecordFailure(email, "198.51.100.3")
+				require.NoError(t, err)
+			}
+
+			// Let the progressive delay pass, so the next failure is counted
+			_, err := h.DB.TestDB().Exec("UPDATE login_attempts SET last_failed_at = ? WHERE key IN (?, ?)",
+				time.Now().Add(-5*time.Minute), email, "198.51.100.3")
+			require.NoError(t, err)
+
+			req := h.newRequest(t, http.MethodPost, "/api/v1/auth/login", handlers.LoginRequest{
+				Email:    email,
+				Password: "wrongpassword",
+			})
+			req.RemoteAddr = "198.51.100.3:1234"
+			rr := h.executeRequest(req)
+			require.Equal(t, http.StatusUnauthorized, rr.Code)
+
+			events, _, err := h.DB.GetAuditEvents(models.AuditEventFilter{Event: "login_locked", Limit: 10})
+			require.NoError(t, err)
+			require.Len(t, events, 1)
+			assert.Equal(t, "email", events[0].Details["scope"])
+			assert.Equal(t, email, events[0].Details["key"])
+			assert.Equal(t, "198.51.100.3", events[0].IPAddress)
+		})
+
+		t.Run("ldap login", func(t *testing.T) {
+			server, err := ldaptest.NewServer(ldaptest.Entry{
+				DN:       "uid=dave,ou=people,dc=example,dc=com",
+				Password: "directorypass",
+				Attributes: map[string][]string{
+					"mail":     {"dave@example.com"},
+					"cn":       {"Dave"},
+					"memberOf": {"cn=readers,ou=groups,dc=example,dc=com"},
+				},
+			})
+			require.NoError(t, err)
+			defer server.Close()
+
+			ldapAuthenticator := auth.NewLDAPAuthenticator(auth.LDAPConfig{
+				URL:         server.URL,
+				BaseDN:      "ou=people,dc=example,dc=com",
+				ViewerGroup: "cn=readers,ou=groups,dc=example,dc=com",
+			}, h.DB, h.Storage)
+
+			localAuthenticator := h.Authenticator
+			h.Authenticator = auth.NewChainAuthenticator(ldapAuthenticator, localAuthenticator)
+			h.restartServer()
+			defer func() {
+				h.Authenticator = localAuthenticator
+				h.restartServer()
+			}()
+
+			login := func(email, password string) *httptest.ResponseRecorder {
+				req := h.newRequest(t, http.MethodPost, "/api/v1/auth/login", handlers.LoginRequest{
+					Email:    email,
+					Password: password,
+				})
+				req.RemoteAddr = "198.51.100.10:1234"
+				return h.executeRequest(req)
+			}
+
+			// The first login creates the user with the role of its directory groups
+			rr := login("dave@example.com", "directorypass")
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var resp handlers.LoginResponse
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+			assert.Equal(t, "dave@example.com", resp.User.Email)
+			assert.Equal(t, "Dave", resp.User.DisplayName)
+			assert.Equal(t, models.RoleViewer, resp.User.Role)
+
+			rr = login("dave@example.com", "wrongpassword")
+			assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+			// Users outside the directory still log in with their local password
+			rr = login("user@test.com", "user123")
+			assert.Equal(t, http.StatusOK, rr.Code)
+		})
 	})
 
 	t.Run("refresh token", func(t *testing.T) {
 		t.Run("successful token refresh", func(t *testing.T) {
+			refreshUser := h.createTestUser(t, "refresh@test.com", "password123", models.RoleEditor)
+			oldRefreshToken := refreshUser.session.RefreshToken
+
 			// Need lower level helpers for precise cookie control
 			req := h.newRequest(t, http.MethodPost, "/api/v1/auth/refresh", nil)
-			h.addAuthCookies(t, req, h.RegularTestUser) // Adds both tokens
+			h.addAuthCookies(t, req, refreshUser) // Adds both tokens
 			h.addCSRFCookie(t, req)
 			rr := h.executeRequest(req)
 			require.Equal(t, http.StatusOK, rr.Code)
@@ -156,6 +286,7 @@ func TestAuthHandlers_Integration(t *testing.T) {
 			// Verify new cookies
 			cookies := rr.Result().Cookies()
 			var foundAccessToken, foundCSRF bool
+			var newRefreshToken string
 			for _, cookie := range cookies {
 				switch cookie.Name {
 				case "access_token":
@@ -165,11 +296,58 @@ func TestAuthHandlers_Integration(t *testing.T) {
 					foundCSRF = true
 					assert.Equal(t, 900, cookie.MaxAge)
 				case "refresh_token":
-					t.Error("refresh token should not be renewed")
+					newRefreshToken = cookie.Value
+					assert.True(t, cookie.HttpOnly, "refresh_token cookie must be HttpOnly")
 				}
 			}
 			assert.True(t, foundAccessToken, "new access_token cookie not found")
 			assert.True(t, foundCSRF, "new csrf_token cookie not found")
+			require.NotEmpty(t, newRefreshToken, "refresh token should be rotated")
+			assert.NotEqual(t, oldRefreshToken, newRefreshToken)
+
+			// The rotated token keeps the session alive
+			session, err := h.DB.GetSessionByRefreshToken(newRefreshToken)
+			require.NoError(t, err)
+			assert.Equal(t, refreshUser.session.ID, session.ID)
+
+			// Refreshing with the old token right after the rotation, as a second tab would,
+			// returns the current refresh token instead of revoking the session
+			req = h.newRequest(t, http.MethodPost, "/api/v1/auth/refresh", nil)
+			req.AddCookie(h.CookieManager.GenerateRefreshTokenCookie(oldRefreshToken))
+			rr = h.executeRequest(req)
+			require.Equal(t, http.StatusOK, rr.Code)
+			for _, cookie := range rr.Result().Cookies() {
+				if cookie.Name == "refresh_token" {
+					assert.Equal(t, newRefreshToken, cookie.Value)
+				}
+			}
+
+			// Reusing the old token after the reuse window revokes the whole token family
+			_, err = h.DB.TestDB().Exec("UPDATE rotated_refresh_tokens SET rotated_at = ? WHERE token = ?",
+				time.Now().Add(-time.Minute), oldRefreshToken)
+			require.NoError(t, err)
+
+			req = h.newRequest(t, http.MethodPost, "/api/v1/auth/refresh", nil)
+			req.AddCookie(h.CookieManager.GenerateRefreshTokenCookie(oldRefreshToken))
+			rr = h.executeRequest(req)
+			assert.Equal(t, http.StatusUnauthorized, rr.Code)
+			for _, cookie := range rr.Result().Cookies() {
+				assert.True(t, cookie.MaxAge < 0, "cookie should be invalidated")
+			}
+
+			_, err = h.DB.GetSessionByID(refreshUser.session.ID)
+			assert.Error(t, err, "session should be revoked after refresh token reuse")
+
+			events, _, err := h.DB.GetAuditEvents(models.AuditEventFilter{Event: "refresh_token_reused", Limit: 10})
+			require.NoError(t, err)
+			require.Len(t, events, 1)
+			assert.Equal(t, models.AuditTargetSession, events[0].TargetType)
+			assert.Equal(t, refreshUser.session.ID, events[0].TargetID)
+
+			req = h.newRequest(t, http.MethodPost, "/api/v1/auth/refresh", nil)
+			req.AddCookie(h.CookieManager.GenerateRefreshTokenCookie(newRefreshToken))
+			rr = h.executeRequest(req)
+			assert.Equal(t, http.StatusUnauthorized, rr.Code)
 		})
 
 		t.Run("refresh token edge cases", func(t *testing.T) {
@@ -296,6 +474,96 @@ func TestAuthHandlers_Integration(t *testing.T) {
 		})
 	})
 
+	t.Run("sessions", func(t *testing.T) {
+		sessionsTestUser := h.createTestUser(t, "sessions@test.com", "password123", models.RoleEditor)
+		otherSession := h.createTestUser(t, "sessions-other@test.com", "password123", models.RoleEditor)
+
+		// Log in a second time to get another session for the same user
+		loginReq := handlers.LoginRequest{Email: "sessions@test.com", Password: "password123"}
+		req := h.newRequest(t, http.MethodPost, "/api/v1/auth/login", loginReq)
+		req.Header.Set("User-Agent", "second-device")
+		rr := h.executeRequest(req)
+		require.Equal(t, http.StatusOK, rr.Code)
+		var loginResp handlers.LoginResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&loginResp))
+		secondSessionID := loginResp.SessionID
+
+		t.Run("list sessions", func(t *testing.T) {
+			rr := h.makeRequest(t, http.MethodGet, "/api/v1/auth/sessions", nil, sessionsTestUser)
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var sessions []handlers.SessionResponse
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&sessions))
+			require.Len(t, sessions, 2)
+
+			for _, session := range sessions {
+				assert.Equal(t, sessionsTestUser.userModel.ID, session.UserID)
+				assert.False(t, session.CreatedAt.IsZero())
+				assert.False(t, session.LastUsedAt.IsZero())
+				switch session.ID {
+				case sessionsTestUser.session.ID:
+					assert.True(t, session.Current)
+				case secondSessionID:
+					assert.False(t, session.Current)
+					assert.Equal(t, "second-device", session.UserAgent)
+					assert.NotEmpty(t, session.IPAddress)
+				default:
+					t.Errorf("unexpected session %s", session.ID)
+				}
+			}
+
+			assert.NotContains(t, rr.Body.String(), sessionsTestUser.session.RefreshToken)
+		})
+
+		t.Run("revoke session of another user", func(t *testing.T) {
+			rr := h.makeRequest(t, http.MethodDelete, "/api/v1/auth/sessions/"+otherSession.session.ID, nil, sessionsTestUser)
+			assert.Equal(t, http.StatusNotFound, rr.Code)
+
+			rr = h.makeRequest(t, http.MethodGet, "/api/v1/auth/me", nil, otherSession)
+			assert.Equal(t, http.StatusOK, rr.Code)
+		})
+
+		t.Run("revoke single session", func(t *testing.T) {
+			rr := h.makeRequest(t, http.MethodDelete, "/api/v1/auth/sessions/"+secondSessionID, nil, sessionsTestUser)
+			require.Equal(t, http.StatusNoContent, rr.Code)
+
+			_, err := h.DB.GetSessionByID(secondSessionID)
+			assert.Error(t, err)
+
+			// The current session is untouched
+			rr = h.makeRequest(t, http.MethodGet, "/api/v1/auth/me", nil, sessionsTestUser)
+			assert.Equal(t, http.StatusOK, rr.Code)
+		})
+
+		t.Run("revoke other sessions", func(t *testing.T) {
+			rr := h.makeRequest(t, http.MethodPost, "/api/v1/auth/login", loginReq, nil)
+			require.Equal(t, http.StatusOK, rr.Code)
+			var resp handlers.LoginResponse
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+
+			rr = h.makeRequest(t, http.MethodDelete, "/api/v1/auth/sessions", nil, sessionsTestUser)
+			require.Equal(t, http.StatusNoContent, rr.Code)
+
+			_, err := h.DB.GetSessionByID(resp.SessionID)
+			assert.Error(t, err)
+
+			rr = h.makeRequest(t, http.MethodGet, "/api/v1/auth/me", nil, sessionsTestUser)
+			assert.Equal(t, http.StatusOK, rr.Code)
+		})
+
+		t.Run("revoke current session", func(t *testing.T) {
+			rr := h.makeRequest(t, http.MethodDelete, "/api/v1/auth/sessions/"+sessionsTestUser.session.ID, nil, sessionsTestUser)
+			require.Equal(t, http.StatusNoContent, rr.Code)
+
+			for _, cookie := range rr.Result().Cookies() {
+				assert.True(t, cookie.MaxAge < 0, "cookie should be invalidated")
+			}
+
+			rr = h.makeRequest(t, http.MethodGet, "/api/v1/auth/me", nil, sessionsTestUser)
+			assert.Equal(t, http.StatusUnauthorized, rr.Code)
+		})
+	})
+
 	t.Run("get current user", func(t *testing.T) {
 
 		getTestUser := h.createTestUser(t, "testgetuser@test.com", "password123", models.RoleEditor)
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"lemma/internal/backup"
+	"lemma/internal/context"
+	"lemma/internal/db"
+	"lemma/internal/models"
+)
+
+// AdminCreateBackup godoc
+// @Summary Create a backup
+// @Description Downloads a gzipped tar archive of the database and all workspace files, with a manifest as first entry.
+// @Description File writes are paused while the archive is created. Only SQLite databases can be backed up.
+// @Tags Admin
+// @Security CookieAuth
+// @ID adminCreateBackup
+// @Produce application/gzip
+// @Success 200 {file} binary "Backup archive"
+// @Failure 500 {object} ErrorResponse "Failed to create backup"
+// @Failure 501 {object} ErrorResponse "Backups are only supported for SQLite databases"
+// @Router /admin/backup [get]
+func (h *Handler) AdminCreateBackup(dbPath string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := context.GetRequestContext(w, r)
+		if !ok {
+			return
+		}
+		log := getAdminLogger().With(
+			"handler", "AdminCreateBackup",
+			"adminID", ctx.UserID,
+			"clientIP", r.RemoteAddr,
+		)
+
+		// The archive is created in a temporary file first, so writes aren't paused
+		// for as long as it takes the client to download it
+		file, err := os.CreateTemp("", "lemma-backup-*.tar.gz")
+		if err != nil {
+			log.Error("failed to create temporary backup file",
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to create backup", http.StatusInternalServerError)
+			return
+		}
+		defer os.Remove(file.Name())
+		defer file.Close()
+
+		manifest, err := backup.Create(file, h.DB, h.Storage, dbPath)
+		if errors.Is(err, db.ErrBackupNotSupported) {
+			respondError(w, "Backups are only supported for SQLite databases", http.StatusNotImplemented)
+			return
+		}
+		if err != nil {
+			log.Error("failed to create backup",
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to create backup", http.StatusInternalServerError)
+			return
+		}
+
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			log.Error("failed to read backup",
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to create backup", http.StatusInternalServerError)
+			return
+		}
+
+		h.audit(r, "backup created", &models.AuditEvent{
+			Event:      "backup_created",
+			ActorID:    &ctx.UserID,
+			TargetType: models.AuditTargetSystem,
+			Details:    map[string]any{"schemaVersion": manifest.SchemaVersion},
+		})
+
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", backup.FileName(manifest.CreatedAt)))
+		if _, err := io.Copy(w, file); err != nil {
+			log.Warn("failed to send backup",
+				"error", err.Error(),
+			)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+//go:build integration
+
+package handlers_test
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"lemma/internal/backup"
+	"lemma/internal/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupHandlers_Integration(t *testing.T) {
+	h := setupTestHarness(t)
+	defer h.teardown(t)
+
+	t.Run("admin downloads backup", func(t *testing.T) {
+		userID := h.RegularTestUser.userModel.ID
+		require.NoError(t, h.Storage.SaveFile(userID, 1, "backup.md", []byte("# Backup")))
+
+		rr := h.makeRequest(t, http.MethodGet, "/api/v1/admin/backup", nil, h.AdminTestUser)
+		if h.DB.Dialect() != db.DialectSQLite {
+			assert.Equal(t, http.StatusNotImplemented, rr.Code)
+			return
+		}
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "application/gzip", rr.Header().Get("Content-Type"))
+		assert.Contains(t, rr.Header().Get("Content-Disposition"), "lemma-backup-")
+
+		gzipReader, err := gzip.NewReader(rr.Body)
+		require.NoError(t, err)
+		tarReader := tar.NewReader(gzipReader)
+
+		header, err := tarReader.Next()
+		require.NoError(t, err)
+		require.Equal(t, "manifest.json", header.Name)
+
+		var manifest backup.Manifest
+		require.NoError(t, json.NewDecoder(tarReader).Decode(&manifest))
+		assert.Equal(t, backup.FormatVersion, manifest.FormatVersion)
+		assert.Equal(t, 18, manifest.SchemaVersion)
+
+		names := make(map[string]bool)
+		for {
+			header, err := tarReader.Next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			require.NoError(t, err)
+			names[header.Name] = true
+		}
+		assert.True(t, names["lemma.db"], "database missing from backup")
+		assert.True(t, names[fmt.Sprintf("data/%d/1/backup.md", userID)], "file missing from backup")
+	})
+
+	t.Run("non-admin can't download backup", func(t *testing.T) {
+		rr := h.makeRequest(t, http.MethodGet, "/api/v1/admin/backup", nil, h.RegularTestUser)
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+
+		rr = h.makeRequest(t, http.MethodGet, "/api/v1/admin/backup", nil, nil)
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+}
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"lemma/internal/context"
+	"lemma/internal/models"
+)
+
+// ProfileExport is the profile data in a profile export
+type ProfileExport struct {
+	Email       string          `json:"email"`
+	DisplayName string          `json:"displayName"`
+	Role        models.UserRole `json:"role"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	ExportedAt  time.Time       `json:"exportedAt"`
+}
+
+// WorkspaceExport is the settings of a workspace in a profile export, without the git token
+type WorkspaceExport struct {
+	Name                 string    `json:"name"`
+	CreatedAt            time.Time `json:"createdAt"`
+	Theme                string    `json:"theme"`
+	AutoSave             bool      `json:"autoSave"`
+	ShowHiddenFiles      bool      `json:"showHiddenFiles"`
+	GitEnabled           bool      `json:"gitEnabled"`
+	GitURL               string    `json:"gitUrl"`
+	GitUser              string    `json:"gitUser"`
+	GitAutoCommit        bool      `json:"gitAutoCommit"`
+	GitCommitMsgTemplate string    `json:"gitCommitMsgTemplate"`
+	GitCommitName        string    `json:"gitCommitName"`
+	GitCommitEmail       string    `json:"gitCommitEmail"`
+}
+
+func newWorkspaceExport(workspace *models.Workspace) WorkspaceExport {
+	return WorkspaceExport{
+		Name:                 workspace.Name,
+		CreatedAt:            workspace.CreatedAt,
+		Theme:                workspace.Theme,
+		AutoSave:             workspace.AutoSave,
+		ShowHiddenFiles:      workspace.ShowHiddenFiles,
+		GitEnabled:           workspace.GitEnabled,
+		GitURL:               workspace.GitURL,
+		GitUser:              workspace.GitUser,
+		GitAutoCommit:        workspace.GitAutoCommit,
+		GitCommitMsgTemplate: workspace.GitCommitMsgTemplate,
+		GitCommitName:        workspace.GitCommitName,
+		GitCommitEmail:       workspace.GitCommitEmail,
+	}
+}
+
+// exportDirName returns a unique directory name for a workspace in an export,
+// as workspace names may contain slashes and don't have to be unique
+func exportDirName(workspace *models.Workspace, used map[string]bool) string {
+	name := strings.TrimSpace(strings.NewReplacer("/", "_", "\\", "_").Replace(workspace.Name))
+	if name == "" || name == "." || name == ".." {
+		name = "workspace"
+	}
+	if used[name] {
+		name = fmt.Sprintf("%s (%d)", name, workspace.ID)
+	}
+	used[name] = true
+	return name
+}
+
+// ExportProfile godoc
+// @Summary Export profile
+// @Description Downloads a zip archive of the user's profile and the files and settings of the workspaces they own.
+// @Description Git tokens are not exported. The archive is streamed while it is created, so errors after the download started abort it.
+// @Tags users
+// @ID exportProfile
+// @Security CookieAuth
+// @Produce application/zip
+// @Success 200 {file} binary "Profile export"
+// @Failure 404 {object} ErrorResponse "User not found"
+// @Failure 500 {object} ErrorResponse "Failed to list workspaces"
+// @Router /profile/export [get]
+func (h *Handler) ExportProfile() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := context.GetRequestContext(w, r)
+		if !ok {
+			return
+		}
+		log := getProfileLogger().With(
+			"handler", "ExportProfile",
+			"userID", ctx.UserID,
+			"clientIP", r.RemoteAddr,
+		)
+
+		user, err := h.DB.GetUserByID(ctx.UserID)
+		if err != nil {
+			log.Error("failed to fetch user from database",
+				"error", err.Error(),
+			)
+			respondError(w, "User not found", http.StatusNotFound)
+			return
+		}
+
+		workspaces, err := h.DB.GetWorkspacesByUserID(ctx.UserID)
+		if err != nil {
+			log.Error("failed to fetch workspaces from database",
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to list workspaces", http.StatusInternalServerError)
+			return
+		}
+
+		exportedAt := time.Now().UTC()
+		fileName := fmt.Sprintf("lemma-export-%s.zip", exportedAt.Format("20060102-150405"))
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+
+		// The archive is written to the response while it is created, the status
+		// can't be changed anymore once the first bytes are sent
+		zw := zip.NewWriter(w)
+		writeJSON := func(name string, v interface{}) error {
+			f, err := zw.Create(name)
+			if err != nil {
+				return err
+			}
+			encoder := json.NewEncoder(f)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(v)
+		}
+
+		profile := ProfileExport{
+			Email:       user.Email,
+			DisplayName: user.DisplayName,
+			Role:        user.Role,
+			CreatedAt:   user.CreatedAt,
+			ExportedAt:  exportedAt,
+		}
+		if err := writeJSON("profile.json", profile); err != nil {
+			log.Warn("failed to send profile export",
+				"error", err.Error(),
+			)
+			return
+		}
+
+		fileCount := 0
+		used := make(map[string]bool, len(workspaces))
+		for _, workspace := range workspaces {
+			dir := path.Join("workspaces", exportDirName(workspace, used))
+			if err := writeJSON(path.Join(dir, "workspace.json"), newWorkspaceExport(workspace)); err != nil {
+				log.Warn("failed to send profile export",
+					"error", err.Error(),
+				)
+				return
+			}
+
+			count, err := h.Storage.ExportWorkspace(zw, ctx.UserID, workspace.ID, path.Join(dir, "files"))
+			fileCount += count
+			if err != nil {
+				log.Error("failed to export workspace",
+					"workspaceID", workspace.ID,
+					"error", err.Error(),
+				)
+				return
+			}
+		}
+
+		if err := zw.Close(); err != nil {
+			log.Warn("failed to send profile export",
+				"error", err.Error(),
+			)
+			return
+		}
+
+		h.audit(r, "profile exported", &models.AuditEvent{
+			Event:      "profile_exported",
+			ActorID:    &ctx.UserID,
+			TargetType: models.AuditTargetUser,
+			TargetID:   strconv.Itoa(ctx.UserID),
+			Details:    map[string]any{"workspaces": len(workspaces), "files": fileCount},
+		})
+	}
+}
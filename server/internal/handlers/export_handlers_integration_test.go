@@ -0,0 +1,79 @@
+//go:build integration
+
+package handlers_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"lemma/internal/handlers"
+	"lemma/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportHandlers_Integration(t *testing.T) {
+	h := setupTestHarness(t)
+	defer h.teardown(t)
+
+	workspace := &models.Workspace{Name: "Export/Workspace"}
+	rr := h.makeRequest(t, http.MethodPost, "/api/v1/workspaces", workspace, h.RegularTestUser)
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(workspace))
+
+	require.NoError(t, h.Storage.SaveFile(workspace.UserID, workspace.ID, "notes/todo.md", []byte("# Todo")))
+
+	workspace.GitURL = "https://example.com/repo.git"
+	workspace.GitToken = "secret-token"
+	require.NoError(t, h.DB.UpdateWorkspace(workspace))
+
+	t.Run("exports profile and workspaces", func(t *testing.T) {
+		rr := h.makeRequest(t, http.MethodGet, "/api/v1/profile/export", nil, h.RegularTestUser)
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "application/zip", rr.Header().Get("Content-Type"))
+		assert.Contains(t, rr.Header().Get("Content-Disposition"), "attachment")
+
+		body := rr.Body.Bytes()
+		zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+		require.NoError(t, err)
+
+		contents := make(map[string]string)
+		for _, f := range zr.File {
+			rc, err := f.Open()
+			require.NoError(t, err)
+			content, err := io.ReadAll(rc)
+			rc.Close()
+			require.NoError(t, err)
+			contents[f.Name] = string(content)
+		}
+
+		var profile handlers.ProfileExport
+		require.Contains(t, contents, "profile.json")
+		require.NoError(t, json.Unmarshal([]byte(contents["profile.json"]), &profile))
+		assert.Equal(t, h.RegularTestUser.userModel.Email, profile.Email)
+
+		var settings handlers.WorkspaceExport
+		require.Contains(t, contents, "workspaces/Export_Workspace/workspace.json")
+		require.NoError(t, json.Unmarshal([]byte(contents["workspaces/Export_Workspace/workspace.json"]), &settings))
+		assert.Equal(t, workspace.Name, settings.Name)
+		assert.Equal(t, workspace.GitURL, settings.GitURL)
+		assert.NotContains(t, contents["workspaces/Export_Workspace/workspace.json"], "secret-token")
+
+		assert.Equal(t, "# Todo", contents["workspaces/Export_Workspace/files/notes/todo.md"])
+
+		// Workspaces of other users are not exported
+		for name := range contents {
+			assert.NotContains(t, name, "Admin")
+		}
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		rr := h.makeRequest(t, http.MethodGet, "/api/v1/profile/export", nil, nil)
+		assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	})
+}
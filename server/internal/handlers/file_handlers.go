@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"os"
@@ -9,6 +10,7 @@ import (
 
 	"lemma/internal/context"
 	"lemma/internal/logging"
+	"lemma/internal/models"
 	"lemma/internal/storage"
 
 	"github.com/go-chi/chi/v5"
@@ -64,7 +66,7 @@ func (h *Handler) ListFiles() http.HandlerFunc {
 			"clientIP", r.RemoteAddr,
 		)
 
-		files, err := h.Storage.ListFilesRecursively(ctx.UserID, ctx.Workspace.ID)
+		files, err := h.Storage.ListFilesRecursively(ctx.Workspace.UserID, ctx.Workspace.ID)
 		if err != nil {
 			log.Error("failed to list files in workspace",
 				"error", err.Error(),
@@ -110,7 +112,7 @@ func (h *Handler) LookupFileByName() http.HandlerFunc {
 			return
 		}
 
-		filePaths, err := h.Storage.FindFileByName(ctx.UserID, ctx.Workspace.ID, filename)
+		filePaths, err := h.Storage.FindFileByName(ctx.Workspace.UserID, ctx.Workspace.ID, filename)
 		if err != nil {
 			if !os.IsNotExist(err) {
 				log.Error("failed to lookup file",
@@ -159,7 +161,7 @@ func (h *Handler) GetFileContent() http.HandlerFunc {
 		)
 
 		filePath := chi.URLParam(r, "*")
-		content, err := h.Storage.GetFileContent(ctx.UserID, ctx.Workspace.ID, filePath)
+		content, err := h.Storage.GetFileContent(ctx.Workspace.UserID, ctx.Workspace.ID, filePath)
 		if err != nil {
 			if storage.IsPathValidationError(err) {
 				log.Error("invalid file path attempted",
@@ -213,6 +215,7 @@ func (h *Handler) GetFileContent() http.HandlerFunc {
 // @Failure 400 {object} ErrorResponse "Failed to read request body"
 // @Failure 400 {object} ErrorResponse "Invalid file path"
 // @Failure 500 {object} ErrorResponse "Failed to save file"
+// @Failure 507 {object} ErrorResponse "Storage quota exceeded"
 // @Router /workspaces/{workspace_name}/files/{file_path} [post]
 func (h *Handler) SaveFile() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -238,7 +241,7 @@ func (h *Handler) SaveFile() http.HandlerFunc {
 			return
 		}
 
-		err = h.Storage.SaveFile(ctx.UserID, ctx.Workspace.ID, filePath, content)
+		err = h.Storage.SaveFile(ctx.Workspace.UserID, ctx.Workspace.ID, filePath, content)
 		if err != nil {
 			if storage.IsPathValidationError(err) {
 				log.Error("invalid file path attempted",
@@ -249,6 +252,15 @@ func (h *Handler) SaveFile() http.HandlerFunc {
 				return
 			}
 
+			if errors.Is(err, storage.ErrQuotaExceeded) {
+				log.Debug("storage quota exceeded",
+					"filePath", filePath,
+					"contentSize", len(content),
+				)
+				respondError(w, "Storage quota exceeded", http.StatusInsufficientStorage)
+				return
+			}
+
 			log.Error("failed to save file",
 				"filePath", filePath,
 				"contentSize", len(content),
@@ -270,7 +282,7 @@ func (h *Handler) SaveFile() http.HandlerFunc {
 
 // DeleteFile godoc
 // @Summary Delete file
-// @Description Deletes a file in the user's workspace
+// @Description Moves a file in the user's workspace to the trash of the workspace, where it can be restored until it expires
 // @Tags files
 // @ID deleteFile
 // @Security CookieAuth
@@ -295,7 +307,7 @@ func (h *Handler) DeleteFile() http.HandlerFunc {
 		)
 
 		filePath := chi.URLParam(r, "*")
-		err := h.Storage.DeleteFile(ctx.UserID, ctx.Workspace.ID, filePath)
+		item, err := h.Storage.DeleteFile(ctx.Workspace.UserID, ctx.Workspace.ID, filePath, ctx.UserID)
 		if err != nil {
 			if storage.IsPathValidationError(err) {
 				log.Error("invalid file path attempted",
@@ -322,6 +334,14 @@ func (h *Handler) DeleteFile() http.HandlerFunc {
 			return
 		}
 
+		h.audit(r, "file deleted", &models.AuditEvent{
+			Event:       "file_deleted",
+			ActorID:     &ctx.UserID,
+			TargetType:  models.AuditTargetFile,
+			TargetID:    filePath,
+			WorkspaceID: &ctx.Workspace.ID,
+			Details:     map[string]any{"trashItemID": item.ID},
+		})
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
@@ -360,7 +380,7 @@ func (h *Handler) GetLastOpenedFile() http.HandlerFunc {
 			return
 		}
 
-		if _, err := h.Storage.ValidatePath(ctx.UserID, ctx.Workspace.ID, filePath); err != nil {
+		if _, err := h.Storage.ValidatePath(ctx.Workspace.UserID, ctx.Workspace.ID, filePath); err != nil {
 			log.Error("invalid file path stored",
 				"filePath", filePath,
 				"error", err.Error(),
@@ -413,7 +433,7 @@ func (h *Handler) UpdateLastOpenedFile() http.HandlerFunc {
 
 		// Validate the file path in the workspace
 		if requestBody.FilePath != "" {
-			_, err := h.Storage.GetFileContent(ctx.UserID, ctx.Workspace.ID, requestBody.FilePath)
+			_, err := h.Storage.GetFileContent(ctx.Workspace.UserID, ctx.Workspace.ID, requestBody.FilePath)
 			if err != nil {
 				if storage.IsPathValidationError(err) {
 					log.Error("invalid file path attempted",
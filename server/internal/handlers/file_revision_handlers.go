@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+
+	"lemma/internal/context"
+	"lemma/internal/logging"
+	"lemma/internal/models"
+	"lemma/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// FileDiffResponse contains the line-based difference between two versions of a file
+type FileDiffResponse struct {
+	Path   string              `json:"path"`
+	From   int                 `json:"from"`
+	To     int                 `json:"to"` // 0 is the current content of the file
+	Chunks []storage.DiffChunk `json:"chunks"`
+}
+
+// parseRevisionID parses a revision ID, which must be a positive integer
+func parseRevisionID(value string) (int, bool) {
+	id, err := strconv.Atoi(value)
+	if err != nil || id <= 0 {
+		return 0, false
+	}
+	return id, true
+}
+
+// respondRevisionError responds to the errors returned by the revision functions of the storage
+func respondRevisionError(w http.ResponseWriter, log logging.Logger, err error, filePath, message string) {
+	switch {
+	case storage.IsPathValidationError(err):
+		log.Error("invalid file path attempted",
+			"filePath", filePath,
+			"error", err.Error(),
+		)
+		respondError(w, "Invalid file path", http.StatusBadRequest)
+	case errors.Is(err, storage.ErrRevisionNotFound):
+		respondError(w, "Revision not found", http.StatusNotFound)
+	case errors.Is(err, storage.ErrQuotaExceeded):
+		respondError(w, "Storage quota exceeded", http.StatusInsufficientStorage)
+	default:
+		log.Error("failed to access file revisions",
+			"filePath", filePath,
+			"error", err.Error(),
+		)
+		respondError(w, message, http.StatusInternalServerError)
+	}
+}
+
+// ListFileRevisions godoc
+// @Summary List file revisions
+// @Description Returns the previous versions of a file, most recent first.
+// @Description Revisions are only kept in workspaces without a Git repository.
+// @Tags files
+// @ID listFileRevisions
+// @Security CookieAuth
+// @Produce json
+// @Param workspace_name path string true "Workspace name"
+// @Param path query string true "File path"
+// @Success 200 {array} storage.FileRevision
+// @Failure 400 {object} ErrorResponse "Path is required"
+// @Failure 400 {object} ErrorResponse "Invalid file path"
+// @Failure 500 {object} ErrorResponse "Failed to list revisions"
+// @Router /workspaces/{workspace_name}/revisions [get]
+func (h *Handler) ListFileRevisions() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := context.GetRequestContext(w, r)
+		if !ok {
+			return
+		}
+		log := getFilesLogger().With(
+			"handler", "ListFileRevisions",
+			"userID", ctx.UserID,
+			"workspaceID", ctx.Workspace.ID,
+			"clientIP", r.RemoteAddr,
+		)
+
+		filePath := r.URL.Query().Get("path")
+		if filePath == "" {
+			respondError(w, "Path is required", http.StatusBadRequest)
+			return
+		}
+
+		revisions, err := h.Storage.ListRevisions(ctx.Workspace.UserID, ctx.Workspace.ID, filePath)
+		if err != nil {
+			respondRevisionError(w, log, err, filePath, "Failed to list revisions")
+			return
+		}
+
+		respondJSON(w, revisions)
+	}
+}
+
+// GetFileRevision godoc
+// @Summary Get file revision
+// @Description Returns the content of a previous version of a file
+// @Tags files
+// @ID getFileRevision
+// @Security CookieAuth
+// @Produce plain
+// @Param workspace_name path string true "Workspace name"
+// @Param revision_id path int true "Revision ID"
+// @Param path query string true "File path"
+// @Success 200 {string} string "Raw file content"
+// @Failure 400 {object} ErrorResponse "Path is required"
+// @Failure 400 {object} ErrorResponse "Invalid revision ID"
+// @Failure 400 {object} ErrorResponse "Invalid file path"
+// @Failure 404 {object} ErrorResponse "Revision not found"
+// @Failure 500 {object} ErrorResponse "Failed to read revision"
+// @Router /workspaces/{workspace_name}/revisions/{revision_id} [get]
+func (h *Handler) GetFileRevision() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := context.GetRequestContext(w, r)
+		if !ok {
+			return
+		}
+		log := getFilesLogger().With(
+			"handler", "GetFileRevision",
+			"userID", ctx.UserID,
+			"workspaceID", ctx.Workspace.ID,
+			"clientIP", r.RemoteAddr,
+		)
+
+		filePath := r.URL.Query().Get("path")
+		if filePath == "" {
+			respondError(w, "Path is required", http.StatusBadRequest)
+			return
+		}
+		revisionID, ok := parseRevisionID(chi.URLParam(r, "revisionId"))
+		if !ok {
+			respondError(w, "Invalid revision ID", http.StatusBadRequest)
+			return
+		}
+
+		content, err := h.Storage.GetRevisionContent(ctx.Workspace.UserID, ctx.Workspace.ID, filePath, revisionID)
+		if err != nil {
+			respondRevisionError(w, log, err, filePath, "Failed to read revision")
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain")
+		if _, err := w.Write(content); err != nil {
+			log.Error("failed to write response",
+				"filePath", filePath,
+				"error", err.Error(),
+			)
+		}
+	}
+}
+
+// DiffFileRevisions godoc
+// @Summary Diff file revisions
+// @Description Returns the line-based difference between two versions of a file.
+// @Description If to is omitted, the revision is compared to the current content of the file.
+// @Tags files
+// @ID diffFileRevisions
+// @Security CookieAuth
+// @Produce json
+// @Param workspace_name path string true "Workspace name"
+// @Param path query string true "File path"
+// @Param from query int true "Revision ID to compare from"
+// @Param to query int false "Revision ID to compare to"
+// @Success 200 {object} FileDiffResponse
+// @Failure 400 {object} ErrorResponse "Path is required"
+// @Failure 400 {object} ErrorResponse "Invalid revision ID"
+// @Failure 400 {object} ErrorResponse "Invalid file path"
+// @Failure 404 {object} ErrorResponse "Revision not found"
+// @Failure 404 {object} ErrorResponse "File not found"
+// @Failure 500 {object} ErrorResponse "Failed to diff revisions"
+// @Router /workspaces/{workspace_name}/revisions/diff [get]
+func (h *Handler) DiffFileRevisions() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := context.GetRequestContext(w, r)
+		if !ok {
+			return
+		}
+		log := getFilesLogger().With(
+			"handler", "DiffFileRevisions",
+			"userID", ctx.UserID,
+			"workspaceID", ctx.Workspace.ID,
+			"clientIP", r.RemoteAddr,
+		)
+
+		query := r.URL.Query()
+		filePath := query.Get("path")
+		if filePath == "" {
+			respondError(w, "Path is required", http.StatusBadRequest)
+			return
+		}
+		fromID, ok := parseRevisionID(query.Get("from"))
+		if !ok {
+			respondError(w, "Invalid revision ID", http.StatusBadRequest)
+			return
+		}
+		toID := storage.CurrentRevision
+		if to := query.Get("to"); to != "" {
+			if toID, ok = parseRevisionID(to); !ok {
+				respondError(w, "Invalid revision ID", http.StatusBadRequest)
+				return
+			}
+		}
+
+		chunks, err := h.Storage.DiffRevisions(ctx.Workspace.UserID, ctx.Workspace.ID, filePath, fromID, toID)
+		if err != nil {
+			if os.IsNotExist(err) {
+				respondError(w, "File not found", http.StatusNotFound)
+				return
+			}
+			respondRevisionError(w, log, err, filePath, "Failed to diff revisions")
+			return
+		}
+
+		respondJSON(w, FileDiffResponse{
+			Path:   filePath,
+			From:   fromID,
+			To:     toID,
+			Chunks: chunks,
+		})
+	}
+}
+
+// RestoreFileRevision godoc
+// @Summary Restore file revision
+// @Description Replaces the content of a file with a previous version.
+// @Description The replaced content is kept as a new revision, so restoring can be undone.
+// @Tags files
+// @ID restoreFileRevision
+// @Security CookieAuth
+// @Produce json
+// @Param workspace_name path string true "Workspace name"
+// @Param revision_id path int true "Revision ID"
+// @Param path query string true "File path"
+// @Success 200 {object} storage.FileRevision
+// @Failure 400 {object} ErrorResponse "Path is required"
+// @Failure 400 {object} ErrorResponse "Invalid revision ID"
+// @Failure 400 {object} ErrorResponse "Invalid file path"
+// @Failure 404 {object} ErrorResponse "Revision not found"
+// @Failure 500 {object} ErrorResponse "Failed to restore revision"
+// @Failure 507 {object} ErrorResponse "Storage quota exceeded"
+// @Router /workspaces/{workspace_name}/revisions/{revision_id}/restore [post]
+func (h *Handler) RestoreFileRevision() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := context.GetRequestContext(w, r)
+		if !ok {
+			return
+		}
+		log := getFilesLogger().With(
+			"handler", "RestoreFileRevision",
+			"userID", ctx.UserID,
+			"workspaceID", ctx.Workspace.ID,
+			"clientIP", r.RemoteAddr,
+		)
+
+		filePath := r.URL.Query().Get("path")
+		if filePath == "" {
+			respondError(w, "Path is required", http.StatusBadRequest)
+			return
+		}
+		revisionID, ok := parseRevisionID(chi.URLParam(r, "revisionId"))
+		if !ok {
+			respondError(w, "Invalid revision ID", http.StatusBadRequest)
+			return
+		}
+
+		revision, err := h.Storage.RestoreRevision(ctx.Workspace.UserID, ctx.Workspace.ID, filePath, revisionID)
+		if err != nil {
+			respondRevisionError(w, log, err, filePath, "Failed to restore revision")
+			return
+		}
+
+		h.audit(r, "file revision restored", &models.AuditEvent{
+			Event:       "file_revision_restored",
+			ActorID:     &ctx.UserID,
+			TargetType:  models.AuditTargetFile,
+			TargetID:    filePath,
+			WorkspaceID: &ctx.Workspace.ID,
+			Details:     map[string]any{"revisionID": revision.ID},
+		})
+		respondJSON(w, revision)
+	}
+}
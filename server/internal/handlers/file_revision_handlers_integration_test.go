@@ -0,0 +1,129 @@
+//go:build integration
+
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"lemma/internal/handlers"
+	"lemma/internal/models"
+	"lemma/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileRevisionHandlers_Integration(t *testing.T) {
+	h := setupTestHarness(t)
+	defer h.teardown(t)
+
+	fileURL := "/api/v1/workspaces/Main/files/notes.md"
+	revisionsURL := "/api/v1/workspaces/Main/revisions"
+	query := "?path=notes.md"
+
+	saveFile := func(t *testing.T, content string) {
+		t.Helper()
+		rr := h.makeRequestRaw(t, http.MethodPost, fileURL, strings.NewReader(content), h.RegularTestUser)
+		require.Equal(t, http.StatusOK, rr.Code)
+	}
+
+	listRevisions := func(t *testing.T) []storage.FileRevision {
+		t.Helper()
+		rr := h.makeRequest(t, http.MethodGet, revisionsURL+query, nil, h.RegularTestUser)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var revisions []storage.FileRevision
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&revisions))
+		return revisions
+	}
+
+	saveFile(t, "first\n")
+	saveFile(t, "first\nsecond\n")
+	saveFile(t, "first\nthird\n")
+
+	t.Run("lists revisions", func(t *testing.T) {
+		revisions := listRevisions(t)
+		require.Len(t, revisions, 2)
+		assert.Equal(t, 2, revisions[0].ID)
+		assert.Equal(t, 1, revisions[1].ID)
+
+		rr := h.makeRequest(t, http.MethodGet, revisionsURL, nil, h.RegularTestUser)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		rr = h.makeRequest(t, http.MethodGet, revisionsURL+"?path=../other.md", nil, h.RegularTestUser)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("gets revision content", func(t *testing.T) {
+		rr := h.makeRequest(t, http.MethodGet, revisionsURL+"/1"+query, nil, h.RegularTestUser)
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "first\n", rr.Body.String())
+
+		rr = h.makeRequest(t, http.MethodGet, revisionsURL+"/42"+query, nil, h.RegularTestUser)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		rr = h.makeRequest(t, http.MethodGet, revisionsURL+"/abc"+query, nil, h.RegularTestUser)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("diffs revisions", func(t *testing.T) {
+		rr := h.makeRequest(t, http.MethodGet, revisionsURL+"/diff"+query+"&from=2", nil, h.RegularTestUser)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var response handlers.FileDiffResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+		assert.Equal(t, 2, response.From)
+		assert.Equal(t, storage.CurrentRevision, response.To)
+		assert.Equal(t, []storage.DiffChunk{
+			{Op: storage.DiffOpEqual, Text: "first\n"},
+			{Op: storage.DiffOpDelete, Text: "second\n"},
+			{Op: storage.DiffOpInsert, Text: "third\n"},
+		}, response.Chunks)
+
+		rr = h.makeRequest(t, http.MethodGet, revisionsURL+"/diff"+query+"&from=1&to=2", nil, h.RegularTestUser)
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+		assert.Equal(t, []storage.DiffChunk{
+			{Op: storage.DiffOpEqual, Text: "first\n"},
+			{Op: storage.DiffOpInsert, Text: "second\n"},
+		}, response.Chunks)
+
+		rr = h.makeRequest(t, http.MethodGet, revisionsURL+"/diff"+query, nil, h.RegularTestUser)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("restores revisions", func(t *testing.T) {
+		rr := h.makeRequest(t, http.MethodPost, revisionsURL+"/1/restore"+query, nil, h.RegularTestUser)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var revision storage.FileRevision
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&revision))
+		assert.Equal(t, 1, revision.ID)
+
+		rr = h.makeRequest(t, http.MethodGet, fileURL, nil, h.RegularTestUser)
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "first\n", rr.Body.String())
+
+		// The replaced content is kept, so restoring can be undone
+		revisions := listRevisions(t)
+		require.Len(t, revisions, 3)
+		rr = h.makeRequest(t, http.MethodGet, revisionsURL+"/3"+query, nil, h.RegularTestUser)
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "first\nthird\n", rr.Body.String())
+
+		events, _, err := h.DB.GetAuditEvents(models.AuditEventFilter{Event: "file_revision_restored", Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		assert.Equal(t, "notes.md", events[0].TargetID)
+	})
+
+	t.Run("revisions are hidden from files", func(t *testing.T) {
+		rr := h.makeRequest(t, http.MethodGet, "/api/v1/workspaces/Main/files", nil, h.RegularTestUser)
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.NotContains(t, rr.Body.String(), ".revisions")
+
+		rr = h.makeRequest(t, http.MethodGet, "/api/v1/workspaces/Main/files/.revisions/history", nil, h.RegularTestUser)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
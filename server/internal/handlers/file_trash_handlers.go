@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"lemma/internal/context"
+	"lemma/internal/models"
+	"lemma/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// EmptyTrashResponse contains the number of permanently deleted trash items
+type EmptyTrashResponse struct {
+	Deleted int `json:"deleted"`
+}
+
+// ListTrash godoc
+// @Summary List trash
+// @Description Returns the deleted files and folders of the current workspace, most recently deleted first.
+// @Description They are permanently deleted after the trash retention period.
+// @Tags files
+// @ID listTrash
+// @Security CookieAuth
+// @Produce json
+// @Param workspace_name path string true "Workspace name"
+// @Success 200 {array} storage.TrashItem
+// @Failure 500 {object} ErrorResponse "Failed to list trash"
+// @Router /workspaces/{workspace_name}/trash [get]
+func (h *Handler) ListTrash() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := context.GetRequestContext(w, r)
+		if !ok {
+			return
+		}
+		log := getFilesLogger().With(
+			"handler", "ListTrash",
+			"userID", ctx.UserID,
+			"workspaceID", ctx.Workspace.ID,
+			"clientIP", r.RemoteAddr,
+		)
+
+		items, err := h.Storage.ListTrash(ctx.Workspace.UserID, ctx.Workspace.ID)
+		if err != nil {
+			log.Error("failed to list trash",
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to list trash", http.StatusInternalServerError)
+			return
+		}
+
+		respondJSON(w, items)
+	}
+}
+
+// RestoreTrashItem godoc
+// @Summary Restore trash item
+// @Description Moves a deleted file or folder from the trash back to its original path
+// @Tags files
+// @ID restoreTrashItem
+// @Security CookieAuth
+// @Produce json
+// @Param workspace_name path string true "Workspace name"
+// @Param item_id path string true "Trash item ID"
+// @Success 200 {object} storage.TrashItem
+// @Failure 404 {object} ErrorResponse "Trash item not found"
+// @Failure 409 {object} ErrorResponse "A file already exists at the original path"
+// @Failure 500 {object} ErrorResponse "Failed to restore trash item"
+// @Failure 507 {object} ErrorResponse "Storage quota exceeded"
+// @Router /workspaces/{workspace_name}/trash/{item_id}/restore [post]
+func (h *Handler) RestoreTrashItem() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := context.GetRequestContext(w, r)
+		if !ok {
+			return
+		}
+		log := getFilesLogger().With(
+			"handler", "RestoreTrashItem",
+			"userID", ctx.UserID,
+			"workspaceID", ctx.Workspace.ID,
+			"clientIP", r.RemoteAddr,
+		)
+
+		itemID := chi.URLParam(r, "itemId")
+		item, err := h.Storage.RestoreTrashItem(ctx.Workspace.UserID, ctx.Workspace.ID, itemID)
+		if errors.Is(err, storage.ErrTrashItemNotFound) {
+			respondError(w, "Trash item not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, storage.ErrFileExists) {
+			respondError(w, "A file already exists at the original path", http.StatusConflict)
+			return
+		}
+		if errors.Is(err, storage.ErrQuotaExceeded) {
+			log.Debug("storage quota exceeded",
+				"itemID", itemID,
+			)
+			respondError(w, "Storage quota exceeded", http.StatusInsufficientStorage)
+			return
+		}
+		if err != nil {
+			log.Error("failed to restore trash item",
+				"itemID", itemID,
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to restore trash item", http.StatusInternalServerError)
+			return
+		}
+
+		h.audit(r, "file restored from trash", &models.AuditEvent{
+			Event:       "file_restored",
+			ActorID:     &ctx.UserID,
+			TargetType:  models.AuditTargetFile,
+			TargetID:    item.Path,
+			WorkspaceID: &ctx.Workspace.ID,
+			Details:     map[string]any{"trashItemID": item.ID},
+		})
+		respondJSON(w, item)
+	}
+}
+
+// EmptyTrash godoc
+// @Summary Empty trash
+// @Description Permanently deletes all files and folders in the trash of the current workspace
+// @Tags files
+// @ID emptyTrash
+// @Security CookieAuth
+// @Produce json
+// @Param workspace_name path string true "Workspace name"
+// @Success 200 {object} EmptyTrashResponse
+// @Failure 500 {object} ErrorResponse "Failed to empty trash"
+// @Router /workspaces/{workspace_name}/trash [delete]
+func (h *Handler) EmptyTrash() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := context.GetRequestContext(w, r)
+		if !ok {
+			return
+		}
+		log := getFilesLogger().With(
+			"handler", "EmptyTrash",
+			"userID", ctx.UserID,
+			"workspaceID", ctx.Workspace.ID,
+			"clientIP", r.RemoteAddr,
+		)
+
+		count, err := h.Storage.EmptyTrash(ctx.Workspace.UserID, ctx.Workspace.ID)
+		if err != nil {
+			log.Error("failed to empty trash",
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to empty trash", http.StatusInternalServerError)
+			return
+		}
+
+		h.audit(r, "trash emptied", &models.AuditEvent{
+			Event:       "trash_emptied",
+			ActorID:     &ctx.UserID,
+			TargetType:  models.AuditTargetWorkspace,
+			TargetID:    strconv.Itoa(ctx.Workspace.ID),
+			WorkspaceID: &ctx.Workspace.ID,
+			Details:     map[string]any{"deleted": count},
+		})
+		respondJSON(w, EmptyTrashResponse{Deleted: count})
+	}
+}
@@ -0,0 +1,89 @@
+//go:build integration
+
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"lemma/internal/handlers"
+	"lemma/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileTrashHandlers_Integration(t *testing.T) {
+	h := setupTestHarness(t)
+	defer h.teardown(t)
+
+	filesURL := "/api/v1/workspaces/Main/files"
+	trashURL := "/api/v1/workspaces/Main/trash"
+
+	listTrash := func(t *testing.T) []storage.TrashItem {
+		t.Helper()
+		rr := h.makeRequest(t, http.MethodGet, trashURL, nil, h.RegularTestUser)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var items []storage.TrashItem
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&items))
+		return items
+	}
+
+	rr := h.makeRequestRaw(t, http.MethodPost, filesURL+"/notes/todo.md", strings.NewReader("# Todo"), h.RegularTestUser)
+	require.Equal(t, http.StatusOK, rr.Code)
+	rr = h.makeRequest(t, http.MethodDelete, filesURL+"/notes/todo.md", nil, h.RegularTestUser)
+	require.Equal(t, http.StatusNoContent, rr.Code)
+
+	t.Run("deleted files are listed in the trash", func(t *testing.T) {
+		items := listTrash(t)
+		require.Len(t, items, 1)
+		assert.Equal(t, "notes/todo.md", items[0].Path)
+		assert.Equal(t, h.RegularTestUser.session.UserID, items[0].DeletedBy)
+
+		rr := h.makeRequest(t, http.MethodGet, filesURL, nil, h.RegularTestUser)
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.NotContains(t, rr.Body.String(), ".trash")
+	})
+
+	t.Run("restores deleted files", func(t *testing.T) {
+		items := listTrash(t)
+		require.Len(t, items, 1)
+		restoreURL := trashURL + "/" + items[0].ID + "/restore"
+
+		rr := h.makeRequestRaw(t, http.MethodPost, filesURL+"/notes/todo.md", strings.NewReader("# New"), h.RegularTestUser)
+		require.Equal(t, http.StatusOK, rr.Code)
+		rr = h.makeRequest(t, http.MethodPost, restoreURL, nil, h.RegularTestUser)
+		assert.Equal(t, http.StatusConflict, rr.Code)
+
+		// Deleting the new file moves it to the trash as well
+		rr = h.makeRequest(t, http.MethodDelete, filesURL+"/notes/todo.md", nil, h.RegularTestUser)
+		require.Equal(t, http.StatusNoContent, rr.Code)
+		rr = h.makeRequest(t, http.MethodPost, restoreURL, nil, h.RegularTestUser)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		rr = h.makeRequest(t, http.MethodGet, filesURL+"/notes/todo.md", nil, h.RegularTestUser)
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "# Todo", rr.Body.String())
+
+		rr = h.makeRequest(t, http.MethodPost, restoreURL, nil, h.RegularTestUser)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("empties the trash", func(t *testing.T) {
+		rr := h.makeRequest(t, http.MethodDelete, trashURL, nil, h.RegularTestUser)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var response handlers.EmptyTrashResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+		assert.Equal(t, 1, response.Deleted)
+		assert.Empty(t, listTrash(t))
+	})
+
+	t.Run("trash is not accessible through the file routes", func(t *testing.T) {
+		rr := h.makeRequest(t, http.MethodGet, filesURL+"/.trash/x/content", nil, h.RegularTestUser)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
@@ -2,8 +2,10 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"lemma/internal/context"
 	"lemma/internal/logging"
+	"lemma/internal/storage"
 	"net/http"
 )
 
@@ -68,7 +70,7 @@ func (h *Handler) StageCommitAndPush() http.HandlerFunc {
 			return
 		}
 
-		hash, err := h.Storage.StageCommitAndPush(ctx.UserID, ctx.Workspace.ID, requestBody.Message)
+		hash, err := h.Storage.StageCommitAndPush(ctx.Workspace.UserID, ctx.Workspace.ID, requestBody.Message)
 		if err != nil {
 			log.Error("failed to perform git operations",
 				"error", err.Error(),
@@ -84,13 +86,14 @@ func (h *Handler) StageCommitAndPush() http.HandlerFunc {
 
 // PullChanges godoc
 // @Summary Pull changes from remote
-// @Description Pulls changes from the remote repository
+// @Description Pulls changes from the remote repository. The pull is stopped if the workspace outgrows its storage quota.
 // @Tags git
 // @ID pullChanges
 // @Security CookieAuth
 // @Produce json
 // @Param workspace_name path string true "Workspace name"
 // @Success 200 {object} PullResponse
+// @Failure 507 {object} ErrorResponse "Storage quota exceeded"
 // @Failure 500 {object} ErrorResponse "Failed to pull changes"
 // @Router /workspaces/{workspace_name}/git/pull [post]
 func (h *Handler) PullChanges() http.HandlerFunc {
@@ -106,7 +109,12 @@ func (h *Handler) PullChanges() http.HandlerFunc {
 			"clientIP", r.RemoteAddr,
 		)
 
-		err := h.Storage.Pull(ctx.UserID, ctx.Workspace.ID)
+		err := h.Storage.Pull(ctx.Workspace.UserID, ctx.Workspace.ID)
+		if errors.Is(err, storage.ErrQuotaExceeded) {
+			log.Debug("storage quota exceeded")
+			respondError(w, "Storage quota exceeded", http.StatusInsufficientStorage)
+			return
+		}
 		if err != nil {
 			log.Error("failed to pull changes from remote",
 				"error", err.Error(),
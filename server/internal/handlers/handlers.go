@@ -5,6 +5,7 @@ import (
 	"lemma/internal/db"
 	"lemma/internal/logging"
 	"lemma/internal/storage"
+	"net"
 	"net/http"
 )
 
@@ -28,6 +29,11 @@ func getHandlersLogger() logging.Logger {
 	return logger
 }
 
+// getAuditLogger returns the logger for security relevant events
+func getAuditLogger() logging.Logger {
+	return logging.WithGroup("audit")
+}
+
 // NewHandler creates a new handler with the given dependencies
 func NewHandler(db db.Database, s storage.Manager) *Handler {
 	return &Handler{
@@ -49,3 +55,13 @@ func respondError(w http.ResponseWriter, message string, code int) {
 	w.WriteHeader(code)
 	respondJSON(w, ErrorResponse{Message: message})
 }
+
+// getClientIP returns the IP address of the client that sent the request, without the port.
+// The remote address is only taken from forwarded headers if the request came from a trusted proxy.
+func getClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -28,11 +28,17 @@ import (
 // testHarness encapsulates all the dependencies needed for testing
 type testHarness struct {
 	Server          *app.Server
+	Config          *app.Config
 	DB              db.TestDatabase
 	Storage         storage.Manager
+	Keyring         auth.Keyring
 	JWTManager      auth.JWTManager
 	SessionManager  auth.SessionManager
 	CookieManager   auth.CookieManager
+	LoginLimiter    auth.LoginLimiter
+	Authenticator   auth.Authenticator
+	UserTokens      auth.UserTokenManager
+	Mailer          *MockMailer
 	AdminTestUser   *testUser
 	RegularTestUser *testUser
 	TempDirectory   string
@@ -45,9 +51,15 @@ type testUser struct {
 	session     *models.Session
 }
 
-// setupTestHarness creates a new test environment
+// setupTestHarness creates a new test environment with an in-memory database
 func setupTestHarness(t *testing.T) *testHarness {
 	t.Helper()
+	return setupTestHarnessWithDB(t, ":memory:")
+}
+
+// setupTestHarnessWithDB creates a new test environment with the database at dbPath
+func setupTestHarnessWithDB(t *testing.T, dbPath string) *testHarness {
+	t.Helper()
 
 	// Create temporary directory for test files
 	tempDir, err := os.MkdirTemp("", "lemma-test-*")
@@ -61,7 +73,7 @@ func setupTestHarness(t *testing.T) *testHarness {
 		t.Fatalf("Failed to initialize secrets service: %v", err)
 	}
 
-	database, err := db.NewTestDB(":memory:", secretsSvc)
+	database, err := db.NewTestDB(dbPath, secretsSvc)
 	if err != nil {
 		t.Fatalf("Failed to initialize test database: %v", err)
 	}
@@ -78,12 +90,21 @@ func setupTestHarness(t *testing.T) *testHarness {
 		NewGitClient: func(url, user, token, path, commitName, commitEmail string) git.Client {
 			return mockGit
 		},
+		Quotas: database,
+		Usage:  database,
 	}
 	storageSvc := storage.NewServiceWithOptions(tempDir, storageOpts)
 
-	// Initialize JWT service
+	// Initialize JWT keyring and service
+	keyring, err := auth.NewKeyring(database, auth.KeyringConfig{
+		RetentionPeriod: 24 * time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("Failed to initialize JWT keyring: %v", err)
+	}
+
 	jwtSvc, err := auth.NewJWTService(auth.JWTConfig{
-		SigningKey:         "test-key",
+		Keyring:            keyring,
 		AccessTokenExpiry:  15 * time.Minute,
 		RefreshTokenExpiry: 24 * time.Hour,
 	})
@@ -97,42 +118,52 @@ func setupTestHarness(t *testing.T) *testHarness {
 	// Initialize cookie service
 	cookieSvc := auth.NewCookieService(true, "localhost")
 
+	// Initialize login limiter. The delays are long enough not to pass between the requests
+	// of a test, however slowly they run.
+	loginLimiter := auth.NewLoginLimiter(database, auth.LoginLimiterConfig{
+		BaseDelay: time.Minute,
+		MaxDelay:  time.Minute,
+	})
+
+	// Initialize authenticator
+	authenticator := auth.NewLocalAuthenticator(database)
+
+	// Initialize user tokens and mock mailer
+	userTokens := auth.NewUserTokenManager(database, auth.UserTokenConfig{})
+	mockMailer := NewMockMailer()
+
 	// Create test config
 	testConfig := &app.Config{
-		DBPath:        ":memory:",
+		DBPath:        dbPath,
 		WorkDir:       tempDir,
 		StaticPath:    "../testdata",
 		Port:          "8081",
 		AdminEmail:    "admin@test.com",
 		AdminPassword: "admin123",
+		RootURL:       "http://localhost:8081",
 		EncryptionKey: "YWJjZGVmZ2hpamtsbW5vcHFyc3R1dnd4eXoxMjM0NTY=",
 		IsDevelopment: true,
 	}
 
-	// Create server options
-	serverOpts := &app.Options{
-		Config:         testConfig,
-		Database:       database,
-		Storage:        storageSvc,
-		JWTManager:     jwtSvc,
-		SessionManager: sessionSvc,
-		CookieService:  cookieSvc,
-	}
-
-	// Create server
-	srv := app.NewServer(serverOpts)
-
 	h := &testHarness{
-		Server:         srv,
+		Config:         testConfig,
 		DB:             database,
 		Storage:        storageSvc,
+		Keyring:        keyring,
 		JWTManager:     jwtSvc,
 		SessionManager: sessionSvc,
 		CookieManager:  cookieSvc,
+		LoginLimiter:   loginLimiter,
+		Authenticator:  authenticator,
+		UserTokens:     userTokens,
+		Mailer:         mockMailer,
 		TempDirectory:  tempDir,
 		MockGit:        mockGit,
 	}
 
+	// Create server
+	h.restartServer()
+
 	// Create test users
 	adminTestUser := h.createTestUser(t, "admin@test.com", "admin123", models.RoleAdmin)
 	regularTestUser := h.createTestUser(t, "user@test.com", "user123", models.RoleEditor)
@@ -143,6 +174,23 @@ func setupTestHarness(t *testing.T) *testHarness {
 	return h
 }
 
+// restartServer creates a new server from the harness dependencies, applying changes to h.Config
+func (h *testHarness) restartServer() {
+	h.Server = app.NewServer(&app.Options{
+		Config:         h.Config,
+		Database:       h.DB,
+		Storage:        h.Storage,
+		Keyring:        h.Keyring,
+		JWTManager:     h.JWTManager,
+		SessionManager: h.SessionManager,
+		CookieService:  h.CookieManager,
+		LoginLimiter:   h.LoginLimiter,
+		Authenticator:  h.Authenticator,
+		UserTokens:     h.UserTokens,
+		Mailer:         h.Mailer,
+	})
+}
+
 // teardownTestHarness cleans up the test environment
 func (h *testHarness) teardown(t *testing.T) {
 	t.Helper()
@@ -183,7 +231,7 @@ func (h *testHarness) createTestUser(t *testing.T, email, password string, role
 		t.Fatalf("Failed to initialize user workspace: %v", err)
 	}
 
-	session, accessToken, err := h.SessionManager.CreateSession(user.ID, string(user.Role))
+	session, accessToken, err := h.SessionManager.CreateSession(user.ID, string(user.Role), "test-agent", "127.0.0.1")
 	if err != nil {
 		t.Fatalf("Failed to create session: %v", err)
 	}
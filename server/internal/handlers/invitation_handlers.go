@@ -0,0 +1,552 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"lemma/internal/auth"
+	"lemma/internal/context"
+	"lemma/internal/db"
+	"lemma/internal/logging"
+	"lemma/internal/mailer"
+	"lemma/internal/models"
+
+	"github.com/go-chi/chi/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// defaultInvitationExpiry is how long an invitation is valid if no expiry is given
+const defaultInvitationExpiry = 7 * 24 * time.Hour
+
+// CreateInvitationRequest holds the request fields for creating an invitation
+type CreateInvitationRequest struct {
+	Role          models.UserRole `json:"role"`
+	Email         string          `json:"email,omitempty"`         // Restricts the invitation to this email if set
+	WorkspaceName string          `json:"workspaceName,omitempty"` // Name of the user's initial workspace, Main if empty
+	MaxUses       int             `json:"maxUses,omitempty"`       // Defaults to 1
+	ExpiresAt     *time.Time      `json:"expiresAt,omitempty"`     // Defaults to 7 days from now
+}
+
+// CreateInvitationResponse holds a new invitation with its token, which is only returned once
+type CreateInvitationResponse struct {
+	*models.Invitation
+	Token string `json:"token"`
+	Link  string `json:"link"`
+}
+
+// SignupRequest represents a user signup request
+type SignupRequest struct {
+	Email           string `json:"email"`
+	DisplayName     string `json:"displayName"`
+	Password        string `json:"password"`
+	InvitationToken string `json:"invitationToken,omitempty"` // Required unless registration is open
+}
+
+func getInvitationLogger() logging.Logger {
+	return getHandlersLogger().WithGroup("invitations")
+}
+
+// AdminListInvitations godoc
+// @Summary List invitations
+// @Description Lists all invitations including expired and used up ones
+// @Tags Admin
+// @Security CookieAuth
+// @ID adminListInvitations
+// @Produce json
+// @Success 200 {array} models.Invitation
+// @Failure 500 {object} ErrorResponse "Failed to list invitations"
+// @Router /admin/invitations [get]
+func (h *Handler) AdminListInvitations() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := context.GetRequestContext(w, r)
+		if !ok {
+			return
+		}
+		log := getInvitationLogger().With(
+			"handler", "AdminListInvitations",
+			"adminID", ctx.UserID,
+			"clientIP", r.RemoteAddr,
+		)
+
+		invitations, err := h.DB.GetInvitations()
+		if err != nil {
+			log.Error("failed to fetch invitations from database",
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to list invitations", http.StatusInternalServerError)
+			return
+		}
+
+		if invitations == nil {
+			invitations = []*models.Invitation{}
+		}
+		respondJSON(w, invitations)
+	}
+}
+
+// AdminCreateInvitation godoc
+// @Summary Create invitation
+// @Description Creates an invitation link for signing up with the given role. The token is only returned in this response.
+// @Tags Admin
+// @Security CookieAuth
+// @ID adminCreateInvitation
+// @Accept json
+// @Produce json
+// @Param body body CreateInvitationRequest true "Invitation details"
+// @Success 200 {object} CreateInvitationResponse
+// @Failure 400 {object} ErrorResponse "Invalid request body"
+// @Failure 400 {object} ErrorResponse "Invalid role"
+// @Failure 400 {object} ErrorResponse "Invalid workspace name"
+// @Failure 400 {object} ErrorResponse "Max uses must not be negative"
+// @Failure 400 {object} ErrorResponse "Expiry must be in the future"
+// @Failure 500 {object} ErrorResponse "Failed to create invitation"
+// @Router /admin/invitations [post]
+func (h *Handler) AdminCreateInvitation(rootURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := context.GetRequestContext(w, r)
+		if !ok {
+			return
+		}
+		log := getInvitationLogger().With(
+			"handler", "AdminCreateInvitation",
+			"adminID", ctx.UserID,
+			"clientIP", r.RemoteAddr,
+		)
+
+		var req CreateInvitationRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			log.Debug("failed to decode request body",
+				"error", err.Error(),
+			)
+			respondError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if !req.Role.Valid() {
+			log.Debug("invalid role",
+				"role", req.Role,
+			)
+			respondError(w, "Invalid role", http.StatusBadRequest)
+			return
+		}
+
+		req.WorkspaceName = strings.TrimSpace(req.WorkspaceName)
+		if !validWorkspaceName(req.WorkspaceName) {
+			respondError(w, "Invalid workspace name", http.StatusBadRequest)
+			return
+		}
+
+		if req.MaxUses < 0 {
+			respondError(w, "Max uses must not be negative", http.StatusBadRequest)
+			return
+		}
+		if req.MaxUses == 0 {
+			req.MaxUses = 1
+		}
+
+		expiresAt := time.Now().Add(defaultInvitationExpiry)
+		if req.ExpiresAt != nil {
+			if !req.ExpiresAt.After(time.Now()) {
+				respondError(w, "Expiry must be in the future", http.StatusBadRequest)
+				return
+			}
+			expiresAt = *req.ExpiresAt
+		}
+
+		token, err := auth.GenerateToken()
+		if err != nil {
+			log.Error("failed to generate invitation token",
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to create invitation", http.StatusInternalServerError)
+			return
+		}
+
+		invitation := &models.Invitation{
+			TokenHash:     auth.HashToken(token),
+			Role:          req.Role,
+			Email:         strings.TrimSpace(req.Email),
+			WorkspaceName: req.WorkspaceName,
+			MaxUses:       req.MaxUses,
+			ExpiresAt:     expiresAt,
+			CreatedBy:     ctx.UserID,
+		}
+		if err := h.DB.CreateInvitation(invitation); err != nil {
+			log.Error("failed to create invitation in database",
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to create invitation", http.StatusInternalServerError)
+			return
+		}
+
+		h.audit(r, "invitation created", &models.AuditEvent{
+			Event:      "invitation_created",
+			ActorID:    &ctx.UserID,
+			TargetType: models.AuditTargetInvitation,
+			TargetID:   strconv.Itoa(invitation.ID),
+			Details:    map[string]any{"role": invitation.Role, "email": invitation.Email, "maxUses": invitation.MaxUses},
+		})
+		respondJSON(w, CreateInvitationResponse{
+			Invitation: invitation,
+			Token:      token,
+			Link:       accountLink(rootURL, "signup", token),
+		})
+	}
+}
+
+// AdminDeleteInvitation godoc
+// @Summary Delete invitation
+// @Description Deletes an invitation so it can no longer be used to sign up
+// @Tags Admin
+// @Security CookieAuth
+// @ID adminDeleteInvitation
+// @Param invitationId path int true "Invitation ID"
+// @Success 204 "No Content - Invitation deleted successfully"
+// @Failure 400 {object} ErrorResponse "Invalid invitation ID"
+// @Failure 404 {object} ErrorResponse "Invitation not found"
+// @Router /admin/invitations/{invitationId} [delete]
+func (h *Handler) AdminDeleteInvitation() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := context.GetRequestContext(w, r)
+		if !ok {
+			return
+		}
+		log := getInvitationLogger().With(
+			"handler", "AdminDeleteInvitation",
+			"adminID", ctx.UserID,
+			"clientIP", r.RemoteAddr,
+		)
+
+		invitationID, err := strconv.Atoi(chi.URLParam(r, "invitationId"))
+		if err != nil {
+			log.Debug("invalid invitation ID format",
+				"invitationIDParam", chi.URLParam(r, "invitationId"),
+				"error", err.Error(),
+			)
+			respondError(w, "Invalid invitation ID", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.DB.DeleteInvitation(invitationID); err != nil {
+			log.Debug("failed to delete invitation",
+				"invitationID", invitationID,
+				"error", err.Error(),
+			)
+			respondError(w, "Invitation not found", http.StatusNotFound)
+			return
+		}
+
+		h.audit(r, "invitation deleted", &models.AuditEvent{
+			Event:      "invitation_deleted",
+			ActorID:    &ctx.UserID,
+			TargetType: models.AuditTargetInvitation,
+			TargetID:   strconv.Itoa(invitationID),
+		})
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// AdminGetRegistrationSettings godoc
+// @Summary Get registration settings
+// @Description Gets whether users can sign up without an invitation and the role they get
+// @Tags Admin
+// @Security CookieAuth
+// @ID adminGetRegistrationSettings
+// @Produce json
+// @Success 200 {object} models.RegistrationSettings
+// @Failure 500 {object} ErrorResponse "Failed to get registration settings"
+// @Router /admin/settings/registration [get]
+func (h *Handler) AdminGetRegistrationSettings() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := context.GetRequestContext(w, r)
+		if !ok {
+			return
+		}
+		log := getInvitationLogger().With(
+			"handler", "AdminGetRegistrationSettings",
+			"adminID", ctx.UserID,
+			"clientIP", r.RemoteAddr,
+		)
+
+		settings, err := h.DB.GetRegistrationSettings()
+		if err != nil {
+			log.Error("failed to fetch registration settings",
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to get registration settings", http.StatusInternalServerError)
+			return
+		}
+
+		respondJSON(w, settings)
+	}
+}
+
+// AdminUpdateRegistrationSettings godoc
+// @Summary Update registration settings
+// @Description Sets whether users can sign up without an invitation, the role they get and whether they have to confirm their email address.
+// @Description The default role can be editor or viewer.
+// @Tags Admin
+// @Security CookieAuth
+// @ID adminUpdateRegistrationSettings
+// @Accept json
+// @Produce json
+// @Param body body models.RegistrationSettings true "Registration settings"
+// @Success 200 {object} models.RegistrationSettings
+// @Failure 400 {object} ErrorResponse "Invalid request body"
+// @Failure 400 {object} ErrorResponse "Invalid default role"
+// @Failure 500 {object} ErrorResponse "Failed to update registration settings"
+// @Router /admin/settings/registration [put]
+func (h *Handler) AdminUpdateRegistrationSettings() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := context.GetRequestContext(w, r)
+		if !ok {
+			return
+		}
+		log := getInvitationLogger().With(
+			"handler", "AdminUpdateRegistrationSettings",
+			"adminID", ctx.UserID,
+			"clientIP", r.RemoteAddr,
+		)
+
+		var settings models.RegistrationSettings
+		if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+			log.Debug("failed to decode request body",
+				"error", err.Error(),
+			)
+			respondError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if !settings.ValidDefaultRole() {
+			log.Debug("invalid default role",
+				"role", settings.DefaultRole,
+			)
+			respondError(w, "Invalid default role", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.DB.UpdateRegistrationSettings(&settings); err != nil {
+			log.Error("failed to update registration settings",
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to update registration settings", http.StatusInternalServerError)
+			return
+		}
+
+		h.audit(r, "registration settings updated", &models.AuditEvent{
+			Event:      "registration_settings_updated",
+			ActorID:    &ctx.UserID,
+			TargetType: models.AuditTargetSystem,
+			Details: map[string]any{
+				"openRegistration":         settings.OpenRegistration,
+				"defaultRole":              settings.DefaultRole,
+				"requireEmailVerification": settings.RequireEmailVerification,
+			},
+		})
+		respondJSON(w, settings)
+	}
+}
+
+// Signup godoc
+// @Summary Sign up
+// @Description Creates a new user with an invitation token, or with the default role if registration is open.
+// @Description If email verification is required, users signing up without an invitation are sent a link to confirm
+// @Description their email address with /auth/verify-email and can't log in before.
+// @Tags auth
+// @ID signup
+// @Accept json
+// @Produce json
+// @Param body body SignupRequest true "Signup request"
+// @Success 200 {object} models.User
+// @Failure 400 {object} ErrorResponse "Invalid request body"
+// @Failure 400 {object} ErrorResponse "Email and password are required"
+// @Failure 400 {object} ErrorResponse "Invalid email address"
+// @Failure 400 {object} ErrorResponse "Password must be at least 8 characters"
+// @Failure 400 {object} ErrorResponse "Invalid or expired invitation"
+// @Failure 403 {object} ErrorResponse "Invitation is not valid for this email"
+// @Failure 403 {object} ErrorResponse "Registration requires an invitation"
+// @Failure 409 {object} ErrorResponse "Email already exists"
+// @Failure 500 {object} ErrorResponse "Failed to get registration settings"
+// @Failure 500 {object} ErrorResponse "Failed to hash password"
+// @Failure 500 {object} ErrorResponse "Failed to create user"
+// @Failure 500 {object} ErrorResponse "Failed to initialize user workspace"
+// @Failure 500 {object} ErrorResponse "Failed to send verification email"
+// @Router /auth/signup [post]
+func (h *Handler) Signup(userTokens auth.UserTokenManager, m mailer.Mailer, rootURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := getInvitationLogger().With(
+			"handler", "Signup",
+			"clientIP", r.RemoteAddr,
+		)
+
+		var req SignupRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			log.Debug("failed to decode request body",
+				"error", err.Error(),
+			)
+			respondError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if req.Email == "" || req.Password == "" {
+			log.Debug("missing required fields",
+				"hasEmail", req.Email != "",
+				"hasPassword", req.Password != "",
+			)
+			respondError(w, "Email and password are required", http.StatusBadRequest)
+			return
+		}
+
+		if address, err := mail.ParseAddress(req.Email); err != nil || address.Address != req.Email {
+			respondError(w, "Invalid email address", http.StatusBadRequest)
+			return
+		}
+
+		if len(req.Password) < 8 {
+			log.Debug("password too short",
+				"passwordLength", len(req.Password),
+			)
+			respondError(w, "Password must be at least 8 characters", http.StatusBadRequest)
+			return
+		}
+
+		user := &models.User{
+			Email:       req.Email,
+			DisplayName: req.DisplayName,
+		}
+
+		var invitation *models.Invitation
+		if req.InvitationToken != "" {
+			var err error
+			invitation, err = h.DB.GetInvitationByTokenHash(auth.HashToken(req.InvitationToken))
+			if err != nil || !invitation.Usable(time.Now()) {
+				log.Warn("signup with invalid invitation")
+				respondError(w, "Invalid or expired invitation", http.StatusBadRequest)
+				return
+			}
+
+			if invitation.Email != "" && !strings.EqualFold(invitation.Email, req.Email) {
+				log.Warn("signup with invitation for another email",
+					"invitationID", invitation.ID,
+					"email", req.Email,
+				)
+				respondError(w, "Invitation is not valid for this email", http.StatusForbidden)
+				return
+			}
+			user.Role = invitation.Role
+		} else {
+			settings, err := h.DB.GetRegistrationSettings()
+			if err != nil {
+				log.Error("failed to fetch registration settings",
+					"error", err.Error(),
+				)
+				respondError(w, "Failed to get registration settings", http.StatusInternalServerError)
+				return
+			}
+
+			if !settings.OpenRegistration {
+				log.Debug("signup without invitation while registration is closed")
+				respondError(w, "Registration requires an invitation", http.StatusForbidden)
+				return
+			}
+			// Settings stored before admin was rejected as default role must not hand it out
+			if !settings.ValidDefaultRole() {
+				log.Error("signup rejected because of an invalid default role",
+					"role", settings.DefaultRole,
+				)
+				respondError(w, "Registration requires an invitation", http.StatusForbidden)
+				return
+			}
+			user.Role = settings.DefaultRole
+			user.EmailVerificationPending = settings.RequireEmailVerification
+		}
+
+		if emailTaken, err := h.DB.EmailExists(req.Email); err == nil && emailTaken {
+			log.Debug("signup with existing email",
+				"email", req.Email,
+			)
+			respondError(w, "Email already exists", http.StatusConflict)
+			return
+		}
+
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			log.Error("failed to hash password",
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to hash password", http.StatusInternalServerError)
+			return
+		}
+		user.PasswordHash = string(hashedPassword)
+
+		var insertedUser *models.User
+		if invitation != nil {
+			insertedUser, err = h.DB.CreateUserWithInvitation(user, invitation.ID)
+		} else {
+			insertedUser, err = h.DB.CreateUser(user)
+		}
+		if errors.Is(err, db.ErrInvitationUnavailable) {
+			log.Warn("invitation used up during signup",
+				"invitationID", invitation.ID,
+			)
+			respondError(w, "Invalid or expired invitation", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			log.Error("failed to create user in database",
+				"error", err.Error(),
+				"email", req.Email,
+			)
+			respondError(w, "Failed to create user", http.StatusInternalServerError)
+			return
+		}
+
+		if err := h.Storage.InitializeUserWorkspace(insertedUser.ID, insertedUser.LastWorkspaceID); err != nil {
+			log.Error("failed to initialize user workspace",
+				"error", err.Error(),
+				"userID", insertedUser.ID,
+				"workspaceID", insertedUser.LastWorkspaceID,
+			)
+			respondError(w, "Failed to initialize user workspace", http.StatusInternalServerError)
+			return
+		}
+
+		details := map[string]any{"email": insertedUser.Email, "role": insertedUser.Role}
+		if invitation != nil {
+			details["invitationID"] = invitation.ID
+		}
+		if insertedUser.EmailVerificationPending {
+			details["emailVerificationPending"] = true
+		}
+		h.audit(r, "user signed up", &models.AuditEvent{
+			Event:      "user_signup",
+			ActorID:    &insertedUser.ID,
+			TargetType: models.AuditTargetUser,
+			TargetID:   strconv.Itoa(insertedUser.ID),
+			Details:    details,
+		})
+
+		// The email address is confirmed like a changed one, with a token for the same address
+		if insertedUser.EmailVerificationPending {
+			token, err := userTokens.Issue(insertedUser.ID, models.UserTokenPurposeEmailChange, insertedUser.Email)
+			if err == nil {
+				err = m.Send(signupVerificationMessage(insertedUser.Email, accountLink(rootURL, "verify-email", token)))
+			}
+			if err != nil {
+				log.Error("failed to send verification email",
+					"error", err.Error(),
+					"userID", insertedUser.ID,
+				)
+				respondError(w, "Failed to send verification email", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		respondJSON(w, insertedUser)
+	}
+}
@@ -0,0 +1,246 @@
+//go:build integration
+
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"lemma/internal/handlers"
+	"lemma/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInvitationHandlers_Integration(t *testing.T) {
+	h := setupTestHarness(t)
+	defer h.teardown(t)
+
+	createInvitation := func(t *testing.T, req handlers.CreateInvitationRequest) handlers.CreateInvitationResponse {
+		t.Helper()
+		rr := h.makeRequest(t, http.MethodPost, "/api/v1/admin/invitations", req, h.AdminTestUser)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var resp handlers.CreateInvitationResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+		return resp
+	}
+
+	signup := func(t *testing.T, req handlers.SignupRequest) int {
+		t.Helper()
+		rr := h.makeRequest(t, http.MethodPost, "/api/v1/auth/signup", req, nil)
+		return rr.Code
+	}
+
+	t.Run("invitation management", func(t *testing.T) {
+		t.Run("create invitation", func(t *testing.T) {
+			resp := createInvitation(t, handlers.CreateInvitationRequest{Role: models.RoleViewer})
+
+			assert.NotEmpty(t, resp.Token)
+			assert.Equal(t, fmt.Sprintf("http://localhost:8081/signup?token=%s", resp.Token), resp.Link)
+			assert.Equal(t, models.RoleViewer, resp.Role)
+			assert.Equal(t, 1, resp.MaxUses)
+			assert.Equal(t, h.AdminTestUser.session.UserID, resp.CreatedBy)
+			assert.WithinDuration(t, time.Now().Add(7*24*time.Hour), resp.ExpiresAt, time.Minute)
+		})
+
+		t.Run("invalid requests", func(t *testing.T) {
+			past := time.Now().Add(-time.Hour)
+			for _, req := range []handlers.CreateInvitationRequest{
+				{Role: "superuser"},
+				{Role: models.RoleEditor, MaxUses: -1},
+				{Role: models.RoleEditor, ExpiresAt: &past},
+			} {
+				rr := h.makeRequest(t, http.MethodPost, "/api/v1/admin/invitations", req, h.AdminTestUser)
+				assert.Equal(t, http.StatusBadRequest, rr.Code)
+			}
+		})
+
+		t.Run("non-admin access", func(t *testing.T) {
+			rr := h.makeRequest(t, http.MethodPost, "/api/v1/admin/invitations",
+				handlers.CreateInvitationRequest{Role: models.RoleAdmin}, h.RegularTestUser)
+			assert.Equal(t, http.StatusForbidden, rr.Code)
+
+			rr = h.makeRequest(t, http.MethodGet, "/api/v1/admin/invitations", nil, h.RegularTestUser)
+			assert.Equal(t, http.StatusForbidden, rr.Code)
+		})
+
+		t.Run("list and delete invitations", func(t *testing.T) {
+			resp := createInvitation(t, handlers.CreateInvitationRequest{Role: models.RoleEditor})
+
+			rr := h.makeRequest(t, http.MethodGet, "/api/v1/admin/invitations", nil, h.AdminTestUser)
+			require.Equal(t, http.StatusOK, rr.Code)
+			assert.NotContains(t, rr.Body.String(), resp.Token)
+
+			var invitations []*models.Invitation
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&invitations))
+			require.NotEmpty(t, invitations)
+			assert.Equal(t, resp.ID, invitations[0].ID)
+
+			path := fmt.Sprintf("/api/v1/admin/invitations/%d", resp.ID)
+			rr = h.makeRequest(t, http.MethodDelete, path, nil, h.AdminTestUser)
+			require.Equal(t, http.StatusNoContent, rr.Code)
+
+			rr = h.makeRequest(t, http.MethodDelete, path, nil, h.AdminTestUser)
+			assert.Equal(t, http.StatusNotFound, rr.Code)
+
+			// Deleted invitations can't be redeemed
+			assert.Equal(t, http.StatusBadRequest, signup(t, handlers.SignupRequest{
+				Email:           "deleted-invite@test.com",
+				Password:        "password123",
+				InvitationToken: resp.Token,
+			}))
+		})
+	})
+
+	t.Run("signup with invitation", func(t *testing.T) {
+		t.Run("role from invitation and max uses", func(t *testing.T) {
+			resp := createInvitation(t, handlers.CreateInvitationRequest{Role: models.RoleViewer, MaxUses: 2})
+
+			for i := 1; i <= 2; i++ {
+				rr := h.makeRequest(t, http.MethodPost, "/api/v1/auth/signup", handlers.SignupRequest{
+					Email:           fmt.Sprintf("invited%d@test.com", i),
+					DisplayName:     "Invited User",
+					Password:        "password123",
+					InvitationToken: resp.Token,
+				}, nil)
+				require.Equal(t, http.StatusOK, rr.Code)
+
+				var user models.User
+				require.NoError(t, json.NewDecoder(rr.Body).Decode(&user))
+				assert.Equal(t, models.RoleViewer, user.Role)
+				assert.Equal(t, "Invited User", user.DisplayName)
+			}
+
+			assert.Equal(t, http.StatusBadRequest, signup(t, handlers.SignupRequest{
+				Email:           "invited3@test.com",
+				Password:        "password123",
+				InvitationToken: resp.Token,
+			}))
+		})
+
+		t.Run("email restriction", func(t *testing.T) {
+			resp := createInvitation(t, handlers.CreateInvitationRequest{Role: models.RoleEditor, Email: "Restricted@test.com"})
+
+			assert.Equal(t, http.StatusForbidden, signup(t, handlers.SignupRequest{
+				Email:           "someone-else@test.com",
+				Password:        "password123",
+				InvitationToken: resp.Token,
+			}))
+			assert.Equal(t, http.StatusOK, signup(t, handlers.SignupRequest{
+				Email:           "restricted@test.com",
+				Password:        "password123",
+				InvitationToken: resp.Token,
+			}))
+		})
+
+		t.Run("invalid requests", func(t *testing.T) {
+			resp := createInvitation(t, handlers.CreateInvitationRequest{Role: models.RoleEditor})
+
+			testCases := []struct {
+				name     string
+				req      handlers.SignupRequest
+				wantCode int
+			}{
+				{"unknown invitation", handlers.SignupRequest{Email: "a@test.com", Password: "password123", InvitationToken: "invalid"}, http.StatusBadRequest},
+				{"missing password", handlers.SignupRequest{Email: "a@test.com", InvitationToken: resp.Token}, http.StatusBadRequest},
+				{"short password", handlers.SignupRequest{Email: "a@test.com", Password: "short", InvitationToken: resp.Token}, http.StatusBadRequest},
+				{"invalid email", handlers.SignupRequest{Email: "not-an-email", Password: "password123", InvitationToken: resp.Token}, http.StatusBadRequest},
+				{"existing email", handlers.SignupRequest{Email: h.RegularTestUser.userModel.Email, Password: "password123", InvitationToken: resp.Token}, http.StatusConflict},
+			}
+
+			for _, tc := range testCases {
+				t.Run(tc.name, func(t *testing.T) {
+					assert.Equal(t, tc.wantCode, signup(t, tc.req))
+				})
+			}
+
+			// Rejected signups don't use up the invitation
+			assert.Equal(t, http.StatusOK, signup(t, handlers.SignupRequest{
+				Email:           "finally@test.com",
+				Password:        "password123",
+				InvitationToken: resp.Token,
+			}))
+		})
+	})
+
+	t.Run("open registration", func(t *testing.T) {
+		req := handlers.SignupRequest{Email: "open@test.com", Password: "password123"}
+
+		// Registration is closed by default
+		rr := h.makeRequest(t, http.MethodGet, "/api/v1/admin/settings/registration", nil, h.AdminTestUser)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var settings models.RegistrationSettings
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&settings))
+		assert.False(t, settings.OpenRegistration)
+		assert.Equal(t, models.RoleEditor, settings.DefaultRole)
+		assert.True(t, settings.RequireEmailVerification)
+		assert.Equal(t, http.StatusForbidden, signup(t, req))
+
+		// Anyone can sign up, so the default role can't be admin
+		for _, role := range []models.UserRole{"superuser", models.RoleAdmin} {
+			rr = h.makeRequest(t, http.MethodPut, "/api/v1/admin/settings/registration",
+				models.RegistrationSettings{OpenRegistration: true, DefaultRole: role}, h.AdminTestUser)
+			assert.Equal(t, http.StatusBadRequest, rr.Code, role)
+		}
+
+		rr = h.makeRequest(t, http.MethodPut, "/api/v1/admin/settings/registration",
+			models.RegistrationSettings{OpenRegistration: true, DefaultRole: models.RoleViewer}, h.RegularTestUser)
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+
+		rr = h.makeRequest(t, http.MethodPut, "/api/v1/admin/settings/registration",
+			models.RegistrationSettings{OpenRegistration: true, DefaultRole: models.RoleViewer, RequireEmailVerification: true}, h.AdminTestUser)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		rr = h.makeRequest(t, http.MethodPost, "/api/v1/auth/signup", req, nil)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var user models.User
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&user))
+		assert.Equal(t, models.RoleViewer, user.Role)
+		assert.True(t, user.EmailVerificationPending)
+
+		login := func(t *testing.T, email, password string) int {
+			t.Helper()
+			loginReq := h.newRequest(t, http.MethodPost, "/api/v1/auth/login", handlers.LoginRequest{
+				Email:    email,
+				Password: password,
+			})
+			loginReq.RemoteAddr = "192.0.2.31:1234"
+			return h.executeRequest(loginReq).Code
+		}
+
+		// The new user can log in after confirming their email address
+		assert.Equal(t, http.StatusForbidden, login(t, req.Email, req.Password))
+
+		messages := h.Mailer.Messages(req.Email)
+		require.Len(t, messages, 1)
+		assert.Contains(t, messages[0].Body, "http://localhost:8081/verify-email?token=")
+		token, err := h.Mailer.LastToken(req.Email)
+		require.NoError(t, err)
+		rr = h.makeRequest(t, http.MethodPost, "/api/v1/auth/verify-email", handlers.VerifyEmailRequest{Token: token}, nil)
+		require.Equal(t, http.StatusNoContent, rr.Code)
+
+		assert.Equal(t, http.StatusOK, login(t, req.Email, req.Password))
+
+		events, _, err := h.DB.GetAuditEvents(models.AuditEventFilter{Event: "email_verified", Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		assert.Equal(t, user.ID, *events[0].ActorID)
+
+		// Without required verification, users can log in right away
+		rr = h.makeRequest(t, http.MethodPut, "/api/v1/admin/settings/registration",
+			models.RegistrationSettings{OpenRegistration: true, DefaultRole: models.RoleViewer}, h.AdminTestUser)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		unverified := handlers.SignupRequest{Email: "unverified@test.com", Password: "password123"}
+		assert.Equal(t, http.StatusOK, signup(t, unverified))
+		assert.Empty(t, h.Mailer.Messages(unverified.Email))
+		assert.Equal(t, http.StatusOK, login(t, unverified.Email, unverified.Password))
+	})
+}
@@ -3,6 +3,7 @@
 package handlers_test
 
 import (
+	"context"
 	"fmt"
 	"lemma/internal/git"
 )
@@ -33,7 +34,7 @@ func NewMockGitClient(shouldError bool) *MockGitClient {
 }
 
 // Clone implements git.Client
-func (m *MockGitClient) Clone() error {
+func (m *MockGitClient) Clone(_ context.Context) error {
 	if m.error != nil {
 		return m.error
 	}
@@ -43,7 +44,7 @@ func (m *MockGitClient) Clone() error {
 }
 
 // Pull implements git.Client
-func (m *MockGitClient) Pull() error {
+func (m *MockGitClient) Pull(_ context.Context) error {
 	if m.error != nil {
 		return m.error
 	}
@@ -71,7 +72,7 @@ func (m *MockGitClient) Push() error {
 }
 
 // EnsureRepo implements git.Client
-func (m *MockGitClient) EnsureRepo() error {
+func (m *MockGitClient) EnsureRepo(_ context.Context) error {
 	if m.error != nil {
 		return m.error
 	}
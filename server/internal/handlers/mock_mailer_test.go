@@ -0,0 +1,61 @@
+//go:build integration
+
+package handlers_test
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sync"
+
+	"lemma/internal/mailer"
+)
+
+var tokenLinkPattern = regexp.MustCompile(`token=([^\s]+)`)
+
+// MockMailer implements the mailer.Mailer interface for testing
+type MockMailer struct {
+	mu       sync.Mutex
+	messages []mailer.Message
+}
+
+// NewMockMailer creates a new mock mailer
+func NewMockMailer() *MockMailer {
+	return &MockMailer{}
+}
+
+// Send implements mailer.Mailer
+func (m *MockMailer) Send(msg mailer.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// Messages returns all messages sent to the given address
+func (m *MockMailer) Messages(to string) []mailer.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var messages []mailer.Message
+	for _, msg := range m.messages {
+		if msg.To == to {
+			messages = append(messages, msg)
+		}
+	}
+	return messages
+}
+
+// LastToken returns the token of the link in the last message sent to the given address
+func (m *MockMailer) LastToken(to string) (string, error) {
+	messages := m.Messages(to)
+	if len(messages) == 0 {
+		return "", fmt.Errorf("no message sent to %s", to)
+	}
+
+	match := tokenLinkPattern.FindStringSubmatch(messages[len(messages)-1].Body)
+	if match == nil {
+		return "", fmt.Errorf("no token link in message to %s", to)
+	}
+	return url.QueryUnescape(match[1])
+}
@@ -0,0 +1,110 @@
+//go:build integration
+
+package handlers_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"lemma/internal/handlers"
+	"lemma/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPermissions_Integration(t *testing.T) {
+	h := setupTestHarness(t)
+	defer h.teardown(t)
+
+	roles := []models.UserRole{models.RoleAdmin, models.RoleEditor, models.RoleViewer}
+
+	for _, role := range roles {
+		t.Run(string(role), func(t *testing.T) {
+			user := h.createTestUser(t, fmt.Sprintf("%s-permissions@test.com", role), "password123", role)
+
+			// Viewers can't create workspaces, so set up a Git workspace with a file directly
+			workspace := &models.Workspace{
+				UserID:               user.userModel.ID,
+				Name:                 "Permissions Workspace",
+				GitEnabled:           true,
+				GitURL:               "https://github.com/test/repo.git",
+				GitUser:              "testuser",
+				GitToken:             "testtoken",
+				GitCommitMsgTemplate: "${action} ${filename}",
+			}
+			require.NoError(t, h.DB.CreateWorkspace(workspace))
+			require.NoError(t, h.Storage.InitializeUserWorkspace(user.userModel.ID, workspace.ID))
+			require.NoError(t, h.Storage.SaveFile(user.userModel.ID, workspace.ID, "note.md", []byte("# Note")))
+			require.NoError(t, h.Storage.SetupGitRepo(user.userModel.ID, workspace.ID,
+				workspace.GitURL, workspace.GitUser, workspace.GitToken, "Test User", user.userModel.Email))
+
+			workspaceURL := "/api/v1/workspaces/" + url.PathEscape(workspace.Name)
+			canWrite := role != models.RoleViewer
+			isAdmin := role == models.RoleAdmin
+
+			testCases := []struct {
+				name       string
+				method     string
+				path       string
+				body       interface{}
+				allowed    bool
+				wantStatus int
+			}{
+				// Read access
+				{"list workspaces", http.MethodGet, "/api/v1/workspaces", nil, true, http.StatusOK},
+				{"get last workspace", http.MethodGet, "/api/v1/workspaces/last", nil, true, http.StatusOK},
+				{"update last workspace", http.MethodPut, "/api/v1/workspaces/last", map[string]string{"workspaceName": workspace.Name}, true, http.StatusNoContent},
+				{"get workspace", http.MethodGet, workspaceURL, nil, true, http.StatusOK},
+				{"list files", http.MethodGet, workspaceURL + "/files", nil, true, http.StatusOK},
+				{"get file", http.MethodGet, workspaceURL + "/files/note.md", nil, true, http.StatusOK},
+				{"lookup file", http.MethodGet, workspaceURL + "/files/lookup?filename=note.md", nil, true, http.StatusOK},
+				{"update last opened file", http.MethodPut, workspaceURL + "/files/last", handlers.UpdateLastOpenedFileRequest{FilePath: "note.md"}, true, http.StatusNoContent},
+				{"get last opened file", http.MethodGet, workspaceURL + "/files/last", nil, true, http.StatusOK},
+
+				// Write access
+				{"create workspace", http.MethodPost, "/api/v1/workspaces", &models.Workspace{Name: "Created Workspace"}, canWrite, http.StatusOK},
+				{"update workspace", http.MethodPut, workspaceURL, workspace, canWrite, http.StatusOK},
+				{"save file", http.MethodPost, workspaceURL + "/files/new.md", "# New", canWrite, http.StatusOK},
+				{"commit and push", http.MethodPost, workspaceURL + "/git/commit", map[string]string{"message": "Update"}, canWrite, http.StatusOK},
+				{"pull changes", http.MethodPost, workspaceURL + "/git/pull", nil, canWrite, http.StatusOK},
+				{"delete file", http.MethodDelete, workspaceURL + "/files/note.md", nil, canWrite, http.StatusNoContent},
+				{"delete workspace", http.MethodDelete, workspaceURL, nil, canWrite, http.StatusOK},
+
+				// Admin access
+				{"list users", http.MethodGet, "/api/v1/admin/users", nil, isAdmin, http.StatusOK},
+				{"get system stats", http.MethodGet, "/api/v1/admin/stats", nil, isAdmin, http.StatusOK},
+			}
+
+			h.MockGit.Reset()
+			for _, tc := range testCases {
+				t.Run(tc.name, func(t *testing.T) {
+					rr := h.makeRequest(t, tc.method, tc.path, tc.body, user)
+					if tc.allowed {
+						assert.Equal(t, tc.wantStatus, rr.Code)
+					} else {
+						assert.Equal(t, http.StatusForbidden, rr.Code)
+					}
+				})
+			}
+
+			if !canWrite {
+				// Nothing was changed by the rejected requests
+				content, err := h.Storage.GetFileContent(user.userModel.ID, workspace.ID, "note.md")
+				require.NoError(t, err)
+				assert.Equal(t, "# Note", string(content))
+
+				_, err = h.Storage.GetFileContent(user.userModel.ID, workspace.ID, "new.md")
+				assert.Error(t, err)
+
+				_, err = h.DB.GetWorkspaceByName(user.userModel.ID, workspace.Name)
+				assert.NoError(t, err)
+
+				assert.Equal(t, 0, h.MockGit.GetCommitCount())
+				assert.Equal(t, 0, h.MockGit.GetPullCount())
+			}
+		})
+	}
+}
@@ -0,0 +1,527 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"lemma/internal/context"
+	"lemma/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// StorageUsageResponse contains the storage used by the workspaces of a user and the quota that applies to them
+type StorageUsageResponse struct {
+	UsedBytes    int64               `json:"usedBytes"`
+	UsedFiles    int                 `json:"usedFiles"`
+	Quota        models.StorageQuota `json:"quota"`        // Zero limits are unlimited
+	DefaultQuota bool                `json:"defaultQuota"` // Whether the system default quota applies
+}
+
+// UserStorageResponse contains a user along with their storage usage
+type UserStorageResponse struct {
+	*models.User
+	Storage StorageUsageResponse `json:"storage"`
+}
+
+// getStorageUsage returns the storage usage and quota of the user
+func (h *Handler) getStorageUsage(userID int) (*StorageUsageResponse, error) {
+	usage, err := h.Storage.GetUsage(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &StorageUsageResponse{
+		UsedBytes: usage.TotalSize,
+		UsedFiles: usage.TotalFiles,
+	}
+
+	quota, err := h.DB.GetUserQuota(userID)
+	if err != nil {
+		return nil, err
+	}
+	if quota == nil {
+		response.DefaultQuota = true
+		if quota, err = h.DB.GetDefaultQuota(); err != nil {
+			return nil, err
+		}
+	}
+	response.Quota = *quota
+
+	return response, nil
+}
+
+// WorkspaceStorageResponse contains the storage used by a workspace and the quota set for it
+type WorkspaceStorageResponse struct {
+	WorkspaceID int                  `json:"workspaceId"`
+	UsedBytes   int64                `json:"usedBytes"`
+	UsedFiles   int                  `json:"usedFiles"`
+	Quota       *models.StorageQuota `json:"quota,omitempty"` // Only the quota of the owner applies if not set
+}
+
+// getWorkspaceStorage returns the storage usage and quota of the workspace
+func (h *Handler) getWorkspaceStorage(workspace *models.Workspace) (*WorkspaceStorageResponse, error) {
+	usage, err := h.Storage.GetWorkspaceUsage(workspace.UserID, workspace.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	quota, err := h.DB.GetWorkspaceQuota(workspace.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WorkspaceStorageResponse{
+		WorkspaceID: workspace.ID,
+		UsedBytes:   usage.TotalSize,
+		UsedFiles:   usage.TotalFiles,
+		Quota:       quota,
+	}, nil
+}
+
+// AdminGetDefaultQuota godoc
+// @Summary Get default storage quota
+// @Description Gets the storage quota of users without their own quota. Zero limits are unlimited.
+// @Tags Admin
+// @Security CookieAuth
+// @ID adminGetDefaultQuota
+// @Produce json
+// @Success 200 {object} models.StorageQuota
+// @Failure 500 {object} ErrorResponse "Failed to get default quota"
+// @Router /admin/settings/quota [get]
+func (h *Handler) AdminGetDefaultQuota() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := context.GetRequestContext(w, r)
+		if !ok {
+			return
+		}
+		log := getAdminLogger().With(
+			"handler", "AdminGetDefaultQuota",
+			"adminID", ctx.UserID,
+			"clientIP", r.RemoteAddr,
+		)
+
+		quota, err := h.DB.GetDefaultQuota()
+		if err != nil {
+			log.Error("failed to fetch default quota",
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to get default quota", http.StatusInternalServerError)
+			return
+		}
+
+		respondJSON(w, quota)
+	}
+}
+
+// AdminUpdateDefaultQuota godoc
+// @Summary Update default storage quota
+// @Description Sets the storage quota of users without their own quota. Zero limits are unlimited.
+// @Tags Admin
+// @Security CookieAuth
+// @ID adminUpdateDefaultQuota
+// @Accept json
+// @Produce json
+// @Param body body models.StorageQuota true "Default storage quota"
+// @Success 200 {object} models.StorageQuota
+// @Failure 400 {object} ErrorResponse "Invalid request body"
+// @Failure 400 {object} ErrorResponse "Quota limits must not be negative"
+// @Failure 500 {object} ErrorResponse "Failed to update default quota"
+// @Router /admin/settings/quota [put]
+func (h *Handler) AdminUpdateDefaultQuota() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := context.GetRequestContext(w, r)
+		if !ok {
+			return
+		}
+		log := getAdminLogger().With(
+			"handler", "AdminUpdateDefaultQuota",
+			"adminID", ctx.UserID,
+			"clientIP", r.RemoteAddr,
+		)
+
+		var quota models.StorageQuota
+		if err := json.NewDecoder(r.Body).Decode(&quota); err != nil {
+			log.Debug("failed to decode request body",
+				"error", err.Error(),
+			)
+			respondError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if !quota.Valid() {
+			respondError(w, "Quota limits must not be negative", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.DB.UpdateDefaultQuota(&quota); err != nil {
+			log.Error("failed to update default quota",
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to update default quota", http.StatusInternalServerError)
+			return
+		}
+
+		h.audit(r, "default quota updated", &models.AuditEvent{
+			Event:      "default_quota_updated",
+			ActorID:    &ctx.UserID,
+			TargetType: models.AuditTargetSystem,
+			Details:    map[string]any{"maxBytes": quota.MaxBytes, "maxFiles": quota.MaxFiles},
+		})
+		respondJSON(w, quota)
+	}
+}
+
+// AdminUpdateUserQuota godoc
+// @Summary Update a user's storage quota
+// @Description Sets the storage quota of a user, replacing the system default for them. Zero limits are unlimited.
+// @Tags Admin
+// @Security CookieAuth
+// @ID adminUpdateUserQuota
+// @Accept json
+// @Produce json
+// @Param userId path int true "User ID"
+// @Param body body models.StorageQuota true "Storage quota"
+// @Success 200 {object} StorageUsageResponse
+// @Failure 400 {object} ErrorResponse "Invalid user ID"
+// @Failure 400 {object} ErrorResponse "Invalid request body"
+// @Failure 400 {object} ErrorResponse "Quota limits must not be negative"
+// @Failure 404 {object} ErrorResponse "User not found"
+// @Failure 500 {object} ErrorResponse "Failed to update quota"
+// @Failure 500 {object} ErrorResponse "Failed to get storage usage"
+// @Router /admin/users/{userId}/quota [put]
+func (h *Handler) AdminUpdateUserQuota() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := context.GetRequestContext(w, r)
+		if !ok {
+			return
+		}
+		log := getAdminLogger().With(
+			"handler", "AdminUpdateUserQuota",
+			"adminID", ctx.UserID,
+			"clientIP", r.RemoteAddr,
+		)
+
+		userID, err := strconv.Atoi(chi.URLParam(r, "userId"))
+		if err != nil {
+			respondError(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		var quota models.StorageQuota
+		if err := json.NewDecoder(r.Body).Decode(&quota); err != nil {
+			log.Debug("failed to decode request body",
+				"error", err.Error(),
+			)
+			respondError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if !quota.Valid() {
+			respondError(w, "Quota limits must not be negative", http.StatusBadRequest)
+			return
+		}
+
+		if _, err := h.DB.GetUserByID(userID); err != nil {
+			respondError(w, "User not found", http.StatusNotFound)
+			return
+		}
+
+		if err := h.DB.SetUserQuota(userID, &quota); err != nil {
+			log.Error("failed to update user quota",
+				"targetUserID", userID,
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to update quota", http.StatusInternalServerError)
+			return
+		}
+
+		h.audit(r, "user quota updated", &models.AuditEvent{
+			Event:      "user_quota_updated",
+			ActorID:    &ctx.UserID,
+			TargetType: models.AuditTargetUser,
+			TargetID:   strconv.Itoa(userID),
+			Details:    map[string]any{"maxBytes": quota.MaxBytes, "maxFiles": quota.MaxFiles},
+		})
+
+		usage, err := h.getStorageUsage(userID)
+		if err != nil {
+			log.Error("failed to get storage usage",
+				"targetUserID", userID,
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to get storage usage", http.StatusInternalServerError)
+			return
+		}
+		respondJSON(w, usage)
+	}
+}
+
+// AdminDeleteUserQuota godoc
+// @Summary Reset a user's storage quota
+// @Description Removes the storage quota of a user, so the system default applies to them again
+// @Tags Admin
+// @Security CookieAuth
+// @ID adminDeleteUserQuota
+// @Produce json
+// @Param userId path int true "User ID"
+// @Success 200 {object} StorageUsageResponse
+// @Failure 400 {object} ErrorResponse "Invalid user ID"
+// @Failure 404 {object} ErrorResponse "User not found"
+// @Failure 500 {object} ErrorResponse "Failed to reset quota"
+// @Failure 500 {object} ErrorResponse "Failed to get storage usage"
+// @Router /admin/users/{userId}/quota [delete]
+func (h *Handler) AdminDeleteUserQuota() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := context.GetRequestContext(w, r)
+		if !ok {
+			return
+		}
+		log := getAdminLogger().With(
+			"handler", "AdminDeleteUserQuota",
+			"adminID", ctx.UserID,
+			"clientIP", r.RemoteAddr,
+		)
+
+		userID, err := strconv.Atoi(chi.URLParam(r, "userId"))
+		if err != nil {
+			respondError(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		if _, err := h.DB.GetUserByID(userID); err != nil {
+			respondError(w, "User not found", http.StatusNotFound)
+			return
+		}
+
+		if err := h.DB.DeleteUserQuota(userID); err != nil {
+			log.Error("failed to reset user quota",
+				"targetUserID", userID,
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to reset quota", http.StatusInternalServerError)
+			return
+		}
+
+		h.audit(r, "user quota reset", &models.AuditEvent{
+			Event:      "user_quota_reset",
+			ActorID:    &ctx.UserID,
+			TargetType: models.AuditTargetUser,
+			TargetID:   strconv.Itoa(userID),
+		})
+
+		usage, err := h.getStorageUsage(userID)
+		if err != nil {
+			log.Error("failed to get storage usage",
+				"targetUserID", userID,
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to get storage usage", http.StatusInternalServerError)
+			return
+		}
+		respondJSON(w, usage)
+	}
+}
+
+// AdminGetWorkspaceQuota godoc
+// @Summary Get a workspace's storage quota
+// @Description Gets the storage used by a workspace, including its Git repository, trash and revisions, and the quota set for it
+// @Tags Admin
+// @Security CookieAuth
+// @ID adminGetWorkspaceQuota
+// @Produce json
+// @Param workspaceId path int true "Workspace ID"
+// @Success 200 {object} WorkspaceStorageResponse
+// @Failure 400 {object} ErrorResponse "Invalid workspace ID"
+// @Failure 404 {object} ErrorResponse "Workspace not found"
+// @Failure 500 {object} ErrorResponse "Failed to get storage usage"
+// @Router /admin/workspaces/{workspaceId}/quota [get]
+func (h *Handler) AdminGetWorkspaceQuota() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := context.GetRequestContext(w, r)
+		if !ok {
+			return
+		}
+		log := getAdminLogger().With(
+			"handler", "AdminGetWorkspaceQuota",
+			"adminID", ctx.UserID,
+			"clientIP", r.RemoteAddr,
+		)
+
+		workspaceID, err := strconv.Atoi(chi.URLParam(r, "workspaceId"))
+		if err != nil {
+			respondError(w, "Invalid workspace ID", http.StatusBadRequest)
+			return
+		}
+
+		workspace, err := h.DB.GetWorkspaceByID(workspaceID)
+		if err != nil {
+			respondError(w, "Workspace not found", http.StatusNotFound)
+			return
+		}
+
+		usage, err := h.getWorkspaceStorage(workspace)
+		if err != nil {
+			log.Error("failed to get storage usage",
+				"workspaceID", workspaceID,
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to get storage usage", http.StatusInternalServerError)
+			return
+		}
+		respondJSON(w, usage)
+	}
+}
+
+// AdminUpdateWorkspaceQuota godoc
+// @Summary Update a workspace's storage quota
+// @Description Limits the storage used by a workspace, in addition to the quota of its owner. Zero limits are unlimited.
+// @Tags Admin
+// @Security CookieAuth
+// @ID adminUpdateWorkspaceQuota
+// @Accept json
+// @Produce json
+// @Param workspaceId path int true "Workspace ID"
+// @Param body body models.StorageQuota true "Storage quota"
+// @Success 200 {object} WorkspaceStorageResponse
+// @Failure 400 {object} ErrorResponse "Invalid workspace ID"
+// @Failure 400 {object} ErrorResponse "Invalid request body"
+// @Failure 400 {object} ErrorResponse "Quota limits must not be negative"
+// @Failure 404 {object} ErrorResponse "Workspace not found"
+// @Failure 500 {object} ErrorResponse "Failed to update quota"
+// @Failure 500 {object} ErrorResponse "Failed to get storage usage"
+// @Router /admin/workspaces/{workspaceId}/quota [put]
+func (h *Handler) AdminUpdateWorkspaceQuota() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := context.GetRequestContext(w, r)
+		if !ok {
+			return
+		}
+		log := getAdminLogger().With(
+			"handler", "AdminUpdateWorkspaceQuota",
+			"adminID", ctx.UserID,
+			"clientIP", r.RemoteAddr,
+		)
+
+		workspaceID, err := strconv.Atoi(chi.URLParam(r, "workspaceId"))
+		if err != nil {
+			respondError(w, "Invalid workspace ID", http.StatusBadRequest)
+			return
+		}
+
+		var quota models.StorageQuota
+		if err := json.NewDecoder(r.Body).Decode(&quota); err != nil {
+			log.Debug("failed to decode request body",
+				"error", err.Error(),
+			)
+			respondError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if !quota.Valid() {
+			respondError(w, "Quota limits must not be negative", http.StatusBadRequest)
+			return
+		}
+
+		workspace, err := h.DB.GetWorkspaceByID(workspaceID)
+		if err != nil {
+			respondError(w, "Workspace not found", http.StatusNotFound)
+			return
+		}
+
+		if err := h.DB.SetWorkspaceQuota(workspaceID, &quota); err != nil {
+			log.Error("failed to update workspace quota",
+				"workspaceID", workspaceID,
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to update quota", http.StatusInternalServerError)
+			return
+		}
+
+		h.audit(r, "workspace quota updated", &models.AuditEvent{
+			Event:       "workspace_quota_updated",
+			ActorID:     &ctx.UserID,
+			TargetType:  models.AuditTargetWorkspace,
+			TargetID:    strconv.Itoa(workspaceID),
+			WorkspaceID: &workspaceID,
+			Details:     map[string]any{"maxBytes": quota.MaxBytes, "maxFiles": quota.MaxFiles},
+		})
+
+		usage, err := h.getWorkspaceStorage(workspace)
+		if err != nil {
+			log.Error("failed to get storage usage",
+				"workspaceID", workspaceID,
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to get storage usage", http.StatusInternalServerError)
+			return
+		}
+		respondJSON(w, usage)
+	}
+}
+
+// AdminDeleteWorkspaceQuota godoc
+// @Summary Remove a workspace's storage quota
+// @Description Removes the storage quota of a workspace, so only the quota of its owner applies
+// @Tags Admin
+// @Security CookieAuth
+// @ID adminDeleteWorkspaceQuota
+// @Produce json
+// @Param workspaceId path int true "Workspace ID"
+// @Success 200 {object} WorkspaceStorageResponse
+// @Failure 400 {object} ErrorResponse "Invalid workspace ID"
+// @Failure 404 {object} ErrorResponse "Workspace not found"
+// @Failure 500 {object} ErrorResponse "Failed to reset quota"
+// @Failure 500 {object} ErrorResponse "Failed to get storage usage"
+// @Router /admin/workspaces/{workspaceId}/quota [delete]
+func (h *Handler) AdminDeleteWorkspaceQuota() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := context.GetRequestContext(w, r)
+		if !ok {
+			return
+		}
+		log := getAdminLogger().With(
+			"handler", "AdminDeleteWorkspaceQuota",
+			"adminID", ctx.UserID,
+			"clientIP", r.RemoteAddr,
+		)
+
+		workspaceID, err := strconv.Atoi(chi.URLParam(r, "workspaceId"))
+		if err != nil {
+			respondError(w, "Invalid workspace ID", http.StatusBadRequest)
+			return
+		}
+
+		workspace, err := h.DB.GetWorkspaceByID(workspaceID)
+		if err != nil {
+			respondError(w, "Workspace not found", http.StatusNotFound)
+			return
+		}
+
+		if err := h.DB.DeleteWorkspaceQuota(workspaceID); err != nil {
+			log.Error("failed to reset workspace quota",
+				"workspaceID", workspaceID,
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to reset quota", http.StatusInternalServerError)
+			return
+		}
+
+		h.audit(r, "workspace quota reset", &models.AuditEvent{
+			Event:       "workspace_quota_reset",
+			ActorID:     &ctx.UserID,
+			TargetType:  models.AuditTargetWorkspace,
+			TargetID:    strconv.Itoa(workspaceID),
+			WorkspaceID: &workspaceID,
+		})
+
+		usage, err := h.getWorkspaceStorage(workspace)
+		if err != nil {
+			log.Error("failed to get storage usage",
+				"workspaceID", workspaceID,
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to get storage usage", http.StatusInternalServerError)
+			return
+		}
+		respondJSON(w, usage)
+	}
+}
@@ -0,0 +1,172 @@
+//go:build integration
+
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"lemma/internal/handlers"
+	"lemma/internal/models"
+	"lemma/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuotaHandlers_Integration(t *testing.T) {
+	h := setupTestHarness(t)
+	defer h.teardown(t)
+
+	userID := h.RegularTestUser.session.UserID
+	quotaURL := fmt.Sprintf("/api/v1/admin/users/%d/quota", userID)
+	filesURL := "/api/v1/workspaces/Main/files"
+
+	getProfile := func(t *testing.T) handlers.UserStorageResponse {
+		t.Helper()
+		rr := h.makeRequest(t, http.MethodGet, "/api/v1/profile", nil, h.RegularTestUser)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var response handlers.UserStorageResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+		return response
+	}
+
+	saveFile := func(t *testing.T, path, content string) int {
+		t.Helper()
+		rr := h.makeRequestRaw(t, http.MethodPost, filesURL+"/"+path, strings.NewReader(content), h.RegularTestUser)
+		return rr.Code
+	}
+
+	require.Equal(t, http.StatusOK, saveFile(t, "notes.md", "0123456789"))
+
+	t.Run("profile shows usage and the default quota", func(t *testing.T) {
+		profile := getProfile(t)
+		assert.Equal(t, userID, profile.ID)
+		assert.Equal(t, int64(10), profile.Storage.UsedBytes)
+		assert.Equal(t, 1, profile.Storage.UsedFiles)
+		assert.True(t, profile.Storage.DefaultQuota)
+		assert.True(t, profile.Storage.Quota.Unlimited())
+	})
+
+	t.Run("default quota applies to users without their own quota", func(t *testing.T) {
+		rr := h.makeRequest(t, http.MethodPut, "/api/v1/admin/settings/quota", models.StorageQuota{MaxFiles: 1}, h.AdminTestUser)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		rr = h.makeRequest(t, http.MethodGet, "/api/v1/admin/settings/quota", nil, h.AdminTestUser)
+		require.Equal(t, http.StatusOK, rr.Code)
+		var quota models.StorageQuota
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&quota))
+		assert.Equal(t, 1, quota.MaxFiles)
+
+		assert.Equal(t, http.StatusInsufficientStorage, saveFile(t, "other.md", "x"))
+		assert.Equal(t, http.StatusOK, saveFile(t, "notes.md", "replaced"))
+
+		rr = h.makeRequest(t, http.MethodPut, "/api/v1/admin/settings/quota", models.StorageQuota{MaxFiles: -1}, h.AdminTestUser)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+		rr = h.makeRequest(t, http.MethodPut, "/api/v1/admin/settings/quota", models.StorageQuota{}, h.RegularTestUser)
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("user quota replaces the default", func(t *testing.T) {
+		// The revision of the replaced content uses bytes, but doesn't count as file
+		usedBytes := getProfile(t).Storage.UsedBytes
+		assert.Greater(t, usedBytes, int64(8))
+
+		rr := h.makeRequest(t, http.MethodPut, quotaURL, models.StorageQuota{MaxBytes: usedBytes + 2}, h.AdminTestUser)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var usage handlers.StorageUsageResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&usage))
+		assert.False(t, usage.DefaultQuota)
+		assert.Equal(t, usedBytes+2, usage.Quota.MaxBytes)
+		assert.Equal(t, usedBytes, usage.UsedBytes)
+		assert.Equal(t, 1, usage.UsedFiles)
+
+		assert.Equal(t, http.StatusOK, saveFile(t, "other.md", "x"))
+		assert.Equal(t, http.StatusInsufficientStorage, saveFile(t, "more.md", "xyz"))
+
+		rr = h.makeRequest(t, http.MethodGet, fmt.Sprintf("/api/v1/admin/users/%d", userID), nil, h.AdminTestUser)
+		require.Equal(t, http.StatusOK, rr.Code)
+		var user handlers.UserStorageResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&user))
+		assert.Equal(t, h.RegularTestUser.userModel.Email, user.Email)
+		assert.Equal(t, usedBytes+1, user.Storage.UsedBytes)
+		assert.Equal(t, 2, user.Storage.UsedFiles)
+
+		rr = h.makeRequest(t, http.MethodPut, "/api/v1/admin/users/999999/quota", models.StorageQuota{}, h.AdminTestUser)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("deleting the user quota restores the default", func(t *testing.T) {
+		rr := h.makeRequest(t, http.MethodDelete, quotaURL, nil, h.AdminTestUser)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		profile := getProfile(t)
+		assert.True(t, profile.Storage.DefaultQuota)
+		assert.Equal(t, 1, profile.Storage.Quota.MaxFiles)
+
+		events, _, err := h.DB.GetAuditEvents(models.AuditEventFilter{TargetType: models.AuditTargetUser, Limit: 10})
+		require.NoError(t, err)
+		var quotaEvents []string
+		for _, event := range events {
+			if strings.Contains(event.Event, "quota") {
+				quotaEvents = append(quotaEvents, event.Event)
+			}
+		}
+		assert.Equal(t, []string{"user_quota_reset", "user_quota_updated"}, quotaEvents)
+	})
+	t.Run("workspace quota limits a single workspace", func(t *testing.T) {
+		rr := h.makeRequest(t, http.MethodPut, "/api/v1/admin/settings/quota", models.StorageQuota{}, h.AdminTestUser)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		workspace, err := h.DB.GetWorkspaceByName(userID, "Main")
+		require.NoError(t, err)
+		workspaceQuotaURL := fmt.Sprintf("/api/v1/admin/workspaces/%d/quota", workspace.ID)
+
+		rr = h.makeRequest(t, http.MethodPut, workspaceQuotaURL, models.StorageQuota{MaxFiles: 2}, h.AdminTestUser)
+		require.Equal(t, http.StatusOK, rr.Code)
+		var usage handlers.WorkspaceStorageResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&usage))
+		assert.Equal(t, workspace.ID, usage.WorkspaceID)
+		require.NotNil(t, usage.Quota)
+		assert.Equal(t, 2, usage.Quota.MaxFiles)
+		assert.Equal(t, 2, usage.UsedFiles)
+
+		assert.Equal(t, http.StatusInsufficientStorage, saveFile(t, "third.md", "x"))
+
+		// Files in the trash don't count as files, so restoring them has to fit in the quota again
+		rr = h.makeRequest(t, http.MethodDelete, filesURL+"/other.md", nil, h.RegularTestUser)
+		require.Equal(t, http.StatusNoContent, rr.Code)
+		assert.Equal(t, http.StatusOK, saveFile(t, "third.md", "x"))
+
+		rr = h.makeRequest(t, http.MethodGet, "/api/v1/workspaces/Main/trash", nil, h.RegularTestUser)
+		require.Equal(t, http.StatusOK, rr.Code)
+		var trash []storage.TrashItem
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&trash))
+		require.Len(t, trash, 1)
+		restoreURL := "/api/v1/workspaces/Main/trash/" + trash[0].ID + "/restore"
+		rr = h.makeRequest(t, http.MethodPost, restoreURL, nil, h.RegularTestUser)
+		assert.Equal(t, http.StatusInsufficientStorage, rr.Code)
+
+		rr = h.makeRequest(t, http.MethodDelete, workspaceQuotaURL, nil, h.AdminTestUser)
+		require.Equal(t, http.StatusOK, rr.Code)
+		rr = h.makeRequest(t, http.MethodPost, restoreURL, nil, h.RegularTestUser)
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		rr = h.makeRequest(t, http.MethodGet, workspaceQuotaURL, nil, h.AdminTestUser)
+		require.Equal(t, http.StatusOK, rr.Code)
+		usage = handlers.WorkspaceStorageResponse{}
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&usage))
+		assert.Nil(t, usage.Quota)
+		assert.Equal(t, 3, usage.UsedFiles)
+
+		rr = h.makeRequest(t, http.MethodPut, "/api/v1/admin/workspaces/999999/quota", models.StorageQuota{}, h.AdminTestUser)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		rr = h.makeRequest(t, http.MethodGet, workspaceQuotaURL, nil, h.RegularTestUser)
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+}
@@ -0,0 +1,549 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"lemma/internal/auth"
+	"lemma/internal/context"
+	"lemma/internal/logging"
+	"lemma/internal/models"
+	"lemma/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// sharePasswordHeader is the request header carrying the password of a protected share link.
+// Browsers can send the password with basic auth instead.
+const sharePasswordHeader = "X-Share-Password"
+
+// CreateShareLinkRequest holds the request fields for creating a share link
+type CreateShareLinkRequest struct {
+	Path      string     `json:"path"`                // File or folder to share
+	Password  string     `json:"password,omitempty"`  // Required to open the link if set
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"` // The link doesn't expire if not set
+}
+
+// CreateShareLinkResponse holds a new share link with its token, which is only returned once
+type CreateShareLinkResponse struct {
+	*models.ShareLink
+	Token string `json:"token"`
+	Link  string `json:"link"`
+}
+
+// markdownRenderer renders shared markdown. Raw HTML in the markdown is omitted.
+var markdownRenderer = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+var sharePageTemplate = template.Must(template.New("share").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>body{max-width:50rem;margin:2rem auto;padding:0 1rem;font-family:sans-serif;line-height:1.5}pre{overflow-x:auto}</style>
+</head>
+<body>
+{{- if .Files}}
+<h1>{{.Title}}</h1>
+<ul>
+{{- range .Files}}
+<li><a href="{{.Href}}">{{.Path}}</a></li>
+{{- end}}
+</ul>
+{{- else}}
+{{.Content}}
+{{- end}}
+</body>
+</html>
+`))
+
+type sharePage struct {
+	Title   string
+	Content template.HTML
+	Files   []sharePageFile
+}
+
+type sharePageFile struct {
+	Path string
+	Href string
+}
+
+func getShareLinkLogger() logging.Logger {
+	return getHandlersLogger().WithGroup("share_links")
+}
+
+// ListShareLinks godoc
+// @Summary List share links
+// @Description Lists the share links of the current workspace, newest first
+// @Tags workspaces
+// @ID listShareLinks
+// @Security CookieAuth
+// @Produce json
+// @Param workspace_name path string true "Workspace name"
+// @Success 200 {array} models.ShareLink
+// @Failure 500 {object} ErrorResponse "Failed to list share links"
+// @Router /workspaces/{workspace_name}/shares [get]
+func (h *Handler) ListShareLinks() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := context.GetRequestContext(w, r)
+		if !ok {
+			return
+		}
+		log := getShareLinkLogger().With(
+			"handler", "ListShareLinks",
+			"userID", ctx.UserID,
+			"workspaceID", ctx.Workspace.ID,
+			"clientIP", r.RemoteAddr,
+		)
+
+		links, err := h.DB.GetShareLinks(ctx.Workspace.ID)
+		if err != nil {
+			log.Error("failed to fetch share links from database",
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to list share links", http.StatusInternalServerError)
+			return
+		}
+
+		respondJSON(w, links)
+	}
+}
+
+// CreateShareLink godoc
+// @Summary Create share link
+// @Description Creates a public read-only link to a file or folder of the current workspace. The token is only returned in this response.
+// @Tags workspaces
+// @ID createShareLink
+// @Security CookieAuth
+// @Accept json
+// @Produce json
+// @Param workspace_name path string true "Workspace name"
+// @Param body body CreateShareLinkRequest true "Share link details"
+// @Success 200 {object} CreateShareLinkResponse
+// @Failure 400 {object} ErrorResponse "Invalid request body"
+// @Failure 400 {object} ErrorResponse "Path is required"
+// @Failure 400 {object} ErrorResponse "Invalid file path"
+// @Failure 400 {object} ErrorResponse "Expiry must be in the future"
+// @Failure 404 {object} ErrorResponse "File not found"
+// @Failure 500 {object} ErrorResponse "Failed to create share link"
+// @Router /workspaces/{workspace_name}/shares [post]
+func (h *Handler) CreateShareLink(rootURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := context.GetRequestContext(w, r)
+		if !ok {
+			return
+		}
+		log := getShareLinkLogger().With(
+			"handler", "CreateShareLink",
+			"userID", ctx.UserID,
+			"workspaceID", ctx.Workspace.ID,
+			"clientIP", r.RemoteAddr,
+		)
+
+		var req CreateShareLinkRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			log.Debug("failed to decode request body",
+				"error", err.Error(),
+			)
+			respondError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if strings.TrimSpace(req.Path) == "" {
+			respondError(w, "Path is required", http.StatusBadRequest)
+			return
+		}
+		sharedPath := filepath.Clean(req.Path)
+
+		isFolder, err := h.Storage.IsDirectory(ctx.Workspace.UserID, ctx.Workspace.ID, sharedPath)
+		if err != nil {
+			if storage.IsPathValidationError(err) {
+				log.Error("invalid file path attempted",
+					"filePath", req.Path,
+					"error", err.Error(),
+				)
+				respondError(w, "Invalid file path", http.StatusBadRequest)
+				return
+			}
+
+			if os.IsNotExist(err) {
+				log.Debug("file not found",
+					"filePath", sharedPath,
+				)
+				respondError(w, "File not found", http.StatusNotFound)
+				return
+			}
+
+			log.Error("failed to check shared path",
+				"filePath", sharedPath,
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to create share link", http.StatusInternalServerError)
+			return
+		}
+
+		if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+			respondError(w, "Expiry must be in the future", http.StatusBadRequest)
+			return
+		}
+
+		var passwordHash string
+		if req.Password != "" {
+			hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+			if err != nil {
+				log.Error("failed to hash share link password",
+					"error", err.Error(),
+				)
+				respondError(w, "Failed to create share link", http.StatusInternalServerError)
+				return
+			}
+			passwordHash = string(hash)
+		}
+
+		token, err := auth.GenerateToken()
+		if err != nil {
+			log.Error("failed to generate share link token",
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to create share link", http.StatusInternalServerError)
+			return
+		}
+
+		link := &models.ShareLink{
+			WorkspaceID:  ctx.Workspace.ID,
+			TokenHash:    auth.HashToken(token),
+			Path:         sharedPath,
+			IsFolder:     isFolder,
+			PasswordHash: passwordHash,
+			ExpiresAt:    req.ExpiresAt,
+			CreatedBy:    ctx.UserID,
+		}
+		if err := h.DB.CreateShareLink(link); err != nil {
+			log.Error("failed to create share link in database",
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to create share link", http.StatusInternalServerError)
+			return
+		}
+
+		h.audit(r, "share link created", &models.AuditEvent{
+			Event:       "share_link_created",
+			ActorID:     &ctx.UserID,
+			TargetType:  models.AuditTargetShareLink,
+			TargetID:    strconv.Itoa(link.ID),
+			WorkspaceID: &ctx.Workspace.ID,
+			Details:     map[string]any{"path": link.Path, "hasPassword": link.HasPassword},
+		})
+		respondJSON(w, CreateShareLinkResponse{
+			ShareLink: link,
+			Token:     token,
+			Link:      fmt.Sprintf("%s/api/v1/shares/%s", strings.TrimRight(rootURL, "/"), token),
+		})
+	}
+}
+
+// DeleteShareLink godoc
+// @Summary Revoke share link
+// @Description Revokes a share link of the current workspace so it can no longer be opened
+// @Tags workspaces
+// @ID deleteShareLink
+// @Security CookieAuth
+// @Param workspace_name path string true "Workspace name"
+// @Param shareId path int true "Share link ID"
+// @Success 204 "No Content - Share link revoked successfully"
+// @Failure 400 {object} ErrorResponse "Invalid share link ID"
+// @Failure 404 {object} ErrorResponse "Share link not found"
+// @Router /workspaces/{workspace_name}/shares/{shareId} [delete]
+func (h *Handler) DeleteShareLink() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := context.GetRequestContext(w, r)
+		if !ok {
+			return
+		}
+		log := getShareLinkLogger().With(
+			"handler", "DeleteShareLink",
+			"userID", ctx.UserID,
+			"workspaceID", ctx.Workspace.ID,
+			"clientIP", r.RemoteAddr,
+		)
+
+		linkID, err := strconv.Atoi(chi.URLParam(r, "shareId"))
+		if err != nil {
+			log.Debug("invalid share link ID format",
+				"shareIDParam", chi.URLParam(r, "shareId"),
+				"error", err.Error(),
+			)
+			respondError(w, "Invalid share link ID", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.DB.DeleteShareLink(ctx.Workspace.ID, linkID); err != nil {
+			log.Debug("failed to delete share link",
+				"shareLinkID", linkID,
+				"error", err.Error(),
+			)
+			respondError(w, "Share link not found", http.StatusNotFound)
+			return
+		}
+
+		h.audit(r, "share link revoked", &models.AuditEvent{
+			Event:       "share_link_revoked",
+			ActorID:     &ctx.UserID,
+			TargetType:  models.AuditTargetShareLink,
+			TargetID:    strconv.Itoa(linkID),
+			WorkspaceID: &ctx.Workspace.ID,
+		})
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// GetSharedContent godoc
+// @Summary Open share link
+// @Description Serves the file of a share link, or a file within its folder given by the path after the token.
+// @Description Markdown is rendered to an HTML page unless the raw format is requested.
+// @Description The root of a folder link lists its files, as HTML page or as JSON file tree in the raw format.
+// @Description The password of a protected link is sent in the X-Share-Password header or with basic auth.
+// @Tags shares
+// @ID getSharedContent
+// @Produce html
+// @Produce plain
+// @Produce json
+// @Param token path string true "Share link token"
+// @Param file_path path string false "File path within a shared folder"
+// @Param format query string false "Response format" Enums(html, raw)
+// @Success 200 {string} string "Rendered or raw file content"
+// @Failure 401 {object} ErrorResponse "Invalid or missing password"
+// @Failure 404 {object} ErrorResponse "Share link not found"
+// @Failure 404 {object} ErrorResponse "File not found"
+// @Failure 429 {object} ErrorResponse "Too many failed password attempts"
+// @Failure 500 {object} ErrorResponse "Failed to check password attempts"
+// @Failure 500 {object} ErrorResponse "Failed to read file"
+// @Failure 500 {object} ErrorResponse "Failed to render file"
+// @Router /shares/{token}/{file_path} [get]
+func (h *Handler) GetSharedContent(loginLimiter auth.LoginLimiter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := getShareLinkLogger().With(
+			"handler", "GetSharedContent",
+			"clientIP", r.RemoteAddr,
+		)
+
+		link, err := h.DB.GetShareLinkByTokenHash(auth.HashToken(chi.URLParam(r, "token")))
+		if err != nil || link.Expired(time.Now()) {
+			log.Debug("share link not found or expired")
+			respondError(w, "Share link not found", http.StatusNotFound)
+			return
+		}
+		log = log.With("shareLinkID", link.ID)
+
+		if link.HasPassword {
+			// Password attempts are limited like logins, keyed by the link instead of an email
+			limiterKey := fmt.Sprintf("share-link:%d", link.ID)
+			clientIP := getClientIP(r)
+			retryAfter, err := loginLimiter.Check(limiterKey, clientIP)
+			if err != nil {
+				log.Error("failed to check share link password attempts",
+					"error", err.Error(),
+				)
+				respondError(w, "Failed to check password attempts", http.StatusInternalServerError)
+				return
+			}
+			if retryAfter > 0 {
+				log.Warn("share link password attempt throttled",
+					"retryAfter", retryAfter,
+				)
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+				respondError(w, "Too many failed password attempts", http.StatusTooManyRequests)
+				return
+			}
+
+			password := r.Header.Get(sharePasswordHeader)
+			if password == "" {
+				_, password, _ = r.BasicAuth()
+			}
+			if bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(password)) != nil {
+				log.Debug("invalid share link password",
+					"hasPassword", password != "",
+				)
+				// Requests without a password are how browsers ask for the basic auth prompt
+				if password != "" {
+					h.recordFailedLogin(r, loginLimiter, limiterKey, clientIP)
+				}
+				w.Header().Set("WWW-Authenticate", `Basic realm="Shared note"`)
+				respondError(w, "Invalid or missing password", http.StatusUnauthorized)
+				return
+			}
+			if err := loginLimiter.RecordSuccess(limiterKey); err != nil {
+				log.Error("failed to reset share link password attempts",
+					"error", err.Error(),
+				)
+			}
+		}
+
+		workspace, err := h.DB.GetWorkspaceByID(link.WorkspaceID)
+		if err != nil {
+			log.Error("failed to get workspace of share link",
+				"workspaceID", link.WorkspaceID,
+				"error", err.Error(),
+			)
+			respondError(w, "Share link not found", http.StatusNotFound)
+			return
+		}
+
+		// Cleaning the path as absolute path keeps it within the shared folder
+		subPath := strings.TrimPrefix(path.Clean("/"+chi.URLParam(r, "*")), "/")
+		raw := r.URL.Query().Get("format") == "raw"
+
+		w.Header().Set("X-Robots-Tag", "noindex")
+		w.Header().Set("Cache-Control", "no-store")
+
+		if !link.IsFolder && subPath != "" {
+			respondError(w, "File not found", http.StatusNotFound)
+			return
+		}
+
+		filePath := link.Path
+		if link.IsFolder {
+			filePath = filepath.Join(link.Path, subPath)
+			isFolder, err := h.Storage.IsDirectory(workspace.UserID, workspace.ID, filePath)
+			if err != nil || (isFolder && subPath != "") {
+				log.Debug("shared file not found",
+					"filePath", filePath,
+				)
+				respondError(w, "File not found", http.StatusNotFound)
+				return
+			}
+
+			if isFolder {
+				files, err := h.Storage.ListDirectoryRecursively(workspace.UserID, workspace.ID, filePath)
+				if err != nil {
+					log.Error("failed to list shared folder",
+						"filePath", filePath,
+						"error", err.Error(),
+					)
+					respondError(w, "Failed to read file", http.StatusInternalServerError)
+					return
+				}
+
+				if raw {
+					if files == nil {
+						files = []storage.FileNode{}
+					}
+					respondJSON(w, files)
+					return
+				}
+
+				baseURL := strings.TrimSuffix(r.URL.Path, "/")
+				page := sharePage{Title: filepath.Base(link.Path)}
+				for _, file := range flattenFileNodes(files) {
+					page.Files = append(page.Files, sharePageFile{
+						Path: file,
+						Href: baseURL + "/" + escapeFilePath(file),
+					})
+				}
+				respondSharePage(w, log, page)
+				return
+			}
+		}
+
+		content, err := h.Storage.GetFileContent(workspace.UserID, workspace.ID, filePath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				log.Debug("shared file not found",
+					"filePath", filePath,
+				)
+				respondError(w, "File not found", http.StatusNotFound)
+				return
+			}
+
+			log.Error("failed to read shared file",
+				"filePath", filePath,
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to read file", http.StatusInternalServerError)
+			return
+		}
+
+		if raw {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			if _, err := w.Write(content); err != nil {
+				log.Error("failed to write response",
+					"filePath", filePath,
+					"error", err.Error(),
+				)
+			}
+			return
+		}
+
+		var rendered bytes.Buffer
+		if err := markdownRenderer.Convert(content, &rendered); err != nil {
+			log.Error("failed to render markdown",
+				"filePath", filePath,
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to render file", http.StatusInternalServerError)
+			return
+		}
+
+		respondSharePage(w, log, sharePage{
+			Title:   filepath.Base(filePath),
+			Content: template.HTML(rendered.String()), // Raw HTML in the markdown is omitted by the renderer
+		})
+	}
+}
+
+// respondSharePage writes a share page as HTML response
+func respondSharePage(w http.ResponseWriter, log logging.Logger, page sharePage) {
+	var body bytes.Buffer
+	if err := sharePageTemplate.Execute(&body, page); err != nil {
+		log.Error("failed to render share page",
+			"error", err.Error(),
+		)
+		respondError(w, "Failed to render file", http.StatusInternalServerError)
+		return
+	}
+
+	// Shared content is untrusted, so scripts and external resources other than images are blocked
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; img-src 'self' https: data:; style-src 'unsafe-inline'")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if _, err := w.Write(body.Bytes()); err != nil {
+		log.Error("failed to write response",
+			"error", err.Error(),
+		)
+	}
+}
+
+// flattenFileNodes returns the paths of all files in the tree, without directories
+func flattenFileNodes(nodes []storage.FileNode) []string {
+	var paths []string
+	for _, node := range nodes {
+		if node.Children != nil {
+			paths = append(paths, flattenFileNodes(node.Children)...)
+			continue
+		}
+		paths = append(paths, node.Path)
+	}
+	return paths
+}
+
+// escapeFilePath escapes each segment of a file path for use in a URL
+func escapeFilePath(filePath string) string {
+	segments := strings.Split(filepath.ToSlash(filePath), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
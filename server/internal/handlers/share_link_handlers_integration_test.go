@@ -0,0 +1,241 @@
+//go:build integration
+
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"lemma/internal/auth"
+	"lemma/internal/handlers"
+	"lemma/internal/models"
+	"lemma/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShareLinkHandlers_Integration(t *testing.T) {
+	h := setupTestHarness(t)
+	defer h.teardown(t)
+
+	owner := h.RegularTestUser
+	viewer := h.createTestUser(t, "share-viewer@test.com", "password123", models.RoleEditor)
+
+	workspace := &models.Workspace{Name: "Shared Notes"}
+	rr := h.makeRequest(t, http.MethodPost, "/api/v1/workspaces", workspace, owner)
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(workspace))
+
+	workspaceURL := "/api/v1/workspaces/" + url.PathEscape(workspace.Name)
+	sharesURL := workspaceURL + "/shares"
+
+	files := map[string]string{
+		"note.md":           "# Note\n\nHello <script>alert(1)</script> **world**",
+		"folder/first.md":   "# First",
+		"folder/sub/two.md": "# Second",
+		"private.md":        "# Private",
+	}
+	for path, content := range files {
+		rr := h.makeRequestRaw(t, http.MethodPost, workspaceURL+"/files/"+path, strings.NewReader(content), owner)
+		require.Equal(t, http.StatusOK, rr.Code)
+	}
+
+	createShareLink := func(t *testing.T, req handlers.CreateShareLinkRequest) handlers.CreateShareLinkResponse {
+		t.Helper()
+		rr := h.makeRequest(t, http.MethodPost, sharesURL, req, owner)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+
+		var resp handlers.CreateShareLinkResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+		require.NotEmpty(t, resp.Token)
+		return resp
+	}
+
+	openShare := func(t *testing.T, path, password string) *http.Response {
+		t.Helper()
+		req := h.newRequestRaw(t, http.MethodGet, path, nil)
+		if password != "" {
+			req.Header.Set("X-Share-Password", password)
+		}
+		return h.executeRequest(req).Result()
+	}
+
+	t.Run("file share", func(t *testing.T) {
+		share := createShareLink(t, handlers.CreateShareLinkRequest{Path: "note.md"})
+		assert.False(t, share.IsFolder)
+		assert.False(t, share.HasPassword)
+		assert.Equal(t, "http://localhost:8081/api/v1/shares/"+share.Token, share.Link)
+
+		rr := h.executeRequest(h.newRequestRaw(t, http.MethodGet, "/api/v1/shares/"+share.Token, nil))
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, rr.Header().Get("Content-Type"), "text/html")
+		assert.Contains(t, rr.Body.String(), "<h1>Note</h1>")
+		assert.Contains(t, rr.Body.String(), "<strong>world</strong>")
+		assert.NotContains(t, rr.Body.String(), "<script>")
+
+		rr = h.executeRequest(h.newRequestRaw(t, http.MethodGet, "/api/v1/shares/"+share.Token+"?format=raw", nil))
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, rr.Header().Get("Content-Type"), "text/plain")
+		assert.Equal(t, files["note.md"], rr.Body.String())
+
+		// A file share doesn't give access to other files
+		rr = h.executeRequest(h.newRequestRaw(t, http.MethodGet, "/api/v1/shares/"+share.Token+"/private.md", nil))
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("folder share", func(t *testing.T) {
+		share := createShareLink(t, handlers.CreateShareLinkRequest{Path: "folder"})
+		assert.True(t, share.IsFolder)
+		base := "/api/v1/shares/" + share.Token
+
+		rr := h.executeRequest(h.newRequestRaw(t, http.MethodGet, base+"?format=raw", nil))
+		require.Equal(t, http.StatusOK, rr.Code)
+		var tree []storage.FileNode
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&tree))
+		require.Len(t, tree, 2)
+		assert.Equal(t, "sub", tree[0].Path)
+		assert.Equal(t, "first.md", tree[1].Path)
+
+		rr = h.executeRequest(h.newRequestRaw(t, http.MethodGet, base, nil))
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, rr.Body.String(), `href="`+base+`/sub/two.md"`)
+
+		rr = h.executeRequest(h.newRequestRaw(t, http.MethodGet, base+"/sub/two.md", nil))
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, rr.Body.String(), "<h1>Second</h1>")
+
+		for _, path := range []string{"/../private.md", "/%2e%2e/private.md", "/sub", "/missing.md"} {
+			rr = h.executeRequest(h.newRequestRaw(t, http.MethodGet, base+path, nil))
+			assert.Equal(t, http.StatusNotFound, rr.Code, path)
+		}
+	})
+
+	t.Run("password protected share", func(t *testing.T) {
+		share := createShareLink(t, handlers.CreateShareLinkRequest{Path: "note.md", Password: "secret"})
+		assert.True(t, share.HasPassword)
+		path := "/api/v1/shares/" + share.Token + "?format=raw"
+
+		resp := openShare(t, path, "")
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+		assert.Contains(t, resp.Header.Get("WWW-Authenticate"), "Basic")
+
+		resp = openShare(t, path, "wrong")
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+		resp = openShare(t, path, "secret")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		req := h.newRequestRaw(t, http.MethodGet, path, nil)
+		req.SetBasicAuth("", "secret")
+		assert.Equal(t, http.StatusOK, h.executeRequest(req).Code)
+	})
+
+	t.Run("password attempts are limited", func(t *testing.T) {
+		share := createShareLink(t, handlers.CreateShareLinkRequest{Path: "note.md", Password: "secret"})
+		path := "/api/v1/shares/" + share.Token + "?format=raw"
+
+		open := func(password, remoteAddr string) *httptest.ResponseRecorder {
+			req := h.newRequestRaw(t, http.MethodGet, path, nil)
+			req.Header.Set("X-Share-Password", password)
+			req.RemoteAddr = remoteAddr
+			return h.executeRequest(req)
+		}
+
+		for i := 0; i < 3; i++ {
+			rr := open("wrong", "198.51.100.10:1234")
+			require.Equal(t, http.StatusUnauthorized, rr.Code)
+		}
+
+		// The link is throttled for everyone, even with the correct password
+		rr := open("secret", "198.51.100.11:1234")
+		assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+		assert.NotEmpty(t, rr.Header().Get("Retry-After"))
+		assert.NotContains(t, rr.Body.String(), files["note.md"])
+
+		// Other links are not affected
+		other := createShareLink(t, handlers.CreateShareLinkRequest{Path: "note.md", Password: "secret"})
+		req := h.newRequestRaw(t, http.MethodGet, "/api/v1/shares/"+other.Token+"?format=raw", nil)
+		req.Header.Set("X-Share-Password", "secret")
+		req.RemoteAddr = "198.51.100.11:1234"
+		assert.Equal(t, http.StatusOK, h.executeRequest(req).Code)
+	})
+
+	t.Run("expired share", func(t *testing.T) {
+		expiresAt := time.Now().Add(-time.Hour)
+		require.NoError(t, h.DB.CreateShareLink(&models.ShareLink{
+			WorkspaceID: workspace.ID,
+			TokenHash:   auth.HashToken("expired-token"),
+			Path:        "note.md",
+			ExpiresAt:   &expiresAt,
+			CreatedBy:   owner.userModel.ID,
+		}))
+
+		rr := h.executeRequest(h.newRequestRaw(t, http.MethodGet, "/api/v1/shares/expired-token", nil))
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("create share link failures", func(t *testing.T) {
+		past := time.Now().Add(-time.Hour)
+		tests := []struct {
+			name     string
+			req      handlers.CreateShareLinkRequest
+			wantCode int
+		}{
+			{"missing path", handlers.CreateShareLinkRequest{}, http.StatusBadRequest},
+			{"path traversal", handlers.CreateShareLinkRequest{Path: "../../etc/passwd"}, http.StatusBadRequest},
+			{"missing file", handlers.CreateShareLinkRequest{Path: "missing.md"}, http.StatusNotFound},
+			{"expiry in the past", handlers.CreateShareLinkRequest{Path: "note.md", ExpiresAt: &past}, http.StatusBadRequest},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				rr := h.makeRequest(t, http.MethodPost, sharesURL, tt.req, owner)
+				assert.Equal(t, tt.wantCode, rr.Code)
+			})
+		}
+	})
+
+	t.Run("list and revoke", func(t *testing.T) {
+		share := createShareLink(t, handlers.CreateShareLinkRequest{Path: "private.md"})
+
+		rr := h.makeRequest(t, http.MethodGet, sharesURL, nil, owner)
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.NotContains(t, rr.Body.String(), share.Token)
+
+		var links []*models.ShareLink
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&links))
+		require.NotEmpty(t, links)
+		assert.Equal(t, share.ID, links[0].ID)
+
+		// Share links are managed by the workspace owner
+		require.NoError(t, h.DB.CreateWorkspaceMember(&models.WorkspaceMember{
+			WorkspaceID: workspace.ID,
+			UserID:      viewer.userModel.ID,
+			Role:        models.WorkspaceRoleEditor,
+		}))
+		rr = h.makeRequest(t, http.MethodGet, sharesURL, nil, viewer)
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+		rr = h.makeRequest(t, http.MethodPost, sharesURL, handlers.CreateShareLinkRequest{Path: "note.md"}, viewer)
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+
+		deletePath := fmt.Sprintf("%s/%d", sharesURL, share.ID)
+		rr = h.makeRequest(t, http.MethodDelete, deletePath, nil, viewer)
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+
+		rr = h.makeRequest(t, http.MethodDelete, deletePath, nil, owner)
+		require.Equal(t, http.StatusNoContent, rr.Code)
+
+		rr = h.executeRequest(h.newRequestRaw(t, http.MethodGet, "/api/v1/shares/"+share.Token, nil))
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+
+		rr = h.makeRequest(t, http.MethodDelete, deletePath, nil, owner)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
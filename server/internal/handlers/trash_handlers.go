@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"lemma/internal/context"
+	"lemma/internal/db"
+	"lemma/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// AdminListDeletedUsers godoc
+// @Summary List deleted users
+// @Description Returns the users in the trash, most recently deleted first. They are permanently deleted after the trash retention period.
+// @Tags Admin
+// @Security CookieAuth
+// @ID adminListDeletedUsers
+// @Produce json
+// @Success 200 {array} models.User
+// @Failure 500 {object} ErrorResponse "Failed to list deleted users"
+// @Router /admin/trash/users [get]
+func (h *Handler) AdminListDeletedUsers() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := context.GetRequestContext(w, r)
+		if !ok {
+			return
+		}
+		log := getAdminLogger().With(
+			"handler", "AdminListDeletedUsers",
+			"adminID", ctx.UserID,
+			"clientIP", r.RemoteAddr,
+		)
+
+		users, err := h.DB.GetDeletedUsers()
+		if err != nil {
+			log.Error("failed to fetch deleted users from database",
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to list deleted users", http.StatusInternalServerError)
+			return
+		}
+
+		respondJSON(w, users)
+	}
+}
+
+// AdminRestoreUser godoc
+// @Summary Restore a deleted user
+// @Description Restores a user from the trash together with the workspaces deleted with them
+// @Tags Admin
+// @Security CookieAuth
+// @ID adminRestoreUser
+// @Param userId path int true "User ID"
+// @Success 204 "No Content - User restored successfully"
+// @Failure 400 {object} ErrorResponse "Invalid user ID"
+// @Failure 404 {object} ErrorResponse "Deleted user not found"
+// @Router /admin/trash/users/{userId}/restore [post]
+func (h *Handler) AdminRestoreUser() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := context.GetRequestContext(w, r)
+		if !ok {
+			return
+		}
+		log := getAdminLogger().With(
+			"handler", "AdminRestoreUser",
+			"adminID", ctx.UserID,
+			"clientIP", r.RemoteAddr,
+		)
+
+		userID, err := strconv.Atoi(chi.URLParam(r, "userId"))
+		if err != nil {
+			log.Debug("invalid user ID format",
+				"userIDParam", chi.URLParam(r, "userId"),
+				"error", err.Error(),
+			)
+			respondError(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.DB.RestoreUser(userID); err != nil {
+			log.Debug("failed to restore user",
+				"targetUserID", userID,
+				"error", err.Error(),
+			)
+			respondError(w, "Deleted user not found", http.StatusNotFound)
+			return
+		}
+
+		h.audit(r, "user restored by admin", &models.AuditEvent{
+			Event:      "user_restored",
+			ActorID:    &ctx.UserID,
+			TargetType: models.AuditTargetUser,
+			TargetID:   strconv.Itoa(userID),
+		})
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// AdminListDeletedWorkspaces godoc
+// @Summary List deleted workspaces
+// @Description Returns the workspaces in the trash, including the workspaces of deleted users, most recently deleted first.
+// @Description They are permanently deleted with their files after the trash retention period.
+// @Tags Admin
+// @Security CookieAuth
+// @ID adminListDeletedWorkspaces
+// @Produce json
+// @Success 200 {array} models.Workspace
+// @Failure 500 {object} ErrorResponse "Failed to list deleted workspaces"
+// @Router /admin/trash/workspaces [get]
+func (h *Handler) AdminListDeletedWorkspaces() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := context.GetRequestContext(w, r)
+		if !ok {
+			return
+		}
+		log := getAdminLogger().With(
+			"handler", "AdminListDeletedWorkspaces",
+			"adminID", ctx.UserID,
+			"clientIP", r.RemoteAddr,
+		)
+
+		workspaces, err := h.DB.GetDeletedWorkspaces()
+		if err != nil {
+			log.Error("failed to fetch deleted workspaces from database",
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to list deleted workspaces", http.StatusInternalServerError)
+			return
+		}
+
+		respondJSON(w, workspaces)
+	}
+}
+
+// AdminRestoreWorkspace godoc
+// @Summary Restore a deleted workspace
+// @Description Restores a workspace from the trash. Workspaces of deleted users are restored by restoring their owner.
+// @Description If a member has access to another workspace with the same name by now, a number is appended to the name.
+// @Tags Admin
+// @Security CookieAuth
+// @ID adminRestoreWorkspace
+// @Param workspaceId path int true "Workspace ID"
+// @Success 204 "No Content - Workspace restored successfully"
+// @Failure 400 {object} ErrorResponse "Invalid workspace ID"
+// @Failure 404 {object} ErrorResponse "Deleted workspace not found"
+// @Failure 409 {object} ErrorResponse "The workspace owner is deleted"
+// @Router /admin/trash/workspaces/{workspaceId}/restore [post]
+func (h *Handler) AdminRestoreWorkspace() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := context.GetRequestContext(w, r)
+		if !ok {
+			return
+		}
+		log := getAdminLogger().With(
+			"handler", "AdminRestoreWorkspace",
+			"adminID", ctx.UserID,
+			"clientIP", r.RemoteAddr,
+		)
+
+		workspaceID, err := strconv.Atoi(chi.URLParam(r, "workspaceId"))
+		if err != nil {
+			log.Debug("invalid workspace ID format",
+				"workspaceIDParam", chi.URLParam(r, "workspaceId"),
+				"error", err.Error(),
+			)
+			respondError(w, "Invalid workspace ID", http.StatusBadRequest)
+			return
+		}
+
+		name, err := h.DB.RestoreWorkspace(workspaceID)
+		if errors.Is(err, db.ErrWorkspaceOwnerDeleted) {
+			respondError(w, "The workspace owner is deleted", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			log.Debug("failed to restore workspace",
+				"workspaceID", workspaceID,
+				"error", err.Error(),
+			)
+			respondError(w, "Deleted workspace not found", http.StatusNotFound)
+			return
+		}
+
+		h.audit(r, "workspace restored by admin", &models.AuditEvent{
+			Event:       "workspace_restored",
+			ActorID:     &ctx.UserID,
+			TargetType:  models.AuditTargetWorkspace,
+			TargetID:    strconv.Itoa(workspaceID),
+			WorkspaceID: &workspaceID,
+			Details:     map[string]any{"name": name},
+		})
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,133 @@
+//go:build integration
+
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"lemma/internal/handlers"
+	"lemma/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrashHandlers_Integration(t *testing.T) {
+	h := setupTestHarness(t)
+	defer h.teardown(t)
+
+	createReq := handlers.CreateUserRequest{
+		Email:    "trash@test.com",
+		Password: "password123",
+		Role:     models.RoleEditor,
+	}
+	rr := h.makeRequest(t, http.MethodPost, "/api/v1/admin/users", createReq, h.AdminTestUser)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var user models.User
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&user))
+	userPath := fmt.Sprintf("/api/v1/admin/users/%d", user.ID)
+
+	t.Run("deleted users can be restored", func(t *testing.T) {
+		rr := h.makeRequest(t, http.MethodDelete, userPath, nil, h.AdminTestUser)
+		require.Equal(t, http.StatusNoContent, rr.Code)
+
+		// The email stays taken while the user is in the trash
+		rr = h.makeRequest(t, http.MethodPost, "/api/v1/admin/users", createReq, h.AdminTestUser)
+		assert.Equal(t, http.StatusConflict, rr.Code)
+
+		rr = h.makeRequest(t, http.MethodGet, "/api/v1/admin/trash/users", nil, h.AdminTestUser)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var deleted []*models.User
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&deleted))
+		require.Len(t, deleted, 1)
+		assert.Equal(t, user.ID, deleted[0].ID)
+		assert.NotNil(t, deleted[0].DeletedAt)
+
+		restorePath := fmt.Sprintf("/api/v1/admin/trash/users/%d/restore", user.ID)
+		rr = h.makeRequest(t, http.MethodPost, restorePath, nil, h.RegularTestUser)
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+
+		rr = h.makeRequest(t, http.MethodPost, restorePath, nil, h.AdminTestUser)
+		require.Equal(t, http.StatusNoContent, rr.Code)
+
+		rr = h.makeRequest(t, http.MethodGet, userPath, nil, h.AdminTestUser)
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		rr = h.makeRequest(t, http.MethodPost, restorePath, nil, h.AdminTestUser)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("deleted workspaces can be restored", func(t *testing.T) {
+		workspace := &models.Workspace{Name: "Trash Workspace"}
+		rr := h.makeRequest(t, http.MethodPost, "/api/v1/workspaces", workspace, h.RegularTestUser)
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(workspace))
+
+		workspacePath := "/api/v1/workspaces/" + url.PathEscape(workspace.Name)
+		rr = h.makeRequest(t, http.MethodDelete, workspacePath, nil, h.RegularTestUser)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		rr = h.makeRequest(t, http.MethodGet, workspacePath, nil, h.RegularTestUser)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+
+		rr = h.makeRequest(t, http.MethodGet, "/api/v1/admin/trash/workspaces", nil, h.AdminTestUser)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var deleted []*models.Workspace
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&deleted))
+		require.NotEmpty(t, deleted)
+		assert.Equal(t, workspace.ID, deleted[0].ID)
+		assert.NotNil(t, deleted[0].DeletedAt)
+
+		rr = h.makeRequest(t, http.MethodPost, fmt.Sprintf("/api/v1/admin/trash/workspaces/%d/restore", workspace.ID), nil, h.AdminTestUser)
+		require.Equal(t, http.StatusNoContent, rr.Code)
+
+		rr = h.makeRequest(t, http.MethodGet, workspacePath, nil, h.RegularTestUser)
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("restored workspaces are renamed if the name was reused", func(t *testing.T) {
+		deleted := &models.Workspace{Name: "Reused Workspace"}
+		rr := h.makeRequest(t, http.MethodPost, "/api/v1/workspaces", deleted, h.RegularTestUser)
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(deleted))
+
+		workspacePath := "/api/v1/workspaces/" + url.PathEscape(deleted.Name)
+		rr = h.makeRequest(t, http.MethodDelete, workspacePath, nil, h.RegularTestUser)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		reused := &models.Workspace{Name: deleted.Name}
+		rr = h.makeRequest(t, http.MethodPost, "/api/v1/workspaces", reused, h.RegularTestUser)
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(reused))
+
+		rr = h.makeRequest(t, http.MethodPost, fmt.Sprintf("/api/v1/admin/trash/workspaces/%d/restore", deleted.ID), nil, h.AdminTestUser)
+		require.Equal(t, http.StatusNoContent, rr.Code)
+
+		// Both workspaces are reachable by name
+		for path, wantID := range map[string]int{
+			workspacePath: reused.ID,
+			"/api/v1/workspaces/" + url.PathEscape(deleted.Name+" (2)"): deleted.ID,
+		} {
+			rr = h.makeRequest(t, http.MethodGet, path, nil, h.RegularTestUser)
+			require.Equal(t, http.StatusOK, rr.Code)
+			var workspace models.Workspace
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&workspace))
+			assert.Equal(t, wantID, workspace.ID, path)
+		}
+	})
+
+	t.Run("workspaces of deleted users are restored with their owner", func(t *testing.T) {
+		rr := h.makeRequest(t, http.MethodDelete, userPath, nil, h.AdminTestUser)
+		require.Equal(t, http.StatusNoContent, rr.Code)
+
+		rr = h.makeRequest(t, http.MethodPost, fmt.Sprintf("/api/v1/admin/trash/workspaces/%d/restore", user.LastWorkspaceID), nil, h.AdminTestUser)
+		assert.Equal(t, http.StatusConflict, rr.Code)
+	})
+}
@@ -3,9 +3,14 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
+	"sort"
+	"strconv"
 
+	"lemma/internal/auth"
 	"lemma/internal/context"
 	"lemma/internal/logging"
+	"lemma/internal/mailer"
+	"lemma/internal/models"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -27,9 +32,55 @@ func getProfileLogger() logging.Logger {
 	return getHandlersLogger().WithGroup("profile")
 }
 
+// GetProfile godoc
+// @Summary Get profile
+// @Description Returns the user's profile along with the storage used by their workspaces and their quota
+// @Tags users
+// @ID getProfile
+// @Security CookieAuth
+// @Produce json
+// @Success 200 {object} UserStorageResponse
+// @Failure 404 {object} ErrorResponse "User not found"
+// @Failure 500 {object} ErrorResponse "Failed to get storage usage"
+// @Router /profile [get]
+func (h *Handler) GetProfile() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := context.GetRequestContext(w, r)
+		if !ok {
+			return
+		}
+		log := getProfileLogger().With(
+			"handler", "GetProfile",
+			"userID", ctx.UserID,
+			"clientIP", r.RemoteAddr,
+		)
+
+		user, err := h.DB.GetUserByID(ctx.UserID)
+		if err != nil {
+			log.Error("failed to fetch user",
+				"error", err.Error(),
+			)
+			respondError(w, "User not found", http.StatusNotFound)
+			return
+		}
+
+		usage, err := h.getStorageUsage(ctx.UserID)
+		if err != nil {
+			log.Error("failed to get storage usage",
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to get storage usage", http.StatusInternalServerError)
+			return
+		}
+
+		respondJSON(w, UserStorageResponse{User: user, Storage: *usage})
+	}
+}
+
 // UpdateProfile godoc
 // @Summary Update profile
-// @Description Updates the user's profile
+// @Description Updates the user's profile. Changing the email or password revokes all other sessions of the user.
+// @Description If email verification is enabled, a new email is only applied once it's confirmed through a link sent to the new address.
 // @Tags users
 // @ID updateProfile
 // @Security CookieAuth
@@ -40,14 +91,17 @@ func getProfileLogger() logging.Logger {
 // @Failure 400 {object} ErrorResponse "Invalid request body"
 // @Failure 400 {object} ErrorResponse "Current password is required to change password"
 // @Failure 400 {object} ErrorResponse "New password must be at least 8 characters long"
+// @Failure 400 {object} ErrorResponse "New password must differ from the current password"
 // @Failure 400 {object} ErrorResponse "Current password is required to change email"
 // @Failure 401 {object} ErrorResponse "Current password is incorrect"
 // @Failure 404 {object} ErrorResponse "User not found"
 // @Failure 409 {object} ErrorResponse "Email already in use"
 // @Failure 500 {object} ErrorResponse "Failed to process new password"
 // @Failure 500 {object} ErrorResponse "Failed to update profile"
+// @Failure 500 {object} ErrorResponse "Failed to revoke other sessions"
+// @Failure 500 {object} ErrorResponse "Failed to send verification email"
 // @Router /profile [put]
-func (h *Handler) UpdateProfile() http.HandlerFunc {
+func (h *Handler) UpdateProfile(userTokens auth.UserTokenManager, m mailer.Mailer, rootURL string, verifyEmailChanges bool) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx, ok := context.GetRequestContext(w, r)
 		if !ok {
@@ -80,6 +134,7 @@ func (h *Handler) UpdateProfile() http.HandlerFunc {
 
 		// Track what's being updated for logging
 		updates := make(map[string]bool)
+		pendingEmail := ""
 
 		// Handle password update if requested
 		if req.NewPassword != "" {
@@ -103,6 +158,12 @@ func (h *Handler) UpdateProfile() http.HandlerFunc {
 				return
 			}
 
+			if user.MustChangePassword && req.NewPassword == req.CurrentPassword {
+				log.Debug("password change rejected - password required to change was kept")
+				respondError(w, "New password must differ from the current password", http.StatusBadRequest)
+				return
+			}
+
 			hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
 			if err != nil {
 				log.Error("failed to hash new password",
@@ -112,6 +173,7 @@ func (h *Handler) UpdateProfile() http.HandlerFunc {
 				return
 			}
 			user.PasswordHash = string(hashedPassword)
+			user.MustChangePassword = false
 			updates["passwordChanged"] = true
 		}
 
@@ -131,16 +193,22 @@ func (h *Handler) UpdateProfile() http.HandlerFunc {
 				}
 			}
 
-			existingUser, err := h.DB.GetUserByEmail(req.Email)
-			if err == nil && existingUser.ID != user.ID {
+			// Emails of deleted users stay in use until they are purged
+			emailTaken, err := h.DB.EmailExists(req.Email)
+			if err == nil && emailTaken {
 				log.Debug("email change rejected - already in use",
 					"requestedEmail", req.Email,
 				)
 				respondError(w, "Email already in use", http.StatusConflict)
 				return
 			}
-			user.Email = req.Email
-			updates["emailChanged"] = true
+
+			if verifyEmailChanges {
+				pendingEmail = req.Email
+			} else {
+				user.Email = req.Email
+				updates["emailChanged"] = true
+			}
 		}
 
 		// Update display name if provided
@@ -159,13 +227,60 @@ func (h *Handler) UpdateProfile() http.HandlerFunc {
 			return
 		}
 
+		// Changing credentials signs out every other session of the user
+		if updates["passwordChanged"] || updates["emailChanged"] {
+			if err := h.DB.DeleteUserSessions(ctx.UserID, ctx.SessionID); err != nil {
+				log.Error("failed to revoke other sessions",
+					"error", err.Error(),
+				)
+				respondError(w, "Failed to revoke other sessions", http.StatusInternalServerError)
+				return
+			}
+			log.Info("other sessions revoked after credentials change",
+				"updates", updates,
+			)
+		}
+
+		if len(updates) > 0 {
+			fields := make([]string, 0, len(updates))
+			for field := range updates {
+				fields = append(fields, field)
+			}
+			sort.Strings(fields)
+			h.audit(r, "profile updated", &models.AuditEvent{
+				Event:      "profile_updated",
+				ActorID:    &ctx.UserID,
+				TargetType: models.AuditTargetUser,
+				TargetID:   strconv.Itoa(ctx.UserID),
+				Details:    map[string]any{"fields": fields},
+			})
+		}
+
+		// The new email is applied once the user follows the link sent to it
+		if pendingEmail != "" {
+			token, err := userTokens.Issue(user.ID, models.UserTokenPurposeEmailChange, pendingEmail)
+			if err == nil {
+				err = m.Send(emailVerificationMessage(pendingEmail, accountLink(rootURL, "verify-email", token)))
+			}
+			if err != nil {
+				log.Error("failed to send verification email",
+					"error", err.Error(),
+				)
+				respondError(w, "Failed to send verification email", http.StatusInternalServerError)
+				return
+			}
+			log.Info("email change pending verification",
+				"requestedEmail", pendingEmail,
+			)
+		}
+
 		respondJSON(w, user)
 	}
 }
 
 // DeleteAccount godoc
 // @Summary Delete account
-// @Description Deletes the user's account and all associated data
+// @Description Deletes the user's account. The account and its workspaces are moved to the trash and permanently deleted after the trash retention period.
 // @Tags users
 // @ID deleteAccount
 // @Security CookieAuth
@@ -235,32 +350,7 @@ func (h *Handler) DeleteAccount() http.HandlerFunc {
 			}
 		}
 
-		// Get user's workspaces for cleanup
-		workspaces, err := h.DB.GetWorkspacesByUserID(ctx.UserID)
-		if err != nil {
-			log.Error("failed to fetch user workspaces",
-				"error", err.Error(),
-			)
-			respondError(w, "Failed to get user workspaces", http.StatusInternalServerError)
-			return
-		}
-
-		// Delete workspace directories
-		for _, workspace := range workspaces {
-			if err := h.Storage.DeleteUserWorkspace(ctx.UserID, workspace.ID); err != nil {
-				log.Error("failed to delete workspace directory",
-					"error", err.Error(),
-					"workspaceID", workspace.ID,
-				)
-				respondError(w, "Failed to delete workspace files", http.StatusInternalServerError)
-				return
-			}
-			log.Debug("workspace deleted",
-				"workspaceID", workspace.ID,
-			)
-		}
-
-		// Delete user from database
+		// Move the user to the trash, workspace files are removed when the user is purged
 		if err := h.DB.DeleteUser(ctx.UserID); err != nil {
 			log.Error("failed to delete user from database",
 				"error", err.Error(),
@@ -269,10 +359,13 @@ func (h *Handler) DeleteAccount() http.HandlerFunc {
 			return
 		}
 
-		log.Info("user account deleted",
-			"email", user.Email,
-			"role", user.Role,
-		)
+		h.audit(r, "user account deleted", &models.AuditEvent{
+			Event:      "user_deleted",
+			ActorID:    &ctx.UserID,
+			TargetType: models.AuditTargetUser,
+			TargetID:   strconv.Itoa(ctx.UserID),
+			Details:    map[string]any{"email": user.Email, "role": user.Role},
+		})
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
@@ -95,16 +95,32 @@ func TestUserHandlers_Integration(t *testing.T) {
 		})
 
 		t.Run("update password", func(t *testing.T) {
+			// Log in from another client to get a second session
+			loginReq := handlers.LoginRequest{
+				Email:    currentEmail,
+				Password: currentPassword,
+			}
+			rr := h.makeRequest(t, http.MethodPost, "/api/v1/auth/login", loginReq, nil)
+			require.Equal(t, http.StatusOK, rr.Code)
+			var loginResp handlers.LoginResponse
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&loginResp))
+
 			updateReq := handlers.UpdateProfileRequest{
 				CurrentPassword: currentPassword,
 				NewPassword:     "newpassword123",
 			}
 
-			rr := h.makeRequest(t, http.MethodPut, "/api/v1/profile", updateReq, h.RegularTestUser)
+			rr = h.makeRequest(t, http.MethodPut, "/api/v1/profile", updateReq, h.RegularTestUser)
 			require.Equal(t, http.StatusOK, rr.Code)
 
+			// Other sessions are revoked, the current one stays valid
+			_, err := h.DB.GetSessionByID(loginResp.SessionID)
+			assert.Error(t, err, "other session should be revoked after password change")
+			_, err = h.DB.GetSessionByID(h.RegularTestUser.session.ID)
+			assert.NoError(t, err, "current session should remain valid")
+
 			// Verify can login with new password
-			loginReq := handlers.LoginRequest{
+			loginReq = handlers.LoginRequest{
 				Email:    currentEmail,
 				Password: "newpassword123",
 			}
@@ -168,6 +184,10 @@ func TestUserHandlers_Integration(t *testing.T) {
 			rr := h.makeRequest(t, http.MethodDelete, "/api/v1/profile", deleteReq, testDeleteUser)
 			require.Equal(t, http.StatusNoContent, rr.Code)
 
+			// Verify sessions are removed with the account
+			_, err := h.DB.GetSessionByID(testDeleteUser.session.ID)
+			assert.Error(t, err)
+
 			// Verify user is deleted
 			loginReq := handlers.LoginRequest{
 				Email:    testDeleteUser.userModel.Email,
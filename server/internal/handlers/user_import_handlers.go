@@ -0,0 +1,531 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"lemma/internal/auth"
+	"lemma/internal/context"
+	"lemma/internal/models"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// maxUserImportRows limits the number of users that can be imported at once
+const maxUserImportRows = 1000
+
+// Credentials that imported users get to access their account
+const (
+	ImportCredentialsPassword = "password" // A temporary password that has to be changed on the first login
+	ImportCredentialsInvite   = "invite"   // An invitation link for signing up with the email of the row
+)
+
+// userImportColumns are the CSV columns of user imports and exports
+var userImportColumns = []string{"email", "displayName", "role", "workspace"}
+
+// UserImportRow holds a user to import
+type UserImportRow struct {
+	Email       string          `json:"email"`
+	DisplayName string          `json:"displayName,omitempty"`
+	Role        models.UserRole `json:"role"`
+	Workspace   string          `json:"workspace,omitempty"` // Name of the user's initial workspace, Main if empty
+}
+
+// UserImportResult holds the outcome of importing a row
+type UserImportResult struct {
+	Row               int    `json:"row"` // Starts at 1, not counting the CSV header
+	Email             string `json:"email"`
+	Error             string `json:"error,omitempty"`
+	UserID            int    `json:"userId,omitempty"`
+	TemporaryPassword string `json:"temporaryPassword,omitempty"`
+	InvitationID      int    `json:"invitationId,omitempty"`
+	InvitationLink    string `json:"invitationLink,omitempty"`
+}
+
+// UserImportResponse reports the outcome of a user import
+type UserImportResponse struct {
+	DryRun      bool               `json:"dryRun"`
+	Credentials string             `json:"credentials"`
+	Valid       int                `json:"valid"` // Rows that were imported, or would be without a dry run
+	Failed      int                `json:"failed"`
+	Rows        []UserImportResult `json:"rows"`
+}
+
+// UserExportRow holds an exported user. The import columns come first, so exports can be imported again.
+type UserExportRow struct {
+	UserImportRow
+	ID                 int        `json:"id"`
+	CreatedAt          time.Time  `json:"createdAt"`
+	MustChangePassword bool       `json:"mustChangePassword"`
+	SuspendedAt        *time.Time `json:"suspendedAt,omitempty"`
+}
+
+// validWorkspaceName reports whether name can be used as the name of an initial workspace.
+// Empty names are valid and stand for the default workspace name.
+func validWorkspaceName(name string) bool {
+	return !strings.ContainsAny(name, "/\\")
+}
+
+// parseUserImport reads the rows of a user import from a JSON array or, for text/csv requests, from CSV with a header
+func parseUserImport(r *http.Request) ([]UserImportRow, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "text/csv" {
+		var rows []UserImportRow
+		if err := json.NewDecoder(r.Body).Decode(&rows); err != nil {
+			return nil, err
+		}
+		return rows, nil
+	}
+
+	reader := csv.NewReader(r.Body)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["email"]; !ok {
+		return nil, errors.New("missing email column")
+	}
+
+	field := func(record []string, name string) string {
+		i, ok := columns[strings.ToLower(name)]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return record[i]
+	}
+
+	var rows []UserImportRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, UserImportRow{
+			Email:       field(record, "email"),
+			DisplayName: field(record, "displayName"),
+			Role:        models.UserRole(field(record, "role")),
+			Workspace:   field(record, "workspace"),
+		})
+		if len(rows) > maxUserImportRows {
+			break
+		}
+	}
+	return rows, nil
+}
+
+// validateUserImportRow returns why the row can't be imported, or an empty string if it can.
+// seen holds the emails of earlier rows of the import.
+func (h *Handler) validateUserImportRow(row *UserImportRow, seen map[string]bool) string {
+	if row.Email == "" {
+		return "Email is required"
+	}
+	if address, err := mail.ParseAddress(row.Email); err != nil || address.Address != row.Email {
+		return "Invalid email address"
+	}
+	if !row.Role.Valid() {
+		return "Invalid role"
+	}
+	if !validWorkspaceName(row.Workspace) {
+		return "Invalid workspace name"
+	}
+
+	key := strings.ToLower(row.Email)
+	if seen[key] {
+		return "Duplicate email in import"
+	}
+	seen[key] = true
+
+	// Deleted users keep their email until they are purged
+	emailTaken, err := h.DB.EmailExists(row.Email)
+	if err != nil {
+		return "Failed to check email"
+	}
+	if emailTaken {
+		return "Email already exists"
+	}
+	return ""
+}
+
+// generateTemporaryPassword returns a random password for imported users
+func generateTemporaryPassword() (string, error) {
+	token, err := auth.GenerateToken()
+	if err != nil {
+		return "", err
+	}
+	return token[:16], nil
+}
+
+// AdminImportUsers godoc
+// @Summary Import users
+// @Description Creates users from a JSON array or, with the text/csv content type, from CSV with a header row.
+// @Description The CSV columns are email, displayName, role and workspace; other columns are ignored.
+// @Description Rows are imported independently and the response reports the outcome of each row.
+// @Description Imported users either get a temporary password they have to change on their first login,
+// @Description or an invitation link for signing up. Passwords and links are only returned in this response.
+// @Tags Admin
+// @Security CookieAuth
+// @ID adminImportUsers
+// @Accept json
+// @Accept text/csv
+// @Produce json
+// @Param body body []UserImportRow true "Users to import"
+// @Param dryRun query bool false "Only validate the rows"
+// @Param credentials query string false "password (default) or invite"
+// @Success 200 {object} UserImportResponse
+// @Failure 400 {object} ErrorResponse "Invalid request body"
+// @Failure 400 {object} ErrorResponse "Invalid credentials type"
+// @Failure 400 {object} ErrorResponse "Too many rows"
+// @Router /admin/users/import [post]
+func (h *Handler) AdminImportUsers(rootURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := context.GetRequestContext(w, r)
+		if !ok {
+			return
+		}
+		log := getAdminLogger().With(
+			"handler", "AdminImportUsers",
+			"adminID", ctx.UserID,
+			"clientIP", r.RemoteAddr,
+		)
+
+		dryRun := r.URL.Query().Get("dryRun") == "true"
+		credentials := r.URL.Query().Get("credentials")
+		if credentials == "" {
+			credentials = ImportCredentialsPassword
+		}
+		if credentials != ImportCredentialsPassword && credentials != ImportCredentialsInvite {
+			respondError(w, "Invalid credentials type", http.StatusBadRequest)
+			return
+		}
+
+		rows, err := parseUserImport(r)
+		if err != nil {
+			log.Debug("failed to parse user import",
+				"error", err.Error(),
+			)
+			respondError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if len(rows) > maxUserImportRows {
+			log.Debug("too many rows in user import",
+				"rows", len(rows),
+			)
+			respondError(w, "Too many rows", http.StatusBadRequest)
+			return
+		}
+
+		response := UserImportResponse{
+			DryRun:      dryRun,
+			Credentials: credentials,
+			Rows:        make([]UserImportResult, 0, len(rows)),
+		}
+		seen := make(map[string]bool, len(rows))
+
+		for i := range rows {
+			row := &rows[i]
+			row.Email = strings.TrimSpace(row.Email)
+			row.DisplayName = strings.TrimSpace(row.DisplayName)
+			row.Role = models.UserRole(strings.ToLower(strings.TrimSpace(string(row.Role))))
+			row.Workspace = strings.TrimSpace(row.Workspace)
+
+			result := UserImportResult{Row: i + 1, Email: row.Email}
+			result.Error = h.validateUserImportRow(row, seen)
+			if result.Error == "" && !dryRun {
+				if credentials == ImportCredentialsInvite {
+					result.Error = h.importInvitation(r, ctx.UserID, rootURL, row, &result)
+				} else {
+					result.Error = h.importUser(r, ctx.UserID, row, &result)
+				}
+			}
+
+			if result.Error != "" {
+				response.Failed++
+			} else {
+				response.Valid++
+			}
+			response.Rows = append(response.Rows, result)
+		}
+
+		log.Debug("users imported",
+			"dryRun", dryRun,
+			"credentials", credentials,
+			"valid", response.Valid,
+			"failed", response.Failed,
+		)
+		if !dryRun {
+			h.audit(r, "users imported by admin", &models.AuditEvent{
+				Event:      "users_imported",
+				ActorID:    &ctx.UserID,
+				TargetType: models.AuditTargetSystem,
+				Details: map[string]any{
+					"credentials": credentials,
+					"imported":    response.Valid,
+					"failed":      response.Failed,
+				},
+			})
+		}
+		respondJSON(w, response)
+	}
+}
+
+// importUser creates the user of an import row with a temporary password.
+// It returns the error of the row, or an empty string if the user was created.
+func (h *Handler) importUser(r *http.Request, adminID int, row *UserImportRow, result *UserImportResult) string {
+	log := getAdminLogger().With(
+		"handler", "AdminImportUsers",
+		"adminID", adminID,
+		"email", row.Email,
+	)
+
+	password, err := generateTemporaryPassword()
+	if err != nil {
+		log.Error("failed to generate temporary password",
+			"error", err.Error(),
+		)
+		return "Failed to generate password"
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		log.Error("failed to hash password",
+			"error", err.Error(),
+		)
+		return "Failed to hash password"
+	}
+
+	workspaceName := row.Workspace
+	if workspaceName == "" {
+		workspaceName = models.DefaultWorkspaceName
+	}
+	user, err := h.DB.CreateUserWithWorkspace(&models.User{
+		Email:              row.Email,
+		DisplayName:        row.DisplayName,
+		PasswordHash:       string(hashedPassword),
+		Role:               row.Role,
+		MustChangePassword: true,
+	}, workspaceName)
+	if err != nil {
+		log.Error("failed to create user in database",
+			"error", err.Error(),
+		)
+		return "Failed to create user"
+	}
+
+	if err := h.Storage.InitializeUserWorkspace(user.ID, user.LastWorkspaceID); err != nil {
+		log.Error("failed to initialize user workspace",
+			"error", err.Error(),
+			"userID", user.ID,
+			"workspaceID", user.LastWorkspaceID,
+		)
+		return "Failed to initialize user workspace"
+	}
+
+	h.audit(r, "user created by admin import", &models.AuditEvent{
+		Event:      "user_created",
+		ActorID:    &adminID,
+		TargetType: models.AuditTargetUser,
+		TargetID:   strconv.Itoa(user.ID),
+		Details:    map[string]any{"email": user.Email, "role": user.Role, "import": true},
+	})
+	result.UserID = user.ID
+	result.TemporaryPassword = password
+	return ""
+}
+
+// importInvitation creates an invitation restricted to the email of an import row.
+// It returns the error of the row, or an empty string if the invitation was created.
+func (h *Handler) importInvitation(r *http.Request, adminID int, rootURL string, row *UserImportRow, result *UserImportResult) string {
+	log := getAdminLogger().With(
+		"handler", "AdminImportUsers",
+		"adminID", adminID,
+		"email", row.Email,
+	)
+
+	token, err := auth.GenerateToken()
+	if err != nil {
+		log.Error("failed to generate invitation token",
+			"error", err.Error(),
+		)
+		return "Failed to create invitation"
+	}
+
+	invitation := &models.Invitation{
+		TokenHash:     auth.HashToken(token),
+		Role:          row.Role,
+		Email:         row.Email,
+		WorkspaceName: row.Workspace,
+		MaxUses:       1,
+		ExpiresAt:     time.Now().Add(defaultInvitationExpiry),
+		CreatedBy:     adminID,
+	}
+	if err := h.DB.CreateInvitation(invitation); err != nil {
+		log.Error("failed to create invitation in database",
+			"error", err.Error(),
+		)
+		return "Failed to create invitation"
+	}
+
+	h.audit(r, "invitation created by admin import", &models.AuditEvent{
+		Event:      "invitation_created",
+		ActorID:    &adminID,
+		TargetType: models.AuditTargetInvitation,
+		TargetID:   strconv.Itoa(invitation.ID),
+		Details:    map[string]any{"role": invitation.Role, "email": invitation.Email, "maxUses": invitation.MaxUses, "import": true},
+	})
+	result.InvitationID = invitation.ID
+	result.InvitationLink = accountLink(rootURL, "signup", token)
+	return ""
+}
+
+// AdminExportUsers godoc
+// @Summary Export users
+// @Description Exports all users as a JSON array or, with format=csv, as CSV with a header row.
+// @Description The export starts with the columns of user imports, so it can be imported again.
+// @Tags Admin
+// @Security CookieAuth
+// @ID adminExportUsers
+// @Produce json
+// @Produce text/csv
+// @Param format query string false "json (default) or csv"
+// @Success 200 {array} UserExportRow
+// @Failure 400 {object} ErrorResponse "Invalid format"
+// @Failure 500 {object} ErrorResponse "Failed to list users"
+// @Failure 500 {object} ErrorResponse "Failed to list workspaces"
+// @Router /admin/users/export [get]
+func (h *Handler) AdminExportUsers() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := context.GetRequestContext(w, r)
+		if !ok {
+			return
+		}
+		log := getAdminLogger().With(
+			"handler", "AdminExportUsers",
+			"adminID", ctx.UserID,
+			"clientIP", r.RemoteAddr,
+		)
+
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = "json"
+		}
+		if format != "json" && format != "csv" {
+			respondError(w, "Invalid format", http.StatusBadRequest)
+			return
+		}
+
+		users, err := h.DB.GetAllUsers()
+		if err != nil {
+			log.Error("failed to fetch users from database",
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to list users", http.StatusInternalServerError)
+			return
+		}
+
+		workspaces, err := h.DB.GetAllWorkspaces()
+		if err != nil {
+			log.Error("failed to fetch workspaces from database",
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to list workspaces", http.StatusInternalServerError)
+			return
+		}
+
+		// The initial workspace of a user is their oldest workspace that still exists
+		initialWorkspaces := make(map[int]*models.Workspace, len(users))
+		for _, workspace := range workspaces {
+			if current, ok := initialWorkspaces[workspace.UserID]; !ok || workspace.ID < current.ID {
+				initialWorkspaces[workspace.UserID] = workspace
+			}
+		}
+
+		rows := make([]UserExportRow, 0, len(users))
+		for _, user := range users {
+			row := UserExportRow{
+				UserImportRow: UserImportRow{
+					Email:       user.Email,
+					DisplayName: user.DisplayName,
+					Role:        user.Role,
+				},
+				ID:                 user.ID,
+				CreatedAt:          user.CreatedAt,
+				MustChangePassword: user.MustChangePassword,
+				SuspendedAt:        user.SuspendedAt,
+			}
+			if workspace, ok := initialWorkspaces[user.ID]; ok {
+				row.Workspace = workspace.Name
+			}
+			rows = append(rows, row)
+		}
+
+		h.audit(r, "users exported by admin", &models.AuditEvent{
+			Event:      "users_exported",
+			ActorID:    &ctx.UserID,
+			TargetType: models.AuditTargetSystem,
+			Details:    map[string]any{"format": format, "users": len(rows)},
+		})
+
+		if format == "json" {
+			respondJSON(w, rows)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="users.csv"`)
+
+		writer := csv.NewWriter(w)
+		header := append(append([]string{}, userImportColumns...), "id", "createdAt", "mustChangePassword", "suspendedAt")
+		if err := writer.Write(header); err != nil {
+			log.Error("failed to write users export",
+				"error", err.Error(),
+			)
+			return
+		}
+		for _, row := range rows {
+			suspendedAt := ""
+			if row.SuspendedAt != nil {
+				suspendedAt = row.SuspendedAt.UTC().Format(time.RFC3339)
+			}
+			record := []string{
+				row.Email,
+				row.DisplayName,
+				string(row.Role),
+				row.Workspace,
+				strconv.Itoa(row.ID),
+				row.CreatedAt.UTC().Format(time.RFC3339),
+				strconv.FormatBool(row.MustChangePassword),
+				suspendedAt,
+			}
+			if err := writer.Write(record); err != nil {
+				log.Error("failed to write users export",
+					"error", err.Error(),
+				)
+				return
+			}
+		}
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			log.Error("failed to write users export",
+				"error", err.Error(),
+			)
+		}
+	}
+}
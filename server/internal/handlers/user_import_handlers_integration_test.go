@@ -0,0 +1,195 @@
+//go:build integration
+
+package handlers_test
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"lemma/internal/handlers"
+	"lemma/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserImportHandlers_Integration(t *testing.T) {
+	h := setupTestHarness(t)
+	defer h.teardown(t)
+
+	importURL := "/api/v1/admin/users/import"
+
+	importCSV := func(t *testing.T, query, body string, user *testUser) handlers.UserImportResponse {
+		t.Helper()
+		req := h.newRequestRaw(t, http.MethodPost, importURL+query, strings.NewReader(body))
+		req.Header.Set("Content-Type", "text/csv")
+		h.addAuthCookies(t, req, user)
+		req.Header.Set("X-CSRF-Token", h.addCSRFCookie(t, req))
+
+		rr := h.executeRequest(req)
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var response handlers.UserImportResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+		return response
+	}
+
+	t.Run("dry run reports errors per row without creating users", func(t *testing.T) {
+		body := "Email,DisplayName,Role,Workspace\n" +
+			"new1@test.com,New One,editor,Class\n" +
+			"not-an-email,Invalid,editor,\n" +
+			"new1@test.com,Duplicate,viewer,\n" +
+			h.RegularTestUser.userModel.Email + ",Existing,viewer,\n" +
+			"new2@test.com,Bad Role,owner,\n" +
+			"new3@test.com,Bad Workspace,viewer,a/b\n"
+
+		response := importCSV(t, "?dryRun=true", body, h.AdminTestUser)
+		assert.True(t, response.DryRun)
+		assert.Equal(t, 1, response.Valid)
+		assert.Equal(t, 5, response.Failed)
+
+		errs := make([]string, 0, len(response.Rows))
+		for _, row := range response.Rows {
+			errs = append(errs, row.Error)
+			assert.Zero(t, row.UserID)
+			assert.Empty(t, row.TemporaryPassword)
+		}
+		assert.Equal(t, []string{
+			"",
+			"Invalid email address",
+			"Duplicate email in import",
+			"Email already exists",
+			"Invalid role",
+			"Invalid workspace name",
+		}, errs)
+
+		_, err := h.DB.GetUserByEmail("new1@test.com")
+		assert.Error(t, err)
+	})
+
+	t.Run("import with temporary passwords", func(t *testing.T) {
+		rows := []handlers.UserImportRow{
+			{Email: "student1@test.com", DisplayName: "Student One", Role: models.RoleEditor, Workspace: "Class"},
+			{Email: "student2@test.com", Role: models.RoleViewer},
+			{Email: "student3@test.com", Role: "invalid"},
+		}
+		rr := h.makeRequest(t, http.MethodPost, importURL, rows, h.AdminTestUser)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var response handlers.UserImportResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+		assert.False(t, response.DryRun)
+		assert.Equal(t, handlers.ImportCredentialsPassword, response.Credentials)
+		assert.Equal(t, 2, response.Valid)
+		assert.Equal(t, 1, response.Failed)
+		require.Len(t, response.Rows, 3)
+		assert.Equal(t, "Invalid role", response.Rows[2].Error)
+
+		first := response.Rows[0]
+		require.NotZero(t, first.UserID)
+		require.NotEmpty(t, first.TemporaryPassword)
+
+		user, err := h.DB.GetUserByID(first.UserID)
+		require.NoError(t, err)
+		assert.Equal(t, "Student One", user.DisplayName)
+		assert.True(t, user.MustChangePassword)
+
+		workspaces, err := h.DB.GetWorkspacesByUserID(first.UserID)
+		require.NoError(t, err)
+		require.Len(t, workspaces, 1)
+		assert.Equal(t, "Class", workspaces[0].Name)
+
+		workspaces, err = h.DB.GetWorkspacesByUserID(response.Rows[1].UserID)
+		require.NoError(t, err)
+		require.Len(t, workspaces, 1)
+		assert.Equal(t, models.DefaultWorkspaceName, workspaces[0].Name)
+
+		rr = h.makeRequest(t, http.MethodPost, "/api/v1/auth/login", handlers.LoginRequest{
+			Email:    first.Email,
+			Password: first.TemporaryPassword,
+		}, nil)
+		require.Equal(t, http.StatusOK, rr.Code)
+		var login handlers.LoginResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&login))
+		assert.True(t, login.User.MustChangePassword)
+	})
+
+	t.Run("import with invitation links", func(t *testing.T) {
+		body := "email,role,workspace\ninvited@test.com,viewer,Team\n"
+		response := importCSV(t, "?credentials=invite", body, h.AdminTestUser)
+		require.Equal(t, 1, response.Valid)
+
+		row := response.Rows[0]
+		assert.Zero(t, row.UserID)
+		require.NotZero(t, row.InvitationID)
+		link, err := url.Parse(row.InvitationLink)
+		require.NoError(t, err)
+
+		rr := h.makeRequest(t, http.MethodPost, "/api/v1/auth/signup", handlers.SignupRequest{
+			Email:           "invited@test.com",
+			Password:        "password123",
+			InvitationToken: link.Query().Get("token"),
+		}, nil)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		user, err := h.DB.GetUserByEmail("invited@test.com")
+		require.NoError(t, err)
+		assert.Equal(t, models.RoleViewer, user.Role)
+		workspaces, err := h.DB.GetWorkspacesByUserID(user.ID)
+		require.NoError(t, err)
+		require.Len(t, workspaces, 1)
+		assert.Equal(t, "Team", workspaces[0].Name)
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		rr := h.makeRequest(t, http.MethodPost, importURL+"?credentials=magic", []handlers.UserImportRow{}, h.AdminTestUser)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+		rr = h.makeRequestRaw(t, http.MethodPost, importURL, strings.NewReader("not json"), h.AdminTestUser)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+		rr = h.makeRequest(t, http.MethodPost, importURL, []handlers.UserImportRow{}, h.RegularTestUser)
+		assert.Equal(t, http.StatusForbidden, rr.Code)
+	})
+
+	t.Run("export", func(t *testing.T) {
+		rr := h.makeRequest(t, http.MethodGet, "/api/v1/admin/users/export", nil, h.AdminTestUser)
+		require.Equal(t, http.StatusOK, rr.Code)
+		var exported []handlers.UserExportRow
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&exported))
+
+		var student *handlers.UserExportRow
+		for i := range exported {
+			if exported[i].Email == "student1@test.com" {
+				student = &exported[i]
+			}
+		}
+		require.NotNil(t, student)
+		assert.Equal(t, "Class", student.Workspace)
+		assert.Equal(t, models.RoleEditor, student.Role)
+		assert.True(t, student.MustChangePassword)
+
+		rr = h.makeRequest(t, http.MethodGet, "/api/v1/admin/users/export?format=csv", nil, h.AdminTestUser)
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, rr.Header().Get("Content-Type"), "text/csv")
+		csvExport := rr.Body.String()
+		records, err := csv.NewReader(strings.NewReader(csvExport)).ReadAll()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"email", "displayName", "role", "workspace", "id", "createdAt", "mustChangePassword", "suspendedAt"}, records[0])
+		assert.Len(t, records, len(exported)+1)
+
+		// Exports can be imported again, which fails for users that already exist
+		response := importCSV(t, "?dryRun=true", csvExport, h.AdminTestUser)
+		assert.Equal(t, 0, response.Valid)
+		assert.Equal(t, len(exported), response.Failed)
+		for _, row := range response.Rows {
+			assert.Equal(t, "Email already exists", row.Error)
+		}
+
+		rr = h.makeRequest(t, http.MethodGet, "/api/v1/admin/users/export?format=xml", nil, h.AdminTestUser)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
@@ -3,11 +3,14 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
 
 	"lemma/internal/context"
 	"lemma/internal/logging"
 	"lemma/internal/models"
+	"lemma/internal/storage"
 )
 
 // DeleteWorkspaceResponse contains the name of the next workspace after deleting the current one
@@ -24,9 +27,21 @@ func getWorkspaceLogger() logging.Logger {
 	return getHandlersLogger().WithGroup("workspace")
 }
 
+// withoutSharedGitToken returns the workspace without its Git token if the user doesn't own it,
+// so the owner's credentials aren't revealed to members
+func withoutSharedGitToken(workspace *models.Workspace, userID int) *models.Workspace {
+	if workspace.UserID == userID {
+		return workspace
+	}
+	shared := *workspace
+	shared.GitToken = ""
+	return &shared
+}
+
 // ListWorkspaces godoc
 // @Summary List workspaces
-// @Description Lists all workspaces for the current user
+// @Description Lists all workspaces the current user owns or is a member of, owned workspaces first.
+// @Description Git tokens of shared workspaces are omitted.
 // @Tags workspaces
 // @ID listWorkspaces
 // @Security CookieAuth
@@ -46,7 +61,7 @@ func (h *Handler) ListWorkspaces() http.HandlerFunc {
 			"clientIP", r.RemoteAddr,
 		)
 
-		workspaces, err := h.DB.GetWorkspacesByUserID(ctx.UserID)
+		workspaces, err := h.DB.GetWorkspacesByMemberID(ctx.UserID)
 		if err != nil {
 			log.Error("failed to fetch workspaces from database",
 				"error", err.Error(),
@@ -55,6 +70,10 @@ func (h *Handler) ListWorkspaces() http.HandlerFunc {
 			return
 		}
 
+		for i, workspace := range workspaces {
+			workspaces[i] = withoutSharedGitToken(workspace, ctx.UserID)
+		}
+
 		respondJSON(w, workspaces)
 	}
 }
@@ -71,9 +90,11 @@ func (h *Handler) ListWorkspaces() http.HandlerFunc {
 // @Success 200 {object} models.Workspace
 // @Failure 400 {object} ErrorResponse "Invalid request body"
 // @Failure 400 {object} ErrorResponse "Invalid workspace"
+// @Failure 409 {object} ErrorResponse "A workspace with this name already exists"
 // @Failure 500 {object} ErrorResponse "Failed to create workspace"
 // @Failure 500 {object} ErrorResponse "Failed to initialize workspace directory"
 // @Failure 500 {object} ErrorResponse "Failed to setup git repo"
+// @Failure 507 {object} ErrorResponse "Storage quota exceeded"
 // @Router /workspaces [post]
 func (h *Handler) CreateWorkspace() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -104,6 +125,15 @@ func (h *Handler) CreateWorkspace() http.HandlerFunc {
 			return
 		}
 
+		// Workspaces are addressed by name, so a shared workspace with the name would become unreachable
+		if h.hasWorkspaceNamed(ctx.UserID, workspace.Name) {
+			log.Debug("workspace name conflicts with an accessible workspace",
+				"workspaceName", workspace.Name,
+			)
+			respondError(w, "A workspace with this name already exists", http.StatusConflict)
+			return
+		}
+
 		workspace.UserID = ctx.UserID
 		if err := h.DB.CreateWorkspace(&workspace); err != nil {
 			log.Error("failed to create workspace in database",
@@ -133,6 +163,10 @@ func (h *Handler) CreateWorkspace() http.HandlerFunc {
 				workspace.GitCommitName,
 				workspace.GitCommitEmail,
 			); err != nil {
+				if errors.Is(err, storage.ErrQuotaExceeded) {
+					respondError(w, "Storage quota exceeded", http.StatusInsufficientStorage)
+					return
+				}
 				log.Error("failed to setup git repository",
 					"error", err.Error(),
 					"workspaceID", workspace.ID,
@@ -142,18 +176,21 @@ func (h *Handler) CreateWorkspace() http.HandlerFunc {
 			}
 		}
 
-		log.Info("workspace created",
-			"workspaceID", workspace.ID,
-			"workspaceName", workspace.Name,
-			"gitEnabled", workspace.GitEnabled,
-		)
+		h.audit(r, "workspace created", &models.AuditEvent{
+			Event:       "workspace_created",
+			ActorID:     &ctx.UserID,
+			TargetType:  models.AuditTargetWorkspace,
+			TargetID:    strconv.Itoa(workspace.ID),
+			WorkspaceID: &workspace.ID,
+			Details:     map[string]any{"name": workspace.Name, "gitEnabled": workspace.GitEnabled},
+		})
 		respondJSON(w, workspace)
 	}
 }
 
 // GetWorkspace godoc
 // @Summary Get workspace
-// @Description Returns the current workspace
+// @Description Returns the current workspace. The Git token is omitted if the workspace is shared with the user.
 // @Tags workspaces
 // @ID getWorkspace
 // @Security CookieAuth
@@ -169,10 +206,16 @@ func (h *Handler) GetWorkspace() http.HandlerFunc {
 			return
 		}
 
-		respondJSON(w, ctx.Workspace)
+		respondJSON(w, withoutSharedGitToken(ctx.Workspace, ctx.UserID))
 	}
 }
 
+// hasWorkspaceNamed reports whether the user owns or is a member of a workspace with the name
+func (h *Handler) hasWorkspaceNamed(userID int, name string) bool {
+	_, err := h.DB.GetMemberWorkspaceByName(userID, name)
+	return err == nil
+}
+
 func gitSettingsChanged(new, old *models.Workspace) bool {
 	// Check if Git was enabled/disabled
 	if new.GitEnabled != old.GitEnabled {
@@ -203,8 +246,11 @@ func gitSettingsChanged(new, old *models.Workspace) bool {
 // @Param body body models.Workspace true "Workspace"
 // @Success 200 {object} models.Workspace
 // @Failure 400 {object} ErrorResponse "Invalid request body"
+// @Failure 409 {object} ErrorResponse "A member already has a workspace with this name"
+// @Failure 500 {object} ErrorResponse "Failed to get workspace members"
 // @Failure 500 {object} ErrorResponse "Failed to update workspace"
 // @Failure 500 {object} ErrorResponse "Failed to setup git repo"
+// @Failure 507 {object} ErrorResponse "Storage quota exceeded"
 // @Router /workspaces/{workspace_name} [put]
 func (h *Handler) UpdateWorkspace() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -230,7 +276,12 @@ func (h *Handler) UpdateWorkspace() http.HandlerFunc {
 
 		// Set IDs from the request
 		workspace.ID = ctx.Workspace.ID
-		workspace.UserID = ctx.UserID
+		workspace.UserID = ctx.Workspace.UserID
+
+		// Members don't see the owner's Git token, so keep it unless a new one is given
+		if workspace.GitToken == "" && ctx.Workspace.UserID != ctx.UserID {
+			workspace.GitToken = ctx.Workspace.GitToken
+		}
 
 		// Validate the workspace
 		if err := workspace.Validate(); err != nil {
@@ -249,11 +300,34 @@ func (h *Handler) UpdateWorkspace() http.HandlerFunc {
 			"autoSave":    workspace.AutoSave != ctx.Workspace.AutoSave,
 		}
 
+		// The new name must not hide another workspace of any member
+		if changes["name"] {
+			members, err := h.DB.GetWorkspaceMembers(ctx.Workspace.ID)
+			if err != nil {
+				log.Error("failed to fetch workspace members",
+					"error", err.Error(),
+				)
+				respondError(w, "Failed to get workspace members", http.StatusInternalServerError)
+				return
+			}
+
+			for _, member := range members {
+				if h.hasWorkspaceNamed(member.UserID, workspace.Name) {
+					log.Debug("workspace name conflicts for member",
+						"memberID", member.UserID,
+						"workspaceName", workspace.Name,
+					)
+					respondError(w, "A member already has a workspace with this name", http.StatusConflict)
+					return
+				}
+			}
+		}
+
 		// Handle Git repository setup/teardown if Git settings changed
 		if changes["gitSettings"] {
 			if workspace.GitEnabled {
 				if err := h.Storage.SetupGitRepo(
-					ctx.UserID,
+					ctx.Workspace.UserID,
 					ctx.Workspace.ID,
 					workspace.GitURL,
 					workspace.GitUser,
@@ -261,6 +335,10 @@ func (h *Handler) UpdateWorkspace() http.HandlerFunc {
 					workspace.GitCommitName,
 					workspace.GitCommitEmail,
 				); err != nil {
+					if errors.Is(err, storage.ErrQuotaExceeded) {
+						respondError(w, "Storage quota exceeded", http.StatusInsufficientStorage)
+						return
+					}
 					log.Error("failed to setup git repository",
 						"error", err.Error(),
 					)
@@ -268,7 +346,7 @@ func (h *Handler) UpdateWorkspace() http.HandlerFunc {
 					return
 				}
 			} else {
-				h.Storage.DisableGitRepo(ctx.UserID, ctx.Workspace.ID)
+				h.Storage.DisableGitRepo(ctx.Workspace.UserID, ctx.Workspace.ID)
 			}
 		}
 
@@ -280,13 +358,28 @@ func (h *Handler) UpdateWorkspace() http.HandlerFunc {
 			return
 		}
 
-		respondJSON(w, workspace)
+		if changes["gitSettings"] {
+			h.audit(r, "workspace git settings changed", &models.AuditEvent{
+				Event:       "git_settings_changed",
+				ActorID:     &ctx.UserID,
+				TargetType:  models.AuditTargetWorkspace,
+				TargetID:    strconv.Itoa(workspace.ID),
+				WorkspaceID: &workspace.ID,
+				Details: map[string]any{
+					"gitEnabled":      workspace.GitEnabled,
+					"gitUrl":          workspace.GitURL,
+					"gitTokenChanged": workspace.GitToken != ctx.Workspace.GitToken,
+				},
+			})
+		}
+
+		respondJSON(w, withoutSharedGitToken(&workspace, ctx.UserID))
 	}
 }
 
 // DeleteWorkspace godoc
 // @Summary Delete workspace
-// @Description Deletes the current workspace
+// @Description Moves the current workspace to the trash. It is permanently deleted with its files after the trash retention period.
 // @Tags workspaces
 // @ID deleteWorkspace
 // @Security CookieAuth
@@ -314,8 +407,8 @@ func (h *Handler) DeleteWorkspace() http.HandlerFunc {
 			"clientIP", r.RemoteAddr,
 		)
 
-		// Check if this is the user's last workspace
-		workspaces, err := h.DB.GetWorkspacesByUserID(ctx.UserID)
+		// Check if this is the owner's last workspace
+		ownedWorkspaces, err := h.DB.GetWorkspacesByUserID(ctx.Workspace.UserID)
 		if err != nil {
 			log.Error("failed to fetch workspaces from database",
 				"error", err.Error(),
@@ -324,13 +417,22 @@ func (h *Handler) DeleteWorkspace() http.HandlerFunc {
 			return
 		}
 
-		if len(workspaces) <= 1 {
+		if len(ownedWorkspaces) <= 1 {
 			log.Debug("attempted to delete last workspace")
 			respondError(w, "Cannot delete the last workspace", http.StatusBadRequest)
 			return
 		}
 
-		// Find another workspace to set as last
+		workspaces, err := h.DB.GetWorkspacesByMemberID(ctx.UserID)
+		if err != nil {
+			log.Error("failed to fetch workspaces from database",
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to get workspaces", http.StatusInternalServerError)
+			return
+		}
+
+		// Find another workspace to set as last, other members are moved by the database
 		var nextWorkspaceName string
 		var nextWorkspaceID int
 		for _, ws := range workspaces {
@@ -388,10 +490,14 @@ func (h *Handler) DeleteWorkspace() http.HandlerFunc {
 			return
 		}
 
-		log.Info("workspace deleted",
-			"workspaceName", ctx.Workspace.Name,
-			"nextWorkspaceName", nextWorkspaceName,
-		)
+		h.audit(r, "workspace deleted", &models.AuditEvent{
+			Event:       "workspace_deleted",
+			ActorID:     &ctx.UserID,
+			TargetType:  models.AuditTargetWorkspace,
+			TargetID:    strconv.Itoa(ctx.Workspace.ID),
+			WorkspaceID: &ctx.Workspace.ID,
+			Details:     map[string]any{"name": ctx.Workspace.Name, "ownerID": ctx.Workspace.UserID},
+		})
 
 		// Return the next workspace ID in the response so frontend knows where to redirect
 		respondJSON(w, &DeleteWorkspaceResponse{NextWorkspaceName: nextWorkspaceName})
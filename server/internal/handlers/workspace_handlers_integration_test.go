@@ -4,8 +4,11 @@ package handlers_test
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/url"
+	"path/filepath"
+	"sync"
 	"testing"
 
 	"lemma/internal/models"
@@ -303,3 +306,49 @@ func TestWorkspaceHandlers_Integration(t *testing.T) {
 		})
 	})
 }
+
+func TestConcurrentWrites_Integration(t *testing.T) {
+	// A database file is used, as in-memory databases are limited to a single connection
+	h := setupTestHarnessWithDB(t, filepath.Join(t.TempDir(), "lemma.db"))
+	defer h.teardown(t)
+
+	const writers = 20
+	type result struct{ got, want int }
+	results := make(chan result, writers*2)
+
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			user := h.RegularTestUser
+			if i%2 == 0 {
+				user = h.AdminTestUser
+			}
+			workspace := &models.Workspace{Name: fmt.Sprintf("Concurrent Workspace %d", i)}
+			rr := h.makeRequest(t, http.MethodPost, "/api/v1/workspaces", workspace, user)
+			results <- result{rr.Code, http.StatusOK}
+
+			rr = h.makeRequest(t, http.MethodPut, "/api/v1/workspaces/last",
+				map[string]string{"workspaceName": workspace.Name}, user)
+			results <- result{rr.Code, http.StatusNoContent}
+		}(i)
+	}
+	wg.Wait()
+	close(results)
+
+	for r := range results {
+		assert.Equal(t, r.want, r.got)
+	}
+
+	for _, user := range []*testUser{h.AdminTestUser, h.RegularTestUser} {
+		rr := h.makeRequest(t, http.MethodGet, "/api/v1/workspaces", nil, user)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var workspaces []*models.Workspace
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&workspaces))
+		// Each user has a default workspace and half of the created ones
+		assert.Len(t, workspaces, 1+writers/2)
+	}
+}
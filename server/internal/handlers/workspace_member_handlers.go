@@ -0,0 +1,337 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"lemma/internal/context"
+	"lemma/internal/logging"
+	"lemma/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// AddWorkspaceMemberRequest represents a request to share a workspace with a user
+type AddWorkspaceMemberRequest struct {
+	Email string               `json:"email"`
+	Role  models.WorkspaceRole `json:"role"`
+}
+
+// UpdateWorkspaceMemberRequest represents a request to change the role of a workspace member
+type UpdateWorkspaceMemberRequest struct {
+	Role models.WorkspaceRole `json:"role"`
+}
+
+func getWorkspaceMemberLogger() logging.Logger {
+	return getHandlersLogger().WithGroup("workspace_member")
+}
+
+// memberRoleValid reports whether a member can be given the role. There is only one owner.
+func memberRoleValid(role models.WorkspaceRole) bool {
+	return role == models.WorkspaceRoleEditor || role == models.WorkspaceRoleViewer
+}
+
+// ListWorkspaceMembers godoc
+// @Summary List workspace members
+// @Description Lists the users with access to the current workspace, the owner first
+// @Tags workspaces
+// @ID listWorkspaceMembers
+// @Security CookieAuth
+// @Produce json
+// @Param workspace_name path string true "Workspace name"
+// @Success 200 {array} models.WorkspaceMember
+// @Failure 500 {object} ErrorResponse "Failed to list workspace members"
+// @Router /workspaces/{workspace_name}/members [get]
+func (h *Handler) ListWorkspaceMembers() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := context.GetRequestContext(w, r)
+		if !ok {
+			return
+		}
+		log := getWorkspaceMemberLogger().With(
+			"handler", "ListWorkspaceMembers",
+			"userID", ctx.UserID,
+			"workspaceID", ctx.Workspace.ID,
+			"clientIP", r.RemoteAddr,
+		)
+
+		members, err := h.DB.GetWorkspaceMembers(ctx.Workspace.ID)
+		if err != nil {
+			log.Error("failed to fetch workspace members from database",
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to list workspace members", http.StatusInternalServerError)
+			return
+		}
+
+		respondJSON(w, members)
+	}
+}
+
+// AddWorkspaceMember godoc
+// @Summary Add workspace member
+// @Description Shares the current workspace with a user as editor or viewer
+// @Tags workspaces
+// @ID addWorkspaceMember
+// @Security CookieAuth
+// @Accept json
+// @Produce json
+// @Param workspace_name path string true "Workspace name"
+// @Param body body AddWorkspaceMemberRequest true "Add workspace member request"
+// @Success 200 {object} models.WorkspaceMember
+// @Failure 400 {object} ErrorResponse "Invalid request body"
+// @Failure 400 {object} ErrorResponse "Role must be editor or viewer"
+// @Failure 404 {object} ErrorResponse "User not found"
+// @Failure 409 {object} ErrorResponse "User is already a member"
+// @Failure 409 {object} ErrorResponse "User already has a workspace with this name"
+// @Failure 500 {object} ErrorResponse "Failed to add workspace member"
+// @Router /workspaces/{workspace_name}/members [post]
+func (h *Handler) AddWorkspaceMember() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := context.GetRequestContext(w, r)
+		if !ok {
+			return
+		}
+		log := getWorkspaceMemberLogger().With(
+			"handler", "AddWorkspaceMember",
+			"userID", ctx.UserID,
+			"workspaceID", ctx.Workspace.ID,
+			"clientIP", r.RemoteAddr,
+		)
+
+		var req AddWorkspaceMemberRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			log.Debug("failed to decode request body",
+				"error", err.Error(),
+			)
+			respondError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if !memberRoleValid(req.Role) {
+			log.Debug("invalid member role",
+				"role", req.Role,
+			)
+			respondError(w, "Role must be editor or viewer", http.StatusBadRequest)
+			return
+		}
+
+		user, err := h.DB.GetUserByEmail(req.Email)
+		if err != nil {
+			log.Debug("user not found",
+				"email", req.Email,
+				"error", err.Error(),
+			)
+			respondError(w, "User not found", http.StatusNotFound)
+			return
+		}
+
+		if _, err := h.DB.GetWorkspaceMember(ctx.Workspace.ID, user.ID); err == nil {
+			respondError(w, "User is already a member", http.StatusConflict)
+			return
+		}
+
+		// Workspaces are addressed by name, so the user can't have access to two with the same name
+		if h.hasWorkspaceNamed(user.ID, ctx.Workspace.Name) {
+			log.Debug("workspace name conflicts for new member",
+				"memberID", user.ID,
+			)
+			respondError(w, "User already has a workspace with this name", http.StatusConflict)
+			return
+		}
+
+		member := &models.WorkspaceMember{
+			WorkspaceID: ctx.Workspace.ID,
+			UserID:      user.ID,
+			Role:        req.Role,
+		}
+		if err := h.DB.CreateWorkspaceMember(member); err != nil {
+			log.Error("failed to create workspace member",
+				"memberID", user.ID,
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to add workspace member", http.StatusInternalServerError)
+			return
+		}
+
+		h.audit(r, "workspace member added", &models.AuditEvent{
+			Event:       "workspace_member_added",
+			ActorID:     &ctx.UserID,
+			TargetType:  models.AuditTargetUser,
+			TargetID:    strconv.Itoa(member.UserID),
+			WorkspaceID: &ctx.Workspace.ID,
+			Details:     map[string]any{"role": member.Role},
+		})
+		respondJSON(w, member)
+	}
+}
+
+// UpdateWorkspaceMember godoc
+// @Summary Update workspace member
+// @Description Changes the role of a member of the current workspace
+// @Tags workspaces
+// @ID updateWorkspaceMember
+// @Security CookieAuth
+// @Accept json
+// @Produce json
+// @Param workspace_name path string true "Workspace name"
+// @Param userId path int true "User ID of the member"
+// @Param body body UpdateWorkspaceMemberRequest true "Update workspace member request"
+// @Success 200 {object} models.WorkspaceMember
+// @Failure 400 {object} ErrorResponse "Invalid user ID"
+// @Failure 400 {object} ErrorResponse "Invalid request body"
+// @Failure 400 {object} ErrorResponse "Role must be editor or viewer"
+// @Failure 400 {object} ErrorResponse "Cannot change the role of the workspace owner"
+// @Failure 404 {object} ErrorResponse "Workspace member not found"
+// @Failure 500 {object} ErrorResponse "Failed to update workspace member"
+// @Router /workspaces/{workspace_name}/members/{userId} [put]
+func (h *Handler) UpdateWorkspaceMember() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := context.GetRequestContext(w, r)
+		if !ok {
+			return
+		}
+		log := getWorkspaceMemberLogger().With(
+			"handler", "UpdateWorkspaceMember",
+			"userID", ctx.UserID,
+			"workspaceID", ctx.Workspace.ID,
+			"clientIP", r.RemoteAddr,
+		)
+
+		memberID, err := strconv.Atoi(chi.URLParam(r, "userId"))
+		if err != nil {
+			log.Debug("invalid user ID format",
+				"userIDParam", chi.URLParam(r, "userId"),
+				"error", err.Error(),
+			)
+			respondError(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		var req UpdateWorkspaceMemberRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			log.Debug("failed to decode request body",
+				"error", err.Error(),
+			)
+			respondError(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if !memberRoleValid(req.Role) {
+			log.Debug("invalid member role",
+				"role", req.Role,
+			)
+			respondError(w, "Role must be editor or viewer", http.StatusBadRequest)
+			return
+		}
+
+		member, err := h.DB.GetWorkspaceMember(ctx.Workspace.ID, memberID)
+		if err != nil {
+			log.Debug("workspace member not found",
+				"memberID", memberID,
+				"error", err.Error(),
+			)
+			respondError(w, "Workspace member not found", http.StatusNotFound)
+			return
+		}
+
+		if member.Role == models.WorkspaceRoleOwner {
+			respondError(w, "Cannot change the role of the workspace owner", http.StatusBadRequest)
+			return
+		}
+
+		previousRole := member.Role
+		member.Role = req.Role
+		if err := h.DB.UpdateWorkspaceMember(member); err != nil {
+			log.Error("failed to update workspace member",
+				"memberID", memberID,
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to update workspace member", http.StatusInternalServerError)
+			return
+		}
+
+		h.audit(r, "workspace member updated", &models.AuditEvent{
+			Event:       "workspace_member_updated",
+			ActorID:     &ctx.UserID,
+			TargetType:  models.AuditTargetUser,
+			TargetID:    strconv.Itoa(member.UserID),
+			WorkspaceID: &ctx.Workspace.ID,
+			Details:     map[string]any{"previousRole": previousRole, "role": member.Role},
+		})
+		respondJSON(w, member)
+	}
+}
+
+// RemoveWorkspaceMember godoc
+// @Summary Remove workspace member
+// @Description Removes a user's access to the current workspace
+// @Tags workspaces
+// @ID removeWorkspaceMember
+// @Security CookieAuth
+// @Param workspace_name path string true "Workspace name"
+// @Param userId path int true "User ID of the member"
+// @Success 204 "No Content - Member removed successfully"
+// @Failure 400 {object} ErrorResponse "Invalid user ID"
+// @Failure 400 {object} ErrorResponse "Cannot remove the workspace owner"
+// @Failure 404 {object} ErrorResponse "Workspace member not found"
+// @Failure 500 {object} ErrorResponse "Failed to remove workspace member"
+// @Router /workspaces/{workspace_name}/members/{userId} [delete]
+func (h *Handler) RemoveWorkspaceMember() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := context.GetRequestContext(w, r)
+		if !ok {
+			return
+		}
+		log := getWorkspaceMemberLogger().With(
+			"handler", "RemoveWorkspaceMember",
+			"userID", ctx.UserID,
+			"workspaceID", ctx.Workspace.ID,
+			"clientIP", r.RemoteAddr,
+		)
+
+		memberID, err := strconv.Atoi(chi.URLParam(r, "userId"))
+		if err != nil {
+			log.Debug("invalid user ID format",
+				"userIDParam", chi.URLParam(r, "userId"),
+				"error", err.Error(),
+			)
+			respondError(w, "Invalid user ID", http.StatusBadRequest)
+			return
+		}
+
+		member, err := h.DB.GetWorkspaceMember(ctx.Workspace.ID, memberID)
+		if err != nil {
+			log.Debug("workspace member not found",
+				"memberID", memberID,
+				"error", err.Error(),
+			)
+			respondError(w, "Workspace member not found", http.StatusNotFound)
+			return
+		}
+
+		if member.Role == models.WorkspaceRoleOwner {
+			respondError(w, "Cannot remove the workspace owner", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.DB.DeleteWorkspaceMember(ctx.Workspace.ID, memberID); err != nil {
+			log.Error("failed to delete workspace member",
+				"memberID", memberID,
+				"error", err.Error(),
+			)
+			respondError(w, "Failed to remove workspace member", http.StatusInternalServerError)
+			return
+		}
+
+		h.audit(r, "workspace member removed", &models.AuditEvent{
+			Event:       "workspace_member_removed",
+			ActorID:     &ctx.UserID,
+			TargetType:  models.AuditTargetUser,
+			TargetID:    strconv.Itoa(memberID),
+			WorkspaceID: &ctx.Workspace.ID,
+		})
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,237 @@
+//go:build integration
+
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"lemma/internal/handlers"
+	"lemma/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkspaceMemberHandlers_Integration(t *testing.T) {
+	h := setupTestHarness(t)
+	defer h.teardown(t)
+
+	owner := h.RegularTestUser
+	editor := h.createTestUser(t, "member-editor@test.com", "password123", models.RoleEditor)
+	viewer := h.createTestUser(t, "member-viewer@test.com", "password123", models.RoleEditor)
+	outsider := h.createTestUser(t, "outsider@test.com", "password123", models.RoleEditor)
+
+	workspace := &models.Workspace{
+		Name:           "Team Notes",
+		GitEnabled:     true,
+		GitURL:         "https://github.com/test/repo.git",
+		GitUser:        "owner",
+		GitToken:       "owner-token",
+		GitCommitEmail: "owner@test.com",
+	}
+	rr := h.makeRequest(t, http.MethodPost, "/api/v1/workspaces", workspace, owner)
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(workspace))
+
+	workspaceURL := "/api/v1/workspaces/" + url.PathEscape(workspace.Name)
+	membersURL := workspaceURL + "/members"
+
+	addMember := func(t *testing.T, email string, role models.WorkspaceRole) *httptest.ResponseRecorder {
+		return h.makeRequest(t, http.MethodPost, membersURL, handlers.AddWorkspaceMemberRequest{
+			Email: email,
+			Role:  role,
+		}, owner)
+	}
+
+	t.Run("add members", func(t *testing.T) {
+		rr := addMember(t, editor.userModel.Email, models.WorkspaceRoleEditor)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var member models.WorkspaceMember
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&member))
+		assert.Equal(t, editor.userModel.ID, member.UserID)
+		assert.Equal(t, models.WorkspaceRoleEditor, member.Role)
+
+		rr = addMember(t, viewer.userModel.Email, models.WorkspaceRoleViewer)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		rr = h.makeRequest(t, http.MethodGet, membersURL, nil, viewer)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var members []models.WorkspaceMember
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&members))
+		require.Len(t, members, 3)
+		assert.Equal(t, owner.userModel.ID, members[0].UserID)
+		assert.Equal(t, models.WorkspaceRoleOwner, members[0].Role)
+	})
+
+	t.Run("add member failures", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			email    string
+			role     models.WorkspaceRole
+			wantCode int
+		}{
+			{"owner role", outsider.userModel.Email, models.WorkspaceRoleOwner, http.StatusBadRequest},
+			{"invalid role", outsider.userModel.Email, "admin", http.StatusBadRequest},
+			{"unknown user", "nobody@test.com", models.WorkspaceRoleEditor, http.StatusNotFound},
+			{"already a member", editor.userModel.Email, models.WorkspaceRoleViewer, http.StatusConflict},
+			{"owner", owner.userModel.Email, models.WorkspaceRoleEditor, http.StatusConflict},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				rr := addMember(t, tt.email, tt.role)
+				assert.Equal(t, tt.wantCode, rr.Code)
+			})
+		}
+
+		// Workspaces are addressed by name, so names must not clash for the new member
+		clashing := &models.Workspace{UserID: outsider.userModel.ID, Name: workspace.Name}
+		require.NoError(t, h.DB.CreateWorkspace(clashing))
+		defer h.DB.DeleteWorkspace(clashing.ID)
+
+		rr := addMember(t, outsider.userModel.Email, models.WorkspaceRoleViewer)
+		assert.Equal(t, http.StatusConflict, rr.Code)
+	})
+
+	t.Run("shared workspace is listed without git token", func(t *testing.T) {
+		rr := h.makeRequest(t, http.MethodGet, "/api/v1/workspaces", nil, editor)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var workspaces []*models.Workspace
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&workspaces))
+		require.Len(t, workspaces, 2)
+		assert.Equal(t, models.WorkspaceRoleOwner, workspaces[0].Role)
+		assert.Equal(t, workspace.ID, workspaces[1].ID)
+		assert.Equal(t, models.WorkspaceRoleEditor, workspaces[1].Role)
+		assert.Empty(t, workspaces[1].GitToken)
+
+		rr = h.makeRequest(t, http.MethodGet, workspaceURL, nil, viewer)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var fetched models.Workspace
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&fetched))
+		assert.Equal(t, owner.userModel.ID, fetched.UserID)
+		assert.Empty(t, fetched.GitToken)
+
+		rr = h.makeRequest(t, http.MethodGet, workspaceURL, nil, owner)
+		require.Equal(t, http.StatusOK, rr.Code)
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&fetched))
+		assert.Equal(t, "owner-token", fetched.GitToken)
+
+		rr = h.makeRequest(t, http.MethodGet, workspaceURL, nil, outsider)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("names must not clash with shared workspaces", func(t *testing.T) {
+		rr := h.makeRequest(t, http.MethodPost, "/api/v1/workspaces", &models.Workspace{Name: workspace.Name}, editor)
+		assert.Equal(t, http.StatusConflict, rr.Code)
+
+		// Renaming checks the workspaces of every member, not only the owner's
+		editorOnly := &models.Workspace{UserID: editor.userModel.ID, Name: "Editor Only"}
+		require.NoError(t, h.DB.CreateWorkspace(editorOnly))
+		defer h.DB.DeleteWorkspace(editorOnly.ID)
+
+		renamed := *workspace
+		renamed.Name = editorOnly.Name
+		rr = h.makeRequest(t, http.MethodPut, workspaceURL, &renamed, owner)
+		assert.Equal(t, http.StatusConflict, rr.Code)
+
+		rr = h.makeRequest(t, http.MethodGet, workspaceURL, nil, editor)
+		require.Equal(t, http.StatusOK, rr.Code)
+		var fetched models.Workspace
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&fetched))
+		assert.Equal(t, workspace.Name, fetched.Name)
+	})
+
+	t.Run("member permissions", func(t *testing.T) {
+		// Files of editors are stored in the owner's workspace directory
+		rr := h.makeRequestRaw(t, http.MethodPost, workspaceURL+"/files/shared.md", strings.NewReader("# Shared"), editor)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		content, err := h.Storage.GetFileContent(owner.userModel.ID, workspace.ID, "shared.md")
+		require.NoError(t, err)
+		assert.Equal(t, "# Shared", string(content))
+
+		rr = h.makeRequest(t, http.MethodGet, workspaceURL+"/files/shared.md", nil, viewer)
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "# Shared", rr.Body.String())
+
+		tests := []struct {
+			name     string
+			method   string
+			path     string
+			body     interface{}
+			user     *testUser
+			wantCode int
+		}{
+			{"viewer saves file", http.MethodPost, workspaceURL + "/files/viewer.md", "# Viewer", viewer, http.StatusForbidden},
+			{"viewer commits", http.MethodPost, workspaceURL + "/git/commit", map[string]string{"message": "Update"}, viewer, http.StatusForbidden},
+			{"editor commits", http.MethodPost, workspaceURL + "/git/commit", map[string]string{"message": "Update"}, editor, http.StatusOK},
+			{"editor updates settings", http.MethodPut, workspaceURL, workspace, editor, http.StatusForbidden},
+			{"editor deletes workspace", http.MethodDelete, workspaceURL, nil, editor, http.StatusForbidden},
+			{"editor adds member", http.MethodPost, membersURL, handlers.AddWorkspaceMemberRequest{Email: outsider.userModel.Email, Role: models.WorkspaceRoleEditor}, editor, http.StatusForbidden},
+			{"outsider reads file", http.MethodGet, workspaceURL + "/files/shared.md", nil, outsider, http.StatusNotFound},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				rr := h.makeRequest(t, tt.method, tt.path, tt.body, tt.user)
+				assert.Equal(t, tt.wantCode, rr.Code)
+			})
+		}
+	})
+
+	t.Run("update member role", func(t *testing.T) {
+		path := fmt.Sprintf("%s/%d", membersURL, viewer.userModel.ID)
+		rr := h.makeRequest(t, http.MethodPut, path, handlers.UpdateWorkspaceMemberRequest{Role: models.WorkspaceRoleEditor}, owner)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		rr = h.makeRequestRaw(t, http.MethodPost, workspaceURL+"/files/promoted.md", strings.NewReader("# Promoted"), viewer)
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		rr = h.makeRequest(t, http.MethodPut, path, handlers.UpdateWorkspaceMemberRequest{Role: models.WorkspaceRoleViewer}, owner)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		ownerPath := fmt.Sprintf("%s/%d", membersURL, owner.userModel.ID)
+		rr = h.makeRequest(t, http.MethodPut, ownerPath, handlers.UpdateWorkspaceMemberRequest{Role: models.WorkspaceRoleViewer}, owner)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+		rr = h.makeRequest(t, http.MethodPut, fmt.Sprintf("%s/%d", membersURL, outsider.userModel.ID), handlers.UpdateWorkspaceMemberRequest{Role: models.WorkspaceRoleViewer}, owner)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+
+	t.Run("remove member", func(t *testing.T) {
+		ownerPath := fmt.Sprintf("%s/%d", membersURL, owner.userModel.ID)
+		rr := h.makeRequest(t, http.MethodDelete, ownerPath, nil, owner)
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+		// The removed member's last workspace falls back to their own workspace
+		rr = h.makeRequest(t, http.MethodPut, "/api/v1/workspaces/last", map[string]string{"workspaceName": workspace.Name}, viewer)
+		require.Equal(t, http.StatusNoContent, rr.Code)
+
+		path := fmt.Sprintf("%s/%d", membersURL, viewer.userModel.ID)
+		rr = h.makeRequest(t, http.MethodDelete, path, nil, owner)
+		require.Equal(t, http.StatusNoContent, rr.Code)
+
+		rr = h.makeRequest(t, http.MethodGet, workspaceURL, nil, viewer)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+
+		rr = h.makeRequest(t, http.MethodGet, "/api/v1/workspaces/last", nil, viewer)
+		require.Equal(t, http.StatusOK, rr.Code)
+
+		var last handlers.LastWorkspaceNameResponse
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&last))
+		assert.Equal(t, "Main", last.LastWorkspaceName)
+
+		rr = h.makeRequest(t, http.MethodDelete, path, nil, owner)
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
@@ -0,0 +1,81 @@
+// Package mailer provides a Mailer interface for sending emails via SMTP, or to a file or log sink when no SMTP server is configured.
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"strings"
+	"time"
+
+	"lemma/internal/logging"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer is an interface for sending emails
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// Config holds the configuration for creating a Mailer
+type Config struct {
+	SMTPHost     string // Enables the SMTP mailer if set
+	SMTPPort     int
+	SMTPUsername string // Authenticates with PLAIN auth if set
+	SMTPPassword string
+	From         string // Sender address of all emails
+	Dir          string // Enables the file mailer if set and no SMTP host is configured
+}
+
+var logger logging.Logger
+
+func getLogger() logging.Logger {
+	if logger == nil {
+		logger = logging.WithGroup("mailer")
+	}
+	return logger
+}
+
+// New creates a Mailer for the given configuration. SMTP is preferred, followed by the
+// file sink. Without either, emails are only written to the log.
+func New(cfg Config) (Mailer, error) {
+	switch {
+	case cfg.SMTPHost != "":
+		if cfg.From == "" {
+			return nil, fmt.Errorf("sender address is required for SMTP")
+		}
+		return NewSMTPMailer(cfg), nil
+	case cfg.Dir != "":
+		return NewFileMailer(cfg.Dir, cfg.From)
+	default:
+		getLogger().Warn("no SMTP server configured, emails will only be logged")
+		return NewLogMailer(), nil
+	}
+}
+
+// formatMessage renders msg as an RFC 5322 message with the given sender
+func formatMessage(from string, msg Message, date time.Time) ([]byte, error) {
+	for _, value := range []string{from, msg.To, msg.Subject} {
+		if strings.ContainsAny(value, "\r\n") {
+			return nil, fmt.Errorf("email headers must not contain line breaks")
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("\r\n")
+	buf.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+
+	return buf.Bytes(), nil
+}
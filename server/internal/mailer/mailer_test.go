@@ -0,0 +1,116 @@
+package mailer_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"lemma/internal/mailer"
+	_ "lemma/internal/testenv"
+)
+
+func TestNew(t *testing.T) {
+	testCases := []struct {
+		name        string
+		cfg         mailer.Config
+		wantErr     bool
+		errContains string
+	}{
+		{
+			name: "smtp mailer",
+			cfg:  mailer.Config{SMTPHost: "smtp.example.com", From: "lemma@example.com"},
+		},
+		{
+			name:        "smtp mailer without sender",
+			cfg:         mailer.Config{SMTPHost: "smtp.example.com"},
+			wantErr:     true,
+			errContains: "sender address is required",
+		},
+		{
+			name: "file mailer",
+			cfg:  mailer.Config{Dir: t.TempDir()},
+		},
+		{
+			name: "log mailer",
+			cfg:  mailer.Config{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m, err := mailer.New(tc.cfg)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				if !strings.Contains(err.Error(), tc.errContains) {
+					t.Errorf("error = %v, want error containing %v", err, tc.errContains)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if m == nil {
+				t.Error("expected mailer, got nil")
+			}
+		})
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+	m, err := mailer.NewFileMailer(dir, "lemma@example.com")
+	if err != nil {
+		t.Fatalf("failed to create file mailer: %v", err)
+	}
+
+	t.Run("writes one file per email", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			err := m.Send(mailer.Message{
+				To:      "user@example.com",
+				Subject: "Reset your password",
+				Body:    "Open this link:\nhttps://example.com/reset",
+			})
+			if err != nil {
+				t.Fatalf("failed to send email: %v", err)
+			}
+		}
+
+		files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+		if err != nil {
+			t.Fatalf("failed to list emails: %v", err)
+		}
+		if len(files) != 2 {
+			t.Fatalf("got %d email files, want 2", len(files))
+		}
+
+		data, err := os.ReadFile(files[0])
+		if err != nil {
+			t.Fatalf("failed to read email: %v", err)
+		}
+		content := string(data)
+		for _, want := range []string{
+			"From: lemma@example.com\r\n",
+			"To: user@example.com\r\n",
+			"Subject: Reset your password\r\n",
+			"\r\n\r\nOpen this link:\r\nhttps://example.com/reset",
+		} {
+			if !strings.Contains(content, want) {
+				t.Errorf("email does not contain %q:\n%s", want, content)
+			}
+		}
+	})
+
+	t.Run("rejects header injection", func(t *testing.T) {
+		err := m.Send(mailer.Message{
+			To:      "user@example.com\r\nBcc: other@example.com",
+			Subject: "Hello",
+		})
+		if err == nil {
+			t.Error("expected error for recipient with line break, got nil")
+		}
+	})
+}
@@ -0,0 +1,64 @@
+package mailer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+type fileMailer struct {
+	dir   string
+	from  string
+	count atomic.Uint64
+}
+
+// NewFileMailer creates a Mailer that writes every email as an .eml file to dir instead of sending it
+func NewFileMailer(dir, from string) (Mailer, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create mail directory: %w", err)
+	}
+
+	if from == "" {
+		from = "lemma@localhost"
+	}
+
+	return &fileMailer{
+		dir:  dir,
+		from: from,
+	}, nil
+}
+
+// Send writes msg to a new file in the mail directory
+func (m *fileMailer) Send(msg Message) error {
+	now := time.Now()
+	data, err := formatMessage(m.from, msg, now)
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s-%d.eml", now.UTC().Format("20060102T150405.000000000"), m.count.Add(1))
+	if err := os.WriteFile(filepath.Join(m.dir, name), data, 0600); err != nil {
+		return fmt.Errorf("failed to write email: %w", err)
+	}
+
+	getLogger().Debug("email written to file", "to", msg.To, "file", name)
+	return nil
+}
+
+type logMailer struct{}
+
+// NewLogMailer creates a Mailer that writes emails to the log instead of sending them
+func NewLogMailer() Mailer {
+	return &logMailer{}
+}
+
+// Send logs msg including its body
+func (m *logMailer) Send(msg Message) error {
+	getLogger().Info("email not sent, no SMTP server configured",
+		"to", msg.To,
+		"subject", msg.Subject,
+		"body", msg.Body)
+	return nil
+}
@@ -0,0 +1,51 @@
+package mailer
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+type smtpMailer struct {
+	addr string
+	host string
+	auth smtp.Auth
+	from string
+}
+
+// NewSMTPMailer creates a Mailer that delivers emails through an SMTP server
+func NewSMTPMailer(cfg Config) Mailer {
+	port := cfg.SMTPPort
+	if port == 0 {
+		port = 587
+	}
+
+	var auth smtp.Auth
+	if cfg.SMTPUsername != "" {
+		auth = smtp.PlainAuth("", cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPHost)
+	}
+
+	return &smtpMailer{
+		addr: net.JoinHostPort(cfg.SMTPHost, strconv.Itoa(port)),
+		host: cfg.SMTPHost,
+		auth: auth,
+		from: cfg.From,
+	}
+}
+
+// Send delivers msg to the SMTP server, using STARTTLS if the server supports it
+func (m *smtpMailer) Send(msg Message) error {
+	data, err := formatMessage(m.from, msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if err := smtp.SendMail(m.addr, m.auth, m.from, []string{msg.To}, data); err != nil {
+		return fmt.Errorf("failed to send email via %s: %w", m.host, err)
+	}
+
+	getLogger().Debug("email sent", "to", msg.To, "subject", msg.Subject)
+	return nil
+}
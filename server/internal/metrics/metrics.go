@@ -0,0 +1,147 @@
+// Package metrics collects Prometheus metrics of HTTP requests, storage operations and the state of the application.
+package metrics
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"lemma/internal/logging"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "lemma"
+
+var logger logging.Logger
+
+func getLogger() logging.Logger {
+	if logger == nil {
+		logger = logging.WithGroup("metrics")
+	}
+	return logger
+}
+
+// Metrics holds the collectors of the application and the registry they are exported from
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests        *prometheus.CounterVec
+	httpRequestDuration *prometheus.HistogramVec
+	fileSaveSize        prometheus.Histogram
+	gitDuration         *prometheus.HistogramVec
+	gitFailures         *prometheus.CounterVec
+}
+
+// New creates the metrics with a registry that also exports the Go runtime and process metrics
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "Number of HTTP requests by method, route pattern and status code.",
+		}, []string{"method", "route", "status"}),
+		httpRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Duration of HTTP requests by method and route pattern.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		fileSaveSize: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "file_save_bytes",
+			Help:      "Size of saved files.",
+			Buckets:   prometheus.ExponentialBuckets(256, 4, 8),
+		}),
+		gitDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "git_operation_duration_seconds",
+			Help:      "Duration of Git operations by operation type.",
+			Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+		}, []string{"operation"}),
+		gitFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "git_operation_failures_total",
+			Help:      "Number of failed Git operations by operation type.",
+		}, []string{"operation"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpRequestDuration,
+		m.fileSaveSize,
+		m.gitDuration,
+		m.gitFailures,
+	)
+	return m
+}
+
+// Register adds a collector, such as the state collector, to the exported metrics
+func (m *Metrics) Register(collector prometheus.Collector) error {
+	return m.registry.Register(collector)
+}
+
+// Middleware counts the requests and measures their duration by the route pattern they matched,
+// so requests for different files of the same route are counted together
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		m.httpRequests.WithLabelValues(r.Method, route, strconv.Itoa(status)).Inc()
+		m.httpRequestDuration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+	})
+}
+
+// ObserveFileSave records the size of a saved file
+func (m *Metrics) ObserveFileSave(size int) {
+	m.fileSaveSize.Observe(float64(size))
+}
+
+// ObserveGitOperation records the duration of a Git operation and counts it as failed if err is not nil
+func (m *Metrics) ObserveGitOperation(operation string, duration time.Duration, err error) {
+	m.gitDuration.WithLabelValues(operation).Observe(duration.Seconds())
+	if err != nil {
+		m.gitFailures.WithLabelValues(operation).Inc()
+	}
+}
+
+// Handler returns the handler that exports the metrics. If token is set, requests must
+// present it as a bearer token in the Authorization header.
+func (m *Metrics) Handler(token string) http.Handler {
+	handler := promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+	if token == "" {
+		return handler
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		presented, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+			getLogger().Debug("rejected metrics request",
+				"clientIP", r.RemoteAddr)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,182 @@
+package metrics_test
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"lemma/internal/metrics"
+	"lemma/internal/storage"
+	_ "lemma/internal/testenv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type mockDatabase struct {
+	sessions int
+	err      error
+}
+
+func (m *mockDatabase) Stats() sql.DBStats {
+	return sql.DBStats{MaxOpenConnections: 10, OpenConnections: 3, InUse: 1, Idle: 2}
+}
+
+func (m *mockDatabase) CountActiveSessions() (int, error) {
+	return m.sessions, m.err
+}
+
+type mockStorage struct {
+	stats *storage.FileCountStats
+	err   error
+}
+
+func (m *mockStorage) GetTotalUsage() (*storage.FileCountStats, error) {
+	return m.stats, m.err
+}
+
+// scrape returns the exported metrics in the text format
+func scrape(t *testing.T, m *metrics.Metrics, token string) string {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rr := httptest.NewRecorder()
+	m.Handler(token).ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("scrape status = %d, want %d", rr.Code, http.StatusOK)
+	}
+	body, err := io.ReadAll(rr.Body)
+	if err != nil {
+		t.Fatalf("failed to read metrics: %v", err)
+	}
+	return string(body)
+}
+
+func assertContains(t *testing.T, output string, lines ...string) {
+	t.Helper()
+	for _, line := range lines {
+		if !strings.Contains(output, line) {
+			t.Errorf("metrics do not contain %q", line)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	m := metrics.New()
+
+	r := chi.NewRouter()
+	r.Use(m.Middleware)
+	r.Get("/workspaces/{workspaceName}/files/*", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	r.Post("/save", func(_ http.ResponseWriter, _ *http.Request) {})
+
+	for _, path := range []string{"/workspaces/a/files/one.md", "/workspaces/b/files/two.md"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/save", nil))
+
+	output := scrape(t, m, "")
+	assertContains(t, output,
+		`lemma_http_requests_total{method="GET",route="/workspaces/{workspaceName}/files/*",status="404"} 2`,
+		`lemma_http_requests_total{method="POST",route="/save",status="200"} 1`,
+		`lemma_http_request_duration_seconds_count{method="GET",route="/workspaces/{workspaceName}/files/*"} 2`,
+	)
+}
+
+func TestStorageObserver(t *testing.T) {
+	m := metrics.New()
+
+	m.ObserveFileSave(100)
+	m.ObserveFileSave(5000)
+	m.ObserveGitOperation(storage.GitOperationPull, time.Second, nil)
+	m.ObserveGitOperation(storage.GitOperationPush, 2*time.Second, errors.New("rejected"))
+
+	output := scrape(t, m, "")
+	assertContains(t, output,
+		"lemma_file_save_bytes_sum 5100",
+		"lemma_file_save_bytes_count 2",
+		`lemma_git_operation_duration_seconds_count{operation="pull"} 1`,
+		`lemma_git_operation_duration_seconds_sum{operation="push"} 2`,
+		`lemma_git_operation_failures_total{operation="push"} 1`,
+	)
+	if strings.Contains(output, `lemma_git_operation_failures_total{operation="pull"}`) {
+		t.Error("successful pull counted as failure")
+	}
+}
+
+func TestStateCollector(t *testing.T) {
+	t.Run("exports sessions, connection pool and storage usage", func(t *testing.T) {
+		m := metrics.New()
+		err := m.Register(metrics.NewStateCollector(
+			&mockDatabase{sessions: 4},
+			&mockStorage{stats: &storage.FileCountStats{TotalFiles: 7, TotalSize: 2048}},
+		))
+		if err != nil {
+			t.Fatalf("Register() error = %v", err)
+		}
+
+		assertContains(t, scrape(t, m, ""),
+			"lemma_active_sessions 4",
+			"lemma_db_open_connections 3",
+			"lemma_db_in_use_connections 1",
+			"lemma_db_max_open_connections 10",
+			"lemma_storage_bytes 2048",
+			"lemma_storage_files 7",
+			"lemma_state_scrape_error 0",
+		)
+	})
+
+	t.Run("reports failures and keeps the other metrics", func(t *testing.T) {
+		m := metrics.New()
+		err := m.Register(metrics.NewStateCollector(
+			&mockDatabase{err: errors.New("database closed")},
+			&mockStorage{stats: &storage.FileCountStats{TotalFiles: 1, TotalSize: 1}},
+		))
+		if err != nil {
+			t.Fatalf("Register() error = %v", err)
+		}
+
+		output := scrape(t, m, "")
+		assertContains(t, output, "lemma_storage_files 1", "lemma_state_scrape_error 1")
+		if strings.Contains(output, "lemma_active_sessions ") {
+			t.Error("active sessions exported although counting failed")
+		}
+	})
+}
+
+func TestHandlerToken(t *testing.T) {
+	m := metrics.New()
+	handler := m.Handler("secret")
+
+	testCases := []struct {
+		name          string
+		authorization string
+		wantStatus    int
+	}{
+		{"missing token", "", http.StatusUnauthorized},
+		{"wrong token", "Bearer wrong", http.StatusUnauthorized},
+		{"token without scheme", "secret", http.StatusUnauthorized},
+		{"valid token", "Bearer secret", http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			if rr.Code != tc.wantStatus {
+				t.Errorf("status = %d, want %d", rr.Code, tc.wantStatus)
+			}
+		})
+	}
+}
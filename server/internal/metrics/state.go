@@ -0,0 +1,109 @@
+package metrics
+
+import (
+	"database/sql"
+
+	"lemma/internal/storage"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Database provides the state of the database that is exported on every scrape
+type Database interface {
+	Stats() sql.DBStats
+	CountActiveSessions() (int, error)
+}
+
+// Storage provides the storage usage that is exported on every scrape. The usage is tracked
+// as files change, so reading it doesn't walk the workspaces.
+type Storage interface {
+	GetTotalUsage() (*storage.FileCountStats, error)
+}
+
+// stateCollector reads the sessions, connection pool and storage usage when the metrics are scraped
+type stateCollector struct {
+	database Database
+	storage  Storage
+
+	activeSessions   *prometheus.Desc
+	openConnections  *prometheus.Desc
+	inUseConnections *prometheus.Desc
+	idleConnections  *prometheus.Desc
+	maxOpenConns     *prometheus.Desc
+	waitCount        *prometheus.Desc
+	waitDuration     *prometheus.Desc
+	storageBytes     *prometheus.Desc
+	storageFiles     *prometheus.Desc
+	scrapeError      *prometheus.Desc
+}
+
+// NewStateCollector creates a collector for the active sessions, the database connection pool
+// and the storage usage
+func NewStateCollector(database Database, storage Storage) prometheus.Collector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "", name), help, nil, nil)
+	}
+
+	return &stateCollector{
+		database:         database,
+		storage:          storage,
+		activeSessions:   desc("active_sessions", "Number of sessions that have not expired."),
+		openConnections:  desc("db_open_connections", "Number of established database connections, in use or idle."),
+		inUseConnections: desc("db_in_use_connections", "Number of database connections in use."),
+		idleConnections:  desc("db_idle_connections", "Number of idle database connections."),
+		maxOpenConns:     desc("db_max_open_connections", "Maximum number of open database connections, 0 if unlimited."),
+		waitCount:        desc("db_wait_count_total", "Number of times a database connection was waited for."),
+		waitDuration:     desc("db_wait_duration_seconds_total", "Time spent waiting for database connections."),
+		storageBytes:     desc("storage_bytes", "Size of all files in all workspaces, including Git repositories, trash and revisions."),
+		storageFiles:     desc("storage_files", "Number of all files in all workspaces, including Git repositories, trash and revisions."),
+		scrapeError:      desc("state_scrape_error", "Whether reading the sessions or storage usage failed during the scrape."),
+	}
+}
+
+// Describe sends the descriptors of the state metrics
+func (c *stateCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.activeSessions
+	ch <- c.openConnections
+	ch <- c.inUseConnections
+	ch <- c.idleConnections
+	ch <- c.maxOpenConns
+	ch <- c.waitCount
+	ch <- c.waitDuration
+	ch <- c.storageBytes
+	ch <- c.storageFiles
+	ch <- c.scrapeError
+}
+
+// Collect reads the current state. Metrics that can't be read are left out and reported
+// as a scrape error, so the other metrics are still exported.
+func (c *stateCollector) Collect(ch chan<- prometheus.Metric) {
+	log := getLogger()
+	failed := 0.0
+
+	stats := c.database.Stats()
+	ch <- prometheus.MustNewConstMetric(c.openConnections, prometheus.GaugeValue, float64(stats.OpenConnections))
+	ch <- prometheus.MustNewConstMetric(c.inUseConnections, prometheus.GaugeValue, float64(stats.InUse))
+	ch <- prometheus.MustNewConstMetric(c.idleConnections, prometheus.GaugeValue, float64(stats.Idle))
+	ch <- prometheus.MustNewConstMetric(c.maxOpenConns, prometheus.GaugeValue, float64(stats.MaxOpenConnections))
+	ch <- prometheus.MustNewConstMetric(c.waitCount, prometheus.CounterValue, float64(stats.WaitCount))
+	ch <- prometheus.MustNewConstMetric(c.waitDuration, prometheus.CounterValue, stats.WaitDuration.Seconds())
+
+	if sessions, err := c.database.CountActiveSessions(); err != nil {
+		log.Warn("failed to count active sessions",
+			"error", err.Error())
+		failed = 1
+	} else {
+		ch <- prometheus.MustNewConstMetric(c.activeSessions, prometheus.GaugeValue, float64(sessions))
+	}
+
+	if usage, err := c.storage.GetTotalUsage(); err != nil {
+		log.Warn("failed to get storage usage",
+			"error", err.Error())
+		failed = 1
+	} else {
+		ch <- prometheus.MustNewConstMetric(c.storageBytes, prometheus.GaugeValue, float64(usage.TotalSize))
+		ch <- prometheus.MustNewConstMetric(c.storageFiles, prometheus.GaugeValue, float64(usage.TotalFiles))
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.scrapeError, prometheus.GaugeValue, failed)
+}
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// AuditTargetType is the kind of object an audit event applies to
+type AuditTargetType string
+
+const (
+	AuditTargetUser       AuditTargetType = "user"
+	AuditTargetWorkspace  AuditTargetType = "workspace"
+	AuditTargetSession    AuditTargetType = "session"
+	AuditTargetFile       AuditTargetType = "file"
+	AuditTargetInvitation AuditTargetType = "invitation"
+	AuditTargetShareLink  AuditTargetType = "share_link"
+	AuditTargetSystem     AuditTargetType = "system"
+)
+
+// AuditEvent is a security or admin relevant action recorded in the audit log
+type AuditEvent struct {
+	ID          int             `json:"id"`
+	Event       string          `json:"event"`
+	ActorID     *int            `json:"actorId,omitempty"` // Not set for unauthenticated requests
+	TargetType  AuditTargetType `json:"targetType,omitempty"`
+	TargetID    string          `json:"targetId,omitempty"`
+	WorkspaceID *int            `json:"workspaceId,omitempty"`
+	IPAddress   string          `json:"ipAddress"`
+	RequestID   string          `json:"requestId"`
+	Details     map[string]any  `json:"details,omitempty"`
+	CreatedAt   time.Time       `json:"createdAt"`
+}
+
+// AuditEventFilter selects audit events, empty fields match all events
+type AuditEventFilter struct {
+	Event       string
+	ActorID     *int
+	TargetType  AuditTargetType
+	TargetID    string
+	WorkspaceID *int
+	Since       *time.Time
+	Until       *time.Time
+	Limit       int
+	Offset      int
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// Invitation allows signing up with a role chosen by an admin.
+// Only a hash of the invitation token is stored.
+type Invitation struct {
+	ID            int       `json:"id"`
+	TokenHash     string    `json:"-"`
+	Role          UserRole  `json:"role"`
+	Email         string    `json:"email,omitempty"`         // Restricts the invitation to this email if set
+	WorkspaceName string    `json:"workspaceName,omitempty"` // Name of the user's initial workspace, Main if empty
+	MaxUses       int       `json:"maxUses"`
+	UseCount      int       `json:"useCount"`
+	ExpiresAt     time.Time `json:"expiresAt"`
+	CreatedBy     int       `json:"createdBy"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// Usable reports whether the invitation can still be redeemed at the given time
+func (i *Invitation) Usable(now time.Time) bool {
+	return i.UseCount < i.MaxUses && now.Before(i.ExpiresAt)
+}
+
+// RegistrationSettings controls whether users can sign up without an invitation
+type RegistrationSettings struct {
+	OpenRegistration bool     `json:"openRegistration"`
+	DefaultRole      UserRole `json:"defaultRole"` // Role of users signing up without an invitation
+	// RequireEmailVerification keeps users signing up without an invitation from logging in
+	// until they confirm their email address
+	RequireEmailVerification bool `json:"requireEmailVerification"`
+}
+
+// ValidDefaultRole reports whether the default role may be given to anyone signing up.
+// Admins can only be created by other admins.
+func (s *RegistrationSettings) ValidDefaultRole() bool {
+	return s.DefaultRole == RoleEditor || s.DefaultRole == RoleViewer
+}
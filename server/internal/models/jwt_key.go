@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// JWTKey represents a key of the keyring used to sign and verify JWT tokens
+type JWTKey struct {
+	ID         string     `json:"id"`                  // Key identifier, used as the kid header of tokens
+	Algorithm  string     `json:"algorithm"`           // Signing algorithm (HS256, RS256 or EdDSA)
+	PrivateKey string     `json:"-"`                   // HMAC secret or PEM encoded private key
+	PublicKey  string     `json:"publicKey,omitempty"` // PEM encoded public key for asymmetric algorithms
+	Active     bool       `json:"active"`              // Whether new tokens are signed with this key
+	CreatedAt  time.Time  `json:"createdAt"`           // When the key was created
+	RetiredAt  *time.Time `json:"retiredAt,omitempty"` // When the key stopped signing new tokens
+}
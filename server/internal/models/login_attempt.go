@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// LoginAttemptScope identifies what failed login attempts are counted against
+type LoginAttemptScope string
+
+const (
+	LoginAttemptScopeEmail LoginAttemptScope = "email" // Attempts against a single account
+	LoginAttemptScopeIP    LoginAttemptScope = "ip"    // Attempts from a single client IP
+)
+
+// LoginAttempt tracks consecutive failed logins for an email address or client IP
+type LoginAttempt struct {
+	Scope        LoginAttemptScope `json:"scope"`        // Whether Key is an email address or an IP
+	Key          string            `json:"key"`          // Normalized email address or client IP
+	FailedCount  int               `json:"failedCount"`  // Number of consecutive failed attempts
+	LastFailedAt time.Time         `json:"lastFailedAt"` // When the last failed attempt happened
+	LockedUntil  time.Time         `json:"lockedUntil"`  // Zero unless logins are locked out
+}
@@ -0,0 +1,24 @@
+package models
+
+// StorageQuota limits the storage used by the workspaces of a user, or by a single workspace.
+// Zero means unlimited.
+type StorageQuota struct {
+	MaxBytes int64 `json:"maxBytes"`
+	MaxFiles int   `json:"maxFiles"`
+}
+
+// Valid reports whether the limits of the quota are not negative
+func (q *StorageQuota) Valid() bool {
+	return q.MaxBytes >= 0 && q.MaxFiles >= 0
+}
+
+// Unlimited reports whether the quota doesn't limit anything
+func (q *StorageQuota) Unlimited() bool {
+	return q.MaxBytes == 0 && q.MaxFiles == 0
+}
+
+// StorageUsage is the storage used by a workspace, including its Git repository, trash and revisions
+type StorageUsage struct {
+	Bytes int64 `json:"bytes"`
+	Files int   `json:"files"`
+}
@@ -5,9 +5,22 @@ import "time"
 
 // Session represents a user session in the database
 type Session struct {
-	ID           string    // Unique session identifier
-	UserID       int       // ID of the user this session belongs to
-	RefreshToken string    // The refresh token associated with this session
-	ExpiresAt    time.Time // When this session expires
-	CreatedAt    time.Time // When this session was created
+	ID           string    `json:"id"`         // Unique session identifier
+	UserID       int       `json:"userId"`     // ID of the user this session belongs to
+	RefreshToken string    `json:"-"`          // The refresh token associated with this session
+	UserAgent    string    `json:"userAgent"`  // User agent of the client that created the session
+	IPAddress    string    `json:"ipAddress"`  // IP address of the client that created the session
+	ExpiresAt    time.Time `json:"expiresAt"`  // When this session expires
+	CreatedAt    time.Time `json:"createdAt"`  // When this session was created
+	LastUsedAt   time.Time `json:"lastUsedAt"` // When this session was last used to authenticate a request
+}
+
+// RotatedRefreshToken records a refresh token that has been replaced during rotation.
+// All refresh tokens issued for a session form a token family identified by the session ID.
+type RotatedRefreshToken struct {
+	Token     string    // The refresh token that was rotated out
+	SessionID string    // ID of the session (token family) the token belonged to
+	UserID    int       // ID of the user the token was issued to
+	RotatedAt time.Time // When the token was replaced
+	ExpiresAt time.Time // When the token would have expired
 }
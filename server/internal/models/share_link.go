@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// ShareLink gives unauthenticated read-only access to a file or folder of a workspace.
+// Only hashes of the link token and the optional password are stored.
+type ShareLink struct {
+	ID           int        `json:"id"`
+	WorkspaceID  int        `json:"workspaceId"`
+	TokenHash    string     `json:"-"`
+	Path         string     `json:"path"`
+	IsFolder     bool       `json:"isFolder"`
+	PasswordHash string     `json:"-"`
+	HasPassword  bool       `json:"hasPassword"`
+	ExpiresAt    *time.Time `json:"expiresAt,omitempty"` // The link doesn't expire if not set
+	CreatedBy    int        `json:"createdBy"`
+	CreatedAt    time.Time  `json:"createdAt"`
+}
+
+// Expired reports whether the share link has expired at the given time
+func (s *ShareLink) Expired(now time.Time) bool {
+	return s.ExpiresAt != nil && !now.Before(*s.ExpiresAt)
+}
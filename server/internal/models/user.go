@@ -18,6 +18,24 @@ const (
 	RoleViewer UserRole = "viewer"
 )
 
+// Valid reports whether r is one of the known user roles
+func (r UserRole) Valid() bool {
+	switch r {
+	case RoleAdmin, RoleEditor, RoleViewer:
+		return true
+	}
+	return false
+}
+
+// AuthSource is where a user authenticates
+type AuthSource string
+
+// Authentication sources
+const (
+	AuthSourceLocal AuthSource = "local"
+	AuthSourceLDAP  AuthSource = "ldap"
+)
+
 // User represents a user in the system
 type User struct {
 	ID              int       `json:"id" validate:"required,min=1"`
@@ -27,6 +45,21 @@ type User struct {
 	Role            UserRole  `json:"role" validate:"required,oneof=admin editor viewer"`
 	CreatedAt       time.Time `json:"createdAt"`
 	LastWorkspaceID int       `json:"lastWorkspaceId"`
+	// MustChangePassword blocks everything but changing the password until the user does so
+	MustChangePassword bool `json:"mustChangePassword"`
+	// SuspendedAt is only set for suspended users, who can't log in
+	SuspendedAt *time.Time `json:"suspendedAt,omitempty"`
+	// EmailVerificationPending blocks logging in until the user confirms their email address
+	EmailVerificationPending bool `json:"emailVerificationPending"`
+	// AuthSource is where the user authenticates, local when empty
+	AuthSource AuthSource `json:"authSource"`
+	// DeletedAt is only set for users in the trash
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+}
+
+// Suspended reports whether the user is suspended
+func (u *User) Suspended() bool {
+	return u.SuspendedAt != nil
 }
 
 // Validate validates the user struct
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// UserTokenPurpose identifies what a single-use user token can be redeemed for
+type UserTokenPurpose string
+
+const (
+	UserTokenPurposePasswordReset UserTokenPurpose = "password_reset" // Resets a forgotten password
+	UserTokenPurposeEmailChange   UserTokenPurpose = "email_change"   // Confirms a new email address
+)
+
+// UserToken is a single-use, time-limited token sent to a user by email.
+// Only a hash of the token is stored so a leaked database can't be used to redeem it.
+type UserToken struct {
+	TokenHash string           `json:"-"`
+	UserID    int              `json:"userId"`
+	Purpose   UserTokenPurpose `json:"purpose"`
+	Email     string           `json:"email"` // New email address for email changes
+	ExpiresAt time.Time        `json:"expiresAt"`
+	CreatedAt time.Time        `json:"createdAt"`
+}
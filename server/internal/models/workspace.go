@@ -4,6 +4,9 @@ import (
 	"time"
 )
 
+// DefaultWorkspaceName is the name of the workspace created for new users
+const DefaultWorkspaceName = "Main"
+
 // Workspace represents a user's workspace in the system
 type Workspace struct {
 	ID                 int       `json:"id" validate:"required,min=1"`
@@ -11,6 +14,10 @@ type Workspace struct {
 	Name               string    `json:"name" validate:"required"`
 	CreatedAt          time.Time `json:"createdAt"`
 	LastOpenedFilePath string    `json:"lastOpenedFilePath"`
+	// Role of the requesting user, only set for workspaces looked up by member
+	Role WorkspaceRole `json:"role,omitempty"`
+	// DeletedAt is only set for workspaces in the trash
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
 
 	// Integrated settings
 	Theme                string `json:"theme" validate:"oneof=light dark"`
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// WorkspaceRole represents the role of a user in a workspace
+type WorkspaceRole string
+
+// Workspace roles
+const (
+	WorkspaceRoleOwner  WorkspaceRole = "owner"
+	WorkspaceRoleEditor WorkspaceRole = "editor"
+	WorkspaceRoleViewer WorkspaceRole = "viewer"
+)
+
+// Valid reports whether r is one of the known workspace roles
+func (r WorkspaceRole) Valid() bool {
+	switch r {
+	case WorkspaceRoleOwner, WorkspaceRoleEditor, WorkspaceRoleViewer:
+		return true
+	}
+	return false
+}
+
+// WorkspaceMember represents a user's access to a workspace. The owner of a
+// workspace is a member with the owner role.
+type WorkspaceMember struct {
+	WorkspaceID int           `json:"workspaceId"`
+	UserID      int           `json:"userId"`
+	Email       string        `json:"email"`
+	DisplayName string        `json:"displayName"`
+	Role        WorkspaceRole `json:"role"`
+	CreatedAt   time.Time     `json:"createdAt"`
+}
@@ -1,13 +1,20 @@
 // Package secrets provides an Encryptor interface for encrypting and decrypting strings using AES-256-GCM.
+//
+// Ciphertexts are prefixed with the format version and the ID of the key they were encrypted with,
+// e.g. "v1:0a1b2c3d:<base64>", so that data encrypted with a previous key can still be decrypted
+// after the key is rotated. Ciphertexts without prefix were written before keys had IDs.
 package secrets
 
 import (
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"io"
+	"strings"
 
 	"lemma/internal/logging"
 )
@@ -18,8 +25,13 @@ type Service interface {
 	Decrypt(ciphertext string) (string, error)
 }
 
+// ciphertextVersion prefixes ciphertexts with a key ID
+const ciphertextVersion = "v1"
+
 type encryptor struct {
-	gcm cipher.AEAD
+	currentKeyID string
+	keyIDs       []string // IDs of all keys, current key first
+	keys         map[string]cipher.AEAD
 }
 
 var logger logging.Logger
@@ -62,24 +74,56 @@ func decodeAndValidateKey(key string) ([]byte, error) {
 	return keyBytes, nil
 }
 
-// NewService creates a new Encryptor instance with the provided base64-encoded key
-func NewService(key string) (Service, error) {
+// KeyID returns the ID of the provided base64-encoded key, which prefixes the data encrypted with it
+func KeyID(key string) (string, error) {
 	keyBytes, err := decodeAndValidateKey(key)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
+	return keyID(keyBytes), nil
+}
 
-	block, err := aes.NewCipher(keyBytes)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create cipher: %w", err)
-	}
+// keyID derives a short, non-secret identifier from the key bytes
+func keyID(keyBytes []byte) string {
+	sum := sha256.Sum256(keyBytes)
+	return hex.EncodeToString(sum[:4])
+}
 
-	gcm, err := cipher.NewGCM(block)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create GCM: %w", err)
+// NewService creates a new Encryptor instance with the provided base64-encoded key.
+// Data is encrypted with key; the previous keys are only used to decrypt data that was
+// encrypted before the key was rotated.
+func NewService(key string, previousKeys ...string) (Service, error) {
+	e := &encryptor{keys: make(map[string]cipher.AEAD)}
+
+	for i, k := range append([]string{key}, previousKeys...) {
+		keyBytes, err := decodeAndValidateKey(k)
+		if err != nil {
+			if i > 0 {
+				return nil, fmt.Errorf("previous key %d: %w", i, err)
+			}
+			return nil, err
+		}
+
+		block, err := aes.NewCipher(keyBytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cipher: %w", err)
+		}
+
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create GCM: %w", err)
+		}
+
+		id := keyID(keyBytes)
+		if _, ok := e.keys[id]; ok {
+			continue
+		}
+		e.keys[id] = gcm
+		e.keyIDs = append(e.keyIDs, id)
 	}
+	e.currentKeyID = e.keyIDs[0]
 
-	return &encryptor{gcm: gcm}, nil
+	return e, nil
 }
 
 // Encrypt encrypts the plaintext using AES-256-GCM
@@ -91,15 +135,16 @@ func (e *encryptor) Encrypt(plaintext string) (string, error) {
 		return "", nil
 	}
 
-	nonce := make([]byte, e.gcm.NonceSize())
+	gcm := e.keys[e.currentKeyID]
+	nonce := make([]byte, gcm.NonceSize())
 	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
 		return "", err
 	}
 
-	ciphertext := e.gcm.Seal(nonce, nonce, []byte(plaintext), nil)
-	encoded := base64.StdEncoding.EncodeToString(ciphertext)
+	ciphertext := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	encoded := ciphertextVersion + ":" + e.currentKeyID + ":" + base64.StdEncoding.EncodeToString(ciphertext)
 
-	log.Debug("data encrypted", "inputLength", len(plaintext), "outputLength", len(encoded))
+	log.Debug("data encrypted", "keyID", e.currentKeyID, "inputLength", len(plaintext), "outputLength", len(encoded))
 	return encoded, nil
 }
 
@@ -112,22 +157,50 @@ func (e *encryptor) Decrypt(ciphertext string) (string, error) {
 		return "", nil
 	}
 
-	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	keyIDs := e.keyIDs
+	encoded := ciphertext
+	if version, rest, ok := strings.Cut(ciphertext, ":"); ok {
+		if version != ciphertextVersion {
+			return "", fmt.Errorf("unsupported ciphertext version %q", version)
+		}
+
+		id, data, ok := strings.Cut(rest, ":")
+		if !ok {
+			return "", fmt.Errorf("invalid ciphertext: missing key ID")
+		}
+		if _, ok := e.keys[id]; !ok {
+			return "", fmt.Errorf("unknown encryption key %q", id)
+		}
+		keyIDs = []string{id}
+		encoded = data
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encoded)
 	if err != nil {
 		return "", fmt.Errorf("invalid base64 encoding: %w", err)
 	}
 
-	nonceSize := e.gcm.NonceSize()
-	if len(data) < nonceSize {
-		return "", fmt.Errorf("invalid ciphertext: too short")
+	// Ciphertexts without key ID are tried with every key, authentication fails for the wrong ones
+	for _, id := range keyIDs {
+		plaintext, err := open(e.keys[id], data)
+		if err == nil {
+			log.Debug("data decrypted", "keyID", id, "inputLength", len(ciphertext), "outputLength", len(plaintext))
+			return string(plaintext), nil
+		}
+		if len(keyIDs) == 1 {
+			return "", err
+		}
 	}
 
-	nonce, ciphertextBytes := data[:nonceSize], data[nonceSize:]
-	plaintext, err := e.gcm.Open(nil, nonce, ciphertextBytes, nil)
-	if err != nil {
-		return "", err
+	return "", fmt.Errorf("failed to decrypt with any of the %d keys", len(keyIDs))
+}
+
+func open(gcm cipher.AEAD, data []byte) ([]byte, error) {
+	nonceSize := gcm.NonceSize()
+	if len(data) < nonceSize {
+		return nil, fmt.Errorf("invalid ciphertext: too short")
 	}
 
-	log.Debug("data decrypted", "inputLength", len(ciphertext), "outputLength", len(plaintext))
-	return string(plaintext), nil
+	nonce, ciphertextBytes := data[:nonceSize], data[nonceSize:]
+	return gcm.Open(nil, nonce, ciphertextBytes, nil)
 }
@@ -1,6 +1,8 @@
 package secrets_test
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
 	"encoding/base64"
 	"strings"
 	"testing"
@@ -256,3 +258,93 @@ func TestDecryptInvalidCiphertext(t *testing.T) {
 		})
 	}
 }
+
+func TestKeyRotation(t *testing.T) {
+	oldKey := base64.StdEncoding.EncodeToString(make([]byte, 32))
+	newKey := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32)))
+
+	oldService, err := secrets.NewService(oldKey)
+	if err != nil {
+		t.Fatalf("failed to create Encryptor instance: %v", err)
+	}
+	rotatedService, err := secrets.NewService(newKey, oldKey)
+	if err != nil {
+		t.Fatalf("failed to create Encryptor instance: %v", err)
+	}
+	newService, err := secrets.NewService(newKey)
+	if err != nil {
+		t.Fatalf("failed to create Encryptor instance: %v", err)
+	}
+
+	oldKeyID, _ := secrets.KeyID(oldKey)
+	newKeyID, _ := secrets.KeyID(newKey)
+
+	oldCiphertext, err := oldService.Encrypt("secret")
+	if err != nil {
+		t.Fatalf("unexpected encryption error: %v", err)
+	}
+
+	t.Run("ciphertext is prefixed with the key ID", func(t *testing.T) {
+		if !strings.HasPrefix(oldCiphertext, "v1:"+oldKeyID+":") {
+			t.Errorf("ciphertext %q has no prefix %q", oldCiphertext, "v1:"+oldKeyID+":")
+		}
+
+		ciphertext, err := rotatedService.Encrypt("secret")
+		if err != nil {
+			t.Fatalf("unexpected encryption error: %v", err)
+		}
+		if !strings.HasPrefix(ciphertext, "v1:"+newKeyID+":") {
+			t.Errorf("ciphertext %q isn't encrypted with the current key %s", ciphertext, newKeyID)
+		}
+	})
+
+	t.Run("previous keys decrypt", func(t *testing.T) {
+		decrypted, err := rotatedService.Decrypt(oldCiphertext)
+		if err != nil {
+			t.Fatalf("unexpected decryption error: %v", err)
+		}
+		if decrypted != "secret" {
+			t.Errorf("decrypted text = %q, want %q", decrypted, "secret")
+		}
+	})
+
+	t.Run("unknown key is rejected", func(t *testing.T) {
+		_, err := newService.Decrypt(oldCiphertext)
+		if err == nil || !strings.Contains(err.Error(), "unknown encryption key") {
+			t.Errorf("error = %v, want unknown encryption key error", err)
+		}
+	})
+
+	t.Run("ciphertext without key ID", func(t *testing.T) {
+		// Ciphertexts written before key IDs were added are the base64 of nonce and sealed data
+		block, err := aes.NewCipher(make([]byte, 32))
+		if err != nil {
+			t.Fatalf("failed to create cipher: %v", err)
+		}
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			t.Fatalf("failed to create GCM: %v", err)
+		}
+		nonce := make([]byte, gcm.NonceSize())
+		legacy := base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte("legacy"), nil))
+
+		decrypted, err := rotatedService.Decrypt(legacy)
+		if err != nil {
+			t.Fatalf("unexpected decryption error: %v", err)
+		}
+		if decrypted != "legacy" {
+			t.Errorf("decrypted text = %q, want %q", decrypted, "legacy")
+		}
+
+		if _, err := newService.Decrypt(legacy); err == nil {
+			t.Error("expected error decrypting with the wrong key, got nil")
+		}
+	})
+
+	t.Run("invalid previous key", func(t *testing.T) {
+		_, err := secrets.NewService(newKey, "invalid-key")
+		if err == nil || !strings.Contains(err.Error(), "previous key 1") {
+			t.Errorf("error = %v, want previous key error", err)
+		}
+	})
+}
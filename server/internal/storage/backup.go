@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"archive/tar"
+	"fmt"
+	"path"
+	"path/filepath"
+)
+
+// BackupManager provides functionalities to consistently archive the storage.
+type BackupManager interface {
+	WithWritesPaused(fn func() error) error
+	ArchiveFiles(tw *tar.Writer, prefix string, exclude ...string) (int, error)
+}
+
+// WithWritesPaused calls fn while no files are written. Writes that are in progress
+// are completed first, new writes wait until fn returns.
+func (s *Service) WithWritesPaused(fn func() error) error {
+	s.writes.Lock()
+	defer s.writes.Unlock()
+
+	getLogger().Debug("storage writes paused")
+	return fn()
+}
+
+// ArchiveFiles adds all files and directories of the storage root directory to the
+// tar archive, with their paths below prefix. Files at the exclude paths are skipped.
+// It returns the number of archived files. Writes should be paused while archiving
+// for a consistent archive.
+func (s *Service) ArchiveFiles(tw *tar.Writer, prefix string, exclude ...string) (int, error) {
+	excluded := make(map[string]bool, len(exclude))
+	for _, path := range exclude {
+		if absPath, err := filepath.Abs(path); err == nil {
+			excluded[absPath] = true
+		}
+	}
+
+	count, err := s.archiveDirectory(tw, s.RootDir, prefix, excluded)
+	if err != nil {
+		return count, fmt.Errorf("failed to archive files: %w", err)
+	}
+
+	getLogger().Debug("files archived", "count", count)
+	return count, nil
+}
+
+func (s *Service) archiveDirectory(tw *tar.Writer, dir, archiveDir string, excluded map[string]bool) (int, error) {
+	entries, err := s.fs.ReadDir(dir)
+	if err != nil {
+		if s.fs.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	count := 0
+	for _, entry := range entries {
+		fullPath := filepath.Join(dir, entry.Name())
+		archivePath := path.Join(archiveDir, entry.Name())
+
+		if absPath, err := filepath.Abs(fullPath); err == nil && excluded[absPath] {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return count, err
+		}
+
+		// Only regular files and directories are archived, links could point outside the root directory
+		if !info.IsDir() && !info.Mode().IsRegular() {
+			continue
+		}
+
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return count, err
+		}
+		header.Name = archivePath
+
+		if info.IsDir() {
+			header.Name += "/"
+			if err := tw.WriteHeader(header); err != nil {
+				return count, err
+			}
+
+			n, err := s.archiveDirectory(tw, fullPath, archivePath, excluded)
+			count += n
+			if err != nil {
+				return count, err
+			}
+			continue
+		}
+
+		content, err := s.fs.ReadFile(fullPath)
+		if err != nil {
+			return count, err
+		}
+		header.Size = int64(len(content))
+
+		if err := tw.WriteHeader(header); err != nil {
+			return count, err
+		}
+		if _, err := tw.Write(content); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	return count, nil
+}
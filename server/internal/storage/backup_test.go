@@ -0,0 +1,101 @@
+package storage_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"lemma/internal/storage"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "lemma/internal/testenv"
+)
+
+func TestArchiveFiles(t *testing.T) {
+	rootDir := t.TempDir()
+	s := storage.NewService(rootDir)
+
+	if err := s.SaveFile(1, 1, "notes/todo.md", []byte("# Todo")); err != nil {
+		t.Fatalf("failed to save file: %v", err)
+	}
+	excludedPath := filepath.Join(rootDir, "lemma.db")
+	if err := os.WriteFile(excludedPath, []byte("db"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	count, err := s.ArchiveFiles(tw, "data", excludedPath)
+	if err != nil {
+		t.Fatalf("ArchiveFiles() error = %v", err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("failed to close archive: %v", err)
+	}
+
+	if count != 1 {
+		t.Errorf("ArchiveFiles() count = %d, want 1", count)
+	}
+
+	contents := make(map[string]string)
+	tr := tar.NewReader(&buf)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("failed to read archive: %v", err)
+		}
+		content, _ := io.ReadAll(tr)
+		contents[header.Name] = string(content)
+	}
+
+	want := map[string]string{
+		"data/1/":                "",
+		"data/1/1/":              "",
+		"data/1/1/notes/":        "",
+		"data/1/1/notes/todo.md": "# Todo",
+	}
+	if len(contents) != len(want) {
+		t.Errorf("archive entries = %v, want %v", contents, want)
+	}
+	for name, content := range want {
+		if got, ok := contents[name]; !ok || got != content {
+			t.Errorf("archive entry %q = %q, want %q", name, got, content)
+		}
+	}
+}
+
+func TestWithWritesPaused(t *testing.T) {
+	s := storage.NewService(t.TempDir())
+
+	saved := make(chan error, 1)
+	err := s.WithWritesPaused(func() error {
+		go func() {
+			saved <- s.SaveFile(1, 1, "note.md", []byte("# Note"))
+		}()
+
+		select {
+		case <-saved:
+			t.Error("file was saved while writes were paused")
+		case <-time.After(50 * time.Millisecond):
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithWritesPaused() error = %v", err)
+	}
+
+	select {
+	case err := <-saved:
+		if err != nil {
+			t.Errorf("SaveFile() error = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("file was not saved after writes were resumed")
+	}
+}
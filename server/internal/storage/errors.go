@@ -6,6 +6,20 @@ import (
 	"fmt"
 )
 
+var (
+	// ErrTrashItemNotFound is returned for items that are not in the trash of a workspace
+	ErrTrashItemNotFound = errors.New("trash item not found")
+
+	// ErrRevisionNotFound is returned for revisions that are not in the history of a file
+	ErrRevisionNotFound = errors.New("revision not found")
+
+	// ErrQuotaExceeded is returned when a change would exceed the storage quota of the workspace owner
+	ErrQuotaExceeded = errors.New("storage quota exceeded")
+
+	// ErrFileExists is returned when restoring a file to a path that is in use
+	ErrFileExists = errors.New("file already exists")
+)
+
 // PathValidationError represents a path validation error (e.g., path traversal attempt)
 type PathValidationError struct {
 	Path    string
@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+)
+
+// ExportManager provides functionalities to export the files of workspaces.
+type ExportManager interface {
+	ExportWorkspace(zw *zip.Writer, userID, workspaceID int, prefix string) (int, error)
+}
+
+// ExportWorkspace adds the files of a workspace to the zip archive, with their paths below prefix.
+// Files are streamed into the archive one at a time. The .git and trash directories are not exported.
+// It returns the number of exported files.
+func (s *Service) ExportWorkspace(zw *zip.Writer, userID, workspaceID int, prefix string) (int, error) {
+	count, err := s.exportDirectory(zw, s.GetWorkspacePath(userID, workspaceID), prefix)
+	if err != nil {
+		return count, fmt.Errorf("failed to export workspace: %w", err)
+	}
+
+	getLogger().Debug("workspace exported",
+		"userID", userID,
+		"workspaceID", workspaceID,
+		"count", count)
+	return count, nil
+}
+
+func (s *Service) exportDirectory(zw *zip.Writer, dir, archiveDir string) (int, error) {
+	entries, err := s.fs.ReadDir(dir)
+	if err != nil {
+		if s.fs.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	count := 0
+	for _, entry := range entries {
+		fullPath := filepath.Join(dir, entry.Name())
+		if entry.IsDir() && (entry.Name() == ".git" || s.isReservedDir(fullPath)) {
+			continue
+		}
+
+		archivePath := path.Join(archiveDir, entry.Name())
+
+		info, err := entry.Info()
+		if err != nil {
+			return count, err
+		}
+
+		if info.IsDir() {
+			n, err := s.exportDirectory(zw, fullPath, archivePath)
+			count += n
+			if err != nil {
+				return count, err
+			}
+			continue
+		}
+
+		// Links could point outside the workspace
+		if !info.Mode().IsRegular() {
+			continue
+		}
+
+		if err := s.exportFile(zw, fullPath, archivePath, info); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+func (s *Service) exportFile(zw *zip.Writer, fullPath, archivePath string, info fs.FileInfo) error {
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Name = archivePath
+	header.Method = zip.Deflate
+
+	w, err := zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+
+	file, err := s.fs.Open(fullPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(w, file)
+	return err
+}
@@ -0,0 +1,67 @@
+package storage_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"lemma/internal/storage"
+
+	_ "lemma/internal/testenv"
+)
+
+func TestExportWorkspace(t *testing.T) {
+	rootDir := t.TempDir()
+	s := storage.NewService(rootDir)
+
+	if err := s.SaveFile(1, 1, "notes/todo.md", []byte("# Todo")); err != nil {
+		t.Fatalf("failed to save file: %v", err)
+	}
+	if err := s.SaveFile(1, 2, "other.md", []byte("# Other")); err != nil {
+		t.Fatalf("failed to save file: %v", err)
+	}
+	gitDir := filepath.Join(s.GetWorkspacePath(1, 1), ".git")
+	if err := os.MkdirAll(gitDir, 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(gitDir, "HEAD"), []byte("ref: refs/heads/main"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	count, err := s.ExportWorkspace(zw, 1, 1, "workspaces/Main/files")
+	if err != nil {
+		t.Fatalf("ExportWorkspace() error = %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("failed to close archive: %v", err)
+	}
+
+	if count != 1 {
+		t.Errorf("ExportWorkspace() count = %d, want 1", count)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("failed to read archive: %v", err)
+	}
+	if len(zr.File) != 1 {
+		t.Fatalf("archive entries = %d, want 1", len(zr.File))
+	}
+	if name := zr.File[0].Name; name != "workspaces/Main/files/notes/todo.md" {
+		t.Errorf("archive entry = %q, want %q", name, "workspaces/Main/files/notes/todo.md")
+	}
+	rc, err := zr.File[0].Open()
+	if err != nil {
+		t.Fatalf("failed to open archive entry: %v", err)
+	}
+	defer rc.Close()
+	content, _ := io.ReadAll(rc)
+	if string(content) != "# Todo" {
+		t.Errorf("archive entry content = %q, want %q", content, "# Todo")
+	}
+}
@@ -11,10 +11,12 @@ import (
 // FileManager provides functionalities to interact with files in the storage.
 type FileManager interface {
 	ListFilesRecursively(userID, workspaceID int) ([]FileNode, error)
+	ListDirectoryRecursively(userID, workspaceID int, dirPath string) ([]FileNode, error)
+	IsDirectory(userID, workspaceID int, path string) (bool, error)
 	FindFileByName(userID, workspaceID int, filename string) ([]string, error)
 	GetFileContent(userID, workspaceID int, filePath string) ([]byte, error)
 	SaveFile(userID, workspaceID int, filePath string, content []byte) error
-	DeleteFile(userID, workspaceID int, filePath string) error
+	DeleteFile(userID, workspaceID int, filePath string, deletedBy int) (*TrashItem, error)
 	GetFileStats(userID, workspaceID int) (*FileCountStats, error)
 	GetTotalFileStats() (*FileCountStats, error)
 }
@@ -39,6 +41,33 @@ func (s *Service) ListFilesRecursively(userID, workspaceID int) ([]FileNode, err
 	return nodes, nil
 }
 
+// ListDirectoryRecursively returns a list of all files in the given directory and its subdirectories.
+// Paths of the returned nodes are relative to the directory.
+// Path must be a relative path within the workspace directory given by userID and workspaceID.
+func (s *Service) ListDirectoryRecursively(userID, workspaceID int, dirPath string) ([]FileNode, error) {
+	fullPath, err := s.ValidatePath(userID, workspaceID, dirPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.walkDirectory(fullPath, "")
+}
+
+// IsDirectory reports whether the given path is a directory.
+// Path must be a relative path within the workspace directory given by userID and workspaceID.
+func (s *Service) IsDirectory(userID, workspaceID int, path string) (bool, error) {
+	fullPath, err := s.ValidatePath(userID, workspaceID, path)
+	if err != nil {
+		return false, err
+	}
+
+	info, err := s.fs.Stat(fullPath)
+	if err != nil {
+		return false, err
+	}
+	return info.IsDir(), nil
+}
+
 // walkDirectory recursively walks the directory and returns a list of files and directories.
 func (s *Service) walkDirectory(dir, prefix string) ([]FileNode, error) {
 	entries, err := s.fs.ReadDir(dir)
@@ -72,6 +101,9 @@ func (s *Service) walkDirectory(dir, prefix string) ([]FileNode, error) {
 		name := entry.Name()
 		path := filepath.Join(prefix, name)
 		fullPath := filepath.Join(dir, name)
+		if s.isReservedDir(fullPath) {
+			continue
+		}
 
 		children, err := s.walkDirectory(fullPath, path)
 		if err != nil {
@@ -114,6 +146,9 @@ func (s *Service) FindFileByName(userID, workspaceID int, filename string) ([]st
 		if err != nil {
 			return err
 		}
+		if info.IsDir() && s.isReservedDir(path) {
+			return filepath.SkipDir
+		}
 		if !info.IsDir() {
 			relPath, err := filepath.Rel(workspacePath, path)
 			if err != nil {
@@ -148,23 +183,54 @@ func (s *Service) GetFileContent(userID, workspaceID int, filePath string) ([]by
 }
 
 // SaveFile writes the content to the file at the given filePath.
+// In workspaces without a Git repository, the replaced content is kept as a revision if it fits in the quotas.
+// It returns ErrQuotaExceeded if the content doesn't fit in the storage quotas of the user or the workspace.
 // Path must be a relative path within the workspace directory given by userID and workspaceID.
 func (s *Service) SaveFile(userID, workspaceID int, filePath string, content []byte) error {
 	log := getLogger()
 
+	s.writes.RLock()
+	defer s.writes.RUnlock()
+
 	fullPath, err := s.ValidatePath(userID, workspaceID, filePath)
 	if err != nil {
 		return err
 	}
 
+	// Only the change in size counts against the quota when a file is replaced
+	bytes, files := int64(len(content)), 1
+	if info, err := s.fs.Stat(fullPath); err == nil {
+		bytes -= info.Size()
+		files = 0
+	} else if !s.fs.IsNotExist(err) {
+		return err
+	}
+	if err := s.reserveUsage(userID, workspaceID, bytes, files); err != nil {
+		return err
+	}
+
+	// A failure to keep the previous version must not prevent saving the new one
+	if err := s.recordRevision(userID, workspaceID, fullPath, content); err != nil {
+		log.Warn("failed to record file revision",
+			"userID", userID,
+			"workspaceID", workspaceID,
+			"path", filePath,
+			"error", err.Error())
+	}
+
 	dir := filepath.Dir(fullPath)
 	if err := s.fs.MkdirAll(dir, 0755); err != nil {
+		s.addUsage(workspaceID, -bytes, -files)
 		return err
 	}
 
 	if err := s.fs.WriteFile(fullPath, content, 0644); err != nil {
+		// The reservation assumed the whole content was written
+		written, exists := s.fileUsage(fullPath)
+		s.addUsage(workspaceID, written-int64(len(content)), exists-1)
 		return err
 	}
+	s.observer.ObserveFileSave(len(content))
 
 	log.Debug("file saved",
 		"userID", userID,
@@ -174,24 +240,30 @@ func (s *Service) SaveFile(userID, workspaceID int, filePath string, content []b
 	return nil
 }
 
-// DeleteFile deletes the file at the given filePath.
+// DeleteFile moves the file at the given filePath to the trash of the workspace,
+// recording deletedBy as the user who deleted it.
 // Path must be a relative path within the workspace directory given by userID and workspaceID.
-func (s *Service) DeleteFile(userID, workspaceID int, filePath string) error {
+func (s *Service) DeleteFile(userID, workspaceID int, filePath string, deletedBy int) (*TrashItem, error) {
 	log := getLogger()
+
+	s.writes.RLock()
+	defer s.writes.RUnlock()
 	fullPath, err := s.ValidatePath(userID, workspaceID, filePath)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if err := s.fs.Remove(fullPath); err != nil {
-		return err
+	item, err := s.moveToTrash(userID, workspaceID, fullPath, filePath, deletedBy)
+	if err != nil {
+		return nil, err
 	}
 
-	log.Debug("file deleted",
+	log.Debug("file moved to trash",
 		"userID", userID,
 		"workspaceID", workspaceID,
-		"path", filePath)
-	return nil
+		"path", filePath,
+		"itemID", item.ID)
+	return item, nil
 }
 
 // FileCountStats holds statistics about files in a workspace
@@ -228,7 +300,8 @@ func (s *Service) GetTotalFileStats() (*FileCountStats, error) {
 	return stats, nil
 }
 
-// countFilesInPath counts the total number of files and the total size of files in the given directory.
+// countFilesInPath counts the total number of files and the total size of the files in the given directory
+// that are visible in the workspace. Use measurePath to count the storage used by the directory.
 func (s *Service) countFilesInPath(directoryPath string) (*FileCountStats, error) {
 	result := &FileCountStats{}
 
@@ -237,8 +310,8 @@ func (s *Service) countFilesInPath(directoryPath string) (*FileCountStats, error
 			return err
 		}
 
-		// Skip the .git directory
-		if d.IsDir() && d.Name() == ".git" {
+		// Skip the .git and reserved directories
+		if d.IsDir() && (d.Name() == ".git" || s.isReservedDir(path)) {
 			return filepath.SkipDir
 		}
 
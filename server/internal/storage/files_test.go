@@ -186,6 +186,62 @@ func TestListFilesRecursively(t *testing.T) {
 	})
 }
 
+func TestListDirectoryRecursively(t *testing.T) {
+	mockFS := NewMockFS()
+	s := storage.NewServiceWithOptions("test-root", storage.Options{
+		Fs:           mockFS,
+		NewGitClient: nil,
+	})
+
+	mockFS.ReadDirReturns = map[string]struct {
+		entries []fs.DirEntry
+		err     error
+	}{
+		"test-root/1/1/notes": {
+			entries: []fs.DirEntry{
+				NewMockDirEntry("sub", true),
+				NewMockDirEntry("file1.md", false),
+			},
+			err: nil,
+		},
+		"test-root/1/1/notes/sub": {
+			entries: []fs.DirEntry{
+				NewMockDirEntry("file2.md", false),
+			},
+			err: nil,
+		},
+	}
+
+	t.Run("paths are relative to the directory", func(t *testing.T) {
+		files, err := s.ListDirectoryRecursively(1, 1, "notes")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(files) != 2 {
+			t.Fatalf("expected 2 entries, got %d", len(files))
+		}
+		if files[0].Path != "sub" || len(files[0].Children) != 1 || files[0].Children[0].Path != "sub/file2.md" {
+			t.Errorf("unexpected directory node: %+v", files[0])
+		}
+		if files[1].Path != "file1.md" {
+			t.Errorf("file path = %q, want %q", files[1].Path, "file1.md")
+		}
+	})
+
+	t.Run("missing directory", func(t *testing.T) {
+		if _, err := s.ListDirectoryRecursively(1, 1, "missing"); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+
+	t.Run("invalid path", func(t *testing.T) {
+		_, err := s.ListDirectoryRecursively(1, 1, "../../2/1")
+		if !storage.IsPathValidationError(err) {
+			t.Errorf("expected path validation error, got %v", err)
+		}
+	})
+}
+
 func TestGetFileContent(t *testing.T) {
 	mockFS := NewMockFS()
 	s := storage.NewServiceWithOptions("test-root", storage.Options{
@@ -379,8 +435,8 @@ func TestDeleteFile(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			mockFS.RemoveError = tc.mockErr
-			err := s.DeleteFile(tc.userID, tc.workspaceID, tc.filePath)
+			mockFS.StatError = tc.mockErr
+			item, err := s.DeleteFile(tc.userID, tc.workspaceID, tc.filePath, 2)
 
 			if tc.wantErr {
 				if err == nil {
@@ -393,16 +449,14 @@ func TestDeleteFile(t *testing.T) {
 				t.Fatalf("unexpected error: %v", err)
 			}
 
-			expectedPath := filepath.Join("test-root", "1", "1", tc.filePath)
-			found := false
-			for _, p := range mockFS.RemoveCalls {
-				if p == expectedPath {
-					found = true
-					break
-				}
+			if item.Path != tc.filePath || item.DeletedBy != 2 {
+				t.Errorf("trash item = %+v, want path %s deleted by 2", item, tc.filePath)
 			}
-			if !found {
-				t.Error("expected delete call not made")
+
+			expectedPath := filepath.Join("test-root", "1", "1", tc.filePath)
+			expectedTrashPath := filepath.Join("test-root", "1", "1", ".trash", item.ID, "content")
+			if got, ok := mockFS.RenameCalls[expectedPath]; !ok || got != expectedTrashPath {
+				t.Errorf("file moved to %q, want %q", got, expectedTrashPath)
 			}
 		})
 	}
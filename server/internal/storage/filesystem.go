@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"io"
 	"io/fs"
 	"lemma/internal/logging"
 	"os"
@@ -9,8 +10,10 @@ import (
 // fileSystem defines the interface for filesystem operations
 type fileSystem interface {
 	ReadFile(path string) ([]byte, error)
+	Open(path string) (io.ReadCloser, error)
 	WriteFile(path string, data []byte, perm fs.FileMode) error
 	Remove(path string) error
+	Rename(oldPath, newPath string) error
 	MkdirAll(path string, perm fs.FileMode) error
 	RemoveAll(path string) error
 	ReadDir(path string) ([]fs.DirEntry, error)
@@ -33,6 +36,9 @@ type osFS struct{}
 // ReadFile reads the file at the given path.
 func (f *osFS) ReadFile(path string) ([]byte, error) { return os.ReadFile(path) }
 
+// Open opens the file at the given path for reading.
+func (f *osFS) Open(path string) (io.ReadCloser, error) { return os.Open(path) }
+
 // WriteFile writes the given data to the file at the given path.
 func (f *osFS) WriteFile(path string, data []byte, perm fs.FileMode) error {
 	return os.WriteFile(path, data, perm)
@@ -41,6 +47,9 @@ func (f *osFS) WriteFile(path string, data []byte, perm fs.FileMode) error {
 // Remove deletes the file at the given path.
 func (f *osFS) Remove(path string) error { return os.Remove(path) }
 
+// Rename moves the file or directory at oldPath to newPath.
+func (f *osFS) Rename(oldPath, newPath string) error { return os.Rename(oldPath, newPath) }
+
 // MkdirAll creates the directory at the given path and any necessary parents.
 func (f *osFS) MkdirAll(path string, perm fs.FileMode) error { return os.MkdirAll(path, perm) }
 
@@ -1,7 +1,9 @@
 package storage_test
 
 import (
+	"bytes"
 	"errors"
+	"io"
 	"io/fs"
 	"path/filepath"
 	"time"
@@ -44,6 +46,7 @@ type mockFS struct {
 	ReadCalls   map[string]int
 	WriteCalls  map[string][]byte
 	RemoveCalls []string
+	RenameCalls map[string]string
 	MkdirCalls  []string
 
 	// Configure test behavior
@@ -57,6 +60,7 @@ type mockFS struct {
 	}
 	WriteFileError error
 	RemoveError    error
+	RenameError    error
 	MkdirError     error
 	StatError      error
 }
@@ -67,6 +71,7 @@ func NewMockFS() *mockFS {
 		ReadCalls:   make(map[string]int),
 		WriteCalls:  make(map[string][]byte),
 		RemoveCalls: make([]string, 0),
+		RenameCalls: make(map[string]string),
 		MkdirCalls:  make([]string, 0),
 		ReadFileReturns: make(map[string]struct {
 			data []byte
@@ -83,6 +88,14 @@ func (m *mockFS) ReadFile(path string) ([]byte, error) {
 	return nil, errors.New("file not found")
 }
 
+func (m *mockFS) Open(path string) (io.ReadCloser, error) {
+	data, err := m.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
 func (m *mockFS) WriteFile(path string, data []byte, _ fs.FileMode) error {
 	m.WriteCalls[path] = data
 	return m.WriteFileError
@@ -93,6 +106,11 @@ func (m *mockFS) Remove(path string) error {
 	return m.RemoveError
 }
 
+func (m *mockFS) Rename(oldPath, newPath string) error {
+	m.RenameCalls[oldPath] = newPath
+	return m.RenameError
+}
+
 func (m *mockFS) MkdirAll(path string, _ fs.FileMode) error {
 	m.MkdirCalls = append(m.MkdirCalls, path)
 	return m.MkdirError
@@ -1,8 +1,19 @@
 package storage
 
 import (
+	"errors"
 	"fmt"
 	"lemma/internal/git"
+	"path/filepath"
+	"time"
+)
+
+// Git operations reported to the observer
+const (
+	GitOperationSetup  = "setup"
+	GitOperationCommit = "commit"
+	GitOperationPush   = "push"
+	GitOperationPull   = "pull"
 )
 
 // RepositoryManager defines the interface for managing Git repositories.
@@ -15,16 +26,68 @@ type RepositoryManager interface {
 
 // SetupGitRepo sets up a Git repository for the given userID and workspaceID.
 // The repository is cloned from the given gitURL using the given gitUser and gitToken.
+// The clone is stopped as soon as it outgrows the storage quotas of the user or the workspace,
+// and is then removed again and ErrQuotaExceeded is returned.
 func (s *Service) SetupGitRepo(userID, workspaceID int, gitURL, gitUser, gitToken, commitName, commitEmail string) error {
+	s.writes.RLock()
+	defer s.writes.RUnlock()
+
 	workspacePath := s.GetWorkspacePath(userID, workspaceID)
 
-	if _, ok := s.GitRepos[userID]; !ok {
-		s.GitRepos[userID] = make(map[int]git.Client)
+	// Remember the existing entries of the workspace, so only the clone is removed if it is too large
+	var existing map[string]bool
+	if _, err := s.fs.Stat(filepath.Join(workspacePath, ".git")); s.fs.IsNotExist(err) {
+		existing = make(map[string]bool)
+		entries, err := s.fs.ReadDir(workspacePath)
+		if err != nil && !s.fs.IsNotExist(err) {
+			return err
+		}
+		for _, entry := range entries {
+			existing[entry.Name()] = true
+		}
 	}
 
-	s.GitRepos[userID][workspaceID] = s.newGitClient(gitURL, gitUser, gitToken, workspacePath, commitName, commitEmail)
+	client := s.newGitClient(gitURL, gitUser, gitToken, workspacePath, commitName, commitEmail)
+	s.setGitRepo(userID, workspaceID, client)
+
+	start := time.Now()
+	err := s.runWithinQuota(userID, workspaceID, client.EnsureRepo)
+	s.observeGitOperation(GitOperationSetup, start, err)
+	if errors.Is(err, ErrQuotaExceeded) && existing != nil {
+		s.removeClone(userID, workspaceID, existing)
+		return ErrQuotaExceeded
+	}
+	if err != nil {
+		return err
+	}
 
-	return s.GitRepos[userID][workspaceID].EnsureRepo()
+	s.excludeReservedDirsFromGit(userID, workspaceID)
+	return nil
+}
+
+// removeClone removes the entries of the workspace that were not in existing
+// and the Git repository of the workspace
+func (s *Service) removeClone(userID, workspaceID int, existing map[string]bool) {
+	s.DisableGitRepo(userID, workspaceID)
+	defer s.remeasureUsage(userID, workspaceID)
+
+	workspacePath := s.GetWorkspacePath(userID, workspaceID)
+	entries, err := s.fs.ReadDir(workspacePath)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if existing[entry.Name()] {
+			continue
+		}
+		if err := s.fs.RemoveAll(filepath.Join(workspacePath, entry.Name())); err != nil {
+			getLogger().Warn("failed to remove clone exceeding the storage quota",
+				"userID", userID,
+				"workspaceID", workspaceID,
+				"path", entry.Name(),
+				"error", err.Error())
+		}
+	}
 }
 
 // DisableGitRepo disables the Git repository for the given userID and workspaceID.
@@ -34,28 +97,33 @@ func (s *Service) DisableGitRepo(userID, workspaceID int) {
 		"userID", userID,
 		"workspaceID", workspaceID)
 
-	if userRepos, ok := s.GitRepos[userID]; ok {
-		delete(userRepos, workspaceID)
-		if len(userRepos) == 0 {
-			delete(s.GitRepos, userID)
-		}
-	}
+	s.removeGitRepo(userID, workspaceID)
 }
 
 // StageCommitAndPush stages, commit with the message, and pushes the changes to the Git repository.
 // The git repository belongs to the given userID and is associated with the given workspaceID.
 func (s *Service) StageCommitAndPush(userID, workspaceID int, message string) (git.CommitHash, error) {
+	// The lock is taken first, so a transfer can't move the repository away from the client
+	s.writes.RLock()
+	defer s.writes.RUnlock()
+
 	repo, ok := s.getGitRepo(userID, workspaceID)
 	if !ok {
 		return git.CommitHash{}, fmt.Errorf("git settings not configured for this workspace")
 	}
 
+	start := time.Now()
 	hash, err := repo.Commit(message)
+	s.observeGitOperation(GitOperationCommit, start, err)
+	s.remeasureUsage(userID, workspaceID)
 	if err != nil {
 		return git.CommitHash{}, err
 	}
 
-	if err = repo.Push(); err != nil {
+	start = time.Now()
+	err = repo.Push()
+	s.observeGitOperation(GitOperationPush, start, err)
+	if err != nil {
 		return hash, err
 	}
 
@@ -63,14 +131,21 @@ func (s *Service) StageCommitAndPush(userID, workspaceID int, message string) (g
 }
 
 // Pull pulls the changes from the remote Git repository.
+// The pull is stopped and ErrQuotaExceeded is returned as soon as the workspace outgrows the
+// storage quotas of the user or the workspace.
 // The git repository belongs to the given userID and is associated with the given workspaceID.
 func (s *Service) Pull(userID, workspaceID int) error {
+	s.writes.RLock()
+	defer s.writes.RUnlock()
+
 	repo, ok := s.getGitRepo(userID, workspaceID)
 	if !ok {
 		return fmt.Errorf("git settings not configured for this workspace")
 	}
 
-	err := repo.Pull()
+	start := time.Now()
+	err := s.runWithinQuota(userID, workspaceID, repo.Pull)
+	s.observeGitOperation(GitOperationPull, start, err)
 	if err != nil {
 		return err
 	}
@@ -78,8 +153,16 @@ func (s *Service) Pull(userID, workspaceID int) error {
 	return nil
 }
 
+// observeGitOperation reports the duration and result of a Git operation started at start
+func (s *Service) observeGitOperation(operation string, start time.Time, err error) {
+	s.observer.ObserveGitOperation(operation, time.Since(start), err)
+}
+
 // getGitRepo returns the Git repository for the given user and workspace IDs.
 func (s *Service) getGitRepo(userID, workspaceID int) (git.Client, bool) {
+	s.gitReposMu.RLock()
+	defer s.gitReposMu.RUnlock()
+
 	userRepos, ok := s.GitRepos[userID]
 	if !ok {
 		return nil, false
@@ -87,3 +170,31 @@ func (s *Service) getGitRepo(userID, workspaceID int) (git.Client, bool) {
 	repo, ok := userRepos[workspaceID]
 	return repo, ok
 }
+
+// setGitRepo stores the Git repository for the given user and workspace IDs
+func (s *Service) setGitRepo(userID, workspaceID int, client git.Client) {
+	s.gitReposMu.Lock()
+	defer s.gitReposMu.Unlock()
+
+	if _, ok := s.GitRepos[userID]; !ok {
+		s.GitRepos[userID] = make(map[int]git.Client)
+	}
+	s.GitRepos[userID][workspaceID] = client
+}
+
+// removeGitRepo removes the Git repository for the given user and workspace IDs and returns it
+func (s *Service) removeGitRepo(userID, workspaceID int) (git.Client, bool) {
+	s.gitReposMu.Lock()
+	defer s.gitReposMu.Unlock()
+
+	userRepos, ok := s.GitRepos[userID]
+	if !ok {
+		return nil, false
+	}
+	repo, ok := userRepos[workspaceID]
+	delete(userRepos, workspaceID)
+	if len(userRepos) == 0 {
+		delete(s.GitRepos, userID)
+	}
+	return repo, ok
+}
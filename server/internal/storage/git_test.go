@@ -1,8 +1,11 @@
 package storage_test
 
 import (
+	"context"
 	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	"lemma/internal/git"
 	"lemma/internal/storage"
@@ -20,12 +23,12 @@ type MockGitClient struct {
 	ReturnError   error
 }
 
-func (m *MockGitClient) Clone() error {
+func (m *MockGitClient) Clone(_ context.Context) error {
 	m.CloneCalled = true
 	return m.ReturnError
 }
 
-func (m *MockGitClient) Pull() error {
+func (m *MockGitClient) Pull(_ context.Context) error {
 	m.PullCalled = true
 	return m.ReturnError
 }
@@ -41,7 +44,7 @@ func (m *MockGitClient) Push() error {
 	return m.ReturnError
 }
 
-func (m *MockGitClient) EnsureRepo() error {
+func (m *MockGitClient) EnsureRepo(_ context.Context) error {
 	m.EnsureCalled = true
 	return m.ReturnError
 }
@@ -260,3 +263,54 @@ func TestDisableGitRepo(t *testing.T) {
 		})
 	}
 }
+
+// recordingObserver records the Git operations and file saves reported by the storage
+type recordingObserver struct {
+	saves      []int
+	operations []string
+	failures   []string
+}
+
+func (o *recordingObserver) ObserveFileSave(size int) {
+	o.saves = append(o.saves, size)
+}
+
+func (o *recordingObserver) ObserveGitOperation(operation string, _ time.Duration, err error) {
+	o.operations = append(o.operations, operation)
+	if err != nil {
+		o.failures = append(o.failures, operation)
+	}
+}
+
+func TestObserver(t *testing.T) {
+	observer := &recordingObserver{}
+	s := storage.NewServiceWithOptions(t.TempDir(), storage.Options{
+		Observer:     observer,
+		NewGitClient: func(_, _, _, _, _, _ string) git.Client { return &MockGitClient{} },
+	})
+
+	if err := s.SaveFile(1, 1, "a.md", []byte("hello")); err != nil {
+		t.Fatalf("SaveFile() error = %v", err)
+	}
+	if err := s.SetupGitRepo(1, 1, "url", "user", "token", "name", "email"); err != nil {
+		t.Fatalf("SetupGitRepo() error = %v", err)
+	}
+	if _, err := s.StageCommitAndPush(1, 1, "message"); err != nil {
+		t.Fatalf("StageCommitAndPush() error = %v", err)
+	}
+	s.GitRepos[1][1] = &MockGitClient{ReturnError: errors.New("network error")}
+	if err := s.Pull(1, 1); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+
+	if len(observer.saves) != 1 || observer.saves[0] != 5 {
+		t.Errorf("file saves = %v, want [5]", observer.saves)
+	}
+	wantOperations := []string{storage.GitOperationSetup, storage.GitOperationCommit, storage.GitOperationPush, storage.GitOperationPull}
+	if strings.Join(observer.operations, ",") != strings.Join(wantOperations, ",") {
+		t.Errorf("git operations = %v, want %v", observer.operations, wantOperations)
+	}
+	if len(observer.failures) != 1 || observer.failures[0] != storage.GitOperationPull {
+		t.Errorf("failed git operations = %v, want [pull]", observer.failures)
+	}
+}
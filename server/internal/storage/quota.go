@@ -0,0 +1,510 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"lemma/internal/models"
+)
+
+// quotaPollInterval is how often a workspace is measured while a Git operation writes to it
+var quotaPollInterval = 200 * time.Millisecond
+
+// QuotaProvider provides the storage quotas that apply to users and workspaces
+type QuotaProvider interface {
+	GetStorageQuota(userID int) (*models.StorageQuota, error)
+	GetWorkspaceQuota(workspaceID int) (*models.StorageQuota, error)
+}
+
+// UsageStore persists the storage used by each workspace, so it isn't measured again after a restart
+type UsageStore interface {
+	GetWorkspaceUsage(workspaceID int) (*models.StorageUsage, error)
+	SetWorkspaceUsage(workspaceID int, usage *models.StorageUsage) error
+}
+
+// QuotaManager provides functionalities to get the storage used by users and workspaces.
+type QuotaManager interface {
+	GetUsage(userID int) (*FileCountStats, error)
+	GetWorkspaceUsage(userID, workspaceID int) (*FileCountStats, error)
+	GetTotalUsage() (*FileCountStats, error)
+}
+
+// GetUsage returns the number and total size of the files in the workspaces of the user.
+// The size includes their Git repositories, trash and revisions, which don't count as files.
+func (s *Service) GetUsage(userID int) (*FileCountStats, error) {
+	defer s.lockUsage()()
+
+	return s.userUsage(userID)
+}
+
+// GetWorkspaceUsage returns the number and total size of the files in the workspace.
+// The size includes its Git repository, trash and revisions, which don't count as files.
+// Usage is measured once and then tracked as files are saved and deleted.
+func (s *Service) GetWorkspaceUsage(userID, workspaceID int) (*FileCountStats, error) {
+	defer s.lockUsage()()
+
+	usage, err := s.workspaceUsage(userID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	result := *usage
+	return &result, nil
+}
+
+// GetTotalUsage returns the number and total size of the files in all workspaces, counted like
+// GetWorkspaceUsage does. It sums the tracked usage of the workspaces, so only workspaces whose
+// usage isn't tracked yet are measured.
+func (s *Service) GetTotalUsage() (*FileCountStats, error) {
+	defer s.lockUsage()()
+
+	total := &FileCountStats{}
+	entries, err := s.fs.ReadDir(s.RootDir)
+	if err != nil {
+		if s.fs.IsNotExist(err) {
+			return total, nil
+		}
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		userID, err := strconv.Atoi(entry.Name())
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+		usage, err := s.userUsage(userID)
+		if err != nil {
+			return nil, err
+		}
+		total.TotalSize += usage.TotalSize
+		total.TotalFiles += usage.TotalFiles
+	}
+	return total, nil
+}
+
+// userUsage returns the sum of the usage of the workspaces of the user.
+// The caller must hold the usage lock.
+func (s *Service) userUsage(userID int) (*FileCountStats, error) {
+	total := &FileCountStats{}
+	entries, err := s.fs.ReadDir(filepath.Join(s.RootDir, strconv.Itoa(userID)))
+	if err != nil {
+		if s.fs.IsNotExist(err) {
+			return total, nil
+		}
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		workspaceID, err := strconv.Atoi(entry.Name())
+		if err != nil || !entry.IsDir() {
+			continue
+		}
+		usage, err := s.workspaceUsage(userID, workspaceID)
+		if err != nil {
+			return nil, err
+		}
+		total.TotalSize += usage.TotalSize
+		total.TotalFiles += usage.TotalFiles
+	}
+	return total, nil
+}
+
+// workspaceUsage returns the tracked usage of the workspace. Usage that isn't tracked yet is read
+// from the usage store, or measured if the store has no record of it.
+// The caller must hold the usage lock.
+func (s *Service) workspaceUsage(userID, workspaceID int) (*FileCountStats, error) {
+	if usage, ok := s.usage[workspaceID]; ok {
+		return usage, nil
+	}
+
+	usage, err := s.storedUsage(workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if usage == nil {
+		if usage, err = s.measurePath(s.GetWorkspacePath(userID, workspaceID)); err != nil {
+			return nil, err
+		}
+		s.unstored[workspaceID] = struct{}{}
+	}
+
+	s.usage[workspaceID] = usage
+	return usage, nil
+}
+
+// storedUsage reads the usage of the workspace from the usage store, or returns nil if it has none
+func (s *Service) storedUsage(workspaceID int) (*FileCountStats, error) {
+	if s.usageStore == nil {
+		return nil, nil
+	}
+	stored, err := s.usageStore.GetWorkspaceUsage(workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get storage usage: %w", err)
+	}
+	if stored == nil {
+		return nil, nil
+	}
+	return &FileCountStats{TotalFiles: stored.Files, TotalSize: stored.Bytes}, nil
+}
+
+// lockUsage locks the tracked usage. The returned function unlocks it and then writes the changed
+// usage to the usage store, so changes don't wait for the database writes of other changes.
+func (s *Service) lockUsage() (unlock func()) {
+	s.usageMu.Lock()
+	return func() {
+		s.usageMu.Unlock()
+		s.storeChangedUsage()
+	}
+}
+
+// storeChangedUsage writes the usage of the workspaces that changed to the usage store. Only one
+// goroutine writes at a time, the others leave their changes to it, which batches changes made
+// while a write is running into the next one. A failure is only logged, as the tracked usage stays
+// correct until the next restart.
+func (s *Service) storeChangedUsage() {
+	s.usageMu.Lock()
+	defer s.usageMu.Unlock()
+
+	if s.storing {
+		return
+	}
+	s.storing = true
+	for len(s.unstored) > 0 {
+		changed := make(map[int]models.StorageUsage, len(s.unstored))
+		for workspaceID := range s.unstored {
+			usage := s.usage[workspaceID]
+			changed[workspaceID] = models.StorageUsage{Bytes: usage.TotalSize, Files: usage.TotalFiles}
+		}
+		clear(s.unstored)
+		if s.usageStore == nil {
+			break
+		}
+
+		s.usageMu.Unlock()
+		for workspaceID, usage := range changed {
+			if err := s.usageStore.SetWorkspaceUsage(workspaceID, &usage); err != nil {
+				getLogger().Warn("failed to store storage usage",
+					"workspaceID", workspaceID,
+					"error", err.Error())
+			}
+		}
+		s.usageMu.Lock()
+	}
+	s.storing = false
+}
+
+// getQuotas returns the quotas of the user and the workspace, or nil for the ones that are unlimited
+func (s *Service) getQuotas(userID, workspaceID int) (*models.StorageQuota, *models.StorageQuota, error) {
+	if s.quotas == nil {
+		return nil, nil, nil
+	}
+	userQuota, err := s.quotas.GetStorageQuota(userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get storage quota: %w", err)
+	}
+	workspaceQuota, err := s.quotas.GetWorkspaceQuota(workspaceID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get workspace quota: %w", err)
+	}
+	if userQuota != nil && userQuota.Unlimited() {
+		userQuota = nil
+	}
+	if workspaceQuota != nil && workspaceQuota.Unlimited() {
+		workspaceQuota = nil
+	}
+	return userQuota, workspaceQuota, nil
+}
+
+// exceedsLimits reports whether increasing usage by bytes and files goes beyond the limits of
+// the quota. Decreases are always allowed, so shrinking works even when the quota is used up.
+func exceedsLimits(quota *models.StorageQuota, usage *FileCountStats, bytes int64, files int) bool {
+	if quota == nil {
+		return false
+	}
+	return (quota.MaxBytes > 0 && bytes > 0 && usage.TotalSize+bytes > quota.MaxBytes) ||
+		(quota.MaxFiles > 0 && files > 0 && usage.TotalFiles+files > quota.MaxFiles)
+}
+
+// reserveUsage adds the bytes and files to the usage of the workspace. It returns ErrQuotaExceeded
+// without changing the usage if the quota of the user or the workspace doesn't allow the increase.
+func (s *Service) reserveUsage(userID, workspaceID int, bytes int64, files int) error {
+	var userQuota, workspaceQuota *models.StorageQuota
+	if bytes > 0 || files > 0 {
+		var err error
+		if userQuota, workspaceQuota, err = s.getQuotas(userID, workspaceID); err != nil {
+			return err
+		}
+	}
+
+	defer s.lockUsage()()
+
+	usage, err := s.workspaceUsage(userID, workspaceID)
+	if err != nil {
+		return err
+	}
+	if exceedsLimits(workspaceQuota, usage, bytes, files) {
+		return ErrQuotaExceeded
+	}
+	if userQuota != nil {
+		total, err := s.userUsage(userID)
+		if err != nil {
+			return err
+		}
+		if exceedsLimits(userQuota, total, bytes, files) {
+			return ErrQuotaExceeded
+		}
+	}
+
+	s.adjustUsage(workspaceID, usage, bytes, files)
+	return nil
+}
+
+// addUsage adds the bytes and files, which may be negative, to the tracked usage of the workspace
+// after files were changed. Usage that isn't tracked yet is measured when it is needed, which
+// already includes the change.
+func (s *Service) addUsage(workspaceID int, bytes int64, files int) {
+	if bytes == 0 && files == 0 {
+		return
+	}
+
+	defer s.lockUsage()()
+
+	usage, ok := s.usage[workspaceID]
+	if !ok {
+		stored, err := s.storedUsage(workspaceID)
+		if err != nil || stored == nil {
+			return
+		}
+		usage = stored
+		s.usage[workspaceID] = usage
+	}
+	s.adjustUsage(workspaceID, usage, bytes, files)
+}
+
+// adjustUsage changes the tracked usage of the workspace, which is stored once the usage lock is released.
+// The caller must hold the usage lock.
+func (s *Service) adjustUsage(workspaceID int, usage *FileCountStats, bytes int64, files int) {
+	usage.TotalSize = max(usage.TotalSize+bytes, 0)
+	usage.TotalFiles = max(usage.TotalFiles+files, 0)
+	s.unstored[workspaceID] = struct{}{}
+}
+
+// measureUsage measures the workspace and replaces its tracked usage with the result.
+// It is used after changes of unknown size, such as pulling a Git repository.
+func (s *Service) measureUsage(userID, workspaceID int) (*FileCountStats, error) {
+	usage, err := s.measurePath(s.GetWorkspacePath(userID, workspaceID))
+	if err != nil {
+		return nil, err
+	}
+
+	defer s.lockUsage()()
+
+	s.usage[workspaceID] = usage
+	s.unstored[workspaceID] = struct{}{}
+	result := *usage
+	return &result, nil
+}
+
+// remeasureUsage measures the workspace after a change of unknown size that can't fail because of
+// the measurement, such as a commit
+func (s *Service) remeasureUsage(userID, workspaceID int) {
+	if _, err := s.measureUsage(userID, workspaceID); err != nil {
+		getLogger().Warn("failed to measure storage usage",
+			"userID", userID,
+			"workspaceID", workspaceID,
+			"error", err.Error())
+	}
+}
+
+// setUsage replaces the tracked usage of the workspace, for example after it was deleted
+func (s *Service) setUsage(workspaceID int, usage *FileCountStats) {
+	defer s.lockUsage()()
+
+	s.usage[workspaceID] = usage
+	s.unstored[workspaceID] = struct{}{}
+}
+
+// checkTransferQuota returns ErrQuotaExceeded if the workspace of fromUserID doesn't fit in the
+// storage quota of toUserID
+func (s *Service) checkTransferQuota(fromUserID, toUserID, workspaceID int) error {
+	quota, _, err := s.getQuotas(toUserID, workspaceID)
+	if err != nil || quota == nil {
+		return err
+	}
+
+	defer s.lockUsage()()
+
+	usage, err := s.workspaceUsage(fromUserID, workspaceID)
+	if err != nil {
+		return err
+	}
+	total, err := s.userUsage(toUserID)
+	if err != nil {
+		return err
+	}
+	if exceedsLimits(quota, total, usage.TotalSize, usage.TotalFiles) {
+		return ErrQuotaExceeded
+	}
+	return nil
+}
+
+// quotaCheck returns a function that reports whether the workspace fits in the quotas of the user
+// and the workspace with the given usage, while the other workspaces of the user keep their current
+// usage. It returns nil if the workspace is unlimited.
+func (s *Service) quotaCheck(userID, workspaceID int) (func(usage *FileCountStats) bool, error) {
+	userQuota, workspaceQuota, err := s.getQuotas(userID, workspaceID)
+	if err != nil || (userQuota == nil && workspaceQuota == nil) {
+		return nil, err
+	}
+
+	defer s.lockUsage()()
+
+	current, err := s.workspaceUsage(userID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	total, err := s.userUsage(userID)
+	if err != nil {
+		return nil, err
+	}
+	others := &FileCountStats{
+		TotalSize:  total.TotalSize - current.TotalSize,
+		TotalFiles: total.TotalFiles - current.TotalFiles,
+	}
+
+	return func(usage *FileCountStats) bool {
+		return !exceedsLimits(workspaceQuota, &FileCountStats{}, usage.TotalSize, usage.TotalFiles) &&
+			!exceedsLimits(userQuota, others, usage.TotalSize, usage.TotalFiles)
+	}, nil
+}
+
+// runWithinQuota runs a Git operation that writes an unknown amount of data to the workspace. The
+// workspace is measured while the operation runs, and its context is canceled as soon as the workspace
+// outgrows the quotas, so a large repository can't fill the disk before the quota is checked.
+// The usage of the workspace is measured again afterwards. ErrQuotaExceeded is returned if the
+// workspace doesn't fit in the quotas, including when it is already too large to start the operation.
+func (s *Service) runWithinQuota(userID, workspaceID int, operation func(ctx context.Context) error) error {
+	fits, err := s.quotaCheck(userID, workspaceID)
+	if err != nil {
+		return err
+	}
+	if fits == nil {
+		err := operation(context.Background())
+		if _, measureErr := s.measureUsage(userID, workspaceID); err == nil {
+			err = measureErr
+		}
+		return err
+	}
+
+	usage, err := s.GetWorkspaceUsage(userID, workspaceID)
+	if err != nil {
+		return err
+	}
+	if !fits(usage) {
+		return ErrQuotaExceeded
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var exceeded atomic.Bool
+	done := make(chan struct{})
+	workspacePath := s.GetWorkspacePath(userID, workspaceID)
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(quotaPollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				// Files may be replaced while they are measured, so failed measurements are retried
+				if usage, err := s.measurePath(workspacePath); err == nil && !fits(usage) {
+					exceeded.Store(true)
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	err = operation(ctx)
+	cancel()
+	<-done
+
+	usage, measureErr := s.measureUsage(userID, workspaceID)
+	if exceeded.Load() || (measureErr == nil && !fits(usage)) {
+		return ErrQuotaExceeded
+	}
+	if err != nil {
+		return err
+	}
+	return measureErr
+}
+
+// measurePath measures the files below the directory at path. The Git repository, trash and revisions
+// of workspaces add to the size, but not to the number of files, as users can't see them.
+// A missing directory is empty.
+func (s *Service) measurePath(directoryPath string) (*FileCountStats, error) {
+	result := &FileCountStats{}
+
+	err := filepath.WalkDir(directoryPath, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return fmt.Errorf("failed to get file info for %s: %w", path, err)
+		}
+		if !s.isHiddenFile(path) {
+			result.TotalFiles++
+		}
+		result.TotalSize += info.Size()
+		return nil
+	})
+	if err != nil {
+		if os.IsNotExist(err) {
+			if _, statErr := os.Stat(directoryPath); os.IsNotExist(statErr) {
+				return &FileCountStats{}, nil
+			}
+		}
+		return nil, fmt.Errorf("error measuring storage usage: %w", err)
+	}
+
+	return result, nil
+}
+
+// countedFiles returns the number of files the file or folder at path counts as
+func (s *Service) countedFiles(path string, isDir bool) (int, error) {
+	if !isDir {
+		_, files := s.fileUsage(path)
+		return files, nil
+	}
+	usage, err := s.measurePath(path)
+	if err != nil {
+		return 0, err
+	}
+	return usage.TotalFiles, nil
+}
+
+// fileUsage returns the size of the file at path and the number of files it counts as, which is
+// 0 for the files users can't see. It returns zeros if there is no file at path.
+func (s *Service) fileUsage(path string) (int64, int) {
+	info, err := s.fs.Stat(path)
+	if err != nil || info.IsDir() {
+		return 0, 0
+	}
+	if s.isHiddenFile(path) {
+		return info.Size(), 0
+	}
+	return info.Size(), 1
+}
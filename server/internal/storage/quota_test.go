@@ -0,0 +1,414 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"lemma/internal/git"
+	"lemma/internal/models"
+	"lemma/internal/storage"
+
+	_ "lemma/internal/testenv"
+)
+
+type mockQuotas struct {
+	users      map[int]*models.StorageQuota
+	workspaces map[int]*models.StorageQuota
+}
+
+func (m mockQuotas) GetStorageQuota(userID int) (*models.StorageQuota, error) {
+	if quota, ok := m.users[userID]; ok {
+		return quota, nil
+	}
+	return &models.StorageQuota{}, nil
+}
+
+func (m mockQuotas) GetWorkspaceQuota(workspaceID int) (*models.StorageQuota, error) {
+	return m.workspaces[workspaceID], nil
+}
+
+type mockUsageStore struct {
+	mu    sync.Mutex
+	usage map[int]models.StorageUsage
+}
+
+func (m *mockUsageStore) GetWorkspaceUsage(workspaceID int) (*models.StorageUsage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	usage, ok := m.usage[workspaceID]
+	if !ok {
+		return nil, nil
+	}
+	return &usage, nil
+}
+
+func (m *mockUsageStore) SetWorkspaceUsage(workspaceID int, usage *models.StorageUsage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.usage[workspaceID] = *usage
+	return nil
+}
+
+// blockingUsageStore blocks writes of usage until release is closed, and closes started
+// when the first write begins
+type blockingUsageStore struct {
+	mockUsageStore
+	started chan struct{}
+	release chan struct{}
+	once    sync.Once
+}
+
+func (m *blockingUsageStore) SetWorkspaceUsage(workspaceID int, usage *models.StorageUsage) error {
+	m.once.Do(func() { close(m.started) })
+	<-m.release
+	return m.mockUsageStore.SetWorkspaceUsage(workspaceID, usage)
+}
+
+// growingGitClient writes size bytes into the Git repository of the workspace in small chunks when
+// the repository is cloned or pulled, and stops early when the context is canceled
+type growingGitClient struct {
+	MockGitClient
+	path     string
+	size     int
+	canceled bool
+}
+
+func (c *growingGitClient) write(ctx context.Context, name string) error {
+	if err := os.MkdirAll(filepath.Join(c.path, ".git"), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(c.path, ".git", name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	chunk := make([]byte, 100)
+	for written := 0; written < c.size; written += len(chunk) {
+		select {
+		case <-ctx.Done():
+			c.canceled = true
+			return ctx.Err()
+		case <-time.After(2 * time.Millisecond):
+		}
+		if _, err := f.Write(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *growingGitClient) EnsureRepo(ctx context.Context) error {
+	return c.write(ctx, "clone.pack")
+}
+
+func (c *growingGitClient) Pull(ctx context.Context) error {
+	return c.write(ctx, "pull.pack")
+}
+
+func TestQuota(t *testing.T) {
+	rootDir := t.TempDir()
+	quotas := mockQuotas{
+		users:      map[int]*models.StorageQuota{1: {MaxBytes: 1000, MaxFiles: 10}},
+		workspaces: map[int]*models.StorageQuota{2: {MaxFiles: 1}, 4: {MaxBytes: 500}},
+	}
+	store := &mockUsageStore{usage: make(map[int]models.StorageUsage)}
+	clients := make(map[string]*growingGitClient)
+	options := storage.Options{
+		Quotas: quotas,
+		Usage:  store,
+		NewGitClient: func(_, _, _, path, _, _ string) git.Client {
+			client := &growingGitClient{path: path, size: 100000}
+			clients[path] = client
+			return client
+		},
+	}
+	s := storage.NewServiceWithOptions(rootDir, options)
+
+	// assertUsage compares the tracked usage of the user with the usage measured from scratch
+	assertUsage := func(t *testing.T, userID int) *storage.FileCountStats {
+		t.Helper()
+		usage, err := s.GetUsage(userID)
+		if err != nil {
+			t.Fatalf("GetUsage() error = %v", err)
+		}
+		measured, err := storage.NewService(rootDir).GetUsage(userID)
+		if err != nil {
+			t.Fatalf("GetUsage() of new service error = %v", err)
+		}
+		if *usage != *measured {
+			t.Errorf("tracked usage = %+v, measured usage = %+v", usage, measured)
+		}
+		return usage
+	}
+
+	// The Git repository of the first workspace already excludes the reserved directories,
+	// so deleting files doesn't change it
+	exclude := []byte("/.trash/\n/.revisions/\n")
+	gitSize := int64(100 + len(exclude))
+
+	t.Run("counts the size of the Git repository but not its files", func(t *testing.T) {
+		gitPath := filepath.Join(s.GetWorkspacePath(1, 1), ".git")
+		if err := os.MkdirAll(filepath.Join(gitPath, "info"), 0755); err != nil {
+			t.Fatalf("failed to create .git: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(gitPath, "objects.pack"), make([]byte, 100), 0644); err != nil {
+			t.Fatalf("failed to write git object: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(gitPath, "info", "exclude"), exclude, 0644); err != nil {
+			t.Fatalf("failed to write git excludes: %v", err)
+		}
+
+		usage := assertUsage(t, 1)
+		if usage.TotalSize != gitSize || usage.TotalFiles != 0 {
+			t.Errorf("GetUsage() = %+v, want %d bytes in 0 files", usage, gitSize)
+		}
+		stats, err := s.GetFileStats(1, 1)
+		if err != nil {
+			t.Fatalf("GetFileStats() error = %v", err)
+		}
+		if stats.TotalFiles != 0 {
+			t.Errorf("GetFileStats() = %+v, want the Git repository to be hidden", stats)
+		}
+	})
+
+	t.Run("rejects exceeding the byte limit", func(t *testing.T) {
+		err := s.SaveFile(1, 1, "a.md", make([]byte, 1000-int(gitSize)+1))
+		if !errors.Is(err, storage.ErrQuotaExceeded) {
+			t.Fatalf("SaveFile() error = %v, want ErrQuotaExceeded", err)
+		}
+		if err := s.SaveFile(1, 1, "a.md", make([]byte, 500)); err != nil {
+			t.Fatalf("SaveFile() error = %v", err)
+		}
+		if usage := assertUsage(t, 1); usage.TotalSize != gitSize+500 {
+			t.Errorf("GetUsage() = %+v, want %d bytes", usage, gitSize+500)
+		}
+	})
+
+	t.Run("revisions count against the quota", func(t *testing.T) {
+		if err := s.SaveFile(1, 1, "a.md", []byte("small")); err != nil {
+			t.Fatalf("SaveFile() error = %v", err)
+		}
+		if revisions, _ := s.ListRevisions(1, 1, "a.md"); len(revisions) != 1 {
+			t.Errorf("ListRevisions() = %d revisions, want 1", len(revisions))
+		}
+		// Revisions use storage, but don't count as files
+		if usage := assertUsage(t, 1); usage.TotalFiles != 1 {
+			t.Errorf("GetUsage() = %+v, want 1 file", usage)
+		}
+
+		// The replaced content is kept as a revision, so it still uses the quota
+		err := s.SaveFile(1, 1, "b.md", make([]byte, 300))
+		if !errors.Is(err, storage.ErrQuotaExceeded) {
+			t.Fatalf("SaveFile() error = %v, want ErrQuotaExceeded", err)
+		}
+	})
+
+	t.Run("trash counts against the quota until it is emptied", func(t *testing.T) {
+		before := assertUsage(t, 1)
+		item, err := s.DeleteFile(1, 1, "a.md", 1)
+		if err != nil {
+			t.Fatalf("DeleteFile() error = %v", err)
+		}
+		if usage := assertUsage(t, 1); usage.TotalSize <= before.TotalSize || usage.TotalFiles != before.TotalFiles-1 {
+			t.Errorf("GetUsage() = %+v after deleting, want more bytes and one file less than %+v", usage, before)
+		}
+
+		if _, err := s.RestoreTrashItem(1, 1, item.ID); err != nil {
+			t.Fatalf("RestoreTrashItem() error = %v", err)
+		}
+		if usage := assertUsage(t, 1); *usage != *before {
+			t.Errorf("GetUsage() = %+v after restoring, want %+v", usage, before)
+		}
+
+		if _, err := s.DeleteFile(1, 1, "a.md", 1); err != nil {
+			t.Fatalf("DeleteFile() error = %v", err)
+		}
+		if _, err := s.EmptyTrash(1, 1); err != nil {
+			t.Fatalf("EmptyTrash() error = %v", err)
+		}
+		if usage := assertUsage(t, 1); usage.TotalSize >= before.TotalSize {
+			t.Errorf("GetUsage() = %+v after emptying the trash, want less than %+v", usage, before)
+		}
+	})
+
+	t.Run("workspace quota", func(t *testing.T) {
+		if err := s.SaveFile(1, 2, "a.md", []byte("x")); err != nil {
+			t.Fatalf("SaveFile() error = %v", err)
+		}
+		err := s.SaveFile(1, 2, "b.md", []byte("x"))
+		if !errors.Is(err, storage.ErrQuotaExceeded) {
+			t.Fatalf("SaveFile() error = %v, want ErrQuotaExceeded", err)
+		}
+		usage, err := s.GetWorkspaceUsage(1, 2)
+		if err != nil {
+			t.Fatalf("GetWorkspaceUsage() error = %v", err)
+		}
+		if usage.TotalFiles != 1 {
+			t.Errorf("GetWorkspaceUsage() = %+v, want 1 file", usage)
+		}
+
+		// Deleted files make room for new ones, so restoring them has to fit in the quota again
+		item, err := s.DeleteFile(1, 2, "a.md", 1)
+		if err != nil {
+			t.Fatalf("DeleteFile() error = %v", err)
+		}
+		if err := s.SaveFile(1, 2, "b.md", []byte("x")); err != nil {
+			t.Fatalf("SaveFile() error = %v", err)
+		}
+		if _, err := s.RestoreTrashItem(1, 2, item.ID); !errors.Is(err, storage.ErrQuotaExceeded) {
+			t.Fatalf("RestoreTrashItem() error = %v, want ErrQuotaExceeded", err)
+		}
+		if items, _ := s.ListTrash(1, 2); len(items) != 1 {
+			t.Errorf("ListTrash() = %d items, want the item to stay in the trash", len(items))
+		}
+		if _, err := os.Stat(filepath.Join(s.GetWorkspacePath(1, 2), "a.md")); !os.IsNotExist(err) {
+			t.Errorf("restored file exists after exceeding the quota, error = %v", err)
+		}
+		assertUsage(t, 1)
+	})
+
+	t.Run("stops and removes clones exceeding the quota", func(t *testing.T) {
+		before := assertUsage(t, 1)
+		err := s.SetupGitRepo(1, 3, "https://example.com/repo", "user", "token", "name", "email")
+		if !errors.Is(err, storage.ErrQuotaExceeded) {
+			t.Fatalf("SetupGitRepo() error = %v, want ErrQuotaExceeded", err)
+		}
+		if !clients[s.GetWorkspacePath(1, 3)].canceled {
+			t.Error("clone was not stopped")
+		}
+		entries, err := os.ReadDir(s.GetWorkspacePath(1, 3))
+		if err != nil {
+			t.Fatalf("failed to read workspace: %v", err)
+		}
+		if len(entries) != 0 {
+			t.Errorf("workspace has %d entries after removing the clone, want 0", len(entries))
+		}
+		if usage := assertUsage(t, 1); *usage != *before {
+			t.Errorf("GetUsage() = %+v, want %+v", usage, before)
+		}
+	})
+
+	t.Run("stops pulls exceeding the quota", func(t *testing.T) {
+		workspacePath := s.GetWorkspacePath(2, 4)
+		options.NewGitClient = func(_, _, _, path, _, _ string) git.Client {
+			client := &growingGitClient{path: path, size: 200}
+			clients[path] = client
+			return client
+		}
+		pulls := storage.NewServiceWithOptions(rootDir, options)
+		if err := pulls.SetupGitRepo(2, 4, "https://example.com/repo", "user", "token", "name", "email"); err != nil {
+			t.Fatalf("SetupGitRepo() error = %v", err)
+		}
+
+		clients[workspacePath].size = 100000
+		if err := pulls.Pull(2, 4); !errors.Is(err, storage.ErrQuotaExceeded) {
+			t.Fatalf("Pull() error = %v, want ErrQuotaExceeded", err)
+		}
+		if !clients[workspacePath].canceled {
+			t.Error("pull was not stopped")
+		}
+
+		// The workspace is already over its quota, so further pulls are rejected right away
+		clients[workspacePath].canceled = false
+		if err := pulls.Pull(2, 4); !errors.Is(err, storage.ErrQuotaExceeded) {
+			t.Fatalf("Pull() error = %v, want ErrQuotaExceeded", err)
+		}
+	})
+
+	t.Run("usage is kept across restarts", func(t *testing.T) {
+		before := assertUsage(t, 1)
+
+		// A file written behind the back of the service shows that usage isn't measured again
+		if err := os.WriteFile(filepath.Join(s.GetWorkspacePath(1, 1), "untracked.md"), []byte("x"), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		usage, err := storage.NewServiceWithOptions(rootDir, options).GetUsage(1)
+		if err != nil {
+			t.Fatalf("GetUsage() error = %v", err)
+		}
+		if *usage != *before {
+			t.Errorf("GetUsage() after restart = %+v, want %+v", usage, before)
+		}
+	})
+
+	t.Run("unlimited users", func(t *testing.T) {
+		if err := s.SaveFile(5, 5, "large.md", make([]byte, 1000)); err != nil {
+			t.Fatalf("SaveFile() error = %v", err)
+		}
+		if usage := assertUsage(t, 5); usage.TotalSize != 1000 || usage.TotalFiles != 1 {
+			t.Errorf("GetUsage() = %+v, want 1000 bytes in 1 file", usage)
+		}
+	})
+
+	t.Run("total usage sums the tracked usage of all users", func(t *testing.T) {
+		want := storage.FileCountStats{}
+		for _, userID := range []int{1, 2, 5} {
+			usage, err := s.GetUsage(userID)
+			if err != nil {
+				t.Fatalf("GetUsage() error = %v", err)
+			}
+			want.TotalSize += usage.TotalSize
+			want.TotalFiles += usage.TotalFiles
+		}
+
+		total, err := s.GetTotalUsage()
+		if err != nil {
+			t.Fatalf("GetTotalUsage() error = %v", err)
+		}
+		if *total != want {
+			t.Errorf("GetTotalUsage() = %+v, want %+v", total, want)
+		}
+	})
+}
+
+func TestQuotaUsageStore(t *testing.T) {
+	rootDir := t.TempDir()
+	store := &blockingUsageStore{
+		mockUsageStore: mockUsageStore{usage: make(map[int]models.StorageUsage)},
+		started:        make(chan struct{}),
+		release:        make(chan struct{}),
+	}
+	s := storage.NewServiceWithOptions(rootDir, storage.Options{Usage: store})
+
+	saved := make(chan error, 1)
+	go func() {
+		saved <- s.SaveFile(1, 1, "a.md", []byte("first"))
+	}()
+	<-store.started
+
+	// Saves don't wait for the usage of other saves to be stored
+	done := make(chan error, 1)
+	go func() {
+		done <- s.SaveFile(1, 2, "b.md", []byte("second"))
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("SaveFile() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("SaveFile() waited for the usage store")
+	}
+
+	close(store.release)
+	if err := <-saved; err != nil {
+		t.Fatalf("SaveFile() error = %v", err)
+	}
+
+	// The usage changed while the store was blocked is stored by the save that was blocked
+	for workspaceID, want := range map[int]models.StorageUsage{1: {Bytes: 5, Files: 1}, 2: {Bytes: 6, Files: 1}} {
+		got, err := store.GetWorkspaceUsage(workspaceID)
+		if err != nil {
+			t.Fatalf("GetWorkspaceUsage() error = %v", err)
+		}
+		if got == nil || *got != want {
+			t.Errorf("stored usage of workspace %d = %+v, want %+v", workspaceID, got, want)
+		}
+	}
+}
@@ -0,0 +1,473 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/utils/diff"
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+const (
+	// revisionsDirName is the hidden directory in the workspace root that holds the revision history of files
+	revisionsDirName = ".revisions"
+
+	revisionObjectsDir = "objects"
+	revisionHistoryDir = "history"
+
+	// DefaultMaxRevisions is the number of revisions kept per file if not configured otherwise
+	DefaultMaxRevisions = 50
+	// DefaultRevisionMaxAge is how long revisions are kept if not configured otherwise
+	DefaultRevisionMaxAge = 30 * 24 * time.Hour
+
+	// CurrentRevision identifies the current content of a file when diffing revisions
+	CurrentRevision = 0
+)
+
+// Operations of the chunks of a diff
+const (
+	DiffOpEqual  = "equal"
+	DiffOpInsert = "insert"
+	DiffOpDelete = "delete"
+)
+
+// RevisionManager provides functionalities to access the revision history of files in
+// workspaces without a Git repository.
+type RevisionManager interface {
+	ListRevisions(userID, workspaceID int, filePath string) ([]FileRevision, error)
+	GetRevisionContent(userID, workspaceID int, filePath string, revisionID int) ([]byte, error)
+	DiffRevisions(userID, workspaceID int, filePath string, fromID, toID int) ([]DiffChunk, error)
+	RestoreRevision(userID, workspaceID int, filePath string, revisionID int) (*FileRevision, error)
+	PurgeExpiredRevisions() (int, error)
+}
+
+// FileRevision is a prior version of a file
+type FileRevision struct {
+	ID        int       `json:"id"`
+	Hash      string    `json:"hash"` // SHA-256 of the content
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"createdAt"` // When the content was replaced by a newer version
+}
+
+// DiffChunk is a part of the line-based difference between two versions of a file
+type DiffChunk struct {
+	Op   string `json:"op"` // equal, insert or delete
+	Text string `json:"text"`
+}
+
+// revisionHistory is the revision history of a single file
+type revisionHistory struct {
+	Path      string         `json:"path"`
+	NextID    int            `json:"nextId"`
+	Revisions []FileRevision `json:"revisions"` // Oldest first
+}
+
+// getRevisionsPath returns the path to the revisions directory of the workspace
+func (s *Service) getRevisionsPath(userID, workspaceID int) string {
+	return filepath.Join(s.GetWorkspacePath(userID, workspaceID), revisionsDirName)
+}
+
+// hashContent returns the hex encoded SHA-256 of the content
+func hashContent(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// revisionHistoryPath returns the path of the history file for the file at fullPath
+// along with the path of the file relative to the workspace
+func (s *Service) revisionHistoryPath(userID, workspaceID int, fullPath string) (string, string, error) {
+	rel, err := filepath.Rel(s.GetWorkspacePath(userID, workspaceID), fullPath)
+	if err != nil {
+		return "", "", err
+	}
+	rel = filepath.ToSlash(rel)
+	historyPath := filepath.Join(s.getRevisionsPath(userID, workspaceID), revisionHistoryDir, hashContent([]byte(rel))+".json")
+	return historyPath, rel, nil
+}
+
+// readRevisionHistory reads the history file at historyPath. A missing file is an empty history.
+func (s *Service) readRevisionHistory(historyPath, filePath string) (*revisionHistory, error) {
+	data, err := s.fs.ReadFile(historyPath)
+	if err != nil {
+		if s.fs.IsNotExist(err) {
+			return &revisionHistory{Path: filePath, NextID: 1, Revisions: []FileRevision{}}, nil
+		}
+		return nil, err
+	}
+
+	var history revisionHistory
+	if err := json.Unmarshal(data, &history); err != nil {
+		return nil, fmt.Errorf("failed to decode revision history: %w", err)
+	}
+	return &history, nil
+}
+
+// writeRevisionHistory writes the history to historyPath, removing the file once it has no revisions left.
+// The change in size of the history file is added to the storage usage of the workspace.
+func (s *Service) writeRevisionHistory(workspaceID int, historyPath string, history *revisionHistory) error {
+	oldSize, oldFiles := s.fileUsage(historyPath)
+	defer func() {
+		size, files := s.fileUsage(historyPath)
+		s.addUsage(workspaceID, size-oldSize, files-oldFiles)
+	}()
+
+	if len(history.Revisions) == 0 {
+		if err := s.fs.Remove(historyPath); err != nil && !s.fs.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	data, err := json.Marshal(history)
+	if err != nil {
+		return fmt.Errorf("failed to encode revision history: %w", err)
+	}
+	if err := s.fs.MkdirAll(filepath.Dir(historyPath), 0755); err != nil {
+		return err
+	}
+	return s.fs.WriteFile(historyPath, data, 0644)
+}
+
+// writeRevisionObject stores the content under its hash unless it is already stored.
+// It returns ErrQuotaExceeded if the content doesn't fit in the storage quotas.
+func (s *Service) writeRevisionObject(userID, workspaceID int, hash string, content []byte) error {
+	objectsPath := filepath.Join(s.getRevisionsPath(userID, workspaceID), revisionObjectsDir)
+	objectPath := filepath.Join(objectsPath, hash)
+	if _, err := s.fs.Stat(objectPath); err == nil {
+		return nil
+	} else if !s.fs.IsNotExist(err) {
+		return err
+	}
+
+	if err := s.reserveUsage(userID, workspaceID, int64(len(content)), 0); err != nil {
+		return err
+	}
+
+	// Objects are written to a temporary file first, so a partially written object is never used
+	tmpPath := objectPath + ".tmp"
+	err := s.fs.MkdirAll(objectsPath, 0755)
+	if err == nil {
+		err = s.fs.WriteFile(tmpPath, content, 0644)
+	}
+	if err == nil {
+		err = s.fs.Rename(tmpPath, objectPath)
+	}
+	if err != nil {
+		s.fs.Remove(tmpPath)
+		s.addUsage(workspaceID, -int64(len(content)), 0)
+		return err
+	}
+	return nil
+}
+
+// pruneRevisions removes the revisions that exceed the retention limits.
+// It returns the number of removed revisions.
+func (s *Service) pruneRevisions(history *revisionHistory, now time.Time) int {
+	cutoff := now.Add(-s.revisionMaxAge)
+	kept := make([]FileRevision, 0, len(history.Revisions))
+	for _, revision := range history.Revisions {
+		if revision.CreatedAt.After(cutoff) {
+			kept = append(kept, revision)
+		}
+	}
+	if len(kept) > s.maxRevisions {
+		kept = kept[len(kept)-s.maxRevisions:]
+	}
+
+	removed := len(history.Revisions) - len(kept)
+	history.Revisions = kept
+	return removed
+}
+
+// recordRevision keeps the current content of the file at fullPath as a revision before it is
+// replaced by content. Revisions count against the storage quotas, so none is kept if it doesn't fit.
+// Files in workspaces with a Git repository are versioned by Git instead.
+// The caller must hold the writes lock.
+func (s *Service) recordRevision(userID, workspaceID int, fullPath string, content []byte) error {
+	if _, ok := s.getGitRepo(userID, workspaceID); ok {
+		return nil
+	}
+
+	current, err := s.fs.ReadFile(fullPath)
+	if err != nil {
+		if s.fs.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if bytes.Equal(current, content) {
+		return nil
+	}
+
+	historyPath, filePath, err := s.revisionHistoryPath(userID, workspaceID, fullPath)
+	if err != nil {
+		return err
+	}
+
+	s.revisions.Lock()
+	defer s.revisions.Unlock()
+
+	history, err := s.readRevisionHistory(historyPath, filePath)
+	if err != nil {
+		return err
+	}
+
+	hash := hashContent(current)
+	if n := len(history.Revisions); n > 0 && history.Revisions[n-1].Hash == hash {
+		return nil
+	}
+	if err := s.writeRevisionObject(userID, workspaceID, hash, current); err != nil {
+		return fmt.Errorf("failed to store revision: %w", err)
+	}
+
+	now := time.Now().UTC()
+	history.Revisions = append(history.Revisions, FileRevision{
+		ID:        history.NextID,
+		Hash:      hash,
+		Size:      int64(len(current)),
+		CreatedAt: now,
+	})
+	history.NextID++
+	s.pruneRevisions(history, now)
+
+	// Objects that are no longer referenced are removed by PurgeExpiredRevisions,
+	// as they may still be referenced by the history of other files
+	return s.writeRevisionHistory(workspaceID, historyPath, history)
+}
+
+// getRevisionHistory returns the revision history of the file at filePath
+func (s *Service) getRevisionHistory(userID, workspaceID int, filePath string) (*revisionHistory, error) {
+	fullPath, err := s.ValidatePath(userID, workspaceID, filePath)
+	if err != nil {
+		return nil, err
+	}
+	historyPath, rel, err := s.revisionHistoryPath(userID, workspaceID, fullPath)
+	if err != nil {
+		return nil, err
+	}
+	return s.readRevisionHistory(historyPath, rel)
+}
+
+// findRevision returns the revision with the given ID or ErrRevisionNotFound
+func findRevision(history *revisionHistory, revisionID int) (*FileRevision, error) {
+	for i := range history.Revisions {
+		if history.Revisions[i].ID == revisionID {
+			return &history.Revisions[i], nil
+		}
+	}
+	return nil, ErrRevisionNotFound
+}
+
+// ListRevisions returns the revisions of the file at filePath, most recent first.
+// Workspace is identified by the given userID and workspaceID.
+func (s *Service) ListRevisions(userID, workspaceID int, filePath string) ([]FileRevision, error) {
+	history, err := s.getRevisionHistory(userID, workspaceID, filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := history.Revisions
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisions[i].ID > revisions[j].ID
+	})
+	return revisions, nil
+}
+
+// GetRevisionContent returns the content of the revision of the file at filePath.
+// It returns ErrRevisionNotFound if the file has no revision with the given ID.
+func (s *Service) GetRevisionContent(userID, workspaceID int, filePath string, revisionID int) ([]byte, error) {
+	history, err := s.getRevisionHistory(userID, workspaceID, filePath)
+	if err != nil {
+		return nil, err
+	}
+	revision, err := findRevision(history, revisionID)
+	if err != nil {
+		return nil, err
+	}
+
+	objectPath := filepath.Join(s.getRevisionsPath(userID, workspaceID), revisionObjectsDir, revision.Hash)
+	content, err := s.fs.ReadFile(objectPath)
+	if err != nil {
+		if s.fs.IsNotExist(err) {
+			return nil, ErrRevisionNotFound
+		}
+		return nil, err
+	}
+	return content, nil
+}
+
+// DiffRevisions returns the line-based difference between two revisions of the file at filePath.
+// CurrentRevision can be used as either ID to compare against the current content of the file.
+func (s *Service) DiffRevisions(userID, workspaceID int, filePath string, fromID, toID int) ([]DiffChunk, error) {
+	from, err := s.getRevisionOrCurrent(userID, workspaceID, filePath, fromID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.getRevisionOrCurrent(userID, workspaceID, filePath, toID)
+	if err != nil {
+		return nil, err
+	}
+
+	diffs := diff.Do(string(from), string(to))
+	chunks := make([]DiffChunk, 0, len(diffs))
+	for _, d := range diffs {
+		chunk := DiffChunk{Op: DiffOpEqual, Text: d.Text}
+		switch d.Type {
+		case diffmatchpatch.DiffInsert:
+			chunk.Op = DiffOpInsert
+		case diffmatchpatch.DiffDelete:
+			chunk.Op = DiffOpDelete
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks, nil
+}
+
+// getRevisionOrCurrent returns the content of the revision, or the current content for CurrentRevision
+func (s *Service) getRevisionOrCurrent(userID, workspaceID int, filePath string, revisionID int) ([]byte, error) {
+	if revisionID != CurrentRevision {
+		return s.GetRevisionContent(userID, workspaceID, filePath, revisionID)
+	}
+	return s.GetFileContent(userID, workspaceID, filePath)
+}
+
+// RestoreRevision replaces the content of the file at filePath with the content of the revision
+// and returns the restored revision. The replaced content is kept as a new revision, so restoring
+// can be undone.
+func (s *Service) RestoreRevision(userID, workspaceID int, filePath string, revisionID int) (*FileRevision, error) {
+	history, err := s.getRevisionHistory(userID, workspaceID, filePath)
+	if err != nil {
+		return nil, err
+	}
+	revision, err := findRevision(history, revisionID)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := s.GetRevisionContent(userID, workspaceID, filePath, revisionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.SaveFile(userID, workspaceID, filePath, content); err != nil {
+		return nil, err
+	}
+
+	getLogger().Debug("file revision restored",
+		"userID", userID,
+		"workspaceID", workspaceID,
+		"path", filePath,
+		"revisionID", revisionID)
+	return revision, nil
+}
+
+// PurgeExpiredRevisions removes the revisions of files in any workspace that exceed the retention
+// limits, along with the stored content no revision refers to anymore.
+// It returns the number of removed revisions.
+func (s *Service) PurgeExpiredRevisions() (int, error) {
+	s.writes.RLock()
+	defer s.writes.RUnlock()
+	s.revisions.Lock()
+	defer s.revisions.Unlock()
+
+	userDirs, err := s.fs.ReadDir(s.RootDir)
+	if err != nil {
+		if s.fs.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	now := time.Now().UTC()
+	count := 0
+	for _, userDir := range userDirs {
+		if !userDir.IsDir() {
+			continue
+		}
+		userPath := filepath.Join(s.RootDir, userDir.Name())
+		workspaceDirs, err := s.fs.ReadDir(userPath)
+		if err != nil {
+			return count, err
+		}
+
+		for _, workspaceDir := range workspaceDirs {
+			workspaceID, err := strconv.Atoi(workspaceDir.Name())
+			if err != nil || !workspaceDir.IsDir() {
+				continue
+			}
+			revisionsPath := filepath.Join(userPath, workspaceDir.Name(), revisionsDirName)
+			removed, err := s.purgeWorkspaceRevisions(workspaceID, revisionsPath, now)
+			count += removed
+			if err != nil {
+				return count, err
+			}
+		}
+	}
+
+	return count, nil
+}
+
+// purgeWorkspaceRevisions prunes the histories in the revisions directory of a workspace and removes
+// unreferenced objects, which frees their storage. The caller must hold the revisions lock.
+func (s *Service) purgeWorkspaceRevisions(workspaceID int, revisionsPath string, now time.Time) (int, error) {
+	historyPath := filepath.Join(revisionsPath, revisionHistoryDir)
+	entries, err := s.fs.ReadDir(historyPath)
+	if err != nil {
+		if s.fs.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	count := 0
+	referenced := make(map[string]bool)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(historyPath, entry.Name())
+		history, err := s.readRevisionHistory(path, "")
+		if err != nil {
+			return count, err
+		}
+
+		if removed := s.pruneRevisions(history, now); removed > 0 {
+			if err := s.writeRevisionHistory(workspaceID, path, history); err != nil {
+				return count, fmt.Errorf("failed to write revision history: %w", err)
+			}
+			count += removed
+		}
+		for _, revision := range history.Revisions {
+			referenced[revision.Hash] = true
+		}
+	}
+
+	objectsPath := filepath.Join(revisionsPath, revisionObjectsDir)
+	objects, err := s.fs.ReadDir(objectsPath)
+	if err != nil {
+		if s.fs.IsNotExist(err) {
+			return count, nil
+		}
+		return count, err
+	}
+	for _, object := range objects {
+		if object.IsDir() || referenced[object.Name()] {
+			continue
+		}
+		objectPath := filepath.Join(objectsPath, object.Name())
+		size, files := s.fileUsage(objectPath)
+		if err := s.fs.Remove(objectPath); err != nil && !s.fs.IsNotExist(err) {
+			return count, fmt.Errorf("failed to remove revision object: %w", err)
+		}
+		s.addUsage(workspaceID, -size, -files)
+	}
+
+	return count, nil
+}
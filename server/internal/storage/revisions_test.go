@@ -0,0 +1,249 @@
+package storage_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"lemma/internal/git"
+	"lemma/internal/storage"
+
+	_ "lemma/internal/testenv"
+)
+
+func TestRevisions(t *testing.T) {
+	rootDir := t.TempDir()
+	s := storage.NewServiceWithOptions(rootDir, storage.Options{MaxRevisions: 3})
+
+	saveFile := func(t *testing.T, path, content string) {
+		t.Helper()
+		if err := s.SaveFile(1, 1, path, []byte(content)); err != nil {
+			t.Fatalf("failed to save file: %v", err)
+		}
+	}
+
+	listRevisions := func(t *testing.T, path string) []storage.FileRevision {
+		t.Helper()
+		revisions, err := s.ListRevisions(1, 1, path)
+		if err != nil {
+			t.Fatalf("ListRevisions() error = %v", err)
+		}
+		return revisions
+	}
+
+	t.Run("saving keeps the previous content", func(t *testing.T) {
+		saveFile(t, "notes.md", "one\n")
+		if got := listRevisions(t, "notes.md"); len(got) != 0 {
+			t.Fatalf("ListRevisions() after create = %d revisions, want 0", len(got))
+		}
+
+		saveFile(t, "notes.md", "two\n")
+		saveFile(t, "notes.md", "two\n") // Unchanged content is not recorded
+
+		revisions := listRevisions(t, "notes.md")
+		if len(revisions) != 1 {
+			t.Fatalf("ListRevisions() = %d revisions, want 1", len(revisions))
+		}
+		if revisions[0].ID != 1 || revisions[0].Size != 4 {
+			t.Errorf("ListRevisions() revision = %+v", revisions[0])
+		}
+
+		content, err := s.GetRevisionContent(1, 1, "notes.md", revisions[0].ID)
+		if err != nil {
+			t.Fatalf("GetRevisionContent() error = %v", err)
+		}
+		if string(content) != "one\n" {
+			t.Errorf("GetRevisionContent() = %q, want %q", content, "one\n")
+		}
+	})
+
+	t.Run("revisions are listed most recent first and limited by count", func(t *testing.T) {
+		for _, content := range []string{"a", "b", "c", "d", "e"} {
+			saveFile(t, "limited.md", content)
+		}
+
+		revisions := listRevisions(t, "limited.md")
+		if len(revisions) != 3 {
+			t.Fatalf("ListRevisions() = %d revisions, want 3", len(revisions))
+		}
+		if revisions[0].ID != 4 || revisions[2].ID != 2 {
+			t.Errorf("ListRevisions() IDs = %d..%d, want 4..2", revisions[0].ID, revisions[2].ID)
+		}
+		if _, err := s.GetRevisionContent(1, 1, "limited.md", 1); !errors.Is(err, storage.ErrRevisionNotFound) {
+			t.Errorf("GetRevisionContent() of pruned revision error = %v, want ErrRevisionNotFound", err)
+		}
+	})
+
+	t.Run("identical content is stored once", func(t *testing.T) {
+		saveFile(t, "copy-a.md", "shared")
+		saveFile(t, "copy-a.md", "changed")
+		saveFile(t, "copy-b.md", "shared")
+		saveFile(t, "copy-b.md", "changed")
+
+		a := listRevisions(t, "copy-a.md")
+		b := listRevisions(t, "copy-b.md")
+		if len(a) != 1 || len(b) != 1 || a[0].Hash != b[0].Hash {
+			t.Fatalf("revisions of identical content have different hashes: %+v, %+v", a, b)
+		}
+
+		objects, err := os.ReadDir(filepath.Join(s.GetWorkspacePath(1, 1), ".revisions", "objects"))
+		if err != nil {
+			t.Fatalf("failed to read objects: %v", err)
+		}
+		count := 0
+		for _, object := range objects {
+			if object.Name() == a[0].Hash {
+				count++
+			}
+		}
+		if count != 1 {
+			t.Errorf("object %s stored %d times, want 1", a[0].Hash, count)
+		}
+	})
+
+	t.Run("diff between revisions", func(t *testing.T) {
+		saveFile(t, "diff.md", "first\nsecond\n")
+		saveFile(t, "diff.md", "first\nthird\n")
+
+		chunks, err := s.DiffRevisions(1, 1, "diff.md", 1, storage.CurrentRevision)
+		if err != nil {
+			t.Fatalf("DiffRevisions() error = %v", err)
+		}
+		want := []storage.DiffChunk{
+			{Op: storage.DiffOpEqual, Text: "first\n"},
+			{Op: storage.DiffOpDelete, Text: "second\n"},
+			{Op: storage.DiffOpInsert, Text: "third\n"},
+		}
+		if len(chunks) != len(want) {
+			t.Fatalf("DiffRevisions() = %+v, want %+v", chunks, want)
+		}
+		for i := range want {
+			if chunks[i] != want[i] {
+				t.Errorf("DiffRevisions()[%d] = %+v, want %+v", i, chunks[i], want[i])
+			}
+		}
+
+		if _, err := s.DiffRevisions(1, 1, "diff.md", 1, 42); !errors.Is(err, storage.ErrRevisionNotFound) {
+			t.Errorf("DiffRevisions() with unknown revision error = %v, want ErrRevisionNotFound", err)
+		}
+	})
+
+	t.Run("restoring a revision keeps the replaced content", func(t *testing.T) {
+		saveFile(t, "restore.md", "old")
+		saveFile(t, "restore.md", "new")
+
+		revision, err := s.RestoreRevision(1, 1, "restore.md", 1)
+		if err != nil {
+			t.Fatalf("RestoreRevision() error = %v", err)
+		}
+		if revision.ID != 1 {
+			t.Errorf("RestoreRevision() = %+v, want revision 1", revision)
+		}
+
+		content, err := s.GetFileContent(1, 1, "restore.md")
+		if err != nil {
+			t.Fatalf("GetFileContent() error = %v", err)
+		}
+		if string(content) != "old" {
+			t.Errorf("restored content = %q, want %q", content, "old")
+		}
+
+		revisions := listRevisions(t, "restore.md")
+		if len(revisions) != 2 {
+			t.Fatalf("ListRevisions() = %d revisions, want 2", len(revisions))
+		}
+		undo, err := s.GetRevisionContent(1, 1, "restore.md", revisions[0].ID)
+		if err != nil {
+			t.Fatalf("GetRevisionContent() error = %v", err)
+		}
+		if string(undo) != "new" {
+			t.Errorf("latest revision = %q, want %q", undo, "new")
+		}
+
+		if _, err := s.RestoreRevision(1, 1, "restore.md", 42); !errors.Is(err, storage.ErrRevisionNotFound) {
+			t.Errorf("RestoreRevision() with unknown revision error = %v, want ErrRevisionNotFound", err)
+		}
+	})
+
+	t.Run("revisions are hidden and not accessible as files", func(t *testing.T) {
+		nodes, err := s.ListFilesRecursively(1, 1)
+		if err != nil {
+			t.Fatalf("ListFilesRecursively() error = %v", err)
+		}
+		for _, node := range nodes {
+			if strings.HasPrefix(node.Path, ".revisions") {
+				t.Errorf("ListFilesRecursively() contains revisions node %q", node.Path)
+			}
+		}
+
+		err = s.SaveFile(1, 1, ".revisions/history/x.json", []byte("{}"))
+		if !storage.IsPathValidationError(err) {
+			t.Errorf("SaveFile() into revisions error = %v, want path validation error", err)
+		}
+	})
+
+	t.Run("workspaces with git are not recorded", func(t *testing.T) {
+		gs := storage.NewServiceWithOptions(rootDir, storage.Options{
+			NewGitClient: func(_, _, _, _, _, _ string) git.Client { return &MockGitClient{} },
+		})
+		if err := gs.SetupGitRepo(1, 2, "https://example.com/repo", "user", "token", "name", "email"); err != nil {
+			t.Fatalf("SetupGitRepo() error = %v", err)
+		}
+		for _, content := range []string{"one", "two"} {
+			if err := gs.SaveFile(1, 2, "git.md", []byte(content)); err != nil {
+				t.Fatalf("failed to save file: %v", err)
+			}
+		}
+
+		revisions, err := gs.ListRevisions(1, 2, "git.md")
+		if err != nil {
+			t.Fatalf("ListRevisions() error = %v", err)
+		}
+		if len(revisions) != 0 {
+			t.Errorf("ListRevisions() = %d revisions, want 0", len(revisions))
+		}
+	})
+}
+
+func TestPurgeExpiredRevisions(t *testing.T) {
+	s := storage.NewServiceWithOptions(t.TempDir(), storage.Options{RevisionMaxAge: 50 * time.Millisecond})
+
+	for _, content := range []string{"one", "two", "three"} {
+		if err := s.SaveFile(1, 1, "notes.md", []byte(content)); err != nil {
+			t.Fatalf("failed to save file: %v", err)
+		}
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	count, err := s.PurgeExpiredRevisions()
+	if err != nil {
+		t.Fatalf("PurgeExpiredRevisions() error = %v", err)
+	}
+	if count != 2 {
+		t.Errorf("PurgeExpiredRevisions() = %d, want 2", count)
+	}
+
+	revisions, err := s.ListRevisions(1, 1, "notes.md")
+	if err != nil {
+		t.Fatalf("ListRevisions() error = %v", err)
+	}
+	if len(revisions) != 0 {
+		t.Errorf("ListRevisions() = %d revisions, want 0", len(revisions))
+	}
+
+	objects, err := os.ReadDir(filepath.Join(s.GetWorkspacePath(1, 1), ".revisions", "objects"))
+	if err != nil {
+		t.Fatalf("failed to read objects: %v", err)
+	}
+	if len(objects) != 0 {
+		t.Errorf("%d unreferenced objects left, want 0", len(objects))
+	}
+
+	content, err := s.GetFileContent(1, 1, "notes.md")
+	if err != nil || string(content) != "three" {
+		t.Errorf("GetFileContent() = %q, %v, want current content to be kept", content, err)
+	}
+}
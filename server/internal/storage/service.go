@@ -2,6 +2,8 @@ package storage
 
 import (
 	"lemma/internal/git"
+	"sync"
+	"time"
 )
 
 // Manager interface combines all storage interfaces.
@@ -9,6 +11,11 @@ type Manager interface {
 	FileManager
 	WorkspaceManager
 	RepositoryManager
+	BackupManager
+	ExportManager
+	TrashManager
+	RevisionManager
+	QuotaManager
 }
 
 // Service represents the file system structure.
@@ -17,12 +24,50 @@ type Service struct {
 	newGitClient func(url, user, token, path, commitName, commitEmail string) git.Client
 	RootDir      string
 	GitRepos     map[int]map[int]git.Client // map[userID]map[workspaceID]*git.Client
+	gitReposMu   sync.RWMutex               // guards GitRepos
+	writes       sync.RWMutex               // held for reading while files are written
+
+	maxRevisions   int
+	revisionMaxAge time.Duration
+	revisions      sync.Mutex // held while revision histories are updated
+
+	quotas     QuotaProvider
+	usageStore UsageStore
+	usage      map[int]*FileCountStats // map[workspaceID]usage, loaded or measured on first use
+	unstored   map[int]struct{}        // workspaces whose usage changed since it was last stored
+	storing    bool                    // whether a goroutine is writing usage to the usage store
+	usageMu    sync.Mutex
+
+	observer Observer
 }
 
+// Observer receives measurements of storage operations, for example to export them as metrics
+type Observer interface {
+	ObserveFileSave(size int)
+	ObserveGitOperation(operation string, duration time.Duration, err error)
+}
+
+// noopObserver discards all measurements
+type noopObserver struct{}
+
+func (noopObserver) ObserveFileSave(int)                              {}
+func (noopObserver) ObserveGitOperation(string, time.Duration, error) {}
+
 // Options represents the options for the storage service.
 type Options struct {
 	Fs           fileSystem
 	NewGitClient func(url, user, token, path, commitName, commitEmail string) git.Client
+
+	// MaxRevisions is the number of revisions kept per file, DefaultMaxRevisions if zero
+	MaxRevisions int
+	// RevisionMaxAge is how long revisions are kept, DefaultRevisionMaxAge if zero
+	RevisionMaxAge time.Duration
+	// Quotas provides the storage quotas of users and workspaces, storage is unlimited if nil
+	Quotas QuotaProvider
+	// Usage persists the storage usage of workspaces, usage is only kept in memory if nil
+	Usage UsageStore
+	// Observer receives measurements of file saves and Git operations, they are discarded if nil
+	Observer Observer
 }
 
 // NewService creates a new Storage instance with the default options and the given rootDir root directory.
@@ -43,10 +88,29 @@ func NewServiceWithOptions(rootDir string, options Options) *Service {
 		options.NewGitClient = git.New
 	}
 
+	if options.MaxRevisions <= 0 {
+		options.MaxRevisions = DefaultMaxRevisions
+	}
+
+	if options.RevisionMaxAge <= 0 {
+		options.RevisionMaxAge = DefaultRevisionMaxAge
+	}
+
+	if options.Observer == nil {
+		options.Observer = noopObserver{}
+	}
+
 	return &Service{
-		fs:           options.Fs,
-		newGitClient: options.NewGitClient,
-		RootDir:      rootDir,
-		GitRepos:     make(map[int]map[int]git.Client),
+		fs:             options.Fs,
+		newGitClient:   options.NewGitClient,
+		RootDir:        rootDir,
+		GitRepos:       make(map[int]map[int]git.Client),
+		maxRevisions:   options.MaxRevisions,
+		revisionMaxAge: options.RevisionMaxAge,
+		quotas:         options.Quotas,
+		usageStore:     options.Usage,
+		usage:          make(map[int]*FileCountStats),
+		unstored:       make(map[int]struct{}),
+		observer:       options.Observer,
 	}
 }
@@ -0,0 +1,318 @@
+package storage
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// trashDirName is the hidden directory in the workspace root that deleted files are moved to
+	trashDirName = ".trash"
+
+	trashMetadataFile = "meta.json"
+	trashContentFile  = "content"
+)
+
+// TrashManager provides functionalities to restore deleted files of a workspace.
+type TrashManager interface {
+	ListTrash(userID, workspaceID int) ([]TrashItem, error)
+	RestoreTrashItem(userID, workspaceID int, itemID string) (*TrashItem, error)
+	EmptyTrash(userID, workspaceID int) (int, error)
+	PurgeExpiredTrash(cutoff time.Time) (int, error)
+}
+
+// TrashItem is a deleted file or folder in the trash of a workspace
+type TrashItem struct {
+	ID        string    `json:"id"`
+	Path      string    `json:"path"` // Original path within the workspace
+	IsFolder  bool      `json:"isFolder"`
+	Size      int64     `json:"size"`
+	DeletedBy int       `json:"deletedBy"`
+	DeletedAt time.Time `json:"deletedAt"`
+}
+
+// getTrashPath returns the path to the trash directory of the workspace
+func (s *Service) getTrashPath(userID, workspaceID int) string {
+	return filepath.Join(s.GetWorkspacePath(userID, workspaceID), trashDirName)
+}
+
+// newTrashItemID returns a unique ID for a trash item that sorts by deletion time
+func newTrashItemID(deletedAt time.Time) (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(deletedAt.UnixNano(), 10) + "-" + hex.EncodeToString(b), nil
+}
+
+// moveToTrash moves the file or folder at fullPath into the trash of the workspace.
+// The moved content keeps adding to the storage size, but no longer counts as files, and the
+// metadata of the item adds to the size as well.
+// The caller must hold the writes lock.
+func (s *Service) moveToTrash(userID, workspaceID int, fullPath, filePath string, deletedBy int) (*TrashItem, error) {
+	info, err := s.fs.Stat(fullPath)
+	if err != nil {
+		return nil, err
+	}
+	files, err := s.countedFiles(fullPath, info.IsDir())
+	if err != nil {
+		return nil, err
+	}
+
+	item := &TrashItem{
+		Path:      filepath.ToSlash(filePath),
+		IsFolder:  info.IsDir(),
+		DeletedBy: deletedBy,
+		DeletedAt: time.Now().UTC(),
+	}
+	if !info.IsDir() {
+		item.Size = info.Size()
+	}
+	item.ID, err = newTrashItemID(item.DeletedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate trash item ID: %w", err)
+	}
+
+	metadata, err := json.Marshal(item)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode trash item: %w", err)
+	}
+
+	// The metadata is written first, so items without metadata are never left behind
+	itemPath := filepath.Join(s.getTrashPath(userID, workspaceID), item.ID)
+	if err := s.fs.MkdirAll(itemPath, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create trash directory: %w", err)
+	}
+	if err := s.fs.WriteFile(filepath.Join(itemPath, trashMetadataFile), metadata, 0644); err != nil {
+		s.fs.RemoveAll(itemPath)
+		return nil, fmt.Errorf("failed to write trash item: %w", err)
+	}
+	s.addUsage(workspaceID, int64(len(metadata)), 0)
+	if err := s.fs.Rename(fullPath, filepath.Join(itemPath, trashContentFile)); err != nil {
+		s.fs.RemoveAll(itemPath)
+		s.addUsage(workspaceID, -int64(len(metadata)), 0)
+		return nil, err
+	}
+	s.addUsage(workspaceID, 0, -files)
+
+	s.excludeReservedDirsFromGit(userID, workspaceID)
+	return item, nil
+}
+
+// readTrashItem reads the metadata of the trash item in itemPath
+func (s *Service) readTrashItem(itemPath string) (*TrashItem, error) {
+	metadata, err := s.fs.ReadFile(filepath.Join(itemPath, trashMetadataFile))
+	if err != nil {
+		return nil, err
+	}
+
+	var item TrashItem
+	if err := json.Unmarshal(metadata, &item); err != nil {
+		return nil, fmt.Errorf("failed to decode trash item: %w", err)
+	}
+	item.ID = filepath.Base(itemPath)
+	return &item, nil
+}
+
+// readTrash reads the items in the trash directory, most recently deleted first
+func (s *Service) readTrash(trashPath string) ([]TrashItem, error) {
+	entries, err := s.fs.ReadDir(trashPath)
+	if err != nil {
+		if s.fs.IsNotExist(err) {
+			return []TrashItem{}, nil
+		}
+		return nil, err
+	}
+
+	items := make([]TrashItem, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		item, err := s.readTrashItem(filepath.Join(trashPath, entry.Name()))
+		if err != nil {
+			getLogger().Warn("skipping unreadable trash item",
+				"path", filepath.Join(trashPath, entry.Name()),
+				"error", err.Error())
+			continue
+		}
+		items = append(items, *item)
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].DeletedAt.Equal(items[j].DeletedAt) {
+			return items[i].DeletedAt.After(items[j].DeletedAt)
+		}
+		return items[i].ID > items[j].ID
+	})
+	return items, nil
+}
+
+// ListTrash returns the deleted files and folders of the workspace, most recently deleted first.
+// Workspace is identified by the given userID and workspaceID.
+func (s *Service) ListTrash(userID, workspaceID int) ([]TrashItem, error) {
+	return s.readTrash(s.getTrashPath(userID, workspaceID))
+}
+
+// RestoreTrashItem moves the trash item back to its original path and returns it.
+// It returns ErrTrashItemNotFound if the item isn't in the trash of the workspace and
+// ErrFileExists if a file was created at the original path in the meantime.
+// The size of the item already counts against the storage quotas while it is in the trash, but its
+// files only count as files again once restored. ErrQuotaExceeded is returned if they don't fit.
+func (s *Service) RestoreTrashItem(userID, workspaceID int, itemID string) (*TrashItem, error) {
+	s.writes.RLock()
+	defer s.writes.RUnlock()
+
+	if itemID == "" || itemID != filepath.Base(itemID) || strings.HasPrefix(itemID, ".") {
+		return nil, ErrTrashItemNotFound
+	}
+
+	itemPath := filepath.Join(s.getTrashPath(userID, workspaceID), itemID)
+	item, err := s.readTrashItem(itemPath)
+	if err != nil {
+		if s.fs.IsNotExist(err) {
+			return nil, ErrTrashItemNotFound
+		}
+		return nil, err
+	}
+
+	fullPath, err := s.ValidatePath(userID, workspaceID, item.Path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.fs.Stat(fullPath); err == nil {
+		return nil, ErrFileExists
+	} else if !s.fs.IsNotExist(err) {
+		return nil, err
+	}
+
+	if err := s.fs.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return nil, err
+	}
+	contentPath := filepath.Join(itemPath, trashContentFile)
+	if err := s.fs.Rename(contentPath, fullPath); err != nil {
+		return nil, err
+	}
+	files, err := s.countedFiles(fullPath, item.IsFolder)
+	if err == nil {
+		err = s.reserveUsage(userID, workspaceID, 0, files)
+	}
+	if err != nil {
+		if renameErr := s.fs.Rename(fullPath, contentPath); renameErr != nil {
+			getLogger().Warn("failed to move trash item back after failed restore",
+				"userID", userID,
+				"workspaceID", workspaceID,
+				"itemID", itemID,
+				"error", renameErr.Error())
+		}
+		return nil, err
+	}
+	metadataBytes, metadataFiles := s.fileUsage(filepath.Join(itemPath, trashMetadataFile))
+	if err := s.fs.RemoveAll(itemPath); err != nil {
+		return nil, fmt.Errorf("failed to remove restored trash item: %w", err)
+	}
+	s.addUsage(workspaceID, -metadataBytes, -metadataFiles)
+
+	getLogger().Debug("trash item restored",
+		"userID", userID,
+		"workspaceID", workspaceID,
+		"itemID", itemID,
+		"path", item.Path)
+	return item, nil
+}
+
+// EmptyTrash permanently deletes all items in the trash of the workspace, which frees their storage.
+// It returns the number of deleted items.
+func (s *Service) EmptyTrash(userID, workspaceID int) (int, error) {
+	s.writes.RLock()
+	defer s.writes.RUnlock()
+
+	trashPath := s.getTrashPath(userID, workspaceID)
+	items, err := s.readTrash(trashPath)
+	if err != nil {
+		return 0, err
+	}
+
+	usage, err := s.measurePath(trashPath)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.fs.RemoveAll(trashPath); err != nil {
+		// Only the part of the trash that was removed is freed
+		if remaining, measureErr := s.measurePath(trashPath); measureErr == nil {
+			s.addUsage(workspaceID, remaining.TotalSize-usage.TotalSize, remaining.TotalFiles-usage.TotalFiles)
+		}
+		return 0, fmt.Errorf("failed to empty trash: %w", err)
+	}
+	s.addUsage(workspaceID, -usage.TotalSize, -usage.TotalFiles)
+
+	getLogger().Debug("trash emptied",
+		"userID", userID,
+		"workspaceID", workspaceID,
+		"count", len(items))
+	return len(items), nil
+}
+
+// PurgeExpiredTrash permanently deletes the items that were moved to the trash of any
+// workspace before the cutoff. It returns the number of deleted items.
+func (s *Service) PurgeExpiredTrash(cutoff time.Time) (int, error) {
+	s.writes.RLock()
+	defer s.writes.RUnlock()
+
+	userDirs, err := s.fs.ReadDir(s.RootDir)
+	if err != nil {
+		if s.fs.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	count := 0
+	for _, userDir := range userDirs {
+		if !userDir.IsDir() {
+			continue
+		}
+		userPath := filepath.Join(s.RootDir, userDir.Name())
+		workspaceDirs, err := s.fs.ReadDir(userPath)
+		if err != nil {
+			return count, err
+		}
+
+		for _, workspaceDir := range workspaceDirs {
+			workspaceID, err := strconv.Atoi(workspaceDir.Name())
+			if err != nil || !workspaceDir.IsDir() {
+				continue
+			}
+			trashPath := filepath.Join(userPath, workspaceDir.Name(), trashDirName)
+			items, err := s.readTrash(trashPath)
+			if err != nil {
+				return count, err
+			}
+
+			for _, item := range items {
+				if !item.DeletedAt.Before(cutoff) {
+					continue
+				}
+				itemPath := filepath.Join(trashPath, item.ID)
+				usage, err := s.measurePath(itemPath)
+				if err != nil {
+					return count, err
+				}
+				if err := s.fs.RemoveAll(itemPath); err != nil {
+					return count, fmt.Errorf("failed to purge trash item: %w", err)
+				}
+				s.addUsage(workspaceID, -usage.TotalSize, -usage.TotalFiles)
+				count++
+			}
+		}
+	}
+
+	return count, nil
+}
@@ -0,0 +1,158 @@
+package storage_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"lemma/internal/storage"
+
+	_ "lemma/internal/testenv"
+)
+
+func TestTrash(t *testing.T) {
+	s := storage.NewService(t.TempDir())
+
+	saveFile := func(t *testing.T, path, content string) {
+		t.Helper()
+		if err := s.SaveFile(1, 1, path, []byte(content)); err != nil {
+			t.Fatalf("failed to save file: %v", err)
+		}
+	}
+
+	t.Run("deleted files are moved to the trash", func(t *testing.T) {
+		saveFile(t, "notes/todo.md", "# Todo")
+
+		item, err := s.DeleteFile(1, 1, "notes/todo.md", 2)
+		if err != nil {
+			t.Fatalf("DeleteFile() error = %v", err)
+		}
+		if _, err := s.GetFileContent(1, 1, "notes/todo.md"); !os.IsNotExist(err) {
+			t.Errorf("deleted file still exists: %v", err)
+		}
+
+		items, err := s.ListTrash(1, 1)
+		if err != nil {
+			t.Fatalf("ListTrash() error = %v", err)
+		}
+		if len(items) != 1 {
+			t.Fatalf("ListTrash() = %d items, want 1", len(items))
+		}
+		got := items[0]
+		if got.ID != item.ID || got.Path != "notes/todo.md" || got.DeletedBy != 2 || got.Size != 6 || got.IsFolder {
+			t.Errorf("ListTrash() item = %+v", got)
+		}
+	})
+
+	t.Run("trash is hidden from listings and stats", func(t *testing.T) {
+		nodes, err := s.ListFilesRecursively(1, 1)
+		if err != nil {
+			t.Fatalf("ListFilesRecursively() error = %v", err)
+		}
+		for _, node := range nodes {
+			if strings.HasPrefix(node.Path, ".trash") {
+				t.Errorf("ListFilesRecursively() contains trash node %q", node.Path)
+			}
+		}
+
+		if _, err := s.FindFileByName(1, 1, "content"); err == nil {
+			t.Error("FindFileByName() found a file in the trash")
+		}
+
+		stats, err := s.GetFileStats(1, 1)
+		if err != nil {
+			t.Fatalf("GetFileStats() error = %v", err)
+		}
+		if stats.TotalFiles != 0 {
+			t.Errorf("GetFileStats() = %d files, want 0", stats.TotalFiles)
+		}
+
+		if _, err := s.GetFileContent(1, 1, ".trash/x/content"); !storage.IsPathValidationError(err) {
+			t.Errorf("GetFileContent() of trash path error = %v, want path validation error", err)
+		}
+	})
+
+	t.Run("restores items to their original path", func(t *testing.T) {
+		items, _ := s.ListTrash(1, 1)
+		saveFile(t, "notes/todo.md", "# New")
+
+		if _, err := s.RestoreTrashItem(1, 1, items[0].ID); !errors.Is(err, storage.ErrFileExists) {
+			t.Errorf("RestoreTrashItem() error = %v, want ErrFileExists", err)
+		}
+
+		if _, err := s.DeleteFile(1, 1, "notes/todo.md", 1); err != nil {
+			t.Fatalf("DeleteFile() error = %v", err)
+		}
+		if _, err := s.RestoreTrashItem(1, 1, items[0].ID); err != nil {
+			t.Fatalf("RestoreTrashItem() error = %v", err)
+		}
+
+		content, err := s.GetFileContent(1, 1, "notes/todo.md")
+		if err != nil || string(content) != "# Todo" {
+			t.Errorf("restored content = %q, %v, want %q", content, err, "# Todo")
+		}
+		if _, err := s.RestoreTrashItem(1, 1, items[0].ID); !errors.Is(err, storage.ErrTrashItemNotFound) {
+			t.Errorf("RestoreTrashItem() of restored item error = %v, want ErrTrashItemNotFound", err)
+		}
+		if _, err := s.RestoreTrashItem(1, 1, "../../2"); !errors.Is(err, storage.ErrTrashItemNotFound) {
+			t.Errorf("RestoreTrashItem() of invalid ID error = %v, want ErrTrashItemNotFound", err)
+		}
+	})
+
+	t.Run("empties the trash", func(t *testing.T) {
+		count, err := s.EmptyTrash(1, 1)
+		if err != nil {
+			t.Fatalf("EmptyTrash() error = %v", err)
+		}
+		if count != 1 {
+			t.Errorf("EmptyTrash() = %d, want 1", count)
+		}
+		if items, _ := s.ListTrash(1, 1); len(items) != 0 {
+			t.Errorf("ListTrash() after EmptyTrash() = %d items, want 0", len(items))
+		}
+	})
+
+	t.Run("purges expired items", func(t *testing.T) {
+		saveFile(t, "old.md", "# Old")
+		if _, err := s.DeleteFile(1, 1, "old.md", 1); err != nil {
+			t.Fatalf("DeleteFile() error = %v", err)
+		}
+
+		count, err := s.PurgeExpiredTrash(time.Now().Add(-time.Hour))
+		if err != nil || count != 0 {
+			t.Errorf("PurgeExpiredTrash() = %d, %v, want 0", count, err)
+		}
+		count, err = s.PurgeExpiredTrash(time.Now().Add(time.Hour))
+		if err != nil || count != 1 {
+			t.Errorf("PurgeExpiredTrash() = %d, %v, want 1", count, err)
+		}
+		if items, _ := s.ListTrash(1, 1); len(items) != 0 {
+			t.Errorf("ListTrash() after PurgeExpiredTrash() = %d items, want 0", len(items))
+		}
+	})
+
+	t.Run("excludes the trash from git", func(t *testing.T) {
+		gitDir := filepath.Join(s.GetWorkspacePath(1, 1), ".git")
+		if err := os.MkdirAll(gitDir, 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+
+		for _, name := range []string{"a.md", "b.md"} {
+			saveFile(t, name, "# Note")
+			if _, err := s.DeleteFile(1, 1, name, 1); err != nil {
+				t.Fatalf("DeleteFile() error = %v", err)
+			}
+		}
+
+		excludes, err := os.ReadFile(filepath.Join(gitDir, "info", "exclude"))
+		if err != nil {
+			t.Fatalf("failed to read git excludes: %v", err)
+		}
+		if string(excludes) != "/.trash/\n/.revisions/\n" {
+			t.Errorf("git excludes = %q, want %q", excludes, "/.trash/\n/.revisions/\n")
+		}
+	})
+}
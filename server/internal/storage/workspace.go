@@ -2,6 +2,7 @@ package storage
 
 import (
 	"fmt"
+	"lemma/internal/models"
 	"path/filepath"
 	"strings"
 )
@@ -12,6 +13,7 @@ type WorkspaceManager interface {
 	GetWorkspacePath(userID, workspaceID int) string
 	InitializeUserWorkspace(userID, workspaceID int) error
 	DeleteUserWorkspace(userID, workspaceID int) error
+	TransferWorkspace(fromUserID, toUserID int, workspace *models.Workspace, commit func() error) error
 }
 
 // ValidatePath validates the if the given path is valid within the workspace directory.
@@ -28,14 +30,118 @@ func (s *Service) ValidatePath(userID, workspaceID int, path string) (string, er
 	fullPath := filepath.Join(workspacePath, path)
 	cleanPath := filepath.Clean(fullPath)
 
-	// Verify the path is still within the workspace
-	if !strings.HasPrefix(cleanPath, workspacePath) {
+	// Verify the path is still within the workspace. The separator keeps workspace 1 from
+	// reaching into workspace 12 of the same user.
+	if cleanPath != workspacePath && !strings.HasPrefix(cleanPath, workspacePath+string(filepath.Separator)) {
 		return "", &PathValidationError{Path: path, Message: "path traversal attempt"}
 	}
 
+	// The trash and the revision history are only accessible through their own functions
+	for _, name := range reservedDirs {
+		reservedPath := filepath.Join(workspacePath, name)
+		if cleanPath == reservedPath || strings.HasPrefix(cleanPath, reservedPath+string(filepath.Separator)) {
+			return "", &PathValidationError{Path: path, Message: "reserved path"}
+		}
+	}
+
 	return cleanPath, nil
 }
 
+// reservedDirs are the hidden directories in the workspace root that hold data managed by the storage
+var reservedDirs = []string{trashDirName, revisionsDirName}
+
+// isReservedDir reports whether the path is one of the reserved directories of a workspace
+func (s *Service) isReservedDir(path string) bool {
+	rel, err := filepath.Rel(s.RootDir, path)
+	if err != nil {
+		return false
+	}
+	parts := strings.Split(rel, string(filepath.Separator))
+	if len(parts) != 3 {
+		return false
+	}
+	for _, name := range reservedDirs {
+		if parts[2] == name {
+			return true
+		}
+	}
+	return false
+}
+
+// isHiddenFile reports whether the path is within the Git repository or a reserved directory of a workspace
+func (s *Service) isHiddenFile(path string) bool {
+	rel, err := filepath.Rel(s.RootDir, path)
+	if err != nil {
+		return false
+	}
+	parts := strings.Split(rel, string(filepath.Separator))
+	if len(parts) < 4 {
+		return false
+	}
+	if parts[2] == ".git" {
+		return true
+	}
+	for _, name := range reservedDirs {
+		if parts[2] == name {
+			return true
+		}
+	}
+	return false
+}
+
+// excludeReservedDirsFromGit keeps the reserved directories out of commits of workspaces with a Git repository
+func (s *Service) excludeReservedDirsFromGit(userID, workspaceID int) {
+	gitPath := filepath.Join(s.GetWorkspacePath(userID, workspaceID), ".git")
+	if _, err := s.fs.Stat(gitPath); err != nil {
+		return
+	}
+
+	excludePath := filepath.Join(gitPath, "info", "exclude")
+	content, err := s.fs.ReadFile(excludePath)
+	if err != nil && !s.fs.IsNotExist(err) {
+		getLogger().Warn("failed to read git excludes",
+			"userID", userID,
+			"workspaceID", workspaceID,
+			"error", err.Error())
+		return
+	}
+
+	excluded := make(map[string]bool)
+	for _, line := range strings.Split(string(content), "\n") {
+		excluded[strings.TrimSpace(line)] = true
+	}
+
+	changed := false
+	for _, name := range reservedDirs {
+		pattern := "/" + name + "/"
+		if excluded[pattern] {
+			continue
+		}
+		if len(content) > 0 && !strings.HasSuffix(string(content), "\n") {
+			content = append(content, '\n')
+		}
+		content = append(content, pattern+"\n"...)
+		changed = true
+	}
+	if !changed {
+		return
+	}
+
+	oldSize, oldFiles := s.fileUsage(excludePath)
+	err = s.fs.MkdirAll(filepath.Dir(excludePath), 0755)
+	if err == nil {
+		err = s.fs.WriteFile(excludePath, content, 0644)
+	}
+	size, files := s.fileUsage(excludePath)
+	s.addUsage(workspaceID, size-oldSize, files-oldFiles)
+	if err != nil {
+		getLogger().Warn("failed to exclude reserved directories from git",
+			"userID", userID,
+			"workspaceID", workspaceID,
+			"error", err.Error())
+	}
+}
+
 // GetWorkspacePath returns the path to the workspace directory for the given userID and workspaceID.
 func (s *Service) GetWorkspacePath(userID, workspaceID int) string {
 	return filepath.Join(s.RootDir, fmt.Sprintf("%d", userID), fmt.Sprintf("%d", workspaceID))
@@ -48,6 +154,9 @@ func (s *Service) InitializeUserWorkspace(userID, workspaceID int) error {
 		"userID", userID,
 		"workspaceID", workspaceID)
 
+	s.writes.RLock()
+	defer s.writes.RUnlock()
+
 	workspacePath := s.GetWorkspacePath(userID, workspaceID)
 	err := s.fs.MkdirAll(workspacePath, 0755)
 	if err != nil {
@@ -64,11 +173,82 @@ func (s *Service) DeleteUserWorkspace(userID, workspaceID int) error {
 		"userID", userID,
 		"workspaceID", workspaceID)
 
+	s.writes.RLock()
+	defer s.writes.RUnlock()
+
 	workspacePath := s.GetWorkspacePath(userID, workspaceID)
-	err := s.fs.RemoveAll(workspacePath)
-	if err != nil {
+	if err := s.fs.RemoveAll(workspacePath); err != nil {
+		s.remeasureUsage(userID, workspaceID)
 		return fmt.Errorf("failed to delete workspace directory: %w", err)
 	}
+	s.setUsage(workspaceID, &FileCountStats{})
+
+	return nil
+}
+
+// TransferWorkspace moves the workspace directory from fromUserID to toUserID and removes its Git
+// client, which uses the credentials of fromUserID. commit is called after the move, while no files are written, to record the new owner.
+// If the move or commit fails, the workspace is moved back to fromUserID.
+// ErrQuotaExceeded is returned if the workspace doesn't fit in the storage quota of toUserID.
+// The usage of the workspace moves with it, as it is tracked per workspace.
+func (s *Service) TransferWorkspace(fromUserID, toUserID int, workspace *models.Workspace, commit func() error) error {
+	log := getLogger()
+	log.Debug("transferring workspace directory",
+		"fromUserID", fromUserID,
+		"toUserID", toUserID,
+		"workspaceID", workspace.ID)
+
+	s.writes.Lock()
+	defer s.writes.Unlock()
+
+	fromPath := s.GetWorkspacePath(fromUserID, workspace.ID)
+	toPath := s.GetWorkspacePath(toUserID, workspace.ID)
+
+	if _, err := s.fs.Stat(toPath); err == nil {
+		return fmt.Errorf("workspace directory already exists: %s", toPath)
+	} else if !s.fs.IsNotExist(err) {
+		return fmt.Errorf("failed to check workspace directory: %w", err)
+	}
+
+	moved := false
+	if _, err := s.fs.Stat(fromPath); err == nil {
+		if err := s.checkTransferQuota(fromUserID, toUserID, workspace.ID); err != nil {
+			return err
+		}
+
+		if err := s.fs.MkdirAll(filepath.Dir(toPath), 0755); err != nil {
+			return fmt.Errorf("failed to create user directory: %w", err)
+		}
+		if err := s.fs.Rename(fromPath, toPath); err != nil {
+			return fmt.Errorf("failed to move workspace directory: %w", err)
+		}
+		moved = true
+	} else if !s.fs.IsNotExist(err) {
+		return fmt.Errorf("failed to check workspace directory: %w", err)
+	}
+
+	// The Git client holds the credentials of the previous owner, the new owner has to set up Git again
+	oldClient, hadClient := s.removeGitRepo(fromUserID, workspace.ID)
+
+	rollback := func() {
+		if hadClient {
+			s.setGitRepo(fromUserID, workspace.ID, oldClient)
+		}
+		if moved {
+			if err := s.fs.Rename(toPath, fromPath); err != nil {
+				log.Error("failed to move workspace directory back",
+					"fromUserID", fromUserID,
+					"toUserID", toUserID,
+					"workspaceID", workspace.ID,
+					"error", err.Error())
+			}
+		}
+	}
+
+	if err := commit(); err != nil {
+		rollback()
+		return err
+	}
 
 	return nil
 }
@@ -2,10 +2,14 @@ package storage_test
 
 import (
 	"errors"
+	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 
+	"lemma/internal/git"
+	"lemma/internal/models"
 	"lemma/internal/storage"
 	_ "lemma/internal/testenv"
 )
@@ -51,6 +55,23 @@ func TestValidatePath(t *testing.T) {
 			wantErr:     true,
 			errContains: "path traversal attempt",
 		},
+		{
+			name:        "path into workspace with same prefix",
+			userID:      1,
+			workspaceID: 1,
+			path:        "../12/notes/test.md",
+			want:        "",
+			wantErr:     true,
+			errContains: "path traversal attempt",
+		},
+		{
+			name:        "path into own workspace root",
+			userID:      1,
+			workspaceID: 1,
+			path:        "../1/notes/test.md",
+			want:        filepath.Join("test-root", "1", "1", "notes", "test.md"),
+			wantErr:     false,
+		},
 		{
 			name:        "absolute path attempt",
 			userID:      1,
@@ -274,3 +295,170 @@ func TestDeleteUserWorkspace(t *testing.T) {
 		})
 	}
 }
+
+func TestTransferWorkspace(t *testing.T) {
+	quotas := mockQuotas{users: map[int]*models.StorageQuota{3: {MaxFiles: 1}}}
+	s := storage.NewServiceWithOptions(t.TempDir(), storage.Options{
+		Quotas: quotas,
+		NewGitClient: func(_, _, _, _, _, _ string) git.Client {
+			return &MockGitClient{}
+		},
+	})
+
+	workspace := &models.Workspace{ID: 1, UserID: 1, Name: "Notes", GitEnabled: true}
+	if err := s.InitializeUserWorkspace(1, 1); err != nil {
+		t.Fatalf("InitializeUserWorkspace() error = %v", err)
+	}
+	if err := s.SaveFile(1, 1, "a.md", []byte("a")); err != nil {
+		t.Fatalf("SaveFile() error = %v", err)
+	}
+	if err := s.SaveFile(1, 1, "b.md", []byte("b")); err != nil {
+		t.Fatalf("SaveFile() error = %v", err)
+	}
+	if err := s.SetupGitRepo(1, 1, "url", "user", "token", "name", "email"); err != nil {
+		t.Fatalf("SetupGitRepo() error = %v", err)
+	}
+	original := s.GitRepos[1][1]
+
+	t.Run("rolls back when commit fails", func(t *testing.T) {
+		err := s.TransferWorkspace(1, 2, workspace, func() error { return errors.New("commit failed") })
+		if err == nil {
+			t.Fatal("expected error, got nil")
+		}
+
+		if _, err := s.GetFileContent(1, 1, "a.md"); err != nil {
+			t.Errorf("file not restored: %v", err)
+		}
+		if _, err := os.Stat(s.GetWorkspacePath(2, 1)); !os.IsNotExist(err) {
+			t.Errorf("workspace directory of new owner exists, err = %v", err)
+		}
+		if s.GitRepos[1][1] != original {
+			t.Error("git client of previous owner not restored")
+		}
+	})
+
+	t.Run("rejects exceeding the quota of the new owner", func(t *testing.T) {
+		err := s.TransferWorkspace(1, 3, workspace, func() error { return nil })
+		if !errors.Is(err, storage.ErrQuotaExceeded) {
+			t.Fatalf("TransferWorkspace() error = %v, want ErrQuotaExceeded", err)
+		}
+		if _, err := s.GetFileContent(1, 1, "a.md"); err != nil {
+			t.Errorf("file moved: %v", err)
+		}
+	})
+
+	t.Run("moves files and removes git client", func(t *testing.T) {
+		committed := false
+		err := s.TransferWorkspace(1, 2, workspace, func() error {
+			committed = true
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("TransferWorkspace() error = %v", err)
+		}
+		if !committed {
+			t.Error("commit was not called")
+		}
+
+		content, err := s.GetFileContent(2, 1, "a.md")
+		if err != nil || string(content) != "a" {
+			t.Errorf("GetFileContent() = %q, %v, want a", content, err)
+		}
+		if _, err := os.Stat(s.GetWorkspacePath(1, 1)); !os.IsNotExist(err) {
+			t.Errorf("workspace directory of previous owner exists, err = %v", err)
+		}
+
+		// The client uses the credentials of the previous owner
+		if _, ok := s.GitRepos[1][1]; ok {
+			t.Error("git client of previous owner not removed")
+		}
+		if _, ok := s.GitRepos[2][1]; ok {
+			t.Error("git client created for new owner")
+		}
+
+		for userID, want := range map[int]int{1: 0, 2: 2} {
+			usage, err := s.GetUsage(userID)
+			if err != nil {
+				t.Fatalf("GetUsage() error = %v", err)
+			}
+			if usage.TotalFiles != want {
+				t.Errorf("usage of user %d = %d files, want %d", userID, usage.TotalFiles, want)
+			}
+		}
+	})
+}
+
+// errRepoMoved is returned by pathGitClient if its repository was moved away
+var errRepoMoved = errors.New("repository was moved")
+
+// pathGitClient is a Git client that fails to commit if its workspace path doesn't exist anymore
+type pathGitClient struct {
+	MockGitClient
+	path string
+}
+
+func (c *pathGitClient) Commit(_ string) (git.CommitHash, error) {
+	if _, err := os.Stat(c.path); err != nil {
+		return git.CommitHash{}, errRepoMoved
+	}
+	return git.CommitHash{}, nil
+}
+
+func (c *pathGitClient) Push() error {
+	return nil
+}
+
+func TestTransferWorkspaceDuringCommit(t *testing.T) {
+	s := storage.NewServiceWithOptions(t.TempDir(), storage.Options{
+		NewGitClient: func(_, _, _, path, _, _ string) git.Client {
+			return &pathGitClient{path: path}
+		},
+	})
+
+	if err := s.InitializeUserWorkspace(1, 1); err != nil {
+		t.Fatalf("InitializeUserWorkspace() error = %v", err)
+	}
+	if err := s.SetupGitRepo(1, 1, "url", "user", "token", "name", "email"); err != nil {
+		t.Fatalf("SetupGitRepo() error = %v", err)
+	}
+
+	// The workspace is transferred back and forth while commits run for either owner
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(done)
+		owner := 1
+		for i := 0; i < 50; i++ {
+			workspace := &models.Workspace{ID: 1, UserID: owner, Name: "Notes", GitEnabled: true}
+			if err := s.TransferWorkspace(owner, 3-owner, workspace, func() error { return nil }); err != nil {
+				t.Errorf("TransferWorkspace() error = %v", err)
+				return
+			}
+			owner = 3 - owner
+		}
+	}()
+
+	for _, userID := range []int{1, 2} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				// Commits for the previous owner fail as the repository is gone, but never
+				// use a client whose repository was moved away
+				if _, err := s.StageCommitAndPush(userID, 1, "commit"); errors.Is(err, errRepoMoved) {
+					t.Errorf("StageCommitAndPush() used the client of a moved repository")
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+}